@@ -0,0 +1,256 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package kubeschema validates rendered manifests against the JSON schemas of
+the Kubernetes API, without a cluster.
+
+Schemas are read from a directory or a URL laid out like the
+kubernetes-json-schema repository used by kubeconform, with one directory of
+strict standalone schemas per Kubernetes version. Schemas fetched over HTTP are
+cached on disk, so that charts can be validated offline once the schemas of
+their target version have been fetched.
+*/
+package kubeschema // import "helm.sh/helm/v4/internal/kubeschema"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"helm.sh/helm/v4/internal/fileutil"
+)
+
+// DefaultLocation is the kubernetes-json-schema repository.
+const DefaultLocation = "https://raw.githubusercontent.com/yannh/kubernetes-json-schema/master"
+
+// Validator validates manifests against the schemas of one Kubernetes version.
+type Validator struct {
+	// Location is the URL or directory the schemas are read from.
+	Location string
+	// CacheDir, when set, caches the schemas fetched over HTTP.
+	CacheDir string
+	Client   *http.Client
+
+	version string
+
+	mu      sync.Mutex
+	schemas map[string]*jsonschema.Schema
+}
+
+// New returns a Validator for the Kubernetes version with the given major and
+// minor numbers. The schemas of the first patch release of the version are
+// used.
+func New(major, minor, location, cacheDir string) *Validator {
+	if location == "" {
+		location = DefaultLocation
+	}
+	return &Validator{
+		Location: location,
+		CacheDir: cacheDir,
+		version:  fmt.Sprintf("v%s.%s.0", major, strings.TrimSuffix(minor, "+")),
+		schemas:  map[string]*jsonschema.Schema{},
+	}
+}
+
+// Validate checks the resources in a YAML stream of manifests. It returns one
+// error per invalid resource. Resources of kinds without a schema, such as
+// custom resources, are skipped. The second result is set when schemas cannot
+// be loaded.
+func (v *Validator) Validate(manifests string) ([]error, error) {
+	var invalid []error
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return invalid, err
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+		if err != nil {
+			return invalid, err
+		}
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		apiVersion, _ := obj["apiVersion"].(string)
+		kind, _ := obj["kind"].(string)
+		if apiVersion == "" || kind == "" {
+			continue
+		}
+
+		schema, err := v.schema(apiVersion, kind)
+		if err != nil {
+			return invalid, err
+		}
+		if schema == nil {
+			continue
+		}
+		if err := schema.Validate(doc); err != nil {
+			invalid = append(invalid, resourceError(obj, err))
+		}
+	}
+	return invalid, nil
+}
+
+// schemaFile returns the name of the schema file of a kind, such as
+// deployment-apps-v1.json.
+func schemaFile(apiVersion, kind string) string {
+	name := strings.ToLower(kind)
+	group, version, found := strings.Cut(apiVersion, "/")
+	if !found {
+		return fmt.Sprintf("%s-%s.json", name, group)
+	}
+	group, _, _ = strings.Cut(group, ".")
+	return fmt.Sprintf("%s-%s-%s.json", name, group, version)
+}
+
+// schema returns the compiled schema of a kind, or nil if it has none.
+func (v *Validator) schema(apiVersion, kind string) (*jsonschema.Schema, error) {
+	file := schemaFile(apiVersion, kind)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.schemas[file]; ok {
+		return s, nil
+	}
+
+	data, err := v.load(v.version+"-standalone-strict", file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading the Kubernetes %s schema of %s %s", v.version, apiVersion, kind)
+	}
+	if len(data) == 0 {
+		v.schemas[file] = nil
+		return nil, nil
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing schema %s", file)
+	}
+	// The schemas declare a meta-schema the validator does not know. They
+	// are written in draft 4.
+	if obj, ok := doc.(map[string]interface{}); ok {
+		delete(obj, "$schema")
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft4)
+	url := "file:///" + file
+	if err := compiler.AddResource(url, doc); err != nil {
+		return nil, errors.Wrapf(err, "parsing schema %s", file)
+	}
+	s, err := compiler.Compile(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "compiling schema %s", file)
+	}
+	v.schemas[file] = s
+	return s, nil
+}
+
+// load reads a schema file, returning nil data if it does not exist.
+func (v *Validator) load(dir, file string) ([]byte, error) {
+	if !strings.HasPrefix(v.Location, "http://") && !strings.HasPrefix(v.Location, "https://") {
+		data, err := os.ReadFile(filepath.Join(v.Location, dir, file))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+
+	var cached string
+	if v.CacheDir != "" {
+		cached = filepath.Join(v.CacheDir, dir, file)
+		// An empty file records that the kind has no schema.
+		if data, err := os.ReadFile(cached); err == nil {
+			if len(data) == 0 {
+				return nil, nil
+			}
+			return data, nil
+		}
+	}
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(strings.TrimSuffix(v.Location, "/") + "/" + dir + "/" + file)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var data []byte
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	case http.StatusNotFound:
+	default:
+		return nil, errors.Errorf("fetching %s: %s", resp.Request.URL, resp.Status)
+	}
+	if cached != "" {
+		if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+			return nil, err
+		}
+		if err := fileutil.AtomicWriteFile(cached, bytes.NewReader(data), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+var printer = message.NewPrinter(language.English)
+
+// resourceError describes the violations of a resource's schema.
+func resourceError(obj map[string]interface{}, err error) error {
+	name := fmt.Sprint(obj["kind"])
+	if meta, ok := obj["metadata"].(map[string]interface{}); ok {
+		if n, ok := meta["name"].(string); ok {
+			name += "/" + n
+		}
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return errors.Wrap(err, name)
+	}
+
+	var leaves []string
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			leaves = append(leaves, fmt.Sprintf("at '/%s': %s", strings.Join(e.InstanceLocation, "/"), e.ErrorKind.LocalizedString(printer)))
+		}
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+	}
+	walk(validationErr)
+	return errors.Errorf("%s does not match the Kubernetes schema: %s", name, strings.Join(leaves, "; "))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeschema
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const manifests = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: "2"
+  selector: {}
+  template: {}
+  replica: 2
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: custom
+spec:
+  anything: goes
+`
+
+func TestSchemaFile(t *testing.T) {
+	assert.Equal(t, "configmap-v1.json", schemaFile("v1", "ConfigMap"))
+	assert.Equal(t, "deployment-apps-v1.json", schemaFile("apps/v1", "Deployment"))
+	assert.Equal(t, "ingress-networking-v1.json", schemaFile("networking.k8s.io/v1", "Ingress"))
+}
+
+func TestValidate(t *testing.T) {
+	v := New("1", "20", "testdata", "")
+	invalid, err := v.Validate(manifests)
+	require.NoError(t, err)
+	require.Len(t, invalid, 1)
+	assert.Contains(t, invalid[0].Error(), "Deployment/web does not match the Kubernetes schema")
+	assert.Contains(t, invalid[0].Error(), "at '/spec/replicas'")
+	assert.Contains(t, invalid[0].Error(), "replica")
+
+	// Versions without schemas have no kinds to validate.
+	invalid, err = New("1", "99", "testdata", "").Validate(manifests)
+	require.NoError(t, err)
+	assert.Empty(t, invalid)
+}
+
+func TestValidateCachesFetchedSchemas(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeFile(w, r, filepath.Join("testdata", filepath.FromSlash(r.URL.Path)))
+	}))
+	defer srv.Close()
+
+	cacheDir := t.TempDir()
+	invalid, err := New("1", "20", srv.URL, cacheDir).Validate(manifests)
+	require.NoError(t, err)
+	assert.Len(t, invalid, 1)
+	assert.Equal(t, 3, requests)
+	_, err = os.Stat(filepath.Join(cacheDir, "v1.20.0-standalone-strict", "deployment-apps-v1.json"))
+	require.NoError(t, err)
+
+	// Once cached, schemas are read from disk.
+	srv.Close()
+	invalid, err = New("1", "20", srv.URL, cacheDir).Validate(manifests)
+	require.NoError(t, err)
+	assert.Len(t, invalid, 1)
+}
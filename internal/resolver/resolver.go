@@ -58,6 +58,22 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 	locked := make([]*chart.Dependency, len(reqs))
 	missing := []string{}
 	for i, d := range reqs {
+		if d.Digest != "" && d.Version == "" {
+			// A dependency pinned only by digest takes its version from the
+			// chart the digest refers to.
+			version, err := r.digestVersion(d)
+			if err != nil {
+				return nil, err
+			}
+			locked[i] = &chart.Dependency{
+				Name:       d.Name,
+				Repository: d.Repository,
+				Version:    version,
+				Digest:     d.Digest,
+			}
+			continue
+		}
+
 		constraint, err := semver.NewConstraint(d.Version)
 		if err != nil {
 			return nil, errors.Wrapf(err, "dependency %q has an invalid version/constraint format", d.Name)
@@ -113,6 +129,7 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 				Name:       d.Name,
 				Repository: d.Repository,
 				Version:    d.Version,
+				Digest:     d.Digest,
 			}
 			continue
 		}
@@ -171,6 +188,7 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 			Name:       d.Name,
 			Repository: d.Repository,
 			Version:    version,
+			Digest:     d.Digest,
 		}
 		// The versions are already sorted and hence the first one to satisfy the constraint is used
 		for _, ver := range vs {
@@ -207,12 +225,29 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 	}, nil
 }
 
+// digestVersion looks up the version of the chart a digest-pinned OCI
+// dependency refers to.
+func (r *Resolver) digestVersion(d *chart.Dependency) (string, error) {
+	if !registry.IsOCI(d.Repository) {
+		return "", errors.Errorf("dependency %q is pinned to a digest, which is only supported for oci:// repositories", d.Name)
+	}
+	ref := fmt.Sprintf("%s/%s@%s", strings.TrimPrefix(d.Repository, fmt.Sprintf("%s://", registry.OCIScheme)), d.Name, d.Digest)
+	result, err := r.registryClient.Pull(ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not resolve dependency %q at digest %s", d.Name, d.Digest)
+	}
+	if result.Chart == nil || result.Chart.Meta == nil {
+		return "", errors.Errorf("could not resolve dependency %q at digest %s: no chart metadata found", d.Name, d.Digest)
+	}
+	return result.Chart.Meta.Version, nil
+}
+
 // HashReq generates a hash of the dependencies.
 //
 // This should be used only to compare against another hash generated by this
 // function.
 func HashReq(req, lock []*chart.Dependency) (string, error) {
-	data, err := json.Marshal([2][]*chart.Dependency{req, lock})
+	data, err := json.Marshal([2][]*chart.Dependency{req, withoutResolution(lock)})
 	if err != nil {
 		return "", err
 	}
@@ -220,6 +255,23 @@ func HashReq(req, lock []*chart.Dependency) (string, error) {
 	return "sha256:" + s, err
 }
 
+// withoutResolution strips the resolution metadata from locked dependencies,
+// so that the hash only changes with the dependencies themselves.
+func withoutResolution(lock []*chart.Dependency) []*chart.Dependency {
+	out := make([]*chart.Dependency, len(lock))
+	for i, d := range lock {
+		if d == nil {
+			continue
+		}
+		dd := *d
+		dd.ContentDigest = ""
+		dd.ResolvedURL = ""
+		dd.Resolved = nil
+		out[i] = &dd
+	}
+	return out
+}
+
 // HashV2Req generates a hash of requirements generated in Helm v2.
 //
 // This should be used only to compare against another hash generated by the
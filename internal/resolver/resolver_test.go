@@ -17,7 +17,9 @@ package resolver
 
 import (
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/registry"
@@ -181,6 +183,37 @@ func TestResolve(t *testing.T) {
 	}
 }
 
+func TestResolveDigestPinned(t *testing.T) {
+	const digest = "sha256:6f1a8a35ea8b1d5ea4b5c0d16e0b1b9ad1e28a8e1e7e5a2cba6a8d1c64bbe3b4"
+	req := []*chart.Dependency{
+		{Name: "alpine", Repository: "oci://example.com/charts", Version: "0.1.0", Digest: digest},
+		{Name: "base", Repository: "oci://example.com/charts", Version: "0.1.0"},
+	}
+	repoNames := map[string]string{"alpine": "oci://example.com/charts", "base": "oci://example.com/charts"}
+
+	registryClient, _ := registry.NewClient()
+	r := New("testdata/chartpath", "testdata/repository", registryClient)
+	l, err := r.Resolve(req, repoNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.Dependencies[0].Digest != digest {
+		t.Errorf("expected locked digest %s, got %q", digest, l.Dependencies[0].Digest)
+	}
+	if l.Dependencies[1].Digest != "" {
+		t.Errorf("expected no locked digest for an unpinned dependency, got %q", l.Dependencies[1].Digest)
+	}
+
+	// Changing the pinned digest must invalidate the lock.
+	req[0].Digest = "sha256:" + strings.Repeat("0", 64)
+	if h, err := HashReq(req, l.Dependencies); err != nil {
+		t.Fatal(err)
+	} else if h == l.Digest {
+		t.Error("expected a different hash once the pinned digest changes")
+	}
+}
+
 func TestHashReq(t *testing.T) {
 	expect := "sha256:fb239e836325c5fa14b29d1540a13b7d3ba13151b67fe719f820e0ef6d66aaaf"
 
@@ -308,3 +341,27 @@ func TestGetLocalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestHashReqIgnoresResolution(t *testing.T) {
+	req := []*chart.Dependency{{Name: "alpine", Version: "0.1.0", Repository: "http://localhost:8879/charts"}}
+	lock := []*chart.Dependency{{Name: "alpine", Version: "0.1.0", Repository: "http://localhost:8879/charts"}}
+	expect, err := HashReq(req, lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	lock[0].ContentDigest = "sha256:" + strings.Repeat("0", 64)
+	lock[0].ResolvedURL = "http://localhost:8879/charts/alpine-0.1.0.tgz"
+	lock[0].Resolved = &now
+	h, err := HashReq(req, lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h != expect {
+		t.Errorf("expected the hash to ignore resolution metadata, got %s, want %s", h, expect)
+	}
+	if lock[0].ContentDigest == "" {
+		t.Error("expected HashReq to leave the locked dependencies untouched")
+	}
+}
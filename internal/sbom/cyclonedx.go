@@ -0,0 +1,147 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type cdxBOM struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components,omitempty"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type               string                 `json:"type"`
+	BOMRef             string                 `json:"bom-ref,omitempty"`
+	Name               string                 `json:"name"`
+	Version            string                 `json:"version,omitempty"`
+	Hashes             []cdxHash              `json:"hashes,omitempty"`
+	ExternalReferences []cdxExternalReference `json:"externalReferences,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// serialNumber derives a stable RFC 4122 URN from the chart digest.
+func serialNumber(digest string) string {
+	b, err := hex.DecodeString(digest)
+	if err != nil || len(b) < 16 {
+		b = make([]byte, 16)
+	}
+	b = append([]byte(nil), b[:16]...)
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+func generateCycloneDX(c *Chart, created time.Time, tool string) ([]byte, error) {
+	chartRef := fmt.Sprintf("chart:%s@%s", c.Name, c.Version)
+	toolName, toolVersion, _ := strings.Cut(tool, "-")
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: serialNumber(c.Digest),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: created.UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type:    "application",
+				Name:    toolName,
+				Version: toolVersion,
+			}}},
+			Component: cdxComponent{
+				Type:    "application",
+				BOMRef:  chartRef,
+				Name:    c.Name,
+				Version: c.Version,
+				Hashes:  []cdxHash{{Alg: "SHA-256", Content: c.Digest}},
+			},
+		},
+	}
+
+	chartDeps := cdxDependency{Ref: chartRef}
+	for _, f := range c.Files {
+		bom.Components = append(bom.Components, cdxComponent{
+			Type:   "file",
+			BOMRef: "file:" + f.Name,
+			Name:   f.Name,
+			Hashes: []cdxHash{{Alg: "SHA-256", Content: f.Digest}},
+		})
+	}
+
+	for _, d := range c.Dependencies {
+		ref := fmt.Sprintf("chart:%s@%s", d.Name, d.Version)
+		component := cdxComponent{
+			Type:    "application",
+			BOMRef:  ref,
+			Name:    d.Name,
+			Version: d.Version,
+		}
+		if d.Repository != "" {
+			component.ExternalReferences = []cdxExternalReference{{Type: "distribution", URL: d.Repository}}
+		}
+		bom.Components = append(bom.Components, component)
+		chartDeps.DependsOn = append(chartDeps.DependsOn, ref)
+	}
+
+	for _, image := range c.Images {
+		ref := "image:" + image
+		name, version := splitImage(image)
+		bom.Components = append(bom.Components, cdxComponent{
+			Type:    "container",
+			BOMRef:  ref,
+			Name:    name,
+			Version: version,
+		})
+		chartDeps.DependsOn = append(chartDeps.DependsOn, ref)
+	}
+	bom.Dependencies = []cdxDependency{chartDeps}
+
+	return json.MarshalIndent(bom, "", "  ")
+}
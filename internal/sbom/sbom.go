@@ -0,0 +1,234 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package sbom generates software bills of materials for chart archives.
+
+An SBOM lists the files in a chart archive, the chart's dependencies and the
+container images referenced by its manifests, in either the SPDX or the
+CycloneDX JSON format.
+*/
+package sbom // import "helm.sh/helm/v4/internal/sbom"
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+// Format is the document format of an SBOM.
+type Format string
+
+const (
+	// SPDX is the SPDX 2.3 JSON format.
+	SPDX Format = "spdx"
+	// CycloneDX is the CycloneDX 1.5 JSON format.
+	CycloneDX Format = "cyclonedx"
+)
+
+// ParseFormat returns the Format with the given name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case SPDX, CycloneDX:
+		return f, nil
+	}
+	return "", errors.Errorf("unknown SBOM format %q: must be one of %q or %q", name, SPDX, CycloneDX)
+}
+
+// MediaType returns the media type of documents in the format.
+func (f Format) MediaType() string {
+	if f == CycloneDX {
+		return registry.CycloneDXMediaType
+	}
+	return registry.SPDXMediaType
+}
+
+// Extension returns the file extension of documents in the format.
+func (f Format) Extension() string {
+	if f == CycloneDX {
+		return ".cdx.json"
+	}
+	return ".spdx.json"
+}
+
+// Formats lists the supported formats.
+var Formats = []Format{SPDX, CycloneDX}
+
+// FileName returns the name of the SBOM file written next to a chart archive.
+func FileName(archive string, f Format) string {
+	return archive + f.Extension()
+}
+
+// Chart describes the contents of a chart archive.
+type Chart struct {
+	Name    string
+	Version string
+	// Digest is the hex encoded SHA-256 digest of the chart archive.
+	Digest       string
+	Files        []File
+	Dependencies []Dependency
+	Images       []string
+}
+
+// File is a file in a chart archive.
+type File struct {
+	Name string
+	// Digest is the hex encoded SHA-256 digest of the file.
+	Digest string
+}
+
+// Dependency is a chart dependency.
+type Dependency struct {
+	Name       string
+	Version    string
+	Repository string
+}
+
+// FromArchive reads the files, metadata and dependencies of a chart archive.
+//
+// Dependencies are taken from the chart's lock file when it has one, so that
+// they carry the exact versions that were packaged.
+func FromArchive(path string) (*Chart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := loader.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	c := &Chart{
+		Name:    ch.Name(),
+		Version: ch.Metadata.Version,
+		Digest:  hex.EncodeToString(sum[:]),
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(hash, tr); err != nil {
+			return nil, err
+		}
+		c.Files = append(c.Files, File{Name: h.Name, Digest: hex.EncodeToString(hash.Sum(nil))})
+	}
+	sort.Slice(c.Files, func(i, j int) bool { return c.Files[i].Name < c.Files[j].Name })
+
+	if ch.Lock != nil {
+		for _, d := range ch.Lock.Dependencies {
+			c.Dependencies = append(c.Dependencies, Dependency{Name: d.Name, Version: d.Version, Repository: d.Repository})
+		}
+	} else {
+		for _, d := range ch.Metadata.Dependencies {
+			c.Dependencies = append(c.Dependencies, Dependency{Name: d.Name, Version: d.Version, Repository: d.Repository})
+		}
+	}
+	return c, nil
+}
+
+// Images returns the sorted container image references found in rendered
+// manifests. Any "image" field holding a string is considered a reference.
+// Manifests that are not valid YAML are skipped.
+func Images(manifests map[string]string) []string {
+	found := map[string]bool{}
+	for _, m := range manifests {
+		for _, doc := range strings.Split(m, "\n---") {
+			var v interface{}
+			if err := yaml.Unmarshal([]byte(doc), &v); err != nil {
+				continue
+			}
+			collectImages(v, found)
+		}
+	}
+	images := make([]string, 0, len(found))
+	for image := range found {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+func collectImages(v interface{}, found map[string]bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if s, ok := val.(string); ok && k == "image" {
+				if s = strings.TrimSpace(s); s != "" {
+					found[s] = true
+				}
+				continue
+			}
+			collectImages(val, found)
+		}
+	case []interface{}:
+		for _, val := range v {
+			collectImages(val, found)
+		}
+	}
+}
+
+// Generate renders an SBOM for the chart in the given format. The creation
+// time is recorded in the document, so that identical inputs produce
+// identical documents.
+func Generate(c *Chart, f Format, created time.Time, tool string) ([]byte, error) {
+	switch f {
+	case SPDX:
+		return generateSPDX(c, created, tool)
+	case CycloneDX:
+		return generateCycloneDX(c, created, tool)
+	}
+	return nil, errors.Errorf("unknown SBOM format %q", f)
+}
+
+// splitImage splits an image reference into its repository and its tag or digest.
+func splitImage(image string) (string, string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		return image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
@@ -0,0 +1,127 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("SPDX")
+	require.NoError(t, err)
+	assert.Equal(t, SPDX, f)
+
+	_, err = ParseFormat("swid")
+	assert.ErrorContains(t, err, `unknown SBOM format "swid"`)
+}
+
+func TestImages(t *testing.T) {
+	manifests := map[string]string{
+		"chart/templates/deployment.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.36
+      containers:
+      - name: app
+        image: ghcr.io/example/app@sha256:0123
+      - name: sidecar
+        image: busybox:1.36
+---
+apiVersion: v1
+kind: ConfigMap
+data:
+  image: nginx
+`,
+		"chart/templates/NOTES.txt": "Thank you for installing {{ this is not yaml",
+	}
+	assert.Equal(t, []string{"busybox:1.36", "ghcr.io/example/app@sha256:0123", "nginx"}, Images(manifests))
+}
+
+func TestFromArchiveAndGenerate(t *testing.T) {
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: chart.APIVersionV2,
+			Name:       "mychart",
+			Version:    "1.0.0",
+			Dependencies: []*chart.Dependency{
+				{Name: "sub", Version: "^1.0.0", Repository: "https://example.com/charts"},
+			},
+		},
+		Lock: &chart.Lock{
+			Dependencies: []*chart.Dependency{
+				{Name: "sub", Version: "1.2.3", Repository: "https://example.com/charts"},
+			},
+		},
+		Templates: []*chart.File{{Name: "templates/pod.yaml", Data: []byte("kind: Pod\n")}},
+	}
+	archive, err := chartutil.Save(ch, t.TempDir(), chartutil.WithModTime(time.Unix(0, 0)))
+	require.NoError(t, err)
+
+	c, err := FromArchive(archive)
+	require.NoError(t, err)
+	assert.Equal(t, "mychart", c.Name)
+	assert.Len(t, c.Digest, 64)
+	assert.Equal(t, []Dependency{{Name: "sub", Version: "1.2.3", Repository: "https://example.com/charts"}}, c.Dependencies)
+	var names []string
+	for _, f := range c.Files {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"mychart/Chart.lock", "mychart/Chart.yaml", "mychart/templates/pod.yaml"}, names)
+
+	c.Images = []string{"nginx:1.25"}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	data, err := Generate(c, SPDX, created, "helm-v4.0")
+	require.NoError(t, err)
+	var spdx spdxDocument
+	require.NoError(t, json.Unmarshal(data, &spdx))
+	assert.Equal(t, "SPDX-2.3", spdx.SPDXVersion)
+	assert.Equal(t, "2024-01-02T03:04:05Z", spdx.CreationInfo.Created)
+	assert.Len(t, spdx.Files, 3)
+	require.Len(t, spdx.Packages, 3)
+	assert.Equal(t, "sub", spdx.Packages[1].Name)
+	assert.Equal(t, "nginx", spdx.Packages[2].Name)
+	assert.Equal(t, "1.25", spdx.Packages[2].VersionInfo)
+	assert.Equal(t, "CONTAINER", spdx.Packages[2].PrimaryPackagePurpose)
+
+	data, err = Generate(c, CycloneDX, created, "helm-v4.0")
+	require.NoError(t, err)
+	var bom cdxBOM
+	require.NoError(t, json.Unmarshal(data, &bom))
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Equal(t, "mychart", bom.Metadata.Component.Name)
+	assert.Regexp(t, `^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, bom.SerialNumber)
+	require.Len(t, bom.Dependencies, 1)
+	assert.Equal(t, []string{"chart:sub@1.2.3", "image:nginx:1.25"}, bom.Dependencies[0].DependsOn)
+
+	again, err := Generate(c, CycloneDX, created, "helm-v4.0")
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files,omitempty"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID                string         `json:"SPDXID"`
+	Name                  string         `json:"name"`
+	VersionInfo           string         `json:"versionInfo,omitempty"`
+	DownloadLocation      string         `json:"downloadLocation"`
+	FilesAnalyzed         bool           `json:"filesAnalyzed"`
+	Checksums             []spdxChecksum `json:"checksums,omitempty"`
+	PrimaryPackagePurpose string         `json:"primaryPackagePurpose,omitempty"`
+}
+
+type spdxFile struct {
+	SPDXID    string         `json:"SPDXID"`
+	FileName  string         `json:"fileName"`
+	Checksums []spdxChecksum `json:"checksums"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+var spdxIDInvalid = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+
+func spdxID(kind string, i int, name string) string {
+	return fmt.Sprintf("SPDXRef-%s-%d-%s", kind, i, spdxIDInvalid.ReplaceAllString(name, "-"))
+}
+
+func generateSPDX(c *Chart, created time.Time, tool string) ([]byte, error) {
+	chartID := "SPDXRef-Chart-" + spdxIDInvalid.ReplaceAllString(c.Name, "-")
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              fmt.Sprintf("%s-%s", c.Name, c.Version),
+		DocumentNamespace: fmt.Sprintf("https://helm.sh/spdx/%s-%s-%s", c.Name, c.Version, c.Digest),
+		CreationInfo: spdxCreationInfo{
+			Created:  created.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + tool},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:                chartID,
+			Name:                  c.Name,
+			VersionInfo:           c.Version,
+			DownloadLocation:      "NOASSERTION",
+			Checksums:             []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: c.Digest}},
+			PrimaryPackagePurpose: "APPLICATION",
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: chartID,
+		}},
+	}
+
+	for i, f := range c.Files {
+		id := spdxID("File", i, f.Name)
+		doc.Files = append(doc.Files, spdxFile{
+			SPDXID:    id,
+			FileName:  "./" + f.Name,
+			Checksums: []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: f.Digest}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{chartID, "CONTAINS", id})
+	}
+
+	for i, d := range c.Dependencies {
+		id := spdxID("Dependency", i, d.Name)
+		location := d.Repository
+		if location == "" {
+			location = "NOASSERTION"
+		}
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:                id,
+			Name:                  d.Name,
+			VersionInfo:           d.Version,
+			DownloadLocation:      location,
+			PrimaryPackagePurpose: "APPLICATION",
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{chartID, "DEPENDS_ON", id})
+	}
+
+	for i, image := range c.Images {
+		id := spdxID("Image", i, image)
+		name, version := splitImage(image)
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:                id,
+			Name:                  name,
+			VersionInfo:           version,
+			DownloadLocation:      "NOASSERTION",
+			PrimaryPackagePurpose: "CONTAINER",
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{chartID, "DEPENDS_ON", id})
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
@@ -0,0 +1,112 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package sigstore implements keyless signing and verification of chart archives.
+
+A chart is signed with a short-lived key whose certificate is issued by Fulcio
+for an OIDC identity, and the signature is recorded in the Rekor transparency
+log. The result is stored as a Sigstore bundle next to the chart archive.
+*/
+package sigstore // import "helm.sh/helm/v4/internal/sigstore"
+
+const (
+	// BundleMediaType is the media type of Sigstore bundles.
+	BundleMediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+
+	// DefaultFulcioURL is the public Fulcio certificate authority.
+	DefaultFulcioURL = "https://fulcio.sigstore.dev"
+
+	// DefaultRekorURL is the public Rekor transparency log.
+	DefaultRekorURL = "https://rekor.sigstore.dev"
+)
+
+// BundleFileName returns the name of the bundle written next to a chart archive.
+func BundleFileName(archive string) string {
+	return archive + ".sigstore.json"
+}
+
+// Bundle is a Sigstore bundle holding a message signature together with the
+// material needed to verify it offline.
+type Bundle struct {
+	MediaType            string               `json:"mediaType"`
+	VerificationMaterial VerificationMaterial `json:"verificationMaterial"`
+	MessageSignature     MessageSignature     `json:"messageSignature"`
+}
+
+// VerificationMaterial holds the signing certificate and transparency log entries.
+type VerificationMaterial struct {
+	Certificate *Certificate `json:"certificate,omitempty"`
+	TlogEntries []TlogEntry  `json:"tlogEntries"`
+}
+
+// Certificate is a DER encoded X.509 certificate.
+type Certificate struct {
+	RawBytes []byte `json:"rawBytes"`
+}
+
+// TlogEntry is an entry in the Rekor transparency log.
+type TlogEntry struct {
+	LogIndex          int64             `json:"logIndex,string"`
+	LogID             LogID             `json:"logId"`
+	KindVersion       KindVersion       `json:"kindVersion"`
+	IntegratedTime    int64             `json:"integratedTime,string"`
+	InclusionPromise  *InclusionPromise `json:"inclusionPromise,omitempty"`
+	InclusionProof    *InclusionProof   `json:"inclusionProof,omitempty"`
+	CanonicalizedBody []byte            `json:"canonicalizedBody"`
+}
+
+// LogID identifies a transparency log by the digest of its public key.
+type LogID struct {
+	KeyID []byte `json:"keyId"`
+}
+
+// KindVersion is the type of a transparency log entry.
+type KindVersion struct {
+	Kind    string `json:"kind"`
+	Version string `json:"version"`
+}
+
+// InclusionPromise is the log's signed promise to include an entry.
+type InclusionPromise struct {
+	SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+}
+
+// InclusionProof proves the inclusion of an entry in the log's Merkle tree.
+type InclusionProof struct {
+	LogIndex   int64      `json:"logIndex,string"`
+	RootHash   []byte     `json:"rootHash"`
+	TreeSize   int64      `json:"treeSize,string"`
+	Hashes     [][]byte   `json:"hashes"`
+	Checkpoint Checkpoint `json:"checkpoint"`
+}
+
+// Checkpoint is a signed note committing to the log's tree head.
+type Checkpoint struct {
+	Envelope string `json:"envelope"`
+}
+
+// MessageSignature is a signature over the digest of a message.
+type MessageSignature struct {
+	MessageDigest MessageDigest `json:"messageDigest"`
+	Signature     []byte        `json:"signature"`
+}
+
+// MessageDigest is the digest of a signed message.
+type MessageDigest struct {
+	Algorithm string `json:"algorithm"`
+	Digest    []byte `json:"digest"`
+}
@@ -0,0 +1,287 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sigstore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Signer signs messages with a certificate issued by Fulcio for an OIDC
+// identity token, and records the signatures in Rekor.
+type Signer struct {
+	// IdentityToken is the OIDC token proving the signer's identity.
+	IdentityToken string
+	FulcioURL     string
+	RekorURL      string
+	Client        *http.Client
+}
+
+// Sign signs data and returns the bundle needed to verify the signature.
+func (s *Signer) Sign(data []byte) (*Bundle, error) {
+	if s.IdentityToken == "" {
+		return nil, errors.New("an OIDC identity token is required for keyless signing")
+	}
+	subject, err := tokenSubject(s.IdentityToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key only lives for the duration of this call. Its certificate binds
+	// it to the identity in the token.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := s.signingCertificate(key, subject)
+	if err != nil {
+		return nil, errors.Wrap(err, "requesting a signing certificate from Fulcio")
+	}
+
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.logEntry(cert, digest[:], sig)
+	if err != nil {
+		return nil, errors.Wrap(err, "recording the signature in Rekor")
+	}
+
+	return &Bundle{
+		MediaType: BundleMediaType,
+		VerificationMaterial: VerificationMaterial{
+			Certificate: &Certificate{RawBytes: cert.Raw},
+			TlogEntries: []TlogEntry{*entry},
+		},
+		MessageSignature: MessageSignature{
+			MessageDigest: MessageDigest{Algorithm: "SHA2_256", Digest: digest[:]},
+			Signature:     sig,
+		},
+	}, nil
+}
+
+// tokenSubject returns the subject claim of a JWT without verifying it.
+// Fulcio verifies the token; the subject is only needed to prove possession
+// of the signing key.
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.Wrap(err, "decoding identity token")
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", errors.Wrap(err, "decoding identity token")
+	}
+	if claims.Subject == "" {
+		return "", errors.New("identity token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+type fulcioRequest struct {
+	Credentials struct {
+		OIDCIdentityToken string `json:"oidcIdentityToken"`
+	} `json:"credentials"`
+	PublicKeyRequest struct {
+		PublicKey struct {
+			Algorithm string `json:"algorithm"`
+			Content   string `json:"content"`
+		} `json:"publicKey"`
+		ProofOfPossession []byte `json:"proofOfPossession"`
+	} `json:"publicKeyRequest"`
+}
+
+type fulcioChain struct {
+	Chain struct {
+		Certificates []string `json:"certificates"`
+	} `json:"chain"`
+}
+
+type fulcioResponse struct {
+	SignedCertificateEmbeddedSct *fulcioChain `json:"signedCertificateEmbeddedSct"`
+	SignedCertificateDetachedSct *fulcioChain `json:"signedCertificateDetachedSct"`
+}
+
+func (s *Signer) signingCertificate(key *ecdsa.PrivateKey, subject string) (*x509.Certificate, error) {
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	proof := sha256.Sum256([]byte(subject))
+	pop, err := ecdsa.SignASN1(rand.Reader, key, proof[:])
+	if err != nil {
+		return nil, err
+	}
+
+	req := fulcioRequest{}
+	req.Credentials.OIDCIdentityToken = s.IdentityToken
+	req.PublicKeyRequest.PublicKey.Algorithm = "ECDSA"
+	req.PublicKeyRequest.PublicKey.Content = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}))
+	req.PublicKeyRequest.ProofOfPossession = pop
+
+	var resp fulcioResponse
+	if err := s.post(strings.TrimSuffix(s.FulcioURL, "/")+"/api/v2/signingCert", req, &resp); err != nil {
+		return nil, err
+	}
+	chain := resp.SignedCertificateEmbeddedSct
+	if chain == nil {
+		chain = resp.SignedCertificateDetachedSct
+	}
+	if chain == nil || len(chain.Chain.Certificates) == 0 {
+		return nil, errors.New("no certificate in response")
+	}
+	block, _ := pem.Decode([]byte(chain.Chain.Certificates[0]))
+	if block == nil {
+		return nil, errors.New("invalid certificate in response")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// hashedRekord is the body of a Rekor entry for a signed digest.
+type hashedRekord struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+	} `json:"spec"`
+}
+
+type rekorEntry struct {
+	Body           []byte `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+	Verification   struct {
+		SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+		InclusionProof       *struct {
+			Checkpoint string   `json:"checkpoint"`
+			Hashes     []string `json:"hashes"`
+			LogIndex   int64    `json:"logIndex"`
+			RootHash   string   `json:"rootHash"`
+			TreeSize   int64    `json:"treeSize"`
+		} `json:"inclusionProof"`
+	} `json:"verification"`
+}
+
+func (s *Signer) logEntry(cert *x509.Certificate, digest, sig []byte) (*TlogEntry, error) {
+	rekord := hashedRekord{APIVersion: "0.0.1", Kind: "hashedrekord"}
+	rekord.Spec.Signature.Content = sig
+	rekord.Spec.Signature.PublicKey.Content = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	rekord.Spec.Data.Hash.Algorithm = "sha256"
+	rekord.Spec.Data.Hash.Value = hex.EncodeToString(digest)
+
+	var resp map[string]rekorEntry
+	if err := s.post(strings.TrimSuffix(s.RekorURL, "/")+"/api/v1/log/entries", rekord, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp) != 1 {
+		return nil, errors.Errorf("expected one log entry in response, got %d", len(resp))
+	}
+	var e rekorEntry
+	for _, v := range resp {
+		e = v
+	}
+
+	logID, err := hex.DecodeString(e.LogID)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid log ID in response")
+	}
+	entry := &TlogEntry{
+		LogIndex:          e.LogIndex,
+		LogID:             LogID{KeyID: logID},
+		KindVersion:       KindVersion{Kind: rekord.Kind, Version: rekord.APIVersion},
+		IntegratedTime:    e.IntegratedTime,
+		InclusionPromise:  &InclusionPromise{SignedEntryTimestamp: e.Verification.SignedEntryTimestamp},
+		CanonicalizedBody: e.Body,
+	}
+	if p := e.Verification.InclusionProof; p != nil {
+		proof := &InclusionProof{
+			LogIndex:   p.LogIndex,
+			TreeSize:   p.TreeSize,
+			Checkpoint: Checkpoint{Envelope: p.Checkpoint},
+		}
+		if proof.RootHash, err = hex.DecodeString(p.RootHash); err != nil {
+			return nil, errors.Wrap(err, "invalid inclusion proof in response")
+		}
+		for _, h := range p.Hashes {
+			b, err := hex.DecodeString(h)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid inclusion proof in response")
+			}
+			proof.Hashes = append(proof.Hashes, b)
+		}
+		entry.InclusionProof = proof
+	}
+	return entry, nil
+}
+
+func (s *Signer) post(url string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(string(respData)))
+	}
+	return json.Unmarshal(respData, out)
+}
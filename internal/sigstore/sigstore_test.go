@@ -0,0 +1,420 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sigstore
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ct "github.com/google/certificate-transparency-go"
+	cttls "github.com/google/certificate-transparency-go/tls"
+	ctx509 "github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+	rekorutil "github.com/sigstore/rekor/pkg/util"
+	sgbundle "github.com/sigstore/sigstore-go/pkg/bundle"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIdentity = "chart-maintainer@example.com"
+	testIssuer   = "https://oauth2.example.com/auth"
+)
+
+var (
+	// oidIssuerV2 is the Fulcio extension holding the OIDC issuer as a DER encoded UTF8String.
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	// oidCTPoison marks a precertificate, which is never used to sign.
+	oidCTPoison = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3}
+	// oidSCTList holds the timestamps embedded in a certificate by CT logs.
+	oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+)
+
+// testSigstore runs fake Fulcio and Rekor services, and a certificate
+// transparency log that Fulcio submits its certificates to.
+type testSigstore struct {
+	fulcio, rekor *httptest.Server
+	caKey         *ecdsa.PrivateKey
+	ca            *x509.Certificate
+	logKey        *ecdsa.PrivateKey
+	logID         []byte
+	ctKey         *ecdsa.PrivateKey
+	ctID          []byte
+
+	// withoutSCT issues certificates without a certificate transparency timestamp.
+	withoutSCT bool
+	// withoutProof records entries without an inclusion proof.
+	withoutProof bool
+	// hashAlgorithm overrides the digest algorithm recorded in entries.
+	hashAlgorithm string
+}
+
+func newTestSigstore(t *testing.T) *testSigstore {
+	t.Helper()
+	s := &testSigstore{}
+
+	var err error
+	s.caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, s.caKey.Public(), s.caKey)
+	require.NoError(t, err)
+	s.ca, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	s.logKey, s.logID = testLogKey(t)
+	s.ctKey, s.ctID = testLogKey(t)
+
+	s.fulcio = httptest.NewServer(http.HandlerFunc(s.serveFulcio(t)))
+	t.Cleanup(s.fulcio.Close)
+	s.rekor = httptest.NewServer(http.HandlerFunc(s.serveRekor(t)))
+	t.Cleanup(s.rekor.Close)
+	return s
+}
+
+// testLogKey returns a log signing key and the log ID derived from it.
+func testLogKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	sum := sha256.Sum256(pub)
+	return key, sum[:]
+}
+
+func (s *testSigstore) serveFulcio(t *testing.T) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req fulcioRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		block, _ := pem.Decode([]byte(req.PublicKeyRequest.PublicKey.Content))
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		require.NoError(t, err)
+		subject, err := tokenSubject(req.Credentials.OIDCIdentityToken)
+		require.NoError(t, err)
+		proof := sha256.Sum256([]byte(subject))
+		if !ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), proof[:], req.PublicKeyRequest.ProofOfPossession) {
+			http.Error(w, "invalid proof of possession", http.StatusBadRequest)
+			return
+		}
+
+		issuer, err := asn1.MarshalWithParams(testIssuer, "utf8")
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber:    big.NewInt(2),
+			NotBefore:       time.Now().Add(-time.Minute),
+			NotAfter:        time.Now().Add(10 * time.Minute),
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			EmailAddresses:  []string{subject},
+			ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+		}
+		if !s.withoutSCT {
+			template.ExtraExtensions = append(template.ExtraExtensions, s.embeddedSCT(t, template, pub))
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, s.ca, pub, s.caKey)
+		require.NoError(t, err)
+		chain := &fulcioChain{}
+		chain.Chain.Certificates = []string{
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.ca.Raw})),
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(fulcioResponse{SignedCertificateEmbeddedSct: chain}))
+	}
+}
+
+// embeddedSCT logs a precertificate for template in the certificate
+// transparency log, and returns the extension embedding the log's timestamp
+// in the final certificate.
+func (s *testSigstore) embeddedSCT(t *testing.T, template *x509.Certificate, pub crypto.PublicKey) pkix.Extension {
+	t.Helper()
+	precert := *template
+	precert.ExtraExtensions = append(append([]pkix.Extension(nil), template.ExtraExtensions...),
+		pkix.Extension{Id: oidCTPoison, Critical: true, Value: asn1.NullBytes})
+	der, err := x509.CreateCertificate(rand.Reader, &precert, s.ca, pub, s.caKey)
+	require.NoError(t, err)
+	chain, err := ctx509.ParseCertificates(append(der, s.ca.Raw...))
+	require.NoError(t, err)
+
+	sct := ct.SignedCertificateTimestamp{
+		SCTVersion: ct.V1,
+		LogID:      ct.LogID{KeyID: [32]byte(s.ctID)},
+		Timestamp:  uint64(time.Now().UnixMilli()),
+	}
+	leaf, err := ct.MerkleTreeLeafFromChain(chain, ct.PrecertLogEntryType, sct.Timestamp)
+	require.NoError(t, err)
+	input, err := ct.SerializeSCTSignatureInput(sct, ct.LogEntry{Leaf: *leaf})
+	require.NoError(t, err)
+	sum := sha256.Sum256(input)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.ctKey, sum[:])
+	require.NoError(t, err)
+	sct.Signature = ct.DigitallySigned{
+		Algorithm: cttls.SignatureAndHashAlgorithm{Hash: cttls.SHA256, Signature: cttls.ECDSA},
+		Signature: sig,
+	}
+
+	list, err := x509util.MarshalSCTsIntoSCTList([]*ct.SignedCertificateTimestamp{&sct})
+	require.NoError(t, err)
+	raw, err := cttls.Marshal(*list)
+	require.NoError(t, err)
+	value, err := asn1.Marshal(raw)
+	require.NoError(t, err)
+	return pkix.Extension{Id: oidSCTList, Value: value}
+}
+
+func (s *testSigstore) serveRekor(t *testing.T) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rekord hashedRekord
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&rekord))
+		if s.hashAlgorithm != "" {
+			rekord.Spec.Data.Hash.Algorithm = s.hashAlgorithm
+		}
+		body, err := json.Marshal(rekord)
+		require.NoError(t, err)
+
+		entry := rekorEntry{
+			Body:           body,
+			IntegratedTime: time.Now().Unix(),
+			LogID:          hex.EncodeToString(s.logID),
+			LogIndex:       42,
+		}
+		payload, err := json.Marshal(map[string]interface{}{
+			"body":           entry.Body,
+			"integratedTime": entry.IntegratedTime,
+			"logID":          entry.LogID,
+			"logIndex":       entry.LogIndex,
+		})
+		require.NoError(t, err)
+		sum := sha256.Sum256(payload)
+		entry.Verification.SignedEntryTimestamp, err = ecdsa.SignASN1(rand.Reader, s.logKey, sum[:])
+		require.NoError(t, err)
+
+		// The entry is the only leaf of the log's current shard, so the root
+		// of the tree is the hash of the leaf and the inclusion proof is empty.
+		if !s.withoutProof {
+			rootHash := sha256.Sum256(append([]byte{0}, body...))
+			signer, err := signature.LoadECDSASignerVerifier(s.logKey, crypto.SHA256)
+			require.NoError(t, err)
+			checkpoint, err := rekorutil.CreateAndSignCheckpoint(context.Background(), "rekor.example.com", 1, 1, rootHash[:], signer)
+			require.NoError(t, err)
+			entry.Verification.InclusionProof = &struct {
+				Checkpoint string   `json:"checkpoint"`
+				Hashes     []string `json:"hashes"`
+				LogIndex   int64    `json:"logIndex"`
+				RootHash   string   `json:"rootHash"`
+				TreeSize   int64    `json:"treeSize"`
+			}{
+				Checkpoint: string(checkpoint),
+				LogIndex:   0,
+				RootHash:   hex.EncodeToString(rootHash[:]),
+				TreeSize:   1,
+			}
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]rekorEntry{"entry-uuid": entry}))
+	}
+}
+
+// trustedRoot writes a trusted root for the fake services, whose keys are
+// valid from start to end.
+func (s *testSigstore) trustedRoot(t *testing.T, start, end time.Time) string {
+	t.Helper()
+	validFor := map[string]interface{}{"start": start.UTC(), "end": end.UTC()}
+	logInstance := func(url string, key *ecdsa.PrivateKey, id []byte) map[string]interface{} {
+		pub, err := x509.MarshalPKIXPublicKey(key.Public())
+		require.NoError(t, err)
+		return map[string]interface{}{
+			"baseUrl":       url,
+			"hashAlgorithm": "SHA2_256",
+			"publicKey": map[string]interface{}{
+				"rawBytes":   pub,
+				"keyDetails": "PKIX_ECDSA_P256_SHA_256",
+				"validFor":   validFor,
+			},
+			"logId": map[string]interface{}{"keyId": id},
+		}
+	}
+	root := map[string]interface{}{
+		"mediaType": "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+		"tlogs":     []interface{}{logInstance(s.rekor.URL, s.logKey, s.logID)},
+		"ctlogs":    []interface{}{logInstance("https://ctfe.example.com", s.ctKey, s.ctID)},
+		"certificateAuthorities": []interface{}{map[string]interface{}{
+			"uri": s.fulcio.URL,
+			"certChain": map[string]interface{}{
+				"certificates": []interface{}{map[string]interface{}{"rawBytes": s.ca.Raw}},
+			},
+			"validFor": validFor,
+		}},
+	}
+	data, err := json.Marshal(root)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "trusted_root.json")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+// sign signs data with the fake services.
+func (s *testSigstore) sign(t *testing.T, data []byte) *Bundle {
+	t.Helper()
+	signer := &Signer{
+		IdentityToken: testToken(testIdentity),
+		FulcioURL:     s.fulcio.URL,
+		RekorURL:      s.rekor.URL,
+	}
+	bundle, err := signer.Sign(data)
+	require.NoError(t, err)
+	assert.Equal(t, BundleMediaType, bundle.MediaType)
+	return bundle
+}
+
+func testToken(subject string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		enc.EncodeToString([]byte(`{"sub":"`+subject+`","iss":"`+testIssuer+`"}`)) + ".sig"
+}
+
+func TestSignAndVerify(t *testing.T) {
+	s := newTestSigstore(t)
+	data := []byte("chart archive")
+	bundle := s.sign(t, data)
+
+	now := time.Now()
+	root, err := LoadTrustedRoot(s.trustedRoot(t, now.Add(-time.Hour), now.Add(time.Hour)))
+	require.NoError(t, err)
+	opts := VerifyOptions{Identity: testIdentity, Issuer: testIssuer}
+
+	result, err := bundle.Verify(data, root, opts)
+	require.NoError(t, err)
+	assert.Equal(t, testIdentity, result.Identity)
+	assert.Equal(t, testIssuer, result.Issuer)
+	assert.Equal(t, int64(42), result.LogIndex)
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), result.Digest)
+
+	// The bundle file can be read by other Sigstore clients.
+	path := BundleFileName(filepath.Join(t.TempDir(), "mychart-0.1.0.tgz"))
+	raw, err := json.Marshal(bundle)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, raw, 0644))
+	entity, err := sgbundle.LoadJSONFromPath(path)
+	require.NoError(t, err)
+	_, err = VerifyEntity(entity, data, root, opts)
+	require.NoError(t, err)
+
+	_, err = bundle.Verify([]byte("tampered"), root, opts)
+	assert.ErrorContains(t, err, "invalid signature")
+
+	_, err = bundle.Verify(data, root, VerifyOptions{Identity: "someone@example.com", Issuer: testIssuer})
+	assert.ErrorContains(t, err, "someone@example.com")
+
+	_, err = bundle.Verify(data, root, VerifyOptions{Identity: testIdentity, Issuer: "https://accounts.example.com"})
+	assert.ErrorContains(t, err, "https://accounts.example.com")
+
+	_, err = bundle.Verify(data, root, VerifyOptions{})
+	assert.ErrorContains(t, err, "are required")
+
+	other := newTestSigstore(t)
+	otherRoot, err := LoadTrustedRoot(other.trustedRoot(t, now.Add(-time.Hour), now.Add(time.Hour)))
+	require.NoError(t, err)
+	_, err = bundle.Verify(data, otherRoot, opts)
+	assert.ErrorContains(t, err, "log inclusion")
+
+	// The keys of the root were no longer valid when the chart was signed.
+	expiredRoot, err := LoadTrustedRoot(s.trustedRoot(t, now.Add(-2*time.Hour), now.Add(-time.Hour)))
+	require.NoError(t, err)
+	_, err = bundle.Verify(data, expiredRoot, opts)
+	assert.ErrorContains(t, err, "log inclusion")
+
+	bundle.VerificationMaterial.TlogEntries[0].LogIndex++
+	_, err = bundle.Verify(data, root, opts)
+	assert.ErrorContains(t, err, "log inclusion")
+}
+
+func TestVerifyRequiresInclusionProof(t *testing.T) {
+	s := newTestSigstore(t)
+	s.withoutProof = true
+	data := []byte("chart archive")
+	bundle := s.sign(t, data)
+
+	now := time.Now()
+	root, err := LoadTrustedRoot(s.trustedRoot(t, now.Add(-time.Hour), now.Add(time.Hour)))
+	require.NoError(t, err)
+	_, err = bundle.Verify(data, root, VerifyOptions{Identity: testIdentity, Issuer: testIssuer})
+	assert.ErrorContains(t, err, "inclusion proof")
+}
+
+func TestVerifyRequiresSCT(t *testing.T) {
+	s := newTestSigstore(t)
+	s.withoutSCT = true
+	data := []byte("chart archive")
+	bundle := s.sign(t, data)
+
+	now := time.Now()
+	root, err := LoadTrustedRoot(s.trustedRoot(t, now.Add(-time.Hour), now.Add(time.Hour)))
+	require.NoError(t, err)
+	_, err = bundle.Verify(data, root, VerifyOptions{Identity: testIdentity, Issuer: testIssuer})
+	assert.ErrorContains(t, err, "SCT")
+}
+
+func TestVerifyRejectsHashAlgorithm(t *testing.T) {
+	s := newTestSigstore(t)
+	s.hashAlgorithm = "sha512"
+	data := []byte("chart archive")
+	bundle := s.sign(t, data)
+
+	now := time.Now()
+	root, err := LoadTrustedRoot(s.trustedRoot(t, now.Add(-time.Hour), now.Add(time.Hour)))
+	require.NoError(t, err)
+	_, err = bundle.Verify(data, root, VerifyOptions{Identity: testIdentity, Issuer: testIssuer})
+	assert.ErrorContains(t, err, "invalid value for hash")
+}
+
+func TestSignRequiresToken(t *testing.T) {
+	_, err := (&Signer{}).Sign([]byte("chart archive"))
+	assert.ErrorContains(t, err, "identity token is required")
+
+	_, err = (&Signer{IdentityToken: "not-a-jwt"}).Sign([]byte("chart archive"))
+	assert.ErrorContains(t, err, "not a JWT")
+}
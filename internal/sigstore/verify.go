@@ -0,0 +1,121 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sigstore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	sgbundle "github.com/sigstore/sigstore-go/pkg/bundle"
+	"github.com/sigstore/sigstore-go/pkg/root"
+	"github.com/sigstore/sigstore-go/pkg/verify"
+)
+
+// TrustedRoot holds the certificate authorities, transparency logs and
+// certificate transparency logs that signatures are verified against, with
+// the periods each of their keys is valid for.
+type TrustedRoot struct {
+	root *root.TrustedRoot
+}
+
+// LoadTrustedRoot reads a Sigstore trusted root, as distributed through the
+// Sigstore TUF repository in trusted_root.json.
+func LoadTrustedRoot(path string) (*TrustedRoot, error) {
+	r, err := root.NewTrustedRootFromPath(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing trusted root %s", path)
+	}
+	return &TrustedRoot{root: r}, nil
+}
+
+// VerifyOptions are the identity a signature is expected to be made by.
+type VerifyOptions struct {
+	// Identity is the expected subject alternative name of the signing
+	// certificate, such as an email address or a workflow URI.
+	Identity string
+	// Issuer is the expected OIDC issuer of the signer's identity token.
+	Issuer string
+}
+
+// Result describes a verified signature.
+type Result struct {
+	Identity string
+	Issuer   string
+	// Digest is the hex encoded SHA-256 digest of the signed message.
+	Digest         string
+	LogIndex       int64
+	IntegratedTime time.Time
+}
+
+// Verify checks that the bundle holds a valid signature over data made by the
+// expected identity. See VerifyEntity for what is verified.
+func (b *Bundle) Verify(data []byte, root *TrustedRoot, opts VerifyOptions) (*Result, error) {
+	raw, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	entity := &sgbundle.Bundle{}
+	if err := entity.UnmarshalJSON(raw); err != nil {
+		return nil, errors.Wrap(err, "parsing Sigstore bundle")
+	}
+	return VerifyEntity(entity, data, root, opts)
+}
+
+// VerifyEntity checks that entity holds a valid signature over data made by
+// the expected identity. The signing certificate must chain to one of the
+// root's certificate authorities and carry a timestamp from one of its
+// certificate transparency logs, and the signature must be included in one of
+// its transparency logs while the certificate was valid.
+func VerifyEntity(entity verify.SignedEntity, data []byte, root *TrustedRoot, opts VerifyOptions) (*Result, error) {
+	if opts.Identity == "" || opts.Issuer == "" {
+		return nil, errors.New("both the expected certificate identity and OIDC issuer are required for keyless verification")
+	}
+	identity, err := verify.NewShortCertificateIdentity(opts.Issuer, "", opts.Identity, "")
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := verify.NewSignedEntityVerifier(root.root,
+		verify.WithSignedCertificateTimestamps(1),
+		verify.WithTransparencyLog(1),
+		verify.WithObserverTimestamps(1))
+	if err != nil {
+		return nil, err
+	}
+	result, err := verifier.Verify(entity, verify.NewPolicy(
+		verify.WithArtifact(bytes.NewReader(data)),
+		verify.WithCertificateIdentity(identity)))
+	if err != nil {
+		return nil, errors.Wrap(err, "verifying Sigstore bundle")
+	}
+
+	entries, err := entity.TlogEntries()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(data)
+	return &Result{
+		Identity:       result.Signature.Certificate.SubjectAlternativeName,
+		Issuer:         result.Signature.Certificate.Issuer,
+		Digest:         hex.EncodeToString(digest[:]),
+		LogIndex:       entries[0].LogIndex(),
+		IntegratedTime: entries[0].IntegratedTime(),
+	}, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreaders
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/engine"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/statusreaders"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/object"
+)
+
+type customAPIServiceStatusReader struct {
+	genericStatusReader engine.StatusReader
+}
+
+func NewCustomAPIServiceStatusReader(mapper meta.RESTMapper) engine.StatusReader {
+	genericStatusReader := statusreaders.NewGenericStatusReader(mapper, apiServiceConditions)
+	return &customAPIServiceStatusReader{
+		genericStatusReader: genericStatusReader,
+	}
+}
+
+func (a *customAPIServiceStatusReader) Supports(gk schema.GroupKind) bool {
+	return gk == schema.GroupKind{Group: "apiregistration.k8s.io", Kind: "APIService"}
+}
+
+func (a *customAPIServiceStatusReader) ReadStatus(ctx context.Context, reader engine.ClusterReader, resource object.ObjMetadata) (*event.ResourceStatus, error) {
+	return a.genericStatusReader.ReadStatus(ctx, reader, resource)
+}
+
+func (a *customAPIServiceStatusReader) ReadStatusForObject(ctx context.Context, reader engine.ClusterReader, resource *unstructured.Unstructured) (*event.ResourceStatus, error) {
+	return a.genericStatusReader.ReadStatusForObject(ctx, reader, resource)
+}
+
+// apiServiceConditions considers an APIService current once its Available
+// condition is true.
+func apiServiceConditions(u *unstructured.Unstructured) (*status.Result, error) {
+	objc, err := status.GetObjectWithConditions(u.UnstructuredContent())
+	if err != nil {
+		return nil, err
+	}
+	message := "APIService is not available"
+	for _, c := range objc.Status.Conditions {
+		if c.Type != "Available" {
+			continue
+		}
+		if c.Status == corev1.ConditionTrue {
+			return &status.Result{
+				Status:     status.CurrentStatus,
+				Message:    fmt.Sprintf("APIService %s is available", u.GetName()),
+				Conditions: []status.Condition{},
+			}, nil
+		}
+		if c.Message != "" {
+			message = fmt.Sprintf("APIService is not available: %s", c.Message)
+		}
+	}
+
+	return &status.Result{
+		Status:  status.InProgressStatus,
+		Message: message,
+		Conditions: []status.Condition{
+			{
+				Type:    status.ConditionReconciling,
+				Status:  corev1.ConditionTrue,
+				Reason:  "APIServiceInProgress",
+				Message: message,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreaders
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+)
+
+func TestAPIServiceConditions(t *testing.T) {
+	tests := []struct {
+		name           string
+		conditions     []interface{}
+		expectedStatus status.Status
+	}{
+		{
+			name:           "apiservice without conditions returns in progress",
+			expectedStatus: status.InProgressStatus,
+		},
+		{
+			name: "apiservice that is not available returns in progress",
+			conditions: []interface{}{
+				map[string]interface{}{"type": "Available", "status": string(v1.ConditionFalse), "message": "endpoints not found"},
+			},
+			expectedStatus: status.InProgressStatus,
+		},
+		{
+			name: "available apiservice returns current status",
+			conditions: []interface{}{
+				map[string]interface{}{"type": "Available", "status": string(v1.ConditionTrue)},
+			},
+			expectedStatus: status.CurrentStatus,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			us, err := toUnstructured(t, &metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "apiregistration.k8s.io/v1", Kind: "APIService"},
+				ObjectMeta: metav1.ObjectMeta{Name: "v1beta1.metrics.k8s.io"},
+			})
+			assert.NoError(t, err)
+			if tc.conditions != nil {
+				us.Object["status"] = map[string]interface{}{"conditions": tc.conditions}
+			}
+			result, err := apiServiceConditions(us)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, result.Status)
+		})
+	}
+}
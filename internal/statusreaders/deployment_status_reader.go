@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreaders
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/engine"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/statusreaders"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/object"
+)
+
+// customDeploymentStatusReader treats paused Deployments as current. A paused
+// Deployment does not roll out changes, so waiting for it would only end when
+// the timeout is reached.
+type customDeploymentStatusReader struct {
+	deploymentStatusReader engine.StatusReader
+}
+
+func NewCustomDeploymentStatusReader(mapper meta.RESTMapper) engine.StatusReader {
+	genericStatusReader := statusreaders.NewGenericStatusReader(mapper, status.Compute)
+	replicaSetStatusReader := statusreaders.NewReplicaSetStatusReader(mapper, genericStatusReader)
+	return &customDeploymentStatusReader{
+		deploymentStatusReader: statusreaders.NewDeploymentResourceReader(mapper, replicaSetStatusReader),
+	}
+}
+
+func (d *customDeploymentStatusReader) Supports(gk schema.GroupKind) bool {
+	return gk == appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind()
+}
+
+func (d *customDeploymentStatusReader) ReadStatus(ctx context.Context, reader engine.ClusterReader, resource object.ObjMetadata) (*event.ResourceStatus, error) {
+	rs, err := d.deploymentStatusReader.ReadStatus(ctx, reader, resource)
+	return pausedAsCurrent(rs), err
+}
+
+func (d *customDeploymentStatusReader) ReadStatusForObject(ctx context.Context, reader engine.ClusterReader, resource *unstructured.Unstructured) (*event.ResourceStatus, error) {
+	rs, err := d.deploymentStatusReader.ReadStatusForObject(ctx, reader, resource)
+	return pausedAsCurrent(rs), err
+}
+
+func pausedAsCurrent(rs *event.ResourceStatus) *event.ResourceStatus {
+	if rs == nil || rs.Resource == nil {
+		return rs
+	}
+	paused, _, _ := unstructured.NestedBool(rs.Resource.Object, "spec", "paused")
+	if !paused {
+		return rs
+	}
+	slog.Debug("Deployment is paused", "namespace", rs.Identifier.Namespace, "name", rs.Identifier.Name)
+	rs.Status = status.CurrentStatus
+	rs.Message = fmt.Sprintf("Deployment %s is paused", rs.Identifier.Name)
+	rs.Error = nil
+	return rs
+}
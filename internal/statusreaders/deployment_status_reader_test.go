@@ -0,0 +1,75 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreaders
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/object"
+)
+
+func TestPausedAsCurrent(t *testing.T) {
+	tests := []struct {
+		name            string
+		deployment      *appsv1.Deployment
+		status          status.Status
+		expectedStatus  status.Status
+		expectedMessage string
+	}{
+		{
+			name: "paused deployment in progress returns current status",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "paused"},
+				Spec:       appsv1.DeploymentSpec{Paused: true},
+			},
+			status:          status.InProgressStatus,
+			expectedStatus:  status.CurrentStatus,
+			expectedMessage: "Deployment paused is paused",
+		},
+		{
+			name: "deployment that is not paused keeps its status",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "not-paused"},
+			},
+			status:          status.InProgressStatus,
+			expectedStatus:  status.InProgressStatus,
+			expectedMessage: "in progress",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			us, err := toUnstructured(t, tc.deployment)
+			assert.NoError(t, err)
+			rs := pausedAsCurrent(&event.ResourceStatus{
+				Identifier: object.ObjMetadata{Name: tc.deployment.Name},
+				Status:     tc.status,
+				Resource:   us,
+				Message:    "in progress",
+			})
+			assert.Equal(t, tc.expectedStatus, rs.Status)
+			assert.Equal(t, tc.expectedMessage, rs.Message)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreaders
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/engine"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/statusreaders"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/object"
+)
+
+type customIngressStatusReader struct {
+	genericStatusReader engine.StatusReader
+}
+
+func NewCustomIngressStatusReader(mapper meta.RESTMapper) engine.StatusReader {
+	genericStatusReader := statusreaders.NewGenericStatusReader(mapper, ingressConditions)
+	return &customIngressStatusReader{
+		genericStatusReader: genericStatusReader,
+	}
+}
+
+func (i *customIngressStatusReader) Supports(gk schema.GroupKind) bool {
+	return gk == networkingv1.SchemeGroupVersion.WithKind("Ingress").GroupKind()
+}
+
+func (i *customIngressStatusReader) ReadStatus(ctx context.Context, reader engine.ClusterReader, resource object.ObjMetadata) (*event.ResourceStatus, error) {
+	return i.genericStatusReader.ReadStatus(ctx, reader, resource)
+}
+
+func (i *customIngressStatusReader) ReadStatusForObject(ctx context.Context, reader engine.ClusterReader, resource *unstructured.Unstructured) (*event.ResourceStatus, error) {
+	return i.genericStatusReader.ReadStatusForObject(ctx, reader, resource)
+}
+
+// ingressConditions considers an Ingress current once the ingress controller
+// has populated the load balancer address.
+func ingressConditions(u *unstructured.Unstructured) (*status.Result, error) {
+	ingress, _, err := unstructured.NestedSlice(u.Object, "status", "loadBalancer", "ingress")
+	if err != nil {
+		return nil, err
+	}
+	if len(ingress) > 0 {
+		return &status.Result{
+			Status:     status.CurrentStatus,
+			Message:    fmt.Sprintf("Ingress %s has a load balancer address", u.GetName()),
+			Conditions: []status.Condition{},
+		}, nil
+	}
+
+	message := "Ingress does not have a load balancer address"
+	return &status.Result{
+		Status:  status.InProgressStatus,
+		Message: message,
+		Conditions: []status.Condition{
+			{
+				Type:    status.ConditionReconciling,
+				Status:  corev1.ConditionTrue,
+				Reason:  "IngressInProgress",
+				Message: message,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statusreaders
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+)
+
+func TestIngressConditions(t *testing.T) {
+	tests := []struct {
+		name           string
+		ingress        *networkingv1.Ingress
+		expectedStatus status.Status
+	}{
+		{
+			name: "ingress without load balancer address returns in progress",
+			ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress-no-address"},
+			},
+			expectedStatus: status.InProgressStatus,
+		},
+		{
+			name: "ingress with load balancer address returns current status",
+			ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "ingress-address"},
+				Status: networkingv1.IngressStatus{
+					LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+						Ingress: []networkingv1.IngressLoadBalancerIngress{{IP: "10.0.0.1"}},
+					},
+				},
+			},
+			expectedStatus: status.CurrentStatus,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			us, err := toUnstructured(t, tc.ingress)
+			assert.NoError(t, err)
+			result, err := ingressConditions(us)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, result.Status)
+		})
+	}
+}
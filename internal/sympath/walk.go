@@ -35,11 +35,21 @@ import (
 // output deterministic but means that for very large directories Walk can be
 // inefficient. Walk follows symbolic links.
 func Walk(root string, walkFn filepath.WalkFunc) error {
+	return WalkLinks(root, walkFn, nil)
+}
+
+// LinkFunc is called with each symbolic link found by WalkLinks and the path
+// it resolves to, before the link is followed. Returning an error stops the
+// walk.
+type LinkFunc func(path, resolved string) error
+
+// WalkLinks is like Walk, but calls linkFn for each symbolic link it follows.
+func WalkLinks(root string, walkFn filepath.WalkFunc, linkFn LinkFunc) error {
 	info, err := os.Lstat(root)
 	if err != nil {
 		err = walkFn(root, nil, err)
 	} else {
-		err = symwalk(root, info, walkFn)
+		err = symwalk(root, info, walkFn, linkFn)
 	}
 	if err == filepath.SkipDir {
 		return nil
@@ -64,19 +74,24 @@ func readDirNames(dirname string) ([]string, error) {
 }
 
 // symwalk recursively descends path, calling walkFn.
-func symwalk(path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
+func symwalk(path string, info os.FileInfo, walkFn filepath.WalkFunc, linkFn LinkFunc) error {
 	// Recursively walk symlinked directories.
 	if IsSymlink(info) {
 		resolved, err := filepath.EvalSymlinks(path)
 		if err != nil {
 			return errors.Wrapf(err, "error evaluating symlink %s", path)
 		}
+		if linkFn != nil {
+			if err := linkFn(path, resolved); err != nil {
+				return err
+			}
+		}
 		//This log message is to highlight a symlink that is being used within a chart, symlinks can be used for nefarious reasons.
 		slog.Info("found symbolic link in path. Contents of linked file included and used", "path", path, "resolved", resolved)
 		if info, err = os.Lstat(resolved); err != nil {
 			return err
 		}
-		if err := symwalk(path, info, walkFn); err != nil && err != filepath.SkipDir {
+		if err := symwalk(path, info, walkFn, linkFn); err != nil && err != filepath.SkipDir {
 			return err
 		}
 		return nil
@@ -103,7 +118,7 @@ func symwalk(path string, info os.FileInfo, walkFn filepath.WalkFunc) error {
 				return err
 			}
 		} else {
-			err = symwalk(filename, fileInfo, walkFn)
+			err = symwalk(filename, fileInfo, walkFn, linkFn)
 			if err != nil {
 				if (!fileInfo.IsDir() && !IsSymlink(fileInfo)) || err != filepath.SkipDir {
 					return err
@@ -24,18 +24,25 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	stdtime "time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"helm.sh/helm/v4/internal/tlsutil"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/engine"
+	"helm.sh/helm/v4/pkg/helmpath"
 	"helm.sh/helm/v4/pkg/kube"
 	"helm.sh/helm/v4/pkg/postrender"
 	"helm.sh/helm/v4/pkg/registry"
@@ -43,6 +50,8 @@ import (
 	release "helm.sh/helm/v4/pkg/release/v1"
 	"helm.sh/helm/v4/pkg/storage"
 	"helm.sh/helm/v4/pkg/storage/driver"
+	"helm.sh/helm/v4/pkg/storage/encryption"
+	"helm.sh/helm/v4/pkg/storage/objectstore"
 	"helm.sh/helm/v4/pkg/time"
 )
 
@@ -82,6 +91,119 @@ type Configuration struct {
 
 	// HookOutputFunc called with container name and returns and expects writer that will receive the log output.
 	HookOutputFunc func(namespace, pod, container string) io.Writer
+
+	// CaptureHookLogs keeps the logs of the Job and Pod hooks in the release
+	// record when they succeed. The logs of the failed hooks are always kept.
+	CaptureHookLogs bool
+
+	// WaitForHookDeletion waits for the deleted hook resources, and the pods
+	// of deleted hook Jobs, to be gone before going on, even when the wait
+	// strategy does not wait for deletions. This keeps a hook that is created
+	// again from conflicting with its previous run.
+	WaitForHookDeletion bool
+
+	// InstallSorter orders resources for installation. When it is nil,
+	// releaseutil.InstallSorter is used.
+	InstallSorter releaseutil.KindSorter
+
+	// UninstallSorter orders resources for uninstallation. When it is nil,
+	// the sorter of DeletionOrder is used.
+	UninstallSorter releaseutil.KindSorter
+
+	// DeletionOrder decides the order in which resources are deleted when
+	// UninstallSorter is nil.
+	DeletionOrder DeletionOrder
+
+	// TracerProvider provides the tracer of the spans of the install, upgrade,
+	// rollback and uninstall actions. When it is nil, the global
+	// TracerProvider of OpenTelemetry is used.
+	TracerProvider trace.TracerProvider
+
+	// Observer receives the events of the progress of the install, upgrade,
+	// rollback and uninstall actions. It is optional.
+	Observer Observer
+}
+
+func (cfg *Configuration) installSorter() releaseutil.KindSorter {
+	if cfg.InstallSorter != nil {
+		return cfg.InstallSorter
+	}
+	return releaseutil.InstallSorter
+}
+
+func (cfg *Configuration) uninstallSorter() releaseutil.KindSorter {
+	if cfg.UninstallSorter != nil {
+		return cfg.UninstallSorter
+	}
+	if cfg.DeletionOrder == DeletionOrderKind {
+		return releaseutil.KindUninstallSorter
+	}
+	return releaseutil.UninstallSorter
+}
+
+// DeletionOrder is a policy for the order in which resources are deleted.
+type DeletionOrder string
+
+const (
+	// DeletionOrderReverseInstall deletes resources in the reverse of the
+	// order they are installed in, so that the kinds unknown to Helm, such as
+	// custom resources, are deleted first. It is the default.
+	DeletionOrderReverseInstall DeletionOrder = "reverse-install"
+	// DeletionOrderKind deletes resources in the order of
+	// releaseutil.UninstallOrder, with the kinds unknown to Helm deleted last.
+	DeletionOrderKind DeletionOrder = "kind"
+)
+
+// Validate checks that the policy is known.
+func (o DeletionOrder) Validate() error {
+	switch o {
+	case "", DeletionOrderReverseInstall, DeletionOrderKind:
+		return nil
+	}
+	return errors.Errorf("invalid deletion order %q. Valid inputs are %s and %s", o, DeletionOrderReverseInstall, DeletionOrderKind)
+}
+
+// resourceSorter orders the resources handled by the Kubernetes client with
+// the KindSorter it returns.
+type resourceSorter func() releaseutil.KindSorter
+
+func (s resourceSorter) Less(a, b *resource.Info) bool {
+	return s().Less(resourceHead(a), resourceHead(b))
+}
+
+func resourceHead(info *resource.Info) *releaseutil.SimpleHead {
+	head := &releaseutil.SimpleHead{}
+	if info.Object == nil {
+		return head
+	}
+	head.Version = info.Object.GetObjectKind().GroupVersionKind().GroupVersion().String()
+	head.Kind = info.Object.GetObjectKind().GroupVersionKind().Kind
+	if accessor, err := meta.Accessor(info.Object); err == nil {
+		head.Metadata = &struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		}{Name: accessor.GetName(), Annotations: accessor.GetAnnotations()}
+	}
+	return head
+}
+
+// renderOptions configure the engine that renders the templates of a chart.
+type renderOptions struct {
+	enableDNS      bool
+	collectErrors  bool
+	cacheIncludes  bool
+	parallel       bool
+	lookupBudget   int
+	lookupFixtures []*unstructured.Unstructured
+}
+
+func (o renderOptions) apply(e *engine.Engine) {
+	e.EnableDNS = o.enableDNS
+	e.CollectErrors = o.collectErrors
+	e.CacheIncludes = o.cacheIncludes
+	e.Parallel = o.parallel
+	e.LookupBudget = o.lookupBudget
+	e.LookupFixtures = o.lookupFixtures
 }
 
 // renderResources renders the templates in a chart
@@ -90,7 +212,7 @@ type Configuration struct {
 // TODO: As part of the refactor the duplicate code in cmd/helm/template.go should be removed
 //
 //	This code has to do with writing files to disk.
-func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Values, releaseName, outputDir string, subNotes, useReleaseName, includeCrds bool, pr postrender.PostRenderer, interactWithRemote, enableDNS, hideSecret bool) ([]*release.Hook, *bytes.Buffer, string, error) {
+func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Values, releaseName, outputDir string, subNotes, useReleaseName, includeCrds bool, pr postrender.PostRenderer, interactWithRemote bool, opts renderOptions, hideSecret bool) ([]*release.Hook, *bytes.Buffer, string, error) {
 	hs := []*release.Hook{}
 	b := bytes.NewBuffer(nil)
 
@@ -117,11 +239,11 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 			return hs, b, "", err
 		}
 		e := engine.New(restConfig)
-		e.EnableDNS = enableDNS
+		opts.apply(&e)
 		files, err2 = e.Render(ch, values)
 	} else {
 		var e engine.Engine
-		e.EnableDNS = enableDNS
+		opts.apply(&e)
 		files, err2 = e.Render(ch, values)
 	}
 
@@ -152,8 +274,12 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	// Sort hooks, manifests, and partials. Only hooks and manifests are returned,
 	// as partials are not used after renderer.Render. Empty manifests are also
 	// removed here.
-	hs, manifests, err := releaseutil.SortManifests(files, nil, releaseutil.InstallOrder)
+	hs, manifests, err := releaseutil.SortManifests(files, nil, cfg.installSorter())
 	if err != nil {
+		var parseErr *releaseutil.YAMLParseError
+		if errors.As(err, &parseErr) {
+			locateTemplateLine(ch, parseErr)
+		}
 		// By catching parse errors here, we can prevent bogus releases from going
 		// to Kubernetes.
 		//
@@ -348,14 +474,104 @@ func GetVersionSet(client discovery.ServerResourcesInterface) (chartutil.Version
 
 // recordRelease with an update operation in case reuse has been set.
 func (cfg *Configuration) recordRelease(r *release.Release) {
-	if err := cfg.Releases.Update(r); err != nil {
+	if err := cfg.storeRelease(r); err != nil {
 		slog.Warn("failed to update release", "name", r.Name, "revision", r.Version, slog.Any("error", err))
 	}
 }
 
+// preflightDryRun submits the changes that would be applied to the cluster
+// with server-side dry-run, so that every validation and admission webhook
+// rejection is reported before anything is changed.
+func (cfg *Configuration) preflightDryRun(current, target kube.ResourceList, force bool) error {
+	// TODO Helm 4: Remove this check when DryRunApply is moved from InterfaceDryRunApply to Interface
+	kubeClient, ok := cfg.KubeClient.(kube.InterfaceDryRunApply)
+	if !ok {
+		return errors.New("the kubernetes client does not support server-side dry-run")
+	}
+	if _, err := kubeClient.DryRunApply(current, target, force); err != nil {
+		return errors.Wrap(err, "preflight dry-run failed")
+	}
+	return nil
+}
+
+// dryRunApply submits the changes that would be applied to the cluster with
+// server-side dry-run, and returns the objects the server would store along
+// with the resources it rejects.
+func (cfg *Configuration) dryRunApply(current, target kube.ResourceList, force bool) (*release.DryRun, error) {
+	// TODO Helm 4: Remove this check when DryRunApply is moved from InterfaceDryRunApply to Interface
+	kubeClient, ok := cfg.KubeClient.(kube.InterfaceDryRunApply)
+	if !ok {
+		return nil, errors.New("the kubernetes client does not support server-side dry-run")
+	}
+	diffs, err := kubeClient.DryRunApply(current, target, force)
+	result := &release.DryRun{}
+	for _, diff := range diffs {
+		result.Objects = append(result.Objects, diff.Merged)
+	}
+	if err == nil {
+		return result, nil
+	}
+
+	var aggregate *kube.AggregateError
+	if !errors.As(err, &aggregate) {
+		return nil, errors.Wrap(err, "dry-run apply failed")
+	}
+	for _, e := range aggregate.Errs {
+		var resourceErr *kube.ResourceError
+		if !errors.As(e, &resourceErr) {
+			return nil, errors.Wrap(e, "dry-run apply failed")
+		}
+		info := resourceErr.Info
+		result.Rejections = append(result.Rejections, release.DryRunRejection{
+			Kind:      info.Mapping.GroupVersionKind.Kind,
+			Namespace: info.Namespace,
+			Name:      info.Name,
+			Reason:    resourceErr.Err.Error(),
+		})
+	}
+	return result, nil
+}
+
+// releasePostRenderer binds the release being rendered to pr, for the
+// post-renderers that make use of it.
+func releasePostRenderer(pr postrender.PostRenderer, options chartutil.ReleaseOptions, ch *chart.Chart, dryRun bool) postrender.PostRenderer {
+	if pr == nil {
+		return nil
+	}
+	return postrender.ForRelease(pr, &postrender.Release{
+		Name:      options.Name,
+		Namespace: options.Namespace,
+		Revision:  options.Revision,
+		IsInstall: options.IsInstall,
+		IsUpgrade: options.IsUpgrade,
+		DryRun:    dryRun,
+		Chart:     ch.Metadata,
+	})
+}
+
+// dryRunRejectionsError reports every resource rejected by a server-side dry
+// run, or returns nil if none was.
+func dryRunRejectionsError(result *release.DryRun) error {
+	if result == nil || len(result.Rejections) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "the cluster rejected %d resource(s) in server-side dry-run:", len(result.Rejections))
+	for _, r := range result.Rejections {
+		name := r.Name
+		if r.Namespace != "" {
+			name = r.Namespace + "/" + r.Name
+		}
+		fmt.Fprintf(&sb, "\n  %s %s: %s", r.Kind, name, r.Reason)
+	}
+	return errors.New(sb.String())
+}
+
 // Init initializes the action configuration
 func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namespace, helmDriver string) error {
 	kc := kube.New(getter)
+	kc.CreateSorter = resourceSorter(cfg.installSorter)
+	kc.DeleteSorter = resourceSorter(cfg.uninstallSorter)
 
 	lazyClient := &lazyClient{
 		namespace: namespace,
@@ -386,18 +602,62 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 		d.SetNamespace(namespace)
 		store = storage.Init(d)
 	case "sql":
-		d, err := driver.NewSQL(
+		pool, err := sqlPoolConfigFromEnv()
+		if err != nil {
+			return errors.Wrap(err, "unable to instantiate SQL driver")
+		}
+		d, err := driver.NewSQLWithPool(
 			os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING"),
 			namespace,
+			pool,
 		)
 		if err != nil {
 			return errors.Wrap(err, "unable to instantiate SQL driver")
 		}
 		store = storage.Init(d)
+	case "etcd":
+		d, err := etcdDriverFromEnv(namespace)
+		if err != nil {
+			return errors.Wrap(err, "unable to instantiate etcd driver")
+		}
+		store = storage.Init(d)
+	case "filesystem":
+		dir := os.Getenv("HELM_DRIVER_FILESYSTEM_DIR")
+		if dir == "" {
+			dir = helmpath.DataPath("releases")
+		}
+		d, err := driver.NewFilesystem(dir, namespace)
+		if err != nil {
+			return errors.Wrap(err, "unable to instantiate filesystem driver")
+		}
+		store = storage.Init(d)
+	case "objectstore":
+		objStore, prefix, err := objectstore.Open(os.Getenv("HELM_DRIVER_OBJECT_STORE_URL"))
+		if err != nil {
+			return errors.Wrap(err, "unable to instantiate object storage driver")
+		}
+		store = storage.Init(driver.NewObjectStorage(objStore, prefix, namespace))
 	default:
 		return errors.Errorf("unknown driver %q", helmDriver)
 	}
 
+	// The memory driver does not encode releases, so it has neither
+	// compression nor encryption.
+	if d, ok := store.Driver.(encodingDriver); ok {
+		if c := os.Getenv("HELM_DRIVER_COMPRESSION"); c != "" {
+			if err := d.SetCompression(driver.Compression(c)); err != nil {
+				return errors.Wrap(err, "invalid $HELM_DRIVER_COMPRESSION")
+			}
+		}
+		if u := os.Getenv("HELM_DRIVER_ENCRYPTION_KEY_URL"); u != "" {
+			kek, err := encryption.Open(u)
+			if err != nil {
+				return errors.Wrap(err, "unable to instantiate the key encryption key")
+			}
+			d.SetKeyEncrypter(kek)
+		}
+	}
+
 	cfg.RESTClientGetter = getter
 	cfg.KubeClient = kc
 	cfg.Releases = store
@@ -406,7 +666,81 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 	return nil
 }
 
+// encodingDriver is implemented by the storage drivers encoding releases,
+// which are configured with the compression and the key encryption key of the
+// environment.
+type encodingDriver interface {
+	SetCompression(c driver.Compression) error
+	SetKeyEncrypter(kek driver.KeyEncrypter)
+}
+
+// sqlPoolConfigFromEnv reads the connection pool settings of the SQL driver
+// from the environment.
+func sqlPoolConfigFromEnv() (driver.SQLPoolConfig, error) {
+	var pool driver.SQLPoolConfig
+	for name, p := range map[string]*int{
+		"HELM_DRIVER_SQL_MAX_OPEN_CONNS": &pool.MaxOpenConns,
+		"HELM_DRIVER_SQL_MAX_IDLE_CONNS": &pool.MaxIdleConns,
+	} {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return pool, errors.Errorf("invalid $%s %q: must be a non-negative integer", name, v)
+			}
+			*p = n
+		}
+	}
+	for name, p := range map[string]*stdtime.Duration{
+		"HELM_DRIVER_SQL_CONN_MAX_LIFETIME": &pool.ConnMaxLifetime,
+		"HELM_DRIVER_SQL_CONN_IDLE_TIMEOUT": &pool.ConnMaxIdleTime,
+	} {
+		if v := os.Getenv(name); v != "" {
+			d, err := stdtime.ParseDuration(v)
+			if err != nil || d < 0 {
+				return pool, errors.Errorf("invalid $%s %q: must be a non-negative duration such as 5m", name, v)
+			}
+			*p = d
+		}
+	}
+	return pool, nil
+}
+
+// etcdDriverFromEnv creates the etcd driver of the endpoints, key prefix, TLS
+// files and credentials of the environment.
+func etcdDriverFromEnv(namespace string) (*driver.Etcd, error) {
+	var endpoints []string
+	for _, endpoint := range strings.Split(os.Getenv("HELM_DRIVER_ETCD_ENDPOINTS"), ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	tlsConf, err := tlsutil.NewTLSConfig(
+		tlsutil.WithCertKeyPairFiles(os.Getenv("HELM_DRIVER_ETCD_CERT_FILE"), os.Getenv("HELM_DRIVER_ETCD_KEY_FILE")),
+		tlsutil.WithCAFile(os.Getenv("HELM_DRIVER_ETCD_CA_FILE")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return driver.NewEtcd(driver.EtcdConfig{
+		Endpoints: endpoints,
+		Prefix:    os.Getenv("HELM_DRIVER_ETCD_PREFIX"),
+		TLSConfig: tlsConf,
+		Username:  os.Getenv("HELM_DRIVER_ETCD_USERNAME"),
+		Password:  os.Getenv("HELM_DRIVER_ETCD_PASSWORD"),
+	}, namespace)
+}
+
 // SetHookOutputFunc sets the HookOutputFunc on the Configuration.
 func (cfg *Configuration) SetHookOutputFunc(hookOutputFunc func(_, _, _ string) io.Writer) {
 	cfg.HookOutputFunc = hookOutputFunc
 }
+
+// schemaWarnings turns the values schema violations into warnings of the
+// release.
+func schemaWarnings(violations []chartutil.SchemaViolation) []release.Warning {
+	var warnings []release.Warning
+	for _, v := range violations {
+		warnings = append(warnings, release.Warning{Chart: v.Chart, Path: v.Path, Message: v.Message})
+	}
+	return warnings
+}
@@ -16,13 +16,20 @@ limitations under the License.
 package action
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
+	stdtime "time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
 
 	"helm.sh/helm/v4/internal/logging"
@@ -280,6 +287,8 @@ func namedReleaseStub(name string, status release.Status) *release.Release {
 }
 
 func TestConfiguration_Init(t *testing.T) {
+	t.Setenv("HELM_DRIVER_FILESYSTEM_DIR", t.TempDir())
+
 	tests := []struct {
 		name               string
 		helmDriver         string
@@ -323,6 +332,23 @@ func TestConfiguration_Init(t *testing.T) {
 			expectErr:  true,
 			errMsg:     "unable to instantiate SQL driver",
 		},
+		{
+			name:       "Test etcd driver",
+			helmDriver: "etcd",
+			expectErr:  true,
+			errMsg:     "unable to instantiate etcd driver",
+		},
+		{
+			name:       "Test objectstore driver",
+			helmDriver: "objectstore",
+			expectErr:  true,
+			errMsg:     "unable to instantiate object storage driver",
+		},
+		{
+			name:               "Test filesystem driver",
+			helmDriver:         "filesystem",
+			expectedDriverType: &driver.Filesystem{},
+		},
 		{
 			name:       "Test unknown driver",
 			helmDriver: "someDriver",
@@ -347,6 +373,90 @@ func TestConfiguration_Init(t *testing.T) {
 	}
 }
 
+func TestConfiguration_InitCompression(t *testing.T) {
+	t.Setenv("HELM_DRIVER_COMPRESSION", "lz4")
+
+	cfg := &Configuration{}
+	t.Setenv("HELM_DRIVER_FILESYSTEM_DIR", t.TempDir())
+	err := cfg.Init(nil, "default", "filesystem")
+	assert.ErrorContains(t, err, "invalid $HELM_DRIVER_COMPRESSION")
+
+	// The compression of a configuration does not change that of the others.
+	payload := func(compression string) []byte {
+		t.Helper()
+		dir := t.TempDir()
+		t.Setenv("HELM_DRIVER_FILESYSTEM_DIR", dir)
+		t.Setenv("HELM_DRIVER_COMPRESSION", compression)
+		cfg := &Configuration{}
+		require.NoError(t, cfg.Init(nil, "default", "filesystem"))
+		require.NoError(t, cfg.Releases.Create(releaseStub()))
+
+		files, err := filepath.Glob(filepath.Join(dir, "default", "*.json"))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		data, err := os.ReadFile(files[0])
+		require.NoError(t, err)
+		var record struct{ Release string }
+		require.NoError(t, json.Unmarshal(data, &record))
+		b, err := base64.StdEncoding.DecodeString(record.Release)
+		require.NoError(t, err)
+		return b
+	}
+	assert.Equal(t, byte(0x01), payload("zstd")[0], "expected a zstd payload")
+	assert.Equal(t, byte(0x1f), payload("")[0], "expected a gzip payload")
+}
+
+func TestConfiguration_InitEncryption(t *testing.T) {
+	t.Setenv("HELM_DRIVER_ENCRYPTION_KEY_URL", "local://"+filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("HELM_DRIVER_FILESYSTEM_DIR", t.TempDir())
+
+	cfg := &Configuration{}
+	err := cfg.Init(nil, "default", "filesystem")
+	assert.ErrorContains(t, err, "unable to instantiate the key encryption key")
+
+	// The key encryption key of a configuration is not shared with the others.
+	key := filepath.Join(t.TempDir(), "kek")
+	require.NoError(t, os.WriteFile(key, bytes.Repeat([]byte{0x42}, 32), 0600))
+	t.Setenv("HELM_DRIVER_ENCRYPTION_KEY_URL", "local://"+key)
+	encrypted := &Configuration{}
+	require.NoError(t, encrypted.Init(nil, "default", "filesystem"))
+	rel := releaseStub()
+	require.NoError(t, encrypted.Releases.Create(rel))
+
+	t.Setenv("HELM_DRIVER_ENCRYPTION_KEY_URL", "")
+	plain := &Configuration{}
+	require.NoError(t, plain.Init(nil, "default", "filesystem"))
+	_, err = plain.Releases.Get(rel.Name, rel.Version)
+	assert.ErrorIs(t, err, driver.ErrNoKeyEncrypter)
+	_, err = encrypted.Releases.Get(rel.Name, rel.Version)
+	assert.NoError(t, err)
+}
+
+func TestEtcdDriverFromEnv(t *testing.T) {
+	t.Setenv("HELM_DRIVER_ETCD_ENDPOINTS", "http://etcd-0:2379, http://etcd-1:2379")
+
+	d, err := etcdDriverFromEnv("default")
+	assert.NoError(t, err)
+	assert.Equal(t, driver.EtcdDriverName, d.Name())
+
+	t.Setenv("HELM_DRIVER_ETCD_CA_FILE", "testdata/nonexistent-ca.pem")
+	_, err = etcdDriverFromEnv("default")
+	assert.Error(t, err)
+}
+
+func TestSQLPoolConfigFromEnv(t *testing.T) {
+	t.Setenv("HELM_DRIVER_SQL_MAX_OPEN_CONNS", "20")
+	t.Setenv("HELM_DRIVER_SQL_CONN_MAX_LIFETIME", "30m")
+
+	pool, err := sqlPoolConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, driver.SQLPoolConfig{MaxOpenConns: 20, ConnMaxLifetime: 30 * stdtime.Minute}, pool)
+
+	t.Setenv("HELM_DRIVER_SQL_MAX_IDLE_CONNS", "some")
+	_, err = sqlPoolConfigFromEnv()
+	assert.ErrorContains(t, err, "HELM_DRIVER_SQL_MAX_IDLE_CONNS")
+}
+
 func TestGetVersionSet(t *testing.T) {
 	client := fakeclientset.NewClientset()
 
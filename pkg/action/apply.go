@@ -0,0 +1,134 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+// applyInDependencyOrder creates or updates the target resources in the order
+// declared by kube.DependsOnAnno. Before the resources depending on others
+// are applied, the resources they depend on are waited for until they are
+// ready. Resources of the original list missing from the target are deleted
+// once all target resources are applied.
+//
+// Waiting for dependencies is required for the order to be meaningful, so
+// wait strategies that do not wait for resources are replaced with the
+// status watcher for the dependencies.
+//
+// The resources are applied with Update when opts are given, so that the
+// options also apply to the created resources.
+func (cfg *Configuration) applyInDependencyOrder(original, target kube.ResourceList, force bool, waitStrategy kube.WaitStrategy, timeout time.Duration, opts ...kube.UpdateOption) (*kube.Result, error) {
+	result := &kube.Result{}
+	levels, required, err := kube.DependencyLevels(target)
+	if err != nil {
+		return result, err
+	}
+
+	if waitStrategy != kube.LegacyStrategy {
+		waitStrategy = kube.StatusWatcherStrategy
+	}
+	waiter, err := cfg.KubeClient.GetWaiter(waitStrategy)
+	if err != nil {
+		return result, errors.Wrap(err, "failed to get waiter")
+	}
+
+	for i, level := range levels {
+		slog.Debug("applying resources", "level", i, "resources", len(level))
+		var res *kube.Result
+		if existing := original.Intersect(level); len(existing) == 0 && len(opts) == 0 {
+			res, err = cfg.KubeClient.Create(level)
+		} else {
+			res, err = cfg.updateResources(existing, level, force, opts...)
+		}
+		mergeResults(result, res)
+		if err != nil {
+			return result, err
+		}
+
+		if dependencies := level.Intersect(required); len(dependencies) > 0 && i < len(levels)-1 {
+			slog.Debug("waiting for dependencies to be ready", "level", i, "resources", len(dependencies))
+			if err := waiter.Wait(dependencies, timeout); err != nil {
+				return result, errors.Wrap(err, "dependencies did not become ready")
+			}
+		}
+	}
+
+	if removed := original.Difference(target); len(removed) > 0 {
+		res, err := cfg.updateResources(removed, kube.ResourceList{}, force, opts...)
+		mergeResults(result, res)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// updateResources updates the resources with the Update options given. The
+// options are left out for clients that do not support them, which only
+// changes how the progress of the update is reported: options changing how
+// the resources are applied are checked for support when they are built.
+func (cfg *Configuration) updateResources(original, target kube.ResourceList, force bool, opts ...kube.UpdateOption) (*kube.Result, error) {
+	// TODO Helm 4: Remove this check when UpdateWithOptions is moved from InterfaceUpdateOptions to Interface
+	if kubeClient, ok := cfg.KubeClient.(kube.InterfaceUpdateOptions); ok {
+		return kubeClient.UpdateWithOptions(original, target, force, opts...)
+	}
+	return cfg.KubeClient.Update(original, target, force)
+}
+
+// serverSideApplyOptions returns the Update options for server-side apply, or
+// none when it is disabled. Replacing resources with force cannot be combined
+// with server-side apply, and conflicts can only be forced when it is enabled.
+func (cfg *Configuration) serverSideApplyOptions(serverSideApply, forceConflicts, force bool) ([]kube.UpdateOption, error) {
+	if !serverSideApply {
+		if forceConflicts {
+			return nil, errors.New("--force-conflicts only works with --server-side")
+		}
+		return nil, nil
+	}
+	if force {
+		return nil, errors.New("--force cannot be used with --server-side")
+	}
+	if err := cfg.checkServerSideApply(); err != nil {
+		return nil, err
+	}
+	return []kube.UpdateOption{kube.ServerSideApply(true), kube.ForceConflicts(forceConflicts)}, nil
+}
+
+// checkServerSideApply returns an error when the kubernetes client cannot
+// apply resources with server-side apply.
+func (cfg *Configuration) checkServerSideApply() error {
+	// TODO Helm 4: Remove this check when UpdateWithOptions is moved from InterfaceUpdateOptions to Interface
+	if _, ok := cfg.KubeClient.(kube.InterfaceUpdateOptions); !ok {
+		return errors.New("the kubernetes client does not support server-side apply")
+	}
+	return nil
+}
+
+func mergeResults(into, from *kube.Result) {
+	if from == nil {
+		return
+	}
+	into.Created = append(into.Created, from.Created...)
+	into.Updated = append(into.Updated, from.Updated...)
+	into.Deleted = append(into.Deleted, from.Deleted...)
+}
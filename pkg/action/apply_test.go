@@ -0,0 +1,155 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v4/pkg/kube"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+)
+
+// recordingKubeClient records the resources it creates, updates and waits for.
+type recordingKubeClient struct {
+	kubefake.PrintingKubeClient
+	calls []string
+}
+
+func (r *recordingKubeClient) record(op string, resources kube.ResourceList) {
+	var names []string
+	for _, info := range resources {
+		names = append(names, info.Name)
+	}
+	r.calls = append(r.calls, op+" "+strings.Join(names, ","))
+}
+
+func (r *recordingKubeClient) Create(resources kube.ResourceList) (*kube.Result, error) {
+	r.record("create", resources)
+	return &kube.Result{Created: resources}, nil
+}
+
+func (r *recordingKubeClient) Update(original, target kube.ResourceList, force bool) (*kube.Result, error) {
+	return r.UpdateWithOptions(original, target, force)
+}
+
+func (r *recordingKubeClient) UpdateWithOptions(original, target kube.ResourceList, _ bool, _ ...kube.UpdateOption) (*kube.Result, error) {
+	r.record("update", target)
+	return &kube.Result{Updated: target, Deleted: original.Difference(target)}, nil
+}
+
+func (r *recordingKubeClient) GetWaiter(_ kube.WaitStrategy) (kube.Waiter, error) {
+	return &recordingWaiter{PrintingKubeWaiter: &kubefake.PrintingKubeWaiter{Out: io.Discard}, client: r}, nil
+}
+
+type recordingWaiter struct {
+	*kubefake.PrintingKubeWaiter
+	client *recordingKubeClient
+}
+
+func (w *recordingWaiter) Wait(resources kube.ResourceList, _ time.Duration) error {
+	w.client.record("wait", resources)
+	return nil
+}
+
+func dependencyInfo(kind, name, dependsOn string) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetName(name)
+	if dependsOn != "" {
+		obj.SetAnnotations(map[string]string{kube.DependsOnAnno: dependsOn})
+	}
+	return &resource.Info{
+		Name:      name,
+		Namespace: "default",
+		Object:    obj,
+		Mapping:   &meta.RESTMapping{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: kind}},
+	}
+}
+
+func TestApplyInDependencyOrder(t *testing.T) {
+	client := &recordingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+	cfg := actionConfigFixture(t)
+	cfg.KubeClient = client
+
+	operator := dependencyInfo("Deployment", "operator", "")
+	unrelated := dependencyInfo("ConfigMap", "unrelated", "")
+	widget := dependencyInfo("Widget", "widget", "Deployment/operator")
+	removed := dependencyInfo("ConfigMap", "removed", "")
+
+	original := kube.ResourceList{operator, removed}
+	target := kube.ResourceList{widget, operator, unrelated}
+	result, err := cfg.applyInDependencyOrder(original, target, false, kube.HookOnlyStrategy, time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"update operator,unrelated",
+		"wait operator",
+		"create widget",
+		"update ",
+	}, client.calls)
+	assert.Len(t, result.Created, 1)
+	assert.Len(t, result.Updated, 2)
+	assert.Len(t, result.Deleted, 1)
+}
+
+func TestApplyInDependencyOrderCycle(t *testing.T) {
+	client := &recordingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+	cfg := actionConfigFixture(t)
+	cfg.KubeClient = client
+
+	target := kube.ResourceList{
+		dependencyInfo("ConfigMap", "a", "ConfigMap/b"),
+		dependencyInfo("ConfigMap", "b", "ConfigMap/a"),
+	}
+	_, err := cfg.applyInDependencyOrder(nil, target, false, kube.HookOnlyStrategy, time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle")
+	assert.Empty(t, client.calls)
+}
+
+func TestUpdateResourcesWithoutOptions(t *testing.T) {
+	client := &recordingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+	cfg := actionConfigFixture(t)
+	// Embedding the Interface hides the optional interfaces of the client.
+	cfg.KubeClient = struct{ kube.Interface }{client}
+
+	var applied []string
+	target := kube.ResourceList{dependencyInfo("ConfigMap", "a", "")}
+	_, err := cfg.updateResources(nil, target, false, kube.OnApplied(func(info *resource.Info) {
+		applied = append(applied, info.Name)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"update a"}, client.calls)
+	assert.Empty(t, applied)
+
+	_, err = cfg.serverSideApplyOptions(true, false, false)
+	assert.EqualError(t, err, "the kubernetes client does not support server-side apply")
+	opts, err := cfg.serverSideApplyOptions(false, false, true)
+	require.NoError(t, err)
+	assert.Empty(t, opts)
+}
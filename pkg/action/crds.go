@@ -0,0 +1,246 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+// CRDUpgradePolicy decides what an upgrade does with the CRDs in the crds/
+// directory of the chart.
+type CRDUpgradePolicy string
+
+const (
+	// CRDSkip leaves the CRDs alone. It is the default.
+	CRDSkip CRDUpgradePolicy = ""
+	// CRDCreateOnly creates the CRDs missing from the cluster, leaving the
+	// existing ones unchanged.
+	CRDCreateOnly CRDUpgradePolicy = "create-only"
+	// CRDUpgrade creates the missing CRDs and updates the existing ones with
+	// server-side apply. Changes that would make stored objects unreadable or
+	// drop their fields are refused unless the upgrade is forced.
+	CRDUpgrade CRDUpgradePolicy = "upgrade"
+	// CRDFailOnChange creates the missing CRDs, and fails the upgrade when
+	// an existing CRD differs from the chart.
+	CRDFailOnChange CRDUpgradePolicy = "fail-on-change"
+)
+
+// upgradeCRDs handles the CRDs of the chart according to the CRD upgrade
+// policy, before the resources that may depend on them are built.
+func (u *Upgrade) upgradeCRDs(crds []chart.CRD) error {
+	var applied kube.ResourceList
+	for _, obj := range crds {
+		res, err := u.cfg.KubeClient.Build(bytes.NewBuffer(obj.File.Data), false)
+		if err != nil {
+			return errors.Wrapf(err, "failed to upgrade CRD %s", obj.Name)
+		}
+		for _, info := range res {
+			live, err := resource.NewHelper(info.Client, info.Mapping).Get(info.Namespace, info.Name)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "could not get information about CRD %s", info.Name)
+			}
+			if err == nil {
+				if u.CRDUpgradePolicy == CRDCreateOnly {
+					slog.Debug("CRD is already present. Skipping", "crd", info.Name)
+					continue
+				}
+				problems, err := crdChanges(live, info.Object, u.CRDUpgradePolicy)
+				if err != nil {
+					return errors.Wrapf(err, "failed to compare CRD %s", info.Name)
+				}
+				if len(problems) == 0 {
+					continue
+				}
+				if u.CRDUpgradePolicy == CRDFailOnChange {
+					return errors.Errorf("CRD %s differs from the chart:\n%s", info.Name, strings.Join(problems, "\n"))
+				}
+				if !u.Force {
+					return errors.Errorf("refusing to upgrade CRD %s with destructive changes, use --force to upgrade it anyway:\n%s", info.Name, strings.Join(problems, "\n"))
+				}
+				slog.Warn("upgrading CRD with destructive changes", "crd", info.Name, "changes", problems)
+			}
+			applied = append(applied, info)
+		}
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	if err := u.cfg.checkServerSideApply(); err != nil {
+		return err
+	}
+	slog.Debug("applying CRDs", "count", len(applied))
+	if _, err := u.cfg.updateResources(kube.ResourceList{}, applied, false, kube.ServerSideApply(true), kube.ForceConflicts(true)); err != nil {
+		return errors.Wrap(err, "failed to upgrade CRDs")
+	}
+	return u.cfg.waitForCRDs(applied, u.WaitStrategy)
+}
+
+// crdChanges returns the changes from the live CRD to the desired one that the
+// policy does not allow: any change of the chart with CRDFailOnChange, and the
+// destructive changes with CRDUpgrade.
+func crdChanges(liveObj, desiredObj runtime.Object, policy CRDUpgradePolicy) ([]string, error) {
+	live, err := toCRD(liveObj)
+	if err != nil {
+		return nil, err
+	}
+	desired, err := toCRD(desiredObj)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == CRDFailOnChange {
+		liveSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&live.Spec)
+		if err != nil {
+			return nil, err
+		}
+		desiredSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&desired.Spec)
+		if err != nil {
+			return nil, err
+		}
+		// Fields only set on the live CRD are defaults filled in by the API
+		// server, not changes.
+		var changes []string
+		for _, change := range diffFields("spec", liveSpec, desiredSpec) {
+			if change.New != nil {
+				changes = append(changes, fmt.Sprintf("%s changes from %v to %v", change.Path, change.Old, change.New))
+			}
+		}
+		return changes, nil
+	}
+
+	var problems []string
+	if live.Spec.Scope != desired.Spec.Scope {
+		problems = append(problems, fmt.Sprintf("the scope changes from %s to %s", live.Spec.Scope, desired.Spec.Scope))
+	}
+	for _, stored := range live.Status.StoredVersions {
+		if !slices.ContainsFunc(desired.Spec.Versions, func(v apiextensionsv1.CustomResourceDefinitionVersion) bool { return v.Name == stored }) {
+			problems = append(problems, fmt.Sprintf("version %s is removed while objects are still stored in it", stored))
+		}
+	}
+	for _, liveVersion := range live.Spec.Versions {
+		idx := slices.IndexFunc(desired.Spec.Versions, func(v apiextensionsv1.CustomResourceDefinitionVersion) bool { return v.Name == liveVersion.Name })
+		if idx < 0 || liveVersion.Schema == nil || desired.Spec.Versions[idx].Schema == nil {
+			continue
+		}
+		for _, field := range removedSchemaFields("", liveVersion.Schema.OpenAPIV3Schema, desired.Spec.Versions[idx].Schema.OpenAPIV3Schema) {
+			problems = append(problems, fmt.Sprintf("field %s of version %s is removed, and would be pruned from stored objects", field, liveVersion.Name))
+		}
+	}
+	return problems, nil
+}
+
+// removedSchemaFields returns the paths of the properties of the live schema
+// missing from the desired one. Schemas preserving unknown fields do not prune
+// them, so their removed properties are not reported.
+func removedSchemaFields(path string, live, desired *apiextensionsv1.JSONSchemaProps) []string {
+	if live == nil || desired == nil {
+		return nil
+	}
+	if desired.XPreserveUnknownFields != nil && *desired.XPreserveUnknownFields {
+		return nil
+	}
+	var removed []string
+	names := make([]string, 0, len(live.Properties))
+	for name := range live.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		liveProp := live.Properties[name]
+		desiredProp, ok := desired.Properties[name]
+		if !ok {
+			removed = append(removed, fieldPath(path, name))
+			continue
+		}
+		removed = append(removed, removedSchemaFields(fieldPath(path, name), &liveProp, &desiredProp)...)
+	}
+	if live.Items != nil && live.Items.Schema != nil && desired.Items != nil {
+		removed = append(removed, removedSchemaFields(path+"[]", live.Items.Schema, desired.Items.Schema)...)
+	}
+	return removed
+}
+
+func toCRD(obj runtime.Object) (*apiextensionsv1.CustomResourceDefinition, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, crd); err != nil {
+		return nil, err
+	}
+	return crd, nil
+}
+
+// waitForCRDs waits for the created or updated CRDs to be established, and
+// resets the cached discovery information so that their kinds are recognized.
+func (cfg *Configuration) waitForCRDs(crds kube.ResourceList, waitStrategy kube.WaitStrategy) error {
+	waiter, err := cfg.KubeClient.GetWaiter(waitStrategy)
+	if err != nil {
+		return errors.Wrapf(err, "unable to get waiter")
+	}
+	// Give time for the CRD to be recognized.
+	if err := waiter.Wait(crds, 60*time.Second); err != nil {
+		return err
+	}
+
+	// If we have already gathered the capabilities, we need to invalidate
+	// the cache so that the new CRDs are recognized. This should only be
+	// the case when an action configuration is reused for multiple actions,
+	// as otherwise it is later loaded by ourselves when getCapabilities
+	// is called later on in the installation process.
+	if cfg.Capabilities != nil {
+		discoveryClient, err := cfg.RESTClientGetter.ToDiscoveryClient()
+		if err != nil {
+			return err
+		}
+
+		slog.Debug("clearing discovery cache")
+		discoveryClient.Invalidate()
+
+		_, _ = discoveryClient.ServerGroups()
+	}
+
+	// Invalidate the REST mapper, since it will not have the new CRDs
+	// present.
+	restMapper, err := cfg.RESTClientGetter.ToRESTMapper()
+	if err != nil {
+		return err
+	}
+	if resettable, ok := restMapper.(meta.ResettableRESTMapper); ok {
+		slog.Debug("clearing REST mapper cache")
+		resettable.Reset()
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const liveCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  scope: Namespaced
+  names:
+    kind: Widget
+    plural: widgets
+  conversion:
+    strategy: None
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: false
+    schema:
+      openAPIV3Schema:
+        type: object
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+              colour:
+                type: string
+              ports:
+                type: array
+                items:
+                  type: object
+                  properties:
+                    name:
+                      type: string
+                    port:
+                      type: integer
+status:
+  storedVersions:
+  - v1alpha1
+  - v1
+`
+
+func crdObject(t *testing.T, manifest string, edit func(obj map[string]interface{})) *unstructured.Unstructured {
+	t.Helper()
+	obj := &unstructured.Unstructured{}
+	require.NoError(t, yaml.Unmarshal([]byte(manifest), &obj.Object))
+	if edit != nil {
+		edit(obj.Object)
+	}
+	return obj
+}
+
+func TestCRDChanges(t *testing.T) {
+	live := crdObject(t, liveCRD, nil)
+
+	// The desired CRD lacks the defaults and status of the live one.
+	unchanged := crdObject(t, liveCRD, func(obj map[string]interface{}) {
+		delete(obj, "status")
+		unstructured.RemoveNestedField(obj, "spec", "conversion")
+	})
+	for _, policy := range []CRDUpgradePolicy{CRDUpgrade, CRDFailOnChange} {
+		problems, err := crdChanges(live, unchanged, policy)
+		require.NoError(t, err)
+		assert.Empty(t, problems, "policy %s", policy)
+	}
+
+	// Adding a field is not destructive, but is a change.
+	added := crdObject(t, liveCRD, func(obj map[string]interface{}) {
+		versions, _, _ := unstructured.NestedSlice(obj, "spec", "versions")
+		v1 := versions[1].(map[string]interface{})
+		require.NoError(t, unstructured.SetNestedField(v1, map[string]interface{}{"type": "string"}, "schema", "openAPIV3Schema", "properties", "spec", "properties", "shape"))
+		require.NoError(t, unstructured.SetNestedSlice(obj, versions, "spec", "versions"))
+	})
+	problems, err := crdChanges(live, added, CRDUpgrade)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+	problems, err = crdChanges(live, added, CRDFailOnChange)
+	require.NoError(t, err)
+	assert.Len(t, problems, 1)
+
+	destructive := crdObject(t, liveCRD, func(obj map[string]interface{}) {
+		require.NoError(t, unstructured.SetNestedField(obj, "Cluster", "spec", "scope"))
+		versions, _, _ := unstructured.NestedSlice(obj, "spec", "versions")
+		v1 := versions[1].(map[string]interface{})
+		unstructured.RemoveNestedField(v1, "schema", "openAPIV3Schema", "properties", "spec", "properties", "colour")
+		unstructured.RemoveNestedField(v1, "schema", "openAPIV3Schema", "properties", "spec", "properties", "ports", "items", "properties", "port")
+		require.NoError(t, unstructured.SetNestedSlice(obj, versions[1:], "spec", "versions"))
+	})
+	problems, err = crdChanges(live, destructive, CRDUpgrade)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"the scope changes from Namespaced to Cluster",
+		"version v1alpha1 is removed while objects are still stored in it",
+		"field spec.colour of version v1 is removed, and would be pruned from stored objects",
+		"field spec.ports[].port of version v1 is removed, and would be pruned from stored objects",
+	}, problems)
+}
+
+func TestUpgradeRelease_InvalidCRDUpgradePolicy(t *testing.T) {
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	require.NoError(t, upAction.cfg.Releases.Create(rel))
+
+	upAction.CRDUpgradePolicy = "replace"
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	assert.ErrorContains(t, err, `invalid CRD upgrade policy "replace"`)
+}
@@ -0,0 +1,326 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+// ChangeAction is the action an upgrade takes on a resource.
+type ChangeAction string
+
+const (
+	// ChangeCreate is the creation of a resource missing from the cluster.
+	ChangeCreate ChangeAction = "create"
+	// ChangeUpdate is the update of an existing resource.
+	ChangeUpdate ChangeAction = "update"
+	// ChangeDelete is the deletion of a resource removed from the chart.
+	ChangeDelete ChangeAction = "delete"
+)
+
+// sensitiveValue replaces the values of the fields of Secrets in changesets.
+const sensitiveValue = "(sensitive value)"
+
+// FieldChange is the change of a single field of a resource.
+type FieldChange struct {
+	// Path is the path of the field, such as
+	// spec.template.spec.containers[0].image. Keys that are not plain
+	// identifiers are quoted, as in metadata.labels["app.kubernetes.io/name"].
+	Path string `json:"path"`
+	// Old is the value of the field before the change. It is nil when the
+	// field is added.
+	Old interface{} `json:"old,omitempty"`
+	// New is the value of the field after the change. It is nil when the
+	// field is removed.
+	New interface{} `json:"new,omitempty"`
+}
+
+// ResourceChange is the change an upgrade makes to a resource.
+type ResourceChange struct {
+	Action     ChangeAction `json:"action"`
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Namespace  string       `json:"namespace,omitempty"`
+	Name       string       `json:"name"`
+	// ManifestChanges are the changes of the fields of the resource between
+	// the manifest of the deployed release and the upgraded one.
+	ManifestChanges []FieldChange `json:"manifestChanges,omitempty"`
+	// LiveChanges are the changes of the fields of the live object of the
+	// resource, as reported by a server-side dry-run of the upgrade. They also
+	// reveal the changes made to the resource outside of Helm that the
+	// upgrade reverts.
+	LiveChanges []FieldChange `json:"liveChanges,omitempty"`
+}
+
+// Changeset is the set of changes an upgrade makes to the resources of a
+// release. Resources that are left unchanged are not part of it.
+type Changeset struct {
+	Release   string `json:"release"`
+	Namespace string `json:"namespace"`
+	// Revision is the revision the upgrade creates.
+	Revision int              `json:"revision"`
+	Changes  []ResourceChange `json:"changes"`
+}
+
+// DryRunDiff renders the chart as Run would, and returns the changes the
+// upgrade would make without making any, by comparing the rendered resources
+// against the manifest of the deployed release and, with server-side dry-run,
+// against their live objects. Hooks are not part of the changeset.
+//
+// When the Kubernetes client does not support server-side dry-run diffs, the
+// resources of the deployed release are assumed to exist and only their
+// manifest changes are reported.
+func (u *Upgrade) DryRunDiff(name string, chart *chart.Chart, vals map[string]interface{}) (*Changeset, error) {
+	if err := u.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return nil, errors.Errorf("release name is invalid: %s", name)
+	}
+
+	slog.Debug("preparing upgrade diff", "name", name)
+	currentRelease, upgradedRelease, err := u.prepareUpgrade(context.Background(), name, chart, vals)
+	if err != nil {
+		return nil, err
+	}
+	current, target, err := u.buildResources(currentRelease, upgradedRelease)
+	if err != nil {
+		return nil, err
+	}
+	toBeAdopted, err := u.resourcesToAdopt(current, target, upgradedRelease)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to continue with update")
+	}
+
+	changeset := &Changeset{
+		Release:   upgradedRelease.Name,
+		Namespace: upgradedRelease.Namespace,
+		Revision:  upgradedRelease.Version,
+	}
+
+	var live map[string]kube.ResourceDiff
+	if diffClient, ok := u.cfg.KubeClient.(kube.InterfaceDryRunApply); ok {
+		original := append(append(kube.ResourceList{}, current...), toBeAdopted...)
+		diffs, err := diffClient.DryRunApply(original, target, u.Force)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to diff against the live resources")
+		}
+		live = make(map[string]kube.ResourceDiff, len(diffs))
+		for _, diff := range diffs {
+			live[objectKey(diff.Info)] = diff
+		}
+	}
+
+	for _, info := range target {
+		change := newResourceChange(info, ChangeUpdate)
+		adopted := toBeAdopted.Get(info) != nil
+		if original := current.Get(info); original != nil {
+			// The stored manifest lacks the ownership metadata Helm sets on
+			// the rendered resources, which is not a change.
+			originalObj := original.Object.DeepCopyObject()
+			if err := mergeLabels(originalObj, map[string]string{appManagedByLabel: appManagedByHelm}); err != nil {
+				return nil, err
+			}
+			if err := mergeAnnotations(originalObj, map[string]string{
+				helmReleaseNameAnnotation:      upgradedRelease.Name,
+				helmReleaseNamespaceAnnotation: upgradedRelease.Namespace,
+			}); err != nil {
+				return nil, err
+			}
+			if change.ManifestChanges, err = diffObjects(originalObj, info.Object); err != nil {
+				return nil, err
+			}
+		} else if live == nil && !adopted {
+			change.Action = ChangeCreate
+		}
+
+		if diff, ok := live[objectKey(info)]; ok {
+			if diff.Live == nil {
+				change.Action = ChangeCreate
+			} else if change.LiveChanges, err = diffObjects(diff.Live, diff.Merged); err != nil {
+				return nil, err
+			}
+		}
+
+		if change.Action == ChangeUpdate && !adopted && len(change.ManifestChanges) == 0 && len(change.LiveChanges) == 0 {
+			continue
+		}
+		maskSensitiveChanges(&change)
+		changeset.Changes = append(changeset.Changes, change)
+	}
+
+	for _, info := range current.Difference(target) {
+		if hasKeepPolicy(info.Object) {
+			continue
+		}
+		changeset.Changes = append(changeset.Changes, newResourceChange(info, ChangeDelete))
+	}
+	return changeset, nil
+}
+
+func newResourceChange(info *resource.Info, action ChangeAction) ResourceChange {
+	apiVersion, kind := info.Mapping.GroupVersionKind.ToAPIVersionAndKind()
+	return ResourceChange{
+		Action:     action,
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Namespace:  info.Namespace,
+		Name:       info.Name,
+	}
+}
+
+// hasKeepPolicy reports whether an object is annotated to be kept when it is
+// removed from the release.
+func hasKeepPolicy(obj runtime.Object) bool {
+	annotations, err := accessor.Annotations(obj)
+	if err != nil {
+		return false
+	}
+	return strings.ToLower(strings.TrimSpace(annotations[kube.ResourcePolicyAnno])) == kube.KeepPolicy
+}
+
+// maskSensitiveChanges hides the values of the data of Secrets.
+func maskSensitiveChanges(change *ResourceChange) {
+	if change.Kind != "Secret" {
+		return
+	}
+	for _, changes := range [][]FieldChange{change.ManifestChanges, change.LiveChanges} {
+		for i := range changes {
+			if !strings.HasPrefix(changes[i].Path, "data") && !strings.HasPrefix(changes[i].Path, "stringData") {
+				continue
+			}
+			if changes[i].Old != nil {
+				changes[i].Old = sensitiveValue
+			}
+			if changes[i].New != nil {
+				changes[i].New = sensitiveValue
+			}
+		}
+	}
+}
+
+// ignoredDiffPaths are the fields maintained by the API server, which are not
+// part of the changes made to resources.
+var ignoredDiffPaths = []string{
+	"metadata.managedFields",
+	"metadata.resourceVersion",
+	"metadata.generation",
+	"status",
+}
+
+// diffObjects returns the changes of the fields of an object.
+func diffObjects(oldObj, newObj runtime.Object) ([]FieldChange, error) {
+	oldContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(oldObj)
+	if err != nil {
+		return nil, err
+	}
+	newContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(newObj)
+	if err != nil {
+		return nil, err
+	}
+	var changes []FieldChange
+	for _, change := range diffFields("", oldContent, newContent) {
+		if !isIgnoredDiffPath(change.Path) {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func isIgnoredDiffPath(path string) bool {
+	for _, ignored := range ignoredDiffPaths {
+		if path == ignored || strings.HasPrefix(path, ignored+".") || strings.HasPrefix(path, ignored+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// diffFields returns the changes between two values decoded from JSON,
+// descending into objects and lists.
+func diffFields(path string, oldValue, newValue interface{}) []FieldChange {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if (oldIsMap || oldValue == nil) && (newIsMap || newValue == nil) && (oldIsMap || newIsMap) {
+		keys := make(map[string]bool, len(oldMap)+len(newMap))
+		for k := range oldMap {
+			keys[k] = true
+		}
+		for k := range newMap {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		var changes []FieldChange
+		for _, k := range sorted {
+			changes = append(changes, diffFields(fieldPath(path, k), oldMap[k], newMap[k])...)
+		}
+		return changes
+	}
+
+	oldList, oldIsList := oldValue.([]interface{})
+	newList, newIsList := newValue.([]interface{})
+	if oldIsList && newIsList {
+		var changes []FieldChange
+		for i := 0; i < len(oldList) || i < len(newList); i++ {
+			var oldItem, newItem interface{}
+			if i < len(oldList) {
+				oldItem = oldList[i]
+			}
+			if i < len(newList) {
+				newItem = newList[i]
+			}
+			changes = append(changes, diffFields(fmt.Sprintf("%s[%d]", path, i), oldItem, newItem)...)
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(oldValue, newValue) {
+		return nil
+	}
+	return []FieldChange{{Path: path, Old: oldValue, New: newValue}}
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func fieldPath(path, key string) string {
+	if !identifierPattern.MatchString(key) {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
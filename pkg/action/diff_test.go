@@ -0,0 +1,249 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	releaseutil "helm.sh/helm/v4/pkg/release/util"
+)
+
+// diffKubeClient builds the resources of manifests, and diffs them against
+// the live objects it is given.
+type diffKubeClient struct {
+	kubefake.PrintingKubeClient
+	live map[string]runtime.Object
+}
+
+func (c *diffKubeClient) Build(r io.Reader, _ bool) (kube.ResourceList, error) {
+	manifest, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var resources kube.ResourceList
+	docs := releaseutil.SplitManifests(string(manifest))
+	keys := make([]string, 0, len(docs))
+	for k := range docs {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+	for _, k := range keys {
+		doc := docs[k]
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		resources = append(resources, &resource.Info{
+			Name:      obj.GetName(),
+			Namespace: "spaced",
+			Object:    obj,
+			Mapping:   &meta.RESTMapping{GroupVersionKind: obj.GroupVersionKind(), Scope: meta.RESTScopeNamespace},
+		})
+	}
+	return resources, nil
+}
+
+func (c *diffKubeClient) DryRunApply(_, target kube.ResourceList, _ bool) ([]kube.ResourceDiff, error) {
+	var diffs []kube.ResourceDiff
+	for _, info := range target {
+		diffs = append(diffs, kube.ResourceDiff{Info: info, Live: c.live[info.Name], Merged: info.Object})
+	}
+	return diffs, nil
+}
+
+const diffDeployedManifest = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  colour: blue
+  size: small
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  key: value
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  password: hunter2
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: removed
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kept
+  annotations:
+    helm.sh/resource-policy: keep
+`
+
+const diffUpgradedManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  colour: green
+  size: small
+  shape: round
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  key: value
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  password: correct-horse
+`
+
+func TestUpgradeRelease_DryRunDiff(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	client := &diffKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+	upAction.cfg.KubeClient = client
+
+	rel := releaseStub()
+	rel.Name = "diffed"
+	rel.Namespace = "spaced"
+	rel.Manifest = diffDeployedManifest
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	ch := buildChartWithTemplates([]*chart.File{{Name: "templates/resources.yaml", Data: []byte(diffUpgradedManifest)}})
+
+	// Without live objects, every resource is created.
+	changeset, err := upAction.DryRunDiff(rel.Name, ch, map[string]interface{}{})
+	req.NoError(err)
+	is.Equal("diffed", changeset.Release)
+	is.Equal(2, changeset.Revision)
+	actions := map[string]ChangeAction{}
+	for _, change := range changeset.Changes {
+		actions[change.Name] = change.Action
+	}
+	is.Equal(map[string]ChangeAction{
+		"settings":    ChangeCreate,
+		"unchanged":   ChangeCreate,
+		"credentials": ChangeCreate,
+		"removed":     ChangeDelete,
+	}, actions)
+
+	// The live objects match the upgraded resources, except for an edit of
+	// the settings made outside of Helm.
+	target, err := client.Build(strings.NewReader(diffUpgradedManifest), false)
+	req.NoError(err)
+	client.live = map[string]runtime.Object{}
+	for _, info := range target {
+		req.NoError(setMetadataVisitor(rel.Name, rel.Namespace, true)(info, nil))
+		client.live[info.Name] = info.Object
+	}
+	settings := client.live["settings"].(*unstructured.Unstructured)
+	req.NoError(unstructured.SetNestedField(settings.Object, "square", "data", "shape"))
+	req.NoError(unstructured.SetNestedField(settings.Object, "42", "metadata", "resourceVersion"))
+
+	changeset, err = upAction.DryRunDiff(rel.Name, ch, map[string]interface{}{})
+	req.NoError(err)
+	req.Len(changeset.Changes, 3)
+	changes := map[string]ResourceChange{}
+	for _, change := range changeset.Changes {
+		changes[change.Name] = change
+	}
+
+	settingsChange := changes["settings"]
+	is.Equal(ChangeUpdate, settingsChange.Action)
+	is.Equal("ConfigMap", settingsChange.Kind)
+	is.Equal("v1", settingsChange.APIVersion)
+	is.Equal("spaced", settingsChange.Namespace)
+	is.Equal([]FieldChange{
+		{Path: "data.colour", Old: "blue", New: "green"},
+		{Path: "data.shape", New: "round"},
+	}, settingsChange.ManifestChanges)
+	is.Equal([]FieldChange{
+		{Path: "data.shape", Old: "square", New: "round"},
+	}, settingsChange.LiveChanges)
+
+	is.Equal(ChangeUpdate, changes["credentials"].Action)
+	is.Equal([]FieldChange{
+		{Path: "stringData.password", Old: sensitiveValue, New: sensitiveValue},
+	}, changes["credentials"].ManifestChanges)
+	is.Empty(changes["credentials"].LiveChanges)
+
+	is.Equal(ChangeDelete, changes["removed"].Action)
+}
+
+func TestDiffFields(t *testing.T) {
+	old := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":          map[string]interface{}{"app.kubernetes.io/name": "web"},
+			"resourceVersion": "1",
+		},
+		"spec": map[string]interface{}{
+			"ports": []interface{}{int64(80), int64(443)},
+		},
+	}
+	updated := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":          map[string]interface{}{"app.kubernetes.io/name": "api"},
+			"annotations":     map[string]interface{}{"note": "new"},
+			"resourceVersion": "2",
+		},
+		"spec": map[string]interface{}{
+			"ports": []interface{}{int64(8080)},
+		},
+	}
+
+	changes, err := diffObjects(&unstructured.Unstructured{Object: old}, &unstructured.Unstructured{Object: updated})
+	require.NoError(t, err)
+	assert.Equal(t, []FieldChange{
+		{Path: "metadata.annotations.note", New: "new"},
+		{Path: `metadata.labels["app.kubernetes.io/name"]`, Old: "web", New: "api"},
+		{Path: "spec.ports[0]", Old: int64(80), New: int64(8080)},
+		{Path: "spec.ports[1]", Old: int64(443)},
+	}, changes)
+}
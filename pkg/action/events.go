@@ -0,0 +1,202 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/cli-runtime/pkg/resource"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// Observer receives the events of the progress of the install, upgrade,
+// rollback and uninstall actions, such as to report it as it happens.
+//
+// Observe is called synchronously, and may be called concurrently when hooks
+// run concurrently. It must not modify the releases of the events.
+type Observer interface {
+	Observe(Event)
+}
+
+// ObserverFunc is an Observer calling a function.
+type ObserverFunc func(Event)
+
+// Observe calls f(e).
+func (f ObserverFunc) Observe(e Event) {
+	f(e)
+}
+
+// Event is an event of the progress of an action. It is one of
+// *RenderStarted, *ResourceApplied, *HookFired, *WaitProgress and
+// *ReleaseStored.
+type Event interface {
+	event()
+}
+
+// RenderStarted is sent when the templates of a chart start to render.
+type RenderStarted struct {
+	Release   string
+	Namespace string
+	Chart     *chart.Metadata
+}
+
+// ResourceApplied is sent when a resource of a release has been created or
+// updated.
+type ResourceApplied struct {
+	Release   string
+	Namespace string
+	// Kind, Name and ResourceNamespace identify the resource. The
+	// ResourceNamespace is empty for cluster-scoped resources.
+	Kind              string
+	Name              string
+	ResourceNamespace string
+}
+
+// HookFired is sent when a hook starts, with the HookPhaseRunning phase, and
+// when it completes, with the HookPhaseSucceeded or HookPhaseFailed phase.
+type HookFired struct {
+	Release   string
+	Namespace string
+	Event     release.HookEvent
+	Hook      string
+	Phase     release.HookPhase
+	// Err is the failure of the hook.
+	Err error
+}
+
+// WaitProgress is sent when waiting for the resources of a release starts,
+// and when it is done.
+type WaitProgress struct {
+	Release   string
+	Namespace string
+	// Resources is the number of resources waited for.
+	Resources int
+	// Done is set once the wait is over.
+	Done bool
+	// Elapsed is the time the wait took, when it is done.
+	Elapsed time.Duration
+	// Err is the failure of the wait.
+	Err error
+}
+
+// ReleaseStored is sent when a release record has been created or updated.
+type ReleaseStored struct {
+	Release   string
+	Namespace string
+	Revision  int
+	Status    release.Status
+}
+
+func (*RenderStarted) event()   {}
+func (*ResourceApplied) event() {}
+func (*HookFired) event()       {}
+func (*WaitProgress) event()    {}
+func (*ReleaseStored) event()   {}
+
+// notify sends an event to the observer, if any.
+func (cfg *Configuration) notify(e Event) {
+	if cfg.Observer != nil {
+		cfg.Observer.Observe(e)
+	}
+}
+
+// renderStarted notifies the observer that the templates of the chart of a
+// release start to render.
+func (cfg *Configuration) renderStarted(name, namespace string, chrt *chart.Chart) {
+	cfg.notify(&RenderStarted{Release: name, Namespace: namespace, Chart: chrt.Metadata})
+}
+
+// resourceApplied notifies the observer that a resource of a release has been
+// created or updated.
+func (cfg *Configuration) resourceApplied(rel *release.Release, info *resource.Info) {
+	kind := info.Object.GetObjectKind().GroupVersionKind().Kind
+	if info.Mapping != nil {
+		kind = info.Mapping.GroupVersionKind.Kind
+	}
+	cfg.notify(&ResourceApplied{
+		Release:           rel.Name,
+		Namespace:         rel.Namespace,
+		Kind:              kind,
+		Name:              info.Name,
+		ResourceNamespace: info.Namespace,
+	})
+}
+
+// appliedOption returns the UpdateOption notifying the observer of the
+// resources of a release applied by Update.
+func (cfg *Configuration) appliedOption(rel *release.Release) kube.UpdateOption {
+	return kube.OnApplied(func(info *resource.Info) {
+		cfg.resourceApplied(rel, info)
+	})
+}
+
+// hookFired notifies the observer of the phase of a hook.
+func (cfg *Configuration) hookFired(rl *release.Release, h *release.Hook, hook release.HookEvent, phase release.HookPhase, err error) {
+	cfg.notify(&HookFired{
+		Release:   rl.Name,
+		Namespace: rl.Namespace,
+		Event:     hook,
+		Hook:      h.Name,
+		Phase:     phase,
+		Err:       err,
+	})
+}
+
+// waitForResources waits for the resources of a release to be ready, tracing
+// the wait and notifying the observer of its progress.
+func (cfg *Configuration) waitForResources(ctx context.Context, rel *release.Release, waiter kube.Waiter, resources kube.ResourceList, waitForJobs bool, timeout time.Duration) error {
+	_, span := cfg.startSpan(ctx, "helm.wait", resourceCountKey.Int(len(resources)))
+	cfg.notify(&WaitProgress{Release: rel.Name, Namespace: rel.Namespace, Resources: len(resources)})
+
+	start := time.Now()
+	var err error
+	if waitForJobs {
+		err = waiter.WaitWithJobs(resources, timeout)
+	} else {
+		err = waiter.Wait(resources, timeout)
+	}
+
+	cfg.notify(&WaitProgress{Release: rel.Name, Namespace: rel.Namespace, Resources: len(resources), Done: true, Elapsed: time.Since(start), Err: err})
+	endSpan(span, err)
+	return err
+}
+
+// storeNewRelease creates the record of a release.
+func (cfg *Configuration) storeNewRelease(r *release.Release) error {
+	if err := cfg.Releases.Create(r); err != nil {
+		return err
+	}
+	cfg.releaseStored(r)
+	return nil
+}
+
+// storeRelease updates the record of a release.
+func (cfg *Configuration) storeRelease(r *release.Release) error {
+	if err := cfg.Releases.Update(r); err != nil {
+		return err
+	}
+	cfg.releaseStored(r)
+	return nil
+}
+
+func (cfg *Configuration) releaseStored(r *release.Release) {
+	cfg.notify(&ReleaseStored{Release: r.Name, Namespace: r.Namespace, Revision: r.Version, Status: r.Info.Status})
+}
@@ -0,0 +1,102 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (o *recordingObserver) Observe(e Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, e)
+}
+
+func TestInstallRelease_Observer(t *testing.T) {
+	instAction := installAction(t)
+	observer := &recordingObserver{}
+	instAction.cfg.Observer = observer
+
+	rel, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+
+	name, ns := rel.Name, rel.Namespace
+	assert.Equal(t, []Event{
+		&RenderStarted{Release: name, Namespace: ns, Chart: rel.Chart.Metadata},
+		&ReleaseStored{Release: name, Namespace: ns, Revision: 1, Status: release.StatusPendingInstall},
+		&WaitProgress{Release: name, Namespace: ns},
+		&WaitProgress{Release: name, Namespace: ns, Done: true},
+		&ReleaseStored{Release: name, Namespace: ns, Revision: 1, Status: release.StatusPendingInstall},
+		&HookFired{Release: name, Namespace: ns, Event: release.HookPostInstall, Hook: "test-cm", Phase: release.HookPhaseRunning},
+		&HookFired{Release: name, Namespace: ns, Event: release.HookPostInstall, Hook: "test-cm", Phase: release.HookPhaseSucceeded},
+		&ReleaseStored{Release: name, Namespace: ns, Revision: 1, Status: release.StatusDeployed},
+	}, withoutElapsed(observer.events))
+}
+
+func TestUpgradeRelease_ObserverHookFailure(t *testing.T) {
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Info.Status = release.StatusDeployed
+	require.NoError(t, upAction.cfg.Releases.Create(rel))
+	observer := &recordingObserver{}
+	upAction.cfg.Observer = observer
+
+	failer := upAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.WatchUntilReadyError = fmt.Errorf("hook failed")
+	upAction.cfg.KubeClient = failer
+
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	require.Error(t, err)
+
+	var fired []*HookFired
+	for _, e := range observer.events {
+		if h, ok := e.(*HookFired); ok {
+			fired = append(fired, h)
+		}
+	}
+	require.Len(t, fired, 2)
+	assert.Equal(t, release.HookPhaseRunning, fired[0].Phase)
+	assert.Equal(t, release.HookPhaseFailed, fired[1].Phase)
+	assert.EqualError(t, fired[1].Err, "hook failed")
+
+	last := observer.events[len(observer.events)-1].(*ReleaseStored)
+	assert.Equal(t, 2, last.Revision)
+	assert.Equal(t, release.StatusFailed, last.Status)
+}
+
+// withoutElapsed clears the durations of the events, which vary.
+func withoutElapsed(events []Event) []Event {
+	for _, e := range events {
+		if w, ok := e.(*WaitProgress); ok {
+			w.Elapsed = 0
+		}
+	}
+	return events
+}
@@ -17,6 +17,14 @@ limitations under the License.
 package action
 
 import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
@@ -28,6 +36,95 @@ type Get struct {
 
 	// Initializing Version to 0 will get the latest revision of the release.
 	Version int
+
+	// ManifestFilter selects the documents kept in the manifest of the
+	// release. The empty filter keeps the whole manifest.
+	ManifestFilter ManifestFilter
+}
+
+// ManifestFilter selects documents of the manifest of a release. A document
+// is selected when it matches every field that is set, and matches a field
+// listing several values when it matches any of them.
+type ManifestFilter struct {
+	// Kinds are the kinds of the documents, matched case-insensitively.
+	Kinds []string
+	// Names are the names of the documents.
+	Names []string
+	// Selector is a label selector, such as "app.kubernetes.io/component=web".
+	Selector string
+	// Sources are patterns of the paths of the templates the documents are
+	// rendered from, as matched by path.Match. A pattern also matches the
+	// templates under it, so that "mychart/charts/sub" selects the documents
+	// of the sub subchart.
+	Sources []string
+}
+
+func (f ManifestFilter) isEmpty() bool {
+	return len(f.Kinds) == 0 && len(f.Names) == 0 && f.Selector == "" && len(f.Sources) == 0
+}
+
+// Filter returns the documents of a manifest selected by the filter, in the
+// order of the manifest.
+func (f ManifestFilter) Filter(manifest string) (string, error) {
+	if f.isEmpty() {
+		return manifest, nil
+	}
+	selector := labels.Everything()
+	if f.Selector != "" {
+		var err error
+		if selector, err = labels.Parse(f.Selector); err != nil {
+			return "", errors.Wrap(err, "invalid label selector")
+		}
+	}
+	for _, pattern := range f.Sources {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return "", errors.Wrapf(err, "invalid source pattern %q", pattern)
+		}
+	}
+
+	docs, err := release.ParseManifest(manifest)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, doc := range docs {
+		if !f.matches(doc, selector) {
+			continue
+		}
+		if doc.Source != "" {
+			fmt.Fprintf(&b, "---\n# Source: %s\n%s\n", doc.Source, doc.Raw)
+		} else {
+			fmt.Fprintf(&b, "---\n%s\n", doc.Raw)
+		}
+	}
+	return b.String(), nil
+}
+
+func (f ManifestFilter) matches(doc release.ManifestDocument, selector labels.Selector) bool {
+	if len(f.Kinds) > 0 && !slices.ContainsFunc(f.Kinds, func(kind string) bool { return strings.EqualFold(kind, doc.GroupVersionKind.Kind) }) {
+		return false
+	}
+	if len(f.Names) > 0 && !slices.Contains(f.Names, doc.Name) {
+		return false
+	}
+	if !selector.Matches(labels.Set(doc.Labels)) {
+		return false
+	}
+	if len(f.Sources) > 0 && !slices.ContainsFunc(f.Sources, func(pattern string) bool { return matchesSource(pattern, doc.Source) }) {
+		return false
+	}
+	return true
+}
+
+// matchesSource returns whether the path of a template matches a pattern, or
+// is under a directory matching it.
+func matchesSource(pattern, source string) bool {
+	for p := source; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if ok, _ := path.Match(strings.TrimSuffix(pattern, "/"), p); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // NewGet creates a new Get object with the given configuration.
@@ -43,5 +140,18 @@ func (g *Get) Run(name string) (*release.Release, error) {
 		return nil, err
 	}
 
-	return g.cfg.releaseContent(name, g.Version)
+	rel, err := g.cfg.releaseContent(name, g.Version)
+	if err != nil {
+		return nil, err
+	}
+	if g.ManifestFilter.isEmpty() {
+		return rel, nil
+	}
+	// The release may be the one held by the storage driver, so it is copied
+	// rather than modified.
+	filtered := *rel
+	if filtered.Manifest, err = g.ManifestFilter.Filter(rel.Manifest); err != nil {
+		return nil, err
+	}
+	return &filtered, nil
 }
@@ -0,0 +1,176 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/dynamic"
+
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// GetResources is the action for listing the live objects of a release.
+//
+// It provides the implementation of 'helm get resources'.
+type GetResources struct {
+	cfg *Configuration
+
+	Version int
+}
+
+// LiveResource is a live object of a release.
+type LiveResource struct {
+	Kind      string `json:"kind" yaml:"kind"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name      string `json:"name" yaml:"name"`
+	// Health is one of "Ready", "InProgress", "Failed", "Missing" and
+	// "Unknown".
+	Health  string `json:"health" yaml:"health"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Created is when the object was created. It is zero when the object is
+	// missing.
+	Created time.Time `json:"created,omitempty" yaml:"created,omitempty"`
+	// InManifest is false for the objects labeled and annotated as belonging
+	// to the release that its manifest does not list, such as hooks and the
+	// resources left behind by a failed upgrade.
+	InManifest bool `json:"inManifest" yaml:"inManifest"`
+}
+
+// NewGetResources creates a new GetResources object with the given configuration.
+func NewGetResources(cfg *Configuration) *GetResources {
+	return &GetResources{
+		cfg: cfg,
+	}
+}
+
+// Run executes 'helm get resources' against the given release. It lists the
+// resources of the manifest of the release, then the other objects labeled
+// and annotated as belonging to the release.
+func (g *GetResources) Run(name string) ([]LiveResource, error) {
+	if err := g.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+	// TODO Helm 4: Remove this check when Health is moved from InterfaceHealth to Interface
+	healthClient, ok := g.cfg.KubeClient.(kube.InterfaceHealth)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceHealth")
+	}
+
+	rel, err := g.cfg.releaseContent(name, g.Version)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := g.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build kubernetes objects from the release manifest")
+	}
+	others, err := g.cfg.otherReleaseObjects(context.Background(), rel, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := liveResources(healthClient, resources, true)
+	if err != nil {
+		return nil, err
+	}
+	otherLive, err := liveResources(healthClient, others, false)
+	if err != nil {
+		return nil, err
+	}
+	return append(live, otherLive...), nil
+}
+
+func liveResources(healthClient kube.InterfaceHealth, resources kube.ResourceList, inManifest bool) ([]LiveResource, error) {
+	if len(resources) == 0 {
+		return nil, nil
+	}
+	health, err := healthClient.Health(resources)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get the health of the resources")
+	}
+	live := make([]LiveResource, 0, len(health))
+	for _, h := range health {
+		live = append(live, LiveResource{
+			Kind:       h.Kind,
+			Namespace:  h.Namespace,
+			Name:       h.Name,
+			Health:     string(h.Status),
+			Message:    h.Message,
+			Created:    h.Created,
+			InManifest: inManifest,
+		})
+	}
+	return live, nil
+}
+
+// otherReleaseObjects returns the live objects labeled and annotated as
+// belonging to a release that are missing from its resources. There are none
+// when the configuration cannot query the cluster for them.
+func (cfg *Configuration) otherReleaseObjects(ctx context.Context, rel *release.Release, resources kube.ResourceList) (kube.ResourceList, error) {
+	if cfg.RESTClientGetter == nil {
+		return nil, nil
+	}
+	dc, err := cfg.RESTClientGetter.ToDiscoveryClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get discovery client")
+	}
+	restConfig, err := cfg.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get REST config")
+	}
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get dynamic client")
+	}
+
+	listed := map[string]bool{}
+	for _, info := range resources {
+		gvk := info.Object.GetObjectKind().GroupVersionKind()
+		listed[orphanKey(gvk.Group, gvk.Kind, info.Namespace, info.Name)] = true
+	}
+	var others kube.ResourceList
+	err = visitReleaseObjects(ctx, dc, client, rel, releaseNamespaces(rel, resources), func(gvr schema.GroupVersionResource, kind string, obj *unstructured.Unstructured) {
+		if listed[orphanKey(gvr.Group, kind, obj.GetNamespace(), obj.GetName())] {
+			return
+		}
+		obj.SetGroupVersionKind(gvr.GroupVersion().WithKind(kind))
+		scope := meta.RESTScopeNamespace
+		if obj.GetNamespace() == "" {
+			scope = meta.RESTScopeRoot
+		}
+		others = append(others, &resource.Info{
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+			Object:    obj,
+			Mapping: &meta.RESTMapping{
+				Resource:         gvr,
+				GroupVersionKind: gvr.GroupVersion().WithKind(kind),
+				Scope:            scope,
+			},
+		})
+	})
+	return others, err
+}
@@ -0,0 +1,58 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+func TestGetResources(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	cfg.KubeClient = &diffKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+	rel := releaseStub()
+	rel.Manifest = `---
+# Source: hello/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: hello
+---
+# Source: hello/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello
+`
+	require.NoError(t, cfg.Releases.Create(rel))
+
+	resources, err := NewGetResources(cfg).Run(rel.Name)
+	require.NoError(t, err)
+	assert.Equal(t, []LiveResource{
+		{Kind: "Service", Namespace: "spaced", Name: "hello", Health: "Ready", InManifest: true},
+		{Kind: "Deployment", Namespace: "spaced", Name: "hello", Health: "Ready", InManifest: true},
+	}, resources)
+
+	_, err = NewGetResources(cfg).Run("missing")
+	assert.ErrorIs(t, err, driver.ErrReleaseNotFound)
+}
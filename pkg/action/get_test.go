@@ -0,0 +1,76 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func TestManifestFilter(t *testing.T) {
+	manifest := `---
+# Source: hello/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: hello
+---
+# Source: hello/charts/db/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: db
+---
+# Source: hello/charts/db/templates/statefulset.yaml
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+`
+	for _, test := range []struct {
+		name     string
+		filter   ManifestFilter
+		expected []string
+	}{
+		{"empty", ManifestFilter{}, []string{"Service hello", "Service db", "StatefulSet db"}},
+		{"kind", ManifestFilter{Kinds: []string{"statefulset"}}, []string{"StatefulSet db"}},
+		{"name", ManifestFilter{Names: []string{"hello", "missing"}}, []string{"Service hello"}},
+		{"subchart", ManifestFilter{Sources: []string{"hello/charts/db/"}}, []string{"Service db", "StatefulSet db"}},
+		{"glob", ManifestFilter{Sources: []string{"hello/charts/*/templates/service.yaml"}}, []string{"Service db"}},
+		{"kind and source", ManifestFilter{Kinds: []string{"Service"}, Sources: []string{"hello/templates"}}, []string{"Service hello"}},
+		{"nothing", ManifestFilter{Kinds: []string{"Deployment"}}, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			filtered, err := test.filter.Filter(manifest)
+			require.NoError(t, err)
+			docs, err := release.ParseManifest(filtered)
+			require.NoError(t, err)
+			var got []string
+			for _, doc := range docs {
+				got = append(got, doc.GroupVersionKind.Kind+" "+doc.Name)
+			}
+			assert.Equal(t, test.expected, got)
+		})
+	}
+
+	_, err := ManifestFilter{Sources: []string{"[bad"}}.Filter(manifest)
+	assert.ErrorContains(t, err, `invalid source pattern "[bad"`)
+}
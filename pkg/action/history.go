@@ -17,10 +17,13 @@ limitations under the License.
 package action
 
 import (
+	"fmt"
 	"log/slog"
 
 	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
 
+	chart "helm.sh/helm/v4/pkg/chart/v2"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -58,3 +61,149 @@ func (h *History) Run(name string) ([]*release.Release, error) {
 	slog.Debug("getting history for release", "release", name)
 	return h.cfg.Releases.History(name)
 }
+
+// RevisionDiff is the set of differences between two revisions of a release.
+type RevisionDiff struct {
+	Release string `json:"release"`
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+	// ChartChanges are the changes of the name, version and app version of
+	// the chart.
+	ChartChanges []FieldChange `json:"chartChanges,omitempty"`
+	// ValueChanges are the changes of the values supplied by the user.
+	ValueChanges []FieldChange `json:"valueChanges,omitempty"`
+	// ResourceChanges are the changes of the resources of the manifest. The
+	// resources added by the later revision are created, and those it
+	// removed are deleted. Hooks are not part of them.
+	ResourceChanges []ResourceChange `json:"resourceChanges,omitempty"`
+}
+
+// Diff returns the differences in chart, values and manifest between two
+// revisions of the given release. The data of Secrets is masked.
+func (h *History) Diff(name string, rev1, rev2 int) (*RevisionDiff, error) {
+	if err := h.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return nil, errors.Errorf("release name is invalid: %s", name)
+	}
+	if rev1 <= 0 || rev2 <= 0 {
+		return nil, errors.Errorf("revisions must be positive: %d, %d", rev1, rev2)
+	}
+
+	slog.Debug("getting diff between revisions", "release", name, "from", rev1, "to", rev2)
+	from, err := h.cfg.Releases.Get(name, rev1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get revision %d", rev1)
+	}
+	to, err := h.cfg.Releases.Get(name, rev2)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get revision %d", rev2)
+	}
+
+	resourceChanges, err := diffManifests(from.Manifest, to.Manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &RevisionDiff{
+		Release:         name,
+		From:            rev1,
+		To:              rev2,
+		ChartChanges:    diffFields("", chartFields(from.Chart), chartFields(to.Chart)),
+		ValueChanges:    diffFields("", from.Config, to.Config),
+		ResourceChanges: resourceChanges,
+	}, nil
+}
+
+// chartFields returns the fields of the metadata of a chart compared between
+// revisions.
+func chartFields(ch *chart.Chart) map[string]interface{} {
+	if ch == nil || ch.Metadata == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"name":       ch.Metadata.Name,
+		"version":    ch.Metadata.Version,
+		"appVersion": ch.Metadata.AppVersion,
+	}
+}
+
+// manifestResource is a resource of a manifest, decoded for comparison.
+type manifestResource struct {
+	doc     release.ManifestDocument
+	content map[string]interface{}
+}
+
+// diffManifests returns the changes of the resources between two manifests.
+// The changes are ordered by the later manifest, followed by the deleted
+// resources in the order of the earlier one.
+func diffManifests(oldManifest, newManifest string) ([]ResourceChange, error) {
+	oldResources, oldKeys, err := manifestResources(oldManifest)
+	if err != nil {
+		return nil, err
+	}
+	newResources, newKeys, err := manifestResources(newManifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ResourceChange
+	for _, key := range newKeys {
+		res := newResources[key]
+		change := newManifestChange(res.doc, ChangeCreate)
+		if old, ok := oldResources[key]; ok {
+			change.Action = ChangeUpdate
+			for _, field := range diffFields("", old.content, res.content) {
+				if !isIgnoredDiffPath(field.Path) {
+					change.ManifestChanges = append(change.ManifestChanges, field)
+				}
+			}
+			if len(change.ManifestChanges) == 0 {
+				continue
+			}
+		}
+		maskSensitiveChanges(&change)
+		changes = append(changes, change)
+	}
+	for _, key := range oldKeys {
+		if _, ok := newResources[key]; !ok {
+			changes = append(changes, newManifestChange(oldResources[key].doc, ChangeDelete))
+		}
+	}
+	return changes, nil
+}
+
+// manifestResources decodes the resources of a manifest, by key, along with
+// their keys in the order of the manifest.
+func manifestResources(manifest string) (map[string]manifestResource, []string, error) {
+	docs, err := release.ParseManifest(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources := make(map[string]manifestResource, len(docs))
+	keys := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		content := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(doc.Raw), &content); err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to decode %s %q", doc.GroupVersionKind.Kind, doc.Name)
+		}
+		key := fmt.Sprintf("%s/%s/%s/%s", doc.GroupVersionKind.GroupVersion().String(), doc.GroupVersionKind.Kind, doc.Namespace, doc.Name)
+		if _, ok := resources[key]; !ok {
+			keys = append(keys, key)
+		}
+		resources[key] = manifestResource{doc: doc, content: content}
+	}
+	return resources, keys, nil
+}
+
+func newManifestChange(doc release.ManifestDocument, action ChangeAction) ResourceChange {
+	apiVersion, kind := doc.GroupVersionKind.ToAPIVersionAndKind()
+	return ResourceChange{
+		Action:     action,
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Namespace:  doc.Namespace,
+		Name:       doc.Name,
+	}
+}
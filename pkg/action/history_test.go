@@ -0,0 +1,132 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func TestHistoryDiff(t *testing.T) {
+	config := actionConfigFixture(t)
+
+	first := releaseStub()
+	first.Manifest = `---
+# Source: hello/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: fast
+---
+# Source: hello/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  password: hunter2
+---
+# Source: hello/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: hello
+`
+	second := releaseStub()
+	second.Version = 2
+	second.Chart.Metadata.Version = "0.2.0"
+	second.Config = map[string]interface{}{"name": "other", "replicas": 2}
+	second.Manifest = `---
+# Source: hello/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: safe
+---
+# Source: hello/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  password: correct-horse
+---
+# Source: hello/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello
+`
+	for _, rel := range []*release.Release{first, second} {
+		require.NoError(t, config.Releases.Create(rel))
+	}
+
+	client := NewHistory(config)
+	diff, err := client.Diff(first.Name, 1, 2)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, diff.From)
+	assert.Equal(t, 2, diff.To)
+	assert.Equal(t, []FieldChange{{Path: "version", Old: first.Chart.Metadata.Version, New: "0.2.0"}}, diff.ChartChanges)
+	assert.Equal(t, []FieldChange{
+		{Path: "name", Old: "value", New: "other"},
+		{Path: "replicas", New: 2},
+	}, diff.ValueChanges)
+	assert.Equal(t, []ResourceChange{{
+		Action:          ChangeUpdate,
+		APIVersion:      "v1",
+		Kind:            "ConfigMap",
+		Name:            "settings",
+		ManifestChanges: []FieldChange{{Path: "data.mode", Old: "fast", New: "safe"}},
+	}, {
+		Action:          ChangeUpdate,
+		APIVersion:      "v1",
+		Kind:            "Secret",
+		Name:            "credentials",
+		ManifestChanges: []FieldChange{{Path: "stringData.password", Old: sensitiveValue, New: sensitiveValue}},
+	}, {
+		Action:     ChangeCreate,
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "hello",
+	}, {
+		Action:     ChangeDelete,
+		APIVersion: "v1",
+		Kind:       "Service",
+		Name:       "hello",
+	}}, diff.ResourceChanges)
+
+	same, err := client.Diff(first.Name, 1, 1)
+	require.NoError(t, err)
+	assert.Empty(t, same.ChartChanges)
+	assert.Empty(t, same.ValueChanges)
+	assert.Empty(t, same.ResourceChanges)
+
+	_, err = client.Diff(first.Name, 1, 3)
+	assert.ErrorContains(t, err, "unable to get revision 3")
+
+	_, err = client.Diff(first.Name, 0, 1)
+	assert.ErrorContains(t, err, "revisions must be positive")
+}
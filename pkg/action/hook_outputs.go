@@ -0,0 +1,112 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+const (
+	// hookOutputConfigMapPrefix prefixes the name of the ConfigMap whose data
+	// are the outputs of a hook.
+	hookOutputConfigMapPrefix = "configmap/"
+	// hookOutputTerminationMessage reads the outputs of a hook from the
+	// termination messages of its pods, written as JSON objects.
+	hookOutputTerminationMessage = "termination-message"
+)
+
+// readHookOutputs reads the outputs published by a succeeded hook. They are
+// recorded in the release, and available to the templates of the following
+// releases as .Release.HookOutputs.<hook name>.<key>.
+func (cfg *Configuration) readHookOutputs(ctx context.Context, h *release.Hook, releaseNamespace string) (map[string]string, error) {
+	namespace, err := cfg.deriveNamespace(h, releaseNamespace)
+	if err != nil {
+		return nil, err
+	}
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	return readHookOutputs(ctx, client, h, namespace)
+}
+
+func readHookOutputs(ctx context.Context, client kubernetes.Interface, h *release.Hook, namespace string) (map[string]string, error) {
+	switch {
+	case strings.HasPrefix(h.Output, hookOutputConfigMapPrefix):
+		name := strings.TrimPrefix(h.Output, hookOutputConfigMapPrefix)
+		cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read the outputs of hook %s", h.Name)
+		}
+		return cm.Data, nil
+	case h.Output == hookOutputTerminationMessage:
+		var listOptions metav1.ListOptions
+		switch h.Kind {
+		case "Job":
+			listOptions = metav1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", h.Name)}
+		case "Pod":
+			listOptions = metav1.ListOptions{FieldSelector: fmt.Sprintf("metadata.name=%s", h.Name)}
+		default:
+			return nil, errors.Errorf("hook %s of kind %s has no pods to publish outputs in their termination messages", h.Name, h.Kind)
+		}
+		pods, err := client.CoreV1().Pods(namespace).List(ctx, listOptions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read the outputs of hook %s", h.Name)
+		}
+		outputs := map[string]string{}
+		for _, pod := range pods.Items {
+			if pod.Status.Phase != v1.PodSucceeded {
+				continue
+			}
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.State.Terminated == nil || status.State.Terminated.Message == "" {
+					continue
+				}
+				var published map[string]string
+				if err := json.Unmarshal([]byte(status.State.Terminated.Message), &published); err != nil {
+					return nil, errors.Wrapf(err, "termination message of container %s of pod %s is not a JSON object of strings", status.Name, pod.Name)
+				}
+				maps.Copy(outputs, published)
+			}
+		}
+		return outputs, nil
+	default:
+		return nil, errors.Errorf("hook %s has an invalid %s annotation %q: must be %s<name> or %s", h.Name, release.HookOutputAnnotation, h.Output, hookOutputConfigMapPrefix, hookOutputTerminationMessage)
+	}
+}
+
+func copyHookOutputs(outputs map[string]map[string]string) map[string]map[string]string {
+	if outputs == nil {
+		return nil
+	}
+	copied := make(map[string]map[string]string, len(outputs))
+	for name, values := range outputs {
+		copied[name] = maps.Clone(values)
+	}
+	return copied
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func hookPod(name, phase string, messages ...string) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "spaced", Labels: map[string]string{"job-name": "allocate"}},
+		Status:     v1.PodStatus{Phase: v1.PodPhase(phase)},
+	}
+	for _, msg := range messages {
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, v1.ContainerStatus{
+			Name:  "main",
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Message: msg}},
+		})
+	}
+	return pod
+}
+
+func TestReadHookOutputs(t *testing.T) {
+	client := fakeclientset.NewClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "allocated-ids", Namespace: "spaced"},
+			Data:       map[string]string{"tenant": "t-123"},
+		},
+		hookPod("allocate-failed", "Failed", `{"id": "1"}`),
+		hookPod("allocate-done", "Succeeded", `{"id": "2"}`, `{"password": "s3cr3t"}`),
+	)
+
+	outputs, err := readHookOutputs(context.Background(), client, &release.Hook{Name: "allocate", Kind: "Job", Output: "configmap/allocated-ids"}, "spaced")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"tenant": "t-123"}, outputs)
+
+	outputs, err = readHookOutputs(context.Background(), client, &release.Hook{Name: "allocate", Kind: "Job", Output: "termination-message"}, "spaced")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"id": "2", "password": "s3cr3t"}, outputs)
+
+	_, err = readHookOutputs(context.Background(), client, &release.Hook{Name: "allocate", Kind: "ConfigMap", Output: "termination-message"}, "spaced")
+	assert.ErrorContains(t, err, "has no pods")
+
+	_, err = readHookOutputs(context.Background(), client, &release.Hook{Name: "allocate", Kind: "Job", Output: "secret/allocated-ids"}, "spaced")
+	assert.ErrorContains(t, err, `invalid helm.sh/hook-output annotation "secret/allocated-ids"`)
+
+	client = fakeclientset.NewClientset(hookPod("allocate-done", "Succeeded", "id=2"))
+	_, err = readHookOutputs(context.Background(), client, &release.Hook{Name: "allocate", Kind: "Job", Output: "termination-message"}, "spaced")
+	assert.ErrorContains(t, err, "is not a JSON object of strings")
+}
+
+func TestUpgradeRelease_KeepsHookOutputs(t *testing.T) {
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Info.Status = release.StatusDeployed
+	rel.HookOutputs = map[string]map[string]string{"allocate": {"id": "42"}}
+	require.NoError(t, upAction.cfg.Releases.Create(rel))
+
+	tmpl := []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  id: "{{ .Release.HookOutputs.allocate.id }}"
+`)
+	res, err := upAction.Run(rel.Name, buildChartWithTemplates([]*chart.File{{Name: "templates/settings", Data: tmpl}}), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Contains(t, res.Manifest, `id: "42"`)
+	assert.Equal(t, rel.HookOutputs, res.HookOutputs)
+}
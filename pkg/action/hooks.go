@@ -17,10 +17,16 @@ package action
 
 import (
 	"bytes"
+	"context"
+	stderrors "errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"helm.sh/helm/v4/pkg/kube"
@@ -28,78 +34,96 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 
 	release "helm.sh/helm/v4/pkg/release/v1"
 	helmtime "helm.sh/helm/v4/pkg/time"
 )
 
-// execHook executes all of the hooks for the given hook event.
-func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent, waitStrategy kube.WaitStrategy, timeout time.Duration) error {
+// execHook executes all of the hooks for the given hook event, except for the
+// hooks named in skipHooks. Hooks of the same weight run concurrently, up to
+// concurrency at a time, when concurrency is greater than one. No more hooks
+// are started once ctx is done.
+func (cfg *Configuration) execHook(ctx context.Context, rl *release.Release, hook release.HookEvent, skipHooks []string, concurrency int, waitStrategy kube.WaitStrategy, timeout time.Duration) error {
+	return cfg.execHookWithRecorder(ctx, rl, cfg.recordRelease, hook, skipHooks, concurrency, waitStrategy, timeout)
+}
+
+// execHookWithRecorder is execHook, with the release written through record
+// when the hooks are started.
+func (cfg *Configuration) execHookWithRecorder(ctx context.Context, rl *release.Release, record func(*release.Release), hook release.HookEvent, skipHooks []string, concurrency int, waitStrategy kube.WaitStrategy, timeout time.Duration) (err error) {
 	executingHooks := []*release.Hook{}
 
 	for _, h := range rl.Hooks {
 		for _, e := range h.Events {
-			if e == hook {
-				executingHooks = append(executingHooks, h)
+			if e != hook {
+				continue
+			}
+			if slices.Contains(skipHooks, h.Name) {
+				slog.Debug("skipping hook", "hook", h.Name, "event", hook, "path", h.Path)
+				continue
 			}
+			executingHooks = append(executingHooks, h)
 		}
 	}
 
 	// hooke are pre-ordered by kind, so keep order stable
 	sort.Stable(hookByWeight(executingHooks))
 
-	for i, h := range executingHooks {
-		// Set default delete policy to before-hook-creation
-		if len(h.DeletePolicies) == 0 {
-			// TODO(jlegrone): Only apply before-hook-creation delete policy to run to completion
-			//                 resources. For all other resource types update in place if a
-			//                 resource with the same name already exists and is owned by the
-			//                 current release.
-			h.DeletePolicies = []release.HookDeletePolicy{release.HookBeforeHookCreation}
-		}
+	if len(executingHooks) > 0 {
+		var span trace.Span
+		ctx, span = cfg.startSpan(ctx, "helm.hooks "+string(hook), hookEventKey.String(string(hook)))
+		defer func() { endSpan(span, err) }()
+	}
 
-		if err := cfg.deleteHookByPolicy(h, release.HookBeforeHookCreation, waitStrategy, timeout); err != nil {
+	// mu guards the hooks' LastRun, which is recorded along with the release.
+	var mu sync.Mutex
+	for start := 0; start < len(executingHooks); {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-
-		resources, err := cfg.KubeClient.Build(bytes.NewBufferString(h.Manifest), true)
-		if err != nil {
-			return errors.Wrapf(err, "unable to build kubernetes object for %s hook %s", hook, h.Path)
-		}
-
-		// Record the time at which the hook was applied to the cluster
-		h.LastRun = release.HookExecution{
-			StartedAt: helmtime.Now(),
-			Phase:     release.HookPhaseRunning,
+		end := start + 1
+		if concurrency > 1 {
+			for end < len(executingHooks) && executingHooks[end].Weight == executingHooks[start].Weight {
+				end++
+			}
 		}
-		cfg.recordRelease(rl)
-
-		// As long as the implementation of WatchUntilReady does not panic, HookPhaseFailed or HookPhaseSucceeded
-		// should always be set by this function. If we fail to do that for any reason, then HookPhaseUnknown is
-		// the most appropriate value to surface.
-		h.LastRun.Phase = release.HookPhaseUnknown
-
-		// Create hook resources
-		if _, err := cfg.KubeClient.Create(resources); err != nil {
-			h.LastRun.CompletedAt = helmtime.Now()
-			h.LastRun.Phase = release.HookPhaseFailed
-			return errors.Wrapf(err, "warning: Hook %s %s failed", hook, h.Path)
+		batch := executingHooks[start:end]
+
+		results := make([]hookResult, len(batch))
+		if len(batch) == 1 {
+			results[0] = cfg.runHook(ctx, rl, record, batch[0], hook, &mu, waitStrategy, timeout)
+		} else {
+			slog.Debug("running hooks concurrently", "event", hook, "weight", batch[0].Weight, "count", len(batch), "concurrency", concurrency)
+			var wg sync.WaitGroup
+			sem := make(chan struct{}, concurrency)
+			for j, h := range batch {
+				wg.Add(1)
+				sem <- struct{}{}
+				go func() {
+					defer wg.Done()
+					defer func() { <-sem }()
+					results[j] = cfg.runHook(ctx, rl, record, h, hook, &mu, waitStrategy, timeout)
+				}()
+			}
+			wg.Wait()
 		}
 
-		waiter, err := cfg.KubeClient.GetWaiter(waitStrategy)
-		if err != nil {
-			return errors.Wrapf(err, "unable to get waiter")
-		}
-		// Watch hook resources until they have completed
-		err = waiter.WatchUntilReady(resources, timeout)
-		// Note the time of success/failure
-		h.LastRun.CompletedAt = helmtime.Now()
-		// Mark hook as succeeded or failed
-		if err != nil {
-			h.LastRun.Phase = release.HookPhaseFailed
-			// If a hook is failed, check the annotation of the hook to determine if we should copy the logs client side
-			if errOutputting := cfg.outputLogsByPolicy(h, rl.Namespace, release.HookOutputOnFailed); errOutputting != nil {
+		var errs []error
+		succeeded := slices.Clone(executingHooks[:start])
+		for j, h := range batch {
+			result := results[j]
+			if result.err == nil {
+				succeeded = append(succeeded, h)
+				continue
+			}
+			errs = append(errs, result.err)
+			if !result.watched {
+				continue
+			}
+			// If a hook is failed, check the annotation of the hook to determine if we should copy the logs client side.
+			// The logs are also kept in the release record, so that the failure can be investigated after the hook is deleted.
+			if errOutputting := cfg.outputLogsByPolicy(h, rl.Namespace, release.HookOutputOnFailed, true); errOutputting != nil {
 				// We log the error here as we want to propagate the hook failure upwards to the release object.
 				log.Printf("error outputting logs for hook failure: %v", errOutputting)
 			}
@@ -109,23 +133,28 @@ func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent,
 				// We log the error here as we want to propagate the hook failure upwards to the release object.
 				log.Printf("error deleting the hook resource on hook failure: %v", errDeleting)
 			}
-
+		}
+		if len(errs) > 0 {
 			// If a hook is failed, check the annotation of the previous successful hooks to determine whether the hooks
 			// should be deleted under succeeded condition.
-			if err := cfg.deleteHooksByPolicy(executingHooks[0:i], release.HookSucceeded, timeout); err != nil {
-				return err
+			if slices.ContainsFunc(results, func(r hookResult) bool { return r.watched }) {
+				if err := cfg.deleteHooksByPolicy(succeeded, release.HookSucceeded, waitStrategy, timeout); err != nil {
+					return err
+				}
 			}
-
-			return err
+			if len(errs) == 1 {
+				return errs[0]
+			}
+			return stderrors.Join(errs...)
 		}
-		h.LastRun.Phase = release.HookPhaseSucceeded
+		start = end
 	}
 
 	// If all hooks are successful, check the annotation of each hook to determine whether the hook should be deleted
 	// or output should be logged under succeeded condition. If so, then clear the corresponding resource object in each hook
 	for i := len(executingHooks) - 1; i >= 0; i-- {
 		h := executingHooks[i]
-		if err := cfg.outputLogsByPolicy(h, rl.Namespace, release.HookOutputOnSucceeded); err != nil {
+		if err := cfg.outputLogsByPolicy(h, rl.Namespace, release.HookOutputOnSucceeded, cfg.CaptureHookLogs); err != nil {
 			// We log here as we still want to attempt hook resource deletion even if output logging fails.
 			log.Printf("error outputting logs for hook failure: %v", err)
 		}
@@ -137,6 +166,127 @@ func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent,
 	return nil
 }
 
+// hookResult is the outcome of running a single hook.
+type hookResult struct {
+	err error
+	// watched is set when the hook resources were created, so that a failure
+	// comes from watching them.
+	watched bool
+}
+
+// runHook creates the resources of a hook and watches them until they have
+// completed. The start of the hook is written to the release through record.
+func (cfg *Configuration) runHook(ctx context.Context, rl *release.Release, record func(*release.Release), h *release.Hook, hook release.HookEvent, mu *sync.Mutex, waitStrategy kube.WaitStrategy, timeout time.Duration) (result hookResult) {
+	ctx, span := cfg.startSpan(ctx, "helm.hook "+h.Name, hookEventKey.String(string(hook)), hookNameKey.String(h.Name), hookKindKey.String(h.Kind))
+	defer func() { endSpan(span, result.err) }()
+
+	// Set default delete policy to before-hook-creation
+	if len(h.DeletePolicies) == 0 {
+		// TODO(jlegrone): Only apply before-hook-creation delete policy to run to completion
+		//                 resources. For all other resource types update in place if a
+		//                 resource with the same name already exists and is owned by the
+		//                 current release.
+		h.DeletePolicies = []release.HookDeletePolicy{release.HookBeforeHookCreation}
+	}
+
+	if err := cfg.deleteHookByPolicy(h, release.HookBeforeHookCreation, waitStrategy, timeout); err != nil {
+		return hookResult{err: err}
+	}
+
+	resources, err := cfg.KubeClient.Build(bytes.NewBufferString(h.Manifest), true)
+	if err != nil {
+		return hookResult{err: errors.Wrapf(err, "unable to build kubernetes object for %s hook %s", hook, h.Path)}
+	}
+
+	// Record the time at which the hook was applied to the cluster
+	mu.Lock()
+	h.LastRun = release.HookExecution{
+		StartedAt: helmtime.Now(),
+		Phase:     release.HookPhaseRunning,
+	}
+	record(rl)
+
+	// As long as the implementation of WatchUntilReady does not panic, HookPhaseFailed or HookPhaseSucceeded
+	// should always be set by this function. If we fail to do that for any reason, then HookPhaseUnknown is
+	// the most appropriate value to surface.
+	h.LastRun.Phase = release.HookPhaseUnknown
+	mu.Unlock()
+	cfg.hookFired(rl, h, hook, release.HookPhaseRunning, nil)
+
+	if h.Timeout > 0 {
+		timeout = h.Timeout
+	}
+	for attempt := 0; ; attempt++ {
+		result = cfg.createAndWatchHook(ctx, h, hook, resources, waitStrategy, timeout)
+		if result.err == nil || attempt >= h.Retries || ctx.Err() != nil {
+			break
+		}
+		slog.Warn("hook failed, retrying", "hook", h.Name, "event", hook, "attempt", attempt+1, "retries", h.Retries, slog.Any("error", result.err))
+		// Remove the failed run of the hook so that it can be created again
+		if result.watched {
+			if err := cfg.deleteHookResources(resources, waitStrategy, timeout); err != nil {
+				result = hookResult{err: errors.Wrapf(err, "unable to delete hook %s before retrying it", h.Path)}
+				break
+			}
+		}
+	}
+
+	var outputs map[string]string
+	if result.err == nil && h.Output != "" {
+		if outputs, err = cfg.readHookOutputs(ctx, h, rl.Namespace); err != nil {
+			result = hookResult{err: err, watched: true}
+		}
+	}
+
+	mu.Lock()
+	// Note the time of success/failure
+	h.LastRun.CompletedAt = helmtime.Now()
+	// Mark hook as succeeded or failed
+	if result.err != nil {
+		h.LastRun.Phase = release.HookPhaseFailed
+	} else {
+		h.LastRun.Phase = release.HookPhaseSucceeded
+		if outputs != nil {
+			if rl.HookOutputs == nil {
+				rl.HookOutputs = map[string]map[string]string{}
+			}
+			rl.HookOutputs[h.Name] = outputs
+		}
+	}
+	phase := h.LastRun.Phase
+	mu.Unlock()
+	cfg.hookFired(rl, h, hook, phase, result.err)
+	return result
+}
+
+// createAndWatchHook creates the resources of a hook and watches them until
+// they have completed, or until ctx is done.
+func (cfg *Configuration) createAndWatchHook(ctx context.Context, h *release.Hook, hook release.HookEvent, resources kube.ResourceList, waitStrategy kube.WaitStrategy, timeout time.Duration) hookResult {
+	// Create hook resources
+	if _, err := cfg.KubeClient.Create(resources); err != nil {
+		return hookResult{err: errors.Wrapf(err, "warning: Hook %s %s failed", hook, h.Path)}
+	}
+
+	waiter, err := cfg.KubeClient.GetWaiter(waitStrategy)
+	if err != nil {
+		return hookResult{err: errors.Wrapf(err, "unable to get waiter")}
+	}
+	// Watch hook resources until they have completed
+	if err := watchUntilReady(ctx, waiter, resources, timeout); err != nil {
+		return hookResult{err: err, watched: true}
+	}
+	return hookResult{}
+}
+
+// watchUntilReady watches resources until they are ready. The watch stops when
+// ctx is done if the waiter supports it.
+func watchUntilReady(ctx context.Context, waiter kube.Waiter, resources kube.ResourceList, timeout time.Duration) error {
+	if w, ok := waiter.(kube.WaiterContext); ok {
+		return w.WatchUntilReadyWithContext(ctx, resources, timeout)
+	}
+	return waiter.WatchUntilReady(resources, timeout)
+}
+
 // hookByWeight is a sorter for hooks
 type hookByWeight []*release.Hook
 
@@ -161,26 +311,32 @@ func (cfg *Configuration) deleteHookByPolicy(h *release.Hook, policy release.Hoo
 		if err != nil {
 			return errors.Wrapf(err, "unable to build kubernetes object for deleting hook %s", h.Path)
 		}
-		_, errs := cfg.KubeClient.Delete(resources)
-		if len(errs) > 0 {
-			return errors.New(joinErrors(errs))
-		}
-
-		waiter, err := cfg.KubeClient.GetWaiter(waitStrategy)
-		if err != nil {
-			return err
-		}
-		if err := waiter.WaitForDelete(resources, timeout); err != nil {
-			return err
-		}
+		return cfg.deleteHookResources(resources, waitStrategy, timeout)
 	}
 	return nil
 }
 
+// deleteHookResources deletes the resources of a hook and waits for them to be gone.
+func (cfg *Configuration) deleteHookResources(resources kube.ResourceList, waitStrategy kube.WaitStrategy, timeout time.Duration) error {
+	_, errs := cfg.KubeClient.Delete(resources)
+	if len(errs) > 0 {
+		return errors.New(joinErrors(errs))
+	}
+
+	waiter, err := cfg.KubeClient.GetWaiter(waitStrategy)
+	if err != nil {
+		return err
+	}
+	if w, ok := waiter.(kube.WaiterHookDeletion); ok && cfg.WaitForHookDeletion {
+		return w.WaitForHookDeletion(resources, timeout)
+	}
+	return waiter.WaitForDelete(resources, timeout)
+}
+
 // deleteHooksByPolicy deletes all hooks if the hook policy instructs it to
-func (cfg *Configuration) deleteHooksByPolicy(hooks []*release.Hook, policy release.HookDeletePolicy, timeout time.Duration) error {
+func (cfg *Configuration) deleteHooksByPolicy(hooks []*release.Hook, policy release.HookDeletePolicy, waitStrategy kube.WaitStrategy, timeout time.Duration) error {
 	for _, h := range hooks {
-		if err := cfg.deleteHookByPolicy(h, policy, timeout); err != nil {
+		if err := cfg.deleteHookByPolicy(h, policy, waitStrategy, timeout); err != nil {
 			return err
 		}
 	}
@@ -199,36 +355,65 @@ func hookHasDeletePolicy(h *release.Hook, policy release.HookDeletePolicy) bool
 	return false
 }
 
-// outputLogsByPolicy outputs a pods logs if the hook policy instructs it to
-func (cfg *Configuration) outputLogsByPolicy(h *release.Hook, releaseNamespace string, policy release.HookOutputLogPolicy) error {
-	if !hookHasOutputLogPolicy(h, policy) {
+// outputLogsByPolicy outputs a pods logs if the hook policy instructs it to, and
+// stores a truncated copy of them in the last run of the hook if capture is set
+func (cfg *Configuration) outputLogsByPolicy(h *release.Hook, releaseNamespace string, policy release.HookOutputLogPolicy, capture bool) error {
+	output := hookHasOutputLogPolicy(h, policy)
+	if !output && !capture {
 		return nil
 	}
 	namespace, err := cfg.deriveNamespace(h, releaseNamespace)
 	if err != nil {
 		return err
 	}
+	var listOptions metav1.ListOptions
 	switch h.Kind {
 	case "Job":
-		return cfg.outputContainerLogsForListOptions(namespace, metav1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", h.Name)})
+		listOptions = metav1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", h.Name)}
 	case "Pod":
-		return cfg.outputContainerLogsForListOptions(namespace, metav1.ListOptions{FieldSelector: fmt.Sprintf("metadata.name=%s", h.Name)})
+		listOptions = metav1.ListOptions{FieldSelector: fmt.Sprintf("metadata.name=%s", h.Name)}
 	default:
 		return nil
 	}
-}
 
-func (cfg *Configuration) outputContainerLogsForListOptions(namespace string, listOptions metav1.ListOptions) error {
 	// TODO Helm 4: Remove this check when GetPodList and OutputContainerLogsForPodList are moved from InterfaceLogs to Interface
-	if kubeClient, ok := cfg.KubeClient.(kube.InterfaceLogs); ok {
-		podList, err := kubeClient.GetPodList(namespace, listOptions)
-		if err != nil {
-			return err
-		}
-		err = kubeClient.OutputContainerLogsForPodList(podList, namespace, cfg.HookOutputFunc)
+	kubeClient, ok := cfg.KubeClient.(kube.InterfaceLogs)
+	if !ok {
+		return nil
+	}
+	podList, err := kubeClient.GetPodList(namespace, listOptions)
+	if err != nil {
 		return err
 	}
-	return nil
+	var logs bytes.Buffer
+	err = kubeClient.OutputContainerLogsForPodList(podList, namespace, func(namespace, pod, container string) io.Writer {
+		fmt.Fprintf(&logs, "==> %s/%s <==\n", pod, container)
+		if !output {
+			return &logs
+		}
+		return io.MultiWriter(&logs, cfg.HookOutputFunc(namespace, pod, container))
+	})
+	if capture {
+		h.LastRun.Logs = truncateHookLogs(logs.String())
+	}
+	return err
+}
+
+// maxHookLogsLength is the length of the hook logs kept in the release record,
+// which must fit in a single Secret or ConfigMap along with the rest of the release.
+const maxHookLogsLength = 8 * 1024
+
+// truncateHookLogs keeps the end of the logs, where the cause of a failure
+// usually is.
+func truncateHookLogs(logs string) string {
+	if len(logs) <= maxHookLogsLength {
+		return logs
+	}
+	logs = logs[len(logs)-maxHookLogsLength:]
+	if i := strings.IndexByte(logs, '\n'); i >= 0 {
+		logs = logs[i+1:]
+	}
+	return "[truncated]\n" + logs
 }
 
 func (cfg *Configuration) deriveNamespace(h *release.Hook, namespace string) (string, error) {
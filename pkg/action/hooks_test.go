@@ -18,9 +18,12 @@ package action
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -175,7 +178,8 @@ func runInstallForHooksWithSuccess(t *testing.T, manifest, expectedNamespace str
 	instAction := installAction(t)
 	instAction.ReleaseName = "failed-hooks"
 	outBuffer := &bytes.Buffer{}
-	instAction.cfg.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard, LogOutput: outBuffer}
+	instAction.cfg.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard}
+	instAction.cfg.HookOutputFunc = func(_, _, _ string) io.Writer { return outBuffer }
 
 	templates := []*chart.File{
 		{Name: "templates/hello", Data: []byte("hello: world")},
@@ -187,6 +191,7 @@ func runInstallForHooksWithSuccess(t *testing.T, manifest, expectedNamespace str
 	is.NoError(err)
 	is.Equal(expectedOutput, outBuffer.String())
 	is.Equal(release.StatusDeployed, res.Info.Status)
+	is.Empty(res.Hooks[0].LastRun.Logs)
 }
 
 func runInstallForHooksWithFailure(t *testing.T, manifest, expectedNamespace string, shouldOutput bool) {
@@ -201,7 +206,8 @@ func runInstallForHooksWithFailure(t *testing.T, manifest, expectedNamespace str
 	failingClient.WatchUntilReadyError = fmt.Errorf("failed watch")
 	instAction.cfg.KubeClient = failingClient
 	outBuffer := &bytes.Buffer{}
-	failingClient.PrintingKubeClient = kubefake.PrintingKubeClient{Out: io.Discard, LogOutput: outBuffer}
+	failingClient.PrintingKubeClient = kubefake.PrintingKubeClient{Out: io.Discard}
+	instAction.cfg.HookOutputFunc = func(_, _, _ string) io.Writer { return outBuffer }
 
 	templates := []*chart.File{
 		{Name: "templates/hello", Data: []byte("hello: world")},
@@ -214,6 +220,81 @@ func runInstallForHooksWithFailure(t *testing.T, manifest, expectedNamespace str
 	is.Contains(res.Info.Description, "failed pre-install")
 	is.Equal(expectedOutput, outBuffer.String())
 	is.Equal(release.StatusFailed, res.Info.Status)
+	// The logs of a failed hook are kept in the release whatever its output log policy
+	is.Contains(res.Hooks[0].LastRun.Logs, "attempted to output logs for namespace")
+}
+
+func TestInstallRelease_CaptureHookLogs(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ReleaseName = "captured-hooks"
+	instAction.cfg.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard}
+	instAction.cfg.CaptureHookLogs = true
+
+	templates := []*chart.File{
+		{Name: "templates/hello", Data: []byte("hello: world")},
+		{Name: "templates/hooks", Data: []byte(jobManifestWithOutputLog([]release.HookOutputLogPolicy{release.HookOutputOnFailed}))},
+	}
+	res, err := instAction.Run(buildChartWithTemplates(templates), map[string]interface{}{})
+	is.NoError(err)
+	is.Equal(release.StatusDeployed, res.Info.Status)
+	is.Equal("==> / <==\nattempted to output logs for namespace: spaced", res.Hooks[0].LastRun.Logs)
+
+	stored, err := instAction.cfg.Releases.Get(res.Name, res.Version)
+	is.NoError(err)
+	is.Equal(res.Hooks[0].LastRun.Logs, stored.Hooks[0].LastRun.Logs)
+}
+
+// hookDeletionKubeClient records whether the deletion of hooks was waited on.
+type hookDeletionKubeClient struct {
+	kubefake.PrintingKubeClient
+	waitedForHookDeletion bool
+}
+
+func (c *hookDeletionKubeClient) GetWaiter(strategy kube.WaitStrategy) (kube.Waiter, error) {
+	waiter, err := c.PrintingKubeClient.GetWaiter(strategy)
+	return &hookDeletionKubeWaiter{Waiter: waiter, client: c}, err
+}
+
+type hookDeletionKubeWaiter struct {
+	kube.Waiter
+	client *hookDeletionKubeClient
+}
+
+func (w *hookDeletionKubeWaiter) WaitForHookDeletion(_ kube.ResourceList, _ time.Duration) error {
+	w.client.waitedForHookDeletion = true
+	return nil
+}
+
+func TestInstallRelease_WaitForHookDeletion(t *testing.T) {
+	for _, wait := range []bool{false, true} {
+		t.Run(fmt.Sprintf("wait=%t", wait), func(t *testing.T) {
+			instAction := installAction(t)
+			client := &hookDeletionKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+			instAction.cfg.KubeClient = client
+			instAction.cfg.WaitForHookDeletion = wait
+			instAction.WaitStrategy = kube.HookOnlyStrategy
+
+			templates := []*chart.File{
+				{Name: "templates/hello", Data: []byte("hello: world")},
+				{Name: "templates/hooks", Data: []byte(jobManifestWithOutputLog(nil))},
+			}
+			_, err := instAction.Run(buildChartWithTemplates(templates), map[string]interface{}{})
+			assert.NoError(t, err)
+			assert.Equal(t, wait, client.waitedForHookDeletion)
+		})
+	}
+}
+
+func TestTruncateHookLogs(t *testing.T) {
+	short := "migrating\ndone\n"
+	assert.Equal(t, short, truncateHookLogs(short))
+
+	long := strings.Repeat("applying migration\n", 1000) + "error: column exists\n"
+	truncated := truncateHookLogs(long)
+	assert.LessOrEqual(t, len(truncated), maxHookLogsLength+len("[truncated]\n"))
+	assert.True(t, strings.HasPrefix(truncated, "[truncated]\napplying migration\n"))
+	assert.True(t, strings.HasSuffix(truncated, "error: column exists\n"))
 }
 
 type HookFailedError struct{}
@@ -243,14 +324,30 @@ func (*HookFailingKubeClient) Build(reader io.Reader, _ bool) (kube.ResourceList
 	}}, nil
 }
 
-func (h *HookFailingKubeClient) WatchUntilReady(resources kube.ResourceList, duration time.Duration) error {
+func (h *HookFailingKubeClient) GetWaiter(strategy kube.WaitStrategy) (kube.Waiter, error) {
+	waiter, err := h.PrintingKubeClient.GetWaiter(strategy)
+	if err != nil {
+		return nil, err
+	}
+	return &HookFailingKubeWaiter{
+		PrintingKubeWaiter: waiter.(*kubefake.PrintingKubeWaiter),
+		failOn:             h.failOn,
+	}, nil
+}
+
+type HookFailingKubeWaiter struct {
+	*kubefake.PrintingKubeWaiter
+	failOn resource.Info
+}
+
+func (w *HookFailingKubeWaiter) WatchUntilReady(resources kube.ResourceList, duration time.Duration) error {
 	for _, res := range resources {
-		if res.Name == h.failOn.Name && res.Namespace == h.failOn.Namespace {
+		if res.Name == w.failOn.Name && res.Namespace == w.failOn.Namespace {
 			return &HookFailedError{}
 		}
 	}
 
-	return h.PrintingKubeClient.WatchUntilReady(resources, duration)
+	return w.PrintingKubeWaiter.WatchUntilReady(resources, duration)
 }
 
 func (h *HookFailingKubeClient) Delete(resources kube.ResourceList) (*kube.Result, []error) {
@@ -369,15 +466,9 @@ data:
 				Releases:     storage.Init(driver.NewMemory()),
 				KubeClient:   kubeClient,
 				Capabilities: chartutil.DefaultCapabilities,
-				Log: func(format string, v ...interface{}) {
-					t.Helper()
-					if *verbose {
-						t.Logf(format, v...)
-					}
-				},
 			}
 
-			err := configuration.execHook(&tc.inputRelease, hookEvent, 600)
+			err := configuration.execHook(context.Background(), &tc.inputRelease, hookEvent, nil, 0, kube.StatusWatcherStrategy, 600)
 
 			if !reflect.DeepEqual(kubeClient.deleteRecord, tc.expectedDeleteRecord) {
 				t.Fatalf("Got unexpected delete record, expected: %#v, but got: %#v", kubeClient.deleteRecord, tc.expectedDeleteRecord)
@@ -393,3 +484,267 @@ data:
 		})
 	}
 }
+
+// concurrentHookKubeClient records how many hooks are watched at the same time.
+type concurrentHookKubeClient struct {
+	HookFailingKubeClient
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+	started    []string
+}
+
+func (c *concurrentHookKubeClient) Delete(resources kube.ResourceList) (*kube.Result, []error) {
+	return c.PrintingKubeClient.Delete(resources)
+}
+
+func (c *concurrentHookKubeClient) GetWaiter(_ kube.WaitStrategy) (kube.Waiter, error) {
+	return &concurrentHookKubeWaiter{PrintingKubeWaiter: &kubefake.PrintingKubeWaiter{Out: io.Discard}, client: c}, nil
+}
+
+type concurrentHookKubeWaiter struct {
+	*kubefake.PrintingKubeWaiter
+	client *concurrentHookKubeClient
+}
+
+func (w *concurrentHookKubeWaiter) WatchUntilReady(resources kube.ResourceList, _ time.Duration) error {
+	c := w.client
+	c.mu.Lock()
+	c.running++
+	c.maxRunning = max(c.maxRunning, c.running)
+	c.started = append(c.started, resources[0].Name)
+	c.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running--
+	if resources[0].Name == c.failOn.Name {
+		return &HookFailedError{}
+	}
+	return nil
+}
+
+func TestExecHook_Concurrency(t *testing.T) {
+	newHook := func(name string, weight int) *release.Hook {
+		return &release.Hook{
+			Name:           name,
+			Kind:           "ConfigMap",
+			Path:           "templates/" + name + ".yaml",
+			Manifest:       fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n", name),
+			Weight:         weight,
+			Events:         []release.HookEvent{release.HookPreInstall},
+			DeletePolicies: []release.HookDeletePolicy{release.HookSucceeded},
+		}
+	}
+	newRelease := func() *release.Release {
+		return &release.Release{
+			Name:      "concurrent-hooks",
+			Namespace: "test",
+			Info:      &release.Info{Status: release.StatusPendingInstall},
+			Hooks: []*release.Hook{
+				newHook("a", 0), newHook("b", 0), newHook("c", 0), newHook("last", 1),
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		concurrency int
+		maxRunning  int
+	}{
+		{concurrency: 0, maxRunning: 1},
+		{concurrency: 2, maxRunning: 2},
+		{concurrency: 5, maxRunning: 3},
+	} {
+		t.Run(fmt.Sprintf("concurrency %d", tc.concurrency), func(t *testing.T) {
+			kubeClient := &concurrentHookKubeClient{HookFailingKubeClient: HookFailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}}
+			cfg := &Configuration{
+				Releases:     storage.Init(driver.NewMemory()),
+				KubeClient:   kubeClient,
+				Capabilities: chartutil.DefaultCapabilities,
+			}
+			rel := newRelease()
+			assert.NoError(t, cfg.Releases.Create(rel))
+
+			assert.NoError(t, cfg.execHook(context.Background(), rel, release.HookPreInstall, nil, tc.concurrency, kube.StatusWatcherStrategy, time.Minute))
+			assert.Equal(t, tc.maxRunning, kubeClient.maxRunning)
+			assert.Equal(t, "last", kubeClient.started[3], "heavier hooks wait for the lighter ones")
+			for _, h := range rel.Hooks {
+				assert.Equal(t, release.HookPhaseSucceeded, h.LastRun.Phase, h.Name)
+			}
+		})
+	}
+
+	t.Run("failure", func(t *testing.T) {
+		kubeClient := &concurrentHookKubeClient{HookFailingKubeClient: HookFailingKubeClient{
+			PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard},
+			failOn:             resource.Info{Name: "b"},
+		}}
+		cfg := &Configuration{
+			Releases:     storage.Init(driver.NewMemory()),
+			KubeClient:   kubeClient,
+			Capabilities: chartutil.DefaultCapabilities,
+		}
+		rel := newRelease()
+		assert.NoError(t, cfg.Releases.Create(rel))
+
+		err := cfg.execHook(context.Background(), rel, release.HookPreInstall, nil, 3, kube.StatusWatcherStrategy, time.Minute)
+		assert.ErrorIs(t, err, &HookFailedError{})
+		phases := map[string]release.HookPhase{}
+		for _, h := range rel.Hooks {
+			phases[h.Name] = h.LastRun.Phase
+		}
+		assert.Equal(t, map[string]release.HookPhase{
+			"a":    release.HookPhaseSucceeded,
+			"b":    release.HookPhaseFailed,
+			"c":    release.HookPhaseSucceeded,
+			"last": "",
+		}, phases)
+	})
+}
+
+// flakyHookKubeClient fails the first watches of the hooks, and records the
+// timeouts they are watched with.
+type flakyHookKubeClient struct {
+	HookFailingKubeClient
+	failures int
+	timeouts []time.Duration
+}
+
+func (c *flakyHookKubeClient) GetWaiter(_ kube.WaitStrategy) (kube.Waiter, error) {
+	return &flakyHookKubeWaiter{PrintingKubeWaiter: &kubefake.PrintingKubeWaiter{Out: io.Discard}, client: c}, nil
+}
+
+type flakyHookKubeWaiter struct {
+	*kubefake.PrintingKubeWaiter
+	client *flakyHookKubeClient
+}
+
+func (w *flakyHookKubeWaiter) WatchUntilReady(_ kube.ResourceList, timeout time.Duration) error {
+	w.client.timeouts = append(w.client.timeouts, timeout)
+	if len(w.client.timeouts) <= w.client.failures {
+		return &HookFailedError{}
+	}
+	return nil
+}
+
+func TestExecHook_TimeoutAndRetries(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		failures    int
+		expectError bool
+		deleted     []string
+	}{
+		{name: "succeeds after retrying", failures: 2, deleted: []string{"migrate", "migrate"}},
+		{name: "fails after running out of retries", failures: 3, expectError: true, deleted: []string{"migrate", "migrate"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := &flakyHookKubeClient{
+				HookFailingKubeClient: HookFailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}},
+				failures:              tc.failures,
+			}
+			cfg := &Configuration{
+				Releases:     storage.Init(driver.NewMemory()),
+				KubeClient:   kubeClient,
+				Capabilities: chartutil.DefaultCapabilities,
+			}
+			rel := &release.Release{
+				Name:      "retried-hooks",
+				Namespace: "test",
+				Info:      &release.Info{Status: release.StatusPendingInstall},
+				Hooks: []*release.Hook{{
+					Name:           "migrate",
+					Kind:           "ConfigMap",
+					Path:           "templates/migrate.yaml",
+					Manifest:       "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: migrate\n",
+					Events:         []release.HookEvent{release.HookPreInstall},
+					DeletePolicies: []release.HookDeletePolicy{release.HookFailed},
+					Timeout:        10 * time.Minute,
+					Retries:        2,
+				}},
+			}
+			assert.NoError(t, cfg.Releases.Create(rel))
+
+			err := cfg.execHook(context.Background(), rel, release.HookPreInstall, nil, 0, kube.StatusWatcherStrategy, time.Minute)
+			expectedPhase := release.HookPhaseSucceeded
+			if tc.expectError {
+				assert.Error(t, err)
+				expectedPhase = release.HookPhaseFailed
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, expectedPhase, rel.Hooks[0].LastRun.Phase)
+			assert.Equal(t, []time.Duration{10 * time.Minute, 10 * time.Minute, 10 * time.Minute}, kubeClient.timeouts)
+
+			// The failed runs are deleted before retrying, and the last one by the hook-failed policy.
+			var deleted []string
+			for _, info := range kubeClient.deleteRecord {
+				deleted = append(deleted, info.Name)
+			}
+			if tc.expectError {
+				tc.deleted = append(tc.deleted, "migrate")
+			}
+			assert.Equal(t, tc.deleted, deleted)
+		})
+	}
+}
+
+// lifecycleKubeClient records when the hooks are created and when the
+// resources of the release are waited for.
+type lifecycleKubeClient struct {
+	HookFailingKubeClient
+	events []string
+}
+
+func (c *lifecycleKubeClient) Build(reader io.Reader, validate bool) (kube.ResourceList, error) {
+	resources, err := c.HookFailingKubeClient.Build(reader, validate)
+	if err != nil || resources[0].Name == "" {
+		// The resources of the release are not needed to follow the hooks.
+		return kube.ResourceList{}, err
+	}
+	return resources, nil
+}
+
+func (c *lifecycleKubeClient) Create(resources kube.ResourceList) (*kube.Result, error) {
+	for _, r := range resources {
+		c.events = append(c.events, r.Name)
+	}
+	return c.PrintingKubeClient.Create(resources)
+}
+
+func (c *lifecycleKubeClient) GetWaiter(_ kube.WaitStrategy) (kube.Waiter, error) {
+	return &lifecycleKubeWaiter{PrintingKubeWaiter: &kubefake.PrintingKubeWaiter{Out: io.Discard}, client: c}, nil
+}
+
+type lifecycleKubeWaiter struct {
+	*kubefake.PrintingKubeWaiter
+	client *lifecycleKubeClient
+}
+
+func (w *lifecycleKubeWaiter) Wait(_ kube.ResourceList, _ time.Duration) error {
+	w.client.events = append(w.client.events, "wait")
+	return nil
+}
+
+func TestInstallRelease_HookLifecycle(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	kubeClient := &lifecycleKubeClient{HookFailingKubeClient: HookFailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}}
+	instAction.cfg.KubeClient = kubeClient
+
+	hook := func(name string, event release.HookEvent) string {
+		return fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n  annotations:\n    \"helm.sh/hook\": %s\n", name, event)
+	}
+	templates := []*chart.File{
+		{Name: "templates/hello", Data: []byte("hello: world")},
+		{Name: "templates/post-install", Data: []byte(hook("post-install", release.HookPostInstall))},
+		{Name: "templates/pre-wait", Data: []byte(hook("pre-wait", release.HookPreWait))},
+		{Name: "templates/pre-install", Data: []byte(hook("pre-install", release.HookPreInstall))},
+		{Name: "templates/post-render", Data: []byte(hook("post-render", release.HookPostRender))},
+	}
+	res, err := instAction.Run(buildChartWithTemplates(templates), map[string]interface{}{})
+	is.NoError(err)
+	is.Equal(release.StatusDeployed, res.Info.Status)
+	is.Equal([]string{"post-render", "pre-install", "pre-wait", "wait", "post-install"}, kubeClient.events)
+}
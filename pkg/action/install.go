@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/url"
 	"os"
 	"path"
@@ -35,8 +36,8 @@ import (
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/resource"
 	"sigs.k8s.io/yaml"
 
@@ -48,6 +49,7 @@ import (
 	"helm.sh/helm/v4/pkg/kube"
 	kubefake "helm.sh/helm/v4/pkg/kube/fake"
 	"helm.sh/helm/v4/pkg/postrender"
+	"helm.sh/helm/v4/pkg/provenance"
 	"helm.sh/helm/v4/pkg/registry"
 	releaseutil "helm.sh/helm/v4/pkg/release/util"
 	release "helm.sh/helm/v4/pkg/release/v1"
@@ -73,8 +75,12 @@ type Install struct {
 	ClientOnly      bool
 	Force           bool
 	CreateNamespace bool
-	DryRun          bool
-	DryRunOption    string
+	// NamespaceLabels and NamespaceAnnotations are added to the namespace
+	// created when CreateNamespace is set.
+	NamespaceLabels      map[string]string
+	NamespaceAnnotations map[string]string
+	DryRun               bool
+	DryRunOption         string
 	// HideSecret can be set to true when DryRun is enabled in order to hide
 	// Kubernetes Secrets in the output. It cannot be used outside of DryRun.
 	HideSecret               bool
@@ -96,6 +102,7 @@ type Install struct {
 	SubNotes                 bool
 	HideNotes                bool
 	SkipSchemaValidation     bool
+	WarnSchemaViolations     bool
 	DisableOpenAPIValidation bool
 	IncludeCRDs              bool
 	Labels                   map[string]string
@@ -104,16 +111,89 @@ type Install struct {
 	// (for things like templating). These are ignored if ClientOnly is false
 	KubeVersion *chartutil.KubeVersion
 	APIVersions chartutil.VersionSet
+	// Capabilities, when set, replace the capabilities of the cluster when
+	// rendering, such as ones saved from a production cluster. They can only
+	// be used with a dry run. KubeVersion and APIVersions still apply on top
+	// of them if ClientOnly is true.
+	Capabilities *chartutil.Capabilities
 	// Used by helm template to render charts with .Release.IsUpgrade. Ignored if Dry-Run is false
 	IsUpgrade bool
 	// Enable DNS lookups when rendering templates
 	EnableDNS bool
+	// CollectErrors renders all the templates of the chart when one fails,
+	// and reports the errors of all the failing templates together.
+	CollectErrors bool
+	// CacheIncludes caches the output of the calls to 'include' with the same
+	// template and arguments while rendering the chart. It must not be set for
+	// charts whose included templates generate random values.
+	CacheIncludes bool
+	// ParallelRender renders the template files of the chart concurrently.
+	// The files are rendered one at a time when a template modifies its
+	// values, or calls 'tpl' or a custom template function.
+	ParallelRender bool
+	// LookupBudget is the number of objects the 'lookup' calls of the
+	// templates may request from the cluster, or unlimited if 0.
+	LookupBudget int
+	// LookupFixtures, when set, are the objects the 'lookup' calls of the
+	// templates find instead of querying the cluster. They can only be used
+	// with a dry run.
+	LookupFixtures []*unstructured.Unstructured
 	// Used by helm template to add the release as part of OutputDir path
 	// OutputDir/<ReleaseName>
 	UseReleaseName bool
-	// TakeOwnership will ignore the check for helm annotations and take ownership of the resources.
+	// TakeOwnership adopts the resources of the chart that already exist,
+	// instead of failing the install, unless they are owned by another
+	// release. The resources created outside of Helm, whatever their
+	// app.kubernetes.io/managed-by label, are adopted, as are the resources
+	// annotated as belonging to this release. The resources whose
+	// meta.helm.sh/release-name or meta.helm.sh/release-namespace annotation
+	// names another release are not. The adopted resources are labeled and
+	// annotated as belonging to the release, and updated to match the chart.
 	TakeOwnership bool
-	PostRenderer  postrender.PostRenderer
+	// PreflightDryRun submits all resources with server-side dry-run before
+	// creating them, so that rejections from validation or admission webhooks
+	// are reported together instead of failing part way through the install.
+	PreflightDryRun bool
+	// DryRunApply submits the resources with server-side dry-run when
+	// DryRunOption is "server", and records the objects the server would
+	// store, as mutated by the admission webhooks, along with every rejection
+	// in the Info.DryRun of the release. The CRDs of the chart and the
+	// namespace are not created by a dry run, so the resources depending on
+	// them are rejected.
+	DryRunApply bool
+	// ServerValidation, on a dry run that is not ClientOnly, submits the
+	// resources with server-side dry-run and fails with every rejection. The
+	// cluster is used to validate the rendered manifests, as it would be by
+	// an install, without anything being created.
+	ServerValidation bool
+	// PreflightChecks are run before the resources are created, and report
+	// all their failures together. The CRDs of the chart are installed before
+	// they run. See DefaultPreflightChecks.
+	PreflightChecks []PreflightCheck
+	// WaitForDependencies applies resources annotated with kube.DependsOnAnno
+	// only once the resources they depend on are ready.
+	WaitForDependencies bool
+	// NamespacePolicy decides how resources outside of the release namespace
+	// are handled, see kube.CheckNamespacePolicy.
+	NamespacePolicy kube.NamespacePolicy
+	// ServerSideApply creates the resources with server-side apply, which
+	// fails on conflicts with the fields owned by other managers unless
+	// ForceConflicts is set. It cannot be combined with Force.
+	ServerSideApply bool
+	ForceConflicts  bool
+	// SkipHooks are the names of the hooks that are not run, while the other
+	// hooks are.
+	SkipHooks []string
+	// HookConcurrency is the number of hooks of the same weight that run at
+	// the same time. Hooks run one at a time when it is 1 or less.
+	HookConcurrency int
+	// RetryCount is the number of times the install is attempted again after
+	// failing with a transient error, such as an unavailable admission
+	// webhook. The resources created by a failed attempt are deleted before
+	// the next one, which waits for RetryBackoff, doubled after every attempt.
+	RetryCount   int
+	RetryBackoff time.Duration
+	PostRenderer postrender.PostRenderer
 	// Lock to control raceconditions when the process receives a SIGTERM
 	Lock sync.Mutex
 }
@@ -133,6 +213,13 @@ type ChartPathOptions struct {
 	Verify                bool   // --verify
 	Version               string // --version
 
+	// CertificateIdentity, CertificateOIDCIssuer and TrustedRoot verify the
+	// keyless Sigstore signature of a chart instead of its provenance file,
+	// when Verify is set and CertificateIdentity is not empty.
+	CertificateIdentity   string // --certificate-identity
+	CertificateOIDCIssuer string // --certificate-oidc-issuer
+	TrustedRoot           string // --trusted-root
+
 	// registryClient provides a registry client but is not added with
 	// options from a flag
 	registryClient *registry.Client
@@ -181,42 +268,7 @@ func (i *Install) installCRDs(crds []chart.CRD) error {
 		totalItems = append(totalItems, res...)
 	}
 	if len(totalItems) > 0 {
-		waiter, err := i.cfg.KubeClient.GetWaiter(i.WaitStrategy)
-		if err != nil {
-			return errors.Wrapf(err, "unable to get waiter")
-		}
-		// Give time for the CRD to be recognized.
-		if err := waiter.Wait(totalItems, 60*time.Second); err != nil {
-			return err
-		}
-
-		// If we have already gathered the capabilities, we need to invalidate
-		// the cache so that the new CRDs are recognized. This should only be
-		// the case when an action configuration is reused for multiple actions,
-		// as otherwise it is later loaded by ourselves when getCapabilities
-		// is called later on in the installation process.
-		if i.cfg.Capabilities != nil {
-			discoveryClient, err := i.cfg.RESTClientGetter.ToDiscoveryClient()
-			if err != nil {
-				return err
-			}
-
-			slog.Debug("clearing discovery cache")
-			discoveryClient.Invalidate()
-
-			_, _ = discoveryClient.ServerGroups()
-		}
-
-		// Invalidate the REST mapper, since it will not have the new CRDs
-		// present.
-		restMapper, err := i.cfg.RESTClientGetter.ToRESTMapper()
-		if err != nil {
-			return err
-		}
-		if resettable, ok := restMapper.(meta.ResettableRESTMapper); ok {
-			slog.Debug("clearing REST mapper cache")
-			resettable.Reset()
-		}
+		return i.cfg.waitForCRDs(totalItems, i.WaitStrategy)
 	}
 	return nil
 }
@@ -235,6 +287,13 @@ func (i *Install) Run(chrt *chart.Chart, vals map[string]interface{}) (*release.
 // When the task is cancelled through ctx, the function returns and the install
 // proceeds in the background.
 func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals map[string]interface{}) (*release.Release, error) {
+	ctx, span := i.cfg.startSpan(ctx, "helm.install", releaseAttributes(i.ReleaseName, i.Namespace, chrt)...)
+	rel, err := i.runWithContext(ctx, chrt, vals)
+	endSpan(span, err)
+	return rel, err
+}
+
+func (i *Install) runWithContext(ctx context.Context, chrt *chart.Chart, vals map[string]interface{}) (*release.Release, error) {
 	// Check reachability of cluster unless in client-only mode (e.g. `helm template` without `--validate`)
 	if !i.ClientOnly {
 		if err := i.cfg.KubeClient.IsReachable(); err != nil {
@@ -248,6 +307,22 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		slog.Error("hiding Kubernetes secrets requires a dry-run mode")
 		return nil, errors.New("Hiding Kubernetes secrets requires a dry-run mode")
 	}
+	if i.DryRunApply && i.DryRunOption != "server" {
+		return nil, errors.New("Applying with dry-run requires the server dry-run mode")
+	}
+	if i.ServerValidation && (!i.isDryRun() || i.ClientOnly) {
+		return nil, errors.New("Server-side validation requires a dry-run against a cluster")
+	}
+	if i.Capabilities != nil && !i.isDryRun() {
+		return nil, errors.New("Custom capabilities can only be used with a dry-run")
+	}
+	if i.LookupFixtures != nil && !i.isDryRun() {
+		return nil, errors.New("Lookup fixtures can only be used with a dry-run")
+	}
+
+	if _, err := i.cfg.serverSideApplyOptions(i.ServerSideApply, i.ForceConflicts, i.Force); err != nil {
+		return nil, err
+	}
 
 	if err := i.availableName(); err != nil {
 		slog.Error("release name check failed", slog.Any("error", err))
@@ -279,6 +354,9 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		// Add mock objects in here so it doesn't use Kube API server
 		// NOTE(bacongobbler): used for `helm template`
 		i.cfg.Capabilities = chartutil.DefaultCapabilities.Copy()
+		if i.Capabilities != nil {
+			i.cfg.Capabilities = i.Capabilities.Copy()
+		}
 		if i.KubeVersion != nil {
 			i.cfg.Capabilities.KubeVersion = *i.KubeVersion
 		}
@@ -291,6 +369,9 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 	} else if !i.ClientOnly && len(i.APIVersions) > 0 {
 		slog.Debug("API Version list given outside of client only mode, this list will be ignored")
 	}
+	if !i.ClientOnly && i.Capabilities != nil {
+		i.cfg.Capabilities = i.Capabilities.Copy()
+	}
 
 	// Make sure if Atomic is set, that wait is set as well. This makes it so
 	// the user doesn't have to specify both
@@ -302,6 +383,7 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 	if err != nil {
 		return nil, err
 	}
+	chartutil.ProcessDependencyCapabilities(chrt, caps)
 
 	// special case for helm template --is-upgrade
 	isUpgrade := i.IsUpgrade && i.isDryRun()
@@ -312,7 +394,7 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		IsInstall: !isUpgrade,
 		IsUpgrade: isUpgrade,
 	}
-	valuesToRender, err := chartutil.ToRenderValuesWithSchemaValidation(chrt, vals, options, caps, i.SkipSchemaValidation)
+	valuesToRender, violations, err := chartutil.ToRenderValuesWithSchemaWarnings(chrt, vals, options, caps, i.SkipSchemaValidation, i.WarnSchemaViolations)
 	if err != nil {
 		return nil, err
 	}
@@ -322,9 +404,20 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 	}
 
 	rel := i.createRelease(chrt, vals, i.Labels)
+	rel.Info.Warnings = schemaWarnings(violations)
 
 	var manifestDoc *bytes.Buffer
-	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.IncludeCRDs, i.PostRenderer, interactWithRemote, i.EnableDNS, i.HideSecret)
+	_, renderSpan := i.cfg.startSpan(ctx, "helm.render")
+	i.cfg.renderStarted(i.ReleaseName, i.Namespace, chrt)
+	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.IncludeCRDs, releasePostRenderer(i.PostRenderer, options, chrt, i.isDryRun()), interactWithRemote, renderOptions{
+		enableDNS:      i.EnableDNS,
+		collectErrors:  i.CollectErrors,
+		cacheIncludes:  i.CacheIncludes,
+		parallel:       i.ParallelRender,
+		lookupBudget:   i.LookupBudget,
+		lookupFixtures: i.LookupFixtures,
+	}, i.HideSecret)
+	endSpan(renderSpan, err)
 	// Even for errors, attach this if available
 	if manifestDoc != nil {
 		rel.Manifest = manifestDoc.String()
@@ -351,6 +444,10 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		return nil, err
 	}
 
+	if err := kube.CheckNamespacePolicy(resources, rel.Namespace, i.NamespacePolicy); err != nil {
+		return nil, err
+	}
+
 	// Install requires an extra validation step of checking that resources
 	// don't already exist before we actually create resources. If we continue
 	// forward and create the release object with resources that already exist,
@@ -358,11 +455,7 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 	// deleting the release because the manifest will be pointing at that
 	// resource
 	if !i.ClientOnly && !isUpgrade && len(resources) > 0 {
-		if i.TakeOwnership {
-			toBeAdopted, err = requireAdoption(resources)
-		} else {
-			toBeAdopted, err = existingResourceConflict(resources, rel.Name, rel.Namespace)
-		}
+		toBeAdopted, err = i.resourcesToAdopt(resources, rel)
 		if err != nil {
 			return nil, errors.Wrap(err, "Unable to continue with install")
 		}
@@ -370,33 +463,38 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 
 	// Bail out here if it is a dry run
 	if i.isDryRun() {
+		if (i.DryRunApply && i.DryRunOption == "server") || i.ServerValidation {
+			slog.Debug("dry-running the apply of the resources", "name", rel.Name)
+			if rel.Info.DryRun, err = i.cfg.dryRunApply(toBeAdopted, resources, i.Force); err != nil {
+				return rel, err
+			}
+			if i.ServerValidation {
+				if err := dryRunRejectionsError(rel.Info.DryRun); err != nil {
+					return rel, err
+				}
+			}
+		}
 		rel.Info.Description = "Dry run complete"
 		return rel, nil
 	}
 
-	if i.CreateNamespace {
-		ns := &v1.Namespace{
-			TypeMeta: metav1.TypeMeta{
-				APIVersion: "v1",
-				Kind:       "Namespace",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name: i.Namespace,
-				Labels: map[string]string{
-					"name": i.Namespace,
-				},
-			},
-		}
-		buf, err := yaml.Marshal(ns)
-		if err != nil {
-			return nil, err
+	if len(i.PreflightChecks) > 0 {
+		req := &PreflightRequest{Release: rel, Current: toBeAdopted, Target: resources}
+		if err := i.cfg.runPreflightChecks(ctx, i.PreflightChecks, req); err != nil {
+			return rel, err
 		}
-		resourceList, err := i.cfg.KubeClient.Build(bytes.NewBuffer(buf), true)
-		if err != nil {
+	}
+
+	if i.CreateNamespace {
+		if err := i.createNamespace(); err != nil {
 			return nil, err
 		}
-		if _, err := i.cfg.KubeClient.Create(resourceList); err != nil && !apierrors.IsAlreadyExists(err) {
-			return nil, err
+	}
+
+	if i.PreflightDryRun {
+		slog.Debug("running preflight dry-run", "name", rel.Name)
+		if err := i.cfg.preflightDryRun(toBeAdopted, resources, i.Force); err != nil {
+			return rel, err
 		}
 	}
 
@@ -409,18 +507,74 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 
 	// Store the release in history before continuing (new in Helm 3). We always know
 	// that this is a create operation.
-	if err := i.cfg.Releases.Create(rel); err != nil {
+	if err := i.cfg.storeNewRelease(rel); err != nil {
 		// We could try to recover gracefully here, but since nothing has been installed
 		// yet, this is probably safer than trying to continue when we know storage is
 		// not working.
 		return rel, err
 	}
 
-	rel, err = i.performInstallCtx(ctx, rel, toBeAdopted, resources)
+	return i.performInstallWithRetries(ctx, rel, toBeAdopted, resources)
+}
+
+// performInstallWithRetries performs the install, attempting it again up to
+// RetryCount times when it fails with a transient error. The release is only
+// failed, and uninstalled when Atomic is set, once no attempt is left, so that
+// an attempt never runs after the record of the release was deleted.
+func (i *Install) performInstallWithRetries(ctx context.Context, rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
+	backoff := i.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		result, err := i.performInstallCtx(ctx, rel, toBeAdopted, resources)
+		if err == nil {
+			return result, nil
+		}
+		if attempt > i.RetryCount || !isTransientError(err) || ctx.Err() != nil {
+			return i.failRelease(result, err)
+		}
+		slog.Warn("install failed with a transient error, retrying", "release", rel.Name, "attempt", attempt, "retries", i.RetryCount, "backoff", backoff, slog.Any("error", err))
+
+		rel.SetStatus(release.StatusPendingInstall, fmt.Sprintf("Retrying install after attempt %d failed: %s", attempt, err))
+		if recordErr := i.recordRelease(rel); recordErr != nil {
+			slog.Debug("failed to record the release", "release", rel.Name, slog.Any("error", recordErr))
+		}
+		if cleanupErr := i.cleanupFailedAttempt(toBeAdopted, resources); cleanupErr != nil {
+			return i.failRelease(rel, errors.Wrapf(cleanupErr, "failed to clean up after install attempt %d. original install error: %s", attempt, err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return i.failRelease(rel, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// cleanupFailedAttempt deletes the resources created by a failed install
+// attempt, leaving the adopted resources alone, and waits for them to be
+// removed so that the next attempt can create them again.
+func (i *Install) cleanupFailedAttempt(toBeAdopted kube.ResourceList, resources kube.ResourceList) error {
+	created := resources.Difference(toBeAdopted)
+	if len(created) == 0 {
+		return nil
+	}
+	_, errs := i.cfg.KubeClient.Delete(created)
+	for _, err := range errs {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	// The hook-only waiter does not wait for deletions on its own.
+	strategy := i.WaitStrategy
+	if strategy == kube.HookOnlyStrategy {
+		strategy = kube.StatusWatcherStrategy
+	}
+	waiter, err := i.cfg.KubeClient.GetWaiter(strategy)
 	if err != nil {
-		rel, err = i.failRelease(rel, err)
+		return fmt.Errorf("failed to get waiter: %w", err)
 	}
-	return rel, err
+	return waiter.WaitForDelete(created, i.Timeout)
 }
 
 func (i *Install) performInstallCtx(ctx context.Context, rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
@@ -431,7 +585,7 @@ func (i *Install) performInstallCtx(ctx context.Context, rel *release.Release, t
 	resultChan := make(chan Msg, 1)
 
 	go func() {
-		rel, err := i.performInstall(rel, toBeAdopted, resources)
+		rel, err := i.performInstall(ctx, rel, toBeAdopted, resources)
 		resultChan <- Msg{rel, err}
 	}()
 	select {
@@ -451,11 +605,30 @@ func (i *Install) isDryRun() bool {
 	return false
 }
 
-func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
-	var err error
+// resourcesToAdopt returns the resources of the release that already exist,
+// and can be adopted by it.
+func (i *Install) resourcesToAdopt(resources kube.ResourceList, rel *release.Release) (kube.ResourceList, error) {
+	if i.TakeOwnership {
+		return adoptableResources(resources, rel.Name, rel.Namespace)
+	}
+	return existingResourceConflict(resources, rel.Name, rel.Namespace)
+}
+
+func (i *Install) performInstall(ctx context.Context, rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
+	applyOpts, err := i.cfg.serverSideApplyOptions(i.ServerSideApply, i.ForceConflicts, i.Force)
+	if err != nil {
+		return rel, err
+	}
+	// post-render hooks
+	if !i.DisableHooks {
+		if err := i.cfg.execHook(ctx, rel, release.HookPostRender, i.SkipHooks, i.HookConcurrency, i.WaitStrategy, i.Timeout); err != nil {
+			return rel, fmt.Errorf("failed post-render: %s", err)
+		}
+	}
+
 	// pre-install hooks
 	if !i.DisableHooks {
-		if err := i.cfg.execHook(rel, release.HookPreInstall, i.WaitStrategy, i.Timeout); err != nil {
+		if err := i.cfg.execHook(ctx, rel, release.HookPreInstall, i.SkipHooks, i.HookConcurrency, i.WaitStrategy, i.Timeout); err != nil {
 			return rel, fmt.Errorf("failed pre-install: %s", err)
 		}
 	}
@@ -463,31 +636,41 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 	// At this point, we can do the install. Note that before we were detecting whether to
 	// do an update, but it's not clear whether we WANT to do an update if the reuse is set
 	// to true, since that is basically an upgrade operation.
-	if len(toBeAdopted) == 0 && len(resources) > 0 {
-		_, err = i.cfg.KubeClient.Create(resources)
+	apply := i.cfg.startApply(ctx, resources)
+	if i.WaitForDependencies && len(resources) > 0 {
+		_, err = i.cfg.applyInDependencyOrder(toBeAdopted, resources, i.Force, i.WaitStrategy, i.Timeout, append(applyOpts, apply.option(), i.cfg.appliedOption(rel))...)
+	} else if len(toBeAdopted) == 0 && len(resources) > 0 && len(applyOpts) == 0 {
+		if _, err = i.cfg.KubeClient.Create(resources); err == nil {
+			for _, info := range resources {
+				i.cfg.resourceApplied(rel, info)
+			}
+		}
 	} else if len(resources) > 0 {
-		_, err = i.cfg.KubeClient.Update(toBeAdopted, resources, i.Force)
+		_, err = i.cfg.updateResources(toBeAdopted, resources, i.Force, append(applyOpts, apply.option(), i.cfg.appliedOption(rel))...)
 	}
+	apply.end(err)
 	if err != nil {
 		return rel, err
 	}
 
+	// pre-wait hooks
+	if !i.DisableHooks {
+		if err := i.cfg.execHook(ctx, rel, release.HookPreWait, i.SkipHooks, i.HookConcurrency, i.WaitStrategy, i.Timeout); err != nil {
+			return rel, fmt.Errorf("failed pre-wait: %s", err)
+		}
+	}
+
 	waiter, err := i.cfg.KubeClient.GetWaiter(i.WaitStrategy)
 	if err != nil {
 		return rel, fmt.Errorf("failed to get waiter: %w", err)
 	}
 
-	if i.WaitForJobs {
-		err = waiter.WaitWithJobs(resources, i.Timeout)
-	} else {
-		err = waiter.Wait(resources, i.Timeout)
-	}
-	if err != nil {
+	if err := i.cfg.waitForResources(ctx, rel, waiter, resources, i.WaitForJobs, i.Timeout); err != nil {
 		return rel, err
 	}
 
 	if !i.DisableHooks {
-		if err := i.cfg.execHook(rel, release.HookPostInstall, i.WaitStrategy, i.Timeout); err != nil {
+		if err := i.cfg.execHook(ctx, rel, release.HookPostInstall, i.SkipHooks, i.HookConcurrency, i.WaitStrategy, i.Timeout); err != nil {
 			return rel, fmt.Errorf("failed post-install: %s", err)
 		}
 	}
@@ -583,7 +766,46 @@ func (i *Install) createRelease(chrt *chart.Chart, rawVals map[string]interface{
 func (i *Install) recordRelease(r *release.Release) error {
 	// This is a legacy function which has been reduced to a oneliner. Could probably
 	// refactor it out.
-	return i.cfg.Releases.Update(r)
+	return i.cfg.storeRelease(r)
+}
+
+// createNamespace creates the release namespace with the configured labels
+// and annotations.
+func (i *Install) createNamespace() error {
+	options := kube.NamespaceOptions{
+		Labels:      i.NamespaceLabels,
+		Annotations: i.NamespaceAnnotations,
+	}
+	// TODO Helm 4: Remove this check when CreateNamespace is moved from InterfaceNamespaces to Interface
+	if kubeClient, ok := i.cfg.KubeClient.(kube.InterfaceNamespaces); ok {
+		return kubeClient.CreateNamespace(i.Namespace, options)
+	}
+
+	labels := map[string]string{"name": i.Namespace}
+	maps.Copy(labels, options.Labels)
+	ns := &v1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        i.Namespace,
+			Labels:      labels,
+			Annotations: options.Annotations,
+		},
+	}
+	buf, err := yaml.Marshal(ns)
+	if err != nil {
+		return err
+	}
+	resourceList, err := i.cfg.KubeClient.Build(bytes.NewBuffer(buf), true)
+	if err != nil {
+		return err
+	}
+	if _, err := i.cfg.KubeClient.Create(resourceList); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
 }
 
 // replaceRelease replaces an older release with this one
@@ -738,9 +960,117 @@ OUTER:
 	if len(missing) > 0 {
 		return errors.Errorf("found in Chart.yaml, but missing in charts/ directory: %s", strings.Join(missing, ", "))
 	}
+	return checkDependencyDigests(ch, reqs)
+}
+
+// checkDependencyDigests verifies that every dependency pinned to a digest in
+// Chart.yaml was resolved to that digest in the chart's lock file.
+func checkDependencyDigests(ch *chart.Chart, reqs []*chart.Dependency) error {
+	var unverified []string
+
+OUTER:
+	for _, r := range reqs {
+		if r.Digest == "" {
+			continue
+		}
+		if ch.Lock != nil {
+			for _, l := range ch.Lock.Dependencies {
+				if l.Name == r.Name && l.Repository == r.Repository && l.Digest == r.Digest {
+					continue OUTER
+				}
+			}
+		}
+		unverified = append(unverified, fmt.Sprintf("%s@%s", r.Name, r.Digest))
+	}
+
+	if len(unverified) > 0 {
+		return errors.Errorf("pinned in Chart.yaml, but not locked to the same digest in Chart.lock: %s", strings.Join(unverified, ", "))
+	}
 	return nil
 }
 
+// VerifyDependencyContents checks the archives in the charts/ directory of the
+// chart directory at chartPath against the content digests recorded in the
+// chart's lock file. Packaged charts are not checked.
+func VerifyDependencyContents(chartPath string, ch *chart.Chart) error {
+	if ch.Lock == nil {
+		return nil
+	}
+	if fi, err := os.Stat(chartPath); err != nil || !fi.IsDir() {
+		return nil
+	}
+
+	archives, err := filepath.Glob(filepath.Join(chartPath, "charts", "*.tgz"))
+	if err != nil {
+		return err
+	}
+	sums := make(map[string]bool, len(archives))
+	for _, archive := range archives {
+		sum, err := provenance.DigestFile(archive)
+		if err != nil {
+			return errors.Wrapf(err, "could not compute the digest of %s", archive)
+		}
+		sums["sha256:"+sum] = true
+	}
+
+	var mismatched []string
+	for _, d := range ch.Lock.Dependencies {
+		if d.ContentDigest != "" && !sums[d.ContentDigest] {
+			mismatched = append(mismatched, d.Name)
+		}
+	}
+
+	if len(mismatched) > 0 {
+		return errors.Errorf("no archive in charts/ directory matches the content digest recorded in the lock file for: %s", strings.Join(mismatched, ", "))
+	}
+	return nil
+}
+
+// sigstoreOptions returns the constraints keyless signatures are verified
+// against, or nil if charts are verified against the keyring.
+func (c *ChartPathOptions) sigstoreOptions() *provenance.SigstoreOptions {
+	if c.CertificateIdentity == "" {
+		return nil
+	}
+	return &provenance.SigstoreOptions{
+		TrustedRoot:           c.TrustedRoot,
+		CertificateIdentity:   c.CertificateIdentity,
+		CertificateOIDCIssuer: c.CertificateOIDCIssuer,
+	}
+}
+
+// verifyChart verifies the signature of a local chart archive.
+func (c *ChartPathOptions) verifyChart(path string) (*provenance.Verification, error) {
+	if opts := c.sigstoreOptions(); opts != nil {
+		return downloader.VerifyChartSigstore(path, *opts)
+	}
+	return downloader.VerifyChart(path, c.Keyring)
+}
+
+// ValuesGetterOptions returns the getter options to fetch the remote values
+// files of the chart named chartRef with. The values files are fetched with
+// the TLS settings and registry client of the chart. The credentials are
+// only sent to the host of the chart repository, or of the chart URL, unless
+// PassCredentialsAll is set.
+func (c *ChartPathOptions) ValuesGetterOptions(chartRef string) []getter.Option {
+	credentialsURL := c.RepoURL
+	if credentialsURL == "" {
+		credentialsURL = chartRef
+	}
+	opts := []getter.Option{
+		getter.WithURL(credentialsURL),
+		getter.WithPassCredentialsAll(c.PassCredentialsAll),
+		getter.WithTLSClientConfig(c.CertFile, c.KeyFile, c.CaFile),
+		getter.WithInsecureSkipVerifyTLS(c.InsecureSkipTLSverify),
+		getter.WithPlainHTTP(c.PlainHTTP),
+		getter.WithBasicAuth(c.Username, c.Password),
+	}
+	if c.registryClient != nil {
+		opts = append(opts, getter.WithRegistryClient(c.registryClient))
+	}
+	return opts
+}
+
 // LocateChart looks for a chart directory in known places, and returns either the full path or an error.
 //
 // This does not ensure that the chart is well-formed; only that the requested filename exists.
@@ -765,7 +1095,7 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 			return abs, err
 		}
 		if c.Verify {
-			if _, err := downloader.VerifyChart(abs, c.Keyring); err != nil {
+			if _, err := c.verifyChart(abs); err != nil {
 				return "", err
 			}
 		}
@@ -776,9 +1106,10 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 	}
 
 	dl := downloader.ChartDownloader{
-		Out:     os.Stdout,
-		Keyring: c.Keyring,
-		Getters: getter.All(settings),
+		Out:      os.Stdout,
+		Keyring:  c.Keyring,
+		Sigstore: c.sigstoreOptions(),
+		Getters:  getter.All(settings),
 		Options: []getter.Option{
 			getter.WithPassCredentialsAll(c.PassCredentialsAll),
 			getter.WithTLSClientConfig(c.CertFile, c.KeyFile, c.CaFile),
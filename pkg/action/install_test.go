@@ -19,6 +19,7 @@ package action
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -35,8 +36,11 @@ import (
 	"helm.sh/helm/v4/internal/test"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/engine"
 	"helm.sh/helm/v4/pkg/kube"
 	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	"helm.sh/helm/v4/pkg/postrender"
+	"helm.sh/helm/v4/pkg/provenance"
 	release "helm.sh/helm/v4/pkg/release/v1"
 	"helm.sh/helm/v4/pkg/storage/driver"
 	helmtime "helm.sh/helm/v4/pkg/time"
@@ -176,6 +180,58 @@ func TestInstallRelease_WithNotes(t *testing.T) {
 	is.Equal(rel.Info.Notes, "note here")
 }
 
+func TestInstallRelease_WarnSchemaViolations(t *testing.T) {
+	is := assert.New(t)
+	withSchema := func(opts *chartOptions) {
+		opts.Schema = []byte(`{"properties": {"replicas": {"type": "integer", "minimum": 1}}}`)
+	}
+	vals := map[string]interface{}{"replicas": 0}
+
+	instAction := installAction(t)
+	_, err := instAction.Run(buildChart(withSchema), vals)
+	is.ErrorContains(err, "values don't meet the specifications of the schema(s)")
+
+	instAction = installAction(t)
+	instAction.WarnSchemaViolations = true
+	res, err := instAction.Run(buildChart(withSchema), vals)
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+
+	rel, err := instAction.cfg.Releases.Get(res.Name, res.Version)
+	is.NoError(err)
+	is.Equal([]release.Warning{{Chart: "hello", Path: "/replicas", Message: "minimum: got 0, want 1"}}, rel.Info.Warnings)
+}
+
+func TestInstallRelease_CapabilityDependency(t *testing.T) {
+	is := assert.New(t)
+	const gateway = "gateway.networking.k8s.io/v1/Gateway"
+	newChart := func() *chart.Chart {
+		return buildChart(
+			withName("parent"),
+			withDependency(withName("gateway")),
+			withMetadataDependency(chart.Dependency{Name: "gateway", Version: "0.1.0", Capabilities: []string{gateway}}),
+		)
+	}
+
+	instAction := installAction(t)
+	instAction.ClientOnly = true
+	res, err := instAction.Run(newChart(), nil)
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+	is.NotContains(res.Manifest, "# Source: parent/charts/gateway/")
+
+	instAction = installAction(t)
+	instAction.ClientOnly = true
+	instAction.APIVersions = []string{gateway}
+	res, err = instAction.Run(newChart(), nil)
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+	is.Contains(res.Manifest, "# Source: parent/charts/gateway/templates/hello")
+}
+
 func TestInstallRelease_WithNotesRendered(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -333,6 +389,68 @@ func TestInstallReleaseIncorrectTemplate_DryRun(t *testing.T) {
 	}
 }
 
+func TestInstallRelease_CollectErrors(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.DryRun = true
+	instAction.CollectErrors = true
+	templates := []*chart.File{
+		{Name: "templates/a", Data: []byte("{{ .Values.a.b }}")},
+		{Name: "templates/b", Data: []byte("b: {{ fail \"b is broken\" }}")},
+		{Name: "templates/c", Data: []byte("c: ok")},
+	}
+	_, err := instAction.Run(buildChartWithTemplates(templates), map[string]interface{}{})
+	is.Error(err)
+
+	var renderErrs engine.RenderErrors
+	is.True(errors.As(err, &renderErrs), "expected RenderErrors, got %T", err)
+	is.Len(renderErrs, 2)
+	is.Contains(err.Error(), "nil pointer evaluating interface {}.b")
+	is.Contains(err.Error(), "b is broken")
+}
+
+func TestInstallRelease_CacheIncludes(t *testing.T) {
+	templates := []*chart.File{
+		{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "token" }}{{ randAlphaNum 16 }}{{ end }}`)},
+		{Name: "templates/token", Data: []byte(`first: {{ include "token" . }}
+second: {{ include "token" . }}`)},
+	}
+	for _, cache := range []bool{false, true} {
+		t.Run(fmt.Sprintf("cache=%t", cache), func(t *testing.T) {
+			instAction := installAction(t)
+			instAction.DryRun = true
+			instAction.CacheIncludes = cache
+			res, err := instAction.Run(buildChartWithTemplates(templates), map[string]interface{}{})
+			require.NoError(t, err)
+
+			_, tokens, _ := strings.Cut(res.Manifest, "first: ")
+			first, second, _ := strings.Cut(strings.TrimSpace(tokens), "\nsecond: ")
+			assert.Equal(t, cache, first == second, "first: %s, second: %s", first, second)
+		})
+	}
+}
+
+func TestInstallRelease_ParallelRender(t *testing.T) {
+	var templates []*chart.File
+	for i := range 20 {
+		templates = append(templates, &chart.File{
+			Name: fmt.Sprintf("templates/cm%02d", i),
+			Data: []byte(fmt.Sprintf("name: cm%02d\nrelease: {{ .Release.Name }}", i)),
+		})
+	}
+	manifests := map[bool]string{}
+	for _, parallel := range []bool{false, true} {
+		instAction := installAction(t)
+		instAction.DryRun = true
+		instAction.ParallelRender = parallel
+		res, err := instAction.Run(buildChartWithTemplates(templates), map[string]interface{}{})
+		require.NoError(t, err)
+		manifests[parallel] = res.Manifest
+	}
+	assert.Contains(t, manifests[true], "name: cm07\nrelease: test-install-release")
+	assert.Equal(t, manifests[false], manifests[true])
+}
+
 func TestInstallRelease_NoHooks(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -349,6 +467,26 @@ func TestInstallRelease_NoHooks(t *testing.T) {
 	is.True(res.Hooks[0].LastRun.CompletedAt.IsZero(), "hooks should not run with no-hooks")
 }
 
+func TestInstallRelease_SkipHooks(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	instAction := installAction(t)
+	instAction.ReleaseName = "skip-hooks"
+	instAction.SkipHooks = []string{"test-cm"}
+	res, err := instAction.Run(buildChart(), map[string]interface{}{})
+	req.NoError(err)
+	req.Len(res.Hooks, 1)
+	is.True(res.Hooks[0].LastRun.CompletedAt.IsZero(), "skipped hooks should not run")
+
+	instAction = installAction(t)
+	instAction.ReleaseName = "other-hooks"
+	instAction.SkipHooks = []string{"other-hook"}
+	res, err = instAction.Run(buildChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(release.HookPhaseSucceeded, res.Hooks[0].LastRun.Phase, "hooks that are not skipped should run")
+}
+
 func TestInstallRelease_FailedHooks(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -357,7 +495,8 @@ func TestInstallRelease_FailedHooks(t *testing.T) {
 	failer.WatchUntilReadyError = fmt.Errorf("Failed watch")
 	instAction.cfg.KubeClient = failer
 	outBuffer := &bytes.Buffer{}
-	failer.PrintingKubeClient = kubefake.PrintingKubeClient{Out: io.Discard, LogOutput: outBuffer}
+	failer.PrintingKubeClient = kubefake.PrintingKubeClient{Out: io.Discard}
+	instAction.cfg.HookOutputFunc = func(_, _, _ string) io.Writer { return outBuffer }
 
 	vals := map[string]interface{}{}
 	res, err := instAction.Run(buildChart(), vals)
@@ -367,6 +506,234 @@ func TestInstallRelease_FailedHooks(t *testing.T) {
 	is.Equal(release.StatusFailed, res.Info.Status)
 }
 
+func TestInstallRelease_PreflightDryRun(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ReleaseName = "preflight-dry-run"
+	instAction.PreflightDryRun = true
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.DryRunApplyError = fmt.Errorf("admission webhook denied the request")
+	instAction.cfg.KubeClient = failer
+
+	vals := map[string]interface{}{}
+	_, err := instAction.Run(buildChart(), vals)
+	is.Error(err)
+	is.Contains(err.Error(), "preflight dry-run failed")
+	is.Contains(err.Error(), "admission webhook denied the request")
+
+	// Nothing was applied, so no release should have been recorded.
+	_, err = instAction.cfg.Releases.Get(instAction.ReleaseName, 1)
+	is.Error(err)
+}
+
+func TestInstallRelease_DryRunApply(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ReleaseName = "dry-run-apply"
+	instAction.DryRunOption = "server"
+	instAction.DryRunApply = true
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.DryRunApplyError = &kube.AggregateError{Errs: []error{&kube.ResourceError{
+		Info: newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "settings"),
+		Err:  fmt.Errorf("admission webhook denied the request"),
+	}}}
+	instAction.cfg.KubeClient = failer
+
+	res, err := instAction.Run(buildChart(), map[string]interface{}{})
+	is.NoError(err)
+	is.Equal("Dry run complete", res.Info.Description)
+	is.Equal([]release.DryRunRejection{{
+		Kind:      "ConfigMap",
+		Namespace: "spaced",
+		Name:      "settings",
+		Reason:    "admission webhook denied the request",
+	}}, res.Info.DryRun.Rejections)
+
+	instAction.DryRunOption = "client"
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.EqualError(err, "Applying with dry-run requires the server dry-run mode")
+}
+
+func TestInstallRelease_ServerValidation(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ReleaseName = "server-validation"
+	instAction.DryRun = true
+	instAction.ServerValidation = true
+
+	res, err := instAction.Run(buildChart(), map[string]interface{}{})
+	is.NoError(err)
+	is.Equal("Dry run complete", res.Info.Description)
+
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.DryRunApplyError = &kube.AggregateError{Errs: []error{&kube.ResourceError{
+		Info: newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "settings"),
+		Err:  fmt.Errorf("admission webhook denied the request"),
+	}}}
+	instAction.cfg.KubeClient = failer
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.EqualError(err, "the cluster rejected 1 resource(s) in server-side dry-run:\n  ConfigMap spaced/settings: admission webhook denied the request")
+
+	// Nothing was created, so no release should have been recorded.
+	_, err = instAction.cfg.Releases.Get(instAction.ReleaseName, 1)
+	is.Error(err)
+
+	instAction.ClientOnly = true
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.EqualError(err, "Server-side validation requires a dry-run against a cluster")
+}
+
+type recordingPostRenderer struct {
+	rel *postrender.Release
+}
+
+func (r *recordingPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return renderedManifests, nil
+}
+
+func (r *recordingPostRenderer) RunForRelease(renderedManifests *bytes.Buffer, rel *postrender.Release) (*bytes.Buffer, error) {
+	r.rel = rel
+	return renderedManifests, nil
+}
+
+func TestInstallRelease_PostRendererRelease(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ReleaseName = "post-rendered"
+	instAction.DryRun = true
+	pr := &recordingPostRenderer{}
+	instAction.PostRenderer = postrender.NewChain(pr)
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	is.NoError(err)
+	is.Equal(&postrender.Release{
+		Name:      "post-rendered",
+		Namespace: "spaced",
+		Revision:  1,
+		IsInstall: true,
+		DryRun:    true,
+		Chart:     buildChart().Metadata,
+	}, pr.rel)
+}
+
+func TestInstallRelease_CreateNamespace(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ReleaseName = "create-namespace"
+	instAction.CreateNamespace = true
+	instAction.NamespaceLabels = map[string]string{"pod-security.kubernetes.io/enforce": "restricted"}
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.CreateNamespaceError = fmt.Errorf("namespaces is forbidden")
+	instAction.cfg.KubeClient = failer
+
+	vals := map[string]interface{}{}
+	_, err := instAction.Run(buildChart(), vals)
+	is.Error(err)
+	is.Contains(err.Error(), "namespaces is forbidden")
+}
+
+func TestInstallRelease_ServerSideApply(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ServerSideApply = true
+	instAction.ForceConflicts = true
+
+	vals := map[string]interface{}{}
+	_, err := instAction.Run(buildChart(), vals)
+	is.NoError(err)
+
+	instAction.ReleaseName = "force-conflicts"
+	instAction.Force = true
+	_, err = instAction.Run(buildChart(), vals)
+	is.EqualError(err, "--force cannot be used with --server-side")
+
+	instAction.ServerSideApply = false
+	instAction.Force = false
+	_, err = instAction.Run(buildChart(), vals)
+	is.EqualError(err, "--force-conflicts only works with --server-side")
+}
+
+// flakyKubeClient fails the first waits of an install with err.
+type flakyKubeClient struct {
+	kubefake.FailingKubeClient
+	err      error
+	failures int
+	attempts int
+}
+
+func (c *flakyKubeClient) GetWaiter(strategy kube.WaitStrategy) (kube.Waiter, error) {
+	waiter, err := c.FailingKubeClient.GetWaiter(strategy)
+	return &flakyWaiter{Waiter: waiter, client: c}, err
+}
+
+type flakyWaiter struct {
+	kube.Waiter
+	client *flakyKubeClient
+}
+
+func (w *flakyWaiter) Wait(resources kube.ResourceList, timeout time.Duration) error {
+	w.client.attempts++
+	if w.client.attempts <= w.client.failures {
+		return w.client.err
+	}
+	return w.Waiter.Wait(resources, timeout)
+}
+
+func TestInstallRelease_Retries(t *testing.T) {
+	transient := fmt.Errorf("Internal error occurred: etcdserver: leader changed")
+
+	tests := []struct {
+		name         string
+		err          error
+		failures     int
+		retries      int
+		atomic       bool
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "transient error", err: transient, failures: 2, retries: 2, wantAttempts: 3},
+		{name: "retries exhausted", err: transient, failures: 3, retries: 1, wantAttempts: 2, wantErr: true},
+		{name: "atomic transient error", err: transient, failures: 1, retries: 1, atomic: true, wantAttempts: 2},
+		{name: "atomic retries exhausted", err: transient, failures: 3, retries: 1, atomic: true, wantAttempts: 2, wantErr: true},
+		{name: "permanent error", err: fmt.Errorf("Deployment.apps \"web\" is invalid"), failures: 1, retries: 2, wantAttempts: 1, wantErr: true},
+		{name: "no retries", err: transient, failures: 1, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := assert.New(t)
+			instAction := installAction(t)
+			client := &flakyKubeClient{
+				FailingKubeClient: kubefake.FailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}},
+				err:               tt.err,
+				failures:          tt.failures,
+			}
+			instAction.cfg.KubeClient = client
+			instAction.RetryCount = tt.retries
+			instAction.RetryBackoff = time.Millisecond
+			instAction.Atomic = tt.atomic
+
+			res, err := instAction.Run(buildChart(), map[string]interface{}{})
+			is.Equal(tt.wantAttempts, client.attempts)
+			if tt.wantErr {
+				is.ErrorIs(err, tt.err)
+				if tt.atomic {
+					is.ErrorContains(err, "has been uninstalled due to atomic being set")
+					_, err := instAction.cfg.Releases.Get(res.Name, res.Version)
+					is.ErrorIs(err, driver.ErrReleaseNotFound)
+					return
+				}
+				is.Equal(release.StatusFailed, res.Info.Status)
+				return
+			}
+			is.NoError(err)
+			is.Equal(release.StatusDeployed, res.Info.Status)
+			stored, err := instAction.cfg.Releases.Get(res.Name, res.Version)
+			is.NoError(err)
+			is.Equal(release.StatusDeployed, stored.Info.Status)
+		})
+	}
+}
+
 func TestInstallRelease_ReplaceRelease(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -405,6 +772,40 @@ func TestInstallRelease_KubeVersion(t *testing.T) {
 	is.Contains(err.Error(), "chart requires kubeVersion")
 }
 
+func TestInstallRelease_Capabilities(t *testing.T) {
+	is := assert.New(t)
+	tmpl := []*chart.File{
+		{Name: "templates/caps", Data: []byte(`kube: {{ .Capabilities.KubeVersion }}
+monitoring: {{ .Capabilities.APIVersions.Has "monitoring.coreos.com/v1" }}
+apps: {{ .Capabilities.APIVersions.Has "apps/v1" }}`)},
+	}
+	caps := chartutil.DefaultCapabilities.Copy()
+	caps.KubeVersion = chartutil.KubeVersion{Version: "v1.29.4", Major: "1", Minor: "29"}
+	caps.APIVersions = chartutil.VersionSet{"v1", "monitoring.coreos.com/v1"}
+
+	instAction := installAction(t)
+	instAction.ClientOnly = true
+	instAction.DryRun = true
+	instAction.Capabilities = caps
+	instAction.APIVersions = chartutil.VersionSet{"apps/v1"}
+	res, err := instAction.Run(buildChartWithTemplates(tmpl, withKube(">=1.29.0")), map[string]interface{}{})
+	is.NoError(err)
+	is.Contains(res.Manifest, "kube: v1.29.4\nmonitoring: true\napps: true")
+
+	// The capabilities replace the ones of the cluster on a dry run.
+	instAction = installAction(t)
+	instAction.DryRun = true
+	instAction.Capabilities = caps
+	res, err = instAction.Run(buildChartWithTemplates(tmpl), map[string]interface{}{})
+	is.NoError(err)
+	is.Contains(res.Manifest, "kube: v1.29.4\nmonitoring: true\napps: false")
+
+	instAction = installAction(t)
+	instAction.Capabilities = caps
+	_, err = instAction.Run(buildChartWithTemplates(tmpl), map[string]interface{}{})
+	is.EqualError(err, "Custom capabilities can only be used with a dry-run")
+}
+
 func TestInstallRelease_Wait(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -808,3 +1209,40 @@ func TestInstallWithSystemLabels(t *testing.T) {
 
 	is.Equal(fmt.Errorf("user supplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels()), err)
 }
+
+func TestCheckDependenciesDigest(t *testing.T) {
+	is := assert.New(t)
+	const digest = "sha256:6f1a8a35ea8b1d5ea4b5c0d16e0b1b9ad1e28a8e1e7e5a2cba6a8d1c64bbe3b4"
+	dep := chart.Dependency{Name: "hello", Repository: "oci://example.com/charts", Version: "0.1.0", Digest: digest}
+	ch := buildChart(withName("parent"), withDependency(), withMetadataDependency(dep))
+
+	err := CheckDependencies(ch, ch.Metadata.Dependencies)
+	is.ErrorContains(err, "not locked to the same digest in Chart.lock: hello@"+digest)
+
+	locked := dep
+	locked.Digest = "sha256:" + strings.Repeat("0", 64)
+	ch.Lock = &chart.Lock{Dependencies: []*chart.Dependency{&locked}}
+	is.Error(CheckDependencies(ch, ch.Metadata.Dependencies))
+
+	locked.Digest = digest
+	is.NoError(CheckDependencies(ch, ch.Metadata.Dependencies))
+}
+
+func TestVerifyDependencyContents(t *testing.T) {
+	is := assert.New(t)
+	dir := t.TempDir()
+	is.NoError(os.MkdirAll(filepath.Join(dir, "charts"), 0755))
+	archive := filepath.Join(dir, "charts", "hello-0.1.0.tgz")
+	is.NoError(os.WriteFile(archive, []byte("archive"), 0644))
+	sum, err := provenance.DigestFile(archive)
+	is.NoError(err)
+
+	ch := buildChart(withName("parent"), withDependency())
+	is.NoError(VerifyDependencyContents(dir, ch))
+
+	ch.Lock = &chart.Lock{Dependencies: []*chart.Dependency{{Name: "hello", Version: "0.1.0", ContentDigest: "sha256:" + sum}}}
+	is.NoError(VerifyDependencyContents(dir, ch))
+
+	is.NoError(os.WriteFile(archive, []byte("tampered"), 0644))
+	is.ErrorContains(VerifyDependencyContents(dir, ch), "content digest recorded in the lock file for: hello")
+}
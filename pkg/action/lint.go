@@ -38,6 +38,8 @@ type Lint struct {
 	Quiet                bool
 	SkipSchemaValidation bool
 	KubeVersion          *chartutil.KubeVersion
+	// Rules are run in addition to the registered lint rules.
+	Rules []lint.Rule
 }
 
 // LintResult is the result of Lint
@@ -60,7 +62,7 @@ func (l *Lint) Run(paths []string, vals map[string]interface{}) *LintResult {
 	}
 	result := &LintResult{}
 	for _, path := range paths {
-		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion, l.SkipSchemaValidation)
+		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion, l.SkipSchemaValidation, l.Rules)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			continue
@@ -87,7 +89,7 @@ func HasWarningsOrErrors(result *LintResult) bool {
 	return len(result.Errors) > 0
 }
 
-func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion, skipSchemaValidation bool) (support.Linter, error) {
+func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion, skipSchemaValidation bool, rules []lint.Rule) (support.Linter, error) {
 	var chartPath string
 	linter := support.Linter{}
 
@@ -132,5 +134,6 @@ func lintChart(path string, vals map[string]interface{}, namespace string, kubeV
 		namespace,
 		lint.WithKubeVersion(kubeVersion),
 		lint.WithSkipSchemaValidation(skipSchemaValidation),
+		lint.WithRules(rules...),
 	), nil
 }
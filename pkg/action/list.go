@@ -19,11 +19,15 @@ package action
 import (
 	"path"
 	"regexp"
+	"sort"
+	"strings"
 
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/labels"
 
 	releaseutil "helm.sh/helm/v4/pkg/release/util"
 	release "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/storage/driver"
 )
 
 // ListStates represents zero or more status codes that a list item may have set
@@ -122,7 +126,14 @@ type List struct {
 	Limit int
 	// Offset is the starting index for the Run() call
 	Offset int
-	// Filter is a filter that is applied to the results
+	// ChunkSize is the number of stored releases read at a time from
+	// storage drivers that support pagination. Values of 0 or less read all
+	// the releases at once.
+	ChunkSize int
+	// Filter is a filter that is applied to the results. It is either a
+	// regular expression matching release names, or space or comma separated
+	// field=value terms on the name, chart, version and status fields, which
+	// storage drivers can apply without reading every release.
 	Filter       string
 	Short        bool
 	NoHeaders    bool
@@ -150,23 +161,30 @@ func (l *List) Run() ([]*release.Release, error) {
 		return nil, err
 	}
 
+	fields, err := parseFieldFilter(l.Filter)
+	if err != nil {
+		return nil, err
+	}
+
 	var filter *regexp.Regexp
-	if l.Filter != "" {
-		var err error
+	if fields == nil && l.Filter != "" {
 		filter, err = regexp.Compile(l.Filter)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	results, err := l.cfg.Releases.List(func(rel *release.Release) bool {
+	results, err := l.listReleases(func(rel *release.Release) bool {
 		// Skip anything that doesn't match the filter.
 		if filter != nil && !filter.MatchString(rel.Name) {
 			return false
 		}
+		if !matchFields(rel, fields) {
+			return false
+		}
 
 		return true
-	})
+	}, fieldLabels(fields))
 
 	if err != nil {
 		return nil, err
@@ -181,6 +199,12 @@ func (l *List) Run() ([]*release.Release, error) {
 	// is _only_ ListSuperseded, skip the latest release filter
 	if l.StateMask != ListSuperseded {
 		results = filterLatestReleases(results)
+		if revisionFields(fields) {
+			results, err = l.dropStaleRevisions(results, fields)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// State mask application must occur after filtering to
@@ -216,6 +240,157 @@ func (l *List) Run() ([]*release.Release, error) {
 	return results, err
 }
 
+// listReleases returns the stored releases such that filter(release) ==
+// true, reading ChunkSize releases at a time. Storage drivers supporting it
+// only read the releases with the system labels lbs. Unless only superseded releases
+// are listed, older revisions are dropped after each chunk, so that only the
+// latest revision of each release is held in memory.
+func (l *List) listReleases(filter func(*release.Release) bool, lbs map[string]string) ([]*release.Release, error) {
+	var results []*release.Release
+	opts := driver.ListOptions{Limit: l.ChunkSize, Labels: lbs}
+	for {
+		page, next, err := l.cfg.Releases.ListPage(filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, page...)
+		if next == "" {
+			return results, nil
+		}
+		if l.StateMask != ListSuperseded {
+			results = filterLatestReleases(results)
+		}
+		opts.Continue = next
+	}
+}
+
+// dropStaleRevisions drops the releases having a newer revision. Filtering on
+// the fields of revisions hides the newer revisions not matching the filter,
+// so that filterLatestReleases cannot tell on its own that an older matching
+// revision is not the latest one. The latest revisions are read with a single
+// query of the releases, by name when the fields filter on it.
+func (l *List) dropStaleRevisions(releases []*release.Release, fields map[string]string) ([]*release.Release, error) {
+	if len(releases) == 0 {
+		return releases, nil
+	}
+	lbs := map[string]string{"owner": "helm"}
+	if name, ok := fields["name"]; ok {
+		lbs["name"] = name
+	}
+	all, err := l.cfg.Releases.Query(lbs)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, err
+	}
+	revisions := make(map[string]int, len(all))
+	for _, rls := range all {
+		key := rls.Namespace + "/" + rls.Name
+		revisions[key] = max(revisions[key], rls.Version)
+	}
+
+	latest := make([]*release.Release, 0, len(releases))
+	for _, rls := range releases {
+		if rls.Version >= revisions[rls.Namespace+"/"+rls.Name] {
+			latest = append(latest, rls)
+		}
+	}
+	return latest, nil
+}
+
+// listFilterFields are the fields of field filters, and the system labels
+// storage drivers index them with.
+var listFilterFields = map[string]string{
+	"name":    "name",
+	"chart":   driver.ChartLabel,
+	"version": driver.ChartVersionLabel,
+	"status":  "status",
+}
+
+// parseFieldFilter returns the values of the fields of a filter of space or
+// comma separated field=value terms, such as "chart=nginx status=failed". It
+// returns nil for other filters, which are regular expressions matching
+// release names: these cannot contain '=' as release names never do.
+func parseFieldFilter(filter string) (map[string]string, error) {
+	if !strings.Contains(filter, "=") {
+		return nil, nil
+	}
+	fields := make(map[string]string)
+	terms := strings.FieldsFunc(filter, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	for _, term := range terms {
+		field, value, ok := strings.Cut(term, "=")
+		if _, known := listFilterFields[field]; !ok || !known {
+			return nil, errors.Errorf("invalid filter term %q: must be field=value, with a field of %s", term, strings.Join(filterFieldNames(), ", "))
+		}
+		if prev, dup := fields[field]; dup && prev != value {
+			return nil, errors.Errorf("invalid filter: conflicting values %q and %q of field %q", prev, value, field)
+		}
+		fields[field] = value
+	}
+	return fields, nil
+}
+
+func filterFieldNames() []string {
+	names := make([]string, 0, len(listFilterFields))
+	for name := range listFilterFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fieldLabels returns the system labels of the releases matching fields.
+func fieldLabels(fields map[string]string) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	lbs := make(map[string]string, len(fields))
+	for field, value := range fields {
+		lbs[listFilterFields[field]] = value
+	}
+	return lbs
+}
+
+// revisionFields reports whether fields filter on fields that can change
+// between the revisions of a release.
+func revisionFields(fields map[string]string) bool {
+	for field := range fields {
+		if field != "name" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFields reports whether rel has the values of fields. Storage drivers
+// may not filter on fields, or may return releases stored without their
+// labels, so that they are always matched.
+func matchFields(rel *release.Release, fields map[string]string) bool {
+	for field, value := range fields {
+		var got string
+		switch field {
+		case "name":
+			got = rel.Name
+		case "status":
+			if rel.Info != nil {
+				got = rel.Info.Status.String()
+			}
+		case "chart", "version":
+			if rel.Chart == nil || rel.Chart.Metadata == nil {
+				return false
+			}
+			got = rel.Chart.Metadata.Name
+			if field == "version" {
+				got = rel.Chart.Metadata.Version
+			}
+		}
+		if got != value {
+			return false
+		}
+	}
+	return true
+}
+
 // sort is an in-place sort where order is based on the value of a.Sort
 func (l *List) sort(rels []*release.Release) {
 	if l.SortReverse {
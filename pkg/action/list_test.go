@@ -17,12 +17,16 @@ limitations under the License.
 package action
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	release "helm.sh/helm/v4/pkg/release/v1"
 	"helm.sh/helm/v4/pkg/storage"
+	"helm.sh/helm/v4/pkg/storage/driver"
 )
 
 func TestListStates(t *testing.T) {
@@ -205,6 +209,68 @@ func TestList_StateMaskWithStaleRevisions(t *testing.T) {
 	is.Equal("failed", res[0].Name)
 }
 
+// pagedMemory is a memory driver paginating its releases by name and
+// revision, with the index of the next release as continue token. It ignores
+// the labels of the list options, which it records, and counts its queries.
+type pagedMemory struct {
+	*driver.Memory
+	pages   int
+	labels  map[string]string
+	queries int
+}
+
+func (m *pagedMemory) Query(keyvals map[string]string) ([]*release.Release, error) {
+	m.queries++
+	return m.Memory.Query(keyvals)
+}
+
+func (m *pagedMemory) ListPage(filter func(*release.Release) bool, opts driver.ListOptions) ([]*release.Release, string, error) {
+	m.pages++
+	m.labels = opts.Labels
+	all, err := m.List(func(*release.Release) bool { return true })
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return fmt.Sprintf("%s.%09d", all[i].Name, all[i].Version) < fmt.Sprintf("%s.%09d", all[j].Name, all[j].Version)
+	})
+	start := 0
+	if opts.Continue != "" {
+		start, _ = strconv.Atoi(opts.Continue)
+	}
+	end, next := len(all), ""
+	if opts.Limit > 0 && start+opts.Limit < len(all) {
+		end, next = start+opts.Limit, strconv.Itoa(start+opts.Limit)
+	}
+	var page []*release.Release
+	for _, rel := range all[start:end] {
+		if filter(rel) {
+			page = append(page, rel)
+		}
+	}
+	return page, next, nil
+}
+
+func TestList_ChunkSize(t *testing.T) {
+	is := assert.New(t)
+	lister := newListFixture(t)
+	paged := &pagedMemory{Memory: driver.NewMemory()}
+	lister.cfg.Releases = storage.Init(paged)
+	lister.StateMask = ListFailed
+	lister.ChunkSize = 3
+
+	makeMeSomeReleasesWithStaleFailure(lister.cfg.Releases, t)
+
+	res, err := lister.Run()
+
+	is.NoError(err)
+	is.Equal(2, paged.pages)
+	// the failed revision of "dirty" is superseded by a deployed revision
+	// of another chunk
+	is.Len(res, 1)
+	is.Equal("failed", res[0].Name)
+}
+
 func makeMeSomeReleasesWithStaleFailure(store *storage.Storage, t *testing.T) {
 	t.Helper()
 	one := namedReleaseStub("clean", release.StatusDeployed)
@@ -250,6 +316,51 @@ func TestList_Filter(t *testing.T) {
 	is.Equal("three", res[0].Name)
 }
 
+func TestList_FilterFields(t *testing.T) {
+	is := assert.New(t)
+	lister := newListFixture(t)
+	paged := &pagedMemory{Memory: driver.NewMemory()}
+	lister.cfg.Releases = storage.Init(paged)
+	lister.Filter = "chart=hello,version=0.1.0 status=failed"
+
+	makeMeSomeReleasesWithStaleFailure(lister.cfg.Releases, t)
+
+	res, err := lister.Run()
+
+	is.NoError(err)
+	is.Equal(map[string]string{
+		driver.ChartLabel:        "hello",
+		driver.ChartVersionLabel: "0.1.0",
+		"status":                 "failed",
+	}, paged.labels)
+	// the failed revision of "dirty" is superseded by a deployed revision
+	// not matching the filter, which a single query finds
+	is.Len(res, 1)
+	is.Equal("failed", res[0].Name)
+	is.Equal(1, paged.queries)
+
+	lister.Filter = "name=dirty status=failed"
+	res, err = lister.Run()
+	is.NoError(err)
+	is.Len(res, 0)
+	is.Equal(2, paged.queries)
+
+	lister.Filter = "chart=nginx"
+	res, err = lister.Run()
+	is.NoError(err)
+	is.Len(res, 0)
+}
+
+func TestList_FilterFieldsInvalid(t *testing.T) {
+	for _, filter := range []string{"app=nginx", "status=failed,=x", "status=failed status=deployed"} {
+		lister := newListFixture(t)
+		lister.Filter = filter
+		if _, err := lister.Run(); err == nil {
+			t.Errorf("Expected an error for filter %q", filter)
+		}
+	}
+}
+
 func TestList_FilterFailsCompile(t *testing.T) {
 	is := assert.New(t)
 	lister := newListFixture(t)
@@ -18,16 +18,23 @@ package action
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"syscall"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/pkg/errors"
 	"golang.org/x/term"
 
+	"helm.sh/helm/v4/internal/sbom"
+	"helm.sh/helm/v4/internal/sigstore"
+	"helm.sh/helm/v4/internal/version"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/engine"
 	"helm.sh/helm/v4/pkg/provenance"
 )
 
@@ -44,6 +51,19 @@ type Package struct {
 	AppVersion       string
 	Destination      string
 	DependencyUpdate bool
+	// ModTime is the modification time recorded for every file in the
+	// archive. When unset, the Unix epoch is used so that packaging the
+	// same chart twice produces identical archives.
+	ModTime time.Time
+	// SBOMFormat, when set, writes a software bill of materials in this
+	// format ("spdx" or "cyclonedx") next to the chart archive.
+	SBOMFormat string
+	// SignKeyless signs the archive with a short-lived certificate issued by
+	// Fulcio for IdentityToken, recording the signature in Rekor.
+	SignKeyless   bool
+	IdentityToken string
+	FulcioURL     string
+	RekorURL      string
 
 	RepositoryConfig      string
 	RepositoryCache       string
@@ -89,6 +109,9 @@ func (p *Package) Run(path string, _ map[string]interface{}) (string, error) {
 		if err := CheckDependencies(ch, reqs); err != nil {
 			return "", err
 		}
+		if err := VerifyDependencyContents(path, ch); err != nil {
+			return "", err
+		}
 	}
 
 	var dest string
@@ -103,18 +126,84 @@ func (p *Package) Run(path string, _ map[string]interface{}) (string, error) {
 		dest = p.Destination
 	}
 
-	name, err := chartutil.Save(ch, dest)
+	modTime := p.ModTime
+	if modTime.IsZero() {
+		modTime = time.Unix(0, 0)
+	}
+
+	var format sbom.Format
+	if p.SBOMFormat != "" {
+		if format, err = sbom.ParseFormat(p.SBOMFormat); err != nil {
+			return "", err
+		}
+	}
+
+	name, err := chartutil.Save(ch, dest, chartutil.WithModTime(modTime))
 	if err != nil {
 		return "", errors.Wrap(err, "failed to save")
 	}
 
+	if format != "" {
+		if err := writeSBOM(name, format, modTime); err != nil {
+			return "", errors.Wrap(err, "failed to write SBOM")
+		}
+	}
+
 	if p.Sign {
-		err = p.Clearsign(name)
+		if err := p.Clearsign(name); err != nil {
+			return name, err
+		}
+	}
+
+	if p.SignKeyless {
+		err = p.SignWithSigstore(name)
 	}
 
 	return name, err
 }
 
+// writeSBOM writes an SBOM for the chart archive at path. Images are discovered
+// by rendering the chart with its default values. A chart that cannot be
+// rendered that way still gets an SBOM, without images.
+func writeSBOM(path string, format sbom.Format, created time.Time) error {
+	c, err := sbom.FromArchive(path)
+	if err != nil {
+		return err
+	}
+	manifests, err := renderDefaults(path)
+	if err != nil {
+		slog.Warn("unable to render chart with default values, SBOM will not list images", slog.Any("error", err))
+	}
+	c.Images = sbom.Images(manifests)
+
+	data, err := sbom.Generate(c, format, created, "helm-"+version.GetVersion())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sbom.FileName(path, format), data, 0644)
+}
+
+// renderDefaults renders the chart archive at path with its default values.
+func renderDefaults(path string) (map[string]string, error) {
+	ch, err := loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessDependencies(ch, map[string]interface{}{}); err != nil {
+		return nil, err
+	}
+	options := chartutil.ReleaseOptions{
+		Name:      "release-name",
+		Namespace: "default",
+		IsInstall: true,
+	}
+	vals, err := chartutil.ToRenderValues(ch, map[string]interface{}{}, options, chartutil.DefaultCapabilities.Copy())
+	if err != nil {
+		return nil, err
+	}
+	return engine.Render(ch, vals)
+}
+
 // validateVersion Verify that version is a Version, and error out if it is not.
 func validateVersion(ver string) error {
 	if _, err := semver.NewVersion(ver); err != nil {
@@ -123,6 +212,35 @@ func validateVersion(ver string) error {
 	return nil
 }
 
+// SignWithSigstore signs a chart archive without a long-lived key, writing a
+// Sigstore bundle next to the archive.
+func (p *Package) SignWithSigstore(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	signer := &sigstore.Signer{
+		IdentityToken: p.IdentityToken,
+		FulcioURL:     p.FulcioURL,
+		RekorURL:      p.RekorURL,
+	}
+	if signer.FulcioURL == "" {
+		signer.FulcioURL = sigstore.DefaultFulcioURL
+	}
+	if signer.RekorURL == "" {
+		signer.RekorURL = sigstore.DefaultRekorURL
+	}
+	bundle, err := signer.Sign(data)
+	if err != nil {
+		return errors.Wrap(err, "keyless signing failed")
+	}
+	out, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sigstore.BundleFileName(filename), out, 0644)
+}
+
 // Clearsign signs a chart
 func (p *Package) Clearsign(filename string) error {
 	// Load keyring
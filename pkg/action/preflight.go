@@ -0,0 +1,403 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// PreflightRequest describes the changes an install or an upgrade is about to
+// make to the cluster.
+type PreflightRequest struct {
+	// Release is the release being installed or upgraded.
+	Release *release.Release
+	// Current are the resources that already exist: the resources of the
+	// deployed release on upgrade, and the resources adopted on install.
+	Current kube.ResourceList
+	// Target are the resources of Release.
+	Target kube.ResourceList
+}
+
+// created returns the target resources that do not exist yet.
+func (r *PreflightRequest) created() kube.ResourceList {
+	return r.Target.Difference(r.Current)
+}
+
+// deleted returns the current resources that are removed from the release.
+func (r *PreflightRequest) deleted() kube.ResourceList {
+	var deleted kube.ResourceList
+	for _, info := range r.Current.Difference(r.Target) {
+		if !hasKeepPolicy(info.Object) {
+			deleted = append(deleted, info)
+		}
+	}
+	return deleted
+}
+
+// PreflightCheck verifies that the cluster accepts the changes of an install
+// or an upgrade before any of them is made.
+type PreflightCheck interface {
+	// Name identifies the check in the reported failures.
+	Name() string
+	// Check returns the reasons the cluster would not accept the changes, or
+	// an error when the check could not be performed.
+	Check(ctx context.Context, cfg *Configuration, req *PreflightRequest) ([]string, error)
+}
+
+// PreflightFailure is a reason reported by a PreflightCheck.
+type PreflightFailure struct {
+	Check   string
+	Message string
+}
+
+// PreflightError reports the failures of all the preflight checks.
+type PreflightError struct {
+	Failures []PreflightFailure
+}
+
+func (e *PreflightError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d preflight check(s) failed:", len(e.Failures))
+	for _, f := range e.Failures {
+		fmt.Fprintf(&b, "\n- %s: %s", f.Check, f.Message)
+	}
+	return b.String()
+}
+
+// DefaultPreflightChecks returns the built-in preflight checks: the Kubernetes
+// version constraints of the chart and its dependencies, the APIs they
+// require, the availability of the APIs of every resource, the permission to
+// make every change, and the headroom of the object count quotas.
+func DefaultPreflightChecks() []PreflightCheck {
+	return []PreflightCheck{
+		KubeVersionCheck{},
+		RequiredAPIsCheck{},
+		APIAvailabilityCheck{},
+		RBACCheck{},
+		ResourceQuotaCheck{},
+	}
+}
+
+// runPreflightChecks runs every check, and reports all their failures
+// together as a *PreflightError.
+func (cfg *Configuration) runPreflightChecks(ctx context.Context, checks []PreflightCheck, req *PreflightRequest) error {
+	var failures []PreflightFailure
+	for _, check := range checks {
+		slog.Debug("running preflight check", "check", check.Name(), "name", req.Release.Name)
+		messages, err := check.Check(ctx, cfg, req)
+		if err != nil {
+			return errors.Wrapf(err, "preflight check %s could not be performed", check.Name())
+		}
+		for _, msg := range messages {
+			failures = append(failures, PreflightFailure{Check: check.Name(), Message: msg})
+		}
+	}
+	if len(failures) > 0 {
+		return &PreflightError{Failures: failures}
+	}
+	return nil
+}
+
+// KubeVersionCheck verifies the Kubernetes version of the cluster against the
+// kubeVersion constraints of the chart and all of its dependencies.
+type KubeVersionCheck struct{}
+
+// Name implements PreflightCheck.
+func (KubeVersionCheck) Name() string { return "kube-version" }
+
+// Check implements PreflightCheck.
+func (KubeVersionCheck) Check(_ context.Context, cfg *Configuration, req *PreflightRequest) ([]string, error) {
+	if req.Release.Chart == nil {
+		return nil, nil
+	}
+	caps, err := cfg.getCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	var failures []string
+	var check func(ch *chart.Chart)
+	check = func(ch *chart.Chart) {
+		if ch.Metadata != nil && ch.Metadata.KubeVersion != "" &&
+			!chartutil.IsCompatibleRange(ch.Metadata.KubeVersion, caps.KubeVersion.String()) {
+			failures = append(failures, fmt.Sprintf("chart %s requires kubeVersion: %s which is incompatible with Kubernetes %s", ch.Name(), ch.Metadata.KubeVersion, caps.KubeVersion.String()))
+		}
+		for _, dep := range ch.Dependencies() {
+			check(dep)
+		}
+	}
+	check(req.Release.Chart)
+	return failures, nil
+}
+
+// RequiredAPIsCheck verifies that the cluster serves the requiredAPIVersions
+// of the chart and all of its dependencies, which the chart depends on at
+// runtime without rendering resources of them.
+type RequiredAPIsCheck struct{}
+
+// Name implements PreflightCheck.
+func (RequiredAPIsCheck) Name() string { return "required-apis" }
+
+// Check implements PreflightCheck.
+func (RequiredAPIsCheck) Check(_ context.Context, cfg *Configuration, req *PreflightRequest) ([]string, error) {
+	if req.Release.Chart == nil {
+		return nil, nil
+	}
+	caps, err := cfg.getCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	var failures []string
+	var check func(ch *chart.Chart)
+	check = func(ch *chart.Chart) {
+		if ch.Metadata != nil {
+			for _, apiVersion := range ch.Metadata.RequiredAPIVersions {
+				if !caps.APIVersions.Has(apiVersion) {
+					failures = append(failures, fmt.Sprintf("chart %s requires %s, which the cluster does not serve", ch.Name(), describeAPIVersion(apiVersion)))
+				}
+			}
+		}
+		for _, dep := range ch.Dependencies() {
+			check(dep)
+		}
+	}
+	check(req.Release.Chart)
+	return failures, nil
+}
+
+// describeAPIVersion separates the kind of an API version, as in
+// "cert-manager.io/v1 Certificate".
+func describeAPIVersion(apiVersion string) string {
+	i := strings.LastIndex(apiVersion, "/")
+	if i < 0 {
+		return apiVersion
+	}
+	if kind := apiVersion[i+1:]; kind != "" && unicode.IsUpper(rune(kind[0])) {
+		return apiVersion[:i] + " " + kind
+	}
+	return apiVersion
+}
+
+// APIAvailabilityCheck verifies that the cluster serves the API of every
+// resource, such as the CRDs the chart expects to be installed.
+type APIAvailabilityCheck struct{}
+
+// Name implements PreflightCheck.
+func (APIAvailabilityCheck) Name() string { return "api-availability" }
+
+// Check implements PreflightCheck.
+func (APIAvailabilityCheck) Check(_ context.Context, cfg *Configuration, req *PreflightRequest) ([]string, error) {
+	caps, err := cfg.getCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	missing := map[string]bool{}
+	for _, info := range req.Target {
+		apiVersion, kind := info.Mapping.GroupVersionKind.ToAPIVersionAndKind()
+		if caps.APIVersions.Has(apiVersion) || caps.APIVersions.Has(apiVersion+"/"+kind) {
+			continue
+		}
+		missing[fmt.Sprintf("the cluster does not serve %s %s", apiVersion, kind)] = true
+	}
+	return sortedKeys(missing), nil
+}
+
+// RBACCheck verifies with SelfSubjectAccessReviews that the user is allowed
+// to create, update and delete the resources of the release.
+type RBACCheck struct{}
+
+// Name implements PreflightCheck.
+func (RBACCheck) Name() string { return "rbac" }
+
+// Check implements PreflightCheck.
+func (RBACCheck) Check(ctx context.Context, cfg *Configuration, req *PreflightRequest) ([]string, error) {
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	return checkRBAC(ctx, client, req)
+}
+
+func checkRBAC(ctx context.Context, client kubernetes.Interface, req *PreflightRequest) ([]string, error) {
+	var failures []string
+	review := func(verb string, resources kube.ResourceList, named bool) error {
+		for _, i := range resources {
+			attrs := &authorizationv1.ResourceAttributes{
+				Namespace: i.Namespace,
+				Verb:      verb,
+				Group:     i.Mapping.Resource.Group,
+				Resource:  i.Mapping.Resource.Resource,
+			}
+			if named {
+				attrs.Name = i.Name
+			}
+			ssar := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attrs},
+			}
+			res, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, ssar, metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
+			if !res.Status.Allowed {
+				failures = append(failures, describeDenial(attrs, i.Mapping.Scope.Name() == meta.RESTScopeNameNamespace))
+			}
+		}
+		return nil
+	}
+
+	created := req.created()
+	updated := req.Target.Intersect(req.Current)
+	deleted := req.deleted()
+	for _, r := range []struct {
+		verb      string
+		resources kube.ResourceList
+		named     bool
+	}{
+		{"create", created, false},
+		{"get", req.Target, true},
+		{"patch", updated, true},
+		{"delete", deleted, true},
+	} {
+		if err := review(r.verb, r.resources, r.named); err != nil {
+			return nil, err
+		}
+	}
+	return failures, nil
+}
+
+func describeDenial(attrs *authorizationv1.ResourceAttributes, namespaced bool) string {
+	resource := attrs.Resource
+	if attrs.Group != "" {
+		resource += "." + attrs.Group
+	}
+	msg := fmt.Sprintf("not allowed to %s %s", attrs.Verb, resource)
+	if attrs.Name != "" {
+		msg += fmt.Sprintf(" %q", attrs.Name)
+	}
+	if namespaced {
+		msg += fmt.Sprintf(" in namespace %q", attrs.Namespace)
+	}
+	return msg
+}
+
+// ResourceQuotaCheck verifies that the object count quotas of the namespaces
+// of the release leave room for the resources it creates. Quotas on compute
+// resources are not checked.
+type ResourceQuotaCheck struct{}
+
+// Name implements PreflightCheck.
+func (ResourceQuotaCheck) Name() string { return "resource-quota" }
+
+// Check implements PreflightCheck.
+func (ResourceQuotaCheck) Check(ctx context.Context, cfg *Configuration, req *PreflightRequest) ([]string, error) {
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		return nil, err
+	}
+	return checkResourceQuotas(ctx, client, req)
+}
+
+// legacyQuotaResources are the object count quotas that predate the
+// count/<resource>.<group> syntax.
+var legacyQuotaResources = map[string]corev1.ResourceName{
+	"configmaps":             corev1.ResourceConfigMaps,
+	"persistentvolumeclaims": corev1.ResourcePersistentVolumeClaims,
+	"pods":                   corev1.ResourcePods,
+	"replicationcontrollers": corev1.ResourceReplicationControllers,
+	"resourcequotas":         corev1.ResourceQuotas,
+	"secrets":                corev1.ResourceSecrets,
+	"services":               corev1.ResourceServices,
+}
+
+func checkResourceQuotas(ctx context.Context, client kubernetes.Interface, req *PreflightRequest) ([]string, error) {
+	// The number of objects created per namespace and quota resource name.
+	created := map[string]map[corev1.ResourceName]int64{}
+	for _, info := range req.created() {
+		if info.Namespace == "" {
+			continue
+		}
+		if created[info.Namespace] == nil {
+			created[info.Namespace] = map[corev1.ResourceName]int64{}
+		}
+		gr := info.Mapping.Resource.GroupResource()
+		created[info.Namespace][corev1.ResourceName("count/"+gr.String())]++
+		if name, ok := legacyQuotaResources[gr.Resource]; ok && gr.Group == "" {
+			created[info.Namespace][name]++
+		}
+	}
+
+	namespaces := make([]string, 0, len(created))
+	for ns := range created {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	var failures []string
+	for _, ns := range namespaces {
+		quotas, err := client.CoreV1().ResourceQuotas(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, quota := range quotas.Items {
+			names := make([]string, 0, len(quota.Spec.Hard))
+			for name := range quota.Spec.Hard {
+				names = append(names, string(name))
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				count := created[ns][corev1.ResourceName(name)]
+				if count == 0 {
+					continue
+				}
+				hard := quota.Spec.Hard[corev1.ResourceName(name)]
+				used := quota.Status.Used[corev1.ResourceName(name)]
+				total := used.DeepCopy()
+				total.Add(*resource.NewQuantity(count, resource.DecimalSI))
+				if total.Cmp(hard) > 0 {
+					failures = append(failures, fmt.Sprintf("creating %d %s in namespace %q exceeds resource quota %q: %s of %s used", count, name, ns, quota.Name, used.String(), hard.String()))
+				}
+			}
+		}
+	}
+	return failures, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,211 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+type stubPreflightCheck struct {
+	name     string
+	failures []string
+}
+
+func (c stubPreflightCheck) Name() string { return c.name }
+
+func (c stubPreflightCheck) Check(_ context.Context, _ *Configuration, _ *PreflightRequest) ([]string, error) {
+	return c.failures, nil
+}
+
+func newPreflightInfo(gvr schema.GroupVersionResource, kind, namespace, name string) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvr.GroupVersion().WithKind(kind))
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	scope := meta.RESTScopeNamespace
+	if namespace == "" {
+		scope = meta.RESTScopeRoot
+	}
+	return &resource.Info{
+		Name:      name,
+		Namespace: namespace,
+		Object:    obj,
+		Mapping: &meta.RESTMapping{
+			Resource:         gvr,
+			GroupVersionKind: gvr.GroupVersion().WithKind(kind),
+			Scope:            scope,
+		},
+	}
+}
+
+var configMapsGVR = corev1.SchemeGroupVersion.WithResource("configmaps")
+
+func TestInstallRelease_PreflightChecks(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.PreflightChecks = []PreflightCheck{
+		stubPreflightCheck{name: "first", failures: []string{"one", "two"}},
+		stubPreflightCheck{name: "passing"},
+		stubPreflightCheck{name: "second", failures: []string{"three"}},
+	}
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	var preflightErr *PreflightError
+	require.ErrorAs(t, err, &preflightErr)
+	is.Equal([]PreflightFailure{
+		{Check: "first", Message: "one"},
+		{Check: "first", Message: "two"},
+		{Check: "second", Message: "three"},
+	}, preflightErr.Failures)
+	is.Equal("3 preflight check(s) failed:\n- first: one\n- first: two\n- second: three", err.Error())
+
+	// Nothing is recorded when a preflight check fails.
+	_, err = instAction.cfg.Releases.Get(instAction.ReleaseName, 1)
+	is.Error(err)
+}
+
+func TestKubeVersionCheck(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	rel := releaseStub()
+	rel.Chart = buildChart(withDependency(withName("old"), withKube("<1.10.0")), withDependency(withName("new")))
+
+	failures, err := KubeVersionCheck{}.Check(context.Background(), cfg, &PreflightRequest{Release: rel})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chart old requires kubeVersion: <1.10.0 which is incompatible with Kubernetes v1.20.0"}, failures)
+}
+
+func TestRequiredAPIsCheck(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	withRequiredAPIs := func(apiVersions ...string) chartOption {
+		return func(opts *chartOptions) {
+			opts.Metadata.RequiredAPIVersions = apiVersions
+		}
+	}
+	rel := releaseStub()
+	rel.Chart = buildChart(
+		withRequiredAPIs("apps/v1", "cert-manager.io/v1/Certificate"),
+		withDependency(withName("operator"), withRequiredAPIs("example.com/v1")),
+	)
+
+	failures, err := RequiredAPIsCheck{}.Check(context.Background(), cfg, &PreflightRequest{Release: rel})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"chart hello requires cert-manager.io/v1 Certificate, which the cluster does not serve",
+		"chart operator requires example.com/v1, which the cluster does not serve",
+	}, failures)
+}
+
+func TestAPIAvailabilityCheck(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	widgets := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	req := &PreflightRequest{
+		Release: releaseStub(),
+		Target: kube.ResourceList{
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "settings"),
+			newPreflightInfo(widgets, "Widget", "spaced", "first"),
+			newPreflightInfo(widgets, "Widget", "spaced", "second"),
+		},
+	}
+
+	failures, err := APIAvailabilityCheck{}.Check(context.Background(), cfg, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"the cluster does not serve example.com/v1 Widget"}, failures)
+}
+
+func TestCheckRBAC(t *testing.T) {
+	client := fakeclientset.NewClientset()
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Verb != "delete"
+		return true, review, nil
+	})
+
+	kept := newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "kept")
+	kept.Object.(*unstructured.Unstructured).SetAnnotations(map[string]string{kube.ResourcePolicyAnno: kube.KeepPolicy})
+	req := &PreflightRequest{
+		Release: releaseStub(),
+		Current: kube.ResourceList{
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "settings"),
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "removed"),
+			kept,
+		},
+		Target: kube.ResourceList{
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "settings"),
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "added"),
+		},
+	}
+
+	failures, err := checkRBAC(context.Background(), client, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`not allowed to delete configmaps "removed" in namespace "spaced"`}, failures)
+
+	var verbs []string
+	for _, action := range client.Actions() {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		verbs = append(verbs, review.Spec.ResourceAttributes.Verb+" "+review.Spec.ResourceAttributes.Name)
+	}
+	assert.Equal(t, []string{"create ", "get settings", "get added", "patch settings", "delete removed"}, verbs)
+}
+
+func TestCheckResourceQuotas(t *testing.T) {
+	client := fakeclientset.NewClientset(&corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "objects", Namespace: "spaced"},
+		Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{
+			"count/configmaps": apiresource.MustParse("3"),
+			"services":         apiresource.MustParse("5"),
+		}},
+		Status: corev1.ResourceQuotaStatus{Used: corev1.ResourceList{
+			"count/configmaps": apiresource.MustParse("2"),
+			"services":         apiresource.MustParse("1"),
+		}},
+	})
+	servicesGVR := corev1.SchemeGroupVersion.WithResource("services")
+	req := &PreflightRequest{
+		Release: releaseStub(),
+		Current: kube.ResourceList{
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "existing"),
+		},
+		Target: kube.ResourceList{
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "existing"),
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "first"),
+			newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "second"),
+			newPreflightInfo(servicesGVR, "Service", "spaced", "web"),
+			newPreflightInfo(configMapsGVR, "ConfigMap", "other", "elsewhere"),
+		},
+	}
+
+	failures, err := checkResourceQuotas(context.Background(), client, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{`creating 2 count/configmaps in namespace "spaced" exceeds resource quota "objects": 2 of 3 used`}, failures)
+}
@@ -0,0 +1,181 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// orphan is a resource of a release missing from its manifest.
+type orphan struct {
+	resource  schema.GroupVersionResource
+	kind      string
+	namespace string
+	name      string
+}
+
+func (o orphan) String() string {
+	if o.namespace == "" {
+		return fmt.Sprintf("%s %q", o.kind, o.name)
+	}
+	return fmt.Sprintf("%s %q in namespace %q", o.kind, o.name, o.namespace)
+}
+
+// pruneOrphans deletes the resources of a release that are missing from the
+// resources of its manifest, such as the resources left behind by a failed
+// upgrade, which the manifests of the releases do not record.
+func (cfg *Configuration) pruneOrphans(ctx context.Context, rel *release.Release, target kube.ResourceList) error {
+	dc, err := cfg.RESTClientGetter.ToDiscoveryClient()
+	if err != nil {
+		return errors.Wrap(err, "unable to get discovery client")
+	}
+	restConfig, err := cfg.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return errors.Wrap(err, "unable to get REST config")
+	}
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return errors.Wrap(err, "unable to get dynamic client")
+	}
+
+	orphans, err := findOrphans(ctx, dc, client, rel, target)
+	if err != nil {
+		return err
+	}
+	return deleteOrphans(ctx, client, orphans)
+}
+
+// findOrphans finds the resources labeled as managed by Helm and annotated
+// as belonging to the release that are missing from the target resources.
+// The namespaced resources are looked for in the release namespace and the
+// namespaces of the target resources. Hooks and the resources kept by their
+// resource policy are not orphans.
+func findOrphans(ctx context.Context, dc discovery.DiscoveryInterface, client dynamic.Interface, rel *release.Release, target kube.ResourceList) ([]orphan, error) {
+	wanted := map[string]bool{}
+	for _, info := range target {
+		gvk := info.Object.GetObjectKind().GroupVersionKind()
+		wanted[orphanKey(gvk.Group, gvk.Kind, info.Namespace, info.Name)] = true
+	}
+
+	var orphans []orphan
+	err := visitReleaseObjects(ctx, dc, client, rel, releaseNamespaces(rel, target), func(gvr schema.GroupVersionResource, kind string, obj *unstructured.Unstructured) {
+		annos := obj.GetAnnotations()
+		if _, ok := annos[release.HookAnnotation]; ok || annos[kube.ResourcePolicyAnno] == kube.KeepPolicy || obj.GetDeletionTimestamp() != nil {
+			return
+		}
+		if wanted[orphanKey(gvr.Group, kind, obj.GetNamespace(), obj.GetName())] {
+			return
+		}
+		orphans = append(orphans, orphan{resource: gvr, kind: kind, namespace: obj.GetNamespace(), name: obj.GetName()})
+	})
+	return orphans, err
+}
+
+// releaseNamespaces returns the release namespace and the namespaces of the
+// resources of a release.
+func releaseNamespaces(rel *release.Release, resources kube.ResourceList) []string {
+	namespaces := map[string]bool{rel.Namespace: true}
+	for _, info := range resources {
+		if info.Namespace != "" {
+			namespaces[info.Namespace] = true
+		}
+	}
+	return sortedKeys(namespaces)
+}
+
+// visitReleaseObjects visits the live objects labeled as managed by Helm and
+// annotated as belonging to the release, among the cluster-scoped objects and
+// the objects of the namespaces, with the resource and kind of each.
+func visitReleaseObjects(ctx context.Context, dc discovery.DiscoveryInterface, client dynamic.Interface, rel *release.Release, namespaces []string, visit func(schema.GroupVersionResource, string, *unstructured.Unstructured)) error {
+	lists, err := discovery.ServerPreferredResources(dc)
+	if err != nil && len(lists) == 0 {
+		return errors.Wrap(err, "unable to discover the resources of the cluster")
+	}
+	lists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "delete"}}, lists)
+
+	selector := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", appManagedByLabel, appManagedByHelm)}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") {
+				continue
+			}
+			gvr := gv.WithResource(res.Name)
+			scopes := []string{metav1.NamespaceNone}
+			if res.Namespaced {
+				scopes = namespaces
+			}
+			for _, namespace := range scopes {
+				objs, err := client.Resource(gvr).Namespace(namespace).List(ctx, selector)
+				if err != nil {
+					if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+						slog.Debug("unable to look for the resources of the release", "resource", gvr.String(), "namespace", namespace, slog.Any("error", err))
+						continue
+					}
+					return errors.Wrapf(err, "unable to list %s", gvr.String())
+				}
+				for i := range objs.Items {
+					obj := &objs.Items[i]
+					annos := obj.GetAnnotations()
+					if annos[helmReleaseNameAnnotation] != rel.Name || annos[helmReleaseNamespaceAnnotation] != rel.Namespace {
+						continue
+					}
+					visit(gvr, res.Kind, obj)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// deleteOrphans deletes the orphaned resources, in the background.
+func deleteOrphans(ctx context.Context, client dynamic.Interface, orphans []orphan) error {
+	var errs []string
+	propagation := metav1.DeletePropagationBackground
+	for _, o := range orphans {
+		slog.Debug("deleting orphaned resource", "kind", o.kind, "name", o.name, "namespace", o.namespace)
+		err := client.Resource(o.resource).Namespace(o.namespace).Delete(ctx, o.name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Sprintf("%s: %s", o, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Errorf("unable to delete orphaned resources: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func orphanKey(group, kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", group, kind, namespace, name)
+}
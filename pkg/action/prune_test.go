@@ -0,0 +1,94 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func releaseConfigMap(name, namespace string, labels, annotations map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetLabels(labels)
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func TestFindAndDeleteOrphans(t *testing.T) {
+	managed := map[string]string{appManagedByLabel: appManagedByHelm}
+	owned := map[string]string{helmReleaseNameAnnotation: "angry-panda", helmReleaseNamespaceAnnotation: "spaced"}
+	withAnnotation := func(key, value string) map[string]string {
+		annos := map[string]string{key: value}
+		for k, v := range owned {
+			annos[k] = v
+		}
+		return annos
+	}
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{configMapsGVR: "ConfigMapList"},
+		releaseConfigMap("settings", "spaced", managed, owned),
+		releaseConfigMap("orphaned", "spaced", managed, owned),
+		releaseConfigMap("kept", "spaced", managed, withAnnotation(kube.ResourcePolicyAnno, kube.KeepPolicy)),
+		releaseConfigMap("hook", "spaced", managed, withAnnotation(release.HookAnnotation, "pre-install")),
+		releaseConfigMap("unmanaged", "spaced", nil, owned),
+		releaseConfigMap("other-release", "spaced", managed, map[string]string{helmReleaseNameAnnotation: "other", helmReleaseNamespaceAnnotation: "spaced"}),
+		releaseConfigMap("elsewhere", "default", managed, owned),
+	)
+	dc := fakeclientset.NewClientset().Discovery().(*fakediscovery.FakeDiscovery)
+	dc.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: metav1.Verbs{"list", "delete"}},
+			{Name: "pods/log", Kind: "Pod", Namespaced: true, Verbs: metav1.Verbs{"get"}},
+		},
+	}}
+
+	rel := releaseStub()
+	rel.Namespace = "spaced"
+	target := kube.ResourceList{newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "settings")}
+
+	orphans, err := findOrphans(context.Background(), dc, client, rel, target)
+	require.NoError(t, err)
+	assert.Equal(t, []orphan{{resource: configMapsGVR, kind: "ConfigMap", namespace: "spaced", name: "orphaned"}}, orphans)
+
+	require.NoError(t, deleteOrphans(context.Background(), client, orphans))
+	remaining, err := client.Resource(configMapsGVR).Namespace("spaced").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, obj := range remaining.Items {
+		names = append(names, obj.GetName())
+	}
+	assert.ElementsMatch(t, []string{"settings", "kept", "hook", "unmanaged", "other-release"}, names)
+}
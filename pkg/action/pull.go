@@ -17,7 +17,6 @@ limitations under the License.
 package action
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -76,10 +75,11 @@ func (p *Pull) Run(chartRef string) (string, error) {
 	var out strings.Builder
 
 	c := downloader.ChartDownloader{
-		Out:     &out,
-		Keyring: p.Keyring,
-		Verify:  downloader.VerifyNever,
-		Getters: getter.All(p.Settings),
+		Out:      &out,
+		Keyring:  p.Keyring,
+		Sigstore: p.sigstoreOptions(),
+		Verify:   downloader.VerifyNever,
+		Getters:  getter.All(p.Settings),
 		Options: []getter.Option{
 			getter.WithBasicAuth(p.Username, p.Password),
 			getter.WithPassCredentialsAll(p.PassCredentialsAll),
@@ -139,11 +139,7 @@ func (p *Pull) Run(chartRef string) (string, error) {
 	}
 
 	if p.Verify {
-		for name := range v.SignedBy.Identities {
-			fmt.Fprintf(&out, "Signed by: %v\n", name)
-		}
-		fmt.Fprintf(&out, "Using Key With Fingerprint: %X\n", v.SignedBy.PrimaryKey.Fingerprint)
-		fmt.Fprintf(&out, "Chart Hash Verified: %s\n", v.FileHash)
+		writeVerification(&out, v)
 	}
 
 	// After verification, untar the chart into the requested directory.
@@ -37,6 +37,7 @@ type Push struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	withSBOM              bool
 	out                   io.Writer
 }
 
@@ -73,6 +74,13 @@ func WithPlainHTTP(plainHTTP bool) PushOpt {
 	}
 }
 
+// WithSBOM attaches the SBOMs written next to the chart archive by 'helm package --sbom'.
+func WithSBOM(withSBOM bool) PushOpt {
+	return func(p *Push) {
+		p.withSBOM = withSBOM
+	}
+}
+
 // WithPushOptWriter sets the registryOut field on the push configuration object.
 func WithPushOptWriter(out io.Writer) PushOpt {
 	return func(p *Push) {
@@ -100,6 +108,7 @@ func (p *Push) Run(chartRef string, remote string) (string, error) {
 			pusher.WithTLSClientConfig(p.certFile, p.keyFile, p.caFile),
 			pusher.WithInsecureSkipTLSVerify(p.insecureSkipTLSverify),
 			pusher.WithPlainHTTP(p.plainHTTP),
+			pusher.WithSBOM(p.withSBOM),
 		},
 	}
 
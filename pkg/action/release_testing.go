@@ -20,12 +20,18 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"slices"
 	"sort"
 	"time"
 
+	"github.com/mitchellh/copystructure"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
 
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/kube"
@@ -47,6 +53,18 @@ type ReleaseTesting struct {
 	Namespace string
 	Filters   map[string][]string
 	HideNotes bool
+	// LabelSelector selects the tests to run by the labels of their
+	// manifests, along with Filters.
+	LabelSelector string
+	// Parallelism is the number of tests of the same weight that run at the
+	// same time. Tests run one at a time when it is 1 or less.
+	Parallelism int
+	// Values override the values of the release to render the tests before
+	// running them, such as to test other endpoints than the release uses. Only
+	// the tests are rendered again: the release is not upgraded and keeps the
+	// manifests of its tests. The conditions and imports of the dependencies
+	// of the chart remain those of the release.
+	Values map[string]interface{}
 }
 
 // NewReleaseTesting creates a new ReleaseTesting object with the given configuration.
@@ -73,6 +91,19 @@ func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
 		return rel, err
 	}
 
+	if _, err := labels.Parse(r.LabelSelector); err != nil {
+		return nil, errors.Wrapf(err, "invalid label selector %q", r.LabelSelector)
+	}
+
+	var storedHooks []*release.Hook
+	if len(r.Values) > 0 {
+		hooks, err := r.renderTests(rel)
+		if err != nil {
+			return rel, errors.Wrap(err, "unable to render the tests with the given values")
+		}
+		storedHooks, rel.Hooks = rel.Hooks, hooks
+	}
+
 	skippedHooks := []*release.Hook{}
 	executingHooks := []*release.Hook{}
 	if len(r.Filters[ExcludeNameFilter]) != 0 {
@@ -96,17 +127,92 @@ func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
 		}
 		rel.Hooks = executingHooks
 	}
+	if r.LabelSelector != "" {
+		executingHooks = nil
+		for _, h := range rel.Hooks {
+			if r.selected(h) {
+				executingHooks = append(executingHooks, h)
+			} else {
+				skippedHooks = append(skippedHooks, h)
+			}
+		}
+		rel.Hooks = executingHooks
+	}
 
-	if err := r.cfg.execHook(rel, release.HookTest, kube.StatusWatcherStrategy, r.Timeout); err != nil {
-		rel.Hooks = append(skippedHooks, rel.Hooks...)
+	if err := r.cfg.execHook(context.Background(), rel, release.HookTest, nil, r.Parallelism, kube.StatusWatcherStrategy, r.Timeout); err != nil {
+		rel.Hooks = restoreTests(storedHooks, append(skippedHooks, rel.Hooks...))
 		r.cfg.Releases.Update(rel)
 		return rel, err
 	}
 
-	rel.Hooks = append(skippedHooks, rel.Hooks...)
+	rel.Hooks = restoreTests(storedHooks, append(skippedHooks, rel.Hooks...))
 	return rel, r.cfg.Releases.Update(rel)
 }
 
+// renderTests renders the chart of the release with the values of the release
+// overridden by Values, and returns the hooks of the release with its tests
+// replaced by the rendered ones.
+func (r *ReleaseTesting) renderTests(rel *release.Release) ([]*release.Hook, error) {
+	overrides, err := copystructure.Copy(r.Values)
+	if err != nil {
+		return nil, err
+	}
+	vals := chartutil.CoalesceTables(overrides.(map[string]interface{}), rel.Config)
+
+	caps, err := r.cfg.getCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	options := chartutil.ReleaseOptions{
+		Name:        rel.Name,
+		Namespace:   rel.Namespace,
+		Revision:    rel.Version,
+		IsInstall:   rel.Version == 1,
+		IsUpgrade:   rel.Version > 1,
+		HookOutputs: rel.HookOutputs,
+	}
+	valuesToRender, err := chartutil.ToRenderValuesWithSchemaValidation(rel.Chart, vals, options, caps, false)
+	if err != nil {
+		return nil, err
+	}
+	rendered, _, _, err := r.cfg.renderResources(rel.Chart, valuesToRender, "", "", false, false, false, nil, true, renderOptions{}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []*release.Hook
+	for _, h := range rel.Hooks {
+		if !isTestHook(h) {
+			hooks = append(hooks, h)
+		}
+	}
+	for _, h := range rendered {
+		if isTestHook(h) {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks, nil
+}
+
+// restoreTests returns the hooks stored with the release, with the results of
+// the tests run from their rendered hooks. The hooks run are returned as they
+// are when the tests were not rendered again.
+func restoreTests(stored, run []*release.Hook) []*release.Hook {
+	if stored == nil {
+		return run
+	}
+	lastRuns := make(map[string]release.HookExecution, len(run))
+	for _, h := range run {
+		lastRuns[h.Name] = h.LastRun
+	}
+	for _, h := range stored {
+		if lastRun, ok := lastRuns[h.Name]; ok && isTestHook(h) {
+			h.LastRun = lastRun
+		}
+	}
+	return stored
+}
+
 // GetPodLogs will write the logs for all test pods in the given release into
 // the given writer. These can be immediately output to the user or captured for
 // other uses
@@ -119,28 +225,79 @@ func (r *ReleaseTesting) GetPodLogs(out io.Writer, rel *release.Release) error {
 	hooksByWight := append([]*release.Hook{}, rel.Hooks...)
 	sort.Stable(hookByWeight(hooksByWight))
 	for _, h := range hooksByWight {
-		for _, e := range h.Events {
-			if e == release.HookTest {
-				if slices.Contains(r.Filters[ExcludeNameFilter], h.Name) {
-					continue
-				}
-				if len(r.Filters[IncludeNameFilter]) > 0 && !slices.Contains(r.Filters[IncludeNameFilter], h.Name) {
-					continue
-				}
-				req := client.CoreV1().Pods(r.Namespace).GetLogs(h.Name, &v1.PodLogOptions{})
-				logReader, err := req.Stream(context.Background())
-				if err != nil {
-					return errors.Wrapf(err, "unable to get pod logs for %s", h.Name)
-				}
-
-				fmt.Fprintf(out, "POD LOGS: %s\n", h.Name)
-				_, err = io.Copy(out, logReader)
-				fmt.Fprintln(out)
-				if err != nil {
-					return errors.Wrapf(err, "unable to write pod logs for %s", h.Name)
-				}
-			}
+		if !isTestHook(h) || r.filteredOut(h) {
+			continue
+		}
+		if err := r.writePodLogs(client, out, h); err != nil {
+			return err
 		}
 	}
 	return nil
 }
+
+// writePodLogs writes the logs of the containers of the pod of a test.
+func (r *ReleaseTesting) writePodLogs(client kubernetes.Interface, out io.Writer, h *release.Hook) error {
+	pod, err := client.CoreV1().Pods(r.Namespace).Get(context.Background(), h.Name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "unable to get pod %s", h.Name)
+	}
+	containers := kube.ContainersWithLogs(pod)
+	for _, container := range containers {
+		req := client.CoreV1().Pods(r.Namespace).GetLogs(h.Name, &v1.PodLogOptions{Container: container.Name})
+		logReader, err := req.Stream(context.Background())
+		if err != nil {
+			return errors.Wrapf(err, "unable to get pod logs for %s", h.Name)
+		}
+
+		// Flag the container only when the pod has more than one,
+		// so single container test pods keep the plain heading.
+		if len(containers) > 1 || container.Type != kube.RegularContainerType {
+			fmt.Fprintf(out, "POD LOGS: %s (%s: %s)\n", h.Name, container.Type, container.Name)
+		} else {
+			fmt.Fprintf(out, "POD LOGS: %s\n", h.Name)
+		}
+		_, err = io.Copy(out, logReader)
+		logReader.Close()
+		fmt.Fprintln(out)
+		if err != nil {
+			return errors.Wrapf(err, "unable to write pod logs for %s", h.Name)
+		}
+	}
+	return nil
+}
+
+// filteredOut reports whether the filters exclude a test.
+func (r *ReleaseTesting) filteredOut(h *release.Hook) bool {
+	if slices.Contains(r.Filters[ExcludeNameFilter], h.Name) {
+		return true
+	}
+	if len(r.Filters[IncludeNameFilter]) > 0 && !slices.Contains(r.Filters[IncludeNameFilter], h.Name) {
+		return true
+	}
+	return !r.selected(h)
+}
+
+// selected reports whether the labels of a test match the label selector.
+func (r *ReleaseTesting) selected(h *release.Hook) bool {
+	if r.LabelSelector == "" {
+		return true
+	}
+	selector, err := labels.Parse(r.LabelSelector)
+	if err != nil {
+		return false
+	}
+	var head struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := yaml.Unmarshal([]byte(h.Manifest), &head); err != nil {
+		slog.Warn("unable to read the labels of the test", "name", h.Name, slog.Any("error", err))
+		return false
+	}
+	return selector.Matches(labels.Set(head.Metadata.Labels))
+}
+
+func isTestHook(h *release.Hook) bool {
+	return slices.Contains(h.Events, release.HookTest)
+}
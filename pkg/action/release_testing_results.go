@@ -0,0 +1,230 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+	helmtime "helm.sh/helm/v4/pkg/time"
+)
+
+// TestStatus is the outcome of a test.
+type TestStatus string
+
+const (
+	// TestPassed is the status of a test whose pod succeeded.
+	TestPassed TestStatus = "passed"
+	// TestFailed is the status of a test whose pod failed.
+	TestFailed TestStatus = "failed"
+	// TestSkipped is the status of a test excluded by the filters, or never run.
+	TestSkipped TestStatus = "skipped"
+	// TestUnknown is the status of a test whose outcome was not recorded.
+	TestUnknown TestStatus = "unknown"
+)
+
+// TestResult is the result of a single test of a release.
+type TestResult struct {
+	Name        string        `json:"name"`
+	Status      TestStatus    `json:"status"`
+	StartedAt   helmtime.Time `json:"started_at,omitempty"`
+	CompletedAt helmtime.Time `json:"completed_at,omitempty"`
+	// Duration is the time the test took to complete.
+	Duration time.Duration `json:"duration"`
+	// Logs are the logs of the test pod.
+	Logs string `json:"logs,omitempty"`
+}
+
+// TestSuiteResult is the result of the tests of a release.
+type TestSuiteResult struct {
+	Release   string       `json:"release"`
+	Namespace string       `json:"namespace"`
+	Revision  int          `json:"revision"`
+	Tests     []TestResult `json:"tests"`
+}
+
+// Results returns the results of the tests of a release run by Run, in the
+// order they ran. When withLogs is set, the logs of the test pods that ran are
+// fetched, falling back to the logs kept in the release for the pods that no
+// longer exist.
+func (r *ReleaseTesting) Results(rel *release.Release, withLogs bool) (*TestSuiteResult, error) {
+	suite := &TestSuiteResult{
+		Release:   rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+		Tests:     []TestResult{},
+	}
+
+	// tested are the hooks of the tests, in the order of suite.Tests.
+	var tested []*release.Hook
+	hooks := append([]*release.Hook{}, rel.Hooks...)
+	sort.Stable(hookByWeight(hooks))
+	for _, h := range hooks {
+		if !isTestHook(h) {
+			continue
+		}
+		tested = append(tested, h)
+		result := TestResult{Name: h.Name, Status: TestSkipped}
+		if r.filteredOut(h) || h.LastRun.StartedAt.IsZero() {
+			suite.Tests = append(suite.Tests, result)
+			continue
+		}
+
+		result.StartedAt = h.LastRun.StartedAt
+		result.CompletedAt = h.LastRun.CompletedAt
+		if !h.LastRun.CompletedAt.IsZero() {
+			result.Duration = h.LastRun.CompletedAt.Sub(h.LastRun.StartedAt)
+		}
+		switch h.LastRun.Phase {
+		case release.HookPhaseSucceeded:
+			result.Status = TestPassed
+		case release.HookPhaseFailed:
+			result.Status = TestFailed
+		default:
+			result.Status = TestUnknown
+		}
+		result.Logs = h.LastRun.Logs
+		suite.Tests = append(suite.Tests, result)
+	}
+
+	if !withLogs {
+		return suite, nil
+	}
+	client, err := r.cfg.KubernetesClientSet()
+	if err != nil {
+		return suite, errors.Wrap(err, "unable to get kubernetes client to fetch pod logs")
+	}
+	for i, h := range tested {
+		test := &suite.Tests[i]
+		if test.Status == TestSkipped {
+			continue
+		}
+		var logs bytes.Buffer
+		if err := r.writePodLogs(client, &logs, h); err != nil {
+			if test.Logs == "" {
+				slog.Warn("unable to get the logs of the test", "name", h.Name, slog.Any("error", err))
+			}
+			continue
+		}
+		test.Logs = logs.String()
+	}
+	return suite, nil
+}
+
+// Failed returns the number of failed tests.
+func (s *TestSuiteResult) Failed() int {
+	return s.count(TestFailed) + s.count(TestUnknown)
+}
+
+func (s *TestSuiteResult) count(status TestStatus) int {
+	n := 0
+	for _, t := range s.Tests {
+		if t.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteJSON writes the results as JSON.
+func (s *TestSuiteResult) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the results as a JUnit XML report, with a test suite named
+// after the release and a test case per test.
+func (s *TestSuiteResult) WriteJUnit(out io.Writer) error {
+	suite := junitTestSuite{
+		Name:     s.Release,
+		Tests:    len(s.Tests),
+		Failures: s.Failed(),
+		Skipped:  s.count(TestSkipped),
+	}
+	var total time.Duration
+	for _, t := range s.Tests {
+		total += t.Duration
+		tc := junitTestCase{
+			Name:      t.Name,
+			ClassName: fmt.Sprintf("%s.%s", s.Namespace, s.Release),
+			Time:      junitSeconds(t.Duration),
+			SystemOut: t.Logs,
+		}
+		switch t.Status {
+		case TestFailed:
+			tc.Failure = &junitMessage{Message: "test pod failed"}
+		case TestUnknown:
+			tc.Failure = &junitMessage{Message: "test outcome is unknown"}
+		case TestSkipped:
+			tc.Skipped = &junitMessage{Message: "test did not run"}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,239 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/kube"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	release "helm.sh/helm/v4/pkg/release/v1"
+	helmtime "helm.sh/helm/v4/pkg/time"
+)
+
+func testSuiteRelease() *release.Release {
+	started := helmtime.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	return &release.Release{
+		Name:      "angry-panda",
+		Namespace: "default",
+		Version:   1,
+		Hooks: []*release.Hook{
+			{
+				Name:   "connection",
+				Events: []release.HookEvent{release.HookTest},
+				LastRun: release.HookExecution{
+					StartedAt:   started,
+					CompletedAt: started.Add(2 * time.Second),
+					Phase:       release.HookPhaseSucceeded,
+				},
+			},
+			{
+				Name:   "migrations",
+				Weight: 1,
+				Events: []release.HookEvent{release.HookTest},
+				LastRun: release.HookExecution{
+					StartedAt:   started.Add(2 * time.Second),
+					CompletedAt: started.Add(3500 * time.Millisecond),
+					Phase:       release.HookPhaseFailed,
+					Logs:        "pending migration 0042\n",
+				},
+			},
+			{
+				Name:   "load",
+				Weight: 2,
+				Events: []release.HookEvent{release.HookTest},
+				LastRun: release.HookExecution{
+					StartedAt:   started,
+					CompletedAt: started.Add(time.Minute),
+					Phase:       release.HookPhaseSucceeded,
+				},
+			},
+		},
+	}
+}
+
+func TestReleaseTestingResults(t *testing.T) {
+	client := NewReleaseTesting(actionConfigFixture(t))
+	client.Filters[ExcludeNameFilter] = []string{"load"}
+
+	results, err := client.Results(testSuiteRelease(), false)
+	require.NoError(t, err)
+	assert.Equal(t, "angry-panda", results.Release)
+	assert.Equal(t, []TestResult{
+		{
+			Name:        "connection",
+			Status:      TestPassed,
+			StartedAt:   helmtime.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+			CompletedAt: helmtime.Date(2025, 1, 2, 15, 4, 7, 0, time.UTC),
+			Duration:    2 * time.Second,
+		},
+		{
+			Name:        "migrations",
+			Status:      TestFailed,
+			StartedAt:   helmtime.Date(2025, 1, 2, 15, 4, 7, 0, time.UTC),
+			CompletedAt: helmtime.Date(2025, 1, 2, 15, 4, 8, 500000000, time.UTC),
+			Duration:    1500 * time.Millisecond,
+			Logs:        "pending migration 0042\n",
+		},
+		{Name: "load", Status: TestSkipped},
+	}, results.Tests)
+	assert.Equal(t, 1, results.Failed())
+
+	var out bytes.Buffer
+	require.NoError(t, results.WriteJSON(&out))
+	var decoded TestSuiteResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, results.Tests[1].Logs, decoded.Tests[1].Logs)
+
+	out.Reset()
+	require.NoError(t, results.WriteJUnit(&out))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="angry-panda" tests="3" failures="1" skipped="1" time="3.500">
+    <testcase name="connection" classname="default.angry-panda" time="2.000"></testcase>
+    <testcase name="migrations" classname="default.angry-panda" time="1.500">
+      <failure message="test pod failed"></failure>
+      <system-out>pending migration 0042&#xA;</system-out>
+    </testcase>
+    <testcase name="load" classname="default.angry-panda" time="0.000">
+      <skipped message="test did not run"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, out.String())
+}
+
+func TestReleaseTesting_LabelSelector(t *testing.T) {
+	testHook := func(name, labels string) *release.Hook {
+		return &release.Hook{
+			Name:     name,
+			Kind:     "Pod",
+			Manifest: fmt.Sprintf("apiVersion: v1\nkind: Pod\nmetadata:\n  name: %s\n  labels: {%s}\n", name, labels),
+			Events:   []release.HookEvent{release.HookTest},
+		}
+	}
+	cfg := actionConfigFixture(t)
+	rel := releaseStub()
+	rel.Hooks = []*release.Hook{
+		testHook("smoke", "suite: smoke"),
+		testHook("db", "suite: integration, component: db"),
+		testHook("api", "suite: integration, component: api"),
+	}
+	require.NoError(t, cfg.Releases.Create(rel))
+
+	client := NewReleaseTesting(cfg)
+	client.LabelSelector = "suite=integration"
+	client.Filters[ExcludeNameFilter] = []string{"api"}
+	client.Parallelism = 2
+	res, err := client.Run(rel.Name)
+	require.NoError(t, err)
+
+	phases := map[string]release.HookPhase{}
+	for _, h := range res.Hooks {
+		phases[h.Name] = h.LastRun.Phase
+	}
+	assert.Equal(t, map[string]release.HookPhase{"smoke": "", "db": release.HookPhaseSucceeded, "api": ""}, phases)
+
+	results, err := client.Results(res, false)
+	require.NoError(t, err)
+	var statuses []TestStatus
+	for _, test := range results.Tests {
+		statuses = append(statuses, test.Status)
+	}
+	assert.Equal(t, []TestStatus{TestSkipped, TestPassed, TestSkipped}, statuses)
+
+	client.LabelSelector = "suite in (smoke"
+	_, err = client.Run(rel.Name)
+	assert.ErrorContains(t, err, "invalid label selector")
+}
+
+// manifestRecordingKubeClient records the manifests it builds.
+type manifestRecordingKubeClient struct {
+	kubefake.PrintingKubeClient
+	manifests []string
+}
+
+func (c *manifestRecordingKubeClient) Build(r io.Reader, _ bool) (kube.ResourceList, error) {
+	manifest, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	c.manifests = append(c.manifests, string(manifest))
+	return kube.ResourceList{}, nil
+}
+
+func TestReleaseTesting_Values(t *testing.T) {
+	const testTemplate = `apiVersion: v1
+kind: Pod
+metadata:
+  name: connection
+  annotations:
+    "helm.sh/hook": test
+spec:
+  containers:
+  - name: curl
+    image: curl
+    args: ["{{ .Values.endpoint }}", "{{ .Values.port }}"]
+`
+	cfg := actionConfigFixture(t)
+	kubeClient := &manifestRecordingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+	cfg.KubeClient = kubeClient
+
+	rel := releaseStub()
+	rel.Chart = buildChartWithTemplates([]*chart.File{
+		{Name: "templates/tests/connection.yaml", Data: []byte(testTemplate)},
+	}, withValues(map[string]interface{}{"endpoint": "internal", "port": 80}))
+	rel.Config = map[string]interface{}{"port": 8080}
+	storedManifest := `apiVersion: v1
+kind: Pod
+metadata:
+  name: connection
+`
+	rel.Hooks = []*release.Hook{{
+		Name:     "connection",
+		Kind:     "Pod",
+		Path:     "hello/templates/tests/connection.yaml",
+		Manifest: storedManifest,
+		Events:   []release.HookEvent{release.HookTest},
+	}}
+	require.NoError(t, cfg.Releases.Create(rel))
+
+	client := NewReleaseTesting(cfg)
+	client.Values = map[string]interface{}{"endpoint": "external"}
+	res, err := client.Run(rel.Name)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, kubeClient.manifests)
+	assert.Contains(t, kubeClient.manifests[0], `args: ["external", "8080"]`)
+	require.Len(t, res.Hooks, 1)
+	assert.Equal(t, storedManifest, res.Hooks[0].Manifest)
+	assert.Equal(t, release.HookPhaseSucceeded, res.Hooks[0].LastRun.Phase)
+	assert.Equal(t, map[string]interface{}{"endpoint": "external"}, client.Values)
+
+	stored, err := cfg.Releases.Get(rel.Name, rel.Version)
+	require.NoError(t, err)
+	assert.Equal(t, storedManifest, stored.Hooks[0].Manifest)
+}
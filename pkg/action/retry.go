@@ -0,0 +1,57 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// transientErrorMessages are the messages of failures that go away on their
+// own, such as an admission webhook whose pods are restarting or an etcd
+// leader election.
+var transientErrorMessages = []string{
+	"etcdserver: leader changed",
+	"etcdserver: request timed out",
+	"failed calling webhook",
+	"no endpoints available for service",
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+}
+
+// isTransientError reports whether an error is worth retrying, as opposed to
+// an error in the chart or a rejection by the cluster that would fail again.
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) {
+		return true
+	}
+	msg := err.Error()
+	for _, transient := range transientErrorMessages {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
@@ -18,12 +18,15 @@ package action
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/kube"
@@ -42,11 +45,18 @@ type Rollback struct {
 	WaitStrategy  kube.WaitStrategy
 	WaitForJobs   bool
 	DisableHooks  bool
+	SkipHooks     []string // SkipHooks are the names of the hooks that are not run
 	DryRun        bool
 	Recreate      bool // will (if true) recreate pods after a rollback.
 	Force         bool // will (if true) force resource upgrade through uninstall/recreate if needed
 	CleanupOnFail bool
 	MaxHistory    int // MaxHistory limits the maximum number of revisions saved per release
+	// MaxHistoryAge limits the age of the revisions saved per release, except
+	// for the last deployed one
+	MaxHistoryAge time.Duration
+	// HookConcurrency is the number of hooks of the same weight that run at
+	// the same time. Hooks run one at a time when it is 1 or less.
+	HookConcurrency int
 }
 
 // NewRollback creates a new Rollback object with the given configuration.
@@ -58,33 +68,48 @@ func NewRollback(cfg *Configuration) *Rollback {
 
 // Run executes 'helm rollback' against the given release.
 func (r *Rollback) Run(name string) error {
+	return r.RunWithContext(context.Background(), name)
+}
+
+// RunWithContext executes 'helm rollback' against the given release. The
+// rollback stops waiting for the changes to complete when ctx is done.
+func (r *Rollback) RunWithContext(ctx context.Context, name string) error {
+	ctx, span := r.cfg.startSpan(ctx, "helm.rollback", releaseNameKey.String(name))
+	err := r.runWithContext(ctx, name)
+	endSpan(span, err)
+	return err
+}
+
+func (r *Rollback) runWithContext(ctx context.Context, name string) error {
 	if err := r.cfg.KubeClient.IsReachable(); err != nil {
 		return err
 	}
 
 	r.cfg.Releases.MaxHistory = r.MaxHistory
+	r.cfg.Releases.MaxHistoryAge = r.MaxHistoryAge
 
 	slog.Debug("preparing rollback", "name", name)
 	currentRelease, targetRelease, err := r.prepareRollback(name)
 	if err != nil {
 		return err
 	}
+	trace.SpanFromContext(ctx).SetAttributes(append(releaseAttributes(name, targetRelease.Namespace, targetRelease.Chart), releaseRevisionKey.Int(targetRelease.Version))...)
 
 	if !r.DryRun {
 		slog.Debug("creating rolled back release", "name", name)
-		if err := r.cfg.Releases.Create(targetRelease); err != nil {
+		if err := r.cfg.storeNewRelease(targetRelease); err != nil {
 			return err
 		}
 	}
 
 	slog.Debug("performing rollback", "name", name)
-	if _, err := r.performRollback(currentRelease, targetRelease); err != nil {
+	if _, err := r.performRollbackCtx(ctx, currentRelease, targetRelease); err != nil {
 		return err
 	}
 
 	if !r.DryRun {
 		slog.Debug("updating status for rolled back release", "name", name)
-		if err := r.cfg.Releases.Update(targetRelease); err != nil {
+		if err := r.cfg.storeRelease(targetRelease); err != nil {
 			return err
 		}
 	}
@@ -156,12 +181,83 @@ func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Rele
 		Labels:   previousRelease.Labels,
 		Manifest: previousRelease.Manifest,
 		Hooks:    previousRelease.Hooks,
+		// The outputs of the hooks describe the state of the cluster, which
+		// the rollback does not undo.
+		HookOutputs: copyHookOutputs(currentRelease.HookOutputs),
 	}
 
 	return currentRelease, targetRelease, nil
 }
 
-func (r *Rollback) performRollback(currentRelease, targetRelease *release.Release) (*release.Release, error) {
+// rollbackGuard serializes the writes a rollback makes to its release records,
+// including those of its hooks, with its cancellation, so that the records are
+// left alone once it is cancelled and a cancellation does not fail a rollback
+// that completed. Hooks and waits are not guarded, they stop on their own when
+// the context is done.
+type rollbackGuard struct {
+	mu        sync.Mutex
+	cancelled bool
+	finished  bool
+}
+
+// run calls fn with the guard locked, unless the rollback was cancelled. fn
+// must only update the release records, so that it never holds up a
+// cancellation.
+func (g *rollbackGuard) run(ctx context.Context, fn func() error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cancelled {
+		return ctx.Err()
+	}
+	return fn()
+}
+
+// recordRelease returns a function recording a release with cfg, unless the
+// rollback was cancelled.
+func (g *rollbackGuard) recordRelease(ctx context.Context, cfg *Configuration) func(*release.Release) {
+	return func(rel *release.Release) {
+		_ = g.run(ctx, func() error {
+			cfg.recordRelease(rel)
+			return nil
+		})
+	}
+}
+
+func (r *Rollback) performRollbackCtx(ctx context.Context, currentRelease, targetRelease *release.Release) (*release.Release, error) {
+	type Msg struct {
+		r *release.Release
+		e error
+	}
+	resultChan := make(chan Msg, 1)
+
+	guard := &rollbackGuard{}
+	go func() {
+		rel, err := r.performRollback(ctx, guard, currentRelease, targetRelease)
+		resultChan <- Msg{rel, err}
+	}()
+	select {
+	case <-ctx.Done():
+		guard.mu.Lock()
+		if guard.finished {
+			guard.mu.Unlock()
+			msg := <-resultChan
+			return msg.r, msg.e
+		}
+		guard.cancelled = true
+		if !r.DryRun {
+			targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Rollback %q failed: %s", targetRelease.Name, ctx.Err()))
+			r.cfg.recordRelease(targetRelease)
+		}
+		guard.mu.Unlock()
+		return targetRelease, ctx.Err()
+	case msg := <-resultChan:
+		return msg.r, msg.e
+	}
+}
+
+// performRollback applies the target release. Its writes to the release
+// records go through guard, and are skipped once the rollback is cancelled.
+func (r *Rollback) performRollback(ctx context.Context, guard *rollbackGuard, currentRelease, targetRelease *release.Release) (*release.Release, error) {
 	if r.DryRun {
 		slog.Debug("dry run", "name", targetRelease.Name)
 		return targetRelease, nil
@@ -175,10 +271,11 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 	if err != nil {
 		return targetRelease, errors.Wrap(err, "unable to build kubernetes objects from new release manifest")
 	}
+	record := guard.recordRelease(ctx, r.cfg)
 
 	// pre-rollback hooks
 	if !r.DisableHooks {
-		if err := r.cfg.execHook(targetRelease, release.HookPreRollback, r.WaitStrategy, r.Timeout); err != nil {
+		if err := r.cfg.execHookWithRecorder(ctx, targetRelease, record, release.HookPreRollback, r.SkipHooks, r.HookConcurrency, r.WaitStrategy, r.Timeout); err != nil {
 			return targetRelease, err
 		}
 	} else {
@@ -190,16 +287,23 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 	if err != nil {
 		return targetRelease, errors.Wrap(err, "unable to set metadata visitor from target release")
 	}
-	results, err := r.cfg.KubeClient.Update(current, target, r.Force)
+	apply := r.cfg.startApply(ctx, target)
+	results, err := r.cfg.updateResources(current, target, r.Force, apply.option(), r.cfg.appliedOption(targetRelease))
+	apply.end(err)
 
 	if err != nil {
 		msg := fmt.Sprintf("Rollback %q failed: %s", targetRelease.Name, err)
 		slog.Warn(msg)
-		currentRelease.Info.Status = release.StatusSuperseded
-		targetRelease.Info.Status = release.StatusFailed
-		targetRelease.Info.Description = msg
-		r.cfg.recordRelease(currentRelease)
-		r.cfg.recordRelease(targetRelease)
+		if err := guard.run(ctx, func() error {
+			currentRelease.Info.Status = release.StatusSuperseded
+			targetRelease.Info.Status = release.StatusFailed
+			targetRelease.Info.Description = msg
+			r.cfg.recordRelease(currentRelease)
+			r.cfg.recordRelease(targetRelease)
+			return nil
+		}); err != nil {
+			return targetRelease, err
+		}
 		if r.CleanupOnFail {
 			slog.Debug("cleanup on fail set, cleaning up resources", "count", len(results.Created))
 			_, errs := r.cfg.KubeClient.Delete(results.Created)
@@ -224,45 +328,53 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 			slog.Error(err.Error())
 		}
 	}
+
+	// pre-wait hooks
+	if !r.DisableHooks {
+		if err := r.cfg.execHookWithRecorder(ctx, targetRelease, record, release.HookPreWait, r.SkipHooks, r.HookConcurrency, r.WaitStrategy, r.Timeout); err != nil {
+			return targetRelease, err
+		}
+	}
+
 	waiter, err := r.cfg.KubeClient.GetWaiter(r.WaitStrategy)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to set metadata visitor from target release")
 	}
-	if r.WaitForJobs {
-		if err := waiter.WaitWithJobs(target, r.Timeout); err != nil {
+	if err := r.cfg.waitForResources(ctx, targetRelease, waiter, target, r.WaitForJobs, r.Timeout); err != nil {
+		return targetRelease, guard.run(ctx, func() error {
 			targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
 			r.cfg.recordRelease(currentRelease)
 			r.cfg.recordRelease(targetRelease)
-			return targetRelease, errors.Wrapf(err, "release %s failed", targetRelease.Name)
-		}
-	} else {
-		if err := waiter.Wait(target, r.Timeout); err != nil {
-			targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
-			r.cfg.recordRelease(currentRelease)
-			r.cfg.recordRelease(targetRelease)
-			return targetRelease, errors.Wrapf(err, "release %s failed", targetRelease.Name)
-		}
+			return errors.Wrapf(err, "release %s failed", targetRelease.Name)
+		})
 	}
 
 	// post-rollback hooks
 	if !r.DisableHooks {
-		if err := r.cfg.execHook(targetRelease, release.HookPostRollback, r.WaitStrategy, r.Timeout); err != nil {
+		if err := r.cfg.execHookWithRecorder(ctx, targetRelease, record, release.HookPostRollback, r.SkipHooks, r.HookConcurrency, r.WaitStrategy, r.Timeout); err != nil {
 			return targetRelease, err
 		}
 	}
 
-	deployed, err := r.cfg.Releases.DeployedAll(currentRelease.Name)
-	if err != nil && !strings.Contains(err.Error(), "has no deployed releases") {
+	err = guard.run(ctx, func() error {
+		deployed, err := r.cfg.Releases.DeployedAll(currentRelease.Name)
+		if err != nil && !strings.Contains(err.Error(), "has no deployed releases") {
+			return err
+		}
+		// Supersede all previous deployments, see issue #2941.
+		for _, rel := range deployed {
+			slog.Debug("superseding previous deployment", "version", rel.Version)
+			rel.Info.Status = release.StatusSuperseded
+			r.cfg.recordRelease(rel)
+		}
+
+		targetRelease.Info.Status = release.StatusDeployed
+		guard.finished = true
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	// Supersede all previous deployments, see issue #2941.
-	for _, rel := range deployed {
-		slog.Debug("superseding previous deployment", "version", rel.Version)
-		rel.Info.Status = release.StatusSuperseded
-		r.cfg.recordRelease(rel)
-	}
-
-	targetRelease.Info.Status = release.StatusDeployed
 
 	return targetRelease, nil
 }
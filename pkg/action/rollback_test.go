@@ -0,0 +1,221 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/kube"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// slowUpdateKubeClient signals updating when an update starts, and finishes
+// it after delay.
+type slowUpdateKubeClient struct {
+	*kubefake.FailingKubeClient
+	delay    time.Duration
+	updating chan struct{}
+}
+
+func (c *slowUpdateKubeClient) UpdateWithOptions(original, target kube.ResourceList, force bool, opts ...kube.UpdateOption) (*kube.Result, error) {
+	close(c.updating)
+	time.Sleep(c.delay)
+	return c.FailingKubeClient.UpdateWithOptions(original, target, force, opts...)
+}
+
+// blockingHookKubeClient watches hooks until the context is done.
+type blockingHookKubeClient struct {
+	*kubefake.FailingKubeClient
+	watching chan struct{}
+}
+
+func (c *blockingHookKubeClient) GetWaiter(ws kube.WaitStrategy) (kube.Waiter, error) {
+	waiter, err := c.FailingKubeClient.GetWaiter(ws)
+	return &blockingHookWaiter{Waiter: waiter, watching: c.watching}, err
+}
+
+type blockingHookWaiter struct {
+	kube.Waiter
+	watching chan struct{}
+}
+
+func (w *blockingHookWaiter) WatchUntilReadyWithContext(ctx context.Context, _ kube.ResourceList, timeout time.Duration) error {
+	close(w.watching)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return errors.New("timed out waiting for the hook")
+	}
+}
+
+func TestRollbackInterruptedDuringHook(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	client := &blockingHookKubeClient{
+		FailingKubeClient: cfg.KubeClient.(*kubefake.FailingKubeClient),
+		watching:          make(chan struct{}),
+	}
+	cfg.KubeClient = client
+
+	previous := namedReleaseStub("interrupted", release.StatusSuperseded)
+	previous.Hooks = previous.Hooks[:1]
+	previous.Hooks[0].Events = []release.HookEvent{release.HookPreRollback}
+	require.NoError(t, cfg.Releases.Create(previous))
+	current := namedReleaseStub("interrupted", release.StatusDeployed)
+	current.Version = 2
+	require.NoError(t, cfg.Releases.Create(current))
+
+	rollback := NewRollback(cfg)
+	rollback.Version = 1
+	rollback.Timeout = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-client.watching
+		cancel()
+	}()
+	start := time.Now()
+	err := rollback.RunWithContext(ctx, "interrupted")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), rollback.Timeout/2, "the cancelled rollback waited for the hook to time out")
+
+	rel, err := cfg.Releases.Get("interrupted", 3)
+	require.NoError(t, err)
+	assert.Equal(t, release.StatusFailed, rel.Info.Status)
+}
+
+// slowHookKubeClient signals watching when a hook is first watched, and
+// finishes watching hooks after delay without stopping when the context is
+// done.
+type slowHookKubeClient struct {
+	*kubefake.FailingKubeClient
+	delay    time.Duration
+	watching chan struct{}
+	once     sync.Once
+}
+
+func (c *slowHookKubeClient) GetWaiter(_ kube.WaitStrategy) (kube.Waiter, error) {
+	return &slowHookWaiter{PrintingKubeWaiter: &kubefake.PrintingKubeWaiter{Out: io.Discard}, client: c}, nil
+}
+
+type slowHookWaiter struct {
+	*kubefake.PrintingKubeWaiter
+	client *slowHookKubeClient
+}
+
+func (w *slowHookWaiter) WatchUntilReady(_ kube.ResourceList, _ time.Duration) error {
+	w.client.once.Do(func() { close(w.client.watching) })
+	time.Sleep(w.client.delay)
+	return nil
+}
+
+func TestRollbackInterruptedDuringPreRollbackHook(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	client := &slowHookKubeClient{
+		FailingKubeClient: cfg.KubeClient.(*kubefake.FailingKubeClient),
+		delay:             200 * time.Millisecond,
+		watching:          make(chan struct{}),
+	}
+	cfg.KubeClient = client
+
+	previous := namedReleaseStub("interrupted", release.StatusSuperseded)
+	previous.Hooks = []*release.Hook{
+		{Name: "first", Kind: "ConfigMap", Path: "first", Manifest: manifestWithHook, Events: []release.HookEvent{release.HookPreRollback}},
+		{Name: "second", Kind: "ConfigMap", Path: "second", Manifest: manifestWithHook, Events: []release.HookEvent{release.HookPreRollback}, Weight: 1},
+	}
+	require.NoError(t, cfg.Releases.Create(previous))
+	current := namedReleaseStub("interrupted", release.StatusDeployed)
+	current.Version = 2
+	require.NoError(t, cfg.Releases.Create(current))
+
+	rollback := NewRollback(cfg)
+	rollback.Version = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-client.watching
+		cancel()
+	}()
+	err := rollback.RunWithContext(ctx, "interrupted")
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The interrupted rollback finishes the first hook in the background, and
+	// must neither start the next one nor write the failed release again.
+	assert.Never(t, func() bool {
+		rel, err := cfg.Releases.Get("interrupted", 3)
+		require.NoError(t, err)
+		return rel.Info.Status != release.StatusFailed || !rel.Hooks[1].LastRun.StartedAt.IsZero()
+	}, 2*client.delay, 10*time.Millisecond)
+
+	rel, err := cfg.Releases.Get("interrupted", 3)
+	require.NoError(t, err)
+	assert.Equal(t, `Rollback "interrupted" failed: context canceled`, rel.Info.Description)
+	assert.False(t, rel.Hooks[0].LastRun.StartedAt.IsZero())
+}
+
+func TestRollbackInterruptedDuringUpdate(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	client := &slowUpdateKubeClient{
+		FailingKubeClient: cfg.KubeClient.(*kubefake.FailingKubeClient),
+		delay:             200 * time.Millisecond,
+		updating:          make(chan struct{}),
+	}
+	cfg.KubeClient = client
+
+	previous := namedReleaseStub("interrupted", release.StatusSuperseded)
+	require.NoError(t, cfg.Releases.Create(previous))
+	current := namedReleaseStub("interrupted", release.StatusDeployed)
+	current.Version = 2
+	require.NoError(t, cfg.Releases.Create(current))
+
+	rollback := NewRollback(cfg)
+	rollback.Version = 1
+	rollback.DisableHooks = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-client.updating
+		cancel()
+	}()
+	err := rollback.RunWithContext(ctx, "interrupted")
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The interrupted rollback finishes the update in the background, and
+	// must leave the failed release alone.
+	assert.Never(t, func() bool {
+		rel, err := cfg.Releases.Get("interrupted", 3)
+		require.NoError(t, err)
+		return rel.Info.Status != release.StatusFailed
+	}, 2*client.delay, 10*time.Millisecond)
+
+	rel, err := cfg.Releases.Get("interrupted", 3)
+	require.NoError(t, err)
+	assert.Equal(t, release.StatusFailed, rel.Info.Status)
+	assert.Equal(t, `Rollback "interrupted" failed: context canceled`, rel.Info.Description)
+	deployed, err := cfg.Releases.Get("interrupted", 2)
+	require.NoError(t, err)
+	assert.Equal(t, release.StatusDeployed, deployed.Info.Status)
+}
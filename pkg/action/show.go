@@ -120,10 +120,14 @@ func (s *Show) Run(chartpath string) (string, error) {
 	if s.OutputFormat == ShowReadme || s.OutputFormat == ShowAll {
 		readme := findReadme(s.chart.Files)
 		if readme != nil {
+			data, err := readme.GetData()
+			if err != nil {
+				return "", err
+			}
 			if s.OutputFormat == ShowAll {
 				fmt.Fprintln(&out, "---")
 			}
-			fmt.Fprintf(&out, "%s\n", readme.Data)
+			fmt.Fprintf(&out, "%s\n", data)
 		}
 	}
 
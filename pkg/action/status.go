@@ -20,6 +20,8 @@ import (
 	"bytes"
 	"errors"
 
+	"k8s.io/apimachinery/pkg/runtime"
+
 	"helm.sh/helm/v4/pkg/kube"
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -35,6 +37,16 @@ type Status struct {
 	// ShowResourcesTable is used with ShowResources. When true this will cause
 	// the resulting objects to be retrieved as a kind=table.
 	ShowResourcesTable bool
+
+	// TableOptions selects the columns of the resources retrieved as
+	// kind=table.
+	TableOptions kube.TableOptions
+
+	// ShowHealth queries the cluster for the health of the resources of the
+	// release, as judged when waiting for them, and records it in
+	// Info.Health, so that the status reflects the cluster rather than the
+	// last stored state of the release.
+	ShowHealth bool
 }
 
 // NewStatus creates a new Status object with the given configuration.
@@ -69,14 +81,51 @@ func (s *Status) Run(name string) (*release.Release, error) {
 			}
 		}
 
-		resp, err := kubeClient.Get(resources, true)
+		var resp map[string][]runtime.Object
+		if tableClient, ok := kubeClient.(kube.InterfaceTables); ok && s.ShowResourcesTable {
+			resp, err = tableClient.GetTable(resources, true, s.TableOptions)
+		} else {
+			resp, err = kubeClient.Get(resources, true)
+		}
 		if err != nil {
 			return nil, err
 		}
 
 		rel.Info.Resources = resp
 
+		if s.ShowHealth {
+			if rel.Info.Health, err = s.health(rel); err != nil {
+				return nil, err
+			}
+		}
 		return rel, nil
 	}
 	return nil, errors.New("unable to get kubeClient with interface InterfaceResources")
 }
+
+// health returns the health of the live resources of a release.
+func (s *Status) health(rel *release.Release) ([]release.ResourceHealth, error) {
+	healthClient, ok := s.cfg.KubeClient.(kube.InterfaceHealth)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceHealth")
+	}
+	resources, err := s.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+	if err != nil {
+		return nil, err
+	}
+	health, err := healthClient.Health(resources)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]release.ResourceHealth, 0, len(health))
+	for _, h := range health {
+		result = append(result, release.ResourceHealth{
+			Kind:      h.Kind,
+			Namespace: h.Namespace,
+			Name:      h.Name,
+			Status:    string(h.Status),
+			Message:   h.Message,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	releaseutil "helm.sh/helm/v4/pkg/release/util"
+)
+
+var (
+	// templateActionPattern matches the actions of a line of a template.
+	templateActionPattern = regexp.MustCompile(`{{.*?}}`)
+	// valueReferencePattern matches the references to values of a template,
+	// such as .Values.image.tag.
+	valueReferencePattern = regexp.MustCompile(`\$?\.Values(?:\.[A-Za-z0-9_]+)+`)
+)
+
+// locateTemplateLine sets the line of the template that the line a rendered
+// template failed to parse at was rendered from, along with the values the
+// line of the template uses.
+//
+// Templates render lines from the text around their actions, so the line is
+// found by matching the text of the lines of the template, taking their
+// actions as wildcards. When several lines match, the one with the most text
+// is taken, then the one closest to the rendered line. None is taken when the
+// lines of the template are only made of actions.
+func locateTemplateLine(ch *chart.Chart, parseErr *releaseutil.YAMLParseError) {
+	if parseErr.Line == 0 {
+		return
+	}
+	tpl := chartTemplate(ch, parseErr.Path)
+	if tpl == nil {
+		return
+	}
+
+	best, bestText := 0, 0
+	for i, line := range strings.Split(string(tpl.Data), "\n") {
+		text := matchTemplateLine(line, parseErr.Content)
+		if text == 0 {
+			continue
+		}
+		if text > bestText || (text == bestText && distance(i+1, parseErr.Line) < distance(best, parseErr.Line)) {
+			best, bestText = i+1, text
+			parseErr.References = valueReferencePattern.FindAllString(line, -1)
+		}
+	}
+	parseErr.TemplateLine = best
+}
+
+// matchTemplateLine returns the length of the text of a line of a template
+// when the line can render a line, and zero otherwise.
+func matchTemplateLine(templateLine, rendered string) int {
+	literals := templateActionPattern.Split(strings.TrimSpace(templateLine), -1)
+	text := 0
+	for i := range literals {
+		literals[i] = strings.TrimSpace(literals[i])
+		text += len(literals[i])
+		literals[i] = regexp.QuoteMeta(literals[i])
+	}
+	if text == 0 {
+		return 0
+	}
+	pattern, err := regexp.Compile(`^\s*` + strings.Join(literals, `.*`) + `\s*$`)
+	if err != nil || !pattern.MatchString(rendered) {
+		return 0
+	}
+	return text
+}
+
+func distance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// chartTemplate returns the template of a chart or of its dependencies at a
+// path, such as mychart/charts/db/templates/service.yaml.
+func chartTemplate(ch *chart.Chart, tplPath string) *chart.File {
+	fullPath := ch.ChartFullPath()
+	if !strings.HasPrefix(tplPath, fullPath+"/") {
+		return nil
+	}
+	for _, t := range ch.Templates {
+		if path.Join(fullPath, t.Name) == tplPath {
+			return t
+		}
+	}
+	for _, dep := range ch.Dependencies() {
+		if t := chartTemplate(dep, tplPath); t != nil {
+			return t
+		}
+	}
+	return nil
+}
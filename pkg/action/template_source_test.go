@@ -0,0 +1,77 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	releaseutil "helm.sh/helm/v4/pkg/release/util"
+)
+
+func TestInstallReleaseLocatesYAMLParseErrors(t *testing.T) {
+	const configMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}
+data:
+  {{- range $key, $value := .Values.settings }}
+  {{ $key }}: {{ $value | quote }}
+  {{- end }}
+  greeting: {{ .Values.greeting }}
+  port: "80"
+`
+	dependency := buildChartWithTemplates([]*chart.File{
+		{Name: "templates/configmap.yaml", Data: []byte(configMap)},
+	}, withName("greeter"))
+	ch := buildChart(withDependency(), withValues(map[string]interface{}{
+		"greeter": map[string]interface{}{
+			"settings": map[string]interface{}{"a": "1", "b": "2"},
+			"greeting": "hello: world",
+		},
+	}))
+	ch.SetDependencies(dependency)
+
+	instAction := installAction(t)
+	_, err := instAction.Run(ch, map[string]interface{}{})
+
+	var parseErr *releaseutil.YAMLParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, "hello/charts/greeter/templates/configmap.yaml", parseErr.Path)
+	assert.Equal(t, 8, parseErr.Line)
+	assert.Equal(t, "greeting: hello: world", parseErr.Content)
+	assert.Equal(t, 9, parseErr.TemplateLine)
+	assert.Equal(t, []string{".Values.greeting"}, parseErr.References)
+	assert.Contains(t, err.Error(), "rendered from line 9 of the template using .Values.greeting")
+}
+
+func TestMatchTemplateLine(t *testing.T) {
+	for _, test := range []struct {
+		templateLine, rendered string
+		text                   int
+	}{
+		{"  image: {{ .Values.image }}:{{ .Values.tag }}", "image: nginx:1.0", 7},
+		{"  image: {{ .Values.image }}", "  name: nginx", 0},
+		{"{{- include \"labels\" . | nindent 4 }}", "app: nginx", 0},
+		{"  {{ $key }}: {{ $value }}", "app: nginx", 1},
+	} {
+		assert.Equal(t, test.text, matchTemplateLine(test.templateLine, test.rendered), "%q on %q", test.templateLine, test.rendered)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+// tracerName is the name of the tracer of the spans of the actions.
+const tracerName = "helm.sh/helm/v4/pkg/action"
+
+// Attributes of the spans of the actions.
+const (
+	releaseNameKey      = attribute.Key("helm.release.name")
+	releaseNamespaceKey = attribute.Key("helm.release.namespace")
+	releaseRevisionKey  = attribute.Key("helm.release.revision")
+	chartNameKey        = attribute.Key("helm.chart.name")
+	chartVersionKey     = attribute.Key("helm.chart.version")
+	hookEventKey        = attribute.Key("helm.hook.event")
+	hookNameKey         = attribute.Key("helm.hook.name")
+	hookKindKey         = attribute.Key("helm.hook.kind")
+	resourceKindKey     = attribute.Key("helm.resource.kind")
+	resourceCountKey    = attribute.Key("helm.resource.count")
+)
+
+func (cfg *Configuration) tracer() trace.Tracer {
+	tp := cfg.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
+
+// startSpan starts a span of an action, as a child of the span in ctx.
+func (cfg *Configuration) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return cfg.tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends a span, recording err as its status.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func releaseAttributes(name, namespace string, chrt *chart.Chart) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{releaseNameKey.String(name), releaseNamespaceKey.String(namespace)}
+	if chrt != nil && chrt.Metadata != nil {
+		attrs = append(attrs, chartNameKey.String(chrt.Metadata.Name), chartVersionKey.String(chrt.Metadata.Version))
+	}
+	return attrs
+}
+
+// applyTracer traces applying the resources of a release. Its span has a
+// child span per kind of resource, covering the time the resources of that
+// kind took to apply, when the resources are applied by Update.
+type applyTracer struct {
+	cfg  *Configuration
+	ctx  context.Context
+	span trace.Span
+
+	mu       sync.Mutex
+	kind     string
+	kindSpan trace.Span
+	count    int
+	last     time.Time
+}
+
+// startApply starts the span of applying the resources.
+func (cfg *Configuration) startApply(ctx context.Context, resources kube.ResourceList) *applyTracer {
+	ctx, span := cfg.startSpan(ctx, "helm.apply", resourceCountKey.Int(len(resources)))
+	return &applyTracer{cfg: cfg, ctx: ctx, span: span, last: time.Now()}
+}
+
+// option returns the UpdateOption recording the resources applied by Update.
+func (t *applyTracer) option() kube.UpdateOption {
+	return kube.OnApplied(t.applied)
+}
+
+func (t *applyTracer) applied(info *resource.Info) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kind := info.Object.GetObjectKind().GroupVersionKind().Kind
+	if info.Mapping != nil {
+		kind = info.Mapping.GroupVersionKind.Kind
+	}
+	if t.kindSpan == nil || kind != t.kind {
+		t.endKind()
+		t.kind = kind
+		_, t.kindSpan = t.cfg.tracer().Start(t.ctx, "helm.apply "+kind, trace.WithTimestamp(t.last), trace.WithAttributes(resourceKindKey.String(kind)))
+	}
+	t.count++
+	t.last = time.Now()
+}
+
+func (t *applyTracer) endKind() {
+	if t.kindSpan == nil {
+		return
+	}
+	t.kindSpan.SetAttributes(resourceCountKey.Int(t.count))
+	t.kindSpan.End(trace.WithTimestamp(t.last))
+	t.kindSpan = nil
+	t.count = 0
+}
+
+// end ends the span of applying the resources, recording err as its status.
+func (t *applyTracer) end(err error) {
+	t.mu.Lock()
+	t.endKind()
+	t.mu.Unlock()
+	endSpan(t.span, err)
+}
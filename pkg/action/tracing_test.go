@@ -0,0 +1,110 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func recordSpans(cfg *Configuration) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	cfg.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return recorder
+}
+
+// spanParents maps the names of the ended spans to the names of their parents.
+func spanParents(recorder *tracetest.SpanRecorder) map[string]string {
+	names := map[string]string{}
+	for _, span := range recorder.Ended() {
+		names[span.SpanContext().SpanID().String()] = span.Name()
+	}
+	parents := map[string]string{}
+	for _, span := range recorder.Ended() {
+		parents[span.Name()] = names[span.Parent().SpanID().String()]
+	}
+	return parents
+}
+
+func TestInstallRelease_Tracing(t *testing.T) {
+	instAction := installAction(t)
+	recorder := recordSpans(instAction.cfg)
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"helm.install":            "",
+		"helm.render":             "helm.install",
+		"helm.apply":              "helm.install",
+		"helm.wait":               "helm.install",
+		"helm.hooks post-install": "helm.install",
+		"helm.hook test-cm":       "helm.hooks post-install",
+	}, spanParents(recorder))
+
+	install := recorder.Ended()[len(recorder.Ended())-1]
+	assert.Equal(t, "helm.install", install.Name())
+	assert.Contains(t, install.Attributes(), attribute.String("helm.release.name", instAction.ReleaseName))
+	assert.Contains(t, install.Attributes(), attribute.String("helm.chart.name", "hello"))
+}
+
+func TestUninstallRelease_Tracing(t *testing.T) {
+	unAction := uninstallAction(t)
+	recorder := recordSpans(unAction.cfg)
+	rel := releaseStub()
+	require.NoError(t, unAction.cfg.Releases.Create(rel))
+
+	_, err := unAction.Run(rel.Name)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"helm.uninstall":        "",
+		"helm.hooks pre-delete": "helm.uninstall",
+		"helm.hook test-cm":     "helm.hooks pre-delete",
+		"helm.delete":           "helm.uninstall",
+		"helm.wait":             "helm.uninstall",
+	}, spanParents(recorder))
+}
+
+func TestApplyTracer(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	recorder := recordSpans(cfg)
+	servicesGVR := corev1.SchemeGroupVersion.WithResource("services")
+
+	apply := cfg.startApply(context.Background(), nil)
+	apply.applied(newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "first"))
+	apply.applied(newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "second"))
+	apply.applied(newPreflightInfo(servicesGVR, "Service", "spaced", "web"))
+	apply.end(nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "helm.apply ConfigMap", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("helm.resource.count", 2))
+	assert.Equal(t, "helm.apply Service", spans[1].Name())
+	assert.Contains(t, spans[1].Attributes(), attribute.Int("helm.resource.count", 1))
+	assert.False(t, spans[1].StartTime().Before(spans[0].EndTime()))
+	assert.Equal(t, "helm.apply", spans[2].Name())
+	assert.Equal(t, spans[2].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
@@ -17,7 +17,11 @@ limitations under the License.
 package action
 
 import (
+	"context"
+	stderrors "errors"
+	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
@@ -57,9 +61,22 @@ func NewUninstall(cfg *Configuration) *Uninstall {
 
 // Run uninstalls the given release.
 func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error) {
+	ctx, span := u.cfg.startSpan(context.Background(), "helm.uninstall", releaseNameKey.String(name))
+	res, err := u.run(ctx, name)
+	if res != nil && res.Release != nil {
+		span.SetAttributes(append(releaseAttributes(name, res.Release.Namespace, res.Release.Chart), releaseRevisionKey.Int(res.Release.Version))...)
+	}
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *Uninstall) run(ctx context.Context, name string) (*release.UninstallReleaseResponse, error) {
 	if err := u.cfg.KubeClient.IsReachable(); err != nil {
 		return nil, err
 	}
+	if err := u.cfg.DeletionOrder.Validate(); err != nil {
+		return nil, err
+	}
 
 	waiter, err := u.cfg.KubeClient.GetWaiter(u.WaitStrategy)
 	if err != nil {
@@ -112,7 +129,7 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 	res := &release.UninstallReleaseResponse{Release: rel}
 
 	if !u.DisableHooks {
-		if err := u.cfg.execHook(rel, release.HookPreDelete, u.WaitStrategy, u.Timeout); err != nil {
+		if err := u.cfg.execHook(ctx, rel, release.HookPreDelete, nil, 0, u.WaitStrategy, u.Timeout); err != nil {
 			return res, err
 		}
 	} else {
@@ -121,11 +138,14 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 
 	// From here on out, the release is currently considered to be in StatusUninstalling
 	// state.
-	if err := u.cfg.Releases.Update(rel); err != nil {
+	if err := u.cfg.storeRelease(rel); err != nil {
 		slog.Debug("uninstall: Failed to store updated release", slog.Any("error", err))
 	}
 
+	_, deleteSpan := u.cfg.startSpan(ctx, "helm.delete")
 	deletedResources, kept, errs := u.deleteRelease(rel)
+	deleteSpan.SetAttributes(resourceCountKey.Int(len(deletedResources)))
+	endSpan(deleteSpan, stderrors.Join(errs...))
 	if errs != nil {
 		slog.Debug("uninstall: Failed to delete release", slog.Any("error", errs))
 		return nil, errors.Errorf("failed to delete release: %s", name)
@@ -136,12 +156,22 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 	}
 	res.Info = kept
 
-	if err := waiter.WaitForDelete(deletedResources, u.Timeout); err != nil {
+	_, waitSpan := u.cfg.startSpan(ctx, "helm.wait", resourceCountKey.Int(len(deletedResources)))
+	u.cfg.notify(&WaitProgress{Release: rel.Name, Namespace: rel.Namespace, Resources: len(deletedResources)})
+	start := time.Now()
+	err = waiter.WaitForDelete(deletedResources, u.Timeout)
+	u.cfg.notify(&WaitProgress{Release: rel.Name, Namespace: rel.Namespace, Resources: len(deletedResources), Done: true, Elapsed: time.Since(start), Err: err})
+	endSpan(waitSpan, err)
+	if err != nil {
+		var timeoutErr *kube.DeletionTimeoutError
+		if errors.As(err, &timeoutErr) {
+			res.Info += stuckResourcesInfo(timeoutErr.Resources)
+		}
 		errs = append(errs, err)
 	}
 
 	if !u.DisableHooks {
-		if err := u.cfg.execHook(rel, release.HookPostDelete, u.WaitStrategy, u.Timeout); err != nil {
+		if err := u.cfg.execHook(ctx, rel, release.HookPostDelete, nil, 0, u.WaitStrategy, u.Timeout); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -162,18 +192,18 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 
 		// Return the errors that occurred while deleting the release, if any
 		if len(errs) > 0 {
-			return res, errors.Errorf("uninstallation completed with %d error(s): %s", len(errs), joinErrors(errs))
+			return res, UninstallErrors(errs)
 		}
 
 		return res, nil
 	}
 
-	if err := u.cfg.Releases.Update(rel); err != nil {
+	if err := u.cfg.storeRelease(rel); err != nil {
 		slog.Debug("uninstall: Failed to store updated release", slog.Any("error", err))
 	}
 
 	if len(errs) > 0 {
-		return res, errors.Errorf("uninstallation completed with %d error(s): %s", len(errs), joinErrors(errs))
+		return res, UninstallErrors(errs)
 	}
 	return res, nil
 }
@@ -187,6 +217,37 @@ func (u *Uninstall) purgeReleases(rels ...*release.Release) error {
 	return nil
 }
 
+// UninstallErrors reports the errors that occurred while uninstalling a
+// release that was otherwise uninstalled. They can be inspected with
+// errors.As, such as for the *kube.DeletionTimeoutError listing the resources
+// still being deleted when waiting for them timed out.
+type UninstallErrors []error
+
+func (e UninstallErrors) Error() string {
+	return fmt.Sprintf("uninstallation completed with %d error(s): %s", len(e), joinErrors(e))
+}
+
+func (e UninstallErrors) Unwrap() []error {
+	return e
+}
+
+// stuckResourcesInfo describes the resources still being deleted, along with
+// the finalizers holding them up.
+func stuckResourcesInfo(resources []kube.StuckResource) string {
+	if len(resources) == 0 {
+		return ""
+	}
+	info := "These resources were still being deleted when the timeout expired:\n"
+	for _, r := range resources {
+		info += "[" + r.Kind + "] " + r.Name
+		if len(r.Finalizers) > 0 {
+			info += ", waiting on finalizers " + strings.Join(r.Finalizers, ", ")
+		}
+		info += "\n"
+	}
+	return info
+}
+
 func joinErrors(errs []error) string {
 	es := make([]string, 0, len(errs))
 	for _, e := range errs {
@@ -200,7 +261,7 @@ func (u *Uninstall) deleteRelease(rel *release.Release) (kube.ResourceList, stri
 	var errs []error
 
 	manifests := releaseutil.SplitManifests(rel.Manifest)
-	_, files, err := releaseutil.SortManifests(manifests, nil, releaseutil.UninstallOrder)
+	_, files, err := releaseutil.SortManifests(manifests, nil, u.cfg.uninstallSorter())
 	if err != nil {
 		// We could instead just delete everything in no particular order.
 		// FIXME: One way to delete at this point would be to try a label-based
@@ -224,16 +285,68 @@ func (u *Uninstall) deleteRelease(rel *release.Release) (kube.ResourceList, stri
 	if err != nil {
 		return nil, "", []error{errors.Wrap(err, "unable to build kubernetes objects for delete")}
 	}
-	if len(resources) > 0 {
-		if kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceDeletionPropagation); ok {
-			_, errs = kubeClient.DeleteWithPropagationPolicy(resources, parseCascadingFlag(u.DeletionPropagation))
+
+	// The resources of an uninstall weight are gone before the resources of
+	// the next one are deleted, such as for the finalizers of custom resources
+	// to run before their operator is deleted.
+	groups := groupByUninstallWeight(resources)
+	for i, group := range groups {
+		if errs = u.deleteResources(group); errs != nil {
 			return resources, kept, errs
 		}
-		_, errs = u.cfg.KubeClient.Delete(resources)
+		if i == len(groups)-1 {
+			break
+		}
+		if err := u.waitForDeletion(group); err != nil {
+			return resources, kept, []error{errors.Wrapf(err, "resources of uninstall weight %d are not deleted", releaseutil.UninstallWeight(resourceHead(group[0])))}
+		}
 	}
 	return resources, kept, errs
 }
 
+func (u *Uninstall) deleteResources(resources kube.ResourceList) []error {
+	if kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceDeletionPropagation); ok {
+		_, errs := kubeClient.DeleteWithPropagationPolicy(resources, parseCascadingFlag(u.DeletionPropagation))
+		return errs
+	}
+	_, errs := u.cfg.KubeClient.Delete(resources)
+	return errs
+}
+
+// waitForDeletion waits for deleted resources to be gone from the cluster.
+func (u *Uninstall) waitForDeletion(resources kube.ResourceList) error {
+	// The hook-only waiter does not wait for deletions on its own.
+	strategy := u.WaitStrategy
+	if strategy == kube.HookOnlyStrategy {
+		strategy = kube.StatusWatcherStrategy
+	}
+	waiter, err := u.cfg.KubeClient.GetWaiter(strategy)
+	if err != nil {
+		return err
+	}
+	return waiter.WaitForDelete(resources, u.Timeout)
+}
+
+// groupByUninstallWeight splits resources by their uninstall weight, by
+// ascending weight.
+func groupByUninstallWeight(resources kube.ResourceList) []kube.ResourceList {
+	byWeight := map[int]kube.ResourceList{}
+	for _, info := range resources {
+		weight := releaseutil.UninstallWeight(resourceHead(info))
+		byWeight[weight] = append(byWeight[weight], info)
+	}
+	weights := make([]int, 0, len(byWeight))
+	for weight := range byWeight {
+		weights = append(weights, weight)
+	}
+	sort.Ints(weights)
+	groups := make([]kube.ResourceList, 0, len(weights))
+	for _, weight := range weights {
+		groups = append(groups, byWeight[weight])
+	}
+	return groups
+}
+
 func parseCascadingFlag(cascadingFlag string) v1.DeletionPropagation {
 	switch cascadingFlag {
 	case "orphan":
@@ -17,13 +17,19 @@ limitations under the License.
 package action
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
 
 	"helm.sh/helm/v4/pkg/kube"
 	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	releaseutil "helm.sh/helm/v4/pkg/release/util"
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
@@ -108,6 +114,37 @@ func TestUninstallRelease_Wait(t *testing.T) {
 	is.Equal(res.Release.Info.Status, release.StatusUninstalled)
 }
 
+func TestUninstallRelease_WaitStuckFinalizers(t *testing.T) {
+	is := assert.New(t)
+
+	unAction := uninstallAction(t)
+	unAction.DisableHooks = true
+	unAction.WaitStrategy = kube.StatusWatcherStrategy
+
+	rel := releaseStub()
+	rel.Name = "stuck"
+	unAction.cfg.Releases.Create(rel)
+	failer := unAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.WaitForDeleteError = &kube.DeletionTimeoutError{
+		Resources: []kube.StuckResource{{
+			Kind:       "PersistentVolumeClaim",
+			Namespace:  "spaced",
+			Name:       "data",
+			Status:     "Terminating",
+			Finalizers: []string{"kubernetes.io/pvc-protection"},
+		}},
+		Err: context.DeadlineExceeded,
+	}
+	res, err := unAction.Run(rel.Name)
+	is.Error(err)
+
+	var timeoutErr *kube.DeletionTimeoutError
+	is.ErrorAs(err, &timeoutErr)
+	is.Equal("data", timeoutErr.Resources[0].Name)
+	is.Contains(err.Error(), "finalizers: kubernetes.io/pvc-protection")
+	is.Contains(res.Info, "[PersistentVolumeClaim] data, waiting on finalizers kubernetes.io/pvc-protection")
+}
+
 func TestUninstallRelease_Cascade(t *testing.T) {
 	is := assert.New(t)
 
@@ -139,3 +176,29 @@ func TestUninstallRelease_Cascade(t *testing.T) {
 	is.Error(err)
 	is.Contains(err.Error(), "failed to delete release: come-fail-away")
 }
+
+func TestUninstallRelease_InvalidDeletionOrder(t *testing.T) {
+	unAction := uninstallAction(t)
+	unAction.cfg.DeletionOrder = "alphabetical"
+
+	rel := releaseStub()
+	require.NoError(t, unAction.cfg.Releases.Create(rel))
+	_, err := unAction.Run(rel.Name)
+	assert.EqualError(t, err, `invalid deletion order "alphabetical". Valid inputs are reverse-install and kind`)
+}
+
+func TestGroupByUninstallWeight(t *testing.T) {
+	withWeight := func(info *resource.Info, weight string) *resource.Info {
+		info.Object.(*unstructured.Unstructured).SetAnnotations(map[string]string{releaseutil.UninstallWeightAnnotation: weight})
+		return info
+	}
+	widgetsGVR := schema.GroupVersionResource{Group: "example.io", Version: "v1", Resource: "widgets"}
+	widget := withWeight(newPreflightInfo(widgetsGVR, "Widget", "spaced", "widget"), "-5")
+	config := newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "config")
+	operator := newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "operator")
+	last := withWeight(newPreflightInfo(configMapsGVR, "ConfigMap", "spaced", "last"), "3")
+
+	groups := groupByUninstallWeight(kube.ResourceList{config, last, widget, operator})
+	assert.Equal(t, []kube.ResourceList{{widget}, {config, operator}, {last}}, groups)
+	assert.Empty(t, groupByUninstallWeight(nil))
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/cli-runtime/pkg/resource"
 
 	chart "helm.sh/helm/v4/pkg/chart/v2"
@@ -63,6 +64,9 @@ type Upgrade struct {
 	Namespace string
 	// SkipCRDs skips installing CRDs when install flag is enabled during upgrade
 	SkipCRDs bool
+	// CRDUpgradePolicy decides what the upgrade does with the CRDs in the
+	// crds/ directory of the chart, which are left alone by default.
+	CRDUpgradePolicy CRDUpgradePolicy
 	// Timeout is the timeout for this operation
 	Timeout time.Duration
 	// WaitStrategy determines what type of waiting should be done
@@ -71,6 +75,12 @@ type Upgrade struct {
 	WaitForJobs bool
 	// DisableHooks disables hook processing if set to true.
 	DisableHooks bool
+	// SkipHooks are the names of the hooks that are not run, while the other
+	// hooks are.
+	SkipHooks []string
+	// HookConcurrency is the number of hooks of the same weight that run at
+	// the same time. Hooks run one at a time when it is 1 or less.
+	HookConcurrency int
 	// DryRun controls whether the operation is prepared, but not executed.
 	DryRun bool
 	// DryRunOption controls whether the operation is prepared, but not executed with options on whether or not to interact with the remote cluster.
@@ -92,8 +102,16 @@ type Upgrade struct {
 	Recreate bool
 	// MaxHistory limits the maximum number of revisions saved per release
 	MaxHistory int
+	// MaxHistoryAge limits the age of the revisions saved per release, except
+	// for the last deployed one
+	MaxHistoryAge time.Duration
 	// Atomic, if true, will roll back on failure.
 	Atomic bool
+	// RollbackTimeout bounds the rollback performed when Atomic is set, which
+	// is otherwise only bounded by Timeout for each of its operations. The
+	// rollback is interrupted when the context of the upgrade is cancelled,
+	// unless the upgrade failed because of that cancellation.
+	RollbackTimeout time.Duration
 	// CleanupOnFail will, if true, cause the upgrade to delete newly-created resources on a failed update.
 	CleanupOnFail bool
 	// SubNotes determines whether sub-notes are rendered in the chart.
@@ -102,6 +120,9 @@ type Upgrade struct {
 	HideNotes bool
 	// SkipSchemaValidation determines if JSON schema validation is disabled.
 	SkipSchemaValidation bool
+	// WarnSchemaViolations reports the values schema violations as warnings
+	// of the release instead of failing.
+	WarnSchemaViolations bool
 	// Description is the description of this operation
 	Description string
 	Labels      map[string]string
@@ -118,8 +139,73 @@ type Upgrade struct {
 	Lock sync.Mutex
 	// Enable DNS lookups when rendering templates
 	EnableDNS bool
-	// TakeOwnership will skip the check for helm annotations and adopt all existing resources.
+	// CollectErrors renders all the templates of the chart when one fails,
+	// and reports the errors of all the failing templates together.
+	CollectErrors bool
+	// CacheIncludes caches the output of the calls to 'include' with the same
+	// template and arguments while rendering the chart. It must not be set for
+	// charts whose included templates generate random values.
+	CacheIncludes bool
+	// ParallelRender renders the template files of the chart concurrently.
+	// The files are rendered one at a time when a template modifies its
+	// values, or calls 'tpl' or a custom template function.
+	ParallelRender bool
+	// LookupBudget is the number of objects the 'lookup' calls of the
+	// templates may request from the cluster, or unlimited if 0.
+	LookupBudget int
+	// LookupFixtures, when set, are the objects the 'lookup' calls of the
+	// templates find instead of querying the cluster. They can only be used
+	// with a dry run.
+	LookupFixtures []*unstructured.Unstructured
+	// TakeOwnership adopts the resources the upgrade adds to the release that
+	// already exist, instead of failing the upgrade, unless they are owned by
+	// another release. It follows the same rules as Install.TakeOwnership, so
+	// that 'upgrade --install' adopts the same resources whether or not the
+	// release exists.
 	TakeOwnership bool
+	// PreflightDryRun submits all changes with server-side dry-run before
+	// applying them, so that rejections from validation or admission webhooks
+	// are reported together instead of failing part way through the upgrade.
+	PreflightDryRun bool
+	// DryRunApply submits the changes with server-side dry-run when
+	// DryRunOption is "server", and records the objects the server would
+	// store, as mutated by the admission webhooks, along with every rejection
+	// in the Info.DryRun of the release.
+	DryRunApply bool
+	// PreflightChecks are run before any change is made, and report all their
+	// failures together. See DefaultPreflightChecks.
+	PreflightChecks []PreflightCheck
+	// WaitForDependencies applies resources annotated with kube.DependsOnAnno
+	// only once the resources they depend on are ready.
+	WaitForDependencies bool
+	// NamespacePolicy decides how resources outside of the release namespace
+	// are handled, see kube.CheckNamespacePolicy.
+	NamespacePolicy kube.NamespacePolicy
+	// ServerSideApply applies the resources with server-side apply, which
+	// fails on conflicts with the fields owned by other managers unless
+	// ForceConflicts is set. It cannot be combined with Force.
+	ServerSideApply bool
+	ForceConflicts  bool
+	// CheckpointBatchSize, when greater than zero, records the progress of
+	// the upgrade in the upgraded release after every CheckpointBatchSize
+	// applied resources, so that an interrupted upgrade can be resumed.
+	CheckpointBatchSize int
+	// Resume resumes the upgrade recorded by the last release when it was
+	// interrupted after recording a checkpoint, leaving it pending or failed.
+	// The release is upgraded to the chart and values of the interrupted
+	// upgrade, whose resources are applied from where it stopped, and the
+	// chart and values given to Run are ignored. Without such a release, the
+	// upgrade runs as usual. It must not be used while the interrupted upgrade
+	// may still be running.
+	Resume bool
+	// PruneOrphans deletes, once the resources are applied, the resources
+	// labeled as managed by Helm and annotated as belonging to the release
+	// that are missing from its manifest, such as the ones left behind by a
+	// failed upgrade or an edited release record. The namespaced resources are
+	// looked for in the release namespace and the namespaces of the resources
+	// of the chart. Hooks and the resources with the "keep" resource policy
+	// are never pruned. Failing to prune them does not fail the upgrade.
+	PruneOrphans bool
 }
 
 type resultMessage struct {
@@ -150,6 +236,16 @@ func (u *Upgrade) Run(name string, chart *chart.Chart, vals map[string]interface
 
 // RunWithContext executes the upgrade on the given release with context.
 func (u *Upgrade) RunWithContext(ctx context.Context, name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, error) {
+	ctx, span := u.cfg.startSpan(ctx, "helm.upgrade", releaseAttributes(name, u.Namespace, chart)...)
+	res, err := u.runWithContext(ctx, name, chart, vals)
+	if res != nil {
+		span.SetAttributes(releaseRevisionKey.Int(res.Version))
+	}
+	endSpan(span, err)
+	return res, err
+}
+
+func (u *Upgrade) runWithContext(ctx context.Context, name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, error) {
 	if err := u.cfg.KubeClient.IsReachable(); err != nil {
 		return nil, err
 	}
@@ -164,16 +260,38 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, chart *chart.
 		return nil, errors.Errorf("release name is invalid: %s", name)
 	}
 
-	slog.Debug("preparing upgrade", "name", name)
-	currentRelease, upgradedRelease, err := u.prepareUpgrade(name, chart, vals)
-	if err != nil {
+	if _, err := u.cfg.serverSideApplyOptions(u.ServerSideApply, u.ForceConflicts, u.Force); err != nil {
 		return nil, err
 	}
 
-	u.cfg.Releases.MaxHistory = u.MaxHistory
+	switch u.CRDUpgradePolicy {
+	case CRDSkip, CRDCreateOnly, CRDUpgrade, CRDFailOnChange:
+	default:
+		return nil, errors.Errorf("invalid CRD upgrade policy %q. Valid inputs are %s, %s, and %s", u.CRDUpgradePolicy, CRDCreateOnly, CRDUpgrade, CRDFailOnChange)
+	}
+
+	var res, upgradedRelease *release.Release
+	var err error
+	if interrupted := u.interruptedRelease(name); interrupted != nil {
+		slog.Debug("resuming upgrade", "name", name, "revision", interrupted.Version, "applied", len(interrupted.Info.Checkpoint.Applied), "total", interrupted.Info.Checkpoint.Total)
+		upgradedRelease = interrupted
+		u.cfg.Releases.MaxHistory = u.MaxHistory
+		u.cfg.Releases.MaxHistoryAge = u.MaxHistoryAge
+		res, err = u.resumeUpgrade(ctx, interrupted)
+	} else {
+		slog.Debug("preparing upgrade", "name", name)
+		var currentRelease *release.Release
+		currentRelease, upgradedRelease, err = u.prepareUpgrade(ctx, name, chart, vals)
+		if err != nil {
+			return nil, err
+		}
+
+		u.cfg.Releases.MaxHistory = u.MaxHistory
+		u.cfg.Releases.MaxHistoryAge = u.MaxHistoryAge
 
-	slog.Debug("performing update", "name", name)
-	res, err := u.performUpgrade(ctx, currentRelease, upgradedRelease)
+		slog.Debug("performing update", "name", name)
+		res, err = u.performUpgrade(ctx, currentRelease, upgradedRelease)
+	}
 	if err != nil {
 		return res, err
 	}
@@ -181,7 +299,7 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, chart *chart.
 	// Do not update for dry runs
 	if !u.isDryRun() {
 		slog.Debug("updating status for upgraded release", "name", name)
-		if err := u.cfg.Releases.Update(upgradedRelease); err != nil {
+		if err := u.cfg.storeRelease(upgradedRelease); err != nil {
 			return res, err
 		}
 	}
@@ -198,7 +316,7 @@ func (u *Upgrade) isDryRun() bool {
 }
 
 // prepareUpgrade builds an upgraded release for an upgrade operation.
-func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, *release.Release, error) {
+func (u *Upgrade) prepareUpgrade(ctx context.Context, name string, chart *chart.Chart, vals map[string]interface{}) (*release.Release, *release.Release, error) {
 	if chart == nil {
 		return nil, nil, errMissingChart
 	}
@@ -207,6 +325,12 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 	if !u.isDryRun() && u.HideSecret {
 		return nil, nil, errors.New("Hiding Kubernetes secrets requires a dry-run mode")
 	}
+	if !u.isDryRun() && u.LookupFixtures != nil {
+		return nil, nil, errors.New("Lookup fixtures can only be used with a dry-run")
+	}
+	if u.DryRunApply && u.DryRunOption != "server" {
+		return nil, nil, errors.New("Applying with dry-run requires the server dry-run mode")
+	}
 
 	// finds the last non-deleted release with the given name
 	lastRelease, err := u.cfg.Releases.Last(name)
@@ -255,17 +379,19 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 	revision := lastRelease.Version + 1
 
 	options := chartutil.ReleaseOptions{
-		Name:      name,
-		Namespace: currentRelease.Namespace,
-		Revision:  revision,
-		IsUpgrade: true,
+		Name:        name,
+		Namespace:   currentRelease.Namespace,
+		Revision:    revision,
+		IsUpgrade:   true,
+		HookOutputs: currentRelease.HookOutputs,
 	}
 
 	caps, err := u.cfg.getCapabilities()
 	if err != nil {
 		return nil, nil, err
 	}
-	valuesToRender, err := chartutil.ToRenderValuesWithSchemaValidation(chart, vals, options, caps, u.SkipSchemaValidation)
+	chartutil.ProcessDependencyCapabilities(chart, caps)
+	valuesToRender, violations, err := chartutil.ToRenderValuesWithSchemaWarnings(chart, vals, options, caps, u.SkipSchemaValidation, u.WarnSchemaViolations)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -276,7 +402,17 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 		interactWithRemote = true
 	}
 
-	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, false, u.PostRenderer, interactWithRemote, u.EnableDNS, u.HideSecret)
+	_, renderSpan := u.cfg.startSpan(ctx, "helm.render")
+	u.cfg.renderStarted(name, currentRelease.Namespace, chart)
+	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, false, releasePostRenderer(u.PostRenderer, options, chart, u.isDryRun()), interactWithRemote, renderOptions{
+		enableDNS:      u.EnableDNS,
+		collectErrors:  u.CollectErrors,
+		cacheIncludes:  u.CacheIncludes,
+		parallel:       u.ParallelRender,
+		lookupBudget:   u.LookupBudget,
+		lookupFixtures: u.LookupFixtures,
+	}, u.HideSecret)
+	endSpan(renderSpan, err)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -296,12 +432,15 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 			LastDeployed:  Timestamper(),
 			Status:        release.StatusPendingUpgrade,
 			Description:   "Preparing upgrade", // This should be overwritten later.
+			Warnings:      schemaWarnings(violations),
 		},
 		Version:  revision,
 		Manifest: manifestDoc.String(),
 		Hooks:    hooks,
 		Labels:   mergeCustomLabels(lastRelease.Labels, u.Labels),
 	}
+	// The outputs of the previous hooks are kept until the hooks publish new ones.
+	upgradedRelease.HookOutputs = copyHookOutputs(currentRelease.HookOutputs)
 
 	if len(notesTxt) > 0 {
 		upgradedRelease.Info.Notes = notesTxt
@@ -311,47 +450,18 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 }
 
 func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedRelease *release.Release) (*release.Release, error) {
-	current, err := u.cfg.KubeClient.Build(bytes.NewBufferString(originalRelease.Manifest), false)
-	if err != nil {
-		// Checking for removed Kubernetes API error so can provide a more informative error message to the user
-		// Ref: https://github.com/helm/helm/issues/7219
-		if strings.Contains(err.Error(), "unable to recognize \"\": no matches for kind") {
-			return upgradedRelease, errors.Wrap(err, "current release manifest contains removed kubernetes api(s) for this "+
-				"kubernetes version and it is therefore unable to build the kubernetes "+
-				"objects for performing the diff. error from kubernetes")
+	if crds := upgradedRelease.Chart.CRDObjects(); !u.isDryRun() && !u.SkipCRDs && u.CRDUpgradePolicy != CRDSkip && len(crds) > 0 {
+		if err := u.upgradeCRDs(crds); err != nil {
+			return upgradedRelease, err
 		}
-		return upgradedRelease, errors.Wrap(err, "unable to build kubernetes objects from current release manifest")
-	}
-	target, err := u.cfg.KubeClient.Build(bytes.NewBufferString(upgradedRelease.Manifest), !u.DisableOpenAPIValidation)
-	if err != nil {
-		return upgradedRelease, errors.Wrap(err, "unable to build kubernetes objects from new release manifest")
 	}
 
-	// It is safe to use force only on target because these are resources currently rendered by the chart.
-	err = target.Visit(setMetadataVisitor(upgradedRelease.Name, upgradedRelease.Namespace, true))
+	current, target, err := u.buildResources(originalRelease, upgradedRelease)
 	if err != nil {
 		return upgradedRelease, err
 	}
 
-	// Do a basic diff using gvk + name to figure out what new resources are being created so we can validate they don't already exist
-	existingResources := make(map[string]bool)
-	for _, r := range current {
-		existingResources[objectKey(r)] = true
-	}
-
-	var toBeCreated kube.ResourceList
-	for _, r := range target {
-		if !existingResources[objectKey(r)] {
-			toBeCreated = append(toBeCreated, r)
-		}
-	}
-
-	var toBeUpdated kube.ResourceList
-	if u.TakeOwnership {
-		toBeUpdated, err = requireAdoption(toBeCreated)
-	} else {
-		toBeUpdated, err = existingResourceConflict(toBeCreated, upgradedRelease.Name, upgradedRelease.Namespace)
-	}
+	toBeUpdated, err := u.resourcesToAdopt(current, target, upgradedRelease)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to continue with update")
 	}
@@ -367,6 +477,11 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 	// Run if it is a dry run
 	if u.isDryRun() {
 		slog.Debug("dry run for release", "name", upgradedRelease.Name)
+		if u.DryRunApply && u.DryRunOption == "server" {
+			if upgradedRelease.Info.DryRun, err = u.cfg.dryRunApply(current, target, u.Force); err != nil {
+				return upgradedRelease, err
+			}
+		}
 		if len(u.Description) > 0 {
 			upgradedRelease.Info.Description = u.Description
 		} else {
@@ -375,15 +490,78 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 		return upgradedRelease, nil
 	}
 
+	if len(u.PreflightChecks) > 0 {
+		req := &PreflightRequest{Release: upgradedRelease, Current: current, Target: target}
+		if err := u.cfg.runPreflightChecks(ctx, u.PreflightChecks, req); err != nil {
+			return upgradedRelease, err
+		}
+	}
+
+	if u.PreflightDryRun {
+		slog.Debug("running preflight dry-run", "name", upgradedRelease.Name)
+		if err := u.cfg.preflightDryRun(current, target, u.Force); err != nil {
+			return upgradedRelease, err
+		}
+	}
+
 	slog.Debug("creating upgraded release", "name", upgradedRelease.Name)
-	if err := u.cfg.Releases.Create(upgradedRelease); err != nil {
+	if err := u.cfg.storeNewRelease(upgradedRelease); err != nil {
+		return nil, err
+	}
+	return u.runUpgrade(ctx, upgradedRelease, current, target, originalRelease)
+}
+
+// interruptedRelease returns the last release of an upgrade that was
+// interrupted after recording a checkpoint, when Resume is set and there is
+// one.
+func (u *Upgrade) interruptedRelease(name string) *release.Release {
+	if !u.Resume || u.isDryRun() {
+		return nil
+	}
+	last, err := u.cfg.Releases.Last(name)
+	if err != nil || last.Info.Checkpoint == nil {
+		return nil
+	}
+	if last.Info.Status != release.StatusPendingUpgrade && last.Info.Status != release.StatusFailed {
+		return nil
+	}
+	return last
+}
+
+// resumeUpgrade resumes the interrupted upgrade of upgradedRelease, applying
+// the resources its checkpoint does not record as applied.
+func (u *Upgrade) resumeUpgrade(ctx context.Context, upgradedRelease *release.Release) (*release.Release, error) {
+	originalRelease, err := u.cfg.Releases.Deployed(upgradedRelease.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to find the release the interrupted upgrade started from")
+	}
+	current, target, err := u.buildResources(originalRelease, upgradedRelease)
+	if err != nil {
+		return upgradedRelease, err
+	}
+
+	// The resources created by the interrupted upgrade are already owned by
+	// the release.
+	toBeUpdated, err := u.resourcesToAdopt(current, target, upgradedRelease)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to continue with update")
+	}
+	current = append(current, toBeUpdated...)
+
+	upgradedRelease.SetStatus(release.StatusPendingUpgrade, "Resuming upgrade")
+	if err := u.cfg.storeRelease(upgradedRelease); err != nil {
 		return nil, err
 	}
+	return u.runUpgrade(ctx, upgradedRelease, current, target, originalRelease)
+}
+
+// runUpgrade applies the upgrade of a stored release, honoring ctx.
+func (u *Upgrade) runUpgrade(ctx context.Context, upgradedRelease *release.Release, current, target kube.ResourceList, originalRelease *release.Release) (*release.Release, error) {
 	rChan := make(chan resultMessage)
 	ctxChan := make(chan resultMessage)
 	doneChan := make(chan interface{})
 	defer close(doneChan)
-	go u.releasingUpgrade(rChan, upgradedRelease, current, target, originalRelease)
+	go u.releasingUpgrade(ctx, rChan, upgradedRelease, current, target, originalRelease)
 	go u.handleContext(ctx, doneChan, ctxChan, upgradedRelease)
 	select {
 	case result := <-rChan:
@@ -396,10 +574,10 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 // Function used to lock the Mutex, this is important for the case when the atomic flag is set.
 // In that case the upgrade will finish before the rollback is finished so it is necessary to wait for the rollback to finish.
 // The rollback will be trigger by the function failRelease
-func (u *Upgrade) reportToPerformUpgrade(c chan<- resultMessage, rel *release.Release, created kube.ResourceList, err error) {
+func (u *Upgrade) reportToPerformUpgrade(ctx context.Context, c chan<- resultMessage, rel *release.Release, created kube.ResourceList, err error) {
 	u.Lock.Lock()
 	if err != nil {
-		rel, err = u.failRelease(rel, created, err)
+		rel, err = u.failRelease(ctx, rel, created, err)
 	}
 	c <- resultMessage{r: rel, e: err}
 	u.Lock.Unlock()
@@ -412,27 +590,100 @@ func (u *Upgrade) handleContext(ctx context.Context, done chan interface{}, c ch
 		err := ctx.Err()
 
 		// when the atomic flag is set the ongoing release finish first and doesn't give time for the rollback happens.
-		u.reportToPerformUpgrade(c, upgradedRelease, kube.ResourceList{}, err)
+		u.reportToPerformUpgrade(ctx, c, upgradedRelease, kube.ResourceList{}, err)
 	case <-done:
 		return
 	}
 }
-func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *release.Release, current kube.ResourceList, target kube.ResourceList, originalRelease *release.Release) {
-	// pre-upgrade hooks
 
-	if !u.DisableHooks {
-		if err := u.cfg.execHook(upgradedRelease, release.HookPreUpgrade, u.WaitStrategy, u.Timeout); err != nil {
-			u.reportToPerformUpgrade(c, upgradedRelease, kube.ResourceList{}, fmt.Errorf("pre-upgrade hooks failed: %s", err))
+// buildResources builds the resources of the current and the upgraded
+// release, labelling the latter as owned by the upgraded release.
+func (u *Upgrade) buildResources(originalRelease, upgradedRelease *release.Release) (current, target kube.ResourceList, err error) {
+	current, err = u.cfg.KubeClient.Build(bytes.NewBufferString(originalRelease.Manifest), false)
+	if err != nil {
+		// Checking for removed Kubernetes API error so can provide a more informative error message to the user
+		// Ref: https://github.com/helm/helm/issues/7219
+		if strings.Contains(err.Error(), "unable to recognize \"\": no matches for kind") {
+			return nil, nil, errors.Wrap(err, "current release manifest contains removed kubernetes api(s) for this "+
+				"kubernetes version and it is therefore unable to build the kubernetes "+
+				"objects for performing the diff. error from kubernetes")
+		}
+		return nil, nil, errors.Wrap(err, "unable to build kubernetes objects from current release manifest")
+	}
+	target, err = u.cfg.KubeClient.Build(bytes.NewBufferString(upgradedRelease.Manifest), !u.DisableOpenAPIValidation)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to build kubernetes objects from new release manifest")
+	}
+
+	// It is safe to use force only on target because these are resources currently rendered by the chart.
+	err = target.Visit(setMetadataVisitor(upgradedRelease.Name, upgradedRelease.Namespace, true))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := kube.CheckNamespacePolicy(target, upgradedRelease.Namespace, u.NamespacePolicy); err != nil {
+		return nil, nil, err
+	}
+
+	return current, target, nil
+}
+
+// resourcesToAdopt returns the resources of target that are new to the
+// release but already exist, and can be adopted by the upgraded release.
+func (u *Upgrade) resourcesToAdopt(current, target kube.ResourceList, upgradedRelease *release.Release) (kube.ResourceList, error) {
+	// Do a basic diff using gvk + name to figure out what new resources are being created so we can validate they don't already exist
+	existingResources := make(map[string]bool)
+	for _, r := range current {
+		existingResources[objectKey(r)] = true
+	}
+
+	var toBeCreated kube.ResourceList
+	for _, r := range target {
+		if !existingResources[objectKey(r)] {
+			toBeCreated = append(toBeCreated, r)
+		}
+	}
+
+	if u.TakeOwnership {
+		return adoptableResources(toBeCreated, upgradedRelease.Name, upgradedRelease.Namespace)
+	}
+	return existingResourceConflict(toBeCreated, upgradedRelease.Name, upgradedRelease.Namespace)
+}
+
+func (u *Upgrade) releasingUpgrade(ctx context.Context, c chan<- resultMessage, upgradedRelease *release.Release, current kube.ResourceList, target kube.ResourceList, originalRelease *release.Release) {
+	// post-render and pre-upgrade hooks, which already ran when resuming an interrupted upgrade
+
+	if !u.DisableHooks && upgradedRelease.Info.Checkpoint == nil {
+		if err := u.cfg.execHook(ctx, upgradedRelease, release.HookPostRender, u.SkipHooks, u.HookConcurrency, u.WaitStrategy, u.Timeout); err != nil {
+			u.reportToPerformUpgrade(ctx, c, upgradedRelease, kube.ResourceList{}, fmt.Errorf("post-render hooks failed: %s", err))
+			return
+		}
+		if err := u.cfg.execHook(ctx, upgradedRelease, release.HookPreUpgrade, u.SkipHooks, u.HookConcurrency, u.WaitStrategy, u.Timeout); err != nil {
+			u.reportToPerformUpgrade(ctx, c, upgradedRelease, kube.ResourceList{}, fmt.Errorf("pre-upgrade hooks failed: %s", err))
 			return
 		}
 	} else {
 		slog.Debug("upgrade hooks disabled", "name", upgradedRelease.Name)
 	}
 
-	results, err := u.cfg.KubeClient.Update(current, target, u.Force)
+	applyOpts, err := u.cfg.serverSideApplyOptions(u.ServerSideApply, u.ForceConflicts, u.Force)
+	if err != nil {
+		u.reportToPerformUpgrade(ctx, c, upgradedRelease, kube.ResourceList{}, err)
+		return
+	}
+	applyOpts = append(applyOpts, u.checkpointOptions(upgradedRelease, target)...)
+	apply := u.cfg.startApply(ctx, target)
+	applyOpts = append(applyOpts, apply.option(), u.cfg.appliedOption(upgradedRelease))
+	var results *kube.Result
+	if u.WaitForDependencies {
+		results, err = u.cfg.applyInDependencyOrder(current, target, u.Force, u.WaitStrategy, u.Timeout, applyOpts...)
+	} else {
+		results, err = u.cfg.updateResources(current, target, u.Force, applyOpts...)
+	}
+	apply.end(err)
 	if err != nil {
 		u.cfg.recordRelease(originalRelease)
-		u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
+		u.reportToPerformUpgrade(ctx, c, upgradedRelease, results.Created, err)
 		return
 	}
 
@@ -445,30 +696,37 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 			slog.Error(err.Error())
 		}
 	}
+
+	if u.PruneOrphans {
+		if err := u.cfg.pruneOrphans(ctx, upgradedRelease, target); err != nil {
+			slog.Warn("unable to prune orphaned resources", "name", upgradedRelease.Name, slog.Any("error", err))
+		}
+	}
+
+	// pre-wait hooks
+	if !u.DisableHooks {
+		if err := u.cfg.execHook(ctx, upgradedRelease, release.HookPreWait, u.SkipHooks, u.HookConcurrency, u.WaitStrategy, u.Timeout); err != nil {
+			u.reportToPerformUpgrade(ctx, c, upgradedRelease, results.Created, fmt.Errorf("pre-wait hooks failed: %s", err))
+			return
+		}
+	}
+
 	waiter, err := u.cfg.KubeClient.GetWaiter(u.WaitStrategy)
 	if err != nil {
 		u.cfg.recordRelease(originalRelease)
-		u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
+		u.reportToPerformUpgrade(ctx, c, upgradedRelease, results.Created, err)
 		return
 	}
-	if u.WaitForJobs {
-		if err := waiter.WaitWithJobs(target, u.Timeout); err != nil {
-			u.cfg.recordRelease(originalRelease)
-			u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
-			return
-		}
-	} else {
-		if err := waiter.Wait(target, u.Timeout); err != nil {
-			u.cfg.recordRelease(originalRelease)
-			u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
-			return
-		}
+	if err := u.cfg.waitForResources(ctx, upgradedRelease, waiter, target, u.WaitForJobs, u.Timeout); err != nil {
+		u.cfg.recordRelease(originalRelease)
+		u.reportToPerformUpgrade(ctx, c, upgradedRelease, results.Created, err)
+		return
 	}
 
 	// post-upgrade hooks
 	if !u.DisableHooks {
-		if err := u.cfg.execHook(upgradedRelease, release.HookPostUpgrade, u.WaitStrategy, u.Timeout); err != nil {
-			u.reportToPerformUpgrade(c, upgradedRelease, results.Created, fmt.Errorf("post-upgrade hooks failed: %s", err))
+		if err := u.cfg.execHook(ctx, upgradedRelease, release.HookPostUpgrade, u.SkipHooks, u.HookConcurrency, u.WaitStrategy, u.Timeout); err != nil {
+			u.reportToPerformUpgrade(ctx, c, upgradedRelease, results.Created, fmt.Errorf("post-upgrade hooks failed: %s", err))
 			return
 		}
 	}
@@ -476,16 +734,17 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 	originalRelease.Info.Status = release.StatusSuperseded
 	u.cfg.recordRelease(originalRelease)
 
+	upgradedRelease.Info.Checkpoint = nil
 	upgradedRelease.Info.Status = release.StatusDeployed
 	if len(u.Description) > 0 {
 		upgradedRelease.Info.Description = u.Description
 	} else {
 		upgradedRelease.Info.Description = "Upgrade complete"
 	}
-	u.reportToPerformUpgrade(c, upgradedRelease, nil, nil)
+	u.reportToPerformUpgrade(ctx, c, upgradedRelease, nil, nil)
 }
 
-func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, err error) (*release.Release, error) {
+func (u *Upgrade) failRelease(ctx context.Context, rel *release.Release, created kube.ResourceList, err error) (*release.Release, error) {
 	msg := fmt.Sprintf("Upgrade %q failed: %s", rel.Name, err)
 	slog.Warn("upgrade failed", "name", rel.Name, slog.Any("error", err))
 
@@ -505,45 +764,141 @@ func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, e
 		slog.Debug("resource cleanup complete")
 	}
 	if u.Atomic {
-		slog.Debug("upgrade failed and atomic is set, rolling back to last successful release")
-
-		// As a protection, get the last successful release before rollback.
-		// If there are no successful releases, bail out
-		hist := NewHistory(u.cfg)
-		fullHistory, herr := hist.Run(rel.Name)
-		if herr != nil {
-			return rel, errors.Wrapf(herr, "an error occurred while finding last successful release. original upgrade error: %s", err)
+		return rel, u.atomicRollback(ctx, rel, err)
+	}
+
+	return rel, err
+}
+
+// checkpointOptions returns the options making Update skip the resources the
+// checkpoint of an interrupted upgrade records as applied, and record the
+// progress of this upgrade when CheckpointBatchSize is set.
+func (u *Upgrade) checkpointOptions(rel *release.Release, target kube.ResourceList) []kube.UpdateOption {
+	var opts []kube.UpdateOption
+	if checkpoint := rel.Info.Checkpoint; checkpoint != nil {
+		applied := make(map[string]bool, len(checkpoint.Applied))
+		for _, key := range checkpoint.Applied {
+			applied[key] = true
 		}
+		var skipped kube.ResourceList
+		for _, info := range target {
+			if applied[objectKey(info)] {
+				skipped = append(skipped, info)
+			}
+		}
+		opts = append(opts, kube.AlreadyApplied(skipped))
+	}
+	if u.CheckpointBatchSize <= 0 {
+		return opts
+	}
 
-		// There isn't a way to tell if a previous release was successful, but
-		// generally failed releases do not get superseded unless the next
-		// release is successful, so this should be relatively safe
-		filteredHistory := releaseutil.FilterFunc(func(r *release.Release) bool {
-			return r.Info.Status == release.StatusSuperseded || r.Info.Status == release.StatusDeployed
-		}).Filter(fullHistory)
-		if len(filteredHistory) == 0 {
-			return rel, errors.Wrap(err, "unable to find a previously successful release when attempting to rollback. original upgrade error")
+	// The first checkpoint records that the pre-upgrade hooks have run.
+	if rel.Info.Checkpoint == nil {
+		rel.Info.Checkpoint = &release.Checkpoint{Total: len(target)}
+	}
+	u.cfg.recordRelease(rel)
+	unrecorded := 0
+	return append(opts, kube.OnApplied(func(info *resource.Info) {
+		rel.Info.Checkpoint.Applied = append(rel.Info.Checkpoint.Applied, objectKey(info))
+		unrecorded++
+		if unrecorded >= u.CheckpointBatchSize {
+			unrecorded = 0
+			u.cfg.recordRelease(rel)
 		}
+	}))
+}
+
+// AtomicRollbackError is the error of an upgrade that failed with Atomic set,
+// reporting both the failure of the upgrade and the outcome of the rollback.
+type AtomicRollbackError struct {
+	// Release is the name of the release.
+	Release string
+	// Err is the failure of the upgrade.
+	Err error
+	// Version is the revision the release was rolled back to. It is 0 when
+	// no successful revision was found.
+	Version int
+	// RollbackErr is the failure of the rollback. It is nil when the release
+	// was rolled back.
+	RollbackErr error
+}
 
-		releaseutil.Reverse(filteredHistory, releaseutil.SortByRevision)
+func (e *AtomicRollbackError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("an error occurred while rolling back the release. original upgrade error: %s: %s", e.Err, e.RollbackErr)
+	}
+	return fmt.Sprintf("release %s failed, and has been rolled back due to atomic being set: %s", e.Release, e.Err)
+}
 
-		rollin := NewRollback(u.cfg)
-		rollin.Version = filteredHistory[0].Version
-		if u.WaitStrategy == kube.HookOnlyStrategy {
-			rollin.WaitStrategy = kube.StatusWatcherStrategy
-		}
-		rollin.WaitForJobs = u.WaitForJobs
-		rollin.DisableHooks = u.DisableHooks
-		rollin.Recreate = u.Recreate
-		rollin.Force = u.Force
-		rollin.Timeout = u.Timeout
-		if rollErr := rollin.Run(rel.Name); rollErr != nil {
-			return rel, errors.Wrapf(rollErr, "an error occurred while rolling back the release. original upgrade error: %s", err)
-		}
-		return rel, errors.Wrapf(err, "release %s failed, and has been rolled back due to atomic being set", rel.Name)
+// Unwrap returns the failures of the upgrade and of the rollback.
+func (e *AtomicRollbackError) Unwrap() []error {
+	if e.RollbackErr != nil {
+		return []error{e.Err, e.RollbackErr}
 	}
+	return []error{e.Err}
+}
 
-	return rel, err
+// RolledBack reports whether the release was rolled back.
+func (e *AtomicRollbackError) RolledBack() bool {
+	return e.RollbackErr == nil
+}
+
+// atomicRollback rolls the release back to its last successful revision after
+// the upgrade failed with err, and returns an *AtomicRollbackError.
+func (u *Upgrade) atomicRollback(ctx context.Context, rel *release.Release, err error) error {
+	slog.Debug("upgrade failed and atomic is set, rolling back to last successful release")
+	rollbackErr := &AtomicRollbackError{Release: rel.Name, Err: err}
+
+	// As a protection, get the last successful release before rollback.
+	// If there are no successful releases, bail out
+	hist := NewHistory(u.cfg)
+	fullHistory, herr := hist.Run(rel.Name)
+	if herr != nil {
+		rollbackErr.RollbackErr = errors.Wrap(herr, "an error occurred while finding last successful release")
+		return rollbackErr
+	}
+
+	// There isn't a way to tell if a previous release was successful, but
+	// generally failed releases do not get superseded unless the next
+	// release is successful, so this should be relatively safe
+	filteredHistory := releaseutil.FilterFunc(func(r *release.Release) bool {
+		return r.Info.Status == release.StatusSuperseded || r.Info.Status == release.StatusDeployed
+	}).Filter(fullHistory)
+	if len(filteredHistory) == 0 {
+		rollbackErr.RollbackErr = errors.New("unable to find a previously successful release")
+		return rollbackErr
+	}
+
+	releaseutil.Reverse(filteredHistory, releaseutil.SortByRevision)
+	rollbackErr.Version = filteredHistory[0].Version
+
+	// When the upgrade was interrupted, the rollback must still run, so it
+	// only honors its own timeout.
+	if ctx.Err() != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+	timeout := u.Timeout
+	if u.RollbackTimeout > 0 {
+		timeout = u.RollbackTimeout
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.RollbackTimeout)
+		defer cancel()
+	}
+
+	rollin := NewRollback(u.cfg)
+	rollin.Version = rollbackErr.Version
+	if u.WaitStrategy == kube.HookOnlyStrategy {
+		rollin.WaitStrategy = kube.StatusWatcherStrategy
+	}
+	rollin.WaitForJobs = u.WaitForJobs
+	rollin.DisableHooks = u.DisableHooks
+	rollin.SkipHooks = u.SkipHooks
+	rollin.HookConcurrency = u.HookConcurrency
+	rollin.Recreate = u.Recreate
+	rollin.Force = u.Force
+	rollin.Timeout = timeout
+	rollbackErr.RollbackErr = rollin.RunWithContext(ctx, rel.Name)
+	return rollbackErr
 }
 
 // reuseValues copies values from the current release to a new release if the
@@ -571,7 +926,9 @@ func (u *Upgrade) reuseValues(chart *chart.Chart, current *release.Release, newV
 			return nil, errors.Wrap(err, "failed to rebuild old values")
 		}
 
-		newVals = chartutil.CoalesceTables(newVals, current.Config)
+		// The list directives of the old values are already applied to the
+		// old coalesced values.
+		newVals = chartutil.CoalesceTables(newVals, chartutil.ResolveListPatches(current.Config, oldVals))
 
 		chart.Values = oldVals
 
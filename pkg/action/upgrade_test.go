@@ -24,6 +24,7 @@ import (
 	"time"
 
 	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/kube"
 	"helm.sh/helm/v4/pkg/storage/driver"
 
@@ -191,6 +192,33 @@ func TestUpgradeRelease_Atomic(t *testing.T) {
 	})
 }
 
+func TestUpgradeRelease_PreflightDryRun(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "preflight-dry-run"
+	rel.Info.Status = release.StatusDeployed
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	failer := upAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.DryRunApplyError = fmt.Errorf("admission webhook denied the request")
+	upAction.cfg.KubeClient = failer
+	upAction.PreflightDryRun = true
+	vals := map[string]interface{}{}
+
+	_, err := upAction.Run(rel.Name, buildChart(), vals)
+	req.Error(err)
+	is.Contains(err.Error(), "preflight dry-run failed")
+
+	// The failed preflight must not leave a new revision behind.
+	lastRelease, err := upAction.cfg.Releases.Last(rel.Name)
+	req.NoError(err)
+	is.Equal(rel.Version, lastRelease.Version)
+	is.Equal(release.StatusDeployed, lastRelease.Info.Status)
+}
+
 func TestUpgradeRelease_ReuseValues(t *testing.T) {
 	is := assert.New(t)
 
@@ -307,6 +335,29 @@ func TestUpgradeRelease_ReuseValues(t *testing.T) {
 		}
 		is.Equal(expectedValues, updatedRes.Config)
 	})
+
+	t.Run("reuse values should apply list directives once", func(t *testing.T) {
+		upAction := upgradeAction(t)
+		listChart := func() *chart.Chart {
+			return buildChartWithTemplates([]*chart.File{
+				{Name: "templates/list", Data: []byte("list: {{ .Values.list }}")},
+			}, withValues(map[string]interface{}{"list": []interface{}{"a"}}))
+		}
+
+		rel := releaseStub()
+		rel.Name = "nuketown"
+		rel.Chart = listChart()
+		rel.Info.Status = release.StatusDeployed
+		rel.Config = map[string]interface{}{
+			"list": []interface{}{map[string]interface{}{chartutil.ListPatchKey: "append"}, "b"},
+		}
+		is.NoError(upAction.cfg.Releases.Create(rel))
+
+		upAction.ReuseValues = true
+		res, err := upAction.Run(rel.Name, listChart(), map[string]interface{}{})
+		is.NoError(err)
+		is.Contains(res.Manifest, "list: [a b]")
+	})
 }
 
 func TestUpgradeRelease_ResetThenReuseValues(t *testing.T) {
@@ -436,6 +487,11 @@ func TestUpgradeRelease_Interrupted_Atomic(t *testing.T) {
 
 	req.Error(err)
 	is.Contains(err.Error(), "release interrupted-release failed, and has been rolled back due to atomic being set: context canceled")
+	var rollbackErr *AtomicRollbackError
+	req.ErrorAs(err, &rollbackErr)
+	is.True(rollbackErr.RolledBack())
+	is.Equal(1, rollbackErr.Version)
+	is.ErrorIs(err, context.Canceled)
 
 	// Now make sure it is actually upgraded
 	updatedRes, err := upAction.cfg.Releases.Get(res.Name, 3)
@@ -444,6 +500,88 @@ func TestUpgradeRelease_Interrupted_Atomic(t *testing.T) {
 	is.Equal(updatedRes.Info.Status, release.StatusDeployed)
 }
 
+func TestUpgradeRelease_Interrupted_AtomicRollbackTimeout(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "slow-rollback"
+	rel.Info.Status = release.StatusDeployed
+	upAction.cfg.Releases.Create(rel)
+
+	failer := upAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.WaitDuration = 2 * time.Second
+	upAction.cfg.KubeClient = failer
+	upAction.Atomic = true
+	upAction.RollbackTimeout = 200 * time.Millisecond
+	vals := map[string]interface{}{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := upAction.RunWithContext(ctx, rel.Name, buildChart(), vals)
+	// The rollback is bounded by its own timeout rather than the waits.
+	is.Less(time.Since(start), 2*time.Second)
+
+	req.Error(err)
+	var rollbackErr *AtomicRollbackError
+	req.ErrorAs(err, &rollbackErr)
+	is.False(rollbackErr.RolledBack())
+	is.ErrorIs(err, context.Canceled)
+	is.ErrorIs(err, context.DeadlineExceeded)
+	is.Contains(err.Error(), "an error occurred while rolling back the release. original upgrade error: context canceled: context deadline exceeded")
+
+	rollback, err := upAction.cfg.Releases.Get(rel.Name, 3)
+	req.NoError(err)
+	is.Equal(release.StatusFailed, rollback.Info.Status)
+}
+
+func TestUpgradeRelease_Resume(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "interrupted"
+	rel.Info.Status = release.StatusDeployed
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	interrupted := releaseStub()
+	interrupted.Name = rel.Name
+	interrupted.Version = 2
+	interrupted.Info.Status = release.StatusPendingUpgrade
+	interrupted.Info.Checkpoint = &release.Checkpoint{Total: 2, Applied: []string{"v1/ConfigMap/spaced/settings"}}
+	req.NoError(upAction.cfg.Releases.Create(interrupted))
+
+	// Without Resume, the pending upgrade blocks new ones.
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.Error(err)
+
+	upAction.Resume = true
+	upAction.CheckpointBatchSize = 1
+	res, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(2, res.Version)
+	is.Equal(release.StatusDeployed, res.Info.Status)
+	is.Nil(res.Info.Checkpoint)
+
+	lastRelease, err := upAction.cfg.Releases.Last(rel.Name)
+	req.NoError(err)
+	is.Equal(2, lastRelease.Version)
+	is.Nil(lastRelease.Info.Checkpoint)
+	previous, err := upAction.cfg.Releases.Get(rel.Name, 1)
+	req.NoError(err)
+	is.Equal(release.StatusSuperseded, previous.Info.Status)
+
+	// Without an interrupted upgrade, Resume upgrades as usual.
+	res, err = upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(3, res.Version)
+	is.Nil(res.Info.Checkpoint)
+}
+
 func TestMergeCustomLabels(t *testing.T) {
 	var tests = [][3]map[string]string{
 		{nil, nil, map[string]string{}},
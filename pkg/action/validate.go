@@ -37,8 +37,7 @@ const (
 	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
 )
 
-// requireAdoption returns the subset of resources that already exist in the cluster.
-func requireAdoption(resources kube.ResourceList) (kube.ResourceList, error) {
+func existingResourceConflict(resources kube.ResourceList, releaseName, releaseNamespace string) (kube.ResourceList, error) {
 	var requireUpdate kube.ResourceList
 
 	err := resources.Visit(func(info *resource.Info, err error) error {
@@ -47,7 +46,7 @@ func requireAdoption(resources kube.ResourceList) (kube.ResourceList, error) {
 		}
 
 		helper := resource.NewHelper(info.Client, info.Mapping)
-		_, err = helper.Get(info.Namespace, info.Name)
+		existing, err := helper.Get(info.Namespace, info.Name)
 		if err != nil {
 			if apierrors.IsNotFound(err) {
 				return nil
@@ -55,6 +54,11 @@ func requireAdoption(resources kube.ResourceList) (kube.ResourceList, error) {
 			return errors.Wrapf(err, "could not get information about the resource %s", resourceString(info))
 		}
 
+		// Allow adoption of the resource if it is managed by Helm and is annotated with correct release name and namespace.
+		if err := checkOwnership(existing, releaseName, releaseNamespace); err != nil {
+			return fmt.Errorf("%s exists and cannot be imported into the current release: %s", resourceString(info), err)
+		}
+
 		requireUpdate.Append(info)
 		return nil
 	})
@@ -62,7 +66,10 @@ func requireAdoption(resources kube.ResourceList) (kube.ResourceList, error) {
 	return requireUpdate, err
 }
 
-func existingResourceConflict(resources kube.ResourceList, releaseName, releaseNamespace string) (kube.ResourceList, error) {
+// adoptableResources returns the resources that already exist, and can be
+// adopted by the release being installed. It fails on the resources owned by
+// another release, as told by checkAdoptable.
+func adoptableResources(resources kube.ResourceList, releaseName, releaseNamespace string) (kube.ResourceList, error) {
 	var requireUpdate kube.ResourceList
 
 	err := resources.Visit(func(info *resource.Info, err error) error {
@@ -79,9 +86,8 @@ func existingResourceConflict(resources kube.ResourceList, releaseName, releaseN
 			return errors.Wrapf(err, "could not get information about the resource %s", resourceString(info))
 		}
 
-		// Allow adoption of the resource if it is managed by Helm and is annotated with correct release name and namespace.
-		if err := checkOwnership(existing, releaseName, releaseNamespace); err != nil {
-			return fmt.Errorf("%s exists and cannot be imported into the current release: %s", resourceString(info), err)
+		if err := checkAdoptable(existing, releaseName, releaseNamespace); err != nil {
+			return fmt.Errorf("%s exists and cannot be adopted by the current release: %s", resourceString(info), err)
 		}
 
 		requireUpdate.Append(info)
@@ -91,6 +97,39 @@ func existingResourceConflict(resources kube.ResourceList, releaseName, releaseN
 	return requireUpdate, err
 }
 
+// checkAdoptable checks that an existing object is not owned by another
+// release, so that it can be adopted by the given release.
+//
+// The conflicts tolerated are the ones checkOwnership reports for an object
+// that no release owns: a missing or different managed-by label, such as for
+// objects created with kubectl or another tool, and missing release name and
+// namespace annotations. A release name or namespace annotation naming another
+// release is not tolerated, since that release still manages the object.
+func checkAdoptable(obj runtime.Object, releaseName, releaseNamespace string) error {
+	annos, err := accessor.Annotations(obj)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if name, ok := annos[helmReleaseNameAnnotation]; ok && name != releaseName {
+		errs = append(errs, fmt.Errorf("annotation %q is %q", helmReleaseNameAnnotation, name))
+	}
+	if namespace, ok := annos[helmReleaseNamespaceAnnotation]; ok && namespace != releaseNamespace {
+		errs = append(errs, fmt.Errorf("annotation %q is %q", helmReleaseNamespaceAnnotation, namespace))
+	}
+
+	if len(errs) > 0 {
+		err := errors.New("owned by another release")
+		for _, e := range errs {
+			err = fmt.Errorf("%w; %s", err, e)
+		}
+		return err
+	}
+
+	return nil
+}
+
 func checkOwnership(obj runtime.Object, releaseName, releaseNamespace string) error {
 	lbls, err := accessor.Labels(obj)
 	if err != nil {
@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
 
 	"github.com/stretchr/testify/assert"
 
@@ -120,20 +121,6 @@ func fakeClientWith(code int, gv schema.GroupVersion, body string) *fake.RESTCli
 	}
 }
 
-func TestRequireAdoption(t *testing.T) {
-	var (
-		missing   = newMissingDeployment("missing", "ns-a")
-		existing  = newDeploymentWithOwner("existing", "ns-a", nil, nil)
-		resources = kube.ResourceList{missing, existing}
-	)
-
-	// Verify that a resource that lacks labels/annotations can be adopted
-	found, err := requireAdoption(resources)
-	assert.NoError(t, err)
-	assert.Len(t, found, 1)
-	assert.Equal(t, found[0], existing)
-}
-
 func TestExistingResourceConflict(t *testing.T) {
 	var (
 		releaseName      = "rel-name"
@@ -163,6 +150,58 @@ func TestExistingResourceConflict(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAdoptableResources(t *testing.T) {
+	var (
+		missing   = newMissingDeployment("missing", "ns-a")
+		unmanaged = newDeploymentWithOwner("unmanaged", "ns-a", map[string]string{appManagedByLabel: "kubectl"}, nil)
+		owned     = newDeploymentWithOwner("owned", "ns-a", nil, map[string]string{
+			helmReleaseNameAnnotation:      "rel-a",
+			helmReleaseNamespaceAnnotation: "ns-a",
+		})
+		resources = kube.ResourceList{missing, unmanaged, owned}
+	)
+
+	// Verify that unmanaged resources and the resources of the release are adopted
+	found, err := adoptableResources(resources, "rel-a", "ns-a")
+	assert.NoError(t, err)
+	assert.Equal(t, kube.ResourceList{unmanaged, owned}, found)
+
+	// Verify that the resources of another release are not adopted
+	_, err = adoptableResources(resources, "rel-b", "ns-a")
+	assert.EqualError(t, err, `Deployment "owned" in namespace "ns-a" exists and cannot be adopted by the current release: owned by another release; annotation "meta.helm.sh/release-name" is "rel-a"`)
+}
+
+func TestTakeOwnership(t *testing.T) {
+	var (
+		missing   = newMissingDeployment("missing", "ns-a")
+		unmanaged = newDeploymentWithOwner("unmanaged", "ns-a", map[string]string{appManagedByLabel: "kubectl"}, nil)
+		owned     = newDeploymentWithOwner("owned", "ns-a", nil, map[string]string{
+			helmReleaseNameAnnotation:      "rel-a",
+			helmReleaseNamespaceAnnotation: "ns-a",
+		})
+	)
+	resourcesToAdopt := map[string]func(resources kube.ResourceList, rel *release.Release) (kube.ResourceList, error){
+		"install": func(resources kube.ResourceList, rel *release.Release) (kube.ResourceList, error) {
+			return (&Install{TakeOwnership: true}).resourcesToAdopt(resources, rel)
+		},
+		"upgrade": func(resources kube.ResourceList, rel *release.Release) (kube.ResourceList, error) {
+			return (&Upgrade{TakeOwnership: true}).resourcesToAdopt(nil, resources, rel)
+		},
+	}
+	for name, fn := range resourcesToAdopt {
+		t.Run(name, func(t *testing.T) {
+			// Verify that unmanaged resources and the resources of the release are adopted
+			found, err := fn(kube.ResourceList{missing, unmanaged, owned}, &release.Release{Name: "rel-a", Namespace: "ns-a"})
+			assert.NoError(t, err)
+			assert.Equal(t, kube.ResourceList{unmanaged, owned}, found)
+
+			// Verify that the resources of another release are not adopted
+			_, err = fn(kube.ResourceList{missing, unmanaged, owned}, &release.Release{Name: "rel-b", Namespace: "ns-a"})
+			assert.ErrorContains(t, err, `Deployment "owned" in namespace "ns-a" exists and cannot be adopted by the current release`)
+		})
+	}
+}
+
 func TestCheckOwnership(t *testing.T) {
 	deployFoo := newDeploymentResource("foo", "ns-a")
 
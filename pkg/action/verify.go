@@ -18,9 +18,11 @@ package action
 
 import (
 	"fmt"
+	"io"
 	"strings"
 
 	"helm.sh/helm/v4/pkg/downloader"
+	"helm.sh/helm/v4/pkg/provenance"
 )
 
 // Verify is the action for building a given chart's Verify tree.
@@ -29,6 +31,16 @@ import (
 type Verify struct {
 	Keyring string
 	Out     string
+
+	// Keyless verifies the Sigstore signature written by keyless signing
+	// instead of the provenance file.
+	Keyless bool
+	// CertificateIdentity and CertificateOIDCIssuer are the identity the
+	// chart is expected to be signed by.
+	CertificateIdentity   string
+	CertificateOIDCIssuer string
+	// TrustedRoot is the path to the Sigstore trusted root to verify against.
+	TrustedRoot string
 }
 
 // NewVerify creates a new Verify object with the given configuration.
@@ -39,16 +51,22 @@ func NewVerify() *Verify {
 // Run executes 'helm verify'.
 func (v *Verify) Run(chartfile string) error {
 	var out strings.Builder
-	p, err := downloader.VerifyChart(chartfile, v.Keyring)
+	var p *provenance.Verification
+	var err error
+	if v.Keyless {
+		p, err = downloader.VerifyChartSigstore(chartfile, provenance.SigstoreOptions{
+			TrustedRoot:           v.TrustedRoot,
+			CertificateIdentity:   v.CertificateIdentity,
+			CertificateOIDCIssuer: v.CertificateOIDCIssuer,
+		})
+	} else {
+		p, err = downloader.VerifyChart(chartfile, v.Keyring)
+	}
 	if err != nil {
 		return err
 	}
 
-	for name := range p.SignedBy.Identities {
-		fmt.Fprintf(&out, "Signed by: %v\n", name)
-	}
-	fmt.Fprintf(&out, "Using Key With Fingerprint: %X\n", p.SignedBy.PrimaryKey.Fingerprint)
-	fmt.Fprintf(&out, "Chart Hash Verified: %s\n", p.FileHash)
+	writeVerification(&out, p)
 
 	// TODO(mattfarina): The output is set as a property rather than returned
 	// to maintain the Go API. In Helm v4 this function should return the out
@@ -57,3 +75,18 @@ func (v *Verify) Run(chartfile string) error {
 
 	return nil
 }
+
+// writeVerification describes who signed a verified chart.
+func writeVerification(out io.Writer, v *provenance.Verification) {
+	if v.Keyless != nil {
+		fmt.Fprintf(out, "Signed by: %s\n", v.Keyless.Identity)
+		fmt.Fprintf(out, "OIDC Issuer: %s\n", v.Keyless.Issuer)
+		fmt.Fprintf(out, "Transparency Log Index: %d\n", v.Keyless.LogIndex)
+	} else {
+		for name := range v.SignedBy.Identities {
+			fmt.Fprintf(out, "Signed by: %v\n", name)
+		}
+		fmt.Fprintf(out, "Using Key With Fingerprint: %X\n", v.SignedBy.PrimaryKey.Fingerprint)
+	}
+	fmt.Fprintf(out, "Chart Hash Verified: %s\n", v.FileHash)
+}
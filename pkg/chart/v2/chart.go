@@ -30,6 +30,9 @@ const APIVersionV2 = "v2"
 // aliasNameFormat defines the characters that are legal in an alias name.
 var aliasNameFormat = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
 
+// digestFormat defines the form of a digest pinning an OCI dependency.
+var digestFormat = regexp.MustCompile("^sha256:[a-f0-9]{64}$")
+
 // Chart is a helm package that contains metadata, a default config, zero or more
 // optionally parameterizable templates, and zero or more charts (dependencies).
 type Chart struct {
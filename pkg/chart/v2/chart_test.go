@@ -209,3 +209,24 @@ func TestCRDObjects(t *testing.T) {
 	crds := chrt.CRDObjects()
 	is.Equal(expected, crds)
 }
+
+func TestLazyFile(t *testing.T) {
+	is := assert.New(t)
+	reads := 0
+	f := NewLazyFile("assets/large.bin", func() ([]byte, error) {
+		reads++
+		return []byte("hello"), nil
+	})
+	is.Nil(f.Data)
+
+	data, err := f.GetData()
+	is.NoError(err)
+	is.Equal([]byte("hello"), data)
+
+	b, err := json.Marshal(&Chart{Files: []*File{f}})
+	is.NoError(err)
+	var decoded Chart
+	is.NoError(json.Unmarshal(b, &decoded))
+	is.Equal([]*File{{Name: "assets/large.bin", Data: []byte("hello")}}, decoded.Files)
+	is.Equal(2, reads)
+}
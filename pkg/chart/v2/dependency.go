@@ -15,7 +15,10 @@ limitations under the License.
 
 package v2
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Dependency describes a chart upon which another chart depends.
 //
@@ -47,6 +50,24 @@ type Dependency struct {
 	ImportValues []interface{} `json:"import-values,omitempty" yaml:"import-values,omitempty"`
 	// Alias usable alias to be used for the chart
 	Alias string `json:"alias,omitempty" yaml:"alias,omitempty"`
+	// Capabilities lists the API versions (e.g. gateway.networking.k8s.io/v1/Gateway)
+	// that the target cluster must serve for the chart to be enabled.
+	Capabilities []string `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+	// Digest pins an OCI dependency to the manifest with this digest
+	// (e.g. sha256:...).
+	//
+	// In Chart.yaml it is the digest the dependency must resolve to. In a lock
+	// file it records the digest the dependency was resolved to.
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	// ContentDigest is the digest of the chart archive the dependency was
+	// resolved to. It is only set in lock files.
+	ContentDigest string `json:"contentDigest,omitempty" yaml:"contentDigest,omitempty"`
+	// ResolvedURL is the URL the chart archive was fetched from. It is only
+	// set in lock files.
+	ResolvedURL string `json:"resolvedURL,omitempty" yaml:"resolvedURL,omitempty"`
+	// Resolved is the time the dependency was resolved. It is only set in
+	// lock files.
+	Resolved *time.Time `json:"resolved,omitempty" yaml:"resolved,omitempty"`
 }
 
 // Validate checks for common problems with the dependency datastructure in
@@ -60,19 +81,41 @@ func (d *Dependency) Validate() error {
 	d.Version = sanitizeString(d.Version)
 	d.Repository = sanitizeString(d.Repository)
 	d.Condition = sanitizeString(d.Condition)
+	d.Digest = sanitizeString(d.Digest)
 	for i := range d.Tags {
 		d.Tags[i] = sanitizeString(d.Tags[i])
 	}
+	for i := range d.Capabilities {
+		d.Capabilities[i] = sanitizeString(d.Capabilities[i])
+		if !isValidAPIVersion(d.Capabilities[i]) {
+			return ValidationErrorf("dependency %q has an invalid capability %q", d.Name, d.Capabilities[i])
+		}
+	}
 	if d.Alias != "" && !aliasNameFormat.MatchString(d.Alias) {
 		return ValidationErrorf("dependency %q has disallowed characters in the alias", d.Name)
 	}
+	if d.Digest != "" {
+		if !strings.HasPrefix(d.Repository, "oci://") {
+			return ValidationErrorf("dependency %q is pinned to a digest, which is only supported for oci:// repositories", d.Name)
+		}
+		if !digestFormat.MatchString(d.Digest) {
+			return ValidationErrorf("dependency %q has an invalid digest %q: must be of the form sha256:<64 hex characters>", d.Name, d.Digest)
+		}
+	}
 	return nil
 }
 
+// LockAPIVersionV2 is the version of lock files that record the content
+// digests and resolution metadata of their dependencies.
+const LockAPIVersionV2 = "v2"
+
 // Lock is a lock file for dependencies.
 //
 // It represents the state that the dependencies should be in.
 type Lock struct {
+	// APIVersion is the version of the lock file format. It is empty for lock
+	// files written before LockAPIVersionV2.
+	APIVersion string `json:"apiVersion,omitempty"`
 	// Generated is the date the lock file was last generated.
 	Generated time.Time `json:"generated"`
 	// Digest is a hash of the dependencies in Chart.yaml.
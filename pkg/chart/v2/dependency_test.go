@@ -42,3 +42,45 @@ func TestValidateDependency(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateDependencyDigest(t *testing.T) {
+	const digest = "sha256:6f1a8a35ea8b1d5ea4b5c0d16e0b1b9ad1e28a8e1e7e5a2cba6a8d1c64bbe3b4"
+	for _, tc := range []struct {
+		repository string
+		digest     string
+		shouldFail bool
+	}{
+		{"oci://example.com/charts", digest, false},
+		{"oci://example.com/charts", "", false},
+		{"https://example.com/charts", digest, true},
+		{"oci://example.com/charts", "sha256:abc", true},
+		{"oci://example.com/charts", "md5:" + digest[7:], true},
+	} {
+		dep := &Dependency{Name: "example", Repository: tc.repository, Digest: tc.digest}
+		res := dep.Validate()
+		if res != nil && !tc.shouldFail {
+			t.Errorf("Failed on case %q %q: %s", tc.repository, tc.digest, res)
+		} else if res == nil && tc.shouldFail {
+			t.Errorf("Expected failure for %q %q", tc.repository, tc.digest)
+		}
+	}
+}
+
+func TestValidateDependencyCapabilities(t *testing.T) {
+	for value, shouldFail := range map[string]bool{
+		"gateway.networking.k8s.io/v1":         false,
+		"gateway.networking.k8s.io/v1/Gateway": false,
+		"v1":                                   false,
+		"":                                     true,
+		"a/b/c/d":                              true,
+		"bad value/v1":                         true,
+	} {
+		dep := &Dependency{Name: "example", Capabilities: []string{value}}
+		res := dep.Validate()
+		if res != nil && !shouldFail {
+			t.Errorf("Failed on case %q", value)
+		} else if res == nil && shouldFail {
+			t.Errorf("Expected failure for %q", value)
+		}
+	}
+}
@@ -15,6 +15,8 @@ limitations under the License.
 
 package v2
 
+import "encoding/json"
+
 // File represents a file as a name/value pair.
 //
 // By convention, name is a relative path within the scope of the chart's
@@ -23,5 +25,38 @@ type File struct {
 	// Name is the path-like name of the template.
 	Name string `json:"name"`
 	// Data is the template as byte data.
+	//
+	// It is empty for files that are loaded lazily; use GetData to read the
+	// data of any file.
 	Data []byte `json:"data"`
+
+	// load reads the data of a file that is loaded lazily.
+	load func() ([]byte, error)
+}
+
+// NewLazyFile returns a file whose data is read by load each time it is
+// needed, rather than being held in memory.
+func NewLazyFile(name string, load func() ([]byte, error)) *File {
+	return &File{Name: name, load: load}
+}
+
+// GetData returns the data of the file, reading it if the file is loaded
+// lazily.
+func (f *File) GetData() ([]byte, error) {
+	if f.load != nil {
+		return f.load()
+	}
+	return f.Data, nil
+}
+
+// MarshalJSON encodes the file, including the data of a file loaded lazily.
+func (f *File) MarshalJSON() ([]byte, error) {
+	data, err := f.GetData()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Name string `json:"name"`
+		Data []byte `json:"data"`
+	}{f.Name, data})
 }
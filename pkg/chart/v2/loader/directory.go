@@ -32,6 +32,49 @@ import (
 
 var utf8bom = []byte{0xEF, 0xBB, 0xBF}
 
+// LazyLoadFileSize is the size above which the files of a chart directory
+// that are only read when rendering are loaded lazily, so that their data is
+// not held in memory.
+var LazyLoadFileSize int64 = 64 * 1024 // Default 64 KiB
+
+// SymlinkPolicy controls how symbolic links in chart directories are handled.
+type SymlinkPolicy int
+
+const (
+	// SymlinkAllow follows symbolic links wherever they resolve to. This is
+	// the default.
+	SymlinkAllow SymlinkPolicy = iota
+	// SymlinkDeny fails to load chart directories containing symbolic links.
+	SymlinkDeny
+	// SymlinkInTree follows symbolic links that resolve within the symlink
+	// root, which is the chart directory unless set with WithSymlinkRoot, and
+	// fails on any others.
+	SymlinkInTree
+)
+
+// DirOption configures how a chart directory is loaded.
+type DirOption func(*dirOptions)
+
+type dirOptions struct {
+	symlinkPolicy SymlinkPolicy
+	symlinkRoot   string
+}
+
+// WithSymlinkPolicy sets how symbolic links in the chart directory are handled.
+func WithSymlinkPolicy(policy SymlinkPolicy) DirOption {
+	return func(o *dirOptions) {
+		o.symlinkPolicy = policy
+	}
+}
+
+// WithSymlinkRoot sets the directory that symbolic links must resolve within
+// under SymlinkInTree, such as the root of a repository holding several charts.
+func WithSymlinkRoot(root string) DirOption {
+	return func(o *dirOptions) {
+		o.symlinkRoot = root
+	}
+}
+
 // DirLoader loads a chart from a directory
 type DirLoader string
 
@@ -43,12 +86,21 @@ func (l DirLoader) Load() (*chart.Chart, error) {
 // LoadDir loads from a directory.
 //
 // This loads charts only from directories.
-func LoadDir(dir string) (*chart.Chart, error) {
+func LoadDir(dir string, opts ...DirOption) (*chart.Chart, error) {
 	topdir, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, err
 	}
 
+	var o dirOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	checkLink, err := symlinkChecker(topdir, o)
+	if err != nil {
+		return nil, err
+	}
+
 	// Just used for errors.
 	c := &chart.Chart{}
 
@@ -105,19 +157,83 @@ func LoadDir(dir string) (*chart.Chart, error) {
 			return fmt.Errorf("chart file %q is larger than the maximum file size %d", fi.Name(), MaxDecompressedFileSize)
 		}
 
-		data, err := os.ReadFile(name)
-		if err != nil {
-			return errors.Wrapf(err, "error reading %s", n)
+		if fi.Size() > LazyLoadFileSize && lazyLoadable(n) {
+			files = append(files, &BufferedFile{Name: n, load: func() ([]byte, error) {
+				return readChartFile(name, n)
+			}})
+			return nil
 		}
 
-		data = bytes.TrimPrefix(data, utf8bom)
+		data, err := readChartFile(name, n)
+		if err != nil {
+			return err
+		}
 
 		files = append(files, &BufferedFile{Name: n, Data: data})
 		return nil
 	}
-	if err = sympath.Walk(topdir, walk); err != nil {
+	if err = sympath.WalkLinks(topdir, walk, checkLink); err != nil {
 		return c, err
 	}
 
 	return LoadFiles(files)
 }
+
+func readChartFile(name, n string) ([]byte, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", n)
+	}
+	return bytes.TrimPrefix(data, utf8bom), nil
+}
+
+// lazyLoadable reports whether the chart file with the given name is only read
+// when rendering, as opposed to while loading the chart.
+func lazyLoadable(name string) bool {
+	// Files of unpacked subcharts are judged by their name in the subchart.
+	for strings.HasPrefix(name, "charts/") {
+		parts := strings.SplitN(strings.TrimPrefix(name, "charts/"), "/", 2)
+		if len(parts) < 2 {
+			return false
+		}
+		name = parts[1]
+	}
+	switch name {
+	case "Chart.yaml", "Chart.lock", "values.yaml", "values.schema.json", "requirements.yaml", "requirements.lock":
+		return false
+	}
+	return !strings.HasPrefix(name, "templates/") && !strings.HasPrefix(name, "crds/")
+}
+
+// symlinkChecker returns the check applied to the symbolic links in the chart
+// directory topdir under the given options.
+func symlinkChecker(topdir string, o dirOptions) (sympath.LinkFunc, error) {
+	switch o.symlinkPolicy {
+	case SymlinkAllow:
+		return nil, nil
+	case SymlinkDeny:
+		return func(path, _ string) error {
+			return errors.Errorf("chart directory %s contains the symbolic link %s, which is not allowed", topdir, path)
+		}, nil
+	case SymlinkInTree:
+		root := o.symlinkRoot
+		if root == "" {
+			root = topdir
+		}
+		root, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		if root, err = filepath.EvalSymlinks(root); err != nil {
+			return nil, err
+		}
+		return func(path, resolved string) error {
+			if resolved == root || strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+				return nil
+			}
+			return errors.Errorf("symbolic link %s resolves to %s, which is outside of %s", path, resolved, root)
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown symlink policy %d", o.symlinkPolicy)
+	}
+}
@@ -70,6 +70,18 @@ func Load(name string) (*chart.Chart, error) {
 type BufferedFile struct {
 	Name string
 	Data []byte
+
+	// load reads the data of a file that is loaded lazily.
+	load func() ([]byte, error)
+}
+
+// chartFile returns the chart file for a buffered file, keeping it lazy if it
+// was loaded lazily.
+func chartFile(f *BufferedFile) *chart.File {
+	if f.load != nil {
+		return chart.NewLazyFile(f.Name, f.load)
+	}
+	return &chart.File{Name: f.Name, Data: f.Data}
 }
 
 // LoadFiles loads from in-memory files.
@@ -80,7 +92,7 @@ func LoadFiles(files []*BufferedFile) (*chart.Chart, error) {
 	// do not rely on assumed ordering of files in the chart and crash
 	// if Chart.yaml was not coming early enough to initialize metadata
 	for _, f := range files {
-		c.Raw = append(c.Raw, &chart.File{Name: f.Name, Data: f.Data})
+		c.Raw = append(c.Raw, chartFile(f))
 		if f.Name == "Chart.yaml" {
 			if c.Metadata == nil {
 				c.Metadata = new(chart.Metadata)
@@ -156,9 +168,9 @@ func LoadFiles(files []*BufferedFile) (*chart.Chart, error) {
 
 			fname := strings.TrimPrefix(f.Name, "charts/")
 			cname := strings.SplitN(fname, "/", 2)[0]
-			subcharts[cname] = append(subcharts[cname], &BufferedFile{Name: fname, Data: f.Data})
+			subcharts[cname] = append(subcharts[cname], &BufferedFile{Name: fname, Data: f.Data, load: f.load})
 		default:
-			c.Files = append(c.Files, &chart.File{Name: f.Name, Data: f.Data})
+			c.Files = append(c.Files, chartFile(f))
 		}
 	}
 
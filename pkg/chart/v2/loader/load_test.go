@@ -48,6 +48,62 @@ func TestLoadDir(t *testing.T) {
 	verifyDependenciesLock(t, c)
 }
 
+func TestLoadDirLazyFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	large := bytes.Repeat([]byte("a"), int(LazyLoadFileSize)+1)
+	writeFile("Chart.yaml", []byte("apiVersion: v2\nname: lazy\nversion: 0.1.0\n"))
+	writeFile("templates/large.yaml", large)
+	writeFile("assets/large.bin", large)
+	writeFile("assets/small.txt", []byte("small"))
+	writeFile("charts/sub/Chart.yaml", []byte("apiVersion: v2\nname: sub\nversion: 0.1.0\n"))
+	writeFile("charts/sub/values.yaml", append([]byte("large: "), large...))
+	writeFile("charts/sub/assets/large.bin", large)
+
+	c, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to load chart: %s", err)
+	}
+
+	files := map[string]*chart.File{}
+	for _, f := range c.Files {
+		files[f.Name] = f
+	}
+	if f := files["assets/small.txt"]; string(f.Data) != "small" {
+		t.Errorf("expected small files to be loaded eagerly, got %q", f.Data)
+	}
+	if len(c.Templates) != 1 || len(c.Templates[0].Data) != len(large) {
+		t.Errorf("expected large templates to be loaded eagerly")
+	}
+	lazy := files["assets/large.bin"]
+	if lazy.Data != nil {
+		t.Errorf("expected large files to be loaded lazily")
+	}
+
+	// The data is read from disk when needed.
+	writeFile("assets/large.bin", []byte("changed"))
+	if data, err := lazy.GetData(); err != nil || string(data) != "changed" {
+		t.Errorf("expected the lazily loaded data to be read from disk, got %q, %v", data, err)
+	}
+
+	sub := c.Dependencies()[0]
+	if sub.Values["large"] != string(large) {
+		t.Errorf("expected the values of unpacked subcharts to be loaded eagerly")
+	}
+	if sub.Files[0].Data != nil {
+		t.Errorf("expected the large files of unpacked subcharts to be loaded lazily")
+	}
+}
+
 func TestLoadDirWithDevNull(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("test only works on unix systems with /dev/null present")
@@ -87,6 +143,82 @@ func TestLoadDirWithSymlink(t *testing.T) {
 	verifyDependenciesLock(t, c)
 }
 
+func TestLoadDirSymlinkPolicy(t *testing.T) {
+	repo := t.TempDir()
+	chartDir := filepath.Join(repo, "chart")
+	for name, data := range map[string]string{
+		"chart/Chart.yaml":        "apiVersion: v2\nname: linked\nversion: 0.1.0\n",
+		"chart/files/config.yaml": "in: tree\n",
+		"shared/_helpers.tpl":     `{{ define "shared" }}{{ end }}`,
+	} {
+		p := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(chartDir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("files", "config.yaml"), filepath.Join(chartDir, "config.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A link resolving within the chart directory.
+	for _, tc := range []struct {
+		name string
+		opts []DirOption
+		err  string
+	}{
+		{name: "allow", opts: nil},
+		{name: "deny", opts: []DirOption{WithSymlinkPolicy(SymlinkDeny)}, err: "contains the symbolic link"},
+		{name: "in tree", opts: []DirOption{WithSymlinkPolicy(SymlinkInTree)}},
+	} {
+		t.Run("in chart/"+tc.name, func(t *testing.T) {
+			_, err := LoadDir(chartDir, tc.opts...)
+			if tc.err == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	// A link resolving outside of the chart directory, but within the repository.
+	if err := os.Symlink(filepath.Join("..", "..", "shared", "_helpers.tpl"), filepath.Join(chartDir, "templates", "_helpers.tpl")); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		opts []DirOption
+		err  string
+	}{
+		{name: "allow", opts: nil},
+		{name: "deny", opts: []DirOption{WithSymlinkPolicy(SymlinkDeny)}, err: "contains the symbolic link"},
+		{name: "in tree", opts: []DirOption{WithSymlinkPolicy(SymlinkInTree)}, err: "which is outside of"},
+		{name: "in repository tree", opts: []DirOption{WithSymlinkPolicy(SymlinkInTree), WithSymlinkRoot(repo)}},
+	} {
+		t.Run("in repository/"+tc.name, func(t *testing.T) {
+			c, err := LoadDir(chartDir, tc.opts...)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(c.Templates) != 1 || c.Templates[0].Name != "templates/_helpers.tpl" {
+				t.Errorf("expected the linked template to be loaded, got %v", c.Templates)
+			}
+		})
+	}
+}
+
 func TestBomTestData(t *testing.T) {
 	testFiles := []string{"frobnitz_with_bom/.helmignore", "frobnitz_with_bom/templates/template.tpl", "frobnitz_with_bom/Chart.yaml"}
 	for _, file := range testFiles {
@@ -81,6 +81,23 @@ type Metadata struct {
 	Dependencies []*Dependency `json:"dependencies,omitempty"`
 	// Specifies the chart type: application or library
 	Type string `json:"type,omitempty"`
+	// Engine is the rendering engine of the chart: gotpl, the default, for
+	// the Go templates of the templates/ directory, cue for the CUE package
+	// of the cue/ directory, or jsonnet for the .jsonnet templates of the
+	// templates/ directory.
+	Engine string `json:"engine,omitempty"`
+	// Transform is the path of a Starlark script of the chart, which
+	// transforms the objects rendered from the chart and its subcharts.
+	Transform string `json:"transform,omitempty"`
+	// RequiredAPIVersions are the APIs the chart depends on at runtime, as
+	// "cert-manager.io/v1" or "cert-manager.io/v1/Certificate", such as the
+	// CRDs of an operator it expects to be installed.
+	RequiredAPIVersions []string `json:"requiredAPIVersions,omitempty"`
+	// Exports are the named templates a library chart makes available to the
+	// charts depending on it. When set, the templates of the library are
+	// namespaced by its name or alias, as in "common.labels" being included
+	// as "lib.common.labels", so that libraries do not collide.
+	Exports []string `json:"exports,omitempty"`
 }
 
 // Validate checks the metadata for known issues and sanitizes string
@@ -104,6 +121,9 @@ func (md *Metadata) Validate() error {
 	for i := range md.Keywords {
 		md.Keywords[i] = sanitizeString(md.Keywords[i])
 	}
+	for i := range md.RequiredAPIVersions {
+		md.RequiredAPIVersions[i] = sanitizeString(md.RequiredAPIVersions[i])
+	}
 
 	if md.APIVersion == "" {
 		return ValidationError("chart.metadata.apiVersion is required")
@@ -125,6 +145,25 @@ func (md *Metadata) Validate() error {
 	if !isValidChartType(md.Type) {
 		return ValidationError("chart.metadata.type must be application or library")
 	}
+	if !isValidChartEngine(md.Engine) {
+		return ValidationError("chart.metadata.engine must be gotpl, cue or jsonnet")
+	}
+	if md.Transform != "" && !isValidTransform(md.Transform) {
+		return ValidationErrorf("chart.metadata.transform %q must be the path of a .star file of the chart", md.Transform)
+	}
+	if len(md.Exports) > 0 && md.Type != "library" {
+		return ValidationError("chart.metadata.exports is only allowed in library charts")
+	}
+	for _, export := range md.Exports {
+		if export == "" {
+			return ValidationError("chart.metadata.exports must not contain empty names")
+		}
+	}
+	for _, apiVersion := range md.RequiredAPIVersions {
+		if !isValidAPIVersion(apiVersion) {
+			return ValidationErrorf("chart.metadata.requiredAPIVersions %q is invalid", apiVersion)
+		}
+	}
 
 	for _, m := range md.Maintainers {
 		if err := m.Validate(); err != nil {
@@ -159,6 +198,33 @@ func isValidChartType(in string) bool {
 	return false
 }
 
+func isValidChartEngine(in string) bool {
+	switch in {
+	case "", "gotpl", "cue", "jsonnet":
+		return true
+	}
+	return false
+}
+
+func isValidTransform(p string) bool {
+	return filepath.Ext(p) == ".star" && filepath.IsLocal(p)
+}
+
+// isValidAPIVersion checks that v is an API group version, optionally
+// followed by a kind, as in "v1", "apps/v1" or "apps/v1/Deployment".
+func isValidAPIVersion(v string) bool {
+	parts := strings.Split(v, "/")
+	if len(parts) > 3 {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" || strings.Contains(part, " ") {
+			return false
+		}
+	}
+	return true
+}
+
 func isValidSemver(v string) bool {
 	_, err := semver.NewVersion(v)
 	return err == nil
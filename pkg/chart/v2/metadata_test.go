@@ -55,6 +55,31 @@ func TestValidate(t *testing.T) {
 			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Type: "test"},
 			ValidationError("chart.metadata.type must be application or library"),
 		},
+		{
+			"chart with bad engine",
+			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Engine: "erb"},
+			ValidationError("chart.metadata.engine must be gotpl, cue or jsonnet"),
+		},
+		{
+			"chart with cue engine",
+			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Engine: "cue"},
+			nil,
+		},
+		{
+			"chart with transform",
+			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Transform: "scripts/transform.star"},
+			nil,
+		},
+		{
+			"chart with transform outside of the chart",
+			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Transform: "../transform.star"},
+			ValidationError("chart.metadata.transform \"../transform.star\" must be the path of a .star file of the chart"),
+		},
+		{
+			"chart with jsonnet engine",
+			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Engine: "jsonnet"},
+			nil,
+		},
 		{
 			"chart without dependency",
 			&Metadata{Name: "test", APIVersion: "v2", Version: "1.0", Type: "application"},
@@ -177,6 +202,26 @@ func TestValidate(t *testing.T) {
 			&Metadata{APIVersion: "v2", Name: "test", Version: "1.2.3.4"},
 			ValidationError("chart.metadata.version \"1.2.3.4\" is invalid"),
 		},
+		{
+			"required API versions",
+			&Metadata{APIVersion: "v2", Name: "test", Version: "1.0", RequiredAPIVersions: []string{"v1", "apps/v1", "cert-manager.io/v1/Certificate"}},
+			nil,
+		},
+		{
+			"required API version invalid",
+			&Metadata{APIVersion: "v2", Name: "test", Version: "1.0", RequiredAPIVersions: []string{"cert-manager.io//Certificate"}},
+			ValidationError("chart.metadata.requiredAPIVersions \"cert-manager.io//Certificate\" is invalid"),
+		},
+		{
+			"exports in library chart",
+			&Metadata{APIVersion: "v2", Name: "test", Version: "1.0", Type: "library", Exports: []string{"common.labels"}},
+			nil,
+		},
+		{
+			"exports in application chart",
+			&Metadata{APIVersion: "v2", Name: "test", Version: "1.0", Type: "application", Exports: []string{"common.labels"}},
+			ValidationError("chart.metadata.exports is only allowed in library charts"),
+		},
 	}
 
 	for _, tt := range tests {
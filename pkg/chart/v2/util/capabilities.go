@@ -17,10 +17,14 @@ package util
 
 import (
 	"fmt"
+	"os"
 	"strconv"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
@@ -67,6 +71,54 @@ func (capabilities *Capabilities) Copy() *Capabilities {
 	}
 }
 
+// CapabilitiesFile is the YAML or JSON form of saved capabilities, as used by
+// LoadCapabilities.
+//
+//	kubeVersion: v1.29.4
+//	apiVersions:
+//	- v1
+//	- apps/v1
+type CapabilitiesFile struct {
+	KubeVersion string     `json:"kubeVersion"`
+	APIVersions VersionSet `json:"apiVersions"`
+}
+
+// File returns the capabilities in their saved form.
+func (capabilities *Capabilities) File() *CapabilitiesFile {
+	return &CapabilitiesFile{
+		KubeVersion: capabilities.KubeVersion.Version,
+		APIVersions: capabilities.APIVersions,
+	}
+}
+
+// LoadCapabilities reads capabilities from a YAML or JSON file, such as one
+// saved from a cluster with 'helm capabilities'. The API versions of the file
+// replace the default ones, so that templates see exactly the APIs it lists.
+// The defaults are used for any field the file leaves out.
+func LoadCapabilities(path string) (*Capabilities, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f CapabilitiesFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, errors.Wrapf(err, "parsing capabilities file %s", path)
+	}
+
+	caps := DefaultCapabilities.Copy()
+	if f.KubeVersion != "" {
+		kv, err := ParseKubeVersion(f.KubeVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid kubeVersion %q in capabilities file %s", f.KubeVersion, path)
+		}
+		caps.KubeVersion = *kv
+	}
+	if f.APIVersions != nil {
+		caps.APIVersions = f.APIVersions
+	}
+	return caps, nil
+}
+
 // KubeVersion is the Kubernetes version.
 type KubeVersion struct {
 	Version string // Kubernetes version
@@ -114,10 +166,12 @@ func allKnownVersions() VersionSet {
 	// We should register the built in extension APIs as well so CRDs are
 	// supported in the default version set. This has caused problems with `helm
 	// template` in the past, so let's be safe
-	apiextensionsv1beta1.AddToScheme(scheme.Scheme)
-	apiextensionsv1.AddToScheme(scheme.Scheme)
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	_ = apiextensionsv1beta1.AddToScheme(s)
+	_ = apiextensionsv1.AddToScheme(s)
 
-	groups := scheme.Scheme.PrioritizedVersionsAllGroups()
+	groups := s.PrioritizedVersionsAllGroups()
 	vs := make(VersionSet, 0, len(groups))
 	for _, gv := range groups {
 		vs = append(vs, gv.String())
@@ -82,3 +82,35 @@ func TestParseKubeVersion(t *testing.T) {
 		t.Errorf("Expected parsed KubeVersion.Minor to be 16, got %q", kv.Minor)
 	}
 }
+
+func TestLoadCapabilities(t *testing.T) {
+	caps, err := LoadCapabilities("testdata/capabilities/cluster.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.KubeVersion.Version != "v1.29.4" || caps.KubeVersion.Major != "1" || caps.KubeVersion.Minor != "29" {
+		t.Errorf("Expected kube version v1.29.4, got %+v", caps.KubeVersion)
+	}
+	if len(caps.APIVersions) != 3 || !caps.APIVersions.Has("monitoring.coreos.com/v1") {
+		t.Errorf("Expected the API versions of the file, got %v", caps.APIVersions)
+	}
+	if caps.HelmVersion != DefaultCapabilities.HelmVersion {
+		t.Errorf("Expected the default Helm version, got %+v", caps.HelmVersion)
+	}
+
+	// Fields left out of the file keep their defaults.
+	caps, err = LoadCapabilities("testdata/capabilities/api-versions.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.KubeVersion != DefaultCapabilities.KubeVersion {
+		t.Errorf("Expected the default kube version, got %+v", caps.KubeVersion)
+	}
+	if len(caps.APIVersions) != 1 || caps.APIVersions.Has("apps/v1") {
+		t.Errorf("Expected only v1, got %v", caps.APIVersions)
+	}
+
+	if _, err := LoadCapabilities("testdata/capabilities/invalid.yaml"); err == nil {
+		t.Error("Expected an error for an invalid kube version")
+	}
+}
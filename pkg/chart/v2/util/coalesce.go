@@ -19,9 +19,9 @@ package util
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/mitchellh/copystructure"
-	"github.com/pkg/errors"
 
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 )
@@ -39,15 +39,38 @@ func concatPrefix(a, b string) string {
 //
 //   - Values in a higher level chart always override values in a lower-level
 //     dependency chart
-//   - Scalar values and arrays are replaced, maps are merged
+//   - Scalar values and arrays are replaced, maps are merged. A list can
+//     instead be merged with the list it overrides by a ListPatchKey
+//     directive
 //   - A chart has access to all of the variables for it, as well as all of
 //     the values destined for its dependencies.
+//
+// Type conflicts between a table and a non-table value are logged as
+// warnings, see CoalesceValuesWithConflicts to collect them instead.
 func CoalesceValues(chrt *chart.Chart, vals map[string]interface{}) (Values, error) {
 	valsCopy, err := copyValues(vals)
 	if err != nil {
 		return vals, err
 	}
-	return coalesce(log.Printf, chrt, valsCopy, "", false)
+	coalesced, err := coalesce(newListReporter(log.Printf), chrt, valsCopy, "", false)
+	StripListDirectives(coalesced)
+	return coalesced, err
+}
+
+// CoalesceValuesWithConflicts coalesces the values like CoalesceValues, but
+// returns the type conflicts found along the way instead of logging them.
+//
+// Each conflict holds the full path of the value and the sources of the two
+// values that could not be merged, so that they can be reported to the user.
+func CoalesceValuesWithConflicts(chrt *chart.Chart, vals map[string]interface{}) (Values, []ValueConflict, error) {
+	valsCopy, err := copyValues(vals)
+	if err != nil {
+		return vals, nil, err
+	}
+	r := newListReporter(func(string, ...interface{}) {})
+	coalesced, err := coalesce(r, chrt, valsCopy, "", false)
+	StripListDirectives(coalesced)
+	return coalesced, r.conflicts, err
 }
 
 // MergeValues is used to merge the values in a chart and its subcharts. This
@@ -57,19 +80,22 @@ func CoalesceValues(chrt *chart.Chart, vals map[string]interface{}) (Values, err
 //
 //   - Values in a higher level chart always override values in a lower-level
 //     dependency chart
-//   - Scalar values and arrays are replaced, maps are merged
+//   - Scalar values and arrays are replaced, maps are merged. A list can
+//     instead be merged with the list it overrides by a ListPatchKey
+//     directive
 //   - A chart has access to all of the variables for it, as well as all of
 //     the values destined for its dependencies.
 //
 // Retaining Nils is useful when processes early in a Helm action or business
 // logic need to retain them for when Coalescing will happen again later in the
-// business logic.
+// business logic. The list directives are retained, and not applied, for the
+// same reason.
 func MergeValues(chrt *chart.Chart, vals map[string]interface{}) (Values, error) {
 	valsCopy, err := copyValues(vals)
 	if err != nil {
 		return vals, err
 	}
-	return coalesce(log.Printf, chrt, valsCopy, "", true)
+	return coalesce(newReporter(log.Printf, UserValuesSource), chrt, valsCopy, "", true)
 }
 
 func copyValues(vals map[string]interface{}) (Values, error) {
@@ -89,6 +115,100 @@ func copyValues(vals map[string]interface{}) (Values, error) {
 
 type printFn func(format string, v ...interface{})
 
+// UserValuesSource is the source reported for the values passed to
+// CoalesceValues, which come from the values files and --set flags.
+const UserValuesSource = "user-supplied values"
+
+// ValueConflict describes a value that could not be coalesced because one of
+// its sources sets a table and the other a non-table value.
+type ValueConflict struct {
+	// Path is the full path of the value, starting with the chart names.
+	Path string
+	// Value is the value that was kept, set by Source.
+	Value  interface{}
+	Source string
+	// Ignored is the value that was dropped, set by IgnoredSource.
+	Ignored       interface{}
+	IgnoredSource string
+}
+
+func (c ValueConflict) Error() string {
+	return fmt.Sprintf("type conflict for %s: %s%s cannot be merged with %s%s",
+		c.Path, describeValue(c.Value), describeSource(c.Source), describeValue(c.Ignored), describeSource(c.IgnoredSource))
+}
+
+func describeValue(v interface{}) string {
+	if istable(v) {
+		return "table"
+	}
+	return fmt.Sprintf("non-table value (%v)", v)
+}
+
+func describeSource(source string) string {
+	if source == "" {
+		return ""
+	}
+	return " from " + source
+}
+
+// reporter reports the warnings found while coalescing, and keeps track of
+// where the values copied along the way come from.
+type reporter struct {
+	printf printFn
+	// origins maps the full path of the values copied from a values file to
+	// that file. Values below a recorded path share its origin.
+	origins       map[string]string
+	defaultSource string
+	conflicts     []ValueConflict
+	// patchLists applies the list directives. Without it, lists are replaced
+	// and keep their directives, to be applied when coalescing them later.
+	patchLists bool
+}
+
+func newReporter(printf printFn, defaultSource string) *reporter {
+	return &reporter{printf: printf, origins: make(map[string]string), defaultSource: defaultSource}
+}
+
+func newListReporter(printf printFn) *reporter {
+	r := newReporter(printf, UserValuesSource)
+	r.patchLists = true
+	return r
+}
+
+// record notes that the value at path was copied from source.
+func (r *reporter) record(path, source string) {
+	if source != "" {
+		r.origins[path] = source
+	}
+}
+
+// source returns where the value at path comes from.
+func (r *reporter) source(path string) string {
+	for {
+		if source, ok := r.origins[path]; ok {
+			return source
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return r.defaultSource
+		}
+		path = path[:i]
+	}
+}
+
+// conflict records a type conflict where kept won over ignored.
+func (r *reporter) conflict(path string, kept, ignored interface{}, keptSource, ignoredSource string) {
+	c := ValueConflict{Path: path, Value: kept, Source: keptSource, Ignored: ignored, IgnoredSource: ignoredSource}
+	r.conflicts = append(r.conflicts, c)
+	r.printf("warning: %s. Keeping the %s.", c, describeValue(kept))
+}
+
+// valuesFile returns the path of the values file of a chart, as reported in
+// the value conflicts.
+func valuesFile(c *chart.Chart) string {
+	return c.ChartFullPath() + "/" + ValuesfileName
+}
+
 // coalesce coalesces the dest values and the chart values, giving priority to the dest values.
 //
 // This is a helper function for CoalesceValues and MergeValues.
@@ -96,28 +216,35 @@ type printFn func(format string, v ...interface{})
 // Note, the merge argument specifies whether this is being used by MergeValues
 // or CoalesceValues. Coalescing removes null values and their keys in some
 // situations while merging keeps the null values.
-func coalesce(printf printFn, ch *chart.Chart, dest map[string]interface{}, prefix string, merge bool) (map[string]interface{}, error) {
-	coalesceValues(printf, ch, dest, prefix, merge)
-	return coalesceDeps(printf, ch, dest, prefix, merge)
+func coalesce(r *reporter, ch *chart.Chart, dest map[string]interface{}, prefix string, merge bool) (map[string]interface{}, error) {
+	coalesceValues(r, ch, dest, prefix, merge)
+	return coalesceDeps(r, ch, dest, prefix, merge)
 }
 
 // coalesceDeps coalesces the dependencies of the given chart.
-func coalesceDeps(printf printFn, chrt *chart.Chart, dest map[string]interface{}, prefix string, merge bool) (map[string]interface{}, error) {
+func coalesceDeps(r *reporter, chrt *chart.Chart, dest map[string]interface{}, prefix string, merge bool) (map[string]interface{}, error) {
+	subPrefix := concatPrefix(prefix, chrt.Metadata.Name)
 	for _, subchart := range chrt.Dependencies() {
 		if c, ok := dest[subchart.Name()]; !ok {
 			// If dest doesn't already have the key, create it.
 			dest[subchart.Name()] = make(map[string]interface{})
 		} else if !istable(c) {
-			return dest, errors.Errorf("type mismatch on %s: %t", subchart.Name(), c)
+			path := concatPrefix(subPrefix, subchart.Name())
+			return dest, ValueConflict{
+				Path:          path,
+				Value:         c,
+				Source:        r.source(path),
+				Ignored:       subchart.Values,
+				IgnoredSource: valuesFile(subchart),
+			}
 		}
 		if dv, ok := dest[subchart.Name()]; ok {
 			dvmap := dv.(map[string]interface{})
-			subPrefix := concatPrefix(prefix, chrt.Metadata.Name)
 			// Get globals out of dest and merge them into dvmap.
-			coalesceGlobals(printf, dvmap, dest, subPrefix, merge)
+			coalesceGlobals(r, dvmap, dest, subPrefix, merge)
 			// Now coalesce the rest of the values.
 			var err error
-			dest[subchart.Name()], err = coalesce(printf, subchart, dvmap, subPrefix, merge)
+			dest[subchart.Name()], err = coalesce(r, subchart, dvmap, subPrefix, merge)
 			if err != nil {
 				return dest, err
 			}
@@ -129,20 +256,20 @@ func coalesceDeps(printf printFn, chrt *chart.Chart, dest map[string]interface{}
 // coalesceGlobals copies the globals out of src and merges them into dest.
 //
 // For convenience, returns dest.
-func coalesceGlobals(printf printFn, dest, src map[string]interface{}, prefix string, _ bool) {
+func coalesceGlobals(r *reporter, dest, src map[string]interface{}, prefix string, _ bool) {
 	var dg, sg map[string]interface{}
 
 	if destglob, ok := dest[GlobalKey]; !ok {
 		dg = make(map[string]interface{})
 	} else if dg, ok = destglob.(map[string]interface{}); !ok {
-		printf("warning: skipping globals because destination %s is not a table.", GlobalKey)
+		r.printf("warning: skipping globals because destination %s is not a table.", GlobalKey)
 		return
 	}
 
 	if srcglob, ok := src[GlobalKey]; !ok {
 		sg = make(map[string]interface{})
 	} else if sg, ok = srcglob.(map[string]interface{}); !ok {
-		printf("warning: skipping globals because source %s is not a table.", GlobalKey)
+		r.printf("warning: skipping globals because source %s is not a table.", GlobalKey)
 		return
 	}
 
@@ -158,7 +285,7 @@ func coalesceGlobals(printf printFn, dest, src map[string]interface{}, prefix st
 				dg[key] = vv
 			} else {
 				if destvmap, ok := destv.(map[string]interface{}); !ok {
-					printf("Conflict: cannot merge map onto non-map for %q. Skipping.", key)
+					r.printf("Conflict: cannot merge map onto non-map for %q. Skipping.", key)
 				} else {
 					// Basically, we reverse order of coalesce here to merge
 					// top-down.
@@ -166,13 +293,13 @@ func coalesceGlobals(printf printFn, dest, src map[string]interface{}, prefix st
 					// In this location coalesceTablesFullKey should always have
 					// merge set to true. The output of coalesceGlobals is run
 					// through coalesce where any nils will be removed.
-					coalesceTablesFullKey(printf, vv, destvmap, subPrefix, true)
+					coalesceTablesFullKey(r, vv, destvmap, subPrefix, true, "")
 					dg[key] = vv
 				}
 			}
 		} else if dv, ok := dg[key]; ok && istable(dv) {
 			// It's not clear if this condition can actually ever trigger.
-			printf("key %s is table. Skipping", key)
+			r.printf("key %s is table. Skipping", key)
 		} else {
 			// TODO: Do we need to do any additional checking on the value?
 			dg[key] = val
@@ -180,7 +307,6 @@ func coalesceGlobals(printf printFn, dest, src map[string]interface{}, prefix st
 	}
 	dest[GlobalKey] = dg
 }
-
 func copyMap(src map[string]interface{}) map[string]interface{} {
 	m := make(map[string]interface{}, len(src))
 	for k, v := range src {
@@ -192,8 +318,9 @@ func copyMap(src map[string]interface{}) map[string]interface{} {
 // coalesceValues builds up a values map for a particular chart.
 //
 // Values in v will override the values in the chart.
-func coalesceValues(printf printFn, c *chart.Chart, v map[string]interface{}, prefix string, merge bool) {
+func coalesceValues(r *reporter, c *chart.Chart, v map[string]interface{}, prefix string, merge bool) {
 	subPrefix := concatPrefix(prefix, c.Metadata.Name)
+	source := valuesFile(c)
 
 	// Using c.Values directly when coalescing a table can cause problems where
 	// the original c.Values is altered. Creating a deep copy stops the problem.
@@ -206,7 +333,7 @@ func coalesceValues(printf printFn, c *chart.Chart, v map[string]interface{}, pr
 		// means there is a problem in the deep copying package or something
 		// wrong with c.Values. In this case we will use c.Values and report
 		// an error.
-		printf("warning: unable to copy values, err: %s", err)
+		r.printf("warning: unable to copy values, err: %s", err)
 		vc = c.Values
 	} else {
 		vc, ok = valuesCopy.(map[string]interface{})
@@ -214,12 +341,13 @@ func coalesceValues(printf printFn, c *chart.Chart, v map[string]interface{}, pr
 			// c.Values has a map[string]interface{} structure. If the copy of
 			// it cannot be treated as map[string]interface{} there is something
 			// strangely wrong. Log it and use c.Values
-			printf("warning: unable to convert values copy to values type")
+			r.printf("warning: unable to convert values copy to values type")
 			vc = c.Values
 		}
 	}
 
 	for key, val := range vc {
+		fullkey := concatPrefix(subPrefix, key)
 		if value, ok := v[key]; ok {
 			if value == nil && !merge {
 				// When the YAML value is null and we are coalescing instead of
@@ -234,7 +362,7 @@ func coalesceValues(printf printFn, c *chart.Chart, v map[string]interface{}, pr
 					// If the original value is nil, there is nothing to coalesce, so we don't print
 					// the warning
 					if val != nil {
-						printf("warning: skipped value for %s.%s: Not a table.", subPrefix, key)
+						r.conflict(fullkey, value, val, r.source(fullkey), source)
 					}
 				} else {
 					// If the key is a child chart, coalesce tables with Merge set to true
@@ -242,12 +370,20 @@ func coalesceValues(printf printFn, c *chart.Chart, v map[string]interface{}, pr
 
 					// Because v has higher precedence than nv, dest values override src
 					// values.
-					coalesceTablesFullKey(printf, dest, src, concatPrefix(subPrefix, key), merge)
+					coalesceTablesFullKey(r, dest, src, fullkey, merge, source)
 				}
+			} else if value != nil && istable(val) {
+				// A non-table value replaces the default table of the chart,
+				// which usually means the templates will not find what they
+				// expect under that key.
+				r.conflict(fullkey, value, val, r.source(fullkey), source)
+			} else if dl, sl, ok := lists(r, value, val); ok {
+				v[key] = patchLists(r, dl, sl, fullkey, merge, source)
 			}
 		} else {
 			// If the key is not in v, copy it from nv.
 			v[key] = val
+			r.record(fullkey, source)
 		}
 	}
 }
@@ -265,17 +401,18 @@ func childChartMergeTrue(chrt *chart.Chart, key string, merge bool) bool {
 //
 // dest is considered authoritative.
 func CoalesceTables(dst, src map[string]interface{}) map[string]interface{} {
-	return coalesceTablesFullKey(log.Printf, dst, src, "", false)
+	return coalesceTablesFullKey(newReporter(log.Printf, ""), dst, src, "", false, "")
 }
 
 func MergeTables(dst, src map[string]interface{}) map[string]interface{} {
-	return coalesceTablesFullKey(log.Printf, dst, src, "", true)
+	return coalesceTablesFullKey(newReporter(log.Printf, ""), dst, src, "", true, "")
 }
 
 // coalesceTablesFullKey merges a source map into a destination map.
 //
-// dest is considered authoritative.
-func coalesceTablesFullKey(printf printFn, dst, src map[string]interface{}, prefix string, merge bool) map[string]interface{} {
+// dest is considered authoritative. srcSource is where the values of src come
+// from, if known.
+func coalesceTablesFullKey(r *reporter, dst, src map[string]interface{}, prefix string, merge bool, srcSource string) map[string]interface{} {
 	// When --reuse-values is set but there are no modifications yet, return new values
 	if src == nil {
 		return dst
@@ -291,15 +428,32 @@ func coalesceTablesFullKey(printf printFn, dst, src map[string]interface{}, pref
 			delete(dst, key)
 		} else if !ok {
 			dst[key] = val
+			r.record(fullkey, srcSource)
 		} else if istable(val) {
 			if istable(dv) {
-				coalesceTablesFullKey(printf, dv.(map[string]interface{}), val.(map[string]interface{}), fullkey, merge)
+				coalesceTablesFullKey(r, dv.(map[string]interface{}), val.(map[string]interface{}), fullkey, merge, srcSource)
 			} else {
-				printf("warning: cannot overwrite table with non table for %s (%v)", fullkey, val)
+				r.conflict(fullkey, dv, val, r.source(fullkey), srcSource)
 			}
 		} else if istable(dv) && val != nil {
-			printf("warning: destination for %s is a table. Ignoring non-table value (%v)", fullkey, val)
+			r.conflict(fullkey, dv, val, r.source(fullkey), srcSource)
+		} else if dl, sl, ok := lists(r, dv, val); ok {
+			dst[key] = patchLists(r, dl, sl, fullkey, merge, srcSource)
 		}
 	}
 	return dst
 }
+
+// lists returns dst and src as lists, if both are lists and their directives
+// are to be applied.
+func lists(r *reporter, dst, src interface{}) ([]interface{}, []interface{}, bool) {
+	if !r.patchLists {
+		return nil, nil, false
+	}
+	dl, ok := dst.([]interface{})
+	if !ok {
+		return nil, nil, false
+	}
+	sl, ok := src.([]interface{})
+	return dl, sl, ok
+}
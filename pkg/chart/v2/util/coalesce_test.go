@@ -708,18 +708,92 @@ func TestCoalesceValuesWarnings(t *testing.T) {
 		warnings = append(warnings, fmt.Sprintf(format, v...))
 	}
 
-	_, err := coalesce(printf, c, vals, "", false)
+	_, err := coalesce(newReporter(printf, UserValuesSource), c, vals, "", false)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	t.Logf("vals: %v", vals)
-	assert.Contains(t, warnings, "warning: skipped value for level1.level2.level3.boat: Not a table.")
-	assert.Contains(t, warnings, "warning: destination for level1.level2.level3.spear.tip is a table. Ignoring non-table value (true)")
-	assert.Contains(t, warnings, "warning: cannot overwrite table with non table for level1.level2.level3.spear.sail (map[cotton:true])")
+	assert.Contains(t, warnings, "warning: type conflict for level1.level2.level3.boat: table from user-supplied values cannot be merged with non-table value (true) from level1/charts/level2/charts/level3/values.yaml. Keeping the table.")
+	assert.Contains(t, warnings, "warning: type conflict for level1.level2.level3.spear.tip: table from user-supplied values cannot be merged with non-table value (true) from level1/charts/level2/charts/level3/values.yaml. Keeping the table.")
+	assert.Contains(t, warnings, "warning: type conflict for level1.level2.level3.spear.sail: non-table value (true) from user-supplied values cannot be merged with table from level1/charts/level2/charts/level3/values.yaml. Keeping the non-table value (true).")
 
 }
 
+func TestCoalesceValuesWithConflicts(t *testing.T) {
+	c := withDeps(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "parent"},
+		Values: map[string]interface{}{
+			"sub": map[string]interface{}{
+				"image": "nginx:1.27",
+			},
+		},
+	},
+		&chart.Chart{
+			Metadata: &chart.Metadata{Name: "sub"},
+			Values: map[string]interface{}{
+				"image": map[string]interface{}{
+					"repository": "nginx",
+					"tag":        "latest",
+				},
+				"ports": map[string]interface{}{
+					"http": 80,
+				},
+			},
+		},
+	)
+
+	vals := map[string]interface{}{
+		"sub": map[string]interface{}{
+			"ports": 8080,
+		},
+	}
+
+	coalesced, conflicts, err := CoalesceValuesWithConflicts(c, vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.ElementsMatch(t, []ValueConflict{
+		{
+			Path:          "parent.sub.image",
+			Value:         "nginx:1.27",
+			Source:        "parent/values.yaml",
+			Ignored:       map[string]interface{}{"repository": "nginx", "tag": "latest"},
+			IgnoredSource: "parent/charts/sub/values.yaml",
+		},
+		{
+			Path:          "parent.sub.ports",
+			Value:         8080,
+			Source:        UserValuesSource,
+			Ignored:       map[string]interface{}{"http": 80},
+			IgnoredSource: "parent/charts/sub/values.yaml",
+		},
+	}, conflicts)
+	assert.Equal(t, "nginx:1.27", coalesced["sub"].(map[string]interface{})["image"])
+}
+
+func TestCoalesceValuesSubchartConflict(t *testing.T) {
+	c := withDeps(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "parent"},
+		Values:   map[string]interface{}{},
+	},
+		&chart.Chart{
+			Metadata: &chart.Metadata{Name: "sub"},
+			Values:   map[string]interface{}{"name": "sub"},
+		},
+	)
+
+	_, err := CoalesceValues(c, map[string]interface{}{"sub": "enabled"})
+
+	var conflict ValueConflict
+	if !assert.ErrorAs(t, err, &conflict) {
+		return
+	}
+	assert.Equal(t, "parent.sub", conflict.Path)
+	assert.EqualError(t, err, "type conflict for parent.sub: non-table value (enabled) from user-supplied values cannot be merged with table from parent/charts/sub/values.yaml")
+}
+
 func TestConcatPrefix(t *testing.T) {
 	assert.Equal(t, "b", concatPrefix("", "b"))
 	assert.Equal(t, "a.b", concatPrefix("a", "b"))
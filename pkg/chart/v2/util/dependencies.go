@@ -32,6 +32,45 @@ func ProcessDependencies(c *chart.Chart, v Values) error {
 	return processDependencyImportValues(c, true)
 }
 
+// ProcessDependencyCapabilities removes the dependencies whose capabilities are
+// not all served by caps.
+//
+// It runs after ProcessDependencies, once the capabilities of the target
+// cluster are known. When rendering offline, caps are the default
+// capabilities plus any API versions supplied by the user.
+func ProcessDependencyCapabilities(c *chart.Chart, caps *Capabilities) {
+	if c.Metadata.Dependencies == nil {
+		return
+	}
+
+	rm := map[string]struct{}{}
+	for _, r := range c.Metadata.Dependencies {
+		for _, api := range r.Capabilities {
+			if !caps.APIVersions.Has(api) {
+				slog.Debug("disabling dependency, capability not served", "chart", r.Name, "capability", api)
+				rm[r.Name] = struct{}{}
+				break
+			}
+		}
+	}
+
+	var cd []*chart.Chart
+	for _, n := range c.Dependencies() {
+		if _, ok := rm[n.Metadata.Name]; !ok {
+			ProcessDependencyCapabilities(n, caps)
+			cd = append(cd, n)
+		}
+	}
+	var cdMetadata []*chart.Dependency
+	for _, n := range c.Metadata.Dependencies {
+		if _, ok := rm[n.Name]; !ok {
+			cdMetadata = append(cdMetadata, n)
+		}
+	}
+	c.Metadata.Dependencies = cdMetadata
+	c.SetDependencies(cd...)
+}
+
 // processDependencyConditions disables charts based on condition path value in values
 func processDependencyConditions(reqs []*chart.Dependency, cvals Values, cpath string) {
 	if reqs == nil {
@@ -134,6 +134,56 @@ func TestDependencyEnabled(t *testing.T) {
 	}
 }
 
+func TestProcessDependencyCapabilities(t *testing.T) {
+	const gateway = "gateway.networking.k8s.io/v1/Gateway"
+	tests := []struct {
+		name string
+		apis []string
+		e    []string
+	}{{
+		"capability not served",
+		nil,
+		[]string{"parentchart"},
+	}, {
+		"capability served",
+		[]string{gateway},
+		[]string{"parentchart", "parentchart.subchart1", "parentchart.subchart1.subcharta", "parentchart.subchart1.subchartb"},
+	}}
+
+	for _, tc := range tests {
+		c := loadChart(t, "testdata/subpop")
+		t.Run(tc.name, func(t *testing.T) {
+			for _, d := range c.Metadata.Dependencies {
+				if d.Name == "subchart1" {
+					d.Capabilities = []string{gateway}
+				}
+			}
+			if err := processDependencyEnabled(c, nil, ""); err != nil {
+				t.Fatalf("error processing enabled dependencies %v", err)
+			}
+
+			caps := DefaultCapabilities.Copy()
+			caps.APIVersions = append(caps.APIVersions, tc.apis...)
+			ProcessDependencyCapabilities(c, caps)
+
+			names := extractChartNames(c)
+			if len(names) != len(tc.e) {
+				t.Fatalf("slice lengths do not match got %v, expected %v", names, tc.e)
+			}
+			for i := range names {
+				if names[i] != tc.e[i] {
+					t.Fatalf("slice values do not match got %v, expected %v", names, tc.e)
+				}
+			}
+			for _, d := range c.Metadata.Dependencies {
+				if d.Name == "subchart1" && len(tc.apis) == 0 {
+					t.Errorf("expected subchart1 to be removed from the chart metadata")
+				}
+			}
+		})
+	}
+}
+
 // extractCharts recursively searches chart dependencies returning all charts found
 func extractChartNames(c *chart.Chart) []string {
 	var out []string
@@ -20,21 +20,73 @@ import (
 	"bytes"
 	"fmt"
 	"log/slog"
+	"net/url"
+	"path"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
 
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 )
 
 // ValidateAgainstSchema checks that values does not violate the structure laid out in schema
+//
+// The relative $refs of a chart schema are resolved against the files of the
+// chart. Any *.schema.json file bundled in the chart or in one of the charts
+// it belongs to with an absolute $id can also be referenced by that $id, which
+// lets subcharts share schema fragments without fetching them.
 func ValidateAgainstSchema(chrt *chart.Chart, values map[string]interface{}) error {
+	_, err := validateAgainstSchemas(chrt, values, func(*chart.Chart) bool { return false })
+	return err
+}
+
+// SchemaValidationAnnotation is the Chart.yaml annotation with which a chart
+// makes the violations of its values schema warnings, by setting it to
+// "warn". This lets chart authors introduce a schema without breaking the
+// existing users of the chart.
+const SchemaValidationAnnotation = "helm.sh/schema-validation"
+
+// SchemaViolation is a violation of a values schema reported as a warning.
+type SchemaViolation struct {
+	// Chart is the path of the chart whose schema is violated, as in
+	// "parent.subchart".
+	Chart string
+	// Path is the JSON pointer of the violating value within the values of
+	// the chart.
+	Path    string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	return fmt.Sprintf("%s: at '%s': %s", v.Chart, v.Path, v.Message)
+}
+
+// ValidateAgainstSchemaWithWarnings checks that values does not violate the
+// structure laid out in the schemas of the chart and its dependencies, like
+// ValidateAgainstSchema.
+//
+// The violations of the schemas of the charts annotated with
+// SchemaValidationAnnotation, or of all the schemas if warnOnly is set, are
+// returned instead of failing the validation.
+func ValidateAgainstSchemaWithWarnings(chrt *chart.Chart, values map[string]interface{}, warnOnly bool) ([]SchemaViolation, error) {
+	return validateAgainstSchemas(chrt, values, func(c *chart.Chart) bool {
+		return warnOnly || c.Metadata.Annotations[SchemaValidationAnnotation] == "warn"
+	})
+}
+
+func validateAgainstSchemas(chrt *chart.Chart, values map[string]interface{}, warn func(*chart.Chart) bool) ([]SchemaViolation, error) {
+	var violations []SchemaViolation
 	var sb strings.Builder
 	if chrt.Schema != nil {
 		slog.Debug("chart name", "chart-name", chrt.Name())
-		err := ValidateAgainstSingleSchema(values, chrt.Schema)
-		if err != nil {
+		err := validateAgainstSchema(values, chrt.Schema, chrt.Files, treeFiles(chrt.Root()))
+		var validationErr JSONSchemaValidationError
+		if errors.As(err, &validationErr) && warn(chrt) {
+			violations = append(violations, schemaViolations(chrt.ChartPath(), validationErr.embeddedErr)...)
+		} else if err != nil {
 			sb.WriteString(fmt.Sprintf("%s:\n", chrt.Name()))
 			sb.WriteString(err.Error())
 		}
@@ -43,53 +95,183 @@ func ValidateAgainstSchema(chrt *chart.Chart, values map[string]interface{}) err
 	// For each dependency, recursively call this function with the coalesced values
 	for _, subchart := range chrt.Dependencies() {
 		subchartValues := values[subchart.Name()].(map[string]interface{})
-		if err := ValidateAgainstSchema(subchart, subchartValues); err != nil {
+		subViolations, err := validateAgainstSchemas(subchart, subchartValues, warn)
+		violations = append(violations, subViolations...)
+		if err != nil {
 			sb.WriteString(err.Error())
 		}
 	}
 
 	if sb.Len() > 0 {
-		return errors.New(sb.String())
+		return violations, errors.New(sb.String())
 	}
 
-	return nil
+	return violations, nil
+}
+
+var (
+	violationPrinter = message.NewPrinter(language.English)
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+)
+
+// schemaViolations flattens a validation error into the violations at its
+// leaves.
+func schemaViolations(chartPath string, err error) []SchemaViolation {
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []SchemaViolation{{Chart: chartPath, Message: err.Error()}}
+	}
+	var violations []SchemaViolation
+	var walk func(*jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			var sb strings.Builder
+			for _, token := range e.InstanceLocation {
+				sb.WriteString("/" + pointerEscaper.Replace(token))
+			}
+			violations = append(violations, SchemaViolation{
+				Chart:   chartPath,
+				Path:    sb.String(),
+				Message: e.ErrorKind.LocalizedString(violationPrinter),
+			})
+		}
+		for _, cause := range e.Causes {
+			walk(cause)
+		}
+	}
+	walk(validationErr)
+	return violations
 }
 
 // ValidateAgainstSingleSchema checks that values does not violate the structure laid out in this schema
-func ValidateAgainstSingleSchema(values Values, schemaJSON []byte) (reterr error) {
+func ValidateAgainstSingleSchema(values Values, schemaJSON []byte) error {
+	return validateAgainstSchema(values, schemaJSON, nil, nil)
+}
+
+// ValidateAgainstSingleSchemaWithFiles checks that values does not violate
+// the structure laid out in this schema, resolving its $refs against files
+// the same way ValidateAgainstSchema does for the files of a chart.
+func ValidateAgainstSingleSchemaWithFiles(values Values, schemaJSON []byte, files []*chart.File) error {
+	return validateAgainstSchema(values, schemaJSON, files, files)
+}
+
+// schemaURL is the location the schema being validated against is compiled
+// from. Relative $refs are resolved against it.
+const schemaURL = "file:///values.schema.json"
+
+// validateAgainstSchema validates values against schemaJSON. Relative $refs
+// are loaded from files, while bundled lists the schemas registered by $id.
+func validateAgainstSchema(values Values, schemaJSON []byte, files, bundled []*chart.File) (reterr error) {
 	defer func() {
 		if r := recover(); r != nil {
 			reterr = fmt.Errorf("unable to validate schema: %s", r)
 		}
 	}()
 
+	validator, err := compileSchema(schemaJSON, files, bundled)
+	if err != nil {
+		return err
+	}
+
+	err = validator.Validate(values.AsMap())
+	if err != nil {
+		return JSONSchemaValidationError{err}
+	}
+
+	return nil
+}
+
+// compileSchema compiles schemaJSON. Relative $refs are loaded from files,
+// while bundled lists the schemas registered by $id.
+func compileSchema(schemaJSON []byte, files, bundled []*chart.File) (*jsonschema.Schema, error) {
 	// This unmarshal function leverages UseNumber() for number precision. The parser
 	// used for values does this as well.
 	schema, err := jsonschema.UnmarshalJSON(bytes.NewReader(schemaJSON))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	slog.Debug("unmarshalled JSON schema", "schema", schemaJSON)
 
 	compiler := jsonschema.NewCompiler()
-	err = compiler.AddResource("file:///values.schema.json", schema)
-	if err != nil {
-		return err
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.UseLoader(chartSchemaLoader(files))
+	if err := compiler.AddResource(schemaURL, schema); err != nil {
+		return nil, err
 	}
-
-	validator, err := compiler.Compile("file:///values.schema.json")
-	if err != nil {
-		return err
+	if err := addBundledSchemas(compiler, bundled); err != nil {
+		return nil, err
 	}
+	return compiler.Compile(schemaURL)
+}
 
-	err = validator.Validate(values.AsMap())
-	if err != nil {
-		return JSONSchemaValidationError{err}
+// chartSchemaLoader loads the schemas referenced by relative $refs from the
+// files of a chart. Validation never reads from the local filesystem or the
+// network.
+type chartSchemaLoader []*chart.File
+
+func (l chartSchemaLoader) Load(loc string) (any, error) {
+	name, ok := strings.CutPrefix(loc, "file:///")
+	if !ok {
+		return nil, errors.Errorf("schema %s is not bundled in the chart", loc)
 	}
+	for _, f := range l {
+		if path.Clean(f.Name) == name {
+			data, err := f.GetData()
+			if err != nil {
+				return nil, err
+			}
+			return jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		}
+	}
+	return nil, errors.Errorf("schema file %s not found in the chart", name)
+}
 
+// addBundledSchemas registers the *.schema.json files that declare an
+// absolute $id under that $id. When several files declare the same $id, the
+// first one wins.
+func addBundledSchemas(compiler *jsonschema.Compiler, files []*chart.File) error {
+	seen := map[string]bool{}
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name, ".schema.json") {
+			continue
+		}
+		data, err := f.GetData()
+		if err != nil {
+			return err
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			slog.Debug("skipping bundled schema", "file", f.Name, slog.Any("error", err))
+			continue
+		}
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			continue
+		}
+		id, ok := obj["$id"].(string)
+		if !ok || seen[id] {
+			continue
+		}
+		if u, err := url.Parse(id); err != nil || !u.IsAbs() || u.Scheme == "file" {
+			continue
+		}
+		seen[id] = true
+		if err := compiler.AddResource(id, doc); err != nil {
+			return errors.Wrapf(err, "unable to add bundled schema %s", f.Name)
+		}
+	}
 	return nil
 }
 
+// treeFiles returns the files of a chart and all its dependencies.
+func treeFiles(chrt *chart.Chart) []*chart.File {
+	files := append([]*chart.File{}, chrt.Files...)
+	for _, dep := range chrt.Dependencies() {
+		files = append(files, treeFiles(dep)...)
+	}
+	return files
+}
+
 // Note, JSONSchemaValidationError is used to wrap the error from the underlying
 // validation package so that Helm has a clean interface and the validation package
 // could be replaced without changing the Helm SDK API.
@@ -18,6 +18,8 @@ package util
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 
 	chart "helm.sh/helm/v4/pkg/chart/v2"
@@ -245,3 +247,178 @@ func TestValidateAgainstSchema2020Negative(t *testing.T) {
 		t.Errorf("Error string :\n`%s`\ndoes not match expected\n`%s`", errString, expectedErrString)
 	}
 }
+
+const refSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "image": {
+      "$ref": "schemas/image.schema.json"
+    },
+    "service": {
+      "$ref": "#/$defs/service"
+    }
+  },
+  "$defs": {
+    "service": {
+      "type": "object",
+      "properties": {
+        "port": {
+          "$ref": "https://charts.example.com/schemas/port.schema.json"
+        }
+      }
+    }
+  }
+}
+`
+
+const imageSchema = `{
+  "type": "object",
+  "properties": {
+    "repository": {
+      "type": "string"
+    }
+  },
+  "required": [
+    "repository"
+  ]
+}
+`
+
+const portSchema = `{
+  "$id": "https://charts.example.com/schemas/port.schema.json",
+  "type": "integer",
+  "minimum": 1,
+  "maximum": 65535
+}
+`
+
+func newRefSchemaChart() *chart.Chart {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name: "subchart",
+		},
+		Schema: []byte(refSchema),
+		Files: []*chart.File{
+			{Name: "schemas/image.schema.json", Data: []byte(imageSchema)},
+		},
+	}
+	chrt := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name: "chrt",
+		},
+		Files: []*chart.File{
+			{Name: "schemas/port.schema.json", Data: []byte(portSchema)},
+		},
+	}
+	chrt.AddDependency(subchart)
+	return chrt
+}
+
+func TestValidateAgainstSchemaRefs(t *testing.T) {
+	vals := map[string]interface{}{
+		"subchart": map[string]interface{}{
+			"image":   map[string]interface{}{"repository": "nginx"},
+			"service": map[string]interface{}{"port": 8080},
+		},
+	}
+
+	if err := ValidateAgainstSchema(newRefSchemaChart(), vals); err != nil {
+		t.Errorf("Error validating Values against Schema: %s", err)
+	}
+}
+
+func TestValidateAgainstSchemaRefsNegative(t *testing.T) {
+	vals := map[string]interface{}{
+		"subchart": map[string]interface{}{
+			"image":   map[string]interface{}{},
+			"service": map[string]interface{}{"port": 80800},
+		},
+	}
+
+	var errString string
+	if err := ValidateAgainstSchema(newRefSchemaChart(), vals); err == nil {
+		t.Fatalf("Expected an error, but got nil")
+	} else {
+		errString = err.Error()
+	}
+
+	// The order of the errors follows the properties of the schema, which
+	// is not stable.
+	for _, expected := range []string{
+		"subchart:\n",
+		"- at '/image': missing property 'repository'\n",
+		"- at '/service/port': maximum: got 80,800, want 65,535\n",
+	} {
+		if !strings.Contains(errString, expected) {
+			t.Errorf("Error string :\n`%s`\ndoes not contain\n`%s`", errString, expected)
+		}
+	}
+}
+
+func TestValidateAgainstSingleSchemaUnbundledRef(t *testing.T) {
+	schema := []byte(`{"properties": {"image": {"$ref": "schemas/image.schema.json"}}}`)
+	vals := map[string]interface{}{"image": map[string]interface{}{}}
+
+	err := ValidateAgainstSingleSchema(vals, schema)
+	if err == nil {
+		t.Fatalf("Expected an error, but got nil")
+	}
+	if !strings.Contains(err.Error(), "schema file schemas/image.schema.json not found in the chart") {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	files := []*chart.File{{Name: "schemas/image.schema.json", Data: []byte(imageSchema)}}
+	if err := ValidateAgainstSingleSchemaWithFiles(vals, schema, files); err == nil {
+		t.Errorf("Expected a validation error, but got nil")
+	}
+}
+
+func TestValidateAgainstSchemaWithWarnings(t *testing.T) {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:        "subchart",
+			Annotations: map[string]string{SchemaValidationAnnotation: "warn"},
+		},
+		Schema: []byte(subchartSchema),
+	}
+	chrt := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name: "chrt",
+		},
+	}
+	chrt.AddDependency(subchart)
+
+	vals := map[string]interface{}{
+		"name":     "John",
+		"subchart": map[string]interface{}{},
+	}
+
+	violations, err := ValidateAgainstSchemaWithWarnings(chrt, vals, false)
+	if err != nil {
+		t.Fatalf("Expected the violations of the annotated chart to be warnings, got: %s", err)
+	}
+	expected := []SchemaViolation{{Chart: "chrt.subchart", Message: "missing property 'age'"}}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("Expected violations %v, got %v", expected, violations)
+	}
+
+	// Without the annotation, the violations only are warnings when asked to.
+	delete(subchart.Metadata.Annotations, SchemaValidationAnnotation)
+	if _, err := ValidateAgainstSchemaWithWarnings(chrt, vals, false); err == nil {
+		t.Errorf("Expected an error, but got nil")
+	}
+	violations, err = ValidateAgainstSchemaWithWarnings(chrt, vals, true)
+	if err != nil {
+		t.Fatalf("Expected the violations to be warnings, got: %s", err)
+	}
+	if !reflect.DeepEqual(violations, expected) {
+		t.Errorf("Expected violations %v, got %v", expected, violations)
+	}
+
+	// ValidateAgainstSchema always fails.
+	subchart.Metadata.Annotations[SchemaValidationAnnotation] = "warn"
+	if err := ValidateAgainstSchema(chrt, vals); err == nil {
+		t.Errorf("Expected an error, but got nil")
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import "fmt"
+
+const (
+	// ListPatchKey is the key of the directive that selects how a list of
+	// values is merged with the list it overrides. The directive is a table
+	// given as the first item of the list:
+	//
+	//	env:
+	//	- $patch: append
+	//	- name: EXTRA
+	//	  value: "1"
+	//
+	// A directive in user-supplied values, or in the values of a parent
+	// chart, applies to the list of the chart defaults it overrides. A
+	// directive in the chart defaults sets the strategy used when the values
+	// that override the list have none. Directives are removed from the
+	// coalesced values.
+	ListPatchKey = "$patch"
+	// ListMergeKey is the key of a merge directive naming the field that
+	// identifies the items of the lists.
+	//
+	//	- $patch: merge
+	//	  $mergeKey: name
+	ListMergeKey = "$mergeKey"
+)
+
+// ListPatch is a strategy for merging a list of values with the list it
+// overrides.
+type ListPatch string
+
+const (
+	// ListPatchReplace replaces the overridden list. This is the default.
+	ListPatchReplace ListPatch = "replace"
+	// ListPatchAppend adds the items after those of the overridden list.
+	ListPatchAppend ListPatch = "append"
+	// ListPatchPrepend adds the items before those of the overridden list.
+	ListPatchPrepend ListPatch = "prepend"
+	// ListPatchMerge coalesces the tables with the same value of the
+	// ListMergeKey field, and appends the other items. Without a merge key,
+	// the items at the same index are coalesced.
+	ListPatchMerge ListPatch = "merge"
+)
+
+type listDirective struct {
+	patch    ListPatch
+	mergeKey string
+}
+
+// splitListDirective returns the directive of a list, if it has one, and
+// the items of the list.
+func splitListDirective(l []interface{}) (*listDirective, []interface{}) {
+	if len(l) == 0 {
+		return nil, l
+	}
+	m, ok := l[0].(map[string]interface{})
+	if !ok {
+		return nil, l
+	}
+	p, ok := m[ListPatchKey]
+	if !ok {
+		return nil, l
+	}
+	d := &listDirective{patch: ListPatch(fmt.Sprint(p))}
+	if k, ok := m[ListMergeKey].(string); ok {
+		d.mergeKey = k
+	}
+	return d, l[1:]
+}
+
+// patchLists merges dst, which has priority, with the list src it overrides.
+//
+// The merged list takes the place of src, along with its directive, so that
+// it is in turn merged with the defaults of the charts below. The directives
+// are removed by StripListDirectives once all the charts are coalesced.
+func patchLists(r *reporter, dst, src []interface{}, path string, merge bool, srcSource string) []interface{} {
+	dd, ditems := splitListDirective(dst)
+	sd, sitems := splitListDirective(src)
+	d := dd
+	if d == nil {
+		d = sd
+	}
+	if d == nil {
+		return dst
+	}
+
+	var items []interface{}
+	switch d.patch {
+	case ListPatchReplace:
+		items = ditems
+	case ListPatchAppend:
+		items = append(append(items, sitems...), ditems...)
+	case ListPatchPrepend:
+		items = append(append(items, ditems...), sitems...)
+	case ListPatchMerge:
+		items = mergeListItems(r, ditems, sitems, d.mergeKey, path, merge, srcSource)
+	default:
+		r.printf("warning: unknown %s directive %q for %s. Replacing the list.", ListPatchKey, d.patch, path)
+		items = ditems
+	}
+	if sd == nil {
+		return items
+	}
+	return append([]interface{}{src[0]}, items...)
+}
+
+func mergeListItems(r *reporter, dst, src []interface{}, key, path string, merge bool, srcSource string) []interface{} {
+	items := append([]interface{}(nil), src...)
+	if key == "" {
+		for i, v := range dst {
+			if i < len(items) {
+				items[i] = mergeListItem(r, v, items[i], fmt.Sprintf("%s[%d]", path, i), merge, srcSource)
+			} else {
+				items = append(items, v)
+			}
+		}
+		return items
+	}
+
+	index := make(map[string]int)
+	for i, v := range items {
+		if id, ok := listItemKey(v, key); ok {
+			index[id] = i
+		}
+	}
+	for _, v := range dst {
+		id, ok := listItemKey(v, key)
+		if i, found := index[id]; ok && found {
+			items[i] = mergeListItem(r, v, items[i], fmt.Sprintf("%s[%s=%s]", path, key, id), merge, srcSource)
+		} else {
+			items = append(items, v)
+		}
+	}
+	return items
+}
+
+func listItemKey(v interface{}, key string) (string, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	id, ok := m[key]
+	if !ok || id == nil || istable(id) {
+		return "", false
+	}
+	return fmt.Sprint(id), true
+}
+
+// mergeListItem coalesces two tables of merged lists. Any other item is
+// replaced.
+func mergeListItem(r *reporter, dst, src interface{}, path string, merge bool, srcSource string) interface{} {
+	dm, ok := dst.(map[string]interface{})
+	if !ok {
+		return dst
+	}
+	sm, ok := src.(map[string]interface{})
+	if !ok {
+		return dst
+	}
+	return coalesceTablesFullKey(r, copyMap(dm), sm, path, merge, srcSource)
+}
+
+// StripListDirectives removes the list directives from values, in place, as
+// they are removed from coalesced values. It returns the stripped value.
+func StripListDirectives(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = StripListDirectives(val)
+		}
+	case []interface{}:
+		_, items := splitListDirective(v)
+		for i, val := range items {
+			items[i] = StripListDirectives(val)
+		}
+		return items
+	}
+	return v
+}
+
+// ResolveListPatches replaces the lists of vals that have a directive with the
+// lists at the same path in coalesced, the values vals were coalesced into.
+// The directives of the returned values are not applied again when they are
+// coalesced with the values of coalesced.
+func ResolveListPatches(vals, coalesced map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(vals))
+	for k, v := range vals {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if cv, ok := coalesced[k].(map[string]interface{}); ok {
+				out[k] = ResolveListPatches(v, cv)
+				continue
+			}
+		case []interface{}:
+			if d, _ := splitListDirective(v); d != nil {
+				if cv, ok := coalesced[k].([]interface{}); ok {
+					out[k] = cv
+					continue
+				}
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
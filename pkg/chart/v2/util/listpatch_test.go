@@ -0,0 +1,138 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+func TestCoalesceValuesListPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults string
+		user     string
+		expect   string
+	}{{
+		name:     "lists are replaced by default",
+		defaults: "list: [a, b]",
+		user:     "list: [c]",
+		expect:   "list: [c]",
+	}, {
+		name:     "append",
+		defaults: "list: [a, b]",
+		user:     "list: [{$patch: append}, c]",
+		expect:   "list: [a, b, c]",
+	}, {
+		name:     "prepend",
+		defaults: "list: [a, b]",
+		user:     "list: [{$patch: prepend}, c]",
+		expect:   "list: [c, a, b]",
+	}, {
+		name:     "merge by key",
+		defaults: "env: [{name: A, value: '1'}, {name: B, value: '2', secret: false}]",
+		user:     "env: [{$patch: merge, $mergeKey: name}, {name: B, value: '3'}, {name: C, value: '4'}]",
+		expect:   "env: [{name: A, value: '1'}, {name: B, value: '3', secret: false}, {name: C, value: '4'}]",
+	}, {
+		name:     "merge by index",
+		defaults: "ports: [{port: 80, name: http}, {port: 443}]",
+		user:     "ports: [{$patch: merge}, {port: 8080}]",
+		expect:   "ports: [{port: 8080, name: http}, {port: 443}]",
+	}, {
+		name:     "chart default strategy",
+		defaults: "list: [{$patch: append}, a]",
+		user:     "list: [b]",
+		expect:   "list: [a, b]",
+	}, {
+		name:     "user directive overrides chart default strategy",
+		defaults: "list: [{$patch: append}, a]",
+		user:     "list: [{$patch: replace}, b]",
+		expect:   "list: [b]",
+	}, {
+		name:     "directive without override is removed",
+		defaults: "nested: {list: [{$patch: append}, a]}",
+		user:     "other: true",
+		expect:   "nested: {list: [a]}\nother: true",
+	}, {
+		name:     "unknown directive replaces",
+		defaults: "list: [a]",
+		user:     "list: [{$patch: shuffle}, b]",
+		expect:   "list: [b]",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defaults, err := ReadValues([]byte(tt.defaults))
+			require.NoError(t, err)
+			user, err := ReadValues([]byte(tt.user))
+			require.NoError(t, err)
+			expect, err := ReadValues([]byte(tt.expect))
+			require.NoError(t, err)
+
+			c := &chart.Chart{Metadata: &chart.Metadata{Name: "test"}, Values: defaults}
+			coalesced, err := CoalesceValues(c, user)
+			require.NoError(t, err)
+			assert.Equal(t, expect.AsMap(), coalesced.AsMap())
+		})
+	}
+}
+
+func TestCoalesceValuesListPatchSubchart(t *testing.T) {
+	newChart := func() *chart.Chart {
+		return withDeps(&chart.Chart{
+			Metadata: &chart.Metadata{Name: "parent", Dependencies: []*chart.Dependency{{Name: "sub"}}},
+			Values: map[string]interface{}{
+				"sub": map[string]interface{}{
+					"list": []interface{}{map[string]interface{}{ListPatchKey: "append"}, "parent"},
+				},
+			},
+		},
+			&chart.Chart{
+				Metadata: &chart.Metadata{Name: "sub"},
+				Values: map[string]interface{}{
+					"list": []interface{}{map[string]interface{}{ListPatchKey: "append"}, "sub"},
+				},
+			},
+		)
+	}
+	user := map[string]interface{}{
+		"sub": map[string]interface{}{"list": []interface{}{"user"}},
+	}
+
+	coalesced, err := CoalesceValues(newChart(), user)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"sub", "parent", "user"}, coalesced["sub"].(map[string]interface{})["list"])
+
+	// The directives are applied once when the values of the dependencies
+	// are merged ahead of time.
+	c := newChart()
+	require.NoError(t, ProcessDependencies(c, user))
+	coalesced, err = CoalesceValues(c, user)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"sub", "parent", "user"}, coalesced["sub"].(map[string]interface{})["list"])
+}
+
+func TestCoalesceTablesKeepsListDirectives(t *testing.T) {
+	dst := map[string]interface{}{"list": []interface{}{map[string]interface{}{ListPatchKey: "append"}, "b"}}
+	src := map[string]interface{}{"list": []interface{}{"a"}}
+	CoalesceTables(dst, src)
+	assert.Equal(t, []interface{}{map[string]interface{}{ListPatchKey: "append"}, "b"}, dst["list"])
+}
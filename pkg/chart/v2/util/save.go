@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
@@ -77,8 +78,12 @@ func SaveDir(c *chart.Chart, dest string) error {
 	// Save templates and files
 	for _, o := range [][]*chart.File{c.Templates, c.Files} {
 		for _, f := range o {
+			data, err := f.GetData()
+			if err != nil {
+				return err
+			}
 			n := filepath.Join(outdir, f.Name)
-			if err := writeFile(n, f.Data); err != nil {
+			if err := writeFile(n, data); err != nil {
 				return err
 			}
 		}
@@ -95,6 +100,22 @@ func SaveDir(c *chart.Chart, dest string) error {
 	return nil
 }
 
+// SaveOption configures how a chart archive is written by Save.
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	modTime time.Time
+}
+
+// WithModTime sets the modification time recorded for every file in the
+// archive. Archives saved with the same modification time from identical
+// charts are byte-for-byte identical.
+func WithModTime(t time.Time) SaveOption {
+	return func(o *saveOptions) {
+		o.modTime = t
+	}
+}
+
 // Save creates an archived chart to the given directory.
 //
 // This takes an existing chart and a destination directory.
@@ -102,8 +123,19 @@ func SaveDir(c *chart.Chart, dest string) error {
 // If the directory is /foo, and the chart is named bar, with version 1.0.0, this
 // will generate /foo/bar-1.0.0.tgz.
 //
+// Files are always written in a stable order with normalized permissions. Unless
+// WithModTime is given, they are stamped with the current time.
+//
 // This returns the absolute path to the chart archive file.
-func Save(c *chart.Chart, outDir string) (string, error) {
+func Save(c *chart.Chart, outDir string, opts ...SaveOption) (string, error) {
+	o := saveOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.modTime.IsZero() {
+		o.modTime = time.Now()
+	}
+
 	if err := c.Validate(); err != nil {
 		return "", errors.Wrap(err, "chart validation")
 	}
@@ -145,14 +177,21 @@ func Save(c *chart.Chart, outDir string) (string, error) {
 		}
 	}()
 
-	if err := writeTarContents(twriter, c, ""); err != nil {
+	w := &archiveWriter{out: twriter, modTime: o.modTime}
+	if err := w.writeTarContents(c, ""); err != nil {
 		rollback = true
 		return filename, err
 	}
 	return filename, nil
 }
 
-func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
+// archiveWriter writes the contents of charts into a tar archive.
+type archiveWriter struct {
+	out     *tar.Writer
+	modTime time.Time
+}
+
+func (w *archiveWriter) writeTarContents(c *chart.Chart, prefix string) error {
 	err := validateName(c.Name())
 	if err != nil {
 		return err
@@ -173,7 +212,7 @@ func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
 	if err != nil {
 		return err
 	}
-	if err := writeToTar(out, filepath.Join(base, ChartfileName), cdata); err != nil {
+	if err := w.writeToTar(filepath.Join(base, ChartfileName), cdata); err != nil {
 		return err
 	}
 
@@ -185,7 +224,7 @@ func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
 			if err != nil {
 				return err
 			}
-			if err := writeToTar(out, filepath.Join(base, "Chart.lock"), ldata); err != nil {
+			if err := w.writeToTar(filepath.Join(base, "Chart.lock"), ldata); err != nil {
 				return err
 			}
 		}
@@ -194,7 +233,7 @@ func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
 	// Save values.yaml
 	for _, f := range c.Raw {
 		if f.Name == ValuesfileName {
-			if err := writeToTar(out, filepath.Join(base, ValuesfileName), f.Data); err != nil {
+			if err := w.writeToTar(filepath.Join(base, ValuesfileName), f.Data); err != nil {
 				return err
 			}
 		}
@@ -205,49 +244,64 @@ func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
 		if !json.Valid(c.Schema) {
 			return errors.New("Invalid JSON in " + SchemafileName)
 		}
-		if err := writeToTar(out, filepath.Join(base, SchemafileName), c.Schema); err != nil {
+		if err := w.writeToTar(filepath.Join(base, SchemafileName), c.Schema); err != nil {
 			return err
 		}
 	}
 
 	// Save templates
-	for _, f := range c.Templates {
+	for _, f := range sortedFiles(c.Templates) {
 		n := filepath.Join(base, f.Name)
-		if err := writeToTar(out, n, f.Data); err != nil {
+		if err := w.writeToTar(n, f.Data); err != nil {
 			return err
 		}
 	}
 
 	// Save files
-	for _, f := range c.Files {
+	for _, f := range sortedFiles(c.Files) {
+		data, err := f.GetData()
+		if err != nil {
+			return err
+		}
 		n := filepath.Join(base, f.Name)
-		if err := writeToTar(out, n, f.Data); err != nil {
+		if err := w.writeToTar(n, data); err != nil {
 			return err
 		}
 	}
 
 	// Save dependencies
-	for _, dep := range c.Dependencies() {
-		if err := writeTarContents(out, dep, filepath.Join(base, ChartsDir)); err != nil {
+	deps := append([]*chart.Chart(nil), c.Dependencies()...)
+	sort.SliceStable(deps, func(i, j int) bool { return deps[i].Name() < deps[j].Name() })
+	for _, dep := range deps {
+		if err := w.writeTarContents(dep, filepath.Join(base, ChartsDir)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// sortedFiles returns a copy of files ordered by name.
+func sortedFiles(files []*chart.File) []*chart.File {
+	sorted := append([]*chart.File(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
 // writeToTar writes a single file to a tar archive.
-func writeToTar(out *tar.Writer, name string, body []byte) error {
+func (w *archiveWriter) writeToTar(name string, body []byte) error {
 	// TODO: Do we need to create dummy parent directory names if none exist?
 	h := &tar.Header{
-		Name:    filepath.ToSlash(name),
-		Mode:    0644,
-		Size:    int64(len(body)),
-		ModTime: time.Now(),
+		Typeflag: tar.TypeReg,
+		Name:     filepath.ToSlash(name),
+		Mode:     0644,
+		Size:     int64(len(body)),
+		ModTime:  w.modTime.Truncate(time.Second),
+		Format:   tar.FormatPAX,
 	}
-	if err := out.WriteHeader(h); err != nil {
+	if err := w.out.WriteHeader(h); err != nil {
 		return err
 	}
-	_, err := out.Write(body)
+	_, err := w.out.Write(body)
 	return err
 }
 
@@ -179,6 +179,65 @@ func TestSavePreservesTimestamps(t *testing.T) {
 	}
 }
 
+func TestSaveReproducible(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	newChart := func(files ...string) *chart.Chart {
+		c := &chart.Chart{
+			Metadata: &chart.Metadata{
+				APIVersion: chart.APIVersionV2,
+				Name:       "ahab",
+				Version:    "1.2.3",
+			},
+		}
+		for _, name := range files {
+			c.Files = append(c.Files, &chart.File{Name: name, Data: []byte(name)})
+		}
+		return c
+	}
+
+	save := func(c *chart.Chart) []byte {
+		t.Helper()
+		where, err := Save(c, t.TempDir(), WithModTime(modTime))
+		if err != nil {
+			t.Fatalf("Failed to save: %s", err)
+		}
+		data, err := os.ReadFile(where)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	first := save(newChart("a.txt", "b.txt", "c.txt"))
+	second := save(newChart("c.txt", "a.txt", "b.txt"))
+	if !bytes.Equal(first, second) {
+		t.Fatal("expected saving identical charts to produce identical archives")
+	}
+
+	where, err := Save(newChart("b.txt", "a.txt"), t.TempDir(), WithModTime(modTime))
+	if err != nil {
+		t.Fatalf("Failed to save: %s", err)
+	}
+	headers, err := retrieveAllHeadersFromTar(where)
+	if err != nil {
+		t.Fatalf("Failed to parse tar: %v", err)
+	}
+	var names []string
+	for _, h := range headers {
+		names = append(names, h.Name)
+		if !h.ModTime.Equal(modTime) {
+			t.Errorf("expected %s to have modification time %s, got %s", h.Name, modTime, h.ModTime)
+		}
+		if h.Mode != 0644 {
+			t.Errorf("expected %s to have mode 0644, got %o", h.Name, h.Mode)
+		}
+	}
+	expect := []string{"ahab/Chart.yaml", "ahab/a.txt", "ahab/b.txt"}
+	if strings.Join(names, ",") != strings.Join(expect, ",") {
+		t.Errorf("expected archive entries %v, got %v", expect, names)
+	}
+}
+
 // We could refactor `load.go` to use this `retrieveAllHeadersFromTar` function
 // as well, so we are not duplicating components of the code which iterate
 // through the tar.
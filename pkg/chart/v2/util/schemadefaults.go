@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// SchemaDefaultsAnnotation is the Chart.yaml annotation with which a chart
+// has the defaults declared in its values schema applied to its values, by
+// setting it to "true". This lets the schema be the single source of the
+// chart's defaults instead of repeating them in values.yaml.
+const SchemaDefaultsAnnotation = "helm.sh/schema-defaults"
+
+// ApplySchemaDefaults sets the properties that are absent from values to the
+// defaults declared for them in the schemas of the chart and its
+// dependencies. The defaults of the properties of an absent object are
+// applied to a new object, which is only added if any of them has a default.
+// Values that are present, even if they do not match the schema, are kept.
+//
+// values are the coalesced values of the chart, and are modified in place.
+func ApplySchemaDefaults(chrt *chart.Chart, values map[string]interface{}) error {
+	return applySchemaDefaults(chrt, values, func(*chart.Chart) bool { return true })
+}
+
+func applySchemaDefaults(chrt *chart.Chart, values map[string]interface{}, apply func(*chart.Chart) bool) error {
+	if chrt.Schema != nil && apply(chrt) {
+		schema, err := compileSchema(chrt.Schema, chrt.Files, treeFiles(chrt.Root()))
+		if err != nil {
+			return errors.Wrapf(err, "%s: unable to apply schema defaults", chrt.Name())
+		}
+		applyDefaults(schema, values, map[*jsonschema.Schema]bool{})
+	}
+	for _, subchart := range chrt.Dependencies() {
+		subchartValues, ok := values[subchart.Name()].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if err := applySchemaDefaults(subchart, subchartValues, apply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDefaults applies the defaults of the properties of s, and of the
+// schemas it references or is made of with allOf, to values. Schemas
+// already being applied are skipped, so that recursive schemas terminate.
+func applyDefaults(s *jsonschema.Schema, values map[string]interface{}, active map[*jsonschema.Schema]bool) {
+	if s == nil || active[s] {
+		return
+	}
+	active[s] = true
+	defer delete(active, s)
+
+	for name, prop := range s.Properties {
+		val, ok := values[name]
+		if !ok {
+			if def := schemaDefault(prop); def != nil {
+				val, ok = copyDefault(*def), true
+				values[name] = val
+			}
+		}
+		if m, isMap := val.(map[string]interface{}); isMap {
+			applyDefaults(prop, m, active)
+		} else if !ok {
+			m := map[string]interface{}{}
+			applyDefaults(prop, m, active)
+			if len(m) > 0 {
+				values[name] = m
+			}
+		}
+	}
+	applyDefaults(s.Ref, values, active)
+	for _, sub := range s.AllOf {
+		applyDefaults(sub, values, active)
+	}
+}
+
+// schemaDefault returns the default of s, or of the schema it references.
+func schemaDefault(s *jsonschema.Schema) *any {
+	for ; s != nil; s = s.Ref {
+		if s.Default != nil {
+			return s.Default
+		}
+	}
+	return nil
+}
+
+// copyDefault copies a default, so that the values it is applied to do not
+// share it with the schema or with each other.
+func copyDefault(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[k] = copyDefault(val)
+		}
+		return m
+	case []any:
+		l := make([]any, len(v))
+		for i, val := range v {
+			l[i] = copyDefault(val)
+		}
+		return l
+	}
+	return v
+}
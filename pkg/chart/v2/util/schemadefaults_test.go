@@ -0,0 +1,119 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+const defaultsSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["replicas"],
+  "properties": {
+    "replicas": {"type": "integer", "default": 1},
+    "image": {
+      "type": "object",
+      "properties": {
+        "repository": {"type": "string", "default": "nginx"},
+        "tag": {"type": "string"}
+      }
+    },
+    "resources": {"type": "object", "properties": {"limits": {"type": "object"}}},
+    "ports": {"type": "array", "default": [80, 443]},
+    "probe": {"$ref": "#/$defs/probe"},
+    "tree": {"$ref": "#/$defs/node"}
+  },
+  "$defs": {
+    "probe": {"type": "object", "properties": {"path": {"type": "string", "default": "/healthz"}}},
+    "node": {"type": "object", "properties": {"children": {"$ref": "#/$defs/node"}}}
+  }
+}`
+
+func TestApplySchemaDefaults(t *testing.T) {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "subchart"},
+		Schema:   []byte(`{"properties": {"enabled": {"type": "boolean", "default": true}}}`),
+	}
+	chrt := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "chrt"},
+		Schema:   []byte(defaultsSchema),
+	}
+	chrt.AddDependency(subchart)
+
+	vals := map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "1.27"},
+		"ports":    []interface{}{8080},
+		"subchart": map[string]interface{}{},
+	}
+	if err := ApplySchemaDefaults(chrt, vals); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"replicas": json.Number("1"),
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "1.27"},
+		"ports":    []interface{}{8080},
+		"probe":    map[string]interface{}{"path": "/healthz"},
+		"subchart": map[string]interface{}{"enabled": true},
+	}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Errorf("Expected %v, got %v", expected, vals)
+	}
+
+	// Defaults are copied into the values they are applied to.
+	vals = map[string]interface{}{}
+	if err := ApplySchemaDefaults(chrt, vals); err != nil {
+		t.Fatal(err)
+	}
+	vals["ports"].([]interface{})[0] = 8080
+	vals = map[string]interface{}{}
+	if err := ApplySchemaDefaults(chrt, vals); err != nil {
+		t.Fatal(err)
+	}
+	if ports := vals["ports"].([]interface{}); ports[0] != json.Number("80") {
+		t.Errorf("Expected the default to be unchanged, got %v", ports)
+	}
+}
+
+func TestToRenderValuesSchemaDefaults(t *testing.T) {
+	chrt := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "chrt"},
+		Schema:   []byte(defaultsSchema),
+	}
+
+	// Without the annotation, the required property is missing.
+	if _, err := ToRenderValues(chrt, map[string]interface{}{}, ReleaseOptions{}, nil); err == nil {
+		t.Errorf("Expected a validation error, but got nil")
+	}
+
+	chrt.Metadata.Annotations = map[string]string{SchemaDefaultsAnnotation: "true"}
+	res, err := ToRenderValues(chrt, map[string]interface{}{"replicas": 3}, ReleaseOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals := res["Values"].(Values)
+	if vals["replicas"] != 3 {
+		t.Errorf("Expected the given value to be kept, got %v", vals["replicas"])
+	}
+	if repo, err := vals.PathValue("image.repository"); err != nil || repo != "nginx" {
+		t.Errorf("Expected the default image repository, got %v (%v)", repo, err)
+	}
+}
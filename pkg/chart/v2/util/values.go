@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -133,6 +134,9 @@ type ReleaseOptions struct {
 	Revision  int
 	IsUpgrade bool
 	IsInstall bool
+	// HookOutputs are the outputs published by the hooks of the previous
+	// releases, by hook name.
+	HookOutputs map[string]map[string]string
 }
 
 // ToRenderValues composes the struct from the data coming from the Releases, Charts and Values files
@@ -145,7 +149,25 @@ func ToRenderValues(chrt *chart.Chart, chrtVals map[string]interface{}, options
 // ToRenderValuesWithSchemaValidation composes the struct from the data coming from the Releases, Charts and Values files
 //
 // This takes both ReleaseOptions and Capabilities to merge into the render values.
+//
+// The schema violations of the charts that only warn about them are logged.
 func ToRenderValuesWithSchemaValidation(chrt *chart.Chart, chrtVals map[string]interface{}, options ReleaseOptions, caps *Capabilities, skipSchemaValidation bool) (Values, error) {
+	top, violations, err := ToRenderValuesWithSchemaWarnings(chrt, chrtVals, options, caps, skipSchemaValidation, false)
+	for _, v := range violations {
+		slog.Warn("values don't meet the specifications of the schema", "chart", v.Chart, "path", v.Path, "message", v.Message)
+	}
+	return top, err
+}
+
+// ToRenderValuesWithSchemaWarnings composes the struct from the data coming
+// from the Releases, Charts and Values files, like
+// ToRenderValuesWithSchemaValidation.
+//
+// The schema violations of the charts annotated with
+// SchemaValidationAnnotation, or all of them if warnOnly is set, are returned
+// instead of failing. The schema defaults of the charts annotated with
+// SchemaDefaultsAnnotation are applied before the values are validated.
+func ToRenderValuesWithSchemaWarnings(chrt *chart.Chart, chrtVals map[string]interface{}, options ReleaseOptions, caps *Capabilities, skipSchemaValidation, warnOnly bool) (Values, []SchemaViolation, error) {
 	if caps == nil {
 		caps = DefaultCapabilities
 	}
@@ -153,29 +175,37 @@ func ToRenderValuesWithSchemaValidation(chrt *chart.Chart, chrtVals map[string]i
 		"Chart":        chrt.Metadata,
 		"Capabilities": caps,
 		"Release": map[string]interface{}{
-			"Name":      options.Name,
-			"Namespace": options.Namespace,
-			"IsUpgrade": options.IsUpgrade,
-			"IsInstall": options.IsInstall,
-			"Revision":  options.Revision,
-			"Service":   "Helm",
+			"Name":        options.Name,
+			"Namespace":   options.Namespace,
+			"IsUpgrade":   options.IsUpgrade,
+			"IsInstall":   options.IsInstall,
+			"Revision":    options.Revision,
+			"Service":     "Helm",
+			"HookOutputs": options.HookOutputs,
 		},
 	}
 
 	vals, err := CoalesceValues(chrt, chrtVals)
 	if err != nil {
-		return top, err
+		return top, nil, err
+	}
+	if err := applySchemaDefaults(chrt, vals, func(c *chart.Chart) bool {
+		return c.Metadata.Annotations[SchemaDefaultsAnnotation] == "true"
+	}); err != nil {
+		return top, nil, err
 	}
 
+	var violations []SchemaViolation
 	if !skipSchemaValidation {
-		if err := ValidateAgainstSchema(chrt, vals); err != nil {
+		violations, err = ValidateAgainstSchemaWithWarnings(chrt, vals, warnOnly)
+		if err != nil {
 			errFmt := "values don't meet the specifications of the schema(s) in the following chart(s):\n%s"
-			return top, fmt.Errorf(errFmt, err.Error())
+			return top, violations, fmt.Errorf(errFmt, err.Error())
 		}
 	}
 
 	top["Values"] = vals
-	return top, nil
+	return top, violations, nil
 }
 
 // istable is a special-purpose function to see if the present thing matches the definition of a YAML table.
@@ -101,6 +101,9 @@ func TestToRenderValues(t *testing.T) {
 		Namespace: "default",
 		Revision:  1,
 		IsInstall: true,
+		HookOutputs: map[string]map[string]string{
+			"allocate": {"id": "42"},
+		},
 	}
 
 	res, err := ToRenderValuesWithSchemaValidation(c, overrideValues, o, nil, false)
@@ -128,6 +131,9 @@ func TestToRenderValues(t *testing.T) {
 	if !relmap["IsInstall"].(bool) {
 		t.Errorf("Expected install to be true.")
 	}
+	if id := relmap["HookOutputs"].(map[string]map[string]string)["allocate"]["id"]; id != "42" {
+		t.Errorf("Expected hook output '42', got %q", id)
+	}
 	if !res["Capabilities"].(*Capabilities).APIVersions.Has("v1") {
 		t.Error("Expected Capabilities to have v1 as an API")
 	}
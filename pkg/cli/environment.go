@@ -29,6 +29,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -85,6 +86,8 @@ type EnvSettings struct {
 	PluginsDirectory string
 	// MaxHistory is the max release history maintained.
 	MaxHistory int
+	// MaxHistoryAge is the max age of the release history maintained.
+	MaxHistoryAge time.Duration
 	// BurstLimit is the default client-side throttling limit.
 	BurstLimit int
 	// QPS is queries per second which may be used to avoid throttling.
@@ -95,6 +98,7 @@ func New() *EnvSettings {
 	env := &EnvSettings{
 		namespace:                 os.Getenv("HELM_NAMESPACE"),
 		MaxHistory:                envIntOr("HELM_MAX_HISTORY", defaultMaxHistory),
+		MaxHistoryAge:             envDurationOr("HELM_MAX_HISTORY_AGE", 0),
 		KubeContext:               os.Getenv("HELM_KUBECONTEXT"),
 		KubeToken:                 os.Getenv("HELM_KUBETOKEN"),
 		KubeAsUser:                os.Getenv("HELM_KUBEASUSER"),
@@ -205,6 +209,18 @@ func envFloat32Or(name string, def float32) float32 {
 	return float32(ret)
 }
 
+func envDurationOr(name string, def time.Duration) time.Duration {
+	if name == "" {
+		return def
+	}
+	envVal := envOr(name, def.String())
+	ret, err := time.ParseDuration(envVal)
+	if err != nil {
+		return def
+	}
+	return ret
+}
+
 func envCSV(name string) (ls []string) {
 	trimmed := strings.Trim(os.Getenv(name), ", ")
 	if trimmed != "" {
@@ -226,6 +242,7 @@ func (s *EnvSettings) EnvVars() map[string]string {
 		"HELM_REPOSITORY_CONFIG": s.RepositoryConfig,
 		"HELM_NAMESPACE":         s.Namespace(),
 		"HELM_MAX_HISTORY":       strconv.Itoa(s.MaxHistory),
+		"HELM_MAX_HISTORY_AGE":   s.MaxHistoryAge.String(),
 		"HELM_BURST_LIMIT":       strconv.Itoa(s.BurstLimit),
 		"HELM_QPS":               strconv.FormatFloat(float64(s.QPS), 'f', 2, 32),
 
@@ -0,0 +1,111 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// envReference matches ${NAME} and ${NAME:-default}, and their escaped form
+// starting with "$$".
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// substituteEnv replaces references to the allowed environment variables in
+// the string values of v, in place. Each allowed name may be a pattern such
+// as CI_*. References to other variables are left as they are, so that values
+// holding shell scripts or templates are not altered.
+func substituteEnv(v interface{}, allowed []string) error {
+	return substituteEnvAt(v, allowed, "")
+}
+
+func substituteEnvAt(v interface{}, allowed []string, key string) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			child := k
+			if key != "" {
+				child = key + "." + k
+			}
+			if s, ok := val.(string); ok {
+				expanded, err := expandEnv(s, allowed)
+				if err != nil {
+					return errors.Wrap(err, child)
+				}
+				v[k] = expanded
+			} else if err := substituteEnvAt(val, allowed, child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			child := fmt.Sprintf("%s[%d]", key, i)
+			if s, ok := val.(string); ok {
+				expanded, err := expandEnv(s, allowed)
+				if err != nil {
+					return errors.Wrap(err, child)
+				}
+				v[i] = expanded
+			} else if err := substituteEnvAt(val, allowed, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandEnv expands the references to allowed environment variables in s.
+// An unset variable takes its default, or is an error if it has none, and
+// "$${NAME}" stands for a literal "${NAME}".
+func expandEnv(s string, allowed []string) (string, error) {
+	var err error
+	out := envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envReference.FindStringSubmatch(ref)
+		name := m[1]
+		if !envAllowed(name, allowed) {
+			return ref
+		}
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		if val, ok := os.LookupEnv(name); ok && (val != "" || m[2] == "") {
+			return val
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		if err == nil {
+			err = errors.Errorf("environment variable %s is not set", name)
+		}
+		return ref
+	})
+	return out, err
+}
+
+func envAllowed(name string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -22,12 +22,17 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/sops"
 	"helm.sh/helm/v4/pkg/strvals"
 )
 
@@ -39,25 +44,63 @@ type Options struct {
 	FileValues    []string // --set-file
 	JSONValues    []string // --set-json
 	LiteralValues []string // --set-literal
+	DecryptSOPS   bool     // --decrypt-sops
+	// EnvSubstitution lists the environment variables, or patterns of them,
+	// that are substituted into ${NAME} references in values files.
+	EnvSubstitution []string // --env-substitution
+	// SOPSKeys decrypt values files encrypted with SOPS. When nil, the keys
+	// are read from the environment with sops.DefaultKeySources.
+	SOPSKeys []sops.KeySource
 }
 
 // MergeValues merges values from files specified via -f/--values and directly
 // via --set-json, --set, --set-string, or --set-file, marshaling them to YAML
-func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, error) {
+//
+// Remote files are fetched with the getter of their scheme, such as https,
+// oci or the scheme of a getter plugin, and the given getter options. The
+// options are applied after the URL of each file, so that a getter.WithURL
+// option limits the credentials to the host of its URL.
+//
+// With DecryptSOPS, values files encrypted with SOPS are decrypted. With
+// EnvSubstitution, the allowed environment variables are substituted into the
+// string values of values files.
+func (opts *Options) MergeValues(p getter.Providers, getterOpts ...getter.Option) (map[string]interface{}, error) {
 	base := map[string]interface{}{}
 
+	for _, pattern := range opts.EnvSubstitution {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Errorf("invalid --env-substitution pattern %q", pattern)
+		}
+	}
+
 	// User specified a values files via -f/--values
-	for _, filePath := range opts.ValueFiles {
-		raw, err := readFile(filePath, p)
+	for _, valuesPath := range opts.ValueFiles {
+		filePaths, err := expandPath(valuesPath, p, valuesFileExtensions)
 		if err != nil {
 			return nil, err
 		}
-		currentMap, err := loader.LoadValues(bytes.NewReader(raw))
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to parse %s", filePath)
+		for _, filePath := range filePaths {
+			raw, err := readFile(filePath, p, getterOpts...)
+			if err != nil {
+				return nil, err
+			}
+			if opts.DecryptSOPS && sops.IsEncrypted(raw) {
+				if raw, err = opts.decryptSOPS(raw); err != nil {
+					return nil, errors.Wrapf(err, "failed to decrypt %s", filePath)
+				}
+			}
+			currentMap, err := loader.LoadValues(bytes.NewReader(raw))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse %s", filePath)
+			}
+			if len(opts.EnvSubstitution) > 0 {
+				if err := substituteEnv(currentMap, opts.EnvSubstitution); err != nil {
+					return nil, errors.Wrapf(err, "failed to substitute environment variables in %s", filePath)
+				}
+			}
+			// Merge with the previous map
+			base = loader.MergeMaps(base, currentMap)
 		}
-		// Merge with the previous map
-		base = loader.MergeMaps(base, currentMap)
 	}
 
 	// User specified a value via --set-json
@@ -95,11 +138,19 @@ func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, er
 	// User specified a value via --set-file
 	for _, value := range opts.FileValues {
 		reader := func(rs []rune) (interface{}, error) {
-			bytes, err := readFile(string(rs), p)
+			filePaths, err := expandPath(string(rs), p, nil)
 			if err != nil {
 				return nil, err
 			}
-			return string(bytes), err
+			var content []byte
+			for _, filePath := range filePaths {
+				bytes, err := readFile(filePath, p, getterOpts...)
+				if err != nil {
+					return nil, err
+				}
+				content = append(content, bytes...)
+			}
+			return string(content), nil
 		}
 		if err := strvals.ParseIntoFile(value, base, reader); err != nil {
 			return nil, errors.Wrap(err, "failed parsing --set-file data")
@@ -116,8 +167,96 @@ func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, er
 	return base, nil
 }
 
+// decryptSOPS decrypts a values file encrypted with SOPS. A file encrypted
+// only with master keys that there is no key source for, such as GCP KMS or
+// Azure Key Vault keys, is decrypted with the sops command when it is installed.
+func (opts *Options) decryptSOPS(data []byte) ([]byte, error) {
+	keys := opts.SOPSKeys
+	if keys == nil {
+		var err error
+		if keys, err = sops.DefaultKeySources(); err != nil {
+			return nil, err
+		}
+	}
+	out, err := sops.Decrypt(data, keys...)
+	if errors.Is(err, sops.ErrNoKey) {
+		if _, lookErr := exec.LookPath("sops"); lookErr == nil {
+			return sops.ExecDecrypt(data)
+		}
+	}
+	return out, err
+}
+
+// valuesFileExtensions are the extensions of the values files read from a
+// directory given to -f/--values.
+var valuesFileExtensions = []string{".yaml", ".yml", ".json"}
+
+// expandPath returns the files named by a path, in the order they are read.
+//
+// A local directory names the files in it with one of the given extensions,
+// or all of them if none are given, skipping hidden files. A glob names the
+// files it matches. In both cases, the files are in lexical order and
+// subdirectories are skipped. Any other path, such as stdin or a URL, names
+// itself.
+func expandPath(path string, p getter.Providers, exts []string) ([]string, error) {
+	if strings.TrimSpace(path) == "-" {
+		return []string{path}, nil
+	}
+	if u, err := url.Parse(path); err == nil {
+		if _, err := p.ByScheme(u.Scheme); err == nil {
+			return []string{path}, nil
+		}
+	}
+
+	var matches []string
+	if fi, err := os.Stat(path); err == nil {
+		if !fi.IsDir() {
+			return []string{path}, nil
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if strings.HasPrefix(name, ".") || (len(exts) > 0 && !hasExtension(name, exts)) {
+				continue
+			}
+			matches = append(matches, filepath.Join(path, name))
+		}
+	} else if strings.ContainsAny(path, "*?[") {
+		if matches, err = filepath.Glob(path); err != nil {
+			return nil, errors.Wrapf(err, "invalid glob %s", path)
+		}
+	} else {
+		return []string{path}, nil
+	}
+
+	var files []string
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err == nil && !fi.IsDir() {
+			files = append(files, m)
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no files found in %s", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func hasExtension(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
 // readFile load a file from stdin, the local directory, or a remote file with a url.
-func readFile(filePath string, p getter.Providers) ([]byte, error) {
+func readFile(filePath string, p getter.Providers, opts ...getter.Option) ([]byte, error) {
 	if strings.TrimSpace(filePath) == "-" {
 		return io.ReadAll(os.Stdin)
 	}
@@ -131,7 +270,8 @@ func readFile(filePath string, p getter.Providers) ([]byte, error) {
 	if err != nil {
 		return os.ReadFile(filePath)
 	}
-	data, err := g.Get(filePath, getter.WithURL(filePath))
+	opts = append(append([]getter.Option{getter.WithURL(filePath)}, opts...), getter.WithValuesFile())
+	data, err := g.Get(filePath, opts...)
 	if err != nil {
 		return nil, err
 	}
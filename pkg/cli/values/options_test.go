@@ -17,10 +17,16 @@ limitations under the License.
 package values
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/sops"
 )
 
 func TestReadFile(t *testing.T) {
@@ -103,6 +109,51 @@ func TestMergeValues(t *testing.T) {
 				"d": "bar1",
 			},
 		},
+		{
+			name: "values directory",
+			opts: Options{
+				ValueFiles: []string{"testdata/values.d"},
+			},
+			expected: map[string]interface{}{
+				"name":     "json",
+				"replicas": json.Number("3"),
+			},
+		},
+		{
+			name: "values glob",
+			opts: Options{
+				ValueFiles: []string{"testdata/values.d/*.y*ml"},
+			},
+			expected: map[string]interface{}{
+				"name":     "base",
+				"replicas": json.Number("3"),
+			},
+		},
+		{
+			name: "values glob without matches",
+			opts: Options{
+				ValueFiles: []string{"testdata/values.d/*.toml"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "set-file directory",
+			opts: Options{
+				FileValues: []string{"config=testdata/files"},
+			},
+			expected: map[string]interface{}{
+				"config": "first\nsecond\nother\n",
+			},
+		},
+		{
+			name: "set-file glob",
+			opts: Options{
+				FileValues: []string{"config=testdata/files/*.conf"},
+			},
+			expected: map[string]interface{}{
+				"config": "first\nsecond\n",
+			},
+		},
 		{
 			name: "invalid json",
 			opts: Options{
@@ -125,3 +176,115 @@ func TestMergeValues(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeValuesRemote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "user" || p != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("env: production\n"))
+	}))
+	defer srv.Close()
+
+	providers := getter.Providers{{Schemes: []string{"http"}, New: getter.NewHTTPGetter}}
+	opts := Options{ValueFiles: []string{srv.URL + "/values.yaml"}}
+
+	got, err := opts.MergeValues(providers, getter.WithBasicAuth("user", "pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]interface{}{"env": "production"}) {
+		t.Errorf("MergeValues() = %v", got)
+	}
+
+	// The credentials are limited to the host of the given URL.
+	if _, err := opts.MergeValues(providers, getter.WithBasicAuth("user", "pass"), getter.WithURL("https://charts.example.com")); err == nil {
+		t.Error("Expected the credentials not to be sent to another host")
+	}
+	if _, err := opts.MergeValues(providers, getter.WithBasicAuth("user", "pass"), getter.WithURL("https://charts.example.com"), getter.WithPassCredentialsAll(true)); err != nil {
+		t.Errorf("Expected the credentials to be sent to all hosts, got %s", err)
+	}
+}
+
+func TestMergeValuesSOPS(t *testing.T) {
+	key, err := os.ReadFile("testdata/age-key.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := sops.AgeKeys(string(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{ValueFiles: []string{"testdata/secrets.yaml"}, DecryptSOPS: true, SOPSKeys: []sops.KeySource{keys}}
+	got, err := opts.MergeValues(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"database": map[string]interface{}{"password": "s3cr3t", "port": json.Number("5432")},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("MergeValues() = %v, want %v", got, expected)
+	}
+
+	// Without the option, the file is read as it is.
+	opts.DecryptSOPS = false
+	got, err = opts.MergeValues(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["sops"]; !ok {
+		t.Error("Expected the SOPS metadata to be kept without decryption")
+	}
+
+	opts = Options{ValueFiles: []string{"testdata/secrets.yaml"}, DecryptSOPS: true, SOPSKeys: []sops.KeySource{}}
+	if _, err := opts.MergeValues(getter.Providers{}); err == nil {
+		t.Error("Expected an error without the decryption key")
+	}
+}
+
+func TestMergeValuesEnvSubstitution(t *testing.T) {
+	t.Setenv("CI_COMMIT_SHA", "abc123")
+	t.Setenv("CI_PIPELINE_ID", "42")
+	t.Setenv("CI_PROJECT", "web")
+	t.Setenv("REGISTRY", "")
+
+	opts := Options{ValueFiles: []string{"testdata/env.yaml"}, EnvSubstitution: []string{"CI_*", "REGISTRY"}}
+	got, err := opts.MergeValues(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"image":       map[string]interface{}{"tag": "abc123", "registry": "docker.io"},
+		"annotations": []interface{}{"build 42 of web"},
+		"script":      "echo ${HOME} ${CI_COMMIT_SHA}",
+		"replicas":    json.Number("2"),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("MergeValues() = %v, want %v", got, expected)
+	}
+
+	// Without the option, the references are kept.
+	opts.EnvSubstitution = nil
+	got, err = opts.MergeValues(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag := got["image"].(map[string]interface{})["tag"]; tag != "${CI_COMMIT_SHA}" {
+		t.Errorf("Expected the reference to be kept, got %v", tag)
+	}
+
+	os.Unsetenv("CI_PROJECT")
+	opts.EnvSubstitution = []string{"CI_*"}
+	_, err = opts.MergeValues(getter.Providers{})
+	if err == nil || !strings.Contains(err.Error(), "annotations[0]: environment variable CI_PROJECT is not set") {
+		t.Errorf("Expected an error for the unset variable, got %v", err)
+	}
+
+	opts.EnvSubstitution = []string{"CI_["}
+	if _, err := opts.MergeValues(getter.Providers{}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/action"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/cli/output"
+	"helm.sh/helm/v4/pkg/cmd/require"
+)
+
+const capabilitiesDesc = `
+Show the Kubernetes version and API versions of the cluster, as seen by the
+templates of a chart through .Capabilities.
+
+Save them as YAML or JSON to render charts offline exactly as they would be
+rendered on this cluster:
+
+    $ helm capabilities -o yaml > production.yaml
+    $ helm template mychart --capabilities-file production.yaml
+`
+
+type capabilitiesWriter struct {
+	caps *chartutil.Capabilities
+}
+
+func newCapabilitiesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	var outfmt output.Format
+	client := action.NewCapabilities(cfg)
+
+	cmd := &cobra.Command{
+		Use:               "capabilities",
+		Short:             "show the capabilities of the cluster",
+		Long:              capabilitiesDesc,
+		Args:              require.NoArgs,
+		ValidArgsFunction: noMoreArgsCompFunc,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			caps, err := client.Run()
+			if err != nil {
+				return err
+			}
+			return outfmt.Write(out, &capabilitiesWriter{caps})
+		},
+	}
+
+	bindOutputFlag(cmd, &outfmt)
+
+	return cmd
+}
+
+func (w capabilitiesWriter) WriteTable(out io.Writer) error {
+	_, _ = fmt.Fprintf(out, "KUBE_VERSION: %s\n", w.caps.KubeVersion.Version)
+	_, _ = fmt.Fprintln(out, "API_VERSIONS:")
+	for _, v := range w.caps.APIVersions {
+		_, _ = fmt.Fprintf(out, "  %s\n", v)
+	}
+	return nil
+}
+
+func (w capabilitiesWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.caps.File())
+}
+
+func (w capabilitiesWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.caps.File())
+}
@@ -0,0 +1,53 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func TestCapabilitiesCmd(t *testing.T) {
+	_, out, err := executeActionCommand("capabilities")
+	require.NoError(t, err)
+	assert.Contains(t, out, "KUBE_VERSION: "+chartutil.DefaultCapabilities.KubeVersion.Version+"\n")
+	assert.Contains(t, out, "API_VERSIONS:\n")
+	assert.Contains(t, out, "\n  apps/v1\n")
+
+	// The saved capabilities load back as they were.
+	for _, format := range []string{"yaml", "json"} {
+		_, out, err := executeActionCommand("capabilities -o " + format)
+		require.NoError(t, err)
+		path := filepath.Join(t.TempDir(), "capabilities."+format)
+		require.NoError(t, os.WriteFile(path, []byte(out), 0644))
+
+		caps, err := chartutil.LoadCapabilities(path)
+		require.NoError(t, err)
+		assert.Equal(t, chartutil.DefaultCapabilities.KubeVersion, caps.KubeVersion)
+		assert.Equal(t, chartutil.DefaultCapabilities.APIVersions, caps.APIVersions)
+	}
+}
+
+func TestCapabilitiesOutputCompletion(t *testing.T) {
+	outputFlagCompletionTest(t, "capabilities")
+}
@@ -134,3 +134,9 @@ func addDependencySubcommandFlags(f *pflag.FlagSet, client *action.Dependency) {
 	f.BoolVar(&client.PlainHTTP, "plain-http", false, "use insecure HTTP connections for the chart download")
 	f.StringVar(&client.CaFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
 }
+
+// contentCache returns the directory of the content-addressed cache of chart
+// archives, which is kept alongside the repository cache.
+func contentCache() string {
+	return filepath.Join(settings.RepositoryCache, "content")
+}
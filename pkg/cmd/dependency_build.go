@@ -69,6 +69,7 @@ func newDependencyBuildCmd(out io.Writer) *cobra.Command {
 				RegistryClient:   registryClient,
 				RepositoryConfig: settings.RepositoryConfig,
 				RepositoryCache:  settings.RepositoryCache,
+				ContentCache:     contentCache(),
 				Debug:            settings.Debug,
 			}
 			if client.Verify {
@@ -175,8 +175,11 @@ func TestDependencyUpdateCmd_DoNotDeleteOldChartsOnError(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Chart repo is down
+	// Chart repo is down, and the archives are not cached
 	srv.Stop()
+	if err := os.RemoveAll(dir("content")); err != nil {
+		t.Fatal(err)
+	}
 
 	_, output, err = executeActionCommand(fmt.Sprintf("dependency update %s --repository-config %s --repository-cache %s --plain-http", dir(chartname), dir("repositories.yaml"), dir()))
 	if err == nil {
@@ -22,16 +22,20 @@ import (
 	"log"
 	"log/slog"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
 
 	"helm.sh/helm/v4/pkg/action"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/cli/output"
 	"helm.sh/helm/v4/pkg/cli/values"
+	"helm.sh/helm/v4/pkg/engine"
 	"helm.sh/helm/v4/pkg/helmpath"
 	"helm.sh/helm/v4/pkg/kube"
 	"helm.sh/helm/v4/pkg/postrender"
@@ -42,15 +46,18 @@ const (
 	outputFlag         = "output"
 	postRenderFlag     = "post-renderer"
 	postRenderArgsFlag = "post-renderer-args"
+	kustomizeFlag      = "post-renderer-kustomize"
 )
 
 func addValueOptionsFlags(f *pflag.FlagSet, v *values.Options) {
-	f.StringSliceVarP(&v.ValueFiles, "values", "f", []string{}, "specify values in a YAML file or a URL (can specify multiple)")
+	f.StringSliceVarP(&v.ValueFiles, "values", "f", []string{}, "specify values in a YAML file or a URL (can specify multiple). A directory or a glob reads the matching files in lexical order")
 	f.StringArrayVar(&v.Values, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
 	f.StringArrayVar(&v.StringValues, "set-string", []string{}, "set STRING values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
-	f.StringArrayVar(&v.FileValues, "set-file", []string{}, "set values from respective files specified via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)")
+	f.StringArrayVar(&v.FileValues, "set-file", []string{}, "set values from respective files specified via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2). A directory or a glob sets the contents of the matching files, concatenated in lexical order")
 	f.StringArrayVar(&v.JSONValues, "set-json", []string{}, "set JSON values on the command line (can specify multiple or separate values with commas: key1=jsonval1,key2=jsonval2 or using json format: {\"key1\": jsonval1, \"key2\": \"jsonval2\"})")
 	f.StringArrayVar(&v.LiteralValues, "set-literal", []string{}, "set a literal STRING value on the command line")
+	f.StringSliceVar(&v.EnvSubstitution, "env-substitution", []string{}, "substitute the given environment variables, or patterns of them such as 'CI_*', into ${NAME} references in values files (can specify multiple)")
+	f.BoolVar(&v.DecryptSOPS, "decrypt-sops", false, "decrypt values files encrypted with SOPS, with the age, PGP and AWS KMS keys of the environment")
 }
 
 func AddWaitFlag(cmd *cobra.Command, wait *kube.WaitStrategy) {
@@ -99,10 +106,139 @@ func (ws *waitValue) Type() string {
 	return "WaitStrategy"
 }
 
+type namespacePolicyValue kube.NamespacePolicy
+
+func newNamespacePolicyValue(p *kube.NamespacePolicy) *namespacePolicyValue {
+	*p = kube.AllowNamespacePolicy
+	return (*namespacePolicyValue)(p)
+}
+
+func (np *namespacePolicyValue) String() string {
+	return string(*np)
+}
+
+func (np *namespacePolicyValue) Set(s string) error {
+	switch kube.NamespacePolicy(s) {
+	case kube.AllowNamespacePolicy, kube.WarnNamespacePolicy, kube.RejectNamespacePolicy:
+		*np = namespacePolicyValue(s)
+		return nil
+	default:
+		return fmt.Errorf("invalid namespace policy %q. Valid inputs are %s, %s, and %s", s, kube.AllowNamespacePolicy, kube.WarnNamespacePolicy, kube.RejectNamespacePolicy)
+	}
+}
+
+func (np *namespacePolicyValue) Type() string {
+	return "NamespacePolicy"
+}
+
+// capabilitiesFileValue loads the capabilities of --capabilities-file when
+// the flag is set.
+type capabilitiesFileValue struct {
+	caps **chartutil.Capabilities
+	path string
+}
+
+func (c *capabilitiesFileValue) String() string {
+	return c.path
+}
+
+func (c *capabilitiesFileValue) Set(s string) error {
+	caps, err := chartutil.LoadCapabilities(s)
+	if err != nil {
+		return err
+	}
+	c.path = s
+	*c.caps = caps
+	return nil
+}
+
+func (c *capabilitiesFileValue) Type() string {
+	return "string"
+}
+
+// lookupFixturesValue loads the objects of --lookup-fixtures when the flag
+// is set.
+type lookupFixturesValue struct {
+	objs *[]*unstructured.Unstructured
+	path string
+}
+
+func (l *lookupFixturesValue) String() string {
+	return l.path
+}
+
+func (l *lookupFixturesValue) Set(s string) error {
+	objs, err := engine.LoadLookupFixtures(s)
+	if err != nil {
+		return err
+	}
+	l.path = s
+	*l.objs = objs
+	return nil
+}
+
+func (l *lookupFixturesValue) Type() string {
+	return "string"
+}
+
+// addPreflightFlag adds the --preflight flag, selecting the built-in
+// preflight checks to run by name.
+func addPreflightFlag(f *pflag.FlagSet, checks *[]action.PreflightCheck) {
+	f.Var((*preflightValue)(checks), "preflight", "run preflight checks before making any change and report all their failures. Set with no value to run all checks, or to a comma-separated list of: "+strings.Join(preflightCheckNames(), ", "))
+	f.Lookup("preflight").NoOptDefVal = "all"
+}
+
+type preflightValue []action.PreflightCheck
+
+func preflightCheckNames() []string {
+	var names []string
+	for _, check := range action.DefaultPreflightChecks() {
+		names = append(names, check.Name())
+	}
+	return names
+}
+
+func (pv *preflightValue) String() string {
+	var names []string
+	for _, check := range *pv {
+		names = append(names, check.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (pv *preflightValue) Set(s string) error {
+	switch s {
+	case "all", "true":
+		*pv = action.DefaultPreflightChecks()
+		return nil
+	case "", "false":
+		*pv = nil
+		return nil
+	}
+	var checks []action.PreflightCheck
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		idx := slices.IndexFunc(action.DefaultPreflightChecks(), func(check action.PreflightCheck) bool { return check.Name() == name })
+		if idx < 0 {
+			return fmt.Errorf("invalid preflight check %q. Valid inputs are all, %s", name, strings.Join(preflightCheckNames(), ", "))
+		}
+		checks = append(checks, action.DefaultPreflightChecks()[idx])
+	}
+	*pv = checks
+	return nil
+}
+
+func (pv *preflightValue) Type() string {
+	return "checks"
+}
+
 func addChartPathOptionsFlags(f *pflag.FlagSet, c *action.ChartPathOptions) {
 	f.StringVar(&c.Version, "version", "", "specify a version constraint for the chart version to use. This constraint can be a specific tag (e.g. 1.1.1) or it may reference a valid range (e.g. ^2.0.0). If this is not specified, the latest version is used")
 	f.BoolVar(&c.Verify, "verify", false, "verify the package before using it")
 	f.StringVar(&c.Keyring, "keyring", defaultKeyring(), "location of public keys used for verification")
+	f.StringVar(&c.CertificateIdentity, "certificate-identity", "", "verify the keyless Sigstore signature of the package, which must be made by this identity, instead of its provenance file. Used if --verify is true")
+	f.StringVar(&c.CertificateOIDCIssuer, "certificate-oidc-issuer", "", "OIDC issuer of the signing identity. Used if --certificate-identity is set")
+	f.StringVar(&c.TrustedRoot, "trusted-root", "", "path to the Sigstore trusted root. Used if --certificate-identity is set")
 	f.StringVar(&c.RepoURL, "repo", "", "chart repository url where to locate the requested chart")
 	f.StringVar(&c.Username, "username", "", "chart repository username where to locate the requested chart")
 	f.StringVar(&c.Password, "password", "", "chart repository password where to locate the requested chart")
@@ -114,6 +250,18 @@ func addChartPathOptionsFlags(f *pflag.FlagSet, c *action.ChartPathOptions) {
 	f.BoolVar(&c.PassCredentialsAll, "pass-credentials", false, "pass credentials to all domains")
 }
 
+// kubeSchemaOptions select the offline validation of rendered manifests
+// against the Kubernetes API schemas.
+type kubeSchemaOptions struct {
+	enabled  bool
+	location string
+}
+
+func addKubeSchemaFlags(f *pflag.FlagSet, o *kubeSchemaOptions) {
+	f.BoolVar(&o.enabled, "kube-schemas", false, "validate rendered manifests against the Kubernetes API schemas of --kube-version, without a cluster. Schemas are cached after they are first fetched")
+	f.StringVar(&o.location, "kube-schema-location", "", "URL or directory of the Kubernetes API schemas, laid out like the kubernetes-json-schema repository. Used if --kube-schemas is true")
+}
+
 // bindOutputFlag will add the output flag to the given command and bind the
 // value to the given format pointer
 func bindOutputFlag(cmd *cobra.Command, varRef *output.Format) {
@@ -164,15 +312,77 @@ func (o *outputValue) Set(s string) error {
 }
 
 func bindPostRenderFlag(cmd *cobra.Command, varRef *postrender.PostRenderer) {
-	p := &postRendererOptions{varRef, "", []string{}}
-	cmd.Flags().Var(&postRendererString{p}, postRenderFlag, "the path to an executable to be used for post rendering. If it exists in $PATH, the binary will be used, otherwise it will try to look for the executable at the given path")
-	cmd.Flags().Var(&postRendererArgsSlice{p}, postRenderArgsFlag, "an argument to the post-renderer (can specify multiple)")
+	p := &postRendererOptions{renderer: varRef}
+	cmd.Flags().Var(&postRendererString{p}, postRenderFlag, "the path to an executable to be used for post rendering. If it exists in $PATH, the binary will be used, otherwise it will try to look for the executable at the given path. The release being rendered is described to it in HELM_RELEASE_*, HELM_CHART_* and HELM_DRY_RUN environment variables. Post-renderers given several times, with this flag or --post-renderer-kustomize, run as a pipeline in the order they are given")
+	cmd.Flags().Var(&postRendererArgsSlice{p}, postRenderArgsFlag, "an argument to the last --post-renderer given before it, or to the first one (can specify multiple)")
+	cmd.Flags().Var(&postRendererKustomize{p}, kustomizeFlag, "the path to a kustomization directory to run over the rendered manifests, without a kustomize binary. The rendered manifests are added to its resources as "+postrender.KustomizeResourceFile)
 }
 
 type postRendererOptions struct {
-	renderer   *postrender.PostRenderer
-	binaryPath string
-	args       []string
+	renderer *postrender.PostRenderer
+	// steps are the post-renderers to run, in the order they were given.
+	steps []*postRendererStep
+	// pendingArgs are the arguments given before any executable, which are
+	// passed to the first one.
+	pendingArgs []string
+}
+
+type postRendererStep struct {
+	binaryPath   string
+	args         []string
+	kustomizeDir string
+}
+
+// lastArgs returns the arguments of the last executable post-renderer given,
+// or the pending arguments if none was.
+func (o *postRendererOptions) lastArgs() *[]string {
+	for i := len(o.steps) - 1; i >= 0; i-- {
+		if o.steps[i].binaryPath != "" {
+			return &o.steps[i].args
+		}
+	}
+	return &o.pendingArgs
+}
+
+func (o *postRendererOptions) addStep(step *postRendererStep) error {
+	if step.binaryPath != "" && o.lastArgs() == &o.pendingArgs {
+		step.args = o.pendingArgs
+	}
+	o.steps = append(o.steps, step)
+	if err := o.update(); err != nil {
+		o.steps = o.steps[:len(o.steps)-1]
+		return err
+	}
+	if step.binaryPath != "" {
+		o.pendingArgs = nil
+	}
+	return nil
+}
+
+// update sets the renderer to run the post-renderers given so far.
+func (o *postRendererOptions) update() error {
+	var renderers []postrender.PostRenderer
+	for _, step := range o.steps {
+		var pr postrender.PostRenderer
+		var err error
+		if step.kustomizeDir != "" {
+			pr, err = postrender.NewKustomize(step.kustomizeDir)
+		} else {
+			pr, err = postrender.NewExec(step.binaryPath, step.args...)
+		}
+		if err != nil {
+			return err
+		}
+		renderers = append(renderers, pr)
+	}
+	switch len(renderers) {
+	case 0:
+	case 1:
+		*o.renderer = renderers[0]
+	default:
+		*o.renderer = postrender.NewChain(renderers...)
+	}
+	return nil
 }
 
 type postRendererString struct {
@@ -180,7 +390,13 @@ type postRendererString struct {
 }
 
 func (p *postRendererString) String() string {
-	return p.options.binaryPath
+	var paths []string
+	for _, step := range p.options.steps {
+		if step.binaryPath != "" {
+			paths = append(paths, step.binaryPath)
+		}
+	}
+	return strings.Join(paths, ",")
 }
 
 func (p *postRendererString) Type() string {
@@ -191,16 +407,7 @@ func (p *postRendererString) Set(val string) error {
 	if val == "" {
 		return nil
 	}
-	if p.options.binaryPath != "" {
-		return fmt.Errorf("cannot specify --post-renderer flag more than once")
-	}
-	p.options.binaryPath = val
-	pr, err := postrender.NewExec(p.options.binaryPath, p.options.args...)
-	if err != nil {
-		return err
-	}
-	*p.options.renderer = pr
-	return nil
+	return p.options.addStep(&postRendererStep{binaryPath: val})
 }
 
 type postRendererArgsSlice struct {
@@ -208,7 +415,7 @@ type postRendererArgsSlice struct {
 }
 
 func (p *postRendererArgsSlice) String() string {
-	return "[" + strings.Join(p.options.args, ",") + "]"
+	return "[" + strings.Join(*p.options.lastArgs(), ",") + "]"
 }
 
 func (p *postRendererArgsSlice) Type() string {
@@ -216,34 +423,50 @@ func (p *postRendererArgsSlice) Type() string {
 }
 
 func (p *postRendererArgsSlice) Set(val string) error {
-
 	// a post-renderer defined by a user may accept empty arguments
-	p.options.args = append(p.options.args, val)
+	args := p.options.lastArgs()
+	*args = append(*args, val)
+	return p.options.update()
+}
 
-	if p.options.binaryPath == "" {
-		return nil
+type postRendererKustomize struct {
+	options *postRendererOptions
+}
+
+func (p *postRendererKustomize) String() string {
+	var dirs []string
+	for _, step := range p.options.steps {
+		if step.kustomizeDir != "" {
+			dirs = append(dirs, step.kustomizeDir)
+		}
 	}
-	// overwrite if already create PostRenderer by `post-renderer` flags
-	pr, err := postrender.NewExec(p.options.binaryPath, p.options.args...)
-	if err != nil {
-		return err
+	return strings.Join(dirs, ",")
+}
+
+func (p *postRendererKustomize) Type() string {
+	return "string"
+}
+
+func (p *postRendererKustomize) Set(val string) error {
+	if val == "" {
+		return nil
 	}
-	*p.options.renderer = pr
-	return nil
+	return p.options.addStep(&postRendererStep{kustomizeDir: val})
 }
 
 func (p *postRendererArgsSlice) Append(val string) error {
-	p.options.args = append(p.options.args, val)
+	args := p.options.lastArgs()
+	*args = append(*args, val)
 	return nil
 }
 
 func (p *postRendererArgsSlice) Replace(val []string) error {
-	p.options.args = val
+	*p.options.lastArgs() = val
 	return nil
 }
 
 func (p *postRendererArgsSlice) GetSlice() []string {
-	return p.options.args
+	return *p.options.lastArgs()
 }
 
 func compVersionFlag(chartRef string, _ string) ([]string, cobra.ShellCompDirective) {
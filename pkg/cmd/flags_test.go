@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"helm.sh/helm/v4/pkg/action"
@@ -97,23 +98,32 @@ func outputFlagCompletionTest(t *testing.T, cmdName string) {
 	runTestCmd(t, tests)
 }
 
-func TestPostRendererFlagSetOnce(t *testing.T) {
+func TestPostRendererFlagChain(t *testing.T) {
 	cfg := action.Configuration{}
 	client := action.NewInstall(&cfg)
-	str := postRendererString{
-		options: &postRendererOptions{
-			renderer: &client.PostRenderer,
-		},
-	}
-	// Set the binary once
-	err := str.Set("echo")
-	require.NoError(t, err)
+	options := &postRendererOptions{renderer: &client.PostRenderer}
+	str := postRendererString{options}
+	args := postRendererArgsSlice{options}
+
+	// Arguments given before any post-renderer go to the first one
+	require.NoError(t, args.Set("first"))
+	require.NoError(t, str.Set("echo"))
+	require.NotNil(t, client.PostRenderer)
+
+	// Each post-renderer given is added to the pipeline, with the arguments
+	// that follow it
+	require.NoError(t, str.Set("cat"))
+	require.NoError(t, args.Set("second"))
+	require.Len(t, options.steps, 2)
+	assert.Equal(t, []string{"first"}, options.steps[0].args)
+	assert.Equal(t, []string{"second"}, options.steps[1].args)
+	assert.Equal(t, "echo,cat", str.String())
 
-	// Set the binary again to the same value is not ok
-	err = str.Set("echo")
-	require.Error(t, err)
+	chain, ok := client.PostRenderer.(fmt.Stringer)
+	require.True(t, ok)
+	assert.Contains(t, chain.String(), " | ")
 
-	// Set the binary again to a different value is not ok
-	err = str.Set("cat")
-	require.Error(t, err)
+	// A post-renderer that cannot be found is not added
+	require.Error(t, str.Set("this-binary-does-not-exist"))
+	assert.Len(t, options.steps, 2)
 }
@@ -33,6 +33,11 @@ This command fetches the generated manifest for a given release.
 A manifest is a YAML-encoded representation of the Kubernetes resources that
 were generated from this release's chart(s). If a chart is dependent on other
 charts, those resources will also be included in the manifest.
+
+The manifest can be filtered to the resources of some kinds, names, labels or
+templates. For example, this prints the Deployments of the 'sub' subchart:
+
+    $ helm get manifest my-release --kind Deployment --source 'mychart/charts/sub'
 `
 
 func newGetManifestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -59,7 +64,12 @@ func newGetManifestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 		},
 	}
 
-	cmd.Flags().IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	f := cmd.Flags()
+	f.IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	f.StringSliceVar(&client.ManifestFilter.Kinds, "kind", nil, "only print the resources of these kinds. Can be specified as a comma-separated list or multiple times")
+	f.StringSliceVar(&client.ManifestFilter.Names, "name", nil, "only print the resources with these names. Can be specified as a comma-separated list or multiple times")
+	f.StringVarP(&client.ManifestFilter.Selector, "selector", "l", "", "only print the resources matching this label selector, such as 'app.kubernetes.io/component=web'")
+	f.StringSliceVar(&client.ManifestFilter.Sources, "source", nil, "only print the resources rendered from the templates matching these path patterns, or under them. Can be specified as a comma-separated list or multiple times")
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
 			return compListRevisions(toComplete, cfg, args[0])
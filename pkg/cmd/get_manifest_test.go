@@ -23,11 +23,44 @@ import (
 )
 
 func TestGetManifest(t *testing.T) {
+	multiDoc := release.Mock(&release.MockReleaseOptions{Name: "juno"})
+	multiDoc.Manifest = `---
+# Source: juno/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: fixture
+---
+# Source: juno/charts/web/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    tier: frontend
+`
+
 	tests := []cmdTestCase{{
 		name:   "get manifest with release",
 		cmd:    "get manifest juno",
 		golden: "output/get-manifest.txt",
 		rels:   []*release.Release{release.Mock(&release.MockReleaseOptions{Name: "juno"})},
+	}, {
+		name:   "get manifest filtered by kind",
+		cmd:    "get manifest juno --kind deployment",
+		golden: "output/get-manifest-filtered.txt",
+		rels:   []*release.Release{multiDoc},
+	}, {
+		name:   "get manifest filtered by source and label",
+		cmd:    "get manifest juno --source juno/charts/web -l tier=frontend",
+		golden: "output/get-manifest-filtered.txt",
+		rels:   []*release.Release{multiDoc},
+	}, {
+		name:      "get manifest with an invalid selector",
+		cmd:       "get manifest juno -l 'tier in'",
+		golden:    "output/get-manifest-invalid-selector.txt",
+		rels:      []*release.Release{multiDoc},
+		wantError: true,
 	}, {
 		name:      "get manifest without args",
 		cmd:       "get manifest",
@@ -0,0 +1,110 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/cli/output"
+	"helm.sh/helm/v4/pkg/cmd/require"
+)
+
+var getResourcesHelp = `
+This command lists the live objects of a named release, with their health and
+age. The objects are the resources of the manifest of the release, followed by
+the other objects labeled and annotated as belonging to the release, such as
+hooks and the resources left behind by a failed upgrade.
+`
+
+type resourcesWriter struct {
+	resources []action.LiveResource
+	now       time.Time
+}
+
+func newGetResourcesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	var outfmt output.Format
+	client := action.NewGetResources(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "resources RELEASE_NAME",
+		Short: "list the live objects of a named release",
+		Long:  getResourcesHelp,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return noMoreArgsComp()
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			resources, err := client.Run(args[0])
+			if err != nil {
+				return err
+			}
+			return outfmt.Write(out, &resourcesWriter{resources: resources, now: time.Now()})
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return compListRevisions(toComplete, cfg, args[0])
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bindOutputFlag(cmd, &outfmt)
+
+	return cmd
+}
+
+func (w resourcesWriter) WriteTable(out io.Writer) error {
+	tbl := uitable.New()
+	tbl.AddRow("NAMESPACE", "KIND", "NAME", "HEALTH", "AGE", "IN MANIFEST", "MESSAGE")
+	for _, r := range w.resources {
+		age := "<none>"
+		if !r.Created.IsZero() {
+			age = duration.HumanDuration(w.now.Sub(r.Created))
+		}
+		inManifest := "no"
+		if r.InManifest {
+			inManifest = "yes"
+		}
+		tbl.AddRow(r.Namespace, r.Kind, r.Name, r.Health, age, inManifest, r.Message)
+	}
+	return output.EncodeTable(out, tbl)
+}
+
+func (w resourcesWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.resources)
+}
+
+func (w resourcesWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.resources)
+}
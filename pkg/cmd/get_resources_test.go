@@ -0,0 +1,69 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v4/internal/test"
+	"helm.sh/helm/v4/pkg/action"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func TestGetResourcesCmd(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "get resources requires release name arg",
+		cmd:       "get resources",
+		golden:    "output/get-resources-args.txt",
+		rels:      []*release.Release{release.Mock(&release.MockReleaseOptions{Name: "thomas-guide"})},
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestGetResourcesWriteTable(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	w := resourcesWriter{now: now, resources: []action.LiveResource{
+		{Kind: "Deployment", Namespace: "default", Name: "web", Health: "InProgress", Message: "Replicas: 1/2", Created: now.Add(-90 * time.Minute), InManifest: true},
+		{Kind: "Job", Namespace: "default", Name: "migrate", Health: "Ready", Created: now.Add(-3 * 24 * time.Hour)},
+		{Kind: "Service", Namespace: "default", Name: "web", Health: "Missing", Message: "Resource not found", InManifest: true},
+	}}
+	var out bytes.Buffer
+	if err := w.WriteTable(&out); err != nil {
+		t.Fatal(err)
+	}
+	test.AssertGoldenString(t, out.String(), "output/get-resources.txt")
+}
+
+func TestGetResourcesCompletion(t *testing.T) {
+	checkReleaseCompletion(t, "get resources", false)
+}
+
+func TestGetResourcesRevisionCompletion(t *testing.T) {
+	revisionFlagCompletionTest(t, "get resources")
+}
+
+func TestGetResourcesOutputCompletion(t *testing.T) {
+	outputFlagCompletionTest(t, "get resources")
+}
+
+func TestGetResourcesFileCompletion(t *testing.T) {
+	checkFileCompletion(t, "get resources", false)
+	checkFileCompletion(t, "get resources myrelease", false)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
@@ -48,11 +49,26 @@ The historical release set is printed as a formatted table, e.g:
     2           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Upgraded successfully
     3           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Rolled back to 2
     4           Mon Oct 3 10:15:13 2016     deployed        alpine-0.1.0      1.0             Upgraded successfully
+
+Setting '--diff' to two revisions prints the differences in chart, values and
+resources between them instead, e.g:
+
+    $ helm history angry-bird --diff 2,4
+    RELEASE: angry-bird
+    REVISIONS: 2 -> 4
+    CHART:
+      version: 0.1.0 -> 0.2.0
+    VALUES:
+      image.tag: 1.0 -> 1.1
+    RESOURCES:
+      update apps/v1 Deployment default/angry-bird
+        spec.replicas: 1 -> 2
 `
 
 func newHistoryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewHistory(cfg)
 	var outfmt output.Format
+	var diffRevisions []int
 
 	cmd := &cobra.Command{
 		Use:     "history RELEASE_NAME",
@@ -67,6 +83,17 @@ func newHistoryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if len(diffRevisions) > 0 {
+				if len(diffRevisions) != 2 {
+					return fmt.Errorf("--diff requires exactly two revisions, got %d", len(diffRevisions))
+				}
+				diff, err := client.Diff(args[0], diffRevisions[0], diffRevisions[1])
+				if err != nil {
+					return err
+				}
+				return outfmt.Write(out, &revisionDiffWriter{diff})
+			}
+
 			history, err := getHistory(client, args[0])
 			if err != nil {
 				return err
@@ -78,6 +105,7 @@ func newHistoryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 	f := cmd.Flags()
 	f.IntVar(&client.Max, "max", 256, "maximum number of revision to include in history")
+	f.IntSliceVar(&diffRevisions, "diff", nil, "print the differences between two revisions, such as 2,4, instead of the history")
 	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
@@ -90,6 +118,8 @@ type releaseInfo struct {
 	Chart       string        `json:"chart"`
 	AppVersion  string        `json:"app_version"`
 	Description string        `json:"description"`
+	// HookLogs are the logs kept from the hooks of the revision, by hook name.
+	HookLogs map[string]string `json:"hook_logs,omitempty"`
 }
 
 type releaseHistory []releaseInfo
@@ -111,6 +141,68 @@ func (r releaseHistory) WriteTable(out io.Writer) error {
 	return output.EncodeTable(out, tbl)
 }
 
+type revisionDiffWriter struct {
+	diff *action.RevisionDiff
+}
+
+func (w *revisionDiffWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.diff)
+}
+
+func (w *revisionDiffWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.diff)
+}
+
+func (w *revisionDiffWriter) WriteTable(out io.Writer) error {
+	d := w.diff
+	fmt.Fprintf(out, "RELEASE: %s\n", d.Release)
+	fmt.Fprintf(out, "REVISIONS: %d -> %d\n", d.From, d.To)
+	if len(d.ChartChanges) == 0 && len(d.ValueChanges) == 0 && len(d.ResourceChanges) == 0 {
+		fmt.Fprintln(out, "No differences")
+		return nil
+	}
+	if len(d.ChartChanges) > 0 {
+		fmt.Fprintln(out, "CHART:")
+		writeFieldChanges(out, "  ", d.ChartChanges)
+	}
+	if len(d.ValueChanges) > 0 {
+		fmt.Fprintln(out, "VALUES:")
+		writeFieldChanges(out, "  ", d.ValueChanges)
+	}
+	if len(d.ResourceChanges) > 0 {
+		fmt.Fprintln(out, "RESOURCES:")
+		for _, change := range d.ResourceChanges {
+			name := change.Name
+			if change.Namespace != "" {
+				name = change.Namespace + "/" + name
+			}
+			fmt.Fprintf(out, "  %s %s %s %s\n", change.Action, change.APIVersion, change.Kind, name)
+			writeFieldChanges(out, "    ", change.ManifestChanges)
+		}
+	}
+	return nil
+}
+
+func writeFieldChanges(out io.Writer, indent string, changes []action.FieldChange) {
+	for _, change := range changes {
+		fmt.Fprintf(out, "%s%s: %s -> %s\n", indent, change.Path, formatFieldValue(change.Old), formatFieldValue(change.New))
+	}
+}
+
+func formatFieldValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
 func getHistory(client *action.History, name string) (releaseHistory, error) {
 	hist, err := client.Run(name)
 	if err != nil {
@@ -153,6 +245,15 @@ func getReleaseHistory(rls []*release.Release) (history releaseHistory) {
 			rInfo.Updated = r.Info.LastDeployed
 
 		}
+		for _, h := range r.Hooks {
+			if h.LastRun.Logs == "" {
+				continue
+			}
+			if rInfo.HookLogs == nil {
+				rInfo.HookLogs = map[string]string{}
+			}
+			rInfo.HookLogs[h.Name] = h.LastRun.Logs
+		}
 		history = append(history, rInfo)
 	}
 
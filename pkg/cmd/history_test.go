@@ -66,6 +66,66 @@ func TestHistoryCmd(t *testing.T) {
 			mk("angry-bird", 3, release.StatusSuperseded),
 		},
 		golden: "output/history.json",
+	}, {
+		name: "get history with hook logs in json output format",
+		cmd:  "history angry-bird --output json",
+		rels: func() []*release.Release {
+			failed := mk("angry-bird", 2, release.StatusFailed)
+			failed.Hooks[0].LastRun.Phase = release.HookPhaseFailed
+			failed.Hooks[0].LastRun.Logs = "error: relation \"users\" does not exist\n"
+			return []*release.Release{failed, mk("angry-bird", 1, release.StatusDeployed)}
+		}(),
+		golden: "output/history-hook-logs.json",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestHistoryDiffCmd(t *testing.T) {
+	mk := func(version int, chartVersion, image string) *release.Release {
+		rel := release.Mock(&release.MockReleaseOptions{
+			Name:    "angry-bird",
+			Version: version,
+		})
+		rel.Chart.Metadata.Version = chartVersion
+		rel.Config = map[string]interface{}{"image": map[string]interface{}{"tag": image}}
+		rel.Manifest = fmt.Sprintf(`---
+# Source: foo/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: angry-bird
+  namespace: default
+spec:
+  replicas: %d
+`, version)
+		return rel
+	}
+	rels := []*release.Release{
+		mk(2, "0.2.0", "1.1"),
+		mk(1, "0.1.0", "1.0"),
+	}
+
+	tests := []cmdTestCase{{
+		name:   "diff between revisions",
+		cmd:    "history angry-bird --diff 1,2",
+		rels:   rels,
+		golden: "output/history-diff.txt",
+	}, {
+		name:   "diff between revisions with json output format",
+		cmd:    "history angry-bird --diff 1,2 --output json",
+		rels:   rels,
+		golden: "output/history-diff.json",
+	}, {
+		name:   "diff of a revision with itself",
+		cmd:    "history angry-bird --diff 2,2",
+		rels:   rels,
+		golden: "output/history-diff-none.txt",
+	}, {
+		name:      "diff with a single revision",
+		cmd:       "history angry-bird --diff 1",
+		rels:      rels,
+		wantError: true,
+		golden:    "output/history-diff-one-revision.txt",
 	}}
 	runTestCmd(t, tests)
 }
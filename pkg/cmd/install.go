@@ -83,6 +83,36 @@ contained a key called 'Test', the value set in override.yaml would take precede
 
     $ helm install -f myvalues.yaml -f override.yaml  myredis ./redis
 
+The '--values'/'-f' flag also accepts a directory, whose YAML and JSON files are
+read in lexical order, or a quoted glob. This lets a 'values.d' directory hold
+the values of an environment:
+
+    $ helm install -f values.d/ -f 'overrides/*.yaml' myredis ./redis
+
+Values files can also be fetched from a URL, an OCI registry or any scheme of
+a getter plugin, with the TLS settings of the chart. The '--username' and
+'--password' credentials are sent only to the host of the chart, unless
+'--pass-credentials' is set. In an OCI registry, a values file is an artifact
+of type 'application/vnd.cncf.helm.values.v1+yaml':
+
+    $ helm install -f oci://registry.example.com/values/production:1.0 myredis oci://registry.example.com/charts/redis
+
+With '--decrypt-sops', values files encrypted with SOPS are decrypted. Like
+the sops command, Helm reads age identities from $SOPS_AGE_KEY and
+$SOPS_AGE_KEY_FILE, decrypts with PGP keys through gpg, or the command in
+$SOPS_GPG_EXEC, and with AWS KMS keys through the credentials of the AWS SDK.
+Files encrypted with other cloud KMS keys, such as GCP KMS or Azure Key Vault
+keys, are decrypted with the sops command when it is installed:
+
+    $ helm install --decrypt-sops -f secrets.yaml myredis ./redis
+
+With '--env-substitution', the given environment variables are substituted into
+'${NAME}' and '${NAME:-default}' references in the string values of values
+files. Only the listed variables, which may be patterns, are substituted, and
+'$${NAME}' stands for a literal '${NAME}':
+
+    $ helm install --env-substitution 'CI_*' -f values.yaml myredis ./redis
+
 You can specify the '--set' flag multiple times. The priority will be given to the
 last (right-most) set specified. For example, if both 'bar' and 'newbar' values are
 set for a key called 'foo', the 'newbar' value would take precedence:
@@ -97,6 +127,16 @@ And in the following example, 'foo' is set to '{"key1":"value1","key2":"bar"}':
 
     $ helm install --set-json='foo={"key1":"value1","key2":"value2"}' --set-json='foo.key2="bar"' myredis ./redis
 
+Lists replace the lists of the chart defaults. To add to them or merge with
+them instead, start the list with a '$patch' directive of 'append', 'prepend'
+or 'merge'. With 'merge', the items with the same '$mergeKey' field are merged:
+
+    env:
+    - $patch: merge
+      $mergeKey: name
+    - name: LOG_LEVEL
+      value: debug
+
 To check the generated manifests of a release without installing the chart,
 the --debug and --dry-run flags can be combined.
 
@@ -107,6 +147,11 @@ which can contain sensitive values. To hide Kubernetes Secrets use the
 If --verify is set, the chart MUST have a provenance file, and the provenance
 file MUST pass all verification steps.
 
+Charts signed with 'helm package --sign-keyless' are verified against their
+Sigstore bundle instead when --certificate-identity is set. The chart must be
+signed by that identity, authenticated by --certificate-oidc-issuer, and the
+signature must chain to the Sigstore trusted root given with --trusted-root.
+
 There are six different ways you can express the chart you want to install:
 
 1. By chart reference: helm install mymaria example/mariadb
@@ -176,6 +221,8 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	// it is added separately
 	f := cmd.Flags()
 	f.BoolVar(&client.HideSecret, "hide-secret", false, "hide Kubernetes Secrets when also using the --dry-run flag")
+	f.BoolVar(&cfg.CaptureHookLogs, "capture-hook-logs", false, "keep the logs of the Job and Pod hooks in the release record even when they succeed. The logs of failed hooks are always kept")
+	f.BoolVar(&cfg.WaitForHookDeletion, "wait-for-hook-deletion", false, "wait for deleted hook resources, and the pods of deleted hook Jobs, to be gone before going on, even without --wait")
 	bindOutputFlag(cmd, &outfmt)
 	bindPostRenderFlag(cmd, &client.PostRenderer)
 
@@ -184,6 +231,8 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Install, valueOpts *values.Options) {
 	f.BoolVar(&client.CreateNamespace, "create-namespace", false, "create the release namespace if not present")
+	f.StringToStringVar(&client.NamespaceLabels, "create-namespace-labels", nil, "labels to add to the namespace created with --create-namespace. Should be divided by comma.")
+	f.StringToStringVar(&client.NamespaceAnnotations, "create-namespace-annotations", nil, "annotations to add to the namespace created with --create-namespace. Should be divided by comma.")
 	// --dry-run options with expected outcome:
 	// - Not set means no dry run and server is contacted.
 	// - Set with no value, a value of client, or a value of true and the server is not contacted
@@ -193,6 +242,8 @@ func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Instal
 	f.Lookup("dry-run").NoOptDefVal = "client"
 	f.BoolVar(&client.Force, "force", false, "force resource updates through a replacement strategy")
 	f.BoolVar(&client.DisableHooks, "no-hooks", false, "prevent hooks from running during install")
+	f.StringSliceVar(&client.SkipHooks, "skip-hooks", []string{}, "names of the hooks not to run during install, while the other hooks run. Can be specified as a comma-separated list or multiple times")
+	f.IntVar(&client.HookConcurrency, "hook-concurrency", 1, "number of hooks of the same weight to run at the same time during install")
 	f.BoolVar(&client.Replace, "replace", false, "reuse the given name, only if that name is a deleted release which remains in the history. This is unsafe in production")
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
@@ -206,10 +257,26 @@ func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Instal
 	f.BoolVar(&client.SkipCRDs, "skip-crds", false, "if set, no CRDs will be installed. By default, CRDs are installed if not already present")
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
+	f.BoolVar(&client.WarnSchemaViolations, "warn-schema-violations", false, "if set, report the values that don't meet the JSON schema of their chart as warnings of the release instead of failing")
 	f.StringToStringVarP(&client.Labels, "labels", "l", nil, "Labels that would be added to release metadata. Should be divided by comma.")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
+	f.BoolVar(&client.CollectErrors, "collect-errors", false, "if set, render all the templates when one fails and report the errors of all the failing templates")
+	f.BoolVar(&client.CacheIncludes, "cache-includes", false, "if set, cache the output of the include calls with the same template and arguments while rendering, which speeds up charts calling the same helpers many times. Do not set it for charts whose helpers generate random values")
+	f.BoolVar(&client.ParallelRender, "parallel-render", false, "if set, render the templates of the chart concurrently. The templates are rendered one at a time when they modify their values, or call tpl or a custom template function")
+	f.IntVar(&client.LookupBudget, "lookup-budget", 0, "limit the number of objects the lookup function may request from the cluster while rendering. Repeated lookups of the same object are cached and do not count. 0 means no limit")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in install output. Does not affect presence in chart metadata")
-	f.BoolVar(&client.TakeOwnership, "take-ownership", false, "if set, install will ignore the check for helm annotations and take ownership of the existing resources")
+	f.BoolVar(&client.TakeOwnership, "take-ownership", false, "if set, install will adopt the existing resources, unless they are owned by another release")
+	f.BoolVar(&client.PreflightDryRun, "preflight-dry-run", false, "if set, submit all resources with server-side dry-run before installing and report every rejection")
+	f.BoolVar(&client.DryRunApply, "dry-run-apply", false, "with --dry-run=server, submit all resources with server-side dry-run and report the objects the server would store, as mutated by admission webhooks, and every rejection")
+	addPreflightFlag(f, &client.PreflightChecks)
+	f.BoolVar(&client.WaitForDependencies, "wait-for-dependencies", false, "if set, resources annotated with helm.sh/depends-on are created only after the resources they depend on are ready")
+	f.BoolVar(&client.ServerSideApply, "server-side", false, "if set, apply the resources with server-side apply, which tracks the fields owned by Helm on the server. Cannot be used with --force")
+	f.BoolVar(&client.ForceConflicts, "force-conflicts", false, "if set with --server-side, take ownership of the fields owned by other field managers instead of failing on conflicts")
+	f.IntVar(&client.RetryCount, "retries", 0, "number of times to attempt the install again after it fails with a transient error, such as an unavailable admission webhook. The resources created by a failed attempt are deleted before the next one")
+	f.DurationVar(&client.RetryBackoff, "retry-backoff", 5*time.Second, "time to wait before the first install retry, doubled after every attempt")
+	f.Var(&lookupFixturesValue{objs: &client.LookupFixtures}, "lookup-fixtures", "render the lookup function with the objects of a YAML or JSON file, such as one saved with 'kubectl get -o yaml', instead of those of the cluster. Requires --dry-run")
+	f.Var(&capabilitiesFileValue{caps: &client.Capabilities}, "capabilities-file", "render with the Kubernetes version and API versions of a YAML or JSON file, such as one saved with 'helm capabilities', instead of those of the cluster. Requires --dry-run")
+	f.Var(newNamespacePolicyValue(&client.NamespacePolicy), "namespace-policy", "how to handle resources outside of the release namespace. One of: allow, warn, reject. Resources annotated with helm.sh/allow-cross-namespace: \"true\" are always allowed")
 	addValueOptionsFlags(f, valueOpts)
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 	AddWaitFlag(cmd, &client.WaitStrategy)
@@ -250,7 +317,7 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 	slog.Debug("Chart path", "path", cp)
 
 	p := getter.All(settings)
-	vals, err := valueOpts.MergeValues(p)
+	vals, err := valueOpts.MergeValues(p, client.ValuesGetterOptions(chart)...)
 	if err != nil {
 		return nil, err
 	}
@@ -284,6 +351,7 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 					Getters:          p,
 					RepositoryConfig: settings.RepositoryConfig,
 					RepositoryCache:  settings.RepositoryCache,
+					ContentCache:     contentCache(),
 					Debug:            settings.Debug,
 					RegistryClient:   client.GetRegistryClient(),
 				}
@@ -298,6 +366,9 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 				return nil, err
 			}
 		}
+		if err := action.VerifyDependencyContents(cp, chartRequested); err != nil {
+			return nil, errors.Wrap(err, "An error occurred while verifying chart dependencies. You may need to run `helm dependency build` to fetch them again")
+		}
 	}
 
 	client.Namespace = settings.Namespace()
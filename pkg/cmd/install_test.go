@@ -141,6 +141,11 @@ func TestInstall(t *testing.T) {
 			name: "install with verification, valid",
 			cmd:  "install signtest testdata/testcharts/signtest-0.1.0.tgz --verify --keyring testdata/helm-test-key.pub",
 		},
+		{
+			name:      "install with keyless verification, missing bundle",
+			cmd:       "install signtest testdata/testcharts/signtest-0.1.0.tgz --verify --certificate-identity me@example.com --certificate-oidc-issuer https://accounts.example.com --trusted-root testdata/trusted_root.json",
+			wantError: true,
+		},
 		// Install, chart with missing dependencies in /charts
 		{
 			name:      "install chart with missing dependencies",
@@ -231,6 +236,12 @@ func TestInstall(t *testing.T) {
 			cmd:    "install schema testdata/testcharts/chart-with-schema-and-subchart --set lastname=doe --set subchart-with-schema.age=-25 --skip-schema-validation",
 			golden: "output/schema.txt",
 		},
+		// Install, values from cli, schematized with errors but only warn about them, expect success
+		{
+			name:   "install with schema file and schematized subchart, extra values from cli, warn about schema violations",
+			cmd:    "install schema testdata/testcharts/chart-with-schema-and-subchart --set lastname=doe --set subchart-with-schema.age=-25 --warn-schema-violations",
+			golden: "output/schema-warnings.txt",
+		},
 		// Install deprecated chart
 		{
 			name:   "install with warning about deprecated chart",
@@ -274,6 +285,12 @@ func TestInstall(t *testing.T) {
 			wantError: true,
 			golden:    "output/install-hide-secret.txt",
 		},
+		{
+			name:      "dry-run-apply error without server dry-run",
+			cmd:       "install secrets testdata/testcharts/chart-with-secret --dry-run --dry-run-apply",
+			wantError: true,
+			golden:    "output/install-dry-run-apply.txt",
+		},
 	}
 
 	runTestCmd(t, tests)
@@ -30,6 +30,8 @@ import (
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/cli/values"
 	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/helmpath"
+	"helm.sh/helm/v4/pkg/lint"
 	"helm.sh/helm/v4/pkg/lint/support"
 )
 
@@ -40,12 +42,22 @@ the chart is well-formed.
 If the linter encounters things that will cause the chart to fail installation,
 it will emit [ERROR] messages. If it encounters issues that break with convention
 or recommendation, it will emit [WARNING] messages.
+
+Organizations can enforce their own rules, such as required labels or resource
+limits, with rule sets built as Go plugins. A plugin exports a 'LintRules'
+function returning the rules to run, and is loaded with '--rules-plugin'.
+
+With '--kube-schemas', the rendered manifests are also validated against the
+Kubernetes API schemas of '--kube-version', catching misspelled or misplaced
+fields without a cluster.
 `
 
 func newLintCmd(out io.Writer) *cobra.Command {
 	client := action.NewLint()
 	valueOpts := &values.Options{}
 	var kubeVersion string
+	var rulePlugins []string
+	var kubeSchemas kubeSchemaOptions
 
 	cmd := &cobra.Command{
 		Use:   "lint PATH",
@@ -80,6 +92,17 @@ func newLintCmd(out io.Writer) *cobra.Command {
 				}
 			}
 
+			for _, p := range rulePlugins {
+				rules, err := lint.LoadPlugin(p)
+				if err != nil {
+					return err
+				}
+				client.Rules = append(client.Rules, rules...)
+			}
+			if kubeSchemas.enabled {
+				client.Rules = append(client.Rules, lint.KubeSchemaRule(kubeSchemas.location, helmpath.CachePath("kubeschemas")))
+			}
+
 			client.Namespace = settings.Namespace()
 			vals, err := valueOpts.MergeValues(getter.All(settings))
 			if err != nil {
@@ -150,6 +173,8 @@ func newLintCmd(out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Quiet, "quiet", false, "print only warnings and errors")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
 	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for capabilities and deprecation checks")
+	f.StringSliceVar(&rulePlugins, "rules-plugin", []string{}, "path to a Go plugin providing additional lint rules (can specify multiple)")
+	addKubeSchemaFlags(f, &kubeSchemas)
 	addValueOptionsFlags(f, valueOpts)
 
 	return cmd
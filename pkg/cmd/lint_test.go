@@ -95,3 +95,22 @@ func TestLintFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "lint", true)
 	checkFileCompletion(t, "lint mypath", true) // Multiple paths can be given
 }
+
+func TestLintCmdWithRulesPlugin(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "lint with a missing rules plugin",
+		cmd:       "lint --rules-plugin testdata/does-not-exist.so testdata/testcharts/alpine",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithKubeSchemas(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "lint chart with manifests not matching the Kubernetes schemas",
+		cmd:       "lint --kube-schemas --kube-schema-location testdata/kubeschemas testdata/testcharts/issue-totoml",
+		golden:    "output/lint-kube-schemas.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
@@ -49,6 +49,13 @@ Only items that match the filter will be returned.
     NAME                UPDATED                                  CHART
     maudlin-arachnid    2020-06-18 14:17:46.125134977 +0000 UTC  alpine-0.1.0
 
+Filters may instead be field=value terms, separated by spaces or commas, on the
+'name', 'chart', 'version' (of the chart) and 'status' fields of the latest
+revision of releases. The storage driver then only reads the matching releases,
+rather than every release:
+
+    $ helm list --filter 'chart=nginx status=failed'
+
 If no results are found, 'helm list' will exit 0, but with no output (or in
 the case of no '-q' flag, only headers).
 
@@ -125,7 +132,8 @@ func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVarP(&client.AllNamespaces, "all-namespaces", "A", false, "list releases across all namespaces")
 	f.IntVarP(&client.Limit, "max", "m", 256, "maximum number of releases to fetch")
 	f.IntVar(&client.Offset, "offset", 0, "next release index in the list, used to offset from start value")
-	f.StringVarP(&client.Filter, "filter", "f", "", "a regular expression (Perl compatible). Any releases that match the expression will be included in the results")
+	f.IntVar(&client.ChunkSize, "chunk-size", 500, "number of stored releases to read at a time from storage backends that support it, such as secret, configmap and sql. 0 reads all releases at once")
+	f.StringVarP(&client.Filter, "filter", "f", "", "a regular expression (Perl compatible), or field=value terms on the name, chart, version and status fields. Any releases that match the filter will be included in the results")
 	f.StringVarP(&client.Selector, "selector", "l", "", "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2). Works only for secret(default) and configmap storage backends.")
 	bindOutputFlag(cmd, &outfmt)
 
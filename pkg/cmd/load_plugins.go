@@ -31,7 +31,9 @@ import (
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
 
+	"helm.sh/helm/v4/pkg/engine"
 	"helm.sh/helm/v4/pkg/plugin"
+	"helm.sh/helm/v4/pkg/plugin/wasm"
 )
 
 const (
@@ -66,6 +68,14 @@ func loadPlugins(baseCmd *cobra.Command, out io.Writer) {
 	for _, plug := range found {
 		plug := plug
 		md := plug.Metadata
+		if md.TemplateFunctions != nil {
+			if err := wasm.RegisterPlugin(engine.DefaultFuncRegistry, plug); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load the template functions of plugin %q: %s\n", md.Name, err)
+			}
+			if !plug.HasCommand() {
+				continue
+			}
+		}
 		if md.Usage == "" {
 			md.Usage = fmt.Sprintf("the %q plugin", md.Name)
 		}
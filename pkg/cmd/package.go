@@ -21,10 +21,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
+	"helm.sh/helm/v4/internal/sigstore"
 	"helm.sh/helm/v4/pkg/action"
 	"helm.sh/helm/v4/pkg/cli/values"
 	"helm.sh/helm/v4/pkg/downloader"
@@ -45,6 +48,29 @@ provide '--keyring path/to/secret/keys' and '--key keyname'.
 
 If '--keyring' is not specified, Helm usually defaults to the public keyring
 unless your environment is otherwise configured.
+
+Charts can also be signed without a long-lived key, using Sigstore. With
+'--sign-keyless', a short-lived certificate is issued by Fulcio for the OIDC
+identity token given with '--identity-token' or the SIGSTORE_ID_TOKEN
+environment variable, and the signature is recorded in the Rekor transparency
+log. The signature is written next to the archive, for example
+'mychart-0.1.0.tgz.sigstore.json', and is attached to the chart by 'helm push'.
+
+  $ helm package --sign-keyless ./mychart
+
+Packaging is reproducible: the same chart always produces a byte-identical
+archive. Files are stored in a stable order with normalized permissions and a
+fixed timestamp. The timestamp defaults to the Unix epoch and can be set with
+the SOURCE_DATE_EPOCH environment variable.
+
+  $ SOURCE_DATE_EPOCH=$(git log -1 --format=%ct) helm package ./mychart
+
+To generate a software bill of materials, use the '--sbom' flag with either
+'spdx' or 'cyclonedx'. The SBOM lists the files in the archive, the chart's
+dependencies and the container images referenced by the manifests rendered
+with the default values. It is written next to the archive, for example
+'mychart-0.1.0.tgz.spdx.json', and can be attached to the chart with
+'helm push --sbom'.
 `
 
 func newPackageCmd(out io.Writer) *cobra.Command {
@@ -67,6 +93,17 @@ func newPackageCmd(out io.Writer) *cobra.Command {
 					return errors.New("--keyring is required for signing a package")
 				}
 			}
+			if client.SignKeyless && client.IdentityToken == "" {
+				client.IdentityToken = os.Getenv("SIGSTORE_ID_TOKEN")
+				if client.IdentityToken == "" {
+					return errors.New("--identity-token or SIGSTORE_ID_TOKEN is required for keyless signing")
+				}
+			}
+			modTime, err := sourceDateEpoch()
+			if err != nil {
+				return err
+			}
+			client.ModTime = modTime
 			client.RepositoryConfig = settings.RepositoryConfig
 			client.RepositoryCache = settings.RepositoryCache
 			p := getter.All(settings)
@@ -100,6 +137,7 @@ func newPackageCmd(out io.Writer) *cobra.Command {
 						RegistryClient:   registryClient,
 						RepositoryConfig: settings.RepositoryConfig,
 						RepositoryCache:  settings.RepositoryCache,
+						ContentCache:     contentCache(),
 					}
 
 					if err := downloadManager.Update(); err != nil {
@@ -121,9 +159,14 @@ func newPackageCmd(out io.Writer) *cobra.Command {
 	f.StringVar(&client.Key, "key", "", "name of the key to use when signing. Used if --sign is true")
 	f.StringVar(&client.Keyring, "keyring", defaultKeyring(), "location of a public keyring")
 	f.StringVar(&client.PassphraseFile, "passphrase-file", "", `location of a file which contains the passphrase for the signing key. Use "-" in order to read from stdin.`)
+	f.BoolVar(&client.SignKeyless, "sign-keyless", false, "sign the package with a short-lived Sigstore certificate for an OIDC identity")
+	f.StringVar(&client.IdentityToken, "identity-token", "", "OIDC identity token to sign with. Used if --sign-keyless is true")
+	f.StringVar(&client.FulcioURL, "fulcio-url", sigstore.DefaultFulcioURL, "address of the Fulcio certificate authority. Used if --sign-keyless is true")
+	f.StringVar(&client.RekorURL, "rekor-url", sigstore.DefaultRekorURL, "address of the Rekor transparency log. Used if --sign-keyless is true")
 	f.StringVar(&client.Version, "version", "", "set the version on the chart to this semver version")
 	f.StringVar(&client.AppVersion, "app-version", "", "set the appVersion on the chart to this version")
 	f.StringVarP(&client.Destination, "destination", "d", ".", "location to write the chart.")
+	f.StringVar(&client.SBOMFormat, "sbom", "", `write a software bill of materials next to the chart archive, in the "spdx" or "cyclonedx" format`)
 	f.BoolVarP(&client.DependencyUpdate, "dependency-update", "u", false, `update dependencies from "Chart.yaml" to dir "charts/" before packaging`)
 	f.StringVar(&client.Username, "username", "", "chart repository username where to locate the requested chart")
 	f.StringVar(&client.Password, "password", "", "chart repository password where to locate the requested chart")
@@ -133,5 +176,23 @@ func newPackageCmd(out io.Writer) *cobra.Command {
 	f.BoolVar(&client.PlainHTTP, "plain-http", false, "use insecure HTTP connections for the chart download")
 	f.StringVar(&client.CaFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
 
+	cmd.RegisterFlagCompletionFunc("sbom", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"cyclonedx", "spdx"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
 	return cmd
 }
+
+// sourceDateEpoch returns the time set by the SOURCE_DATE_EPOCH environment
+// variable, or the zero time if it is not set.
+func sourceDateEpoch() (time.Time, error) {
+	v, ok := os.LookupEnv("SOURCE_DATE_EPOCH")
+	if !ok || v == "" {
+		return time.Time{}, nil
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec < 0 {
+		return time.Time{}, errors.Errorf("invalid SOURCE_DATE_EPOCH %q: must be a non-negative number of seconds since the Unix epoch", v)
+	}
+	return time.Unix(sec, 0), nil
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -90,6 +91,13 @@ func TestPackage(t *testing.T) {
 			expect:  "",
 			hasfile: "alpine-0.1.0.tgz",
 		},
+		{
+			name:   "package --sign-keyless, no identity token",
+			args:   []string{"testdata/testcharts/alpine"},
+			flags:  map[string]string{"sign-keyless": "1"},
+			expect: "--identity-token or SIGSTORE_ID_TOKEN is required for keyless signing",
+			err:    true,
+		},
 		{
 			name:    "package testdata/testcharts/chart-missing-deps",
 			args:    []string{"testdata/testcharts/chart-missing-deps"},
@@ -103,6 +111,7 @@ func TestPackage(t *testing.T) {
 		},
 	}
 
+	t.Setenv("SIGSTORE_ID_TOKEN", "")
 	origDir, err := os.Getwd()
 	if err != nil {
 		t.Fatal(err)
@@ -191,6 +200,58 @@ func TestSetAppVersion(t *testing.T) {
 	}
 }
 
+func TestPackageReproducible(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	chartToPackage := "testdata/testcharts/alpine"
+
+	var archives [][]byte
+	for i := 0; i < 2; i++ {
+		dir := t.TempDir()
+		cmd := fmt.Sprintf("package %s --destination=%s", chartToPackage, dir)
+		if _, output, err := executeActionCommand(cmd); err != nil {
+			t.Logf("Output: %s", output)
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "alpine-0.1.0.tgz"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, data)
+	}
+	if !bytes.Equal(archives[0], archives[1]) {
+		t.Error("expected packaging the same chart twice to produce identical archives")
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	_, _, err := executeActionCommand(fmt.Sprintf("package %s --destination=%s", chartToPackage, t.TempDir()))
+	if err == nil || !strings.Contains(err.Error(), "invalid SOURCE_DATE_EPOCH") {
+		t.Errorf("expected an invalid SOURCE_DATE_EPOCH error, got %v", err)
+	}
+}
+
+func TestPackageSBOM(t *testing.T) {
+	dir := t.TempDir()
+	cmd := fmt.Sprintf("package testdata/testcharts/alpine --destination=%s --sbom=cyclonedx", dir)
+	if _, output, err := executeActionCommand(cmd); err != nil {
+		t.Logf("Output: %s", output)
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "alpine-0.1.0.tgz.cdx.json"))
+	if err != nil {
+		t.Fatalf("expected an SBOM next to the chart archive: %s", err)
+	}
+	for _, expect := range []string{`"bomFormat": "CycloneDX"`, `"bom-ref": "image:alpine:3.9"`, `"name": "alpine/templates/alpine-pod.yaml"`} {
+		if !strings.Contains(string(data), expect) {
+			t.Errorf("expected SBOM to contain %s, got:\n%s", expect, data)
+		}
+	}
+
+	cmd = fmt.Sprintf("package testdata/testcharts/alpine --destination=%s --sbom=swid", dir)
+	if _, _, err := executeActionCommand(cmd); err == nil || !strings.Contains(err.Error(), "unknown SBOM format") {
+		t.Errorf("expected an unknown SBOM format error, got %v", err)
+	}
+}
+
 func TestPackageFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "package", true)
 	checkFileCompletion(t, "package mypath", true) // Multiple paths can be given
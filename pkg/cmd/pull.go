@@ -41,6 +41,8 @@ directory for the chart and uncompress into that directory.
 If the --verify flag is specified, the requested chart MUST have a provenance
 file, and MUST pass the verification process. Failure in any part of this will
 result in an error, and the chart will not be saved locally.
+With --certificate-identity, the chart's keyless Sigstore signature is verified
+in place of its provenance file. See 'helm verify --help' for details.
 `
 
 func newPullCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -31,7 +31,12 @@ const pushDesc = `
 Upload a chart to a registry.
 
 If the chart has an associated provenance file,
-it will also be uploaded.
+it will also be uploaded. A Sigstore signature written by
+'helm package --sign-keyless' is attached to the pushed chart as a
+referring artifact.
+
+With '--sbom', the SBOMs written by 'helm package --sbom' next to the chart
+archive are attached to the pushed chart as referring artifacts.
 `
 
 type registryPushOptions struct {
@@ -40,6 +45,7 @@ type registryPushOptions struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	sbom                  bool
 	password              string
 	username              string
 }
@@ -84,6 +90,7 @@ func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				action.WithTLSClientConfig(o.certFile, o.keyFile, o.caFile),
 				action.WithInsecureSkipTLSVerify(o.insecureSkipTLSverify),
 				action.WithPlainHTTP(o.plainHTTP),
+				action.WithSBOM(o.sbom),
 				action.WithPushOptWriter(out))
 			client.Settings = settings
 			output, err := client.Run(chartRef, remote)
@@ -101,6 +108,7 @@ func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
 	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the chart upload")
 	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the chart upload")
+	f.BoolVar(&o.sbom, "sbom", false, "attach the SBOMs written next to the chart archive by 'helm package --sbom'")
 	f.StringVar(&o.username, "username", "", "chart repository username where to locate the requested chart")
 	f.StringVar(&o.password, "password", "", "chart repository password where to locate the requested chart")
 
@@ -19,15 +19,19 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"os"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v4/pkg/action"
 	"helm.sh/helm/v4/pkg/cli/output"
+	"helm.sh/helm/v4/pkg/cli/values"
 	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/getter"
 )
 
 const releaseTestHelp = `
@@ -35,6 +39,11 @@ The test command runs the tests for a release.
 
 The argument this command takes is the name of a deployed release.
 The tests to be run are defined in the chart that was installed.
+
+Values can be overridden with '--values' and '--set' to run the tests against
+another scenario, such as an external endpoint. The tests are then rendered
+again from the chart of the release with the values of the release and the
+overrides, and run without upgrading the release.
 `
 
 func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -42,6 +51,8 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 	outfmt := output.Table
 	var outputLogs bool
 	var filter []string
+	var junitReport, jsonReport string
+	valueOpts := &values.Options{}
 
 	cmd := &cobra.Command{
 		Use:   "test [RELEASE]",
@@ -64,6 +75,11 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 					client.Filters[action.ExcludeNameFilter] = append(client.Filters[action.ExcludeNameFilter], notName.ReplaceAllLiteralString(f, ""))
 				}
 			}
+			vals, err := valueOpts.MergeValues(getter.All(settings))
+			if err != nil {
+				return err
+			}
+			client.Values = vals
 			rel, runErr := client.Run(args[0])
 			// We only return an error if we weren't even able to get the
 			// release, otherwise we keep going so we can print status and logs
@@ -89,6 +105,19 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 				}
 			}
 
+			if junitReport != "" || jsonReport != "" {
+				results, err := client.Results(rel, outputLogs)
+				if err != nil {
+					return err
+				}
+				if err := writeTestReport(junitReport, results.WriteJUnit); err != nil {
+					return err
+				}
+				if err := writeTestReport(jsonReport, results.WriteJSON); err != nil {
+					return err
+				}
+			}
+
 			return runErr
 		},
 	}
@@ -97,7 +126,28 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.BoolVar(&outputLogs, "logs", false, "dump the logs from test pods (this runs after all tests are complete, but before any cleanup)")
 	f.StringSliceVar(&filter, "filter", []string{}, "specify tests by attribute (currently \"name\") using attribute=value syntax or '!attribute=value' to exclude a test (can specify multiple or separate values with commas: name=test1,name=test2)")
+	f.StringVarP(&client.LabelSelector, "selector", "l", "", "run only the tests whose labels match the selector (e.g. -l key1=value1,key2=value2). Works with --filter")
+	f.IntVar(&client.Parallelism, "parallel", 1, "number of tests of the same weight to run at the same time")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in test output. Does not affect presence in chart metadata")
+	f.StringVar(&junitReport, "junit-report", "", "write the results of the tests to the given file as a JUnit XML report. The report includes the logs of the test pods with --logs")
+	f.StringVar(&jsonReport, "json-report", "", "write the results of the tests to the given file as JSON. The report includes the logs of the test pods with --logs")
+	addValueOptionsFlags(f, valueOpts)
 
 	return cmd
 }
+
+// writeTestReport writes a test report to a file, unless no file is given.
+func writeTestReport(filename string, write func(io.Writer) error) error {
+	if filename == "" {
+		return nil
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return errors.Wrapf(err, "unable to write test report %s", filename)
+	}
+	return f.Close()
+}
@@ -80,10 +80,15 @@ func newRollbackCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Recreate, "recreate-pods", false, "performs pods restart for the resource if applicable")
 	f.BoolVar(&client.Force, "force", false, "force resource update through delete/recreate if needed")
 	f.BoolVar(&client.DisableHooks, "no-hooks", false, "prevent hooks from running during rollback")
+	f.StringSliceVar(&client.SkipHooks, "skip-hooks", []string{}, "names of the hooks not to run during rollback, while the other hooks run. Can be specified as a comma-separated list or multiple times")
+	f.IntVar(&client.HookConcurrency, "hook-concurrency", 1, "number of hooks of the same weight to run at the same time during rollback")
+	f.BoolVar(&cfg.CaptureHookLogs, "capture-hook-logs", false, "keep the logs of the Job and Pod hooks in the release record even when they succeed. The logs of failed hooks are always kept")
+	f.BoolVar(&cfg.WaitForHookDeletion, "wait-for-hook-deletion", false, "wait for deleted hook resources, and the pods of deleted hook Jobs, to be gone before going on, even without --wait")
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.CleanupOnFail, "cleanup-on-fail", false, "allow deletion of new resources created in this rollback when rollback fails")
 	f.IntVar(&client.MaxHistory, "history-max", settings.MaxHistory, "limit the maximum number of revisions saved per release. Use 0 for no limit")
+	f.DurationVar(&client.MaxHistoryAge, "history-max-age", settings.MaxHistoryAge, "remove the revisions of the release last deployed longer ago than this, such as 720h, always keeping the last deployed revision. Use 0 for no limit")
 	AddWaitFlag(cmd, &client.WaitStrategy)
 
 	return cmd
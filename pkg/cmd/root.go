@@ -60,8 +60,23 @@ Environment variables:
 | $HELM_CONFIG_HOME                  | set an alternative location for storing Helm configuration.                                                |
 | $HELM_DATA_HOME                    | set an alternative location for storing Helm data.                                                         |
 | $HELM_DEBUG                        | indicate whether or not Helm is running in Debug mode                                                      |
-| $HELM_DRIVER                       | set the backend storage driver. Values are: configmap, secret, memory, sql.                                |
+| $HELM_DRIVER                       | set the backend storage driver. Values are: configmap, secret, memory, sql, etcd, objectstore, filesystem. |
+| $HELM_DRIVER_COMPRESSION           | set the compression of stored releases. Values are: gzip (default), zstd (unreadable by older Helm).       |
+| $HELM_DRIVER_ENCRYPTION_KEY_URL    | set the URL of the key encrypting stored releases: local:///<file>, vault://<key>, awskms://<key>.         |
 | $HELM_DRIVER_SQL_CONNECTION_STRING | set the connection string the SQL storage driver should use.                                               |
+| $HELM_DRIVER_SQL_MAX_OPEN_CONNS    | set the maximum number of open connections of the SQL storage driver.                                      |
+| $HELM_DRIVER_SQL_MAX_IDLE_CONNS    | set the maximum number of idle connections of the SQL storage driver.                                      |
+| $HELM_DRIVER_SQL_CONN_MAX_LIFETIME | set the maximum amount of time the SQL storage driver reuses a connection, such as 30m.                    |
+| $HELM_DRIVER_SQL_CONN_IDLE_TIMEOUT | set the maximum amount of time a connection of the SQL storage driver stays idle.                          |
+| $HELM_DRIVER_ETCD_ENDPOINTS        | set the comma-separated endpoints the etcd storage driver should use.                                      |
+| $HELM_DRIVER_ETCD_PREFIX           | set the key prefix of the etcd storage driver (default "/helm/releases").                                  |
+| $HELM_DRIVER_ETCD_CERT_FILE        | set the client certificate file of the etcd storage driver.                                                |
+| $HELM_DRIVER_ETCD_KEY_FILE         | set the client key file of the etcd storage driver.                                                        |
+| $HELM_DRIVER_ETCD_CA_FILE          | set the certificate authority file of the etcd storage driver.                                             |
+| $HELM_DRIVER_ETCD_USERNAME         | set the user name of the etcd storage driver, when etcd authentication is enabled.                         |
+| $HELM_DRIVER_ETCD_PASSWORD         | set the password of the etcd storage driver, when etcd authentication is enabled.                          |
+| $HELM_DRIVER_OBJECT_STORE_URL      | set the bucket URL of the object storage driver, such as s3://<bucket>/<prefix>.                           |
+| $HELM_DRIVER_FILESYSTEM_DIR        | set the directory of the filesystem storage driver (default "$HELM_DATA_HOME/releases").                   |
 | $HELM_MAX_HISTORY                  | set the maximum number of helm release history.                                                            |
 | $HELM_NAMESPACE                    | set the namespace used for the helm operations.                                                            |
 | $HELM_NO_PLUGINS                   | disable plugins. Set HELM_NO_PLUGINS=1 to disable plugins.                                                 |
@@ -218,6 +233,7 @@ func newRootCmdWithConfig(actionConfig *action.Configuration, out io.Writer, arg
 
 		// release commands
 		newGetCmd(actionConfig, out),
+		newCapabilitiesCmd(actionConfig, out),
 		newHistoryCmd(actionConfig, out),
 		newInstallCmd(actionConfig, out),
 		newListCmd(actionConfig, out),
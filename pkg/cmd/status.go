@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubectl/pkg/cmd/get"
@@ -45,7 +46,9 @@ The status consists of:
 - revision of the release
 - description of the release (can be completion message or error message)
 - list of resources that this release consists of
+- health of the live resources, read from the cluster, with --show-health
 - details on last test suite run, if applicable
+- logs kept from the hooks, such as the logs of a failed hook
 - additional notes provided by the chart
 `
 
@@ -91,6 +94,9 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f := cmd.Flags()
 
 	f.IntVar(&client.Version, "revision", 0, "if set, display the status of the named release with revision")
+	f.BoolVar(&client.ShowHealth, "show-health", false, "query the cluster for the health of the resources of the release")
+	f.BoolVar(&client.TableOptions.Wide, "wide", false, "include the additional columns of wide output when displaying resources as a table")
+	f.StringSliceVarP(&client.TableOptions.LabelColumns, "label-columns", "L", []string{}, "label keys to display as additional columns when displaying resources as a table")
 
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
@@ -167,6 +173,15 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		_, _ = fmt.Fprintf(out, "RESOURCES:\n%s\n", buf.String())
 	}
 
+	if len(s.release.Info.Health) > 0 {
+		tbl := uitable.New()
+		tbl.AddRow("KIND", "NAMESPACE", "NAME", "HEALTH", "MESSAGE")
+		for _, h := range s.release.Info.Health {
+			tbl.AddRow(h.Kind, h.Namespace, h.Name, h.Status, h.Message)
+		}
+		_, _ = fmt.Fprintf(out, "RESOURCE HEALTH:\n%s\n\n", tbl)
+	}
+
 	executions := executionsByHookEvent(s.release)
 	if tests, ok := executions[release.HookTest]; !ok || len(tests) == 0 {
 		_, _ = fmt.Fprintln(out, "TEST SUITE: None")
@@ -185,6 +200,18 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		}
 	}
 
+	printed := false
+	for _, h := range s.release.Hooks {
+		if h.LastRun.Logs == "" {
+			continue
+		}
+		if !printed {
+			_, _ = fmt.Fprintln(out, "HOOK LOGS:")
+			printed = true
+		}
+		_, _ = fmt.Fprintf(out, "# %s (%s)\n%s\n", h.Name, h.LastRun.Phase, strings.TrimRight(h.LastRun.Logs, "\n"))
+	}
+
 	if s.debug {
 		_, _ = fmt.Fprintln(out, "USER-SUPPLIED VALUES:")
 		err := output.EncodeYAML(out, s.release.Config)
@@ -208,6 +235,17 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		_, _ = fmt.Fprintln(out)
 	}
 
+	if dryRun := s.release.Info.DryRun; dryRun != nil && len(dryRun.Rejections) > 0 {
+		_, _ = fmt.Fprintln(out, "DRY-RUN REJECTIONS:")
+		for _, r := range dryRun.Rejections {
+			name := r.Name
+			if r.Namespace != "" {
+				name = r.Namespace + "/" + r.Name
+			}
+			_, _ = fmt.Fprintf(out, "[%s] %s: %s\n", r.Kind, name, r.Reason)
+		}
+	}
+
 	if strings.EqualFold(s.release.Info.Description, "Dry run complete") || s.debug {
 		_, _ = fmt.Fprintln(out, "HOOKS:")
 		for _, h := range s.release.Hooks {
@@ -216,6 +254,13 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		_, _ = fmt.Fprintf(out, "MANIFEST:\n%s\n", s.release.Manifest)
 	}
 
+	if warnings := s.release.Info.Warnings; len(warnings) > 0 {
+		_, _ = fmt.Fprintln(out, "WARNINGS:")
+		for _, w := range warnings {
+			_, _ = fmt.Fprintf(out, "- %s\n", w)
+		}
+	}
+
 	// Hide notes from output - option in install and upgrades
 	if !s.hideNotes && len(s.release.Info.Notes) > 0 {
 		fmt.Fprintf(out, "NOTES:\n%s\n", strings.TrimSpace(s.release.Info.Notes))
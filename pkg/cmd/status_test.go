@@ -68,6 +68,17 @@ func TestStatusCmd(t *testing.T) {
 			Status: release.StatusDeployed,
 			Notes:  "release notes",
 		}),
+	}, {
+		name:   "get status of a deployed release with resource health",
+		cmd:    "status flummoxed-chickadee",
+		golden: "output/status-with-health.txt",
+		rels: releasesMockWithStatus(&release.Info{
+			Status: release.StatusDeployed,
+			Health: []release.ResourceHealth{
+				{Kind: "Deployment", Namespace: "default", Name: "web", Status: "InProgress", Message: "Replicas: 1/2"},
+				{Kind: "Service", Namespace: "default", Name: "web", Status: "Ready", Message: "Service is ready"},
+			},
+		}),
 	}, {
 		name:   "get status of a deployed release with resources",
 		cmd:    "status flummoxed-chickadee",
@@ -126,6 +137,26 @@ func TestStatusCmd(t *testing.T) {
 				},
 			},
 		),
+	}, {
+		name:   "get status of a failed release with hook logs",
+		cmd:    "status flummoxed-chickadee",
+		golden: "output/status-with-hook-logs.txt",
+		rels: releasesMockWithStatus(
+			&release.Info{
+				Status:      release.StatusFailed,
+				Description: "failed pre-upgrade: job db-migrate failed: BackoffLimitExceeded",
+			},
+			&release.Hook{
+				Name:   "db-migrate",
+				Events: []release.HookEvent{release.HookPreUpgrade},
+				LastRun: release.HookExecution{
+					StartedAt:   mustParseTime("2006-01-02T15:00:05Z"),
+					CompletedAt: mustParseTime("2006-01-02T15:00:07Z"),
+					Phase:       release.HookPhaseFailed,
+					Logs:        "==> db-migrate-x7k2p/migrate <==\napplying 0042_add_index\nerror: relation \"users\" does not exist\n",
+				},
+			},
+		),
 	}}
 	runTestCmd(t, tests)
 }
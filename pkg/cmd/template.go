@@ -32,10 +32,12 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"helm.sh/helm/v4/internal/kubeschema"
 	"helm.sh/helm/v4/pkg/action"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/cli/values"
 	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/helmpath"
 	releaseutil "helm.sh/helm/v4/pkg/release/util"
 )
 
@@ -45,10 +47,29 @@ Render chart templates locally and display the output.
 Any values that would normally be looked up or retrieved in-cluster will be
 faked locally. Additionally, none of the server-side testing of chart validity
 (e.g. whether an API is supported) is done.
+
+To catch misspelled or misplaced fields without a cluster, use --kube-schemas
+to validate the rendered manifests against the Kubernetes API schemas of
+--kube-version.
+
+To render as a specific cluster would, use --capabilities-file with the
+Kubernetes version and API versions saved from it by 'helm capabilities'.
+They are used for .Capabilities in templates, for the kubeVersion constraint of
+the chart and for --kube-schemas.
+
+The lookup function finds no objects when rendering locally. To render charts
+that rely on it deterministically, use --lookup-fixtures with the objects it
+should find, such as the output of 'kubectl get -o yaml'.
+
+With --validate, the manifests are validated against the cluster you are
+currently pointing at: the resource kinds must be served by the cluster and
+match its OpenAPI schemas. '--validate=server' also submits them with
+server-side dry-run, reporting every resource the API server or its admission
+webhooks reject. Nothing is created in either case.
 `
 
 func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
-	var validate bool
+	var validate string
 	var includeCrds bool
 	var skipTests bool
 	client := action.NewInstall(cfg)
@@ -56,6 +77,7 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	var kubeVersion string
 	var extraAPIs []string
 	var showFiles []string
+	var kubeSchemas kubeSchemaOptions
 
 	cmd := &cobra.Command{
 		Use:   "template [NAME] [CHART]",
@@ -90,7 +112,17 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			client.DryRun = true
 			client.ReleaseName = "release-name"
 			client.Replace = true // Skip the name check
-			client.ClientOnly = !validate
+			switch validate {
+			case "false":
+				client.ClientOnly = true
+			case "true":
+				client.ClientOnly = false
+			case "server":
+				client.ClientOnly = false
+				client.ServerValidation = true
+			default:
+				return fmt.Errorf("invalid validate option %q. Valid inputs are false, true, server", validate)
+			}
 			client.APIVersions = chartutil.VersionSet(extraAPIs)
 			client.IncludeCRDs = includeCrds
 			rel, err := runInstall(args, client, valueOpts, out)
@@ -102,6 +134,19 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				return err
 			}
 
+			if rel != nil && kubeSchemas.enabled {
+				schemaVersion := client.KubeVersion
+				if schemaVersion == nil && client.Capabilities != nil {
+					schemaVersion = &client.Capabilities.KubeVersion
+				}
+				if verr := validateKubeSchemas(rel, schemaVersion, kubeSchemas.location); verr != nil {
+					if !settings.Debug {
+						return verr
+					}
+					err = verr
+				}
+			}
+
 			// We ignore a potential error here because, when the --debug flag was specified,
 			// we always want to print the YAML, even if it is not valid. The error is still returned afterwards.
 			if rel != nil {
@@ -194,18 +239,48 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	addInstallFlags(cmd, f, client, valueOpts)
 	f.StringArrayVarP(&showFiles, "show-only", "s", []string{}, "only show manifests rendered from the given templates")
 	f.StringVar(&client.OutputDir, "output-dir", "", "writes the executed templates to files in output-dir instead of stdout")
-	f.BoolVar(&validate, "validate", false, "validate your manifests against the Kubernetes cluster you are currently pointing at. This is the same validation performed on an install")
+	f.StringVar(&validate, "validate", "false", "validate your manifests against the Kubernetes cluster you are currently pointing at. This is the same validation performed on an install. Set to 'server' to also submit them with server-side dry-run, which runs admission webhooks without creating anything")
+	f.Lookup("validate").NoOptDefVal = "true"
 	f.BoolVar(&includeCrds, "include-crds", false, "include CRDs in the templated output")
 	f.BoolVar(&skipTests, "skip-tests", false, "skip tests from templated output")
 	f.BoolVar(&client.IsUpgrade, "is-upgrade", false, "set .Release.IsUpgrade instead of .Release.IsInstall")
 	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for Capabilities.KubeVersion")
-	f.StringSliceVarP(&extraAPIs, "api-versions", "a", []string{}, "Kubernetes api versions used for Capabilities.APIVersions")
+	addKubeSchemaFlags(f, &kubeSchemas)
+	f.StringSliceVarP(&extraAPIs, "api-versions", "a", []string{}, "Kubernetes api versions used for Capabilities.APIVersions and capability-conditional dependencies")
 	f.BoolVar(&client.UseReleaseName, "release-name", false, "use release name in the output-dir path.")
 	bindPostRenderFlag(cmd, &client.PostRenderer)
 
 	return cmd
 }
 
+// validateKubeSchemas validates the manifests and hooks of a release against
+// the Kubernetes API schemas of kubeVersion.
+func validateKubeSchemas(rel *release.Release, kubeVersion *chartutil.KubeVersion, location string) error {
+	if kubeVersion == nil {
+		kubeVersion = &chartutil.DefaultCapabilities.KubeVersion
+	}
+	validator := kubeschema.New(kubeVersion.Major, kubeVersion.Minor, location, helmpath.CachePath("kubeschemas"))
+
+	manifests := []string{rel.Manifest}
+	for _, h := range rel.Hooks {
+		manifests = append(manifests, h.Manifest)
+	}
+	var problems []string
+	for _, m := range manifests {
+		invalid, err := validator.Validate(m)
+		if err != nil {
+			return err
+		}
+		for _, err := range invalid {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("rendered manifests do not match the Kubernetes %s API:\n%s", kubeVersion.Version, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
 func isTestHook(h *release.Hook) bool {
 	return slices.Contains(h.Events, release.HookTest)
 }
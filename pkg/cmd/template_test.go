@@ -98,6 +98,26 @@ func TestTemplateCmd(t *testing.T) {
 			cmd:    fmt.Sprintf("template --api-versions helm.k8s.io/test '%s'", chartPath),
 			golden: "output/template-with-api-version.txt",
 		},
+		{
+			name:   "check capabilities file",
+			cmd:    fmt.Sprintf("template --capabilities-file testdata/capabilities/cluster.yaml '%s'", chartPath),
+			golden: "output/template-with-capabilities-file.txt",
+		},
+		{
+			name:      "check missing capabilities file",
+			cmd:       fmt.Sprintf("template --capabilities-file testdata/capabilities/missing.yaml '%s'", chartPath),
+			wantError: true,
+		},
+		{
+			name:   "check lookup fixtures",
+			cmd:    "template --lookup-fixtures testdata/lookup/fixtures.yaml testdata/testcharts/chart-with-lookup",
+			golden: "output/template-with-lookup-fixtures.txt",
+		},
+		{
+			name:   "check lookup without fixtures",
+			cmd:    "template testdata/testcharts/chart-with-lookup",
+			golden: "output/template-without-lookup-fixtures.txt",
+		},
 		{
 			name:   "template with CRDs",
 			cmd:    fmt.Sprintf("template '%s' --include-crds", chartPath),
@@ -177,6 +197,55 @@ func TestTemplateCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestTemplateCmdValidateOption(t *testing.T) {
+	tests := []cmdTestCase{
+		{
+			name:      "template with an invalid validate option",
+			cmd:       fmt.Sprintf("template '%s' --validate=cluster", chartPath),
+			golden:    "output/template-invalid-validate.txt",
+			wantError: true,
+		},
+	}
+	runTestCmd(t, tests)
+}
+
+func TestTemplateCmdWithKustomize(t *testing.T) {
+	tests := []cmdTestCase{
+		{
+			name:   "template with a kustomize post-renderer",
+			cmd:    "template testdata/testcharts/chart-with-secret --post-renderer-kustomize testdata/kustomize",
+			golden: "output/template-kustomize.txt",
+		},
+		{
+			name:   "template with a kustomize post-renderer chained with an exec post-renderer",
+			cmd:    "template testdata/testcharts/chart-with-secret --post-renderer-kustomize testdata/kustomize --post-renderer cat",
+			golden: "output/template-kustomize.txt",
+		},
+		{
+			name:      "template with a failing post-renderer in a chain",
+			cmd:       "template testdata/testcharts/chart-with-secret --post-renderer-kustomize testdata/kustomize --post-renderer false",
+			wantError: true,
+		},
+	}
+	runTestCmd(t, tests)
+}
+
+func TestTemplateCmdWithKubeSchemas(t *testing.T) {
+	tests := []cmdTestCase{
+		{
+			name: "template with manifests matching the Kubernetes schemas",
+			cmd:  "template testdata/testcharts/chart-with-secret --kube-schemas --kube-schema-location testdata/kubeschemas",
+		},
+		{
+			name:      "template with manifests not matching the Kubernetes schemas",
+			cmd:       "template testdata/testcharts/issue-totoml --kube-schemas --kube-schema-location testdata/kubeschemas",
+			golden:    "output/template-kube-schemas.txt",
+			wantError: true,
+		},
+	}
+	runTestCmd(t, tests)
+}
+
 func TestTemplateVersionCompletion(t *testing.T) {
 	repoFile := "testdata/helmhome/helm/repositories.yaml"
 	repoCache := "testdata/helmhome/helm/repository"
@@ -74,9 +74,11 @@ func newUninstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f := cmd.Flags()
 	f.BoolVar(&client.DryRun, "dry-run", false, "simulate a uninstall")
 	f.BoolVar(&client.DisableHooks, "no-hooks", false, "prevent hooks from running during uninstallation")
+	f.BoolVar(&cfg.WaitForHookDeletion, "wait-for-hook-deletion", false, "wait for deleted hook resources, and the pods of deleted hook Jobs, to be gone before going on, even without --wait")
 	f.BoolVar(&client.IgnoreNotFound, "ignore-not-found", false, `Treat "release not found" as a successful uninstall`)
 	f.BoolVar(&client.KeepHistory, "keep-history", false, "remove all associated resources and mark the release as deleted, but retain the release history")
 	f.StringVar(&client.DeletionPropagation, "cascade", "background", "Must be \"background\", \"orphan\", or \"foreground\". Selects the deletion cascading strategy for the dependents. Defaults to background.")
+	f.StringVar((*string)(&cfg.DeletionOrder), "deletion-order", string(action.DeletionOrderReverseInstall), "order in which the resources are deleted: \"reverse-install\" deletes them in the reverse of the install order, with custom resources first, and \"kind\" deletes them by kind, with custom resources last. The helm.sh/uninstall-weight annotation takes precedence")
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.StringVar(&client.Description, "description", "", "add a custom description")
 	AddWaitFlag(cmd, &client.WaitStrategy)
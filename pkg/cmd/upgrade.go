@@ -85,6 +85,7 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	valueOpts := &values.Options{}
 	var outfmt output.Format
 	var createNamespace bool
+	var namespaceLabels, namespaceAnnotations map[string]string
 
 	cmd := &cobra.Command{
 		Use:   "upgrade [RELEASE] [CHART]",
@@ -130,11 +131,15 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					}
 					instClient := action.NewInstall(cfg)
 					instClient.CreateNamespace = createNamespace
+					instClient.NamespaceLabels = namespaceLabels
+					instClient.NamespaceAnnotations = namespaceAnnotations
 					instClient.ChartPathOptions = client.ChartPathOptions
 					instClient.Force = client.Force
 					instClient.DryRun = client.DryRun
 					instClient.DryRunOption = client.DryRunOption
 					instClient.DisableHooks = client.DisableHooks
+					instClient.SkipHooks = client.SkipHooks
+					instClient.HookConcurrency = client.HookConcurrency
 					instClient.SkipCRDs = client.SkipCRDs
 					instClient.Timeout = client.Timeout
 					instClient.WaitStrategy = client.WaitStrategy
@@ -147,12 +152,25 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					instClient.SubNotes = client.SubNotes
 					instClient.HideNotes = client.HideNotes
 					instClient.SkipSchemaValidation = client.SkipSchemaValidation
+					instClient.WarnSchemaViolations = client.WarnSchemaViolations
 					instClient.Description = client.Description
 					instClient.DependencyUpdate = client.DependencyUpdate
 					instClient.Labels = client.Labels
 					instClient.EnableDNS = client.EnableDNS
+					instClient.CollectErrors = client.CollectErrors
+					instClient.CacheIncludes = client.CacheIncludes
+					instClient.ParallelRender = client.ParallelRender
+					instClient.LookupBudget = client.LookupBudget
+					instClient.LookupFixtures = client.LookupFixtures
 					instClient.HideSecret = client.HideSecret
 					instClient.TakeOwnership = client.TakeOwnership
+					instClient.PreflightDryRun = client.PreflightDryRun
+					instClient.DryRunApply = client.DryRunApply
+					instClient.PreflightChecks = client.PreflightChecks
+					instClient.WaitForDependencies = client.WaitForDependencies
+					instClient.NamespacePolicy = client.NamespacePolicy
+					instClient.ServerSideApply = client.ServerSideApply
+					instClient.ForceConflicts = client.ForceConflicts
 
 					if isReleaseUninstalled(versions) {
 						instClient.Replace = true
@@ -188,7 +206,7 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 
 			p := getter.All(settings)
-			vals, err := valueOpts.MergeValues(p)
+			vals, err := valueOpts.MergeValues(p, client.ValuesGetterOptions(args[1])...)
 			if err != nil {
 				return err
 			}
@@ -210,6 +228,7 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 							Getters:          p,
 							RepositoryConfig: settings.RepositoryConfig,
 							RepositoryCache:  settings.RepositoryCache,
+							ContentCache:     contentCache(),
 							Debug:            settings.Debug,
 						}
 						if err := man.Update(); err != nil {
@@ -223,6 +242,9 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 						return err
 					}
 				}
+				if err := action.VerifyDependencyContents(chartPath, ch); err != nil {
+					return errors.Wrap(err, "An error occurred while verifying chart dependencies. You may need to run `helm dependency build` to fetch them again")
+				}
 			}
 
 			if ch.Metadata.Deprecated {
@@ -264,6 +286,8 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 	f := cmd.Flags()
 	f.BoolVar(&createNamespace, "create-namespace", false, "if --install is set, create the release namespace if not present")
+	f.StringToStringVar(&namespaceLabels, "create-namespace-labels", nil, "if --install and --create-namespace are set, labels to add to the created namespace. Should be divided by comma.")
+	f.StringToStringVar(&namespaceAnnotations, "create-namespace-annotations", nil, "if --install and --create-namespace are set, annotations to add to the created namespace. Should be divided by comma.")
 	f.BoolVarP(&client.Install, "install", "i", false, "if a release by this name doesn't already exist, run an install")
 	f.BoolVar(&client.Devel, "devel", false, "use development versions, too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
 	f.StringVar(&client.DryRunOption, "dry-run", "", "simulate an install. If --dry-run is set with no option being specified or as '--dry-run=client', it will not attempt cluster connections. Setting '--dry-run=server' allows attempting cluster connections.")
@@ -273,7 +297,12 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.MarkDeprecated("recreate-pods", "functionality will no longer be updated. Consult the documentation for other methods to recreate pods")
 	f.BoolVar(&client.Force, "force", false, "force resource updates through a replacement strategy")
 	f.BoolVar(&client.DisableHooks, "no-hooks", false, "disable pre/post upgrade hooks")
+	f.StringSliceVar(&client.SkipHooks, "skip-hooks", []string{}, "names of the pre/post upgrade hooks not to run, while the other hooks run. Can be specified as a comma-separated list or multiple times")
+	f.IntVar(&client.HookConcurrency, "hook-concurrency", 1, "number of hooks of the same weight to run at the same time during upgrade")
+	f.BoolVar(&cfg.CaptureHookLogs, "capture-hook-logs", false, "keep the logs of the Job and Pod hooks in the release record even when they succeed. The logs of failed hooks are always kept")
+	f.BoolVar(&cfg.WaitForHookDeletion, "wait-for-hook-deletion", false, "wait for deleted hook resources, and the pods of deleted hook Jobs, to be gone before going on, even without --wait")
 	f.BoolVar(&client.DisableOpenAPIValidation, "disable-openapi-validation", false, "if set, the upgrade process will not validate rendered templates against the Kubernetes OpenAPI Schema")
+	f.StringVar((*string)(&client.CRDUpgradePolicy), "crd-upgrade-policy", "", "what to do with the CRDs in the crds/ directory of the chart, which are skipped by default. One of: create-only (create missing CRDs), upgrade (also update existing CRDs with server-side apply, refusing destructive changes unless --force is set), fail-on-change (fail when an existing CRD differs from the chart)")
 	f.BoolVar(&client.SkipCRDs, "skip-crds", false, "if set, no CRDs will be installed when an upgrade is performed with install flag enabled. By default, CRDs are installed if not already present, when an upgrade is performed with install flag enabled")
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.BoolVar(&client.ResetValues, "reset-values", false, "when upgrading, reset the values to the ones built into the chart")
@@ -281,16 +310,34 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.ResetThenReuseValues, "reset-then-reuse-values", false, "when upgrading, reset the values to the ones built into the chart, apply the last release's values and merge in any overrides from the command line via --set and -f. If '--reset-values' or '--reuse-values' is specified, this is ignored")
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.Atomic, "atomic", false, "if set, upgrade process rolls back changes made in case of failed upgrade. The --wait flag will be set automatically to \"watcher\" if --atomic is used")
+	f.DurationVar(&client.RollbackTimeout, "rollback-timeout", 0, "time to allow for the rollback performed by --atomic, which still runs when the upgrade is interrupted. Defaults to --timeout for each of its operations without an overall limit")
+	f.IntVar(&client.CheckpointBatchSize, "checkpoint-batch-size", 0, "record the progress of the upgrade in the release after every N applied resources, so that an interrupted upgrade can be resumed with --resume. Use 0 to disable checkpoints")
+	f.BoolVar(&client.Resume, "resume", false, "if the last upgrade of the release was interrupted after recording a checkpoint, resume it from where it stopped instead of upgrading to the given chart and values")
+	f.BoolVar(&client.PruneOrphans, "prune-orphans", false, "delete the resources labeled and annotated as belonging to the release that are missing from its manifest, such as the ones left behind by a failed upgrade")
 	f.IntVar(&client.MaxHistory, "history-max", settings.MaxHistory, "limit the maximum number of revisions saved per release. Use 0 for no limit")
+	f.DurationVar(&client.MaxHistoryAge, "history-max-age", settings.MaxHistoryAge, "remove the revisions of the release last deployed longer ago than this, such as 720h, always keeping the last deployed revision. Use 0 for no limit")
 	f.BoolVar(&client.CleanupOnFail, "cleanup-on-fail", false, "allow deletion of new resources created in this upgrade when upgrade fails")
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in upgrade output. Does not affect presence in chart metadata")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
+	f.BoolVar(&client.WarnSchemaViolations, "warn-schema-violations", false, "if set, report the values that don't meet the JSON schema of their chart as warnings of the release instead of failing")
 	f.StringToStringVarP(&client.Labels, "labels", "l", nil, "Labels that would be added to release metadata. Should be separated by comma. Original release labels will be merged with upgrade labels. You can unset label using null.")
 	f.StringVar(&client.Description, "description", "", "add a custom description")
 	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing before installing the chart")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
-	f.BoolVar(&client.TakeOwnership, "take-ownership", false, "if set, upgrade will ignore the check for helm annotations and take ownership of the existing resources")
+	f.BoolVar(&client.CollectErrors, "collect-errors", false, "if set, render all the templates when one fails and report the errors of all the failing templates")
+	f.BoolVar(&client.CacheIncludes, "cache-includes", false, "if set, cache the output of the include calls with the same template and arguments while rendering, which speeds up charts calling the same helpers many times. Do not set it for charts whose helpers generate random values")
+	f.BoolVar(&client.ParallelRender, "parallel-render", false, "if set, render the templates of the chart concurrently. The templates are rendered one at a time when they modify their values, or call tpl or a custom template function")
+	f.IntVar(&client.LookupBudget, "lookup-budget", 0, "limit the number of objects the lookup function may request from the cluster while rendering. Repeated lookups of the same object are cached and do not count. 0 means no limit")
+	f.Var(&lookupFixturesValue{objs: &client.LookupFixtures}, "lookup-fixtures", "render the lookup function with the objects of a YAML or JSON file, such as one saved with 'kubectl get -o yaml', instead of those of the cluster. Requires --dry-run")
+	f.BoolVar(&client.TakeOwnership, "take-ownership", false, "if set, upgrade will adopt the existing resources, unless they are owned by another release")
+	f.BoolVar(&client.PreflightDryRun, "preflight-dry-run", false, "if set, submit all changes with server-side dry-run before upgrading and report every rejection")
+	f.BoolVar(&client.DryRunApply, "dry-run-apply", false, "with --dry-run=server, submit all changes with server-side dry-run and report the objects the server would store, as mutated by admission webhooks, and every rejection")
+	addPreflightFlag(f, &client.PreflightChecks)
+	f.BoolVar(&client.WaitForDependencies, "wait-for-dependencies", false, "if set, resources annotated with helm.sh/depends-on are applied only after the resources they depend on are ready")
+	f.BoolVar(&client.ServerSideApply, "server-side", false, "if set, apply the resources with server-side apply, which tracks the fields owned by Helm on the server. Cannot be used with --force")
+	f.BoolVar(&client.ForceConflicts, "force-conflicts", false, "if set with --server-side, take ownership of the fields owned by other field managers instead of failing on conflicts")
+	f.Var(newNamespacePolicyValue(&client.NamespacePolicy), "namespace-policy", "how to handle resources outside of the release namespace. One of: allow, warn, reject. Resources annotated with helm.sh/allow-cross-namespace: \"true\" are always allowed")
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 	addValueOptionsFlags(f, valueOpts)
 	bindOutputFlag(cmd, &outfmt)
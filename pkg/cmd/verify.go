@@ -34,6 +34,17 @@ tampered with, and was packaged by a trusted provider.
 This command can be used to verify a local chart. Several other commands provide
 '--verify' flags that run the same validation. To generate a signed package, use
 the 'helm package --sign' command.
+
+Charts signed with 'helm package --sign-keyless' are verified with '--keyless'.
+The signature must have been made by the identity given with
+'--certificate-identity', authenticated by '--certificate-oidc-issuer', and be
+recorded in a transparency log listed in the Sigstore trusted root given with
+'--trusted-root'.
+
+  $ helm verify --keyless --trusted-root trusted_root.json \
+      --certificate-identity maintainer@example.com \
+      --certificate-oidc-issuer https://accounts.google.com \
+      mychart-0.1.0.tgz
 `
 
 func newVerifyCmd(out io.Writer) *cobra.Command {
@@ -64,7 +75,12 @@ func newVerifyCmd(out io.Writer) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys")
+	f := cmd.Flags()
+	f.StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys")
+	f.BoolVar(&client.Keyless, "keyless", false, "verify the Sigstore signature of the chart instead of its provenance file")
+	f.StringVar(&client.CertificateIdentity, "certificate-identity", "", "identity the chart must be signed by. Used if --keyless is true")
+	f.StringVar(&client.CertificateOIDCIssuer, "certificate-oidc-issuer", "", "OIDC issuer of the signing identity. Used if --keyless is true")
+	f.StringVar(&client.TrustedRoot, "trusted-root", "", "path to the Sigstore trusted root. Used if --keyless is true")
 
 	return cmd
 }
@@ -62,6 +62,12 @@ func TestVerifyCmd(t *testing.T) {
 			expect:    fmt.Sprintf("could not load provenance file testdata/testcharts/compressedchart-0.1.0.tgz.prov: %s testdata/testcharts/compressedchart-0.1.0.tgz.prov: %s", statExe, statFileMsg),
 			wantError: true,
 		},
+		{
+			name:      "verify --keyless requires a trusted root",
+			cmd:       "verify testdata/testcharts/signtest-0.1.0.tgz --keyless",
+			expect:    "a Sigstore trusted root is required for keyless verification",
+			wantError: true,
+		},
 		{
 			name:      "verify validates a properly signed chart",
 			cmd:       "verify testdata/testcharts/signtest-0.1.0.tgz --keyring testdata/helm-test-key.pub",
@@ -65,6 +65,9 @@ type ChartDownloader struct {
 	Verify VerificationStrategy
 	// Keyring is the keyring file used for verification.
 	Keyring string
+	// Sigstore, when set, verifies charts against their keyless Sigstore
+	// signature instead of a provenance file signed with a key from Keyring.
+	Sigstore *provenance.SigstoreOptions
 	// Getter collection for the operation
 	Getters getter.Providers
 	// Options provide parameters to be passed along to the Getter being initialized.
@@ -105,8 +108,14 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 
 	name := filepath.Base(u.Path)
 	if u.Scheme == registry.OCIScheme {
-		idx := strings.LastIndexByte(name, ':')
-		name = fmt.Sprintf("%s-%s.tgz", name[:idx], name[idx+1:])
+		if idx := strings.IndexByte(name, '@'); idx >= 0 && version != "" {
+			// A digest reference carries no tag, so name the archive after
+			// the requested version.
+			name = fmt.Sprintf("%s-%s.tgz", name[:idx], version)
+		} else {
+			idx := strings.LastIndexByte(name, ':')
+			name = fmt.Sprintf("%s-%s.tgz", name[:idx], name[idx+1:])
+		}
 	}
 
 	destfile := filepath.Join(dest, name)
@@ -117,21 +126,29 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 	// If provenance is requested, verify it.
 	ver := &provenance.Verification{}
 	if c.Verify > VerifyNever {
-		body, err := g.Get(u.String() + ".prov")
+		ext := ".prov"
+		if c.Sigstore != nil {
+			ext = provenance.SigstoreBundleExt
+		}
+		body, err := g.Get(u.String() + ext)
 		if err != nil {
 			if c.Verify == VerifyAlways {
-				return destfile, ver, errors.Errorf("failed to fetch provenance %q", u.String()+".prov")
+				return destfile, ver, errors.Errorf("failed to fetch provenance %q", u.String()+ext)
 			}
 			fmt.Fprintf(c.Out, "WARNING: Verification not found for %s: %s\n", ref, err)
 			return destfile, ver, nil
 		}
-		provfile := destfile + ".prov"
+		provfile := destfile + ext
 		if err := fileutil.AtomicWriteFile(provfile, body, 0644); err != nil {
 			return destfile, nil, err
 		}
 
 		if c.Verify != VerifyLater {
-			ver, err = VerifyChart(destfile, c.Keyring)
+			if c.Sigstore != nil {
+				ver, err = VerifyChartSigstore(destfile, *c.Sigstore)
+			} else {
+				ver, err = VerifyChart(destfile, c.Keyring)
+			}
 			if err != nil {
 				// Fail always in this case, since it means the verification step
 				// failed.
@@ -298,6 +315,31 @@ func VerifyChart(path, keyring string) (*provenance.Verification, error) {
 	return sig.Verify(path, provfile)
 }
 
+// VerifyChartSigstore takes a path to a chart archive and verifies its keyless
+// Sigstore signature against opts.
+//
+// It assumes that a chart archive file is accompanied by a Sigstore bundle whose
+// name is the archive file name plus the ".sigstore.json" extension.
+func VerifyChartSigstore(path string, opts provenance.SigstoreOptions) (*provenance.Verification, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	switch fi, err := os.Stat(path); {
+	case err != nil:
+		return nil, err
+	case fi.IsDir():
+		return nil, errors.New("unpacked charts cannot be verified")
+	case !isTar(path):
+		return nil, errors.New("chart must be a tgz file")
+	}
+
+	bundlefile := path + provenance.SigstoreBundleExt
+	if _, err := os.Stat(bundlefile); err != nil {
+		return nil, errors.Wrapf(err, "could not load Sigstore bundle %s", bundlefile)
+	}
+	return provenance.VerifySigstore(path, bundlefile, opts)
+}
+
 // isTar tests whether the given file is a tar file.
 //
 // Currently, this simply checks extension, since a subsequent function will
@@ -18,11 +18,13 @@ package downloader
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v4/internal/test/ensure"
 	"helm.sh/helm/v4/pkg/cli"
 	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/provenance"
 	"helm.sh/helm/v4/pkg/repo"
 	"helm.sh/helm/v4/pkg/repo/repotest"
 )
@@ -157,6 +159,21 @@ func TestVerifyChart(t *testing.T) {
 	}
 }
 
+func TestVerifyChartSigstore(t *testing.T) {
+	opts := provenance.SigstoreOptions{
+		TrustedRoot:           "testdata/trusted_root.json",
+		CertificateIdentity:   "me@example.com",
+		CertificateOIDCIssuer: "https://accounts.example.com",
+	}
+	if _, err := VerifyChartSigstore("testdata/signtest", opts); err == nil {
+		t.Error("expected unpacked charts to fail keyless verification")
+	}
+	_, err := VerifyChartSigstore("testdata/signtest-0.1.0.tgz", opts)
+	if err == nil || !strings.Contains(err.Error(), "could not load Sigstore bundle") {
+		t.Errorf("expected a missing bundle error, got %v", err)
+	}
+}
+
 func TestIsTar(t *testing.T) {
 	tests := map[string]bool{
 		"foo.tgz":           true,
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/url"
 	"os"
 	"path"
@@ -28,11 +29,13 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/pkg/errors"
 	"sigs.k8s.io/yaml"
 
+	"helm.sh/helm/v4/internal/fileutil"
 	"helm.sh/helm/v4/internal/resolver"
 	"helm.sh/helm/v4/internal/third_party/dep/fs"
 	"helm.sh/helm/v4/internal/urlutil"
@@ -41,6 +44,7 @@ import (
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/helmpath"
+	"helm.sh/helm/v4/pkg/provenance"
 	"helm.sh/helm/v4/pkg/registry"
 	"helm.sh/helm/v4/pkg/repo"
 )
@@ -75,6 +79,10 @@ type Manager struct {
 	RegistryClient   *registry.Client
 	RepositoryConfig string
 	RepositoryCache  string
+	// ContentCache is the directory of a content-addressed cache of chart
+	// archives. Archives whose digest is known and cached are not downloaded
+	// again. If empty, no cache is used.
+	ContentCache string
 }
 
 // Build rebuilds a local charts directory from a lockfile.
@@ -207,10 +215,11 @@ func (m *Manager) Update() error {
 		return err
 	}
 	lock.Digest = newDigest
+	lock.APIVersion = chart.LockAPIVersionV2
 
 	// If the lock file hasn't changed, don't write a new one.
 	oldLock := c.Lock
-	if oldLock != nil && oldLock.Digest == lock.Digest {
+	if oldLock != nil && oldLock.Digest == lock.Digest && sameResolution(oldLock, lock) {
 		return nil
 	}
 
@@ -268,8 +277,10 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 	defer os.RemoveAll(tmpPath)
 
 	fmt.Fprintf(m.Out, "Saving %d charts\n", len(deps))
+	out := &syncWriter{w: m.Out}
 	var saveError error
-	churls := make(map[string]struct{})
+	churls := make(map[string]*chartDownload)
+	var downloads []*chartDownload
 	for _, dep := range deps {
 		// No repository means the chart is in charts directory
 		if dep.Repository == "" {
@@ -297,6 +308,8 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 			}
 			continue
 		}
+		// Charts archived from local directories are not reproducible byte
+		// for byte, so no content digest is recorded for them.
 		if strings.HasPrefix(dep.Repository, "file://") {
 			if m.Debug {
 				fmt.Fprintf(m.Out, "Archiving %s from repo %s\n", dep.Name, dep.Repository)
@@ -318,46 +331,61 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 			break
 		}
 
-		if _, ok := churls[churl]; ok {
+		if d, ok := churls[churl]; ok {
 			fmt.Fprintf(m.Out, "Already downloaded %s from repo %s\n", dep.Name, dep.Repository)
+			d.deps = append(d.deps, dep)
 			continue
 		}
 
 		fmt.Fprintf(m.Out, "Downloading %s from repo %s\n", dep.Name, dep.Repository)
 
-		dl := ChartDownloader{
-			Out:              m.Out,
-			Verify:           m.Verify,
-			Keyring:          m.Keyring,
-			RepositoryConfig: m.RepositoryConfig,
-			RepositoryCache:  m.RepositoryCache,
-			RegistryClient:   m.RegistryClient,
-			Getters:          m.Getters,
-			Options: []getter.Option{
-				getter.WithBasicAuth(username, password),
-				getter.WithPassCredentialsAll(passcredentialsall),
-				getter.WithInsecureSkipVerifyTLS(insecureskiptlsverify),
-				getter.WithTLSClientConfig(certFile, keyFile, caFile),
+		d := &chartDownload{
+			ref:         churl,
+			resolvedURL: churl,
+			digest:      dep.ContentDigest,
+			deps:        []*chart.Dependency{dep},
+			downloader: ChartDownloader{
+				Out:              out,
+				Verify:           m.Verify,
+				Keyring:          m.Keyring,
+				RepositoryConfig: m.RepositoryConfig,
+				RepositoryCache:  m.RepositoryCache,
+				RegistryClient:   m.RegistryClient,
+				Getters:          m.Getters,
+				Options: []getter.Option{
+					getter.WithBasicAuth(username, password),
+					getter.WithPassCredentialsAll(passcredentialsall),
+					getter.WithInsecureSkipVerifyTLS(insecureskiptlsverify),
+					getter.WithTLSClientConfig(certFile, keyFile, caFile),
+				},
 			},
 		}
+		if d.digest == "" {
+			d.digest = indexDigest(dep, repos)
+		}
+		churls[churl] = d
 
-		version := ""
 		if registry.IsOCI(churl) {
-			churl, version, err = parseOCIRef(churl)
-			if err != nil {
-				return errors.Wrapf(err, "could not parse OCI reference")
+			if dep.Digest != "" {
+				// Pull digest-pinned charts by digest, so that the content
+				// served by the registry is verified against it.
+				d.ref, d.version = fmt.Sprintf("%s/%s@%s", dep.Repository, dep.Name, dep.Digest), dep.Version
+				d.resolvedURL = d.ref
+			} else {
+				d.ref, d.version, err = parseOCIRef(churl)
+				if err != nil {
+					return errors.Wrapf(err, "could not parse OCI reference")
+				}
 			}
-			dl.Options = append(dl.Options,
+			d.downloader.Options = append(d.downloader.Options,
 				getter.WithRegistryClient(m.RegistryClient),
-				getter.WithTagName(version))
-		}
-
-		if _, _, err = dl.DownloadTo(churl, version, tmpPath); err != nil {
-			saveError = errors.Wrapf(err, "could not download %s", churl)
-			break
+				getter.WithTagName(d.version))
 		}
+		downloads = append(downloads, d)
+	}
 
-		churls[churl] = struct{}{}
+	if saveError == nil {
+		saveError = m.fetchAll(downloads, tmpPath)
 	}
 
 	// TODO: this should probably be refactored to be a []error, so we can capture and provide more information rather than "last error wins".
@@ -373,6 +401,183 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 	return nil
 }
 
+// downloadConcurrency bounds the number of charts downloaded at once.
+const downloadConcurrency = 8
+
+// chartDownload is a chart archive to fetch for one or more dependencies.
+type chartDownload struct {
+	downloader ChartDownloader
+	// ref and version are passed to the downloader.
+	ref     string
+	version string
+	// resolvedURL is recorded in the lock file.
+	resolvedURL string
+	// digest is the expected content digest of the archive, if known.
+	digest string
+	deps   []*chart.Dependency
+}
+
+// fetchAll downloads charts into dest concurrently. It returns the error of
+// the first failed download, in dependency order.
+func (m *Manager) fetchAll(downloads []*chartDownload, dest string) error {
+	errs := make([]error, len(downloads))
+	sem := make(chan struct{}, downloadConcurrency)
+	var wg sync.WaitGroup
+	for i, d := range downloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = m.fetch(d, dest)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetch places a chart archive into dest, from the content cache when an
+// archive with the expected digest is cached and from its repository
+// otherwise, and locks the dependencies it satisfies to its digest.
+func (m *Manager) fetch(d *chartDownload, dest string) error {
+	sum := d.digest
+	cached := m.cachedArchive(sum)
+	if cached != "" {
+		// Provenance files are not cached, so charts are always downloaded
+		// when they are to be verified.
+		dep := d.deps[0]
+		if err := copyFile(cached, filepath.Join(dest, fmt.Sprintf("%s-%s.tgz", dep.Name, dep.Version))); err != nil {
+			return err
+		}
+	} else {
+		destfile, _, err := d.downloader.DownloadTo(d.ref, d.version, dest)
+		if err != nil {
+			return errors.Wrapf(err, "could not download %s", d.ref)
+		}
+		hex, err := provenance.DigestFile(destfile)
+		if err != nil {
+			return errors.Wrapf(err, "could not compute the digest of %s", destfile)
+		}
+		sum = "sha256:" + hex
+		m.cacheArchive(destfile, sum)
+	}
+
+	for _, dep := range d.deps {
+		if err := lockContent(dep, d.resolvedURL, sum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cachedArchive returns the path of the cached chart archive with the given
+// digest, or an empty string if there is none to use.
+func (m *Manager) cachedArchive(sum string) string {
+	if m.ContentCache == "" || m.Verify > VerifyNever || !strings.HasPrefix(sum, "sha256:") {
+		return ""
+	}
+	p := filepath.Join(m.ContentCache, "sha256", strings.TrimPrefix(sum, "sha256:")+".tgz")
+	if _, err := os.Stat(p); err != nil {
+		return ""
+	}
+	return p
+}
+
+// cacheArchive adds a downloaded chart archive to the content cache. Failing
+// to do so is not an error, as the archive was downloaded all the same.
+func (m *Manager) cacheArchive(archive, sum string) {
+	if m.ContentCache == "" {
+		return
+	}
+	dir := filepath.Join(m.ContentCache, "sha256")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		slog.Debug("unable to create content cache", "path", dir, slog.Any("error", err))
+		return
+	}
+	if err := copyFile(archive, filepath.Join(dir, strings.TrimPrefix(sum, "sha256:")+".tgz")); err != nil {
+		slog.Debug("unable to cache chart archive", "archive", archive, slog.Any("error", err))
+	}
+}
+
+func copyFile(src, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return fileutil.AtomicWriteFile(dest, f, 0644)
+}
+
+// indexDigest returns the content digest the repository index records for
+// the chart archive of a dependency, if any.
+func indexDigest(dep *chart.Dependency, repos map[string]*repo.ChartRepository) string {
+	for _, cr := range repos {
+		if !urlutil.Equal(dep.Repository, cr.Config.URL) {
+			continue
+		}
+		entry, err := findEntryByName(dep.Name, cr)
+		if err != nil {
+			return ""
+		}
+		ve, err := findVersionedEntry(dep.Version, entry)
+		if err != nil || ve.Digest == "" {
+			return ""
+		}
+		return "sha256:" + ve.Digest
+	}
+	return ""
+}
+
+// syncWriter serializes the writes of concurrent downloads.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// lockContent records the content digest and resolution metadata of a
+// downloaded dependency. If the dependency already carries a content digest,
+// as it does when building from a lock file, the download is verified against
+// it instead.
+func lockContent(dep *chart.Dependency, churl, sum string) error {
+	if dep.ContentDigest != "" {
+		if dep.ContentDigest != sum {
+			return errors.Errorf("content digest mismatch for dependency %s: the lock file records %s, but %s has %s", dep.Name, dep.ContentDigest, churl, sum)
+		}
+		return nil
+	}
+	now := time.Now()
+	dep.ContentDigest = sum
+	dep.ResolvedURL = churl
+	dep.Resolved = &now
+	return nil
+}
+
+// sameResolution reports whether two lock files resolved their dependencies
+// to the same content.
+func sameResolution(a, b *chart.Lock) bool {
+	if a.APIVersion != b.APIVersion || len(a.Dependencies) != len(b.Dependencies) {
+		return false
+	}
+	for i := range a.Dependencies {
+		if a.Dependencies[i].ContentDigest != b.Dependencies[i].ContentDigest || a.Dependencies[i].ResolvedURL != b.Dependencies[i].ResolvedURL {
+			return false
+		}
+	}
+	return true
+}
+
 func parseOCIRef(chartRef string) (string, string, error) {
 	refTagRegexp := regexp.MustCompile(`^(oci://[^:]+(:[0-9]{1,5})?[^:]+):(.*)$`)
 	caps := refTagRegexp.FindStringSubmatch(chartRef)
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,6 +29,7 @@ import (
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/provenance"
 	"helm.sh/helm/v4/pkg/repo"
 	"helm.sh/helm/v4/pkg/repo/repotest"
 )
@@ -538,6 +540,145 @@ func TestBuild_WithRepositoryAlias(t *testing.T) {
 	})
 }
 
+func TestBuild_VerifiesContentDigests(t *testing.T) {
+	// Set up a fake repo
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/*.tgz*"),
+	)
+	defer srv.Stop()
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+	dir := func(p ...string) string {
+		return filepath.Join(append([]string{srv.Root()}, p...)...)
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "with-content-digests",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{{
+				Name:       "local-subchart",
+				Version:    "0.1.0",
+				Repository: srv.URL(),
+			}},
+		},
+	}
+	if err := chartutil.SaveDir(c, dir()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{
+		ChartPath: dir(c.Metadata.Name),
+		Out:       bytes.NewBuffer(nil),
+		Getters: getter.Providers{getter.Provider{
+			Schemes: []string{"http", "https"},
+			New:     getter.NewHTTPGetter,
+		}},
+		RepositoryConfig: dir("repositories.yaml"),
+		RepositoryCache:  dir(),
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	ch, err := loader.LoadDir(m.ChartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := provenance.DigestFile(dir(c.Metadata.Name, "charts", "local-subchart-0.1.0.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	is := assert.New(t)
+	is.Equal(chart.LockAPIVersionV2, ch.Lock.APIVersion)
+	dep := ch.Lock.Dependencies[0]
+	is.Equal("sha256:"+sum, dep.ContentDigest)
+	is.Equal(srv.URL()+"/local-subchart-0.1.0.tgz", dep.ResolvedURL)
+	is.NotNil(dep.Resolved)
+
+	// Building from the lock file verifies the downloaded archive.
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A tampered digest fails the build, even though the lock is in sync.
+	dep.ContentDigest = "sha256:" + strings.Repeat("0", 64)
+	if err := writeLock(m.ChartPath, ch.Lock, false); err != nil {
+		t.Fatal(err)
+	}
+	is.ErrorContains(m.Build(), "content digest mismatch for dependency local-subchart")
+}
+
+func TestBuild_UsesContentCache(t *testing.T) {
+	// Set up a fake repo
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/*.tgz*"),
+	)
+	defer srv.Stop()
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+	dir := func(p ...string) string {
+		return filepath.Join(append([]string{srv.Root()}, p...)...)
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "with-content-cache",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{
+				{Name: "local-subchart", Version: "0.1.0", Repository: srv.URL()},
+				{Name: "signtest", Version: "0.1.0", Repository: srv.URL()},
+			},
+		},
+	}
+	if err := chartutil.SaveDir(c, dir()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{
+		ChartPath: dir(c.Metadata.Name),
+		Out:       bytes.NewBuffer(nil),
+		Getters: getter.Providers{getter.Provider{
+			Schemes: []string{"http", "https"},
+			New:     getter.NewHTTPGetter,
+		}},
+		RepositoryConfig: dir("repositories.yaml"),
+		RepositoryCache:  dir(),
+		ContentCache:     t.TempDir(),
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := filepath.Glob(filepath.Join(m.ContentCache, "sha256", "*.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 2 {
+		t.Fatalf("expected 2 cached archives, got %v", cached)
+	}
+
+	// With the repository gone, the archives can only come from the cache.
+	srv.Stop()
+	if err := os.RemoveAll(dir(c.Metadata.Name, "charts")); err != nil {
+		t.Fatal(err)
+	}
+	m.SkipUpdate = true
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"local-subchart-0.1.0.tgz", "signtest-0.1.0.tgz"} {
+		if _, err := os.Stat(dir(c.Metadata.Name, "charts", name)); err != nil {
+			t.Errorf("expected %s to be restored from the cache: %s", name, err)
+		}
+	}
+}
+
 func TestErrRepoNotFound_Error(t *testing.T) {
 	type fields struct {
 		Repos []string
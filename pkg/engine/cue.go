@@ -0,0 +1,169 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// cueInputs are the fields of the CUE packages of charts that the render
+// values are unified with, by key of the render values.
+var cueInputs = []struct {
+	field string
+	key   string
+}{
+	{"values", "Values"},
+	{"release", "Release"},
+	{"chart", "Chart"},
+	{"capabilities", "Capabilities"},
+}
+
+// renderCUE evaluates the CUE packages of the charts using the cue engine,
+// made of the .cue files of their cue/ directory.
+//
+// The values, release, chart and capabilities fields, which Helm declares in
+// every package, are unified with the Values, Release, Chart and Capabilities of the chart, and
+// values with the #Values definition of the package, if any, which acts as
+// the schema of the values. The fields of chart are those of Chart.yaml, and
+// the fields of release and capabilities those of the Go templates. The objects field, a list or a struct of
+// Kubernetes objects, is then rendered as the YAML documents of the
+// cue/objects.yaml file of the chart.
+func renderCUE(srcs map[string]renderable) (map[string]string, error) {
+	if len(srcs) == 0 {
+		return nil, nil
+	}
+	pkgs := make(map[string][]string)
+	for filename, r := range srcs {
+		pkgs[r.basePath] = append(pkgs[r.basePath], filename)
+	}
+	dirs := make([]string, 0, len(pkgs))
+	for dir := range pkgs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	ctx := cuecontext.New()
+	rendered := make(map[string]string)
+	for _, dir := range dirs {
+		filenames := pkgs[dir]
+		sort.Strings(filenames)
+		inst := build.NewContext().NewInstance(dir, nil)
+		for _, filename := range filenames {
+			if err := inst.AddFile(filename, srcs[filename].tpl); err != nil {
+				return nil, cueError(dir, err)
+			}
+		}
+		// Declare the inputs, so that the package can refer to them.
+		inputs := "package " + inst.PkgName + "\n"
+		for _, in := range cueInputs {
+			inputs += in.field + ": _\n"
+		}
+		if err := inst.AddFile(path.Join(dir, "helm.cue"), inputs); err != nil {
+			return nil, cueError(dir, err)
+		}
+		out, err := evalCUE(ctx, inst, srcs[filenames[0]].vals)
+		if err != nil {
+			return nil, cueError(dir, err)
+		}
+		if out != "" {
+			rendered[path.Join(dir, "objects.yaml")] = out
+		}
+	}
+	return rendered, nil
+}
+
+func evalCUE(ctx *cue.Context, inst *build.Instance, vals chartutil.Values) (string, error) {
+	v := ctx.BuildInstance(inst)
+	if err := v.Err(); err != nil {
+		return "", err
+	}
+	for _, in := range cueInputs {
+		if vals[in.key] == nil {
+			continue
+		}
+		data, err := json.Marshal(vals[in.key])
+		if err != nil {
+			return "", errors.Wrapf(err, "encoding %s", in.field)
+		}
+		v = v.FillPath(cue.ParsePath(in.field), ctx.CompileBytes(data))
+	}
+	valuesPath := cue.ParsePath("values")
+	if schema := v.LookupPath(cue.ParsePath("#Values")); schema.Exists() {
+		v = v.FillPath(valuesPath, schema)
+	}
+	if err := v.LookupPath(valuesPath).Validate(cue.Concrete(true)); err != nil {
+		return "", err
+	}
+
+	objects := v.LookupPath(cue.ParsePath("objects"))
+	if !objects.Exists() {
+		return "", nil
+	}
+	if err := objects.Validate(cue.Concrete(true)); err != nil {
+		return "", err
+	}
+	var docs []string
+	add := func(obj cue.Value) error {
+		data, err := obj.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		doc, err := yaml.JSONToYAML(data)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, string(doc))
+		return nil
+	}
+	switch objects.Kind() {
+	case cue.ListKind:
+		it, _ := objects.List()
+		for it.Next() {
+			if err := add(it.Value()); err != nil {
+				return "", err
+			}
+		}
+	case cue.StructKind:
+		it, _ := objects.Fields()
+		for it.Next() {
+			if err := add(it.Value()); err != nil {
+				return "", err
+			}
+		}
+	default:
+		return "", errors.New("objects must be a list or a struct of Kubernetes objects")
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// cueError returns the error of the CUE package of dir, with the positions
+// of the errors CUE reports.
+func cueError(dir string, err error) error {
+	return errors.Errorf("rendering CUE package %s: %s", dir, strings.TrimSpace(cueerrors.Details(err, nil)))
+}
@@ -0,0 +1,140 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func cueTestChart() *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3", Engine: "cue"},
+		Templates: []*chart.File{
+			{Name: "templates/NOTES.txt", Data: []byte(`{{ .Release.Name }} has {{ .Values.replicas }} replicas`)},
+		},
+		Files: []*chart.File{
+			{Name: "cue/schema.cue", Data: []byte(`package moby
+
+#Values: {
+	replicas: int & >0 | *1
+	image:    string
+}
+`)},
+			{Name: "cue/objects.cue", Data: []byte(`package moby
+
+import "strings"
+
+objects: deployment: {
+	apiVersion: "apps/v1"
+	kind:       "Deployment"
+	metadata: name: strings.ToLower(release.Name)
+	spec: {
+		replicas: values.replicas
+		template: spec: containers: [{name: chart.name, image: values.image}]
+	}
+}
+`)},
+			{Name: "cue/README.md", Data: []byte(`not CUE`)},
+		},
+	}
+}
+
+func cueTestValues(values map[string]interface{}) chartutil.Values {
+	return chartutil.Values{
+		"Values":  values,
+		"Release": map[string]interface{}{"Name": "Whale"},
+	}
+}
+
+func TestRenderCUE(t *testing.T) {
+	out, err := Render(cueTestChart(), cueTestValues(map[string]interface{}{"image": "moby:1", "replicas": 1}))
+	require.NoError(t, err)
+
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: whale
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - image: moby:1
+        name: moby
+`, out["moby/cue/objects.yaml"])
+	assert.Equal(t, "Whale has 1 replicas", out["moby/templates/NOTES.txt"])
+}
+
+func TestRenderCUESchema(t *testing.T) {
+	_, err := Render(cueTestChart(), cueTestValues(map[string]interface{}{"image": "moby:1", "replicas": 0}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rendering CUE package moby/cue")
+	assert.Contains(t, err.Error(), "values.replicas")
+
+	_, err = Render(cueTestChart(), cueTestValues(map[string]interface{}{"replicas": 1}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "values.image")
+}
+
+func TestRenderCUEList(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3", Engine: "cue"},
+		Files: []*chart.File{
+			{Name: "cue/objects.cue", Data: []byte(`package moby
+
+objects: [for n in values.names {
+	apiVersion: "v1"
+	kind:       "ConfigMap"
+	metadata: name: n
+}]
+`)},
+		},
+	}
+	c.AddDependency(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "gotpl", Version: "1.0.0"},
+		Templates: []*chart.File{
+			{Name: "templates/cm.yaml", Data: []byte(`name: {{ .Values.name }}`)},
+		},
+	})
+
+	out, err := Render(c, cueTestValues(map[string]interface{}{
+		"names": []interface{}{"a", "b"},
+		"gotpl": map[string]interface{}{"name": "c"},
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n", out["moby/cue/objects.yaml"])
+	assert.Equal(t, "name: c", out["moby/charts/gotpl/templates/cm.yaml"])
+}
+
+func TestRenderCUEInvalidObjects(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3", Engine: "cue"},
+		Files: []*chart.File{
+			{Name: "cue/objects.cue", Data: []byte("package moby\n\nobjects: 3\n")},
+		},
+	}
+
+	_, err := Render(c, cueTestValues(nil))
+	assert.ErrorContains(t, err, "objects must be a list or a struct of Kubernetes objects")
+}
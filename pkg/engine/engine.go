@@ -27,6 +27,7 @@ import (
 	"text/template"
 
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/rest"
 
 	chart "helm.sh/helm/v4/pkg/chart/v2"
@@ -44,6 +45,37 @@ type Engine struct {
 	clientProvider *ClientProvider
 	// EnableDNS tells the engine to allow DNS lookups when rendering templates
 	EnableDNS bool
+	// CollectErrors makes rendering go on after a template fails, to report
+	// the errors of all the templates together as RenderErrors. The references
+	// to values that a failing template did not reach are checked as well, so
+	// that the missing and mistyped values are reported at once.
+	CollectErrors bool
+	// CacheIncludes caches the output of the calls to 'include' with the same
+	// template and arguments during a render, which speeds up charts calling
+	// helpers such as labels many times. It must not be set for charts whose
+	// included templates render differently from one call to the next, such
+	// as those generating random values.
+	CacheIncludes bool
+	// Parallel renders the template files concurrently, up to GOMAXPROCS at
+	// a time, each with its own copy of the values. The files are rendered one
+	// at a time when a template modifies its arguments, such as with 'set', as
+	// the files share their values, or calls 'tpl' or a function of Funcs.
+	Parallel bool
+	// LookupBudget is the number of objects and lists the 'lookup' calls of
+	// a render may request from the Kubernetes API, or unlimited if 0. The
+	// results of 'lookup' are cached for the duration of a render, so that
+	// repeated calls for the same object do not count.
+	LookupBudget int
+	// LookupConcurrency is the number of 'lookup' requests made at once when
+	// rendering in parallel. It defaults to 4.
+	LookupConcurrency int
+	// LookupFixtures are the objects the 'lookup' function finds instead of
+	// querying a cluster, so that charts relying on lookup render the same
+	// way offline and in tests. When set, no cluster is queried.
+	LookupFixtures []*unstructured.Unstructured
+	// Funcs are the functions added to the functions of the engine. The
+	// functions of DefaultFuncRegistry are added when it is nil.
+	Funcs *FuncRegistry
 }
 
 // New creates a new instance of Engine using the passed in rest config.
@@ -59,7 +91,12 @@ func New(config *rest.Config) Engine {
 // Render can be called repeatedly on the same engine.
 //
 // This will look in the chart's 'templates' data (e.g. the 'templates/' directory)
-// and attempt to render the templates there using the values passed in.
+// and attempt to render the templates there using the values passed in. The
+// CUE package of the 'cue/' directory of the charts using the cue engine is
+// evaluated as well, as described by renderCUE, and the Jsonnet templates of
+// the charts using the jsonnet engine as described by renderJsonnet. The
+// rendered objects are then transformed by the Starlark scripts the charts
+// declare, as described by applyTransforms.
 //
 // Values are scoped to their templates. A dependency template will not have
 // access to the values set for its parent. If chart "foo" includes chart "bar",
@@ -112,6 +149,15 @@ type renderable struct {
 	vals chartutil.Values
 	// namespace prefix to the templates of the current chart
 	basePath string
+	// engine renders the template when it is not a Go template, such as
+	// "cue" for the files of the CUE package of a chart or "jsonnet" for
+	// Jsonnet templates. It is "starlark" for the transform script of a
+	// chart, which is not rendered but run on the rendered objects.
+	engine string
+	// namespace of the templates defined by a library chart declaring
+	// exports, and the exports
+	namespace string
+	exports   []string
 }
 
 const warnStartDelim = "HELM_ERR_START"
@@ -126,8 +172,16 @@ func warnWrap(warn string) string {
 
 // 'include' needs to be defined in the scope of a 'tpl' template as
 // well as regular file-loaded templates.
-func includeFun(t *template.Template, includedNames map[string]int) func(string, interface{}) (string, error) {
+func includeFun(t *template.Template, includedNames map[string]int, cache *includeCache) func(string, interface{}) (string, error) {
 	return func(name string, data interface{}) (string, error) {
+		var key string
+		if cache != nil {
+			var out string
+			var ok bool
+			if key, out, ok = cache.get(name, data); ok {
+				return out, nil
+			}
+		}
 		var buf strings.Builder
 		if v, ok := includedNames[name]; ok {
 			if v > recursionMaxNums {
@@ -139,6 +193,9 @@ func includeFun(t *template.Template, includedNames map[string]int) func(string,
 		}
 		err := t.ExecuteTemplate(&buf, name, data)
 		includedNames[name]--
+		if err == nil && key != "" {
+			cache.put(key, data, buf.String())
+		}
 		return buf.String(), err
 	}
 }
@@ -164,7 +221,9 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 		// Re-inject 'include' so that it can close over our clone of t;
 		// this lets any 'define's inside tpl be 'include'd.
 		t.Funcs(template.FuncMap{
-			"include": includeFun(t, includedNames),
+			// The templates defined by the text are not those the
+			// cache of the render knows of.
+			"include": includeFun(t, includedNames, nil),
 			"tpl":     tplFun(t, includedNames, strict),
 		})
 
@@ -191,11 +250,22 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 
 // initFunMap creates the Engine's FuncMap and adds context-specific functions.
 func (e Engine) initFunMap(t *template.Template) {
+	var cache *includeCache
+	if e.CacheIncludes {
+		cache = newIncludeCache()
+	}
 	funcMap := funcMap()
+	registry := e.Funcs
+	if registry == nil {
+		registry = DefaultFuncRegistry
+	}
+	for name, fn := range registry.FuncMap() {
+		funcMap[name] = fn
+	}
 	includedNames := make(map[string]int)
 
 	// Add the template-rendering functions here so we can close over t.
-	funcMap["include"] = includeFun(t, includedNames)
+	funcMap["include"] = includeFun(t, includedNames, cache)
 	funcMap["tpl"] = tplFun(t, includedNames, e.Strict)
 
 	// Add the `required` function here so we can use lintMode
@@ -230,10 +300,13 @@ func (e Engine) initFunMap(t *template.Template) {
 		return "", errors.New(warnWrap(msg))
 	}
 
-	// If we are not linting and have a cluster connection, provide a Kubernetes-backed
+	// Lookup fixtures take the place of the cluster. Otherwise, if we are not
+	// linting and have a cluster connection, provide a Kubernetes-backed
 	// implementation.
-	if !e.LintMode && e.clientProvider != nil {
-		funcMap["lookup"] = newLookupFunction(*e.clientProvider)
+	if e.LookupFixtures != nil {
+		funcMap["lookup"] = newFixtureLookupFunction(e.LookupFixtures)
+	} else if !e.LintMode && e.clientProvider != nil {
+		funcMap["lookup"] = newLookupCache(*e.clientProvider, e.LookupBudget, e.LookupConcurrency).lookup
 	}
 
 	// When DNS lookups are not enabled override the sprig function and return
@@ -244,6 +317,12 @@ func (e Engine) initFunMap(t *template.Template) {
 		}
 	}
 
+	if cache != nil {
+		for _, name := range mutatingFuncs {
+			funcMap[name] = cache.invalidating(funcMap[name])
+		}
+	}
+
 	t.Funcs(funcMap)
 }
 
@@ -261,6 +340,16 @@ func (e Engine) render(tpls map[string]renderable) (rendered map[string]string,
 			err = errors.Errorf("rendering template failed: %v", r)
 		}
 	}()
+	tpls, sources := splitEngines(tpls)
+	cueRendered, err := renderCUE(sources["cue"])
+	if err != nil {
+		return map[string]string{}, err
+	}
+	jsonnetRendered, err := renderJsonnet(sources["jsonnet"])
+	if err != nil {
+		return map[string]string{}, err
+	}
+
 	t := template.New("gotpl")
 	if e.Strict {
 		t.Option("missingkey=error")
@@ -276,37 +365,101 @@ func (e Engine) render(tpls map[string]renderable) (rendered map[string]string,
 	// higher-level (in file system) templates over deeply nested templates.
 	keys := sortTemplates(tpls)
 
+	var errs RenderErrors
+	unparsed := map[string]bool{}
+	libs := map[string]*library{}
 	for _, filename := range keys {
 		r := tpls[filename]
+		if r.namespace != "" {
+			lib, ok := libs[r.basePath]
+			if !ok {
+				lib = &library{chart: libraryChart(r.basePath), namespace: r.namespace, exports: r.exports}
+				libs[r.basePath] = lib
+			}
+			lib.files = append(lib.files, filename)
+			continue
+		}
 		if _, err := t.New(filename).Parse(r.tpl); err != nil {
-			return map[string]string{}, cleanupParseError(filename, err)
+			if !e.CollectErrors {
+				return map[string]string{}, cleanupParseError(filename, err)
+			}
+			errs = append(errs, newTemplateError(filename, err, true))
+			unparsed[filename] = true
 		}
 	}
 
-	rendered = make(map[string]string, len(keys))
+	if err := e.parseLibraries(t, tpls, libs); err != nil {
+		return map[string]string{}, err
+	}
+
+	// Don't render partials. We don't care out the direct output of partials.
+	// They are only included from other templates.
+	var files []string
 	for _, filename := range keys {
-		// Don't render partials. We don't care out the direct output of partials.
-		// They are only included from other templates.
-		if strings.HasPrefix(path.Base(filename), "_") {
-			continue
+		if !strings.HasPrefix(path.Base(filename), "_") && !unparsed[filename] {
+			files = append(files, filename)
 		}
-		// At render time, add information about the template that is being rendered.
-		vals := tpls[filename].vals
-		vals["Template"] = chartutil.Values{"Name": filename, "BasePath": tpls[filename].basePath}
-		var buf strings.Builder
-		if err := t.ExecuteTemplate(&buf, filename, vals); err != nil {
-			return map[string]string{}, cleanupExecError(filename, err)
+	}
+
+	var results []fileResult
+	if e.Parallel && !usesFuncs(t, e.serialFuncs()) {
+		if results, err = e.executeParallel(t, tpls, files); err != nil {
+			return map[string]string{}, err
+		}
+	} else {
+		results = make([]fileResult, len(files))
+		for i, filename := range files {
+			results[i] = executeFile(t, filename, tpls[filename], false)
+			if results[i].err != nil && !e.CollectErrors {
+				break
+			}
+		}
+	}
+
+	rendered = make(map[string]string, len(files)+len(cueRendered)+len(jsonnetRendered))
+	for filename, out := range cueRendered {
+		rendered[filename] = out
+	}
+	for filename, out := range jsonnetRendered {
+		rendered[filename] = out
+	}
+	for i, filename := range files {
+		if err := results[i].err; err != nil {
+			if !e.CollectErrors {
+				return map[string]string{}, cleanupExecError(filename, err)
+			}
+			errs = append(errs, e.executionErrors(t.Lookup(filename), err, tpls[filename].vals)...)
+			continue
 		}
 
 		// Work around the issue where Go will emit "<no value>" even if Options(missing=zero)
 		// is set. Since missing=error will never get here, we do not need to handle
 		// the Strict case.
-		rendered[filename] = strings.ReplaceAll(buf.String(), "<no value>", "")
+		rendered[filename] = strings.ReplaceAll(results[i].out, "<no value>", "")
 	}
 
+	if len(errs) > 0 {
+		return map[string]string{}, errs
+	}
+	if err := applyTransforms(sources["starlark"], rendered); err != nil {
+		return map[string]string{}, err
+	}
 	return rendered, nil
 }
 
+// executionErrors returns the error a template failed to execute with, along
+// with the errors of the references to values it did not reach.
+func (e Engine) executionErrors(t *template.Template, err error, vals chartutil.Values) []*TemplateError {
+	errs := []*TemplateError{newTemplateError(t.Name(), err, false)}
+	for _, refErr := range e.referenceErrors(t, vals) {
+		if refErr.Template == errs[0].Template && refErr.Line == errs[0].Line && refErr.Column == errs[0].Column {
+			continue
+		}
+		errs = append(errs, refErr)
+	}
+	return errs
+}
+
 func cleanupParseError(filename string, err error) error {
 	tokens := strings.Split(err.Error(), ": ")
 	if len(tokens) == 1 {
@@ -344,6 +497,24 @@ func cleanupExecError(filename string, err error) error {
 	return err
 }
 
+// splitEngines splits tpls into the Go templates and the sources of the
+// other engines, by engine.
+func splitEngines(tpls map[string]renderable) (map[string]renderable, map[string]map[string]renderable) {
+	gotpls := make(map[string]renderable, len(tpls))
+	sources := make(map[string]map[string]renderable)
+	for filename, r := range tpls {
+		if r.engine == "" {
+			gotpls[filename] = r
+			continue
+		}
+		if sources[r.engine] == nil {
+			sources[r.engine] = make(map[string]renderable)
+		}
+		sources[r.engine][filename] = r
+	}
+	return gotpls, sources
+}
+
 func sortTemplates(tpls map[string]renderable) []string {
 	keys := make([]string, len(tpls))
 	i := 0
@@ -409,7 +580,34 @@ func recAllTpls(c *chart.Chart, templates map[string]renderable, vals chartutil.
 		subCharts[child.Name()] = recAllTpls(child, templates, next)
 	}
 
+	var namespace string
+	if isLibraryChart(c) && len(c.Metadata.Exports) > 0 {
+		namespace = c.Name()
+	}
+
 	newParentID := c.ChartFullPath()
+	if c.Metadata.Engine == "cue" && !isLibraryChart(c) {
+		for _, f := range c.Files {
+			if f == nil || path.Dir(f.Name) != "cue" || path.Ext(f.Name) != ".cue" {
+				continue
+			}
+			templates[path.Join(newParentID, f.Name)] = renderable{
+				tpl:      string(f.Data),
+				vals:     next,
+				basePath: path.Join(newParentID, "cue"),
+				engine:   "cue",
+			}
+		}
+	}
+	if script := c.Metadata.Transform; script != "" && !isLibraryChart(c) {
+		r := renderable{vals: next, basePath: newParentID, engine: "starlark"}
+		for _, f := range c.Files {
+			if f != nil && f.Name == script {
+				r.tpl = string(f.Data)
+			}
+		}
+		templates[path.Join(newParentID, script)] = r
+	}
 	for _, t := range c.Templates {
 		if t == nil {
 			continue
@@ -417,11 +615,17 @@ func recAllTpls(c *chart.Chart, templates map[string]renderable, vals chartutil.
 		if !isTemplateValid(c, t.Name) {
 			continue
 		}
-		templates[path.Join(newParentID, t.Name)] = renderable{
-			tpl:      string(t.Data),
-			vals:     next,
-			basePath: path.Join(newParentID, "templates"),
+		r := renderable{
+			tpl:       string(t.Data),
+			vals:      next,
+			basePath:  path.Join(newParentID, "templates"),
+			namespace: namespace,
+			exports:   c.Metadata.Exports,
+		}
+		if c.Metadata.Engine == "jsonnet" && isJsonnetFile(t.Name) {
+			r.engine = "jsonnet"
 		}
+		templates[path.Join(newParentID, t.Name)] = r
 	}
 
 	return next
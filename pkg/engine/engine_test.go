@@ -17,8 +17,10 @@ limitations under the License.
 package engine
 
 import (
+	"errors"
 	"fmt"
 	"path"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -525,6 +527,71 @@ linebreak`,
 	}
 }
 
+func TestCollectErrors(t *testing.T) {
+	vals := chartutil.Values{"Values": map[string]interface{}{
+		"image":   "nginx",
+		"service": map[string]interface{}{"port": 80},
+	}}
+	tpls := map[string]renderable{
+		"mychart/templates/deployment.yaml": {vals: vals, tpl: `image: {{ .Values.image.tag }}
+replicas: {{ .Values.scaling.replicas }}
+{{- if .Values.ingress.enabled }}
+host: {{ .Values.ingress.host.name }}
+{{- end }}
+port: {{ $.Values.service.port }}
+targetPort: {{ .Values.service.target.port | default 8080 }}
+{{- if and .Values.missing .Values.missing.enabled }}{{ end }}
+`},
+		"mychart/templates/service.yaml": {vals: vals, tpl: `port: {{ .Values.service.port }}`},
+		"mychart/templates/broken.yaml":  {vals: vals, tpl: `{{ .Values.image`},
+		"mychart/templates/secret.yaml":  {vals: vals, tpl: `{{ required "password is required" .Values.password }}`},
+	}
+
+	_, err := Engine{CollectErrors: true}.render(tpls)
+	var errs RenderErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected render errors, got %v", err)
+	}
+	expected := RenderErrors{
+		{Template: "mychart/templates/broken.yaml", Line: 1, Parse: true, Message: "unclosed action"},
+		{Template: "mychart/templates/secret.yaml", Line: 1, Column: 3, Message: "password is required"},
+		{Template: "mychart/templates/deployment.yaml", Line: 1, Column: 17, Reference: ".Values.image.tag", Message: "can't evaluate field tag in type interface {}"},
+		{Template: "mychart/templates/deployment.yaml", Line: 2, Column: 20, Reference: ".Values.scaling.replicas", Message: "nil pointer evaluating interface {}.replicas"},
+		{Template: "mychart/templates/deployment.yaml", Line: 3, Column: 14, Reference: ".Values.ingress.enabled", Message: "nil pointer evaluating interface {}.enabled"},
+		{Template: "mychart/templates/deployment.yaml", Line: 7, Column: 22, Reference: ".Values.service.target.port", Message: "nil pointer evaluating interface {}.port"},
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Expected %q, got %q", expected, errs)
+	}
+	if msg := "execution error at (mychart/templates/deployment.yaml:2:20): nil pointer evaluating interface {}.replicas"; !strings.Contains(err.Error(), msg) {
+		t.Errorf("Expected %q to contain %q", err.Error(), msg)
+	}
+
+	_, err = Engine{CollectErrors: true, Strict: true}.render(map[string]renderable{
+		"strict": {vals: vals, tpl: `{{ .Values.service.name }}{{ .Values.image }}{{ .Values.tag }}`},
+	})
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected render errors, got %v", err)
+	}
+	expected = RenderErrors{
+		{Template: "strict", Line: 1, Column: 10, Reference: ".Values.service.name", Message: `map has no entry for key "name"`},
+		{Template: "strict", Line: 1, Column: 55, Reference: ".Values.tag", Message: `map has no entry for key "tag"`},
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Expected %q, got %q", expected, errs)
+	}
+
+	out, err := Engine{CollectErrors: true}.render(map[string]renderable{
+		"mychart/templates/service.yaml": tpls["mychart/templates/service.yaml"],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out["mychart/templates/service.yaml"]; got != "port: 80" {
+		t.Errorf("Expected %q, got %q", "port: 80", got)
+	}
+}
+
 func TestFailErrors(t *testing.T) {
 	vals := chartutil.Values{"Values": map[string]interface{}{}}
 
@@ -0,0 +1,202 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// TemplateError is an error of a template, at a location of the template.
+type TemplateError struct {
+	// Template is the path of the template, such as
+	// mychart/templates/deployment.yaml.
+	Template string
+	// Line and Column locate the error in the template. They are zero when
+	// the error is not located.
+	Line   int
+	Column int
+	// Reference is the reference to a value the error is about, such as
+	// .Values.image.tag. It is empty when the error is not about a value.
+	Reference string
+	// Parse is whether the template failed to parse, rather than to execute.
+	Parse   bool
+	Message string
+}
+
+func (e *TemplateError) Error() string {
+	kind := "execution"
+	if e.Parse {
+		kind = "parse"
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%s error in (%s): %s", kind, e.Template, e.Message)
+	}
+	location := fmt.Sprintf("%s:%d", e.Template, e.Line)
+	if e.Column > 0 {
+		location += fmt.Sprintf(":%d", e.Column)
+	}
+	return fmt.Sprintf("%s error at (%s): %s", kind, location, e.Message)
+}
+
+// RenderErrors are the errors of all the templates of a chart, which the
+// engine returns when it collects errors.
+type RenderErrors []*TemplateError
+
+func (e RenderErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// templateErrorPattern matches the errors of text/template, such as
+//
+//	template: mychart/templates/cm.yaml:3:12: executing "mychart/templates/cm.yaml" at <.Values.a.b>: nil pointer evaluating interface {}.b
+var templateErrorPattern = regexp.MustCompile(`(?s)^template: (.*?):(\d+)(?::(\d+))?: (?:executing "[^"]*" at <([^>]*)>: )?(.*)$`)
+
+// valueReferencePattern matches the references to values, such as
+// .Values.image.tag or $.Values.image.tag.
+var valueReferencePattern = regexp.MustCompile(`^\$?\.Values(\.[A-Za-z0-9_]+)*$`)
+
+// newTemplateError returns the TemplateError of an error returned when
+// parsing or executing a template.
+func newTemplateError(filename string, err error, parse bool) *TemplateError {
+	templateErr := &TemplateError{Template: filename, Parse: parse, Message: err.Error()}
+	parts := templateErrorPattern.FindStringSubmatch(err.Error())
+	if parts == nil {
+		return templateErr
+	}
+	templateErr.Template = parts[1]
+	templateErr.Line, _ = strconv.Atoi(parts[2])
+	templateErr.Column, _ = strconv.Atoi(parts[3])
+	if valueReferencePattern.MatchString(parts[4]) {
+		templateErr.Reference = parts[4]
+	}
+	templateErr.Message = parts[5]
+	if warn := warnRegex.FindStringSubmatch(parts[5]); len(warn) >= 2 {
+		templateErr.Message = warn[1]
+	}
+	return templateErr
+}
+
+// referenceErrors returns the errors of the references to values of a
+// template that are evaluated whatever the values are, which are those
+// outside of the bodies of conditions, loops and 'with' blocks. They are the
+// errors a failing execution of the template stops before reaching.
+func (e Engine) referenceErrors(t *template.Template, vals chartutil.Values) []*TemplateError {
+	if t == nil || t.Tree == nil {
+		return nil
+	}
+	var errs []*TemplateError
+	check := func(node parse.Node, ident []string) {
+		if len(ident) < 2 || ident[0] != "Values" {
+			return
+		}
+		message := e.missingValue(vals["Values"], ident[1:])
+		if message == "" {
+			return
+		}
+		location, _ := t.ErrorContext(node)
+		err := fmt.Errorf("template: %s: executing %q at <%s>: %s", location, t.Name(), node, message)
+		errs = append(errs, newTemplateError(t.Name(), err, false))
+	}
+
+	var walkPipe func(*parse.PipeNode)
+	walkArg := func(arg parse.Node) {
+		switch arg := arg.(type) {
+		case *parse.FieldNode:
+			check(arg, arg.Ident)
+		case *parse.VariableNode:
+			if arg.Ident[0] == "$" {
+				check(arg, arg.Ident[1:])
+			}
+		case *parse.PipeNode:
+			walkPipe(arg)
+		}
+	}
+	walkPipe = func(pipe *parse.PipeNode) {
+		if pipe == nil {
+			return
+		}
+		for _, cmd := range pipe.Cmds {
+			args := cmd.Args
+			// 'and' and 'or' stop evaluating their arguments once the
+			// result is known.
+			if ident, ok := args[0].(*parse.IdentifierNode); ok && (ident.Ident == "and" || ident.Ident == "or") && len(args) > 2 {
+				args = args[:2]
+			}
+			for _, arg := range args {
+				walkArg(arg)
+			}
+		}
+	}
+
+	for _, node := range t.Tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.ActionNode:
+			walkPipe(node.Pipe)
+		case *parse.IfNode:
+			walkPipe(node.Pipe)
+		case *parse.RangeNode:
+			walkPipe(node.Pipe)
+		case *parse.WithNode:
+			walkPipe(node.Pipe)
+		case *parse.TemplateNode:
+			walkPipe(node.Pipe)
+		}
+	}
+	return errs
+}
+
+// missingValue returns the error the evaluation of a path of values fails
+// with, as text/template reports it, or an empty string when it succeeds.
+func (e Engine) missingValue(value interface{}, path []string) string {
+	for _, key := range path {
+		if value == nil {
+			return fmt.Sprintf("nil pointer evaluating interface {}.%s", key)
+		}
+		if reflect.ValueOf(value).MethodByName(key).IsValid() {
+			// Methods, such as those of chartutil.Values, are not values.
+			return ""
+		}
+		var table map[string]interface{}
+		switch v := value.(type) {
+		case chartutil.Values:
+			table = v
+		case map[string]interface{}:
+			table = v
+		default:
+			// The values are decoded as interface{}, which text/template
+			// reports instead of their type.
+			return fmt.Sprintf("can't evaluate field %s in type interface {}", key)
+		}
+		value = table[key]
+		if _, ok := table[key]; !ok && e.Strict {
+			return fmt.Sprintf("map has no entry for key %q", key)
+		}
+	}
+	return ""
+}
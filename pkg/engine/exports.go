@@ -0,0 +1,185 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+)
+
+// library holds the templates of a library chart that declares exports.
+//
+// The templates the library defines are namespaced by its name or alias, so
+// that "common.labels" is included as "lib.common.labels" by the other charts,
+// and only its exports may be referenced from outside of it.
+type library struct {
+	chart     string
+	namespace string
+	exports   []string
+	files     []string
+}
+
+func (l *library) exported(name string) bool {
+	for _, export := range l.exports {
+		if l.namespace+"."+export == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLibraries parses the templates of the libraries into t under their
+// namespaced names, and verifies that the templates of t only reference the
+// templates the libraries export.
+func (e Engine) parseLibraries(t *template.Template, tpls map[string]renderable, libs map[string]*library) error {
+	paths := make([]string, 0, len(libs))
+	for p := range libs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// The library defining each namespaced template.
+	owned := map[string]*library{}
+	for _, p := range paths {
+		lib := libs[p]
+		lt := template.New(p)
+		e.initFunMap(lt)
+		for _, filename := range lib.files {
+			if _, err := lt.New(filename).Parse(tpls[filename].tpl); err != nil {
+				return cleanupParseError(filename, err)
+			}
+		}
+
+		defined := map[string]bool{}
+		for _, tpl := range lt.Templates() {
+			if tpl.Tree != nil && !slices.Contains(lib.files, tpl.Name()) {
+				defined[tpl.Name()] = true
+			}
+		}
+		for _, export := range lib.exports {
+			if !defined[export] {
+				return errors.Errorf("library chart %s exports %q, which it does not define", lib.chart, export)
+			}
+		}
+
+		rename := func(name string) string {
+			if defined[name] {
+				return lib.namespace + "." + name
+			}
+			return name
+		}
+		for _, tpl := range lt.Templates() {
+			if tpl.Tree == nil {
+				continue
+			}
+			renameTemplateRefs(tpl.Tree.Root, rename)
+			name := rename(tpl.Name())
+			tpl.Tree.Name = name
+			if existing := t.Lookup(name); existing != nil && existing.Tree != nil {
+				// The same library may be a dependency of several charts.
+				if other := owned[name]; other != nil && other.namespace == lib.namespace && existing.Tree.Root.String() == tpl.Tree.Root.String() {
+					continue
+				}
+				return errors.Errorf("template %q of library chart %s collides with another template of the same name", name, lib.chart)
+			}
+			if _, err := t.AddParseTree(name, tpl.Tree); err != nil {
+				return err
+			}
+			owned[name] = lib
+		}
+	}
+
+	for _, tpl := range t.Templates() {
+		if tpl.Tree == nil || owned[tpl.Name()] != nil {
+			continue
+		}
+		var err error
+		renameTemplateRefs(tpl.Tree.Root, func(name string) string {
+			if lib := owned[name]; lib != nil && !lib.exported(name) && err == nil {
+				err = errors.Errorf("template %s references %q, which library chart %s does not export", tpl.Name(), name, lib.chart)
+			}
+			return name
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameTemplateRefs renames the templates node references by name, with the
+// template action or the include function.
+func renameTemplateRefs(node parse.Node, rename func(string) string) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			renameTemplateRefs(n, rename)
+		}
+	case *parse.ActionNode:
+		renameTemplateRefs(node.Pipe, rename)
+	case *parse.IfNode:
+		renameTemplateRefs(node.Pipe, rename)
+		renameTemplateRefs(node.List, rename)
+		renameTemplateRefs(node.ElseList, rename)
+	case *parse.RangeNode:
+		renameTemplateRefs(node.Pipe, rename)
+		renameTemplateRefs(node.List, rename)
+		renameTemplateRefs(node.ElseList, rename)
+	case *parse.WithNode:
+		renameTemplateRefs(node.Pipe, rename)
+		renameTemplateRefs(node.List, rename)
+		renameTemplateRefs(node.ElseList, rename)
+	case *parse.TemplateNode:
+		node.Name = rename(node.Name)
+		renameTemplateRefs(node.Pipe, rename)
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, cmd := range node.Cmds {
+			renameTemplateRefs(cmd, rename)
+		}
+	case *parse.CommandNode:
+		if len(node.Args) > 1 {
+			if ident, ok := node.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "include" {
+				if name, ok := node.Args[1].(*parse.StringNode); ok {
+					name.Text = rename(name.Text)
+					name.Quoted = strconv.Quote(name.Text)
+				}
+			}
+		}
+		for _, arg := range node.Args {
+			renameTemplateRefs(arg, rename)
+		}
+	case *parse.ChainNode:
+		renameTemplateRefs(node.Node, rename)
+	}
+}
+
+// libraryChart returns the path of the chart of the templates in basePath.
+func libraryChart(basePath string) string {
+	return strings.TrimSuffix(basePath, "/templates")
+}
@@ -0,0 +1,116 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func newLibraryChart(name string, exports []string, helpers string) *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{Name: name, Type: "library", Exports: exports},
+		Templates: []*chart.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(helpers)},
+		},
+	}
+}
+
+func renderWithLibraries(t *testing.T, parentTemplate string, libs ...*chart.Chart) (map[string]string, error) {
+	t.Helper()
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app"},
+		Templates: []*chart.File{
+			{Name: "templates/labels.yaml", Data: []byte(parentTemplate)},
+		},
+	}
+	for _, lib := range libs {
+		c.AddDependency(lib)
+	}
+	vals, err := chartutil.ToRenderValues(c, map[string]interface{}{}, chartutil.ReleaseOptions{}, nil)
+	require.NoError(t, err)
+	return Render(c, vals)
+}
+
+func TestRenderLibraryExports(t *testing.T) {
+	liba := newLibraryChart("liba", []string{"common.labels"},
+		`{{- define "common.labels" }}owner: a{{ end }}`)
+	libb := newLibraryChart("libb", []string{"common.labels"},
+		`{{- define "common.name" }}b{{ end }}{{- define "common.labels" }}owner: {{ include "common.name" . }}{{ end }}`)
+
+	out, err := renderWithLibraries(t, `{{ include "liba.common.labels" . }}
+{{ template "libb.common.labels" . }}`, liba, libb)
+	require.NoError(t, err)
+	assert.Equal(t, "owner: a\nowner: b", out["app/templates/labels.yaml"])
+}
+
+func TestRenderLibraryExportsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		lib      *chart.Chart
+		expected string
+	}{
+		{
+			name:     "unexported template",
+			template: `{{ include "lib.common.name" . }}`,
+			lib: newLibraryChart("lib", []string{"common.labels"},
+				`{{- define "common.name" }}lib{{ end }}{{- define "common.labels" }}{{ end }}`),
+			expected: `template app/templates/labels.yaml references "lib.common.name", which library chart app/charts/lib does not export`,
+		},
+		{
+			name:     "undefined export",
+			template: ``,
+			lib:      newLibraryChart("lib", []string{"common.labels"}, `{{- define "common.name" }}lib{{ end }}`),
+			expected: `library chart app/charts/lib exports "common.labels", which it does not define`,
+		},
+		{
+			name:     "collision",
+			template: `{{- define "lib.common.labels" }}{{ end }}`,
+			lib:      newLibraryChart("lib", []string{"common.labels"}, `{{- define "common.labels" }}{{ end }}`),
+			expected: `template "lib.common.labels" of library chart app/charts/lib collides with another template of the same name`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := renderWithLibraries(t, tt.template, tt.lib)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func TestRenderSharedLibraryExports(t *testing.T) {
+	// The same library is a dependency of the chart and of its subchart.
+	helpers := `{{- define "common.labels" }}owner: lib{{ end }}`
+	sub := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "sub"},
+		Templates: []*chart.File{
+			{Name: "templates/labels.yaml", Data: []byte(`{{ include "lib.common.labels" . }}`)},
+		},
+	}
+	sub.AddDependency(newLibraryChart("lib", []string{"common.labels"}, helpers))
+
+	out, err := renderWithLibraries(t, `{{ include "lib.common.labels" . }}`, newLibraryChart("lib", []string{"common.labels"}, helpers), sub)
+	require.NoError(t, err)
+	assert.Equal(t, "owner: lib", out["app/templates/labels.yaml"])
+	assert.Equal(t, "owner: lib", out["app/charts/sub/templates/labels.yaml"])
+}
@@ -18,6 +18,7 @@ package engine
 
 import (
 	"encoding/base64"
+	"log/slog"
 	"path"
 	"strings"
 
@@ -31,10 +32,16 @@ type files map[string][]byte
 
 // NewFiles creates a new files from chart files.
 // Given an []*chart.File (the format for files in a chart.Chart), extract a map of files.
+//
+// Files loaded lazily are read here, once rendering starts.
 func newFiles(from []*chart.File) files {
 	files := make(map[string][]byte)
 	for _, f := range from {
-		files[f.Name] = f.Data
+		data, err := f.GetData()
+		if err != nil {
+			slog.Warn("unable to read chart file", "file", f.Name, slog.Any("error", err))
+		}
+		files[f.Name] = data
 	}
 	return files
 }
@@ -0,0 +1,140 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// mutatingFuncs are the functions that modify their arguments, which may
+// change the output of the templates included before.
+var mutatingFuncs = []string{"set", "unset", "merge", "mustMerge", "mergeOverwrite", "mustMergeOverwrite"}
+
+// includeCache caches the output of the calls to 'include' of a render,
+// by template name and arguments.
+//
+// The arguments are identified by their content at the top level, such as
+// the entries of the dict an 'include' is called with, and by their address
+// below it, such as the values of the chart. The cache is cleared whenever a
+// template modifies its arguments.
+type includeCache struct {
+	entries map[string]string
+	// args keeps the arguments of the cached calls, so that the addresses
+	// the keys are made of are not reused.
+	args []interface{}
+}
+
+func newIncludeCache() *includeCache {
+	return &includeCache{entries: map[string]string{}}
+}
+
+func (c *includeCache) get(name string, data interface{}) (string, string, bool) {
+	fingerprint, ok := argumentFingerprint(data, 1)
+	if !ok {
+		return "", "", false
+	}
+	key := name + "\x00" + fingerprint
+	out, ok := c.entries[key]
+	return key, out, ok
+}
+
+func (c *includeCache) put(key string, data interface{}, out string) {
+	c.entries[key] = out
+	c.args = append(c.args, data)
+}
+
+func (c *includeCache) reset() {
+	c.entries = map[string]string{}
+	c.args = nil
+}
+
+// invalidating returns a function that clears the cache before calling fn.
+func (c *includeCache) invalidating(fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	return reflect.MakeFunc(v.Type(), func(args []reflect.Value) []reflect.Value {
+		c.reset()
+		if v.Type().IsVariadic() {
+			return v.CallSlice(args)
+		}
+		return v.Call(args)
+	}).Interface()
+}
+
+// argumentFingerprint identifies an argument of 'include', by the content of
+// its maps and lists up to depth and by their address below it. It reports
+// false when the argument cannot be identified.
+func argumentFingerprint(v interface{}, depth int) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "nil", true
+	case string:
+		return fmt.Sprintf("%q", v), true
+	case bool, int, int64, float64:
+		return fmt.Sprintf("%T:%v", v, v), true
+	case chartutil.Values:
+		return mapFingerprint(v, depth)
+	case map[string]interface{}:
+		return mapFingerprint(v, depth)
+	case []interface{}:
+		if depth == 0 {
+			return fmt.Sprintf("list@%p:%d", v, len(v)), true
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			fingerprint, ok := argumentFingerprint(item, depth-1)
+			if !ok {
+				return "", false
+			}
+			items[i] = fingerprint
+		}
+		return "[" + strings.Join(items, ",") + "]", true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Pointer, reflect.Slice:
+		return fmt.Sprintf("%T@%p", v, v), true
+	case reflect.Struct:
+		return fmt.Sprintf("%T%+v", v, v), true
+	}
+	return "", false
+}
+
+func mapFingerprint(m map[string]interface{}, depth int) (string, bool) {
+	if depth == 0 {
+		return fmt.Sprintf("map@%p", m), true
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make([]string, len(keys))
+	for i, k := range keys {
+		fingerprint, ok := argumentFingerprint(m[k], depth-1)
+		if !ok {
+			return "", false
+		}
+		entries[i] = fmt.Sprintf("%q:%s", k, fingerprint)
+	}
+	return "{" + strings.Join(entries, ",") + "}", true
+}
@@ -0,0 +1,132 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func TestCacheIncludes(t *testing.T) {
+	calls := 0
+	registry := NewFuncRegistry()
+	require.NoError(t, registry.Register("test", "count", func() int {
+		calls++
+		return calls
+	}))
+
+	const helpers = `{{- define "labels" }}app: {{ .Values.name }} call: {{ test_count }}{{ end -}}
+{{- define "port" }}{{ .name }}: {{ .port }} call: {{ test_count }}{{ end -}}`
+	for _, test := range []struct {
+		name, tpl, cached, uncached string
+	}{
+		{
+			name:     "same context",
+			tpl:      `{{ include "labels" . }}|{{ include "labels" . }}|{{ include "labels" $ }}`,
+			cached:   "app: web call: 1|app: web call: 1|app: web call: 1",
+			uncached: "app: web call: 1|app: web call: 2|app: web call: 3",
+		},
+		{
+			name:     "dict arguments",
+			tpl:      `{{ include "port" (dict "name" "http" "port" 80) }}|{{ include "port" (dict "name" "http" "port" 80) }}|{{ include "port" (dict "name" "https" "port" 443) }}`,
+			cached:   "http: 80 call: 1|http: 80 call: 1|https: 443 call: 2",
+			uncached: "http: 80 call: 1|http: 80 call: 2|https: 443 call: 3",
+		},
+		{
+			name:     "modified values",
+			tpl:      `{{ include "labels" . }}|{{ $_ := set .Values "name" "api" }}{{ include "labels" . }}`,
+			cached:   "app: web call: 1|app: api call: 2",
+			uncached: "app: web call: 1|app: api call: 2",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			for _, cache := range []bool{true, false} {
+				calls = 0
+				tpls := map[string]renderable{
+					"_helpers.tpl": {tpl: helpers},
+					"out": {
+						tpl:  test.tpl,
+						vals: chartutil.Values{"Values": map[string]interface{}{"name": "web"}},
+					},
+				}
+				out, err := Engine{CacheIncludes: cache, Funcs: registry}.render(tpls)
+				require.NoError(t, err)
+				expected := test.uncached
+				if cache {
+					expected = test.cached
+				}
+				assert.Equal(t, expected, out["out"], "cache: %t", cache)
+			}
+		})
+	}
+}
+
+// BenchmarkCacheIncludes renders a chart whose templates include a costly
+// labels helper many times, as charts generated by 'helm create' do.
+func BenchmarkCacheIncludes(b *testing.B) {
+	const helpers = `{{- define "name" }}{{ default .Chart.Name .Values.nameOverride | trunc 63 | trimSuffix "-" }}{{ end -}}
+{{- define "selectorLabels" }}
+app.kubernetes.io/name: {{ include "name" . }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end -}}
+{{- define "labels" }}
+helm.sh/chart: {{ printf "%s-%s" .Chart.Name .Chart.Version | replace "+" "_" | trunc 63 | trimSuffix "-" }}
+{{- include "selectorLabels" . }}
+app.kubernetes.io/version: {{ .Chart.AppVersion | quote }}
+{{- with .Values.commonLabels }}
+{{ toYaml . }}
+{{- end }}
+{{- end -}}`
+	const tpl = `{{- range $i := until 20 }}
+---
+metadata:
+  name: {{ include "name" $ }}-{{ $i }}
+  labels:
+    {{- include "labels" $ | nindent 4 }}
+spec:
+  selector:
+    matchLabels:
+      {{- include "selectorLabels" $ | nindent 6 }}
+{{- end }}`
+	vals := chartutil.Values{
+		"Chart":   map[string]interface{}{"Name": "web", "Version": "1.0.0+build", "AppVersion": "2.3.4"},
+		"Release": map[string]interface{}{"Name": "prod"},
+		"Values": map[string]interface{}{
+			"commonLabels": map[string]interface{}{"team": "platform", "tier": "frontend"},
+		},
+	}
+	tpls := map[string]renderable{"_helpers.tpl": {tpl: helpers}}
+	for i := range 10 {
+		tpls[fmt.Sprintf("templates/%d.yaml", i)] = renderable{tpl: tpl, vals: vals}
+	}
+
+	for _, cache := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cache=%t", cache), func(b *testing.B) {
+			e := Engine{CacheIncludes: cache}
+			for range b.N {
+				if _, err := e.render(tpls); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// jsonnetExtVars are the render values bound to the external variables of
+// Jsonnet templates, such as std.extVar('Values').
+var jsonnetExtVars = []string{"Values", "Release", "Chart", "Capabilities"}
+
+// isJsonnetFile reports whether name is a Jsonnet template, or a Jsonnet
+// library only imported by templates.
+func isJsonnetFile(name string) bool {
+	ext := path.Ext(name)
+	return ext == ".jsonnet" || ext == ".libsonnet"
+}
+
+// renderJsonnet evaluates the .jsonnet templates of the charts using the
+// jsonnet engine, with their Values, Release, Chart and Capabilities bound to
+// the external variables of the same names. The templates can import the
+// other Jsonnet files of the templates, such as .libsonnet libraries, by
+// their path relative to the template, but nothing else.
+//
+// A template evaluates to a Kubernetes object, or to a list or an object of
+// Kubernetes objects, nested or not, which are rendered as YAML documents.
+// As with Go templates, templates starting with an underscore are skipped.
+func renderJsonnet(srcs map[string]renderable) (map[string]string, error) {
+	if len(srcs) == 0 {
+		return nil, nil
+	}
+	filenames := make([]string, 0, len(srcs))
+	for filename := range srcs {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	importer := jsonnetImporter(srcs)
+	rendered := make(map[string]string)
+	for _, filename := range filenames {
+		if path.Ext(filename) != ".jsonnet" || strings.HasPrefix(path.Base(filename), "_") {
+			continue
+		}
+		vm := jsonnet.MakeVM()
+		vm.Importer(importer)
+		for _, key := range jsonnetExtVars {
+			data, err := json.Marshal(srcs[filename].vals[key])
+			if err != nil {
+				return nil, errors.Wrapf(err, "encoding %s of Jsonnet template %s", key, filename)
+			}
+			vm.ExtCode(key, string(data))
+		}
+		out, err := vm.EvaluateFile(filename)
+		if err != nil {
+			return nil, errors.Errorf("rendering Jsonnet template %s: %s", filename, strings.TrimSpace(err.Error()))
+		}
+		docs, err := jsonnetDocuments(out)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rendering Jsonnet template %s", filename)
+		}
+		rendered[filename] = docs
+	}
+	return rendered, nil
+}
+
+// jsonnetImporter imports the Jsonnet files of a render by their path
+// relative to the importing file.
+type jsonnetImporter map[string]renderable
+
+func (i jsonnetImporter) Import(importedFrom, importedPath string) (jsonnet.Contents, string, error) {
+	name := importedPath
+	if importedFrom != "" {
+		name = path.Join(path.Dir(importedFrom), importedPath)
+	}
+	r, ok := i[name]
+	if !ok {
+		return jsonnet.Contents{}, "", fmt.Errorf("import not found: %s", importedPath)
+	}
+	return jsonnet.MakeContents(r.tpl), name, nil
+}
+
+// jsonnetDocuments returns the YAML documents of the Kubernetes objects of
+// the JSON output of a Jsonnet template.
+func jsonnetDocuments(out string) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		return "", err
+	}
+	var docs []string
+	var add func(v interface{}) error
+	add = func(v interface{}) error {
+		switch v := v.(type) {
+		case nil:
+			return nil
+		case []interface{}:
+			for _, e := range v {
+				if err := add(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		case map[string]interface{}:
+			if _, ok := v["kind"]; !ok {
+				keys := make([]string, 0, len(v))
+				for key := range v {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					if err := add(v[key]); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			doc, err := yaml.Marshal(v)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, string(doc))
+			return nil
+		default:
+			return errors.Errorf("%v is not a Kubernetes object", v)
+		}
+	}
+	if err := add(v); err != nil {
+		return "", err
+	}
+	return strings.Join(docs, "---\n"), nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func jsonnetTestChart(templates ...*chart.File) *chart.Chart {
+	return &chart.Chart{
+		Metadata:  &chart.Metadata{Name: "moby", Version: "1.2.3", Engine: "jsonnet"},
+		Templates: templates,
+	}
+}
+
+func TestRenderJsonnet(t *testing.T) {
+	c := jsonnetTestChart(
+		&chart.File{Name: "templates/lib/k.libsonnet", Data: []byte(`{
+  configMap(name, data):: { apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: name }, data: data },
+}`)},
+		&chart.File{Name: "templates/configmaps.jsonnet", Data: []byte(`local k = import 'lib/k.libsonnet';
+local values = std.extVar('Values');
+{
+  first: k.configMap(std.extVar('Release').Name, { chart: std.extVar('Chart').name }),
+  rest: [k.configMap(n, {}) for n in values.names],
+}`)},
+		&chart.File{Name: "templates/_skipped.jsonnet", Data: []byte(`error 'rendered'`)},
+		&chart.File{Name: "templates/NOTES.txt", Data: []byte(`{{ len .Values.names }} names`)},
+	)
+
+	out, err := Render(c, chartutil.Values{
+		"Values":  map[string]interface{}{"names": []interface{}{"a"}},
+		"Release": map[string]interface{}{"Name": "whale"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, `apiVersion: v1
+data:
+  chart: moby
+kind: ConfigMap
+metadata:
+  name: whale
+---
+apiVersion: v1
+data: {}
+kind: ConfigMap
+metadata:
+  name: a
+`, out["moby/templates/configmaps.jsonnet"])
+	assert.Equal(t, "1 names", out["moby/templates/NOTES.txt"])
+	assert.NotContains(t, out, "moby/templates/lib/k.libsonnet")
+	assert.NotContains(t, out, "moby/templates/_skipped.jsonnet")
+}
+
+func TestRenderJsonnetErrors(t *testing.T) {
+	for name, tpl := range map[string]string{
+		"error":     `error 'no ' + std.extVar('Values').what`,
+		"import":    `import '../../secrets.libsonnet'`,
+		"no object": `[1]`,
+	} {
+		c := jsonnetTestChart(&chart.File{Name: "templates/t.jsonnet", Data: []byte(tpl)})
+
+		_, err := Render(c, chartutil.Values{"Values": map[string]interface{}{"what": "way"}})
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), "rendering Jsonnet template moby/templates/t.jsonnet", name)
+	}
+}
+
+func TestRenderJsonnetOnlyForJsonnetCharts(t *testing.T) {
+	c := jsonnetTestChart(&chart.File{Name: "templates/t.jsonnet", Data: []byte(`{{ .Values.what }}`)})
+	c.Metadata.Engine = ""
+
+	out, err := Render(c, chartutil.Values{"Values": map[string]interface{}{"what": "way"}})
+	require.NoError(t, err)
+	assert.Equal(t, "way", out["moby/templates/t.jsonnet"])
+}
@@ -0,0 +1,129 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+// defaultLookupConcurrency is the number of 'lookup' requests made at once
+// when the engine does not set LookupConcurrency.
+const defaultLookupConcurrency = 4
+
+// lookupCache serves the 'lookup' calls of a render. The result of each
+// lookup, and the client of each kind, is requested once per render, so
+// that charts calling 'lookup' in loops or from helpers included many times
+// do not flood the API server.
+//
+// Calls for the same object made while it is being requested, as happens
+// when rendering in parallel, wait for that request.
+type lookupCache struct {
+	clients ClientProvider
+	// budget is the number of requests the render may make, or unlimited if 0.
+	budget int
+	// sem bounds the number of requests made at once.
+	sem chan struct{}
+
+	mu       sync.Mutex
+	requests int
+	results  map[string]*lookupResult
+}
+
+type lookupResult struct {
+	done chan struct{}
+	obj  map[string]interface{}
+	err  error
+}
+
+func newLookupCache(clients ClientProvider, budget, concurrency int) *lookupCache {
+	if concurrency <= 0 {
+		concurrency = defaultLookupConcurrency
+	}
+	return &lookupCache{
+		clients: &cachedClientProvider{provider: clients, clients: map[string]*cachedClient{}},
+		budget:  budget,
+		sem:     make(chan struct{}, concurrency),
+		results: map[string]*lookupResult{},
+	}
+}
+
+func (c *lookupCache) lookup(apiversion, kind, namespace, name string) (map[string]interface{}, error) {
+	key := strings.Join([]string{apiversion, kind, namespace, name}, "\x00")
+
+	c.mu.Lock()
+	r, ok := c.results[key]
+	if !ok {
+		if c.budget > 0 && c.requests >= c.budget {
+			c.mu.Unlock()
+			return map[string]interface{}{}, errors.Errorf("lookup of %s %q exceeds the budget of %d lookups per render", kind, name, c.budget)
+		}
+		c.requests++
+		r = &lookupResult{done: make(chan struct{})}
+		c.results[key] = r
+		c.mu.Unlock()
+
+		c.sem <- struct{}{}
+		r.obj, r.err = newLookupFunction(c.clients)(apiversion, kind, namespace, name)
+		<-c.sem
+		close(r.done)
+	} else {
+		c.mu.Unlock()
+		<-r.done
+	}
+
+	if r.err != nil {
+		return map[string]interface{}{}, r.err
+	}
+	// Templates may modify the objects they look up, such as with 'set'.
+	return runtime.DeepCopyJSON(r.obj), nil
+}
+
+// cachedClientProvider gets the client of each kind once, as getting a
+// client discovers the resources of the kind's group version.
+type cachedClientProvider struct {
+	provider ClientProvider
+	mu       sync.Mutex
+	clients  map[string]*cachedClient
+}
+
+type cachedClient struct {
+	once       sync.Once
+	client     dynamic.NamespaceableResourceInterface
+	namespaced bool
+	err        error
+}
+
+func (p *cachedClientProvider) GetClientFor(apiVersion, kind string) (dynamic.NamespaceableResourceInterface, bool, error) {
+	key := apiVersion + "\x00" + kind
+	p.mu.Lock()
+	c, ok := p.clients[key]
+	if !ok {
+		c = &cachedClient{}
+		p.clients[key] = c
+	}
+	p.mu.Unlock()
+
+	c.once.Do(func() {
+		c.client, c.namespaced, c.err = p.provider.GetClientFor(apiVersion, kind)
+	})
+	return c.client, c.namespaced, c.err
+}
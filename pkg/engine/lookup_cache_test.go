@@ -0,0 +1,94 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// countingClientProvider serves all kinds from one fake client, counting the
+// clients it gets.
+type countingClientProvider struct {
+	client     *fake.FakeDynamicClient
+	getClients int
+}
+
+func (p *countingClientProvider) GetClientFor(_, _ string) (dynamic.NamespaceableResourceInterface, bool, error) {
+	p.getClients++
+	return p.client.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}), true, nil
+}
+
+func TestLookupCache(t *testing.T) {
+	provider := &countingClientProvider{
+		client: fake.NewSimpleDynamicClient(runtime.NewScheme(),
+			makeUnstructured("v1", "ConfigMap", "settings", "default"),
+			makeUnstructured("v1", "ConfigMap", "other", "default"),
+		),
+	}
+	var clientProvider ClientProvider = provider
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Templates: []*chart.File{
+			{Name: "templates/loop", Data: []byte(`{{ range until 5 }}{{ (lookup "v1" "ConfigMap" "default" "settings").metadata.name }}{{ end }}`)},
+			// Changes to a looked up object are not seen by the next lookups.
+			{Name: "templates/modify", Data: []byte(`{{ $cm := lookup "v1" "ConfigMap" "default" "settings" }}{{ $_ := set $cm "kind" "Changed" }}{{ (lookup "v1" "ConfigMap" "default" "settings").kind }}`)},
+		},
+	}
+	out, err := Engine{clientProvider: &clientProvider}.Render(c, map[string]interface{}{"Values": map[string]interface{}{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["moby/templates/loop"] != strings.Repeat("settings", 5) {
+		t.Errorf("unexpected output %q", out["moby/templates/loop"])
+	}
+	if out["moby/templates/modify"] != "ConfigMap" {
+		t.Errorf("expected the cached object to be unchanged, got %q", out["moby/templates/modify"])
+	}
+	if provider.getClients != 1 {
+		t.Errorf("expected the client to be got once, got %d", provider.getClients)
+	}
+	if n := len(provider.client.Actions()); n != 1 {
+		t.Errorf("expected one request, got %d", n)
+	}
+
+	// Each render has its own cache.
+	provider.client.ClearActions()
+	c.Templates = []*chart.File{
+		{Name: "templates/budget", Data: []byte(`{{ lookup "v1" "ConfigMap" "default" "settings" }}{{ lookup "v1" "ConfigMap" "default" "settings" }}{{ lookup "v1" "ConfigMap" "default" "other" }}`)},
+	}
+	_, err = Engine{clientProvider: &clientProvider, LookupBudget: 2}.Render(c, map[string]interface{}{"Values": map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("expected the repeated lookup to be within the budget, got %s", err)
+	}
+	if n := len(provider.client.Actions()); n != 2 {
+		t.Errorf("expected two requests, got %d", n)
+	}
+
+	_, err = Engine{clientProvider: &clientProvider, LookupBudget: 1}.Render(c, map[string]interface{}{"Values": map[string]interface{}{}})
+	if err == nil || !strings.Contains(err.Error(), `lookup of ConfigMap "other" exceeds the budget of 1 lookups per render`) {
+		t.Errorf("expected the budget to be exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// LoadLookupFixtures reads the objects of a YAML or JSON file for the
+// 'lookup' function to find instead of querying a cluster. The file holds
+// Kubernetes objects, one per YAML document, or lists of them such as the
+// output of 'kubectl get -o yaml'.
+func LoadLookupFixtures(path string) ([]*unstructured.Unstructured, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var objs []*unstructured.Unstructured
+	r := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading lookup fixtures %s", path)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		jsonDoc, err := yaml.ToJSON(doc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing lookup fixtures %s", path)
+		}
+		if string(jsonDoc) == "null" {
+			continue
+		}
+		obj, err := runtime.Decode(unstructured.UnstructuredJSONScheme, jsonDoc)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing lookup fixtures %s", path)
+		}
+		switch obj := obj.(type) {
+		case *unstructured.Unstructured:
+			objs = append(objs, obj)
+		case *unstructured.UnstructuredList:
+			for i := range obj.Items {
+				objs = append(objs, &obj.Items[i])
+			}
+		}
+	}
+	for _, obj := range objs {
+		if obj.GetName() == "" {
+			return nil, errors.Errorf("lookup fixture of kind %s in %s has no name", obj.GetKind(), path)
+		}
+	}
+	return objs, nil
+}
+
+// newFixtureLookupFunction returns a lookup function that finds the given
+// objects the way the lookup function finds the objects of a cluster.
+func newFixtureLookupFunction(objs []*unstructured.Unstructured) lookupFunc {
+	return func(apiversion string, kind string, namespace string, name string) (map[string]interface{}, error) {
+		items := []interface{}{}
+		for _, obj := range objs {
+			if obj.GetAPIVersion() != apiversion || obj.GetKind() != kind {
+				continue
+			}
+			if name != "" {
+				if obj.GetName() == name && obj.GetNamespace() == namespace {
+					return runtime.DeepCopyJSON(obj.Object), nil
+				}
+				continue
+			}
+			// Listing without a namespace lists all the namespaces.
+			if namespace == "" || obj.GetNamespace() == namespace {
+				items = append(items, runtime.DeepCopyJSONValue(obj.Object))
+			}
+		}
+		if name != "" {
+			return map[string]interface{}{}, nil
+		}
+		return map[string]interface{}{
+			"apiVersion": apiversion,
+			"kind":       kind + "List",
+			"metadata":   map[string]interface{}{},
+			"items":      items,
+		}, nil
+	}
+}
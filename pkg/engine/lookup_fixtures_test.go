@@ -0,0 +1,100 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testFixtures = `apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+  namespace: default
+data:
+  password: c3dvcmRmaXNo
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: default
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: kube-system
+`
+
+func TestFixtureLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(path, []byte(testFixtures), 0644); err != nil {
+		t.Fatal(err)
+	}
+	objs, err := LoadLookupFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 3 {
+		t.Fatalf("expected 3 fixtures, got %d", len(objs))
+	}
+	lookup := newFixtureLookupFunction(objs)
+
+	secret, err := lookup("v1", "Secret", "default", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret["data"].(map[string]interface{})["password"] != "c3dvcmRmaXNo" {
+		t.Errorf("unexpected secret %v", secret)
+	}
+
+	missing, err := lookup("v1", "Secret", "other", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("expected no object from another namespace, got %v", missing)
+	}
+
+	list, err := lookup("v1", "Namespace", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list["kind"] != "NamespaceList" || len(list["items"].([]interface{})) != 2 {
+		t.Errorf("unexpected list %v", list)
+	}
+
+	// Objects handed to templates must not alias the fixtures.
+	secret["data"].(map[string]interface{})["password"] = "changed"
+	again, _ := lookup("v1", "Secret", "default", "credentials")
+	if again["data"].(map[string]interface{})["password"] != "c3dvcmRmaXNo" {
+		t.Error("lookup returned a fixture that templates can modify")
+	}
+}
+
+func TestLoadLookupFixturesWithoutName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := os.WriteFile(path, []byte("apiVersion: v1\nkind: Secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadLookupFixtures(path); err == nil {
+		t.Error("expected an error for a fixture without a name")
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/mitchellh/copystructure"
+	"github.com/pkg/errors"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// fileResult is the result of the execution of a template file.
+type fileResult struct {
+	out string
+	err error
+}
+
+// executeFile executes a template file. With copyVals, the template is given
+// a copy of its values, so that files sharing their values can be executed
+// at the same time.
+func executeFile(t *template.Template, filename string, r renderable, copyVals bool) fileResult {
+	vals := r.vals
+	if copyVals {
+		var err error
+		if vals, err = copyValues(r.vals); err != nil {
+			return fileResult{err: err}
+		}
+	}
+	// At render time, add information about the template that is being rendered.
+	vals["Template"] = chartutil.Values{"Name": filename, "BasePath": r.basePath}
+	var buf strings.Builder
+	err := t.ExecuteTemplate(&buf, filename, vals)
+	return fileResult{out: buf.String(), err: err}
+}
+
+// copyValues copies the values of a template file. The maps among them, such
+// as .Values and .Release, are copied deeply, so that a file writing to them
+// never writes to a map another file is reading at the same time, which the
+// runtime would abort on.
+func copyValues(vals chartutil.Values) (chartutil.Values, error) {
+	copied := make(chartutil.Values, len(vals)+1)
+	for k, v := range vals {
+		switch v.(type) {
+		case chartutil.Values, map[string]interface{}:
+			c, err := copystructure.Copy(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot copy .%s", k)
+			}
+			v = c
+		}
+		copied[k] = v
+	}
+	return copied, nil
+}
+
+// executeParallel executes template files concurrently, and returns their
+// results in the order of the files.
+//
+// Each worker executes the files with its own clone of the templates, as the
+// functions of the engine, such as 'include', keep state during a render.
+func (e Engine) executeParallel(t *template.Template, tpls map[string]renderable, files []string) ([]fileResult, error) {
+	workers := min(runtime.GOMAXPROCS(0), len(files))
+	clones := make([]*template.Template, workers)
+	for i := range clones {
+		clone, err := t.Clone()
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot clone template")
+		}
+		e.initFunMap(clone)
+		clones[i] = clone
+	}
+
+	results := make([]fileResult, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for _, clone := range clones {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = executeFileRecovering(clone, files[i], tpls[files[i]])
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results, nil
+}
+
+// executeFileRecovering executes a template file with a copy of its values,
+// and returns the panics of the execution as errors, as render does for the
+// files it executes itself.
+func executeFileRecovering(t *template.Template, filename string, r renderable) (result fileResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = fileResult{err: errors.Errorf("rendering template failed: %v", r)}
+		}
+	}()
+	return executeFile(t, filename, r, true)
+}
+
+// usesFuncs reports whether a template calls one of the given functions.
+func usesFuncs(t *template.Template, names []string) bool {
+	var calls func(parse.Node) bool
+	calls = func(node parse.Node) bool {
+		switch node := node.(type) {
+		case *parse.ListNode:
+			if node == nil {
+				return false
+			}
+			return slices.ContainsFunc(node.Nodes, calls)
+		case *parse.ActionNode:
+			return calls(node.Pipe)
+		case *parse.IfNode:
+			return calls(node.Pipe) || calls(node.List) || calls(node.ElseList)
+		case *parse.RangeNode:
+			return calls(node.Pipe) || calls(node.List) || calls(node.ElseList)
+		case *parse.WithNode:
+			return calls(node.Pipe) || calls(node.List) || calls(node.ElseList)
+		case *parse.TemplateNode:
+			return calls(node.Pipe)
+		case *parse.PipeNode:
+			if node == nil {
+				return false
+			}
+			for _, cmd := range node.Cmds {
+				if slices.ContainsFunc(cmd.Args, calls) {
+					return true
+				}
+			}
+		case *parse.ChainNode:
+			return calls(node.Node)
+		case *parse.IdentifierNode:
+			return slices.Contains(names, node.Ident)
+		}
+		return false
+	}
+	for _, tpl := range t.Templates() {
+		if tpl.Tree != nil && calls(tpl.Tree.Root) {
+			return true
+		}
+	}
+	return false
+}
+
+// serialFuncs are the functions whose calls make the files of a template be
+// rendered one at a time: the functions that modify their arguments, as the
+// files share their values, and the functions whose effects cannot be told
+// from the parsed templates, which are 'tpl' and the functions of the
+// registry.
+func (e Engine) serialFuncs() []string {
+	registry := e.Funcs
+	if registry == nil {
+		registry = DefaultFuncRegistry
+	}
+	names := append(slices.Clone(mutatingFuncs), "tpl")
+	for name := range registry.FuncMap() {
+		names = append(names, name)
+	}
+	return names
+}
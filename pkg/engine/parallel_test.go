@@ -0,0 +1,142 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func parallelTestChart(templates map[string]string) *chart.Chart {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3"},
+		Values:   map[string]interface{}{},
+	}
+	c.Templates = append(c.Templates, &chart.File{
+		Name: "templates/_helpers.tpl",
+		Data: []byte(`{{ define "labels" }}app: {{ .Chart.Name }}{{ end }}`),
+	})
+	for name, data := range templates {
+		c.Templates = append(c.Templates, &chart.File{Name: name, Data: []byte(data)})
+	}
+	return c
+}
+
+func TestParallelRender(t *testing.T) {
+	templates := map[string]string{}
+	for i := 0; i < 50; i++ {
+		templates[fmt.Sprintf("templates/cm%d.yaml", i)] = fmt.Sprintf(`name: cm%d
+template: {{ .Template.Name }}
+{{ include "labels" . }}
+replicas: {{ .Values.replicas }}`, i)
+	}
+	c := parallelTestChart(templates)
+	vals := chartutil.Values{"Values": map[string]interface{}{"replicas": 3}}
+
+	sequential, err := Engine{}.Render(c, vals)
+	require.NoError(t, err)
+	parallel, err := Engine{Parallel: true, CacheIncludes: true}.Render(c, vals)
+	require.NoError(t, err)
+	assert.Equal(t, sequential, parallel)
+	assert.Equal(t, "name: cm7\ntemplate: moby/templates/cm7.yaml\napp: moby\nreplicas: 3", parallel["moby/templates/cm7.yaml"])
+}
+
+func TestParallelRenderErrors(t *testing.T) {
+	c := parallelTestChart(map[string]string{
+		"templates/a.yaml": `{{ fail "first" }}`,
+		"templates/b.yaml": `{{ fail "second" }}`,
+		"templates/c.yaml": `fine: true`,
+	})
+	vals := chartutil.Values{"Values": map[string]interface{}{}}
+
+	// The files are rendered, and their errors reported, in the order of
+	// sortTemplates.
+	_, err := Engine{Parallel: true}.Render(c, vals)
+	assert.EqualError(t, err, "execution error at (moby/templates/b.yaml:1:3): second")
+
+	_, err = Engine{Parallel: true, CollectErrors: true}.Render(c, vals)
+	assert.EqualError(t, err, "execution error at (moby/templates/b.yaml:1:3): second\nexecution error at (moby/templates/a.yaml:1:3): first")
+}
+
+func TestParallelRenderMutatingTemplates(t *testing.T) {
+	c := parallelTestChart(map[string]string{
+		"templates/b.yaml": `{{ $_ := set .Values "seen" "b" }}b`,
+		"templates/a.yaml": `seen: {{ .Values.seen }}`,
+	})
+	assert.True(t, usesFuncs(parseTemplates(t, c), Engine{}.serialFuncs()))
+
+	out, err := Engine{Parallel: true}.Render(c, chartutil.Values{"Values": map[string]interface{}{}})
+	require.NoError(t, err)
+	assert.Equal(t, "seen: b", out["moby/templates/a.yaml"])
+}
+
+func TestParallelRenderSerialFuncs(t *testing.T) {
+	registry := NewFuncRegistry()
+	require.NoError(t, registry.Register("test", "touch", func(vals map[string]interface{}) string {
+		vals["touched"] = true
+		return ""
+	}))
+	for name, tpl := range map[string]string{
+		"tpl":      `{{ tpl "{{ $_ := set .Values \"seen\" \"b\" }}" . }}b`,
+		"registry": `{{ test_touch .Values }}b`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := parallelTestChart(map[string]string{"templates/b.yaml": tpl})
+			assert.True(t, usesFuncs(parseTemplates(t, c, registry.FuncMap()), Engine{Funcs: registry}.serialFuncs()))
+
+			out, err := Engine{Parallel: true, Funcs: registry}.Render(c, chartutil.Values{"Values": map[string]interface{}{}})
+			require.NoError(t, err)
+			assert.Equal(t, "b", out["moby/templates/b.yaml"])
+		})
+	}
+}
+
+func TestCopyValues(t *testing.T) {
+	vals := chartutil.Values{
+		"Values":  chartutil.Values{"nested": map[string]interface{}{"name": "web"}},
+		"Release": map[string]interface{}{"Name": "prod"},
+		"Chart":   &chart.Metadata{Name: "moby"},
+	}
+	copied, err := copyValues(vals)
+	require.NoError(t, err)
+	copied["Values"].(chartutil.Values)["nested"].(map[string]interface{})["name"] = "api"
+	copied["Release"].(map[string]interface{})["Name"] = "dev"
+
+	assert.Equal(t, "web", vals["Values"].(chartutil.Values)["nested"].(map[string]interface{})["name"])
+	assert.Equal(t, "prod", vals["Release"].(map[string]interface{})["Name"])
+	assert.Same(t, vals["Chart"], copied["Chart"])
+}
+
+func parseTemplates(t *testing.T, c *chart.Chart, funcs ...template.FuncMap) *template.Template {
+	t.Helper()
+	tpl := template.New("gotpl").Funcs(funcMap())
+	for _, f := range funcs {
+		tpl.Funcs(f)
+	}
+	for _, f := range c.Templates {
+		_, err := tpl.New(f.Name).Parse(string(f.Data))
+		require.NoError(t, err)
+	}
+	return tpl
+}
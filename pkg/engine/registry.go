@@ -0,0 +1,126 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// funcNamePattern matches the namespaces and names of registered functions,
+// which are joined into the name of the function in templates.
+var funcNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// FuncRegistry is a set of template functions added to the functions of the
+// engine, such as the helpers a platform provides to the charts it deploys.
+//
+// Functions are registered under a namespace, and are called in templates by
+// their namespace and name joined by an underscore, as in
+// {{ acme_teamOwner .Release.Namespace }}.
+type FuncRegistry struct {
+	mu    sync.RWMutex
+	funcs template.FuncMap
+}
+
+// DefaultFuncRegistry is the registry of the engines that do not set one.
+var DefaultFuncRegistry = NewFuncRegistry()
+
+// NewFuncRegistry creates an empty registry.
+func NewFuncRegistry() *FuncRegistry {
+	return &FuncRegistry{funcs: template.FuncMap{}}
+}
+
+// RegisterFunc registers a function in DefaultFuncRegistry.
+func RegisterFunc(namespace, name string, fn interface{}) error {
+	return DefaultFuncRegistry.Register(namespace, name, fn)
+}
+
+// Register adds a function to the registry, under a namespace.
+//
+// The function must be usable by text/template: it returns a single value, or
+// a value and an error. It is an error to register a function under a name
+// that is already registered or that is the name of a function of the engine.
+func (r *FuncRegistry) Register(namespace, name string, fn interface{}) error {
+	if !funcNamePattern.MatchString(namespace) {
+		return errors.Errorf("invalid namespace %q: namespaces are made of letters and digits, and start with a letter", namespace)
+	}
+	if !funcNamePattern.MatchString(name) {
+		return errors.Errorf("invalid function name %q: names are made of letters and digits, and start with a letter", name)
+	}
+	if err := validateFunc(fn); err != nil {
+		return errors.Wrapf(err, "invalid function %s_%s", namespace, name)
+	}
+
+	fullName := namespace + "_" + name
+	if _, ok := funcMap()[fullName]; ok {
+		return errors.Errorf("function %s is already provided by the engine", fullName)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.funcs[fullName]; ok {
+		return errors.Errorf("function %s is already registered", fullName)
+	}
+	r.funcs[fullName] = fn
+	return nil
+}
+
+// Names returns the names of the registered functions in templates, sorted.
+func (r *FuncRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FuncMap returns the registered functions by their names in templates.
+func (r *FuncRegistry) FuncMap() template.FuncMap {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	funcs := make(template.FuncMap, len(r.funcs))
+	for name, fn := range r.funcs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// validateFunc returns an error when text/template cannot call a function.
+func validateFunc(fn interface{}) error {
+	t := reflect.TypeOf(fn)
+	if t == nil || t.Kind() != reflect.Func {
+		return fmt.Errorf("%T is not a function", fn)
+	}
+	switch {
+	case t.NumOut() == 1:
+		return nil
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+		return nil
+	}
+	return errors.New("functions return a single value, or a value and an error")
+}
@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func TestFuncRegistry(t *testing.T) {
+	registry := NewFuncRegistry()
+	require.NoError(t, registry.Register("acme", "teamOwner", func(namespace string) string {
+		return "team-" + namespace
+	}))
+	require.NoError(t, registry.Register("acme", "upper", func(s string) (string, error) {
+		return strings.ToUpper(s), nil
+	}))
+	assert.Equal(t, []string{"acme_teamOwner", "acme_upper"}, registry.Names())
+
+	for _, test := range []struct {
+		namespace, name string
+		fn              interface{}
+		err             string
+	}{
+		{"acme", "teamOwner", func() string { return "" }, "function acme_teamOwner is already registered"},
+		{"acme.io", "owner", func() string { return "" }, `invalid namespace "acme.io"`},
+		{"acme", "team_owner", func() string { return "" }, `invalid function name "team_owner"`},
+		{"acme", "owner", "owner", "string is not a function"},
+		{"acme", "owner", func() (string, string) { return "", "" }, "functions return a single value, or a value and an error"},
+		{"acme", "owner", func() {}, "functions return a single value, or a value and an error"},
+	} {
+		assert.ErrorContains(t, registry.Register(test.namespace, test.name, test.fn), test.err, "%s_%s", test.namespace, test.name)
+	}
+
+	tpls := map[string]renderable{
+		"owner": {
+			tpl:  `{{ acme_teamOwner .Values.namespace | acme_upper }}`,
+			vals: chartutil.Values{"Values": map[string]interface{}{"namespace": "payments"}},
+		},
+	}
+	out, err := Engine{Funcs: registry}.render(tpls)
+	require.NoError(t, err)
+	assert.Equal(t, "TEAM-PAYMENTS", out["owner"])
+
+	_, err = new(Engine).render(tpls)
+	assert.ErrorContains(t, err, `function "acme_teamOwner" not defined`)
+}
@@ -0,0 +1,334 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.starlark.net/starlark"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// transformMaxSteps bounds the execution steps of transform scripts, so that
+// a script looping forever fails instead of blocking the render.
+const transformMaxSteps = 10_000_000
+
+// transformGlobals are the render values that are the global variables of
+// transform scripts, by name in scripts.
+var transformGlobals = []struct {
+	name string
+	key  string
+}{
+	{"values", "Values"},
+	{"release", "Release"},
+	{"chart", "Chart"},
+	{"capabilities", "Capabilities"},
+}
+
+// applyTransforms runs the transform scripts of the charts declaring one on
+// the objects rendered from the chart and its subcharts, the scripts of
+// subcharts first.
+//
+// A script is a Starlark file defining a transform function, called with
+// the list of the objects as dicts. The function returns the list of the
+// objects to render, or None when it only modified the objects in place.
+// The values, release, chart and capabilities globals of the script hold
+// the Values, Release, Chart and Capabilities of the chart, read-only. The
+// fields of chart are those of Chart.yaml, and the fields of release and
+// capabilities those of the Go templates.
+//
+// The objects are rendered back in their template, in the order of the
+// returned list. The objects the script adds are rendered in a file named
+// after the script. Scripts run sandboxed: they cannot load modules or read
+// anything but their globals, and their execution steps are bounded.
+func applyTransforms(scripts map[string]renderable, rendered map[string]string) error {
+	names := make([]string, 0, len(scripts))
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		di, dj := strings.Count(names[i], "/charts/"), strings.Count(names[j], "/charts/")
+		if di != dj {
+			return di > dj
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		if err := applyTransform(name, scripts[name], rendered); err != nil {
+			return errors.Wrapf(err, "transform script %s", name)
+		}
+	}
+	return nil
+}
+
+func applyTransform(name string, script renderable, rendered map[string]string) error {
+	if script.tpl == "" {
+		return errors.New("not found in the chart files")
+	}
+
+	var files []string
+	for filename := range rendered {
+		if strings.HasPrefix(filename, script.basePath+"/") && !strings.HasSuffix(filename, "NOTES.txt") {
+			files = append(files, filename)
+		}
+	}
+	sort.Strings(files)
+
+	sources := make(map[*starlark.Dict]string)
+	var objects []starlark.Value
+	for _, filename := range files {
+		objs, err := starlarkObjects(rendered[filename])
+		if err != nil {
+			return errors.Wrapf(err, "parsing %s", filename)
+		}
+		for _, obj := range objs {
+			sources[obj] = filename
+			objects = append(objects, obj)
+		}
+	}
+
+	predeclared := make(starlark.StringDict, len(transformGlobals))
+	for _, g := range transformGlobals {
+		v, err := starlarkJSON(script.vals[g.key])
+		if err != nil {
+			return errors.Wrapf(err, "converting %s", g.name)
+		}
+		v.Freeze()
+		predeclared[g.name] = v
+	}
+
+	thread := &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, msg string) {
+			slog.Debug("transform script", "script", name, "message", msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(transformMaxSteps)
+	globals, err := starlark.ExecFile(thread, name, script.tpl, predeclared)
+	if err != nil {
+		return starlarkError(err)
+	}
+	transform, ok := globals["transform"].(starlark.Callable)
+	if !ok {
+		return errors.New("no transform function is defined")
+	}
+	list := starlark.NewList(objects)
+	result, err := starlark.Call(thread, transform, starlark.Tuple{list}, nil)
+	if err != nil {
+		return starlarkError(err)
+	}
+	if result == starlark.None {
+		result = list
+	}
+	iterable, ok := result.(starlark.Iterable)
+	if !ok {
+		return errors.Errorf("transform returned %s, not a list of objects", result.Type())
+	}
+
+	docs := make(map[string][]string)
+	it := iterable.Iterate()
+	defer it.Done()
+	var v starlark.Value
+	for it.Next(&v) {
+		obj, ok := v.(*starlark.Dict)
+		if !ok {
+			return errors.Errorf("transform returned %s, not an object", v.Type())
+		}
+		filename, ok := sources[obj]
+		if !ok {
+			filename = name
+		}
+		goObj, err := fromStarlark(obj)
+		if err != nil {
+			return err
+		}
+		doc, err := yaml.Marshal(goObj)
+		if err != nil {
+			return err
+		}
+		docs[filename] = append(docs[filename], string(doc))
+	}
+	for _, filename := range files {
+		rendered[filename] = strings.Join(docs[filename], "---\n")
+	}
+	if added := docs[name]; len(added) > 0 {
+		rendered[name] = strings.Join(added, "---\n")
+	}
+	return nil
+}
+
+// starlarkObjects returns the objects of the YAML documents of a manifest.
+func starlarkObjects(manifest string) ([]*starlark.Dict, error) {
+	var objs []*starlark.Dict
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(manifest)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		if string(bytes.TrimSpace(data)) == "null" {
+			continue
+		}
+		v, err := starlarkFromJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := v.(*starlark.Dict)
+		if !ok {
+			return nil, errors.Errorf("%s is not an object", v.Type())
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// starlarkJSON converts v to Starlark through its JSON encoding.
+func starlarkJSON(v interface{}) (starlark.Value, error) {
+	if vals, ok := v.(chartutil.Values); ok {
+		v = map[string]interface{}(vals)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return starlarkFromJSON(data)
+}
+
+func starlarkFromJSON(data []byte) (starlark.Value, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return toStarlark(v)
+}
+
+func toStarlark(v interface{}) (starlark.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case string:
+		return starlark.String(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return starlark.MakeInt64(i), nil
+		}
+		f, err := v.Float64()
+		return starlark.Float(f), err
+	case []interface{}:
+		elems := make([]starlark.Value, len(v))
+		for i, e := range v {
+			var err error
+			if elems[i], err = toStarlark(e); err != nil {
+				return nil, err
+			}
+		}
+		return starlark.NewList(elems), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, key := range keys {
+			e, err := toStarlark(v[key])
+			if err != nil {
+				return nil, err
+			}
+			if err := dict.SetKey(starlark.String(key), e); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to Starlark", v)
+}
+
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return nil, errors.Errorf("integer %s is out of range", v)
+		}
+		return i, nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.Indexable:
+		// lists and tuples
+		elems := make([]interface{}, v.Len())
+		for i := range elems {
+			var err error
+			if elems[i], err = fromStarlark(v.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return elems, nil
+	case *starlark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, errors.Errorf("object keys must be strings, not %s", item[0].Type())
+			}
+			e, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[string(key)] = e
+		}
+		return m, nil
+	}
+	return nil, errors.Errorf("cannot convert %s to YAML", v.Type())
+}
+
+// starlarkError returns err with the backtrace of the script, if any.
+func starlarkError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func transformTestChart(script string) *chart.Chart {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "moby", Version: "1.2.3", Transform: "transform.star"},
+		Templates: []*chart.File{
+			{Name: "templates/deployment.yaml", Data: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: {{ .Release.Name }}\nspec:\n  replicas: {{ .Values.replicas }}\n")},
+			{Name: "templates/configmaps.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n")},
+			{Name: "templates/NOTES.txt", Data: []byte("not an object")},
+		},
+		Files: []*chart.File{
+			{Name: "transform.star", Data: []byte(script)},
+		},
+	}
+	c.AddDependency(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "sub", Version: "1.0.0", Transform: "transform.star"},
+		Templates: []*chart.File{
+			{Name: "templates/service.yaml", Data: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: svc\n")},
+		},
+		Files: []*chart.File{
+			{Name: "transform.star", Data: []byte(`
+def transform(objects):
+    for obj in objects:
+        obj["metadata"]["labels"] = {"sub": chart["name"]}
+`)},
+		},
+	})
+	return c
+}
+
+func transformTestValues() chartutil.Values {
+	return chartutil.Values{
+		"Values":  map[string]interface{}{"replicas": 2, "sub": map[string]interface{}{}},
+		"Release": map[string]interface{}{"Name": "whale"},
+	}
+}
+
+func TestRenderTransform(t *testing.T) {
+	c := transformTestChart(`
+def transform(objects):
+    out = []
+    for obj in objects:
+        if obj["kind"] == "ConfigMap" and obj["metadata"]["name"] == "a":
+            continue
+        if obj["kind"] == "Deployment":
+            obj["spec"]["replicas"] = values["replicas"] * 2
+        obj["metadata"].setdefault("labels", {})["release"] = release["Name"]
+        out.append(obj)
+    out.append({"apiVersion": "v1", "kind": "ServiceAccount", "metadata": {"name": release["Name"]}})
+    return out
+`)
+
+	out, err := Render(c, transformTestValues())
+	require.NoError(t, err)
+
+	assert.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  labels:\n    release: whale\n  name: b\n", out["moby/templates/configmaps.yaml"])
+	assert.Equal(t, "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  labels:\n    release: whale\n  name: whale\nspec:\n  replicas: 4\n", out["moby/templates/deployment.yaml"])
+	// the script of the subchart runs first
+	assert.Equal(t, "apiVersion: v1\nkind: Service\nmetadata:\n  labels:\n    release: whale\n    sub: sub\n  name: svc\n", out["moby/charts/sub/templates/service.yaml"])
+	assert.Equal(t, "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: whale\n", out["moby/transform.star"])
+	assert.Equal(t, "not an object", out["moby/templates/NOTES.txt"])
+}
+
+func TestRenderTransformErrors(t *testing.T) {
+	for script, expect := range map[string]string{
+		"x = 1":                                 "transform script moby/transform.star: no transform function is defined",
+		"def transform(objects):\n    return 1": "transform returned int, not a list of objects",
+		"def transform(objects):\n    return [1]":                                "transform returned int, not an object",
+		"def transform(objects):\n    values['replicas'] = 3":                    "cannot insert into frozen hash table",
+		"def transform(objects):\n    while True:\n        pass":                 "does not support while loops",
+		"def transform(objects):\n    for i in range(1000000000):\n        pass": "too many steps",
+		"load('os.star', 'os')\ndef transform(objects):\n    pass":               "load not implemented",
+	} {
+		_, err := Render(transformTestChart(script), transformTestValues())
+		require.Error(t, err, script)
+		assert.Contains(t, err.Error(), expect, script)
+	}
+
+	c := transformTestChart("")
+	c.Files = nil
+	_, err := Render(c, transformTestValues())
+	assert.ErrorContains(t, err, "transform script moby/transform.star: not found in the chart files")
+}
@@ -45,6 +45,7 @@ type options struct {
 	userAgent             string
 	version               string
 	registryClient        *registry.Client
+	valuesFile            bool
 	timeout               time.Duration
 	transport             *http.Transport
 }
@@ -130,6 +131,14 @@ func WithRegistryClient(client *registry.Client) Option {
 	}
 }
 
+// WithValuesFile requests a values file rather than a chart. The OCI getter
+// pulls the values files pushed with registry.Client.PushValues.
+func WithValuesFile() Option {
+	return func(opts *options) {
+		opts.valuesFile = true
+	}
+}
+
 func WithUntar() Option {
 	return func(opts *options) {
 		opts.unTar = true
@@ -62,6 +62,21 @@ func (g *OCIGetter) get(href string) (*bytes.Buffer, error) {
 	if version := g.opts.version; version != "" && !strings.Contains(path.Base(ref), ":") {
 		ref = fmt.Sprintf("%s:%s", ref, version)
 	}
+	if g.opts.valuesFile {
+		values, err := client.PullValues(ref)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewBuffer(values), nil
+	}
+	if strings.HasSuffix(ref, sigstoreBundleExt) {
+		bundle, err := client.PullSigstoreBundle(strings.TrimSuffix(ref, sigstoreBundleExt))
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewBuffer(bundle), nil
+	}
+
 	var pullOpts []registry.PullOption
 	requestingProv := strings.HasSuffix(ref, ".prov")
 	if requestingProv {
@@ -82,6 +97,9 @@ func (g *OCIGetter) get(href string) (*bytes.Buffer, error) {
 	return bytes.NewBuffer(result.Chart.Data), nil
 }
 
+// sigstoreBundleExt is the suffix requesting the Sigstore bundle attached to a chart.
+const sigstoreBundleExt = ".sigstore.json"
+
 // NewOCIGetter constructs a valid http/https client as a Getter
 func NewOCIGetter(ops ...Option) (Getter, error) {
 	var client OCIGetter
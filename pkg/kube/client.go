@@ -26,13 +26,14 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
-	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -42,14 +43,18 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	metav1beta1 "k8s.io/apimachinery/pkg/apis/meta/v1beta1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/openapi/cached"
+	"k8s.io/client-go/openapi3"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/pager"
 	"k8s.io/client-go/util/retry"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
@@ -76,9 +81,43 @@ type Client struct {
 	Factory Factory
 	// Namespace allows to bypass the kubeconfig file for the choice of the namespace
 	Namespace string
+	// HealthCheckers supply readiness rules for specific kinds of resources
+	// to the StatusWatcherStrategy waiter, taking precedence over its
+	// built-in rules.
+	HealthCheckers []HealthChecker
+	// IgnorePVCBinding makes the waiters consider PersistentVolumeClaims ready
+	// without waiting for them to be bound. Individual claims can opt out of
+	// the bound check with the PVCBoundAnno annotation instead.
+	IgnorePVCBinding bool
+	// PatchIgnorePaths are field paths that are left untouched when patching
+	// any resource, such as "spec.replicas" for workloads scaled by a
+	// HorizontalPodAutoscaler. See PatchIgnorePathsAnno for the path syntax
+	// and for ignoring paths on individual resources.
+	PatchIgnorePaths []string
+	// CreateSorter orders the resources created by Create. Resources it
+	// considers equal are created concurrently. When it is nil, resources
+	// are created in the given order and consecutive resources of the same
+	// kind are created concurrently.
+	CreateSorter ResourceSorter
+	// DeleteSorter orders the resources deleted by Delete and
+	// DeleteWithPropagationPolicy in the same way CreateSorter orders
+	// created resources.
+	DeleteSorter ResourceSorter
+	// Scheme converts the resources to typed objects, which decides how they
+	// are patched and checked for readiness. When it is nil, a scheme private
+	// to Helm with the Kubernetes native and API extension types is used, so
+	// that types registered with the global client-go scheme by other code do
+	// not change how resources are handled. See AddToGlobalScheme for the
+	// registration Helm used to make on the global scheme.
+	Scheme *runtime.Scheme
+	// NamespacePolicy decides how Create and Update handle resources outside
+	// of the client namespace, see CheckNamespacePolicy. When it is empty,
+	// resources are created in any namespace.
+	NamespacePolicy NamespacePolicy
 
 	Waiter
-	kubeClient kubernetes.Interface
+	kubeClient    kubernetes.Interface
+	openAPIV3Root openapi3.Root
 }
 
 type WaitStrategy string
@@ -87,19 +126,10 @@ const (
 	StatusWatcherStrategy WaitStrategy = "watcher"
 	LegacyStrategy        WaitStrategy = "legacy"
 	HookOnlyStrategy      WaitStrategy = "hookOnly"
+	// NoneStrategy disables waiting entirely, including for hooks.
+	NoneStrategy WaitStrategy = "none"
 )
 
-func init() {
-	// Add CRDs to the scheme. They are missing by default.
-	if err := apiextv1.AddToScheme(scheme.Scheme); err != nil {
-		// This should never happen.
-		panic(err)
-	}
-	if err := apiextv1beta1.AddToScheme(scheme.Scheme); err != nil {
-		panic(err)
-	}
-}
-
 func (c *Client) newStatusWatcher() (*statusWaiter, error) {
 	cfg, err := c.Factory.ToRESTConfig()
 	if err != nil {
@@ -118,8 +148,9 @@ func (c *Client) newStatusWatcher() (*statusWaiter, error) {
 		return nil, err
 	}
 	return &statusWaiter{
-		restMapper: restMapper,
-		client:     dynamicClient,
+		restMapper:     restMapper,
+		client:         dynamicClient,
+		healthCheckers: append(slices.Clone(c.HealthCheckers), &pvcBindingChecker{ignoreBinding: c.IgnorePVCBinding}),
 	}, nil
 }
 
@@ -130,7 +161,7 @@ func (c *Client) GetWaiter(strategy WaitStrategy) (Waiter, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &legacyWaiter{kubeClient: kc}, nil
+		return &legacyWaiter{kubeClient: kc, ignorePVCBinding: c.IgnorePVCBinding, scheme: c.scheme()}, nil
 	case StatusWatcherStrategy:
 		return c.newStatusWatcher()
 	case HookOnlyStrategy:
@@ -139,6 +170,8 @@ func (c *Client) GetWaiter(strategy WaitStrategy) (Waiter, error) {
 			return nil, err
 		}
 		return &hookOnlyWaiter{sw: sw}, nil
+	case NoneStrategy:
+		return noneWaiter{}, nil
 	default:
 		return nil, errors.New("unknown wait strategy")
 	}
@@ -175,6 +208,17 @@ func (c *Client) getKubeClient() (kubernetes.Interface, error) {
 	return c.kubeClient, err
 }
 
+func (c *Client) getOpenAPIV3Root() (openapi3.Root, error) {
+	if c.openAPIV3Root == nil {
+		kc, err := c.getKubeClient()
+		if err != nil {
+			return nil, err
+		}
+		c.openAPIV3Root = openapi3.NewRoot(cached.NewClient(kc.Discovery().OpenAPIV3()))
+	}
+	return c.openAPIV3Root, nil
+}
+
 // IsReachable tests connectivity to the cluster.
 func (c *Client) IsReachable() error {
 	client, err := c.getKubeClient()
@@ -195,7 +239,10 @@ func (c *Client) IsReachable() error {
 // Create creates Kubernetes resources specified in the resource list.
 func (c *Client) Create(resources ResourceList) (*Result, error) {
 	slog.Debug("creating resource(s)", "resources", len(resources))
-	if err := perform(resources, createResource); err != nil {
+	if err := CheckNamespacePolicy(resources, c.namespace(), c.NamespacePolicy); err != nil {
+		return nil, err
+	}
+	if err := performOrdered(resources, c.CreateSorter, createResource); err != nil {
 		return nil, err
 	}
 	return &Result{Created: resources}, nil
@@ -214,13 +261,22 @@ func transformRequests(req *rest.Request) {
 }
 
 // Get retrieves the resource objects supplied. If related is set to true the
-// related pods are fetched as well. If the passed in resources are a table kind
+// related pods are fetched as well, along with the Services, EndpointSlices,
+// HorizontalPodAutoscalers, PodDisruptionBudgets and owned ReplicaSets of
+// workloads. If the passed in resources are a table kind
 // the related resources will also be fetched as kind=table.
 func (c *Client) Get(resources ResourceList, related bool) (map[string][]runtime.Object, error) {
+	return c.GetTable(resources, related, TableOptions{})
+}
+
+// GetTable is like Get, with the columns of the returned Tables selected by
+// options.
+func (c *Client) GetTable(resources ResourceList, related bool, options TableOptions) (map[string][]runtime.Object, error) {
 	buf := new(bytes.Buffer)
 	objs := make(map[string][]runtime.Object)
 
 	podSelectors := []map[string]string{}
+	relatedSeen := map[string]bool{}
 	err := resources.Visit(func(info *resource.Info, err error) error {
 		if err != nil {
 			return err
@@ -248,6 +304,7 @@ func (c *Client) Get(resources ResourceList, related bool) (map[string][]runtime
 				if err != nil {
 					slog.Warn("get the relation pod is failed", slog.Any("error", err))
 				}
+				objs = c.getRelatedResources(info, objs, isTable, relatedSeen)
 			}
 		}
 
@@ -257,6 +314,11 @@ func (c *Client) Get(resources ResourceList, related bool) (map[string][]runtime
 		return nil, err
 	}
 
+	for _, list := range objs {
+		for _, obj := range list {
+			options.apply(obj)
+		}
+	}
 	return objs, nil
 }
 
@@ -336,6 +398,13 @@ func getResource(info *resource.Info) (runtime.Object, error) {
 	return obj, nil
 }
 
+func (c *Client) scheme() *runtime.Scheme {
+	if c.Scheme != nil {
+		return c.Scheme
+	}
+	return kubernetesNativeScheme()
+}
+
 func (c *Client) namespace() string {
 	if c.Namespace != "" {
 		return c.Namespace
@@ -395,6 +464,71 @@ func (c *Client) BuildTable(reader io.Reader, validate bool) (ResourceList, erro
 	return result, scrubValidationError(err)
 }
 
+// UpdateOption configures the behavior of Update.
+type UpdateOption func(*updateOptions)
+
+type updateOptions struct {
+	continueOnError bool
+	serverSideApply bool
+	forceConflicts  bool
+	applied         ResourceList
+	onApplied       func(*resource.Info)
+}
+
+// ContinueOnError returns an UpdateOption that makes Update apply every target
+// resource even after some of them fail. The failures are returned together as
+// an *AggregateError of *ResourceError once all resources have been processed.
+func ContinueOnError(continueOnError bool) UpdateOption {
+	return func(o *updateOptions) {
+		o.continueOnError = continueOnError
+	}
+}
+
+// ServerSideApply returns an UpdateOption that makes Update create and update
+// the target resources with server-side apply instead of client-side patches.
+// The API server then merges the configuration and tracks the fields owned by
+// Helm, reporting conflicts with the fields owned by other managers.
+func ServerSideApply(serverSideApply bool) UpdateOption {
+	return func(o *updateOptions) {
+		o.serverSideApply = serverSideApply
+	}
+}
+
+// ForceConflicts returns an UpdateOption that makes server-side apply take
+// ownership of the fields owned by other managers instead of failing on the
+// conflicts, like kubectl apply --server-side --force-conflicts.
+func ForceConflicts(forceConflicts bool) UpdateOption {
+	return func(o *updateOptions) {
+		o.forceConflicts = forceConflicts
+	}
+}
+
+// AlreadyApplied returns an UpdateOption that makes Update leave alone the
+// target resources in applied, which were applied by an earlier Update that
+// was interrupted. They are neither applied again nor deleted.
+func AlreadyApplied(applied ResourceList) UpdateOption {
+	return func(o *updateOptions) {
+		o.applied = applied
+	}
+}
+
+// OnApplied returns an UpdateOption that makes Update call fn after each
+// target resource has been successfully created or updated, such as to
+// record the progress of the update. The functions of several OnApplied
+// options are called in order.
+func OnApplied(fn func(*resource.Info)) UpdateOption {
+	return func(o *updateOptions) {
+		if previous := o.onApplied; previous != nil {
+			o.onApplied = func(info *resource.Info) {
+				previous(info)
+				fn(info)
+			}
+			return
+		}
+		o.onApplied = fn
+	}
+}
+
 // Update takes the current list of objects and target list of objects and
 // creates resources that don't already exist, updates resources that have been
 // modified in the target configuration, and deletes resources from the current
@@ -403,7 +537,21 @@ func (c *Client) BuildTable(reader io.Reader, validate bool) (ResourceList, erro
 // resource updates, creations, and deletions that were attempted. These can be
 // used for cleanup or other logging purposes.
 func (c *Client) Update(original, target ResourceList, force bool) (*Result, error) {
-	updateErrors := []string{}
+	return c.UpdateWithOptions(original, target, force)
+}
+
+// UpdateWithOptions is Update, with its behavior configured by opts.
+func (c *Client) UpdateWithOptions(original, target ResourceList, force bool, opts ...UpdateOption) (*Result, error) {
+	updateOpts := &updateOptions{}
+	for _, opt := range opts {
+		opt(updateOpts)
+	}
+
+	if err := CheckNamespacePolicy(target, c.namespace(), c.NamespacePolicy); err != nil {
+		return &Result{}, err
+	}
+
+	updateErrors := []error{}
 	res := &Result{}
 
 	slog.Debug("checking resources for changes", "resources", len(target))
@@ -412,34 +560,67 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 			return err
 		}
 
+		// fail either aborts the visit or, when continuing on error, records
+		// the error against the resource and moves on to the next one.
+		fail := func(err error) error {
+			if !updateOpts.continueOnError {
+				return err
+			}
+			updateErrors = append(updateErrors, &ResourceError{Info: info, Err: err})
+			return nil
+		}
+		applied := func() {
+			if updateOpts.onApplied != nil {
+				updateOpts.onApplied(info)
+			}
+		}
+
+		if updateOpts.applied.Contains(info) {
+			slog.Debug("skipping resource applied by an earlier update", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind)
+			return nil
+		}
+
 		helper := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager())
 		if _, err := helper.Get(info.Namespace, info.Name); err != nil {
 			if !apierrors.IsNotFound(err) {
-				return errors.Wrap(err, "could not get information about the resource")
+				return fail(errors.Wrap(err, "could not get information about the resource"))
 			}
 
 			// Append the created resource to the results, even if something fails
 			res.Created = append(res.Created, info)
 
 			// Since the resource does not exist, create it.
-			if err := createResource(info); err != nil {
-				return errors.Wrap(err, "failed to create resource")
+			if updateOpts.serverSideApply {
+				err = applyResource(info, updateOpts.forceConflicts)
+			} else {
+				err = createResource(info)
+			}
+			if err != nil {
+				return fail(errors.Wrap(err, "failed to create resource"))
 			}
 
 			kind := info.Mapping.GroupVersionKind.Kind
 			slog.Debug("created a new resource", "namespace", info.Namespace, "name", info.Name, "kind", kind)
+			applied()
 			return nil
 		}
 
 		originalInfo := original.Get(info)
 		if originalInfo == nil {
 			kind := info.Mapping.GroupVersionKind.Kind
-			return errors.Errorf("no %s with the name %q found", kind, info.Name)
+			return fail(errors.Errorf("no %s with the name %q found", kind, info.Name))
 		}
 
-		if err := updateResource(c, info, originalInfo.Object, force); err != nil {
+		if updateOpts.serverSideApply {
+			err = applyResource(info, updateOpts.forceConflicts)
+		} else {
+			err = updateResource(c, info, originalInfo.Object, force)
+		}
+		if err != nil {
 			slog.Debug("error updating the resource", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, slog.Any("error", err))
-			updateErrors = append(updateErrors, err.Error())
+			updateErrors = append(updateErrors, &ResourceError{Info: info, Err: err})
+		} else {
+			applied()
 		}
 		// Because we check for errors later, append the info regardless
 		res.Updated = append(res.Updated, info)
@@ -450,8 +631,8 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 	switch {
 	case err != nil:
 		return res, err
-	case len(updateErrors) != 0:
-		return res, errors.New(strings.Join(updateErrors, " && "))
+	case len(updateErrors) != 0 && !updateOpts.continueOnError:
+		return res, &AggregateError{Errs: updateErrors}
 	}
 
 	for _, info := range original.Difference(target) {
@@ -475,9 +656,88 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 		}
 		res.Deleted = append(res.Deleted, info)
 	}
+	if len(updateErrors) != 0 {
+		return res, &AggregateError{Errs: updateErrors}
+	}
 	return res, nil
 }
 
+// DryRunApply submits the changes Update would make for the target resources
+// to the API server using server-side dry-run, so nothing is persisted. It
+// returns the change of every accepted resource, with the object the server
+// would store for it once mutated by the admission webhooks. Every resource is
+// submitted even after failures, and all rejections, such as those from
+// validation or admission webhooks, are returned together as an
+// *AggregateError of *ResourceError.
+func (c *Client) DryRunApply(original, target ResourceList, force bool) ([]ResourceDiff, error) {
+	var diffs []ResourceDiff
+	var errs []error
+	slog.Debug("dry-running resource changes", "resources", len(target))
+	err := target.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		live, merged, err := dryRunResource(c, info, original.Get(info), force)
+		if err != nil {
+			slog.Debug("dry-run rejected resource", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, slog.Any("error", err))
+			errs = append(errs, &ResourceError{Info: info, Err: err})
+			return nil
+		}
+		diffs = append(diffs, ResourceDiff{Info: info, Live: live, Merged: merged})
+		return nil
+	})
+	if err != nil {
+		return diffs, err
+	}
+	if len(errs) != 0 {
+		return diffs, &AggregateError{Errs: errs}
+	}
+	return diffs, nil
+}
+
+// dryRunResource submits the change Update would make to a resource with
+// server-side dry-run. It returns the live object, which is nil when the
+// resource does not exist, and the object the server would store.
+func dryRunResource(c *Client, target, original *resource.Info, force bool) (live, merged runtime.Object, err error) {
+	helper := resource.NewHelper(target.Client, target.Mapping).WithFieldManager(getManagedFieldsManager()).DryRun(true)
+	kind := target.Mapping.GroupVersionKind.Kind
+	live, err = helper.Get(target.Namespace, target.Name)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, nil, errors.Wrap(err, "could not get information about the resource")
+		}
+		merged, err := helper.Create(target.Namespace, true, target.Object.DeepCopyObject())
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot create %q with kind %s", target.Name, kind)
+		}
+		return nil, merged, nil
+	}
+
+	if force {
+		merged, err := helper.Replace(target.Namespace, target.Name, true, target.Object.DeepCopyObject())
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot replace %q with kind %s", target.Name, kind)
+		}
+		return live, merged, nil
+	}
+
+	if original == nil {
+		return nil, nil, errors.Errorf("no %s with the name %q found", kind, target.Name)
+	}
+	patch, patchType, err := createPatch(c, target, original.Object)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create patch")
+	}
+	if patch == nil || string(patch) == "{}" {
+		return live, live, nil
+	}
+	merged, err = helper.Patch(target.Namespace, target.Name, patchType, patch, nil)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "cannot patch %q with kind %s", target.Name, kind)
+	}
+	return live, merged, nil
+}
+
 // Delete deletes Kubernetes resources specified in the resources list with
 // background cascade deletion. It will attempt to delete all resources even
 // if one or more fail and collect any errors. All successfully deleted items
@@ -494,11 +754,11 @@ func (c *Client) DeleteWithPropagationPolicy(resources ResourceList, policy meta
 	return rdelete(c, resources, policy)
 }
 
-func rdelete(_ *Client, resources ResourceList, propagation metav1.DeletionPropagation) (*Result, []error) {
+func rdelete(c *Client, resources ResourceList, propagation metav1.DeletionPropagation) (*Result, []error) {
 	var errs []error
 	res := &Result{}
 	mtx := sync.Mutex{}
-	err := perform(resources, func(info *resource.Info) error {
+	err := performOrdered(resources, c.DeleteSorter, func(info *resource.Info) error {
 		slog.Debug("starting delete resource", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind)
 		err := deleteResource(info, propagation)
 		if err == nil || apierrors.IsNotFound(err) {
@@ -548,15 +808,24 @@ func getManagedFieldsManager() string {
 	return filepath.Base(os.Args[0])
 }
 
-func batchPerform(infos ResourceList, fn func(*resource.Info) error, errs chan<- error) {
+func batchPerform(infos ResourceList, sorter ResourceSorter, fn func(*resource.Info) error, errs chan<- error) {
+	if sorter != nil {
+		infos = slices.Clone(infos)
+		sort.SliceStable(infos, func(i, j int) bool {
+			return sorter.Less(infos[i], infos[j])
+		})
+	}
+
 	var kind string
+	var previous *resource.Info
 	var wg sync.WaitGroup
 	for _, info := range infos {
 		currentKind := info.Object.GetObjectKind().GroupVersionKind().Kind
-		if kind != currentKind {
+		if kind != currentKind || (previous != nil && sorter != nil && sorter.Less(previous, info)) {
 			wg.Wait()
 			kind = currentKind
 		}
+		previous = info
 		wg.Add(1)
 		go func(i *resource.Info) {
 			errs <- fn(i)
@@ -587,7 +856,7 @@ func deleteResource(info *resource.Info, policy metav1.DeletionPropagation) erro
 		})
 }
 
-func createPatch(target *resource.Info, current runtime.Object) ([]byte, types.PatchType, error) {
+func createPatch(c *Client, target *resource.Info, current runtime.Object) ([]byte, types.PatchType, error) {
 	oldData, err := json.Marshal(current)
 	if err != nil {
 		return nil, types.StrategicMergePatchType, errors.Wrap(err, "serializing current configuration")
@@ -597,6 +866,17 @@ func createPatch(target *resource.Info, current runtime.Object) ([]byte, types.P
 		return nil, types.StrategicMergePatchType, errors.Wrap(err, "serializing target configuration")
 	}
 
+	// Fields that are absent from both the current and the target
+	// configuration are never part of the patch, which leaves their live
+	// values alone.
+	ignorePaths := patchIgnorePaths(target.Object, c.PatchIgnorePaths)
+	if oldData, err = removePaths(oldData, ignorePaths); err != nil {
+		return nil, types.StrategicMergePatchType, errors.Wrap(err, "removing ignored paths from current configuration")
+	}
+	if newData, err = removePaths(newData, ignorePaths); err != nil {
+		return nil, types.StrategicMergePatchType, errors.Wrap(err, "removing ignored paths from target configuration")
+	}
+
 	// Fetch the current object for the three way merge
 	helper := resource.NewHelper(target.Client, target.Mapping).WithFieldManager(getManagedFieldsManager())
 	currentObj, err := helper.Get(target.Namespace, target.Name)
@@ -610,8 +890,21 @@ func createPatch(target *resource.Info, current runtime.Object) ([]byte, types.P
 		return nil, types.StrategicMergePatchType, errors.Wrap(err, "serializing live configuration")
 	}
 
+	patchType, err := patchTypeOverride(target.Object)
+	if err != nil {
+		return nil, types.StrategicMergePatchType, err
+	}
+	switch patchType {
+	case types.MergePatchType:
+		patch, err := jsonpatch.CreateMergePatch(oldData, newData)
+		return patch, types.MergePatchType, err
+	case types.JSONPatchType:
+		patch, err := createJSONPatch(oldData, newData, currentData)
+		return patch, types.JSONPatchType, err
+	}
+
 	// Get a versioned object
-	versionedObject := AsVersioned(target)
+	versionedObject := convertWithScheme(target.Object, target.Mapping, c.scheme())
 
 	// Unstructured objects, such as CRDs, may not have a not registered error
 	// returned from ConvertToVersion. Anything that's unstructured should
@@ -623,8 +916,36 @@ func createPatch(target *resource.Info, current runtime.Object) ([]byte, types.P
 	_, isCRD := versionedObject.(*apiextv1beta1.CustomResourceDefinition)
 
 	if isUnstructured || isCRD {
-		// fall back to generic JSON merge patch
-		patch, err := jsonpatch.CreateMergePatch(oldData, newData)
+		if patchType == types.StrategicMergePatchType {
+			return nil, types.StrategicMergePatchType, errors.Errorf("strategic merge patch is not supported for %s %q", target.Mapping.GroupVersionKind.Kind, target.Name)
+		}
+		// Respect the list types declared by the schema of custom resources
+		// when it is available, so that lists keyed by a field are merged
+		// rather than replaced.
+		var meta strategicpatch.LookupPatchMeta
+		if isUnstructured && currentObj != nil {
+			meta = c.customResourcePatchMeta(target.Mapping.GroupVersionKind)
+		}
+		var patch []byte
+		if meta != nil {
+			patch, err = createSchemaAwareMergePatch(oldData, newData, currentData, meta)
+		} else {
+			// fall back to generic JSON merge patch
+			patch, err = jsonpatch.CreateMergePatch(oldData, newData)
+		}
+		if err != nil {
+			return nil, types.MergePatchType, err
+		}
+
+		// Only remove fields that Helm still manages in the live object, as
+		// recorded by its managed fields. Fields that were defaulted by the
+		// server or are managed by other controllers are left alone.
+		owned, found, err := managedFieldsOf(currentObj, getManagedFieldsManager())
+		if err != nil {
+			slog.Debug("unable to extract managed fields", "namespace", target.Namespace, "name", target.Name, "kind", target.Mapping.GroupVersionKind.Kind, slog.Any("error", err))
+		} else if found {
+			patch, err = dropUnownedDeletions(patch, owned)
+		}
 		return patch, types.MergePatchType, err
 	}
 
@@ -637,7 +958,22 @@ func createPatch(target *resource.Info, current runtime.Object) ([]byte, types.P
 	return patch, types.StrategicMergePatchType, err
 }
 
-func updateResource(_ *Client, target *resource.Info, currentObj runtime.Object, force bool) error {
+// customResourcePatchMeta returns the patch metadata for a custom resource
+// from the OpenAPI v3 schema published by the cluster, or nil if the schema
+// cannot be retrieved.
+func (c *Client) customResourcePatchMeta(gvk schema.GroupVersionKind) strategicpatch.LookupPatchMeta {
+	root, err := c.getOpenAPIV3Root()
+	if err == nil {
+		var meta strategicpatch.LookupPatchMeta
+		if meta, err = crdPatchMeta(root, gvk); err == nil {
+			return meta
+		}
+	}
+	slog.Debug("unable to get OpenAPI v3 schema, falling back to JSON merge patch", "group", gvk.Group, "version", gvk.Version, "kind", gvk.Kind, slog.Any("error", err))
+	return nil
+}
+
+func updateResource(c *Client, target *resource.Info, currentObj runtime.Object, force bool) error {
 	var (
 		obj    runtime.Object
 		helper = resource.NewHelper(target.Client, target.Mapping).WithFieldManager(getManagedFieldsManager())
@@ -653,7 +989,7 @@ func updateResource(_ *Client, target *resource.Info, currentObj runtime.Object,
 		}
 		slog.Debug("replace succeeded", "name", target.Name, "initialKind", currentObj.GetObjectKind().GroupVersionKind().Kind, "kind", kind)
 	} else {
-		patch, patchType, err := createPatch(target, currentObj)
+		patch, patchType, err := createPatch(c, target, currentObj)
 		if err != nil {
 			return errors.Wrap(err, "failed to create patch")
 		}
@@ -679,23 +1015,122 @@ func updateResource(_ *Client, target *resource.Info, currentObj runtime.Object,
 	return nil
 }
 
-// GetPodList uses the kubernetes interface to get the list of pods filtered by listOptions
+// applyResource applies the configuration of a resource with server-side
+// apply, as the field manager of Helm.
+func applyResource(target *resource.Info, forceConflicts bool) error {
+	kind := target.Mapping.GroupVersionKind.Kind
+	data, err := json.Marshal(target.Object)
+	if err != nil {
+		return errors.Wrapf(err, "failed to serialize %q with kind %s", target.Name, kind)
+	}
+	slog.Debug("applying resource", "kind", kind, "name", target.Name, "namespace", target.Namespace, "forceConflicts", forceConflicts)
+	helper := resource.NewHelper(target.Client, target.Mapping).WithFieldManager(getManagedFieldsManager())
+	obj, err := helper.Patch(target.Namespace, target.Name, types.ApplyPatchType, data, &metav1.PatchOptions{Force: &forceConflicts})
+	if err != nil {
+		return errors.Wrapf(err, "cannot apply %q with kind %s", target.Name, kind)
+	}
+	return target.Refresh(obj, true)
+}
+
+// GetPodList uses the kubernetes interface to get the list of pods filtered by listOptions.
+//
+// The pods are listed in pages of listOptions.Limit pods, or of 500 pods when
+// no limit is set, following the continue tokens returned by the server
+// until all matching pods are listed.
 func (c *Client) GetPodList(namespace string, listOptions metav1.ListOptions) (*v1.PodList, error) {
-	podList, err := c.kubeClient.CoreV1().Pods(namespace).List(context.Background(), listOptions)
+	kubeClient, err := c.getKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	pods := kubeClient.CoreV1().Pods(namespace)
+	p := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return pods.List(context.Background(), opts)
+	}))
+	if listOptions.Limit > 0 {
+		p.PageSize = listOptions.Limit
+	}
+
+	podList := &v1.PodList{}
+	err = p.EachListItem(context.Background(), listOptions, func(obj runtime.Object) error {
+		pod, ok := obj.(*v1.Pod)
+		if !ok {
+			return fmt.Errorf("unexpected object %T in pod list", obj)
+		}
+		podList.Items = append(podList.Items, *pod)
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pod list with options: %+v with error: %v", listOptions, err)
 	}
 	return podList, nil
 }
 
-// OutputContainerLogsForPodList is a helper that outputs logs for a list of pods
+// PodListOptionsForObject returns the ListOptions selecting the pods of a
+// workload, such as a Deployment, StatefulSet, DaemonSet, ReplicaSet,
+// ReplicationController, Job or Service. For a Pod, the options select the
+// pod itself by name.
+func PodListOptionsForObject(obj runtime.Object) (metav1.ListOptions, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return metav1.ListOptions{}, err
+		}
+		u = &unstructured.Unstructured{Object: content}
+	}
+
+	switch kind := u.GetKind(); kind {
+	case "Pod":
+		return metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", u.GetName()).String()}, nil
+	case "ReplicationController", "Service":
+		selector, found, err := unstructured.NestedStringMap(u.Object, "spec", "selector")
+		if err != nil {
+			return metav1.ListOptions{}, err
+		}
+		if !found || len(selector) == 0 {
+			return metav1.ListOptions{}, fmt.Errorf("%s %q has no pod selector", kind, u.GetName())
+		}
+		return metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()}, nil
+	case "ReplicaSet", "Deployment", "StatefulSet", "DaemonSet", "Job":
+		raw, found, err := unstructured.NestedMap(u.Object, "spec", "selector")
+		if err != nil {
+			return metav1.ListOptions{}, err
+		}
+		if !found {
+			return metav1.ListOptions{}, fmt.Errorf("%s %q has no pod selector", kind, u.GetName())
+		}
+		var labelSelector metav1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &labelSelector); err != nil {
+			return metav1.ListOptions{}, err
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+		if err != nil {
+			return metav1.ListOptions{}, err
+		}
+		if selector.Empty() {
+			return metav1.ListOptions{}, fmt.Errorf("%s %q has no pod selector", kind, u.GetName())
+		}
+		return metav1.ListOptions{LabelSelector: selector.String()}, nil
+	default:
+		return metav1.ListOptions{}, fmt.Errorf("selecting the pods of a %s is not supported", kind)
+	}
+}
+
+// OutputContainerLogsForPodList is a helper that outputs logs for a list of pods.
+// The logs of the init and ephemeral containers of the pods are output along
+// with the logs of their regular containers, see ContainersWithLogs.
 func (c *Client) OutputContainerLogsForPodList(podList *v1.PodList, namespace string, writerFunc func(namespace, pod, container string) io.Writer) error {
-	for _, pod := range podList.Items {
-		for _, container := range pod.Spec.Containers {
+	kubeClient, err := c.getKubeClient()
+	if err != nil {
+		return err
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		for _, container := range ContainersWithLogs(pod) {
 			options := &v1.PodLogOptions{
 				Container: container.Name,
 			}
-			request := c.kubeClient.CoreV1().Pods(namespace).GetLogs(pod.Name, options)
+			request := kubeClient.CoreV1().Pods(namespace).GetLogs(pod.Name, options)
 			err2 := copyRequestStreamToWriter(request, pod.Name, container.Name, writerFunc(namespace, pod.Name, container.Name))
 			if err2 != nil {
 				return err2
@@ -705,6 +1140,50 @@ func (c *Client) OutputContainerLogsForPodList(podList *v1.PodList, namespace st
 	return nil
 }
 
+// ContainerType is the type of a container in a pod.
+type ContainerType string
+
+const (
+	// InitContainerType is the type of the init containers of a pod.
+	InitContainerType ContainerType = "init container"
+	// RegularContainerType is the type of the regular containers of a pod.
+	RegularContainerType ContainerType = "container"
+	// EphemeralContainerType is the type of the ephemeral containers of a pod.
+	EphemeralContainerType ContainerType = "ephemeral container"
+)
+
+// PodContainer is a container of a pod.
+type PodContainer struct {
+	Name string
+	Type ContainerType
+}
+
+// ContainersWithLogs returns the init, regular and ephemeral containers of a
+// pod, in that order. Containers that are waiting to be started for the first
+// time, such as the containers following a failed init container, have no
+// logs yet and are left out.
+func ContainersWithLogs(pod *v1.Pod) []PodContainer {
+	var containers []PodContainer
+	add := func(name string, containerType ContainerType, statuses []v1.ContainerStatus) {
+		for _, status := range statuses {
+			if status.Name == name && status.State.Waiting != nil && status.LastTerminationState.Terminated == nil {
+				return
+			}
+		}
+		containers = append(containers, PodContainer{Name: name, Type: containerType})
+	}
+	for _, container := range pod.Spec.InitContainers {
+		add(container.Name, InitContainerType, pod.Status.InitContainerStatuses)
+	}
+	for _, container := range pod.Spec.Containers {
+		add(container.Name, RegularContainerType, pod.Status.ContainerStatuses)
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		add(container.Name, EphemeralContainerType, pod.Status.EphemeralContainerStatuses)
+	}
+	return containers
+}
+
 func copyRequestStreamToWriter(request *rest.Request, podName, containerName string, writer io.Writer) error {
 	readCloser, err := request.Stream(context.Background())
 	if err != nil {
@@ -18,21 +18,31 @@ package kube
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/resource"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest/fake"
+	k8stesting "k8s.io/client-go/testing"
 	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
 )
 
@@ -327,6 +337,348 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateContinueOnError(t *testing.T) {
+	listA := newPodList("otter", "squid")
+	listB := newPodList("starfish", "otter", "dolphin")
+	listB.Items[1].Spec.Containers[0].Ports = []v1.ContainerPort{{Name: "https", ContainerPort: 443}}
+
+	var actions []string
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			p, m := req.URL.Path, req.Method
+			actions = append(actions, p+":"+m)
+			t.Logf("got request %s %s", p, m)
+			switch {
+			case p == "/namespaces/default/pods/starfish" && m == "GET":
+				return newResponse(500, &metav1.Status{Status: metav1.StatusFailure, Message: "internal error"})
+			case p == "/namespaces/default/pods/otter" && m == "GET":
+				return newResponse(200, &listA.Items[0])
+			case p == "/namespaces/default/pods/otter" && m == "PATCH":
+				return newResponse(500, &metav1.Status{Status: metav1.StatusFailure, Message: "internal error"})
+			case p == "/namespaces/default/pods/dolphin" && m == "GET":
+				return newResponse(404, notFoundBody())
+			case p == "/namespaces/default/pods" && m == "POST":
+				return newResponse(200, &listB.Items[2])
+			case p == "/namespaces/default/pods/squid" && m == "GET":
+				return newResponse(200, &listA.Items[1])
+			case p == "/namespaces/default/pods/squid" && m == "DELETE":
+				return newResponse(200, &listA.Items[1])
+			default:
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return nil, nil
+			}
+		}),
+	}
+	first, err := c.Build(objBody(&listA), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Build(objBody(&listB), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.UpdateWithOptions(first, second, false, ContinueOnError(true))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var aggregate *AggregateError
+	if !errors.As(err, &aggregate) {
+		t.Fatalf("expected an *AggregateError, got %T", err)
+	}
+	var failed []string
+	for _, err := range aggregate.Unwrap() {
+		var resourceErr *ResourceError
+		if !errors.As(err, &resourceErr) {
+			t.Fatalf("expected a *ResourceError, got %T", err)
+		}
+		failed = append(failed, resourceErr.Info.Name)
+	}
+	if !reflect.DeepEqual(failed, []string{"starfish", "otter"}) {
+		t.Errorf("expected starfish and otter to fail, got %v", failed)
+	}
+
+	if len(result.Created) != 1 {
+		t.Errorf("expected 1 resource created, got %d", len(result.Created))
+	}
+	if len(result.Updated) != 1 {
+		t.Errorf("expected 1 resource updated, got %d", len(result.Updated))
+	}
+	if len(result.Deleted) != 1 {
+		t.Errorf("expected 1 resource deleted, got %d", len(result.Deleted))
+	}
+}
+
+func TestUpdateServerSideApply(t *testing.T) {
+	listA := newPodList("otter", "squid")
+	listB := newPodList("starfish", "otter")
+
+	for _, forceConflicts := range []bool{false, true} {
+		var actions []string
+
+		c := newTestClient(t)
+		c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+			NegotiatedSerializer: unstructuredSerializer,
+			Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+				p, m := req.URL.Path, req.Method
+				actions = append(actions, p+":"+m)
+				if m == "PATCH" {
+					assert.Equal(t, string(types.ApplyPatchType), req.Header.Get("Content-Type"))
+					assert.Equal(t, getManagedFieldsManager(), req.URL.Query().Get("fieldManager"))
+					assert.Equal(t, forceConflicts, req.URL.Query().Get("force") == "true")
+				}
+				switch {
+				case p == "/namespaces/default/pods/starfish" && m == "GET":
+					return newResponse(404, notFoundBody())
+				case p == "/namespaces/default/pods/starfish" && m == "PATCH":
+					return newResponse(201, &listB.Items[0])
+				case p == "/namespaces/default/pods/otter" && m == "GET":
+					return newResponse(200, &listA.Items[0])
+				case p == "/namespaces/default/pods/otter" && m == "PATCH":
+					return newResponse(200, &listB.Items[1])
+				case p == "/namespaces/default/pods/squid" && m == "GET":
+					return newResponse(200, &listA.Items[1])
+				case p == "/namespaces/default/pods/squid" && m == "DELETE":
+					return newResponse(200, &listA.Items[1])
+				default:
+					t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+					return nil, nil
+				}
+			}),
+		}
+		first, err := c.Build(objBody(&listA), false)
+		require.NoError(t, err)
+		second, err := c.Build(objBody(&listB), false)
+		require.NoError(t, err)
+
+		result, err := c.UpdateWithOptions(first, second, false, ServerSideApply(true), ForceConflicts(forceConflicts))
+		require.NoError(t, err)
+		assert.Len(t, result.Created, 1)
+		assert.Len(t, result.Updated, 1)
+		assert.Len(t, result.Deleted, 1)
+
+		expectedActions := []string{
+			"/namespaces/default/pods/starfish:GET",
+			"/namespaces/default/pods/starfish:PATCH",
+			"/namespaces/default/pods/otter:GET",
+			"/namespaces/default/pods/otter:PATCH",
+			"/namespaces/default/pods/squid:GET",
+			"/namespaces/default/pods/squid:DELETE",
+		}
+		assert.Equal(t, expectedActions, actions, "forceConflicts %t", forceConflicts)
+	}
+}
+
+func TestUpdateAlreadyApplied(t *testing.T) {
+	listA := newPodList("otter", "squid")
+	listB := newPodList("starfish", "otter")
+
+	var actions []string
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			p, m := req.URL.Path, req.Method
+			actions = append(actions, p+":"+m)
+			switch {
+			case p == "/namespaces/default/pods/otter" && m == "GET":
+				return newResponse(200, &listA.Items[0])
+			case p == "/namespaces/default/pods/otter" && m == "PATCH":
+				return newResponse(200, &listB.Items[1])
+			case p == "/namespaces/default/pods/squid" && m == "GET":
+				return newResponse(200, &listA.Items[1])
+			case p == "/namespaces/default/pods/squid" && m == "DELETE":
+				return newResponse(200, &listA.Items[1])
+			default:
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return nil, nil
+			}
+		}),
+	}
+	first, err := c.Build(objBody(&listA), false)
+	require.NoError(t, err)
+	second, err := c.Build(objBody(&listB), false)
+	require.NoError(t, err)
+
+	var applied []string
+	result, err := c.UpdateWithOptions(first, second, false,
+		ServerSideApply(true),
+		AlreadyApplied(second[:1]),
+		OnApplied(func(info *resource.Info) { applied = append(applied, info.Name) }))
+	require.NoError(t, err)
+	assert.Empty(t, result.Created)
+	assert.Len(t, result.Updated, 1)
+	assert.Len(t, result.Deleted, 1)
+	assert.Equal(t, []string{"otter"}, applied)
+
+	expectedActions := []string{
+		"/namespaces/default/pods/otter:GET",
+		"/namespaces/default/pods/otter:PATCH",
+		"/namespaces/default/pods/squid:GET",
+		"/namespaces/default/pods/squid:DELETE",
+	}
+	assert.Equal(t, expectedActions, actions)
+}
+
+func TestDryRunApply(t *testing.T) {
+	listA := newPodList("otter", "squid")
+	listB := newPodList("starfish", "otter", "dolphin")
+	listB.Items[1].Spec.Containers[0].Ports = []v1.ContainerPort{{Name: "https", ContainerPort: 443}}
+
+	var actions []string
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			p, m := req.URL.Path, req.Method
+			if m != "GET" && req.URL.Query().Get("dryRun") != "All" {
+				t.Fatalf("expected a dry-run request for %s %s", m, p)
+			}
+			actions = append(actions, p+":"+m)
+			t.Logf("got request %s %s", p, m)
+			switch {
+			case p == "/namespaces/default/pods/starfish" && m == "GET":
+				return newResponse(404, notFoundBody())
+			case p == "/namespaces/default/pods/dolphin" && m == "GET":
+				return newResponse(404, notFoundBody())
+			case p == "/namespaces/default/pods" && m == "POST":
+				return newResponse(403, &metav1.Status{Status: metav1.StatusFailure, Message: "admission webhook denied the request", Reason: metav1.StatusReasonForbidden})
+			case p == "/namespaces/default/pods/otter" && m == "GET":
+				return newResponse(200, &listA.Items[0])
+			case p == "/namespaces/default/pods/otter" && m == "PATCH":
+				return newResponse(200, &listB.Items[1])
+			default:
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return nil, nil
+			}
+		}),
+	}
+	first, err := c.Build(objBody(&listA), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.Build(objBody(&listB), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := c.DryRunApply(first, second, false)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var aggregate *AggregateError
+	if !errors.As(err, &aggregate) {
+		t.Fatalf("expected an *AggregateError, got %T", err)
+	}
+	var failed []string
+	for _, err := range aggregate.Unwrap() {
+		var resourceErr *ResourceError
+		if !errors.As(err, &resourceErr) {
+			t.Fatalf("expected a *ResourceError, got %T", err)
+		}
+		failed = append(failed, resourceErr.Info.Name)
+	}
+	if !reflect.DeepEqual(failed, []string{"starfish", "dolphin"}) {
+		t.Errorf("expected starfish and dolphin to be rejected, got %v", failed)
+	}
+
+	// Resources missing from the target must never be deleted by a dry-run.
+	expectedActions := []string{
+		"/namespaces/default/pods/starfish:GET",
+		"/namespaces/default/pods:POST",
+		"/namespaces/default/pods/otter:GET",
+		"/namespaces/default/pods/otter:GET",
+		"/namespaces/default/pods/otter:PATCH",
+		"/namespaces/default/pods/dolphin:GET",
+		"/namespaces/default/pods:POST",
+	}
+	if !reflect.DeepEqual(actions, expectedActions) {
+		t.Errorf("expected actions %v, got %v", expectedActions, actions)
+	}
+
+	if len(diffs) != 1 || diffs[0].Info.Name != "otter" || diffs[0].Merged == nil {
+		t.Errorf("expected otter to be accepted, got %v", diffs)
+	}
+}
+
+func TestGetRelatedResources(t *testing.T) {
+	deployment := `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"default"},` +
+		`"spec":{"selector":{"matchLabels":{"app":"web"}},"template":{"metadata":{"labels":{"app":"web","tier":"frontend"}}}}}`
+	responses := map[string]string{
+		"/namespaces/default/deployments/web": deployment,
+		"/namespaces/default/pods":            `{"apiVersion":"v1","kind":"PodList","items":[]}`,
+		"/namespaces/default/services": `{"apiVersion":"v1","kind":"ServiceList","items":[` +
+			`{"apiVersion":"v1","kind":"Service","metadata":{"name":"web","namespace":"default"},"spec":{"selector":{"app":"web"}}},` +
+			`{"apiVersion":"v1","kind":"Service","metadata":{"name":"db","namespace":"default"},"spec":{"selector":{"app":"db"}}},` +
+			`{"apiVersion":"v1","kind":"Service","metadata":{"name":"external","namespace":"default"},"spec":{}}]}`,
+		"/namespaces/default/endpointslices": `{"apiVersion":"discovery.k8s.io/v1","kind":"EndpointSliceList","items":[` +
+			`{"apiVersion":"discovery.k8s.io/v1","kind":"EndpointSlice","metadata":{"name":"web-abcde","namespace":"default","labels":{"kubernetes.io/service-name":"web"}},"addressType":"IPv4","endpoints":[]}]}`,
+		"/namespaces/default/horizontalpodautoscalers": `{"apiVersion":"autoscaling/v2","kind":"HorizontalPodAutoscalerList","items":[` +
+			`{"apiVersion":"autoscaling/v2","kind":"HorizontalPodAutoscaler","metadata":{"name":"web","namespace":"default"},"spec":{"scaleTargetRef":{"kind":"Deployment","name":"web"}}},` +
+			`{"apiVersion":"autoscaling/v2","kind":"HorizontalPodAutoscaler","metadata":{"name":"api","namespace":"default"},"spec":{"scaleTargetRef":{"kind":"Deployment","name":"api"}}}]}`,
+		"/namespaces/default/poddisruptionbudgets": `{"apiVersion":"policy/v1","kind":"PodDisruptionBudgetList","items":[` +
+			`{"apiVersion":"policy/v1","kind":"PodDisruptionBudget","metadata":{"name":"web","namespace":"default"},"spec":{"selector":{"matchLabels":{"tier":"frontend"}}}}]}`,
+		"/namespaces/default/replicasets": `{"apiVersion":"apps/v1","kind":"ReplicaSetList","items":[` +
+			`{"apiVersion":"apps/v1","kind":"ReplicaSet","metadata":{"name":"web-1234","namespace":"default","ownerReferences":[{"apiVersion":"apps/v1","kind":"Deployment","name":"web","uid":"1"}]}},` +
+			`{"apiVersion":"apps/v1","kind":"ReplicaSet","metadata":{"name":"web-orphan","namespace":"default"}}]}`,
+	}
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Logf("got request %s %s?%s", req.Method, req.URL.Path, req.URL.RawQuery)
+			if body, ok := responses[req.URL.Path]; ok && req.Method == "GET" {
+				return newResponseJSON(200, []byte(body))
+			}
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+
+	resources, err := c.Build(strings.NewReader(deployment), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := c.Get(resources, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string][]string{}
+	for vk, list := range objs {
+		for _, obj := range list {
+			// Related pods are returned as a list.
+			items := []runtime.Object{obj}
+			if meta.IsListType(obj) {
+				if items, err = meta.ExtractList(obj); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, item := range items {
+				names[vk] = append(names[vk], item.(*unstructured.Unstructured).GetName())
+			}
+		}
+	}
+	expected := map[string][]string{
+		"v1/Deployment":                       {"web"},
+		"v1/Service(related)":                 {"web"},
+		"v1/EndpointSlice(related)":           {"web-abcde"},
+		"v2/HorizontalPodAutoscaler(related)": {"web"},
+		"v1/PodDisruptionBudget(related)":     {"web"},
+		"v1/ReplicaSet(related)":              {"web-1234"},
+	}
+	if !reflect.DeepEqual(expected, names) {
+		t.Errorf("expected related resources %v, got %v", expected, names)
+	}
+}
+
 func TestBuild(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -455,6 +807,39 @@ func TestPerform(t *testing.T) {
 	}
 }
 
+type kindSorter struct{}
+
+func (kindSorter) Less(a, b *resource.Info) bool {
+	return a.Mapping.GroupVersionKind.Kind < b.Mapping.GroupVersionKind.Kind
+}
+
+func TestPerformOrdered(t *testing.T) {
+	c := newTestClient(t)
+	infos, err := c.Build(strings.NewReader(guestbookManifest), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mtx sync.Mutex
+	var kinds []string
+	fn := func(info *resource.Info) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		kinds = append(kinds, info.Mapping.GroupVersionKind.Kind)
+		return nil
+	}
+
+	if err := performOrdered(infos, kindSorter{}, fn); err != nil {
+		t.Fatal(err)
+	}
+	if len(kinds) != len(infos) {
+		t.Fatalf("expected %d resources to be visited, got %d", len(infos), len(kinds))
+	}
+	if !slices.IsSorted(kinds) {
+		t.Errorf("expected resources to be visited in sorted order, got %v", kinds)
+	}
+}
+
 func TestWait(t *testing.T) {
 	podList := newPodList("starfish", "otter", "squid")
 
@@ -659,6 +1044,39 @@ func TestWaitDelete(t *testing.T) {
 	}
 }
 
+func TestWaitNone(t *testing.T) {
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).Client = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+	var err error
+	c.Waiter, err = c.GetWaiter(NoneStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	podList := newPodList("starfish")
+	resources, err := c.Build(objBody(&podList), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Wait(resources, time.Second); err != nil {
+		t.Errorf("expected wait without error, got %s", err)
+	}
+	if err := c.WaitWithJobs(resources, time.Second); err != nil {
+		t.Errorf("expected wait with jobs without error, got %s", err)
+	}
+	if err := c.WaitForDelete(resources, time.Second); err != nil {
+		t.Errorf("expected wait for delete without error, got %s", err)
+	}
+	if err := c.WatchUntilReady(resources, time.Second); err != nil {
+		t.Errorf("expected watch until ready without error, got %s", err)
+	}
+}
+
 func TestReal(t *testing.T) {
 	t.Skip("This is a live test, comment this line to run")
 	c := New(nil)
@@ -718,6 +1136,134 @@ func TestGetPodList(t *testing.T) {
 
 }
 
+func TestGetPodListPaginated(t *testing.T) {
+	namespace := "some-namespace"
+	var pods []v1.Pod
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		pods = append(pods, newPodWithStatus(name, v1.PodStatus{}, namespace))
+	}
+
+	kubeClient := k8sfake.NewSimpleClientset()
+	var requests []metav1.ListOptions
+	kubeClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		opts := action.(k8stesting.ListActionImpl).ListOptions
+		requests = append(requests, opts)
+		start := 0
+		if opts.Continue != "" {
+			start = int(opts.Continue[0] - '0')
+		}
+		end := min(start+int(opts.Limit), len(pods))
+		page := &v1.PodList{Items: pods[start:end]}
+		if end < len(pods) {
+			page.Continue = string(rune('0' + end))
+		}
+		return true, page, nil
+	})
+	c := Client{Namespace: namespace, kubeClient: kubeClient}
+
+	podList, err := c.GetPodList(namespace, metav1.ListOptions{Limit: 2, FieldSelector: "status.phase=Running"})
+	assert.NoError(t, err)
+	assert.Equal(t, pods, podList.Items)
+	assert.Len(t, requests, 3)
+	for _, opts := range requests {
+		assert.Equal(t, int64(2), opts.Limit)
+		assert.Equal(t, "status.phase=Running", opts.FieldSelector)
+	}
+}
+
+func TestPodListOptionsForObject(t *testing.T) {
+	tests := []struct {
+		name     string
+		obj      map[string]interface{}
+		expected metav1.ListOptions
+		err      bool
+	}{
+		{
+			name: "deployment with match expressions",
+			obj: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "web"},
+				"spec": map[string]interface{}{
+					"selector": map[string]interface{}{
+						"matchLabels": map[string]interface{}{"app": "web"},
+						"matchExpressions": []interface{}{
+							map[string]interface{}{"key": "tier", "operator": "In", "values": []interface{}{"frontend"}},
+						},
+					},
+				},
+			},
+			expected: metav1.ListOptions{LabelSelector: "app=web,tier in (frontend)"},
+		},
+		{
+			name: "service",
+			obj: map[string]interface{}{
+				"kind":     "Service",
+				"metadata": map[string]interface{}{"name": "web"},
+				"spec":     map[string]interface{}{"selector": map[string]interface{}{"app": "web"}},
+			},
+			expected: metav1.ListOptions{LabelSelector: "app=web"},
+		},
+		{
+			name: "pod",
+			obj: map[string]interface{}{
+				"kind":     "Pod",
+				"metadata": map[string]interface{}{"name": "web-0"},
+			},
+			expected: metav1.ListOptions{FieldSelector: "metadata.name=web-0"},
+		},
+		{
+			name: "service without selector",
+			obj: map[string]interface{}{
+				"kind":     "Service",
+				"metadata": map[string]interface{}{"name": "external"},
+			},
+			err: true,
+		},
+		{
+			name: "unsupported kind",
+			obj: map[string]interface{}{
+				"kind":     "ConfigMap",
+				"metadata": map[string]interface{}{"name": "config"},
+			},
+			err: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := PodListOptionsForObject(&unstructured.Unstructured{Object: tt.obj})
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, opts)
+		})
+	}
+}
+
+func TestCreateNamespace(t *testing.T) {
+	kubeClient := k8sfake.NewSimpleClientset(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Labels: map[string]string{"team": "a"}},
+	})
+	c := Client{kubeClient: kubeClient}
+
+	err := c.CreateNamespace("apps", NamespaceOptions{
+		Labels:      map[string]string{"pod-security.kubernetes.io/enforce": "restricted"},
+		Annotations: map[string]string{"owner": "platform"},
+	})
+	assert.NoError(t, err)
+	ns, err := kubeClient.CoreV1().Namespaces().Get(context.Background(), "apps", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "apps", "pod-security.kubernetes.io/enforce": "restricted"}, ns.Labels)
+	assert.Equal(t, map[string]string{"owner": "platform"}, ns.Annotations)
+
+	err = c.CreateNamespace("existing", NamespaceOptions{Labels: map[string]string{"team": "b"}})
+	assert.NoError(t, err)
+	ns, err = kubeClient.CoreV1().Namespaces().Get(context.Background(), "existing", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "a"}, ns.Labels)
+}
+
 func TestOutputContainerLogsForPodList(t *testing.T) {
 	namespace := "some-namespace"
 	somePodList := newPodList("jimmy", "three", "structs")
@@ -732,6 +1278,55 @@ func TestOutputContainerLogsForPodList(t *testing.T) {
 	clientAssertions.Equal("fake logsfake logsfake logs", outBuffer.String())
 }
 
+func TestContainersWithLogs(t *testing.T) {
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "setup"}},
+			Containers:     []v1.Container{{Name: "test"}, {Name: "sidecar"}},
+			EphemeralContainers: []v1.EphemeralContainer{
+				{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger"}},
+			},
+		},
+		Status: v1.PodStatus{
+			InitContainerStatuses: []v1.ContainerStatus{
+				{Name: "setup", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}},
+			},
+			ContainerStatuses: []v1.ContainerStatus{
+				{Name: "test", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "PodInitializing"}}},
+				{
+					Name:                 "sidecar",
+					State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 2}},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []PodContainer{
+		{Name: "setup", Type: InitContainerType},
+		{Name: "sidecar", Type: RegularContainerType},
+		{Name: "debugger", Type: EphemeralContainerType},
+	}, ContainersWithLogs(pod))
+}
+
+func TestOutputContainerLogsForPodListWithInitContainers(t *testing.T) {
+	namespace := "some-namespace"
+	pod := newPodWithStatus("jimmy", v1.PodStatus{}, namespace)
+	pod.Spec.InitContainers = []v1.Container{{Name: "setup"}}
+	podList := v1.PodList{Items: []v1.Pod{pod}}
+
+	kubeClient := k8sfake.NewSimpleClientset(&podList)
+	c := Client{Namespace: namespace, kubeClient: kubeClient}
+	var containers []string
+	outBufferFunc := func(_, _, container string) io.Writer {
+		containers = append(containers, container)
+		return io.Discard
+	}
+	err := c.OutputContainerLogsForPodList(&podList, namespace, outBufferFunc)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"setup", pod.Spec.Containers[0].Name}, containers)
+}
+
 const testServiceManifest = `
 kind: Service
 apiVersion: v1
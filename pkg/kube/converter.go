@@ -40,7 +40,12 @@ func AsVersioned(info *resource.Info) runtime.Object {
 // convertWithMapper converts the given object with the optional provided
 // RESTMapping. If no mapping is provided, the default schema versioner is used
 func convertWithMapper(obj runtime.Object, mapping *meta.RESTMapping) runtime.Object {
-	s := kubernetesNativeScheme()
+	return convertWithScheme(obj, mapping, kubernetesNativeScheme())
+}
+
+// convertWithScheme converts the given object with the given scheme and the
+// optional provided RESTMapping.
+func convertWithScheme(obj runtime.Object, mapping *meta.RESTMapping, s *runtime.Scheme) runtime.Object {
 	var gv = runtime.GroupVersioner(schema.GroupVersions(s.PrioritizedVersionsAllGroups()))
 	if mapping != nil {
 		gv = mapping.GroupVersionKind.GroupVersion()
@@ -67,3 +72,22 @@ func kubernetesNativeScheme() *runtime.Scheme {
 	})
 	return k8sNativeScheme
 }
+
+var addToGlobalSchemeOnce sync.Once
+var addToGlobalSchemeErr error
+
+// AddToGlobalScheme registers the API extension types, such as
+// CustomResourceDefinition, with the global client-go scheme.Scheme. Helm
+// used to do so when the package was imported; programs relying on that
+// registration can call it to keep the previous behavior. It is safe to call
+// more than once.
+func AddToGlobalScheme() error {
+	addToGlobalSchemeOnce.Do(func() {
+		if err := apiextensionsv1.AddToScheme(scheme.Scheme); err != nil {
+			addToGlobalSchemeErr = err
+			return
+		}
+		addToGlobalSchemeErr = apiextensionsv1beta1.AddToScheme(scheme.Scheme)
+	})
+	return addToGlobalSchemeErr
+}
@@ -0,0 +1,60 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestAddToGlobalScheme(t *testing.T) {
+	crdGVK := apiextv1.SchemeGroupVersion.WithKind("CustomResourceDefinition")
+
+	assert.False(t, scheme.Scheme.Recognizes(crdGVK), "importing the package must not register types with the global scheme")
+	require.NoError(t, AddToGlobalScheme())
+	assert.True(t, scheme.Scheme.Recognizes(crdGVK))
+	require.NoError(t, AddToGlobalScheme())
+}
+
+func TestConvertWithScheme(t *testing.T) {
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "widgets.example.com"},
+	}}
+	info := &resource.Info{
+		Object: crd,
+		Mapping: &meta.RESTMapping{
+			GroupVersionKind: crd.GroupVersionKind(),
+		},
+	}
+
+	_, ok := AsVersioned(info).(*apiextv1.CustomResourceDefinition)
+	assert.True(t, ok, "the default scheme must know the API extension types")
+
+	converted := convertWithScheme(crd, info.Mapping, runtime.NewScheme())
+	_, ok = converted.(runtime.Unstructured)
+	assert.True(t, ok, "types unknown to the scheme must be left unstructured")
+}
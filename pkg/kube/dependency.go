@@ -0,0 +1,121 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// DependsOnAnno is the annotation name for declaring the resources of the
+// same release a resource depends on. Its value is a comma separated list of
+// <kind>/<name> references, such as "Deployment/my-operator". A reference
+// matches resources of that kind and name in any namespace.
+const DependsOnAnno = "helm.sh/depends-on"
+
+// DependencyLevels groups resources into levels so that every resource only
+// depends on resources of earlier levels, as declared by DependsOnAnno.
+// Resources keep their relative order within a level. It also returns the
+// resources that other resources depend on.
+//
+// An error is returned if a resource depends on a resource that is not in
+// the list, or if the dependencies form a cycle.
+func DependencyLevels(resources ResourceList) ([]ResourceList, ResourceList, error) {
+	dependencies := make(map[*resource.Info][]*resource.Info, len(resources))
+	var required ResourceList
+	for _, info := range resources {
+		refs, err := dependsOn(info)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ref := range refs {
+			matches := resources.Filter(func(r *resource.Info) bool {
+				return strings.EqualFold(r.Mapping.GroupVersionKind.Kind, ref.kind) && r.Name == ref.name
+			})
+			if len(matches) == 0 {
+				return nil, nil, fmt.Errorf("%s %q depends on %s/%s, which is not part of the release", info.Mapping.GroupVersionKind.Kind, info.Name, ref.kind, ref.name)
+			}
+			for _, match := range matches {
+				dependencies[info] = append(dependencies[info], match)
+				if !required.Contains(match) {
+					required.Append(match)
+				}
+			}
+		}
+	}
+
+	var levels []ResourceList
+	done := make(map[*resource.Info]bool, len(resources))
+	for len(done) < len(resources) {
+		var level ResourceList
+		for _, info := range resources {
+			if done[info] {
+				continue
+			}
+			ready := true
+			for _, dependency := range dependencies[info] {
+				if !done[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level.Append(info)
+			}
+		}
+		if len(level) == 0 {
+			var cycle []string
+			for _, info := range resources {
+				if !done[info] {
+					cycle = append(cycle, info.Mapping.GroupVersionKind.Kind+"/"+info.Name)
+				}
+			}
+			return nil, nil, fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+		}
+		for _, info := range level {
+			done[info] = true
+		}
+		levels = append(levels, level)
+	}
+	return levels, required, nil
+}
+
+type dependencyRef struct {
+	kind, name string
+}
+
+func dependsOn(info *resource.Info) ([]dependencyRef, error) {
+	annotations, err := metadataAccessor.Annotations(info.Object)
+	if err != nil {
+		return nil, err
+	}
+	value := strings.TrimSpace(annotations[DependsOnAnno])
+	if value == "" {
+		return nil, nil
+	}
+	var refs []dependencyRef
+	for _, ref := range strings.Split(value, ",") {
+		kind, name, ok := strings.Cut(strings.TrimSpace(ref), "/")
+		if !ok || kind == "" || name == "" {
+			return nil, fmt.Errorf("invalid value %q for annotation %s on %s %q: must be a comma separated list of <kind>/<name>", value, DependsOnAnno, info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+		refs = append(refs, dependencyRef{kind: kind, name: name})
+	}
+	return refs, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resourceNames(list ResourceList) []string {
+	var names []string
+	for _, info := range list {
+		names = append(names, info.Mapping.GroupVersionKind.Kind+"/"+info.Name)
+	}
+	return names
+}
+
+func TestDependencyLevels(t *testing.T) {
+	manifest := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  annotations:
+    helm.sh/depends-on: Deployment/operator
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: operator
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operator
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: client
+  annotations:
+    helm.sh/depends-on: "configmap/settings, Service/operator"
+`
+	c := newTestClient(t)
+	resources, err := c.Build(strings.NewReader(manifest), false)
+	require.NoError(t, err)
+
+	levels, required, err := DependencyLevels(resources)
+	require.NoError(t, err)
+	require.Len(t, levels, 3)
+	assert.Equal(t, []string{"Service/operator", "Deployment/operator"}, resourceNames(levels[0]))
+	assert.Equal(t, []string{"ConfigMap/settings"}, resourceNames(levels[1]))
+	assert.Equal(t, []string{"Pod/client"}, resourceNames(levels[2]))
+	assert.ElementsMatch(t, []string{"Deployment/operator", "ConfigMap/settings", "Service/operator"}, resourceNames(required))
+}
+
+func TestDependencyLevelsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		err      string
+	}{
+		{
+			name: "missing dependency",
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  annotations:
+    helm.sh/depends-on: Deployment/operator
+`,
+			err: `ConfigMap "settings" depends on Deployment/operator, which is not part of the release`,
+		},
+		{
+			name: "cycle",
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: a
+  annotations:
+    helm.sh/depends-on: ConfigMap/b
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: b
+  annotations:
+    helm.sh/depends-on: ConfigMap/a
+`,
+			err: "dependency cycle between ConfigMap/a, ConfigMap/b",
+		},
+		{
+			name: "invalid reference",
+			manifest: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  annotations:
+    helm.sh/depends-on: operator
+`,
+			err: `invalid value "operator" for annotation helm.sh/depends-on`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			resources, err := c.Build(strings.NewReader(tt.manifest), false)
+			require.NoError(t, err)
+
+			_, _, err = DependencyLevels(resources)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestDryRunApplyDiffs(t *testing.T) {
+	listA := newPodList("otter", "squid")
+	listB := newPodList("starfish", "otter")
+	listB.Items[1].Spec.Containers[0].Ports = []v1.ContainerPort{{Name: "https", ContainerPort: 443}}
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			p, m := req.URL.Path, req.Method
+			if m != "GET" {
+				assert.Equal(t, "All", req.URL.Query().Get("dryRun"), "%s %s", m, p)
+			}
+			switch {
+			case p == "/namespaces/default/pods/starfish" && m == "GET":
+				return newResponse(404, notFoundBody())
+			case p == "/namespaces/default/pods" && m == "POST":
+				return newResponse(201, &listB.Items[0])
+			case p == "/namespaces/default/pods/otter" && m == "GET":
+				return newResponse(200, &listA.Items[0])
+			case p == "/namespaces/default/pods/otter" && m == "PATCH":
+				return newResponse(200, &listB.Items[1])
+			default:
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return nil, nil
+			}
+		}),
+	}
+	first, err := c.Build(objBody(&listA), false)
+	require.NoError(t, err)
+	second, err := c.Build(objBody(&listB), false)
+	require.NoError(t, err)
+
+	diffs, err := c.DryRunApply(first, second, false)
+	require.NoError(t, err)
+	require.Len(t, diffs, 2)
+
+	assert.Equal(t, "starfish", diffs[0].Info.Name)
+	assert.Nil(t, diffs[0].Live)
+	assert.NotNil(t, diffs[0].Merged)
+
+	assert.Equal(t, "otter", diffs[1].Info.Name)
+	require.NotNil(t, diffs[1].Live)
+	liveContainers, _, err := unstructured.NestedSlice(diffs[1].Live.(*unstructured.Unstructured).Object, "spec", "containers")
+	require.NoError(t, err)
+	mergedContainers, _, err := unstructured.NestedSlice(diffs[1].Merged.(*unstructured.Unstructured).Object, "spec", "containers")
+	require.NoError(t, err)
+	assert.NotEqual(t, liveContainers, mergedContainers)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// ResourceError is an error that occurred while operating on a single resource.
+type ResourceError struct {
+	// Info is the resource the error relates to.
+	Info *resource.Info
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ResourceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+// AggregateError collects the errors of an operation that carried on after
+// individual resources failed. Each error is usually a *ResourceError, which
+// can be retrieved with errors.As or by ranging over Unwrap.
+type AggregateError struct {
+	Errs []error
+}
+
+func (e *AggregateError) Error() string {
+	msgs := make([]string, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, " && ")
+}
+
+func (e *AggregateError) Unwrap() []error {
+	return e.Errs
+}
+
+// StuckResource is a resource that still exists once waiting for its deletion
+// has timed out.
+type StuckResource struct {
+	Kind      string
+	Namespace string
+	Name      string
+	// Status is the last status of the resource, usually Terminating.
+	Status string
+	// Finalizers are the finalizers holding up the deletion of the resource.
+	Finalizers []string
+}
+
+func (r StuckResource) String() string {
+	msg := fmt.Sprintf("resource still exists, name: %s, kind: %s, status: %s", r.Name, r.Kind, r.Status)
+	if len(r.Finalizers) > 0 {
+		msg += fmt.Sprintf(", finalizers: %s", strings.Join(r.Finalizers, ", "))
+	}
+	return msg
+}
+
+// DeletionTimeoutError is returned by the watcher-based WaitForDelete when
+// some of the resources still exist once the timeout has expired.
+type DeletionTimeoutError struct {
+	Resources []StuckResource
+	// Err is the error of the expired context.
+	Err error
+}
+
+func (e *DeletionTimeoutError) Error() string {
+	msgs := make([]string, 0, len(e.Resources)+1)
+	for _, r := range e.Resources {
+		msgs = append(msgs, r.String())
+	}
+	msgs = append(msgs, e.Err.Error())
+	return strings.Join(msgs, "\n")
+}
+
+func (e *DeletionTimeoutError) Unwrap() error {
+	return e.Err
+}
@@ -23,6 +23,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/resource"
 
 	"helm.sh/helm/v4/pkg/kube"
@@ -38,6 +39,11 @@ type FailingKubeClient struct {
 	DeleteError                error
 	DeleteWithPropagationError error
 	UpdateError                error
+	CreateNamespaceError       error
+	DryRunApplyError           error
+	HealthError                error
+	ValidateError              error
+	PatchSubresourceError      error
 	BuildError                 error
 	BuildTableError            error
 	BuildDummy                 bool
@@ -74,6 +80,54 @@ func (f *FailingKubeClient) Get(resources kube.ResourceList, related bool) (map[
 	return f.PrintingKubeClient.Get(resources, related)
 }
 
+// CreateNamespace returns the configured error if set or prints
+func (f *FailingKubeClient) CreateNamespace(name string, options kube.NamespaceOptions) error {
+	if f.CreateNamespaceError != nil {
+		return f.CreateNamespaceError
+	}
+	return f.PrintingKubeClient.CreateNamespace(name, options)
+}
+
+// Validate returns the configured error if set or prints
+func (f *FailingKubeClient) Validate(resources kube.ResourceList, opts ...kube.ValidateOption) error {
+	if f.ValidateError != nil {
+		return f.ValidateError
+	}
+	return f.PrintingKubeClient.Validate(resources, opts...)
+}
+
+// PatchSubresource returns the configured error if set or prints
+func (f *FailingKubeClient) PatchSubresource(info *resource.Info, subresource string, patchType types.PatchType, patch []byte) error {
+	if f.PatchSubresourceError != nil {
+		return f.PatchSubresourceError
+	}
+	return f.PrintingKubeClient.PatchSubresource(info, subresource, patchType, patch)
+}
+
+// PatchStatus returns the configured error if set or prints
+func (f *FailingKubeClient) PatchStatus(info *resource.Info, status interface{}) error {
+	if f.PatchSubresourceError != nil {
+		return f.PatchSubresourceError
+	}
+	return f.PrintingKubeClient.PatchStatus(info, status)
+}
+
+// Scale returns the configured error if set or prints
+func (f *FailingKubeClient) Scale(info *resource.Info, replicas int32) error {
+	if f.PatchSubresourceError != nil {
+		return f.PatchSubresourceError
+	}
+	return f.PrintingKubeClient.Scale(info, replicas)
+}
+
+// GetTable returns the configured error if set or prints
+func (f *FailingKubeClient) GetTable(resources kube.ResourceList, related bool, options kube.TableOptions) (map[string][]runtime.Object, error) {
+	if f.GetError != nil {
+		return nil, f.GetError
+	}
+	return f.PrintingKubeClient.GetTable(resources, related, options)
+}
+
 // Waits the amount of time defined on f.WaitDuration, then returns the configured error if set or prints.
 func (f *FailingKubeWaiter) Wait(resources kube.ResourceList, d time.Duration) error {
 	time.Sleep(f.waitDuration)
@@ -123,6 +177,30 @@ func (f *FailingKubeClient) Update(r, modified kube.ResourceList, ignoreMe bool)
 	return f.PrintingKubeClient.Update(r, modified, ignoreMe)
 }
 
+// UpdateWithOptions returns the configured error if set or prints
+func (f *FailingKubeClient) UpdateWithOptions(r, modified kube.ResourceList, ignoreMe bool, opts ...kube.UpdateOption) (*kube.Result, error) {
+	if f.UpdateError != nil {
+		return &kube.Result{}, f.UpdateError
+	}
+	return f.PrintingKubeClient.UpdateWithOptions(r, modified, ignoreMe, opts...)
+}
+
+// DryRunApply returns the configured error if set or prints
+func (f *FailingKubeClient) DryRunApply(original, target kube.ResourceList, force bool) ([]kube.ResourceDiff, error) {
+	if f.DryRunApplyError != nil {
+		return nil, f.DryRunApplyError
+	}
+	return f.PrintingKubeClient.DryRunApply(original, target, force)
+}
+
+// Health returns the configured error if set or prints
+func (f *FailingKubeClient) Health(resources kube.ResourceList) ([]kube.ResourceHealth, error) {
+	if f.HealthError != nil {
+		return nil, f.HealthError
+	}
+	return f.PrintingKubeClient.Health(resources)
+}
+
 // Build returns the configured error if set or prints
 func (f *FailingKubeClient) Build(r io.Reader, _ bool) (kube.ResourceList, error) {
 	if f.BuildError != nil {
@@ -25,6 +25,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/resource"
 
 	"helm.sh/helm/v4/pkg/kube"
@@ -65,6 +66,43 @@ func (p *PrintingKubeClient) Get(resources kube.ResourceList, _ bool) (map[strin
 	return make(map[string][]runtime.Object), nil
 }
 
+// CreateNamespace implements KubeClient CreateNamespace.
+//
+// It only prints out the name of the namespace to be created.
+func (p *PrintingKubeClient) CreateNamespace(name string, _ kube.NamespaceOptions) error {
+	_, err := fmt.Fprintf(p.Out, "namespace/%s\n", name)
+	return err
+}
+
+// Validate implements KubeClient Validate.
+func (p *PrintingKubeClient) Validate(resources kube.ResourceList, _ ...kube.ValidateOption) error {
+	_, err := io.Copy(p.Out, bufferize(resources))
+	return err
+}
+
+// PatchSubresource implements KubeClient PatchSubresource.
+//
+// It only prints out the subresource and the resource to be patched.
+func (p *PrintingKubeClient) PatchSubresource(info *resource.Info, subresource string, _ types.PatchType, _ []byte) error {
+	_, err := fmt.Fprintf(p.Out, "%s (%s)\n", info.String(), subresource)
+	return err
+}
+
+// PatchStatus implements KubeClient PatchStatus.
+func (p *PrintingKubeClient) PatchStatus(info *resource.Info, _ interface{}) error {
+	return p.PatchSubresource(info, kube.StatusSubresource, types.MergePatchType, nil)
+}
+
+// Scale implements KubeClient Scale.
+func (p *PrintingKubeClient) Scale(info *resource.Info, _ int32) error {
+	return p.PatchSubresource(info, kube.ScaleSubresource, types.MergePatchType, nil)
+}
+
+// GetTable implements KubeClient GetTable.
+func (p *PrintingKubeClient) GetTable(resources kube.ResourceList, related bool, _ kube.TableOptions) (map[string][]runtime.Object, error) {
+	return p.Get(resources, related)
+}
+
 func (p *PrintingKubeWaiter) Wait(resources kube.ResourceList, _ time.Duration) error {
 	_, err := io.Copy(p.Out, bufferize(resources))
 	return err
@@ -98,7 +136,12 @@ func (p *PrintingKubeClient) Delete(resources kube.ResourceList) (*kube.Result,
 }
 
 // Update implements KubeClient Update.
-func (p *PrintingKubeClient) Update(_, modified kube.ResourceList, _ bool) (*kube.Result, error) {
+func (p *PrintingKubeClient) Update(original, modified kube.ResourceList, force bool) (*kube.Result, error) {
+	return p.UpdateWithOptions(original, modified, force)
+}
+
+// UpdateWithOptions implements KubeClient UpdateWithOptions.
+func (p *PrintingKubeClient) UpdateWithOptions(_, modified kube.ResourceList, _ bool, _ ...kube.UpdateOption) (*kube.Result, error) {
 	_, err := io.Copy(p.Out, bufferize(modified))
 	if err != nil {
 		return nil, err
@@ -109,6 +152,33 @@ func (p *PrintingKubeClient) Update(_, modified kube.ResourceList, _ bool) (*kub
 	return &kube.Result{Updated: modified}, nil
 }
 
+// DryRunApply implements KubeClient DryRunApply. Every target resource is
+// accepted as it is, and reported as missing from the cluster.
+func (p *PrintingKubeClient) DryRunApply(_, target kube.ResourceList, _ bool) ([]kube.ResourceDiff, error) {
+	if _, err := io.Copy(p.Out, bufferize(target)); err != nil {
+		return nil, err
+	}
+	diffs := make([]kube.ResourceDiff, 0, len(target))
+	for _, info := range target {
+		diffs = append(diffs, kube.ResourceDiff{Info: info, Merged: info.Object})
+	}
+	return diffs, nil
+}
+
+// Health implements KubeClient Health. Every resource is reported as ready.
+func (p *PrintingKubeClient) Health(resources kube.ResourceList) ([]kube.ResourceHealth, error) {
+	health := make([]kube.ResourceHealth, 0, len(resources))
+	for _, info := range resources {
+		health = append(health, kube.ResourceHealth{
+			Kind:      info.Object.GetObjectKind().GroupVersionKind().Kind,
+			Namespace: info.Namespace,
+			Name:      info.Name,
+			Status:    kube.HealthStatusReady,
+		})
+	}
+	return health, nil
+}
+
 // Build implements KubeClient Build.
 func (p *PrintingKubeClient) Build(_ io.Reader, _ bool) (kube.ResourceList, error) {
 	return []*resource.Info{}, nil
@@ -130,8 +200,8 @@ func (p *PrintingKubeClient) GetPodList(_ string, _ metav1.ListOptions) (*v1.Pod
 }
 
 // OutputContainerLogsForPodList implements KubeClient OutputContainerLogsForPodList.
-func (p *PrintingKubeClient) OutputContainerLogsForPodList(_ *v1.PodList, someNamespace string, _ func(namespace, pod, container string) io.Writer) error {
-	_, err := io.Copy(p.LogOutput, strings.NewReader(fmt.Sprintf("attempted to output logs for namespace: %s", someNamespace)))
+func (p *PrintingKubeClient) OutputContainerLogsForPodList(_ *v1.PodList, someNamespace string, writerFunc func(namespace, pod, container string) io.Writer) error {
+	_, err := io.Copy(writerFunc(someNamespace, "", ""), strings.NewReader(fmt.Sprintf("attempted to output logs for namespace: %s", someNamespace)))
 	return err
 }
 
@@ -0,0 +1,102 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"context"
+	"time"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/clusterreader"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/object"
+)
+
+const (
+	// HealthStatusMissing indicates the resource is missing from the cluster.
+	HealthStatusMissing HealthStatus = "Missing"
+	// HealthStatusUnknown indicates the resource could not be read.
+	HealthStatusUnknown HealthStatus = "Unknown"
+)
+
+// ResourceHealth is the health of the live object of a resource.
+type ResourceHealth struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Status    HealthStatus
+	// Message explains the status, such as why a resource is not ready.
+	Message string
+	// Created is when the live object was created. It is zero when the
+	// resource is missing.
+	Created time.Time
+}
+
+// Health reads the live objects of the resources once and returns their
+// health, judged as the status watcher judges readiness when waiting.
+func (c *Client) Health(resources ResourceList) ([]ResourceHealth, error) {
+	sw, err := c.newStatusWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return sw.health(context.Background(), resources)
+}
+
+func (w *statusWaiter) health(ctx context.Context, resourceList ResourceList) ([]ResourceHealth, error) {
+	reader := &clusterreader.DynamicClusterReader{DynamicClient: w.client, Mapper: w.restMapper}
+	sr := w.statusReader()
+	health := make([]ResourceHealth, 0, len(resourceList))
+	for _, info := range resourceList {
+		id, err := object.RuntimeToObjMeta(info.Object)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := sr.ReadStatus(ctx, reader, id)
+		if err != nil {
+			return nil, err
+		}
+		h := ResourceHealth{
+			Kind:      id.GroupKind.Kind,
+			Namespace: id.Namespace,
+			Name:      id.Name,
+			Status:    healthStatus(rs.Status),
+			Message:   rs.Message,
+		}
+		if rs.Resource != nil {
+			h.Created = rs.Resource.GetCreationTimestamp().Time
+		}
+		if rs.Error != nil && h.Message == "" {
+			h.Message = rs.Error.Error()
+		}
+		health = append(health, h)
+	}
+	return health, nil
+}
+
+func healthStatus(s status.Status) HealthStatus {
+	switch s {
+	case status.CurrentStatus:
+		return HealthStatusReady
+	case status.InProgressStatus, status.TerminatingStatus:
+		return HealthStatusInProgress
+	case status.FailedStatus:
+		return HealthStatusFailed
+	case status.NotFoundStatus:
+		return HealthStatusMissing
+	default:
+		return HealthStatusUnknown
+	}
+}
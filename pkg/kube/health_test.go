@@ -0,0 +1,57 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fluxcd/cli-utils/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+func TestStatusWaiterHealth(t *testing.T) {
+	c := newTestClient(t)
+	fakeClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme)
+	fakeMapper := testutil.NewFakeRESTMapper(
+		v1.SchemeGroupVersion.WithKind("Pod"),
+		batchv1.SchemeGroupVersion.WithKind("Job"),
+	)
+	sw := statusWaiter{client: fakeClient, restMapper: fakeMapper}
+
+	live := getRuntimeObjFromManifests(t, []string{podCurrentManifest, jobNoStatusManifest})
+	for _, obj := range live {
+		u := obj.(*unstructured.Unstructured)
+		require.NoError(t, fakeClient.Tracker().Create(getGVR(t, fakeMapper, u), u, u.GetNamespace()))
+	}
+	objs := append(live, getRuntimeObjFromManifests(t, []string{podNoStatusManifest})...)
+
+	health, err := sw.health(context.Background(), getResourceListFromRuntimeObjs(t, c, objs))
+	require.NoError(t, err)
+	require.Len(t, health, 3)
+
+	assert.Equal(t, ResourceHealth{Kind: "Pod", Namespace: "ns", Name: "current-pod", Status: HealthStatusReady, Message: "Pod is Ready"}, health[0])
+	assert.Equal(t, "test", health[1].Name)
+	assert.Equal(t, HealthStatusInProgress, health[1].Status)
+	assert.Equal(t, ResourceHealth{Kind: "Pod", Namespace: "ns", Name: "in-progress-pod", Status: HealthStatusMissing, Message: "Resource not found"}, health[2])
+}
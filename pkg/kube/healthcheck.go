@@ -0,0 +1,177 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/engine"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/event"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/polling/statusreaders"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/fluxcd/cli-utils/pkg/object"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// HealthStatus is the readiness verdict returned by a HealthChecker.
+type HealthStatus string
+
+const (
+	// HealthStatusReady indicates the resource is ready.
+	HealthStatusReady HealthStatus = "Ready"
+	// HealthStatusInProgress indicates the resource is not ready yet.
+	HealthStatusInProgress HealthStatus = "InProgress"
+	// HealthStatusFailed indicates the resource will not become ready.
+	HealthStatusFailed HealthStatus = "Failed"
+)
+
+// HealthCheckResult is the outcome of a single HealthChecker check.
+type HealthCheckResult struct {
+	Status  HealthStatus `json:"status"`
+	Message string       `json:"message,omitempty"`
+}
+
+// HealthChecker judges the readiness of resources of specific kinds.
+//
+// HealthCheckers are consulted by the status watcher before its built-in
+// rules, which allows readiness logic for bespoke operators and custom
+// resources to be supplied without modifying Helm.
+type HealthChecker interface {
+	// Supports reports whether the checker handles resources of the given kind.
+	Supports(gk schema.GroupKind) bool
+
+	// Check returns the readiness of the given live object.
+	Check(ctx context.Context, obj *unstructured.Unstructured) (*HealthCheckResult, error)
+}
+
+// ExecHealthChecker is a HealthChecker backed by an external executable.
+//
+// The live object is written to the standard input of the command as JSON.
+// The command must write a JSON encoded HealthCheckResult to its standard
+// output and exit with a zero status. A non-zero exit status is treated as an
+// error checking the resource, not as the resource having failed.
+type ExecHealthChecker struct {
+	// Command is the path of the executable to run.
+	Command string
+	// Args are passed to the command on every invocation.
+	Args []string
+	// GroupKinds are the kinds of resources the command can judge.
+	GroupKinds []schema.GroupKind
+}
+
+// Supports implements HealthChecker.
+func (e *ExecHealthChecker) Supports(gk schema.GroupKind) bool {
+	return slices.Contains(e.GroupKinds, gk)
+}
+
+// Check implements HealthChecker.
+func (e *ExecHealthChecker) Check(ctx context.Context, obj *unstructured.Unstructured) (*HealthCheckResult, error) {
+	input, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("serializing %s %q for health check: %w", obj.GetKind(), obj.GetName(), err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.Command, e.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("health check %s failed for %s %q: %w: %s", e.Command, obj.GetKind(), obj.GetName(), err, strings.TrimSpace(stderr.String()))
+	}
+
+	result := &HealthCheckResult{}
+	if err := json.Unmarshal(stdout.Bytes(), result); err != nil {
+		return nil, fmt.Errorf("health check %s returned invalid output for %s %q: %w", e.Command, obj.GetKind(), obj.GetName(), err)
+	}
+	return result, nil
+}
+
+// pvcBindingChecker requires PersistentVolumeClaims to be bound unless binding
+// is ignored for all claims or the claim opts out through PVCBoundAnno.
+type pvcBindingChecker struct {
+	ignoreBinding bool
+}
+
+func (p *pvcBindingChecker) Supports(gk schema.GroupKind) bool {
+	return gk == corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim").GroupKind()
+}
+
+func (p *pvcBindingChecker) Check(_ context.Context, obj *unstructured.Unstructured) (*HealthCheckResult, error) {
+	if p.ignoreBinding || !pvcBindingRequired(obj.GetAnnotations()) {
+		return &HealthCheckResult{Status: HealthStatusReady, Message: "PVC binding is not checked"}, nil
+	}
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase != string(corev1.ClaimBound) {
+		return &HealthCheckResult{Status: HealthStatusInProgress, Message: fmt.Sprintf("PVC is not Bound. phase: %s", phase)}, nil
+	}
+	return &HealthCheckResult{Status: HealthStatusReady, Message: "PVC is Bound"}, nil
+}
+
+// healthCheckStatusReader adapts a HealthChecker to a kstatus status reader.
+type healthCheckStatusReader struct {
+	checker HealthChecker
+	mapper  meta.RESTMapper
+}
+
+func newHealthCheckStatusReaders(mapper meta.RESTMapper, checkers []HealthChecker) []engine.StatusReader {
+	readers := make([]engine.StatusReader, 0, len(checkers))
+	for _, checker := range checkers {
+		readers = append(readers, &healthCheckStatusReader{checker: checker, mapper: mapper})
+	}
+	return readers
+}
+
+func (r *healthCheckStatusReader) Supports(gk schema.GroupKind) bool {
+	return r.checker.Supports(gk)
+}
+
+func (r *healthCheckStatusReader) ReadStatus(ctx context.Context, reader engine.ClusterReader, resource object.ObjMetadata) (*event.ResourceStatus, error) {
+	return statusreaders.NewGenericStatusReader(r.mapper, r.statusFunc(ctx)).ReadStatus(ctx, reader, resource)
+}
+
+func (r *healthCheckStatusReader) ReadStatusForObject(ctx context.Context, reader engine.ClusterReader, resource *unstructured.Unstructured) (*event.ResourceStatus, error) {
+	return statusreaders.NewGenericStatusReader(r.mapper, r.statusFunc(ctx)).ReadStatusForObject(ctx, reader, resource)
+}
+
+func (r *healthCheckStatusReader) statusFunc(ctx context.Context) func(*unstructured.Unstructured) (*status.Result, error) {
+	return func(u *unstructured.Unstructured) (*status.Result, error) {
+		result, err := r.checker.Check(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		switch result.Status {
+		case HealthStatusReady:
+			return &status.Result{Status: status.CurrentStatus, Message: result.Message}, nil
+		case HealthStatusInProgress:
+			return &status.Result{Status: status.InProgressStatus, Message: result.Message}, nil
+		case HealthStatusFailed:
+			return &status.Result{Status: status.FailedStatus, Message: result.Message}, nil
+		default:
+			return nil, fmt.Errorf("unknown health status %q for %s %q", result.Status, u.GetKind(), u.GetName())
+		}
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/cli-utils/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/kubectl/pkg/scheme"
+)
+
+type staticHealthChecker struct {
+	groupKind schema.GroupKind
+	result    HealthCheckResult
+}
+
+func (s *staticHealthChecker) Supports(gk schema.GroupKind) bool {
+	return gk == s.groupKind
+}
+
+func (s *staticHealthChecker) Check(_ context.Context, _ *unstructured.Unstructured) (*HealthCheckResult, error) {
+	return &s.result, nil
+}
+
+func TestStatusWaitWithHealthChecker(t *testing.T) {
+	t.Parallel()
+	deploymentGK := appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind()
+	tests := []struct {
+		name       string
+		result     HealthCheckResult
+		expectErrs []error
+	}{
+		{
+			name:   "health checker overrides built-in readiness",
+			result: HealthCheckResult{Status: HealthStatusReady},
+		},
+		{
+			name:       "health checker reports in progress",
+			result:     HealthCheckResult{Status: HealthStatusInProgress, Message: "operator reconciling"},
+			expectErrs: []error{errors.New("resource not ready, name: not-ready, kind: Deployment, status: InProgress"), errors.New("context deadline exceeded")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := newTestClient(t)
+			fakeClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme)
+			fakeMapper := testutil.NewFakeRESTMapper(
+				v1.SchemeGroupVersion.WithKind("Pod"),
+				appsv1.SchemeGroupVersion.WithKind("Deployment"),
+			)
+			statusWaiter := statusWaiter{
+				client:     fakeClient,
+				restMapper: fakeMapper,
+				healthCheckers: []HealthChecker{
+					&staticHealthChecker{groupKind: deploymentGK, result: tt.result},
+				},
+			}
+			objs := getRuntimeObjFromManifests(t, []string{notReadyDeploymentManifest})
+			for _, obj := range objs {
+				u := obj.(*unstructured.Unstructured)
+				gvr := getGVR(t, fakeMapper, u)
+				err := fakeClient.Tracker().Create(gvr, u, u.GetNamespace())
+				assert.NoError(t, err)
+			}
+			resourceList := getResourceListFromRuntimeObjs(t, c, objs)
+			err := statusWaiter.Wait(resourceList, time.Second)
+			if tt.expectErrs != nil {
+				assert.EqualError(t, err, errors.Join(tt.expectErrs...).Error())
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestExecHealthChecker(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	obj := getRuntimeObjFromManifests(t, []string{podCurrentManifest})[0].(*unstructured.Unstructured)
+
+	checker := &ExecHealthChecker{
+		Command:    "sh",
+		Args:       []string{"-c", `grep -q current-pod && echo '{"status": "Ready", "message": "looks good"}'`},
+		GroupKinds: []schema.GroupKind{v1.SchemeGroupVersion.WithKind("Pod").GroupKind()},
+	}
+	assert.True(t, checker.Supports(v1.SchemeGroupVersion.WithKind("Pod").GroupKind()))
+	assert.False(t, checker.Supports(appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind()))
+
+	result, err := checker.Check(context.Background(), obj)
+	require.NoError(t, err)
+	assert.Equal(t, &HealthCheckResult{Status: HealthStatusReady, Message: "looks good"}, result)
+
+	checker.Args = []string{"-c", "echo broken >&2; exit 1"}
+	_, err = checker.Check(context.Background(), obj)
+	assert.ErrorContains(t, err, "broken")
+
+	checker.Args = []string{"-c", "echo not-json"}
+	_, err = checker.Check(context.Background(), obj)
+	assert.ErrorContains(t, err, "invalid output")
+}
+
+func TestPVCBindingChecker(t *testing.T) {
+	tests := []struct {
+		name          string
+		ignoreBinding bool
+		annotations   map[string]string
+		phase         string
+		expected      HealthStatus
+	}{
+		{
+			name:     "bound claim is ready",
+			phase:    "Bound",
+			expected: HealthStatusReady,
+		},
+		{
+			name:     "pending claim is in progress",
+			phase:    "Pending",
+			expected: HealthStatusInProgress,
+		},
+		{
+			name:          "pending claim is ready when binding is ignored",
+			ignoreBinding: true,
+			phase:         "Pending",
+			expected:      HealthStatusReady,
+		},
+		{
+			name:        "pending claim is ready when annotated",
+			annotations: map[string]string{PVCBoundAnno: "false"},
+			phase:       "Pending",
+			expected:    HealthStatusReady,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "PersistentVolumeClaim",
+				"metadata":   map[string]interface{}{"name": "claim"},
+				"status":     map[string]interface{}{"phase": tt.phase},
+			}}
+			obj.SetAnnotations(tt.annotations)
+			checker := &pvcBindingChecker{ignoreBinding: tt.ignoreBinding}
+			require.True(t, checker.Supports(v1.SchemeGroupVersion.WithKind("PersistentVolumeClaim").GroupKind()))
+			result, err := checker.Check(context.Background(), obj)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Status)
+		})
+	}
+}
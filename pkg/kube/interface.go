@@ -17,12 +17,15 @@ limitations under the License.
 package kube
 
 import (
+	"context"
 	"io"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
 )
 
 // Interface represents a client capable of communicating with the Kubernetes API.
@@ -76,6 +79,23 @@ type Waiter interface {
 	WatchUntilReady(resources ResourceList, timeout time.Duration) error
 }
 
+// WaiterContext is introduced to avoid breaking backwards compatibility for Waiter implementers.
+//
+// TODO Helm 4: Remove WaiterContext and integrate its method(s) into the Waiter.
+type WaiterContext interface {
+	// WatchUntilReadyWithContext is WatchUntilReady, but it also stops
+	// watching when ctx is done.
+	WatchUntilReadyWithContext(ctx context.Context, resources ResourceList, timeout time.Duration) error
+}
+
+// WaiterHookDeletion is implemented by the Waiters that do not wait for
+// deletions by default, so that hook deletions can be waited on anyway.
+type WaiterHookDeletion interface {
+	// WaitForHookDeletion waits up to the given timeout for the deleted hook
+	// resources, and any pods created by deleted Jobs, to be gone.
+	WaitForHookDeletion(resources ResourceList, timeout time.Duration) error
+}
+
 // InterfaceLogs was introduced to avoid breaking backwards compatibility for Interface implementers.
 //
 // TODO Helm 4: Remove InterfaceLogs and integrate its method(s) into the Interface.
@@ -95,6 +115,16 @@ type InterfaceDeletionPropagation interface {
 	DeleteWithPropagationPolicy(resources ResourceList, policy metav1.DeletionPropagation) (*Result, []error)
 }
 
+// InterfaceUpdateOptions is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceUpdateOptions and integrate its method(s) into the Interface.
+type InterfaceUpdateOptions interface {
+	// UpdateWithOptions is Update, with its behavior configured by opts, such
+	// as continuing on errors, applying with server-side apply or being
+	// notified of every applied resource.
+	UpdateWithOptions(original, target ResourceList, force bool, opts ...UpdateOption) (*Result, error)
+}
+
 // InterfaceResources is introduced to avoid breaking backwards compatibility for Interface implementers.
 //
 // TODO Helm 4: Remove InterfaceResources and integrate its method(s) into the Interface.
@@ -117,7 +147,76 @@ type InterfaceResources interface {
 	BuildTable(reader io.Reader, validate bool) (ResourceList, error)
 }
 
+// InterfaceTables is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceTables and integrate its method(s) into the Interface.
+type InterfaceTables interface {
+	// GetTable is like InterfaceResources.Get, with the columns of the Tables
+	// returned for resources built with BuildTable selected by options.
+	GetTable(resources ResourceList, related bool, options TableOptions) (map[string][]runtime.Object, error)
+}
+
+// InterfaceNamespaces is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceNamespaces and integrate its method(s) into the Interface.
+type InterfaceNamespaces interface {
+	// CreateNamespace creates a namespace with the labels and annotations of
+	// options, leaving an existing namespace untouched.
+	CreateNamespace(name string, options NamespaceOptions) error
+}
+
+// InterfaceValidate is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceValidate and integrate its method(s) into the Interface.
+type InterfaceValidate interface {
+	// Validate submits the resources with server-side dry-run and strict
+	// field validation and reports every rejected resource.
+	Validate(resources ResourceList, opts ...ValidateOption) error
+}
+
+// InterfaceSubresources is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceSubresources and integrate its method(s) into the Interface.
+type InterfaceSubresources interface {
+	// PatchSubresource applies a patch to a subresource, such as "status" or
+	// "scale", of the live object of a resource.
+	PatchSubresource(info *resource.Info, subresource string, patchType types.PatchType, patch []byte) error
+
+	// PatchStatus merges status into the status of the live object of a
+	// resource.
+	PatchStatus(info *resource.Info, status interface{}) error
+
+	// Scale sets the number of replicas of the live object of a resource.
+	Scale(info *resource.Info, replicas int32) error
+}
+
+// InterfaceDryRunApply is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceDryRunApply and integrate its method(s) into the Interface.
+type InterfaceDryRunApply interface {
+	// DryRunApply submits the changes Update would make to the server with
+	// server-side dry-run and returns the change of every accepted resource
+	// along with the rejection of every other resource.
+	DryRunApply(original, target ResourceList, force bool) ([]ResourceDiff, error)
+}
+
+// InterfaceHealth is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceHealth and integrate its method(s) into the Interface.
+type InterfaceHealth interface {
+	// Health reads the live objects of the resources once and returns their
+	// health.
+	Health(resources ResourceList) ([]ResourceHealth, error)
+}
+
 var _ Interface = (*Client)(nil)
 var _ InterfaceLogs = (*Client)(nil)
 var _ InterfaceDeletionPropagation = (*Client)(nil)
+var _ InterfaceUpdateOptions = (*Client)(nil)
 var _ InterfaceResources = (*Client)(nil)
+var _ InterfaceTables = (*Client)(nil)
+var _ InterfaceNamespaces = (*Client)(nil)
+var _ InterfaceValidate = (*Client)(nil)
+var _ InterfaceSubresources = (*Client)(nil)
+var _ InterfaceDryRunApply = (*Client)(nil)
+var _ InterfaceHealth = (*Client)(nil)
@@ -0,0 +1,108 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v4/typed"
+)
+
+// managedFieldsOf returns the fields of the live object that are managed by
+// the given field manager. It returns false if the live object does not
+// record any fields for the manager.
+func managedFieldsOf(live runtime.Object, manager string) (map[string]interface{}, bool, error) {
+	if live == nil {
+		return nil, false, nil
+	}
+	accessor, err := meta.Accessor(live)
+	if err != nil {
+		return nil, false, err
+	}
+
+	owned := &fieldpath.Set{}
+	found := false
+	for _, entry := range accessor.GetManagedFields() {
+		if entry.Manager != manager || entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+		set := &fieldpath.Set{}
+		if err := set.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			return nil, false, fmt.Errorf("parsing managed fields of %s: %w", manager, err)
+		}
+		owned = owned.Union(set)
+		found = true
+	}
+	if !found {
+		return nil, false, nil
+	}
+
+	var content map[string]interface{}
+	if u, ok := live.(runtime.Unstructured); ok {
+		content = u.UnstructuredContent()
+	} else if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(live); err != nil {
+		return nil, false, err
+	}
+	typedLive, err := typed.DeducedParseableType.FromUnstructured(content)
+	if err != nil {
+		return nil, false, fmt.Errorf("converting live object: %w", err)
+	}
+	extracted, ok := typedLive.ExtractItems(owned.Leaves()).AsValue().Unstructured().(map[string]interface{})
+	if !ok {
+		extracted = map[string]interface{}{}
+	}
+	return extracted, true, nil
+}
+
+// dropUnownedDeletions removes the deletions from a JSON merge patch that
+// target fields not present in owned, so that fields set by the server or
+// by other controllers are left alone when they are absent from the new
+// configuration.
+func dropUnownedDeletions(patch []byte, owned map[string]interface{}) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(patch, &doc); err != nil {
+		return nil, err
+	}
+	dropDeletions(doc, owned)
+	return json.Marshal(doc)
+}
+
+func dropDeletions(patch, owned map[string]interface{}) {
+	for key, value := range patch {
+		ownedValue, isOwned := owned[key]
+		switch v := value.(type) {
+		case nil:
+			if !isOwned {
+				delete(patch, key)
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				continue
+			}
+			ownedMap, _ := ownedValue.(map[string]interface{})
+			dropDeletions(v, ownedMap)
+			if len(v) == 0 {
+				delete(patch, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func liveWidget() *unstructured.Unstructured {
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.io/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name":      "widget",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"size":     "large",
+			"color":    "red",
+			"replicas": int64(3),
+			"defaults": map[string]interface{}{"timeout": "30s"},
+		},
+	}}
+	live.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:   "helm",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:size":{},"f:color":{}}}`)},
+		},
+		{
+			Manager:   "widget-controller",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+		{
+			Manager:     "helm",
+			Operation:   metav1.ManagedFieldsOperationUpdate,
+			Subresource: "status",
+			FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)},
+		},
+	})
+	return live
+}
+
+func TestManagedFieldsOf(t *testing.T) {
+	owned, found, err := managedFieldsOf(liveWidget(), "helm")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, map[string]interface{}{
+		"spec": map[string]interface{}{"size": "large", "color": "red"},
+	}, owned)
+
+	_, found, err = managedFieldsOf(liveWidget(), "kubectl")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, found, err = managedFieldsOf(nil, "helm")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestDropUnownedDeletions(t *testing.T) {
+	owned, _, err := managedFieldsOf(liveWidget(), "helm")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "keeps deletions of owned fields",
+			patch:    `{"spec":{"color":null}}`,
+			expected: `{"spec":{"color":null}}`,
+		},
+		{
+			name:     "drops deletions of fields managed by others",
+			patch:    `{"spec":{"replicas":null,"size":"small"}}`,
+			expected: `{"spec":{"size":"small"}}`,
+		},
+		{
+			name:     "drops deletions of defaulted fields",
+			patch:    `{"spec":{"defaults":{"timeout":null}}}`,
+			expected: `{}`,
+		},
+		{
+			name:     "keeps new empty objects",
+			patch:    `{"spec":{"extra":{}}}`,
+			expected: `{"spec":{"extra":{}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := dropUnownedDeletions([]byte(tt.patch), owned)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(patch))
+		})
+	}
+}
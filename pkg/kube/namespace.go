@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NamespaceOptions configures the namespace created by CreateNamespace.
+type NamespaceOptions struct {
+	// Labels are added to the namespace, such as the
+	// pod-security.kubernetes.io/enforce level or istio-injection.
+	Labels map[string]string
+	// Annotations are added to the namespace.
+	Annotations map[string]string
+}
+
+// CreateNamespace creates a namespace with the labels and annotations of
+// options. The namespace is also labeled with its name under the "name" key,
+// unless options set that label. An existing namespace is left untouched.
+func (c *Client) CreateNamespace(name string, options NamespaceOptions) error {
+	kubeClient, err := c.getKubeClient()
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{"name": name}
+	maps.Copy(labels, options.Labels)
+	ns := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      labels,
+			Annotations: options.Annotations,
+		},
+	}
+	_, err = kubeClient.CoreV1().Namespaces().Create(context.Background(), ns, metav1.CreateOptions{FieldManager: getManagedFieldsManager()})
+	if apierrors.IsAlreadyExists(err) {
+		slog.Debug("namespace already exists", "namespace", name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create namespace %q: %w", name, err)
+	}
+	return nil
+}
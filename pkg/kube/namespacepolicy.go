@@ -0,0 +1,83 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// AllowCrossNamespaceAnno is the annotation name for allowing a resource to
+// be placed outside of the release namespace when the NamespacePolicy would
+// otherwise warn about or reject it. Its value must be "true".
+const AllowCrossNamespaceAnno = "helm.sh/allow-cross-namespace"
+
+// NamespacePolicy decides how namespaced resources whose namespace differs
+// from the release namespace are handled.
+type NamespacePolicy string
+
+const (
+	// AllowNamespacePolicy places resources in any namespace. It is the
+	// default.
+	AllowNamespacePolicy NamespacePolicy = "allow"
+	// WarnNamespacePolicy places resources in any namespace, logging a
+	// warning for the resources outside of the release namespace.
+	WarnNamespacePolicy NamespacePolicy = "warn"
+	// RejectNamespacePolicy rejects the resources outside of the release
+	// namespace.
+	RejectNamespacePolicy NamespacePolicy = "reject"
+)
+
+// CheckNamespacePolicy applies policy to the resources whose namespace differs
+// from namespace. Cluster scoped resources and resources annotated with
+// AllowCrossNamespaceAnno are always allowed. With RejectNamespacePolicy, an
+// error listing the offending resources is returned.
+func CheckNamespacePolicy(resources ResourceList, namespace string, policy NamespacePolicy) error {
+	switch policy {
+	case "", AllowNamespacePolicy:
+		return nil
+	case WarnNamespacePolicy, RejectNamespacePolicy:
+	default:
+		return fmt.Errorf("unknown namespace policy %q", policy)
+	}
+
+	var outside []string
+	for _, info := range resources {
+		if info.Mapping == nil || info.Mapping.Scope.Name() != meta.RESTScopeNameNamespace || info.Namespace == namespace {
+			continue
+		}
+		annotations, err := metadataAccessor.Annotations(info.Object)
+		if err != nil {
+			return err
+		}
+		if annotations[AllowCrossNamespaceAnno] == "true" {
+			continue
+		}
+		if policy == WarnNamespacePolicy {
+			slog.Warn("resource is outside of the release namespace", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, "releaseNamespace", namespace)
+			continue
+		}
+		outside = append(outside, fmt.Sprintf("%s %q in namespace %q", info.Mapping.GroupVersionKind.Kind, info.Name, info.Namespace))
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("resources outside of the release namespace %q are not allowed (annotate them with %s: \"true\" to allow): %s", namespace, AllowCrossNamespaceAnno, strings.Join(outside, ", "))
+	}
+	return nil
+}
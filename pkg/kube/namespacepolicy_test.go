@@ -0,0 +1,65 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func namespacedInfo(kind, namespace, name string, scope meta.RESTScope, annotations map[string]string) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetAnnotations(annotations)
+	return &resource.Info{
+		Name:      name,
+		Namespace: namespace,
+		Object:    obj,
+		Mapping: &meta.RESTMapping{
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: kind},
+			Scope:            scope,
+		},
+	}
+}
+
+func TestCheckNamespacePolicy(t *testing.T) {
+	resources := ResourceList{
+		namespacedInfo("ConfigMap", "tenant", "config", meta.RESTScopeNamespace, nil),
+		namespacedInfo("ClusterRole", "", "reader", meta.RESTScopeRoot, nil),
+		namespacedInfo("RoleBinding", "kube-system", "allowed", meta.RESTScopeNamespace, map[string]string{AllowCrossNamespaceAnno: "true"}),
+	}
+	outside := append(ResourceList{namespacedInfo("Secret", "other", "token", meta.RESTScopeNamespace, nil)}, resources...)
+
+	for _, policy := range []NamespacePolicy{"", AllowNamespacePolicy, WarnNamespacePolicy, RejectNamespacePolicy} {
+		assert.NoError(t, CheckNamespacePolicy(resources, "tenant", policy), "policy %q", policy)
+	}
+	assert.NoError(t, CheckNamespacePolicy(outside, "tenant", AllowNamespacePolicy))
+	assert.NoError(t, CheckNamespacePolicy(outside, "tenant", WarnNamespacePolicy))
+
+	err := CheckNamespacePolicy(outside, "tenant", RejectNamespacePolicy)
+	assert.ErrorContains(t, err, `Secret "token" in namespace "other"`)
+	assert.NotContains(t, err.Error(), "RoleBinding")
+
+	assert.Error(t, CheckNamespacePolicy(resources, "tenant", NamespacePolicy("deny")))
+}
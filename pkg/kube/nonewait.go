@@ -0,0 +1,47 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"context"
+	"time"
+)
+
+// noneWaiter is the Waiter for NoneStrategy. Every method returns immediately
+// without contacting the cluster, including WatchUntilReady, so hooks are not
+// waited on either.
+type noneWaiter struct{}
+
+func (noneWaiter) Wait(_ ResourceList, _ time.Duration) error {
+	return nil
+}
+
+func (noneWaiter) WaitWithJobs(_ ResourceList, _ time.Duration) error {
+	return nil
+}
+
+func (noneWaiter) WaitForDelete(_ ResourceList, _ time.Duration) error {
+	return nil
+}
+
+func (noneWaiter) WatchUntilReady(_ ResourceList, _ time.Duration) error {
+	return nil
+}
+
+func (noneWaiter) WatchUntilReadyWithContext(_ context.Context, _ ResourceList, _ time.Duration) error {
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PatchTypeAnno is the annotation name for overriding the type of patch Helm
+// uses to update the resource. Valid values are "strategic", "merge" and
+// "json". When it is not set, Helm uses a strategic merge patch for built-in
+// types and a JSON merge patch for everything else.
+const PatchTypeAnno = "helm.sh/patch-type"
+
+// patchTypeOverride returns the patch type requested by PatchTypeAnno, or an
+// empty patch type if the annotation is not set.
+func patchTypeOverride(obj runtime.Object) (types.PatchType, error) {
+	annotations, err := metadataAccessor.Annotations(obj)
+	if err != nil {
+		return "", nil
+	}
+	switch value := annotations[PatchTypeAnno]; value {
+	case "":
+		return "", nil
+	case "strategic":
+		return types.StrategicMergePatchType, nil
+	case "merge":
+		return types.MergePatchType, nil
+	case "json":
+		return types.JSONPatchType, nil
+	default:
+		return "", fmt.Errorf("invalid value %q for annotation %s: must be one of strategic, merge or json", value, PatchTypeAnno)
+	}
+}
+
+// createJSONPatch creates a JSON patch (RFC 6902) that transforms original
+// into modified. Fields removed from the configuration are only removed if
+// they are still present in the live object, as removing a missing field
+// fails the whole patch. A nil patch is returned when there are no changes.
+func createJSONPatch(original, modified, current []byte) ([]byte, error) {
+	var originalObj, modifiedObj, currentObj map[string]interface{}
+	if err := json.Unmarshal(original, &originalObj); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(modified, &modifiedObj); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(current, &currentObj); err != nil {
+		return nil, err
+	}
+	ops := diffJSONObjects("", originalObj, modifiedObj, currentObj, nil)
+	if len(ops) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(ops)
+}
+
+func diffJSONObjects(path string, original, modified, current map[string]interface{}, ops []map[string]interface{}) []map[string]interface{} {
+	removed := []string{}
+	for key := range original {
+		if _, ok := modified[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	for _, key := range removed {
+		if _, ok := current[key]; ok {
+			ops = append(ops, map[string]interface{}{"op": "remove", "path": path + "/" + escapeJSONPointer(key)})
+		}
+	}
+
+	keys := make([]string, 0, len(modified))
+	for key := range modified {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := modified[key]
+		if originalValue, ok := original[key]; ok && reflect.DeepEqual(originalValue, value) {
+			continue
+		}
+		keyPath := path + "/" + escapeJSONPointer(key)
+		originalMap, originalIsMap := original[key].(map[string]interface{})
+		modifiedMap, modifiedIsMap := value.(map[string]interface{})
+		currentMap, currentIsMap := current[key].(map[string]interface{})
+		if originalIsMap && modifiedIsMap && currentIsMap {
+			ops = diffJSONObjects(keyPath, originalMap, modifiedMap, currentMap, ops)
+			continue
+		}
+		// "add" replaces the value of an existing member, so it works whether
+		// or not the field exists in the live object.
+		ops = append(ops, map[string]interface{}{"op": "add", "path": keyPath, "value": value})
+	}
+	return ops
+}
+
+func escapeJSONPointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// PatchIgnorePathsAnno is the annotation name for a comma separated list of
+// field paths that Helm leaves untouched when patching the resource.
+//
+// Paths are dot separated. A segment ending in "[]" applies the rest of the
+// path to every element of a list, for example
+// "webhooks[].clientConfig.caBundle".
+const PatchIgnorePathsAnno = "helm.sh/patch-ignore-paths"
+
+// patchIgnorePaths returns the paths to ignore when patching obj, combining
+// the paths configured on the client with those from PatchIgnorePathsAnno.
+func patchIgnorePaths(obj runtime.Object, clientPaths []string) []string {
+	paths := slices.Clone(clientPaths)
+	annotations, err := metadataAccessor.Annotations(obj)
+	if err != nil {
+		return paths
+	}
+	for _, path := range strings.Split(annotations[PatchIgnorePathsAnno], ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// removePaths removes the given field paths from a JSON encoded object.
+func removePaths(data []byte, paths []string) ([]byte, error) {
+	if len(paths) == 0 {
+		return data, nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return data, nil
+	}
+	for _, path := range paths {
+		removePath(obj, strings.Split(path, "."))
+	}
+	return json.Marshal(obj)
+}
+
+func removePath(obj map[string]interface{}, segments []string) {
+	key, isList := strings.CutSuffix(segments[0], "[]")
+	if len(segments) == 1 {
+		delete(obj, key)
+		return
+	}
+	switch value := obj[key].(type) {
+	case map[string]interface{}:
+		if !isList {
+			removePath(value, segments[1:])
+		}
+	case []interface{}:
+		if isList {
+			for _, item := range value {
+				if m, ok := item.(map[string]interface{}); ok {
+					removePath(m, segments[1:])
+				}
+			}
+		}
+	}
+}
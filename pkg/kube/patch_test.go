@@ -0,0 +1,167 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestRemovePaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		paths    []string
+		expected string
+	}{
+		{
+			name:     "no paths leaves data untouched",
+			data:     `{"spec":{"replicas":3}}`,
+			expected: `{"spec":{"replicas":3}}`,
+		},
+		{
+			name:     "nested field is removed",
+			data:     `{"spec":{"replicas":3,"paused":true}}`,
+			paths:    []string{"spec.replicas"},
+			expected: `{"spec":{"paused":true}}`,
+		},
+		{
+			name:     "field in every list element is removed",
+			data:     `{"webhooks":[{"name":"a","clientConfig":{"caBundle":"Zm9v","url":"https://a"}},{"name":"b","clientConfig":{"caBundle":"YmFy"}}]}`,
+			paths:    []string{"webhooks[].clientConfig.caBundle"},
+			expected: `{"webhooks":[{"clientConfig":{"url":"https://a"},"name":"a"},{"clientConfig":{},"name":"b"}]}`,
+		},
+		{
+			name:     "missing paths are ignored",
+			data:     `{"spec":{"replicas":3}}`,
+			paths:    []string{"spec.template.spec", "status", "spec.replicas.value", "webhooks[].name"},
+			expected: `{"spec":{"replicas":3}}`,
+		},
+		{
+			name:     "null object is left untouched",
+			data:     `null`,
+			paths:    []string{"spec.replicas"},
+			expected: `null`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := removePaths([]byte(tt.data), tt.paths)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(got))
+		})
+	}
+}
+
+func TestPatchIgnorePaths(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "web",
+			Annotations: map[string]string{
+				PatchIgnorePathsAnno: "spec.template.metadata.annotations, spec.minReadySeconds,",
+			},
+		},
+	}
+	paths := patchIgnorePaths(deployment, []string{"spec.replicas"})
+	assert.Equal(t, []string{"spec.replicas", "spec.template.metadata.annotations", "spec.minReadySeconds"}, paths)
+
+	assert.Equal(t, []string{"spec.replicas"}, patchIgnorePaths(&appsv1.Deployment{}, []string{"spec.replicas"}))
+}
+
+func TestPatchTypeOverride(t *testing.T) {
+	tests := []struct {
+		value     string
+		expected  types.PatchType
+		expectErr bool
+	}{
+		{value: "", expected: ""},
+		{value: "strategic", expected: types.StrategicMergePatchType},
+		{value: "merge", expected: types.MergePatchType},
+		{value: "json", expected: types.JSONPatchType},
+		{value: "apply", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "web",
+					Annotations: map[string]string{PatchTypeAnno: tt.value},
+				},
+			}
+			patchType, err := patchTypeOverride(deployment)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, patchType)
+		})
+	}
+}
+
+func TestCreateJSONPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		current  string
+		expected string
+	}{
+		{
+			name:     "no changes",
+			original: `{"spec":{"replicas":1}}`,
+			modified: `{"spec":{"replicas":1}}`,
+			current:  `{"spec":{"replicas":3}}`,
+		},
+		{
+			name:     "nested fields are added, replaced and removed",
+			original: `{"metadata":{"labels":{"a":"1","b/c":"2"}},"spec":{"replicas":1}}`,
+			modified: `{"metadata":{"labels":{"a":"2","d":"3"}},"spec":{"replicas":1,"paused":false}}`,
+			current:  `{"metadata":{"labels":{"a":"1","b/c":"2"}},"spec":{"replicas":1}}`,
+			expected: `[{"op":"remove","path":"/metadata/labels/b~1c"},{"op":"add","path":"/metadata/labels/a","value":"2"},{"op":"add","path":"/metadata/labels/d","value":"3"},{"op":"add","path":"/spec/paused","value":false}]`,
+		},
+		{
+			name:     "fields missing from the live object are not removed",
+			original: `{"data":{"a":"1","b":"2"}}`,
+			modified: `{"data":{"a":"1"}}`,
+			current:  `{"data":{"a":"1"}}`,
+		},
+		{
+			name:     "whole object is added when missing from the live object",
+			original: `{"spec":{"template":{"x":"1"}}}`,
+			modified: `{"spec":{"template":{"x":"2"}}}`,
+			current:  `{"spec":{}}`,
+			expected: `[{"op":"add","path":"/spec/template","value":{"x":"2"}}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := createJSONPatch([]byte(tt.original), []byte(tt.modified), []byte(tt.current))
+			require.NoError(t, err)
+			if tt.expected == "" {
+				assert.Nil(t, patch)
+				return
+			}
+			assert.JSONEq(t, tt.expected, string(patch))
+		})
+	}
+}
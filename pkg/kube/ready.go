@@ -31,7 +31,6 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
 
 	deploymentutil "helm.sh/helm/v4/internal/third_party/k8s.io/kubernetes/deployment/util"
 )
@@ -48,6 +47,14 @@ func PausedAsReady(pausedAsReady bool) ReadyCheckerOption {
 	}
 }
 
+// IgnorePVCBinding returns a ReadyCheckerOption that configures a ReadyChecker
+// to consider PersistentVolumeClaims ready regardless of whether they are bound.
+func IgnorePVCBinding(ignorePVCBinding bool) ReadyCheckerOption {
+	return func(c *ReadyChecker) {
+		c.ignorePVCBinding = ignorePVCBinding
+	}
+}
+
 // CheckJobs returns a ReadyCheckerOption that configures a ReadyChecker
 // to consider readiness of Job resources.
 func CheckJobs(checkJobs bool) ReadyCheckerOption {
@@ -56,6 +63,15 @@ func CheckJobs(checkJobs bool) ReadyCheckerOption {
 	}
 }
 
+// ConversionScheme returns a ReadyCheckerOption that configures a ReadyChecker
+// to convert resources to typed objects with the given scheme instead of the
+// scheme with the Kubernetes native types used by AsVersioned.
+func ConversionScheme(s *runtime.Scheme) ReadyCheckerOption {
+	return func(c *ReadyChecker) {
+		c.scheme = s
+	}
+}
+
 // NewReadyChecker creates a new checker. Passed ReadyCheckerOptions can
 // be used to override defaults.
 func NewReadyChecker(cl kubernetes.Interface, opts ...ReadyCheckerOption) ReadyChecker {
@@ -70,9 +86,11 @@ func NewReadyChecker(cl kubernetes.Interface, opts ...ReadyCheckerOption) ReadyC
 
 // ReadyChecker is a type that can check core Kubernetes types for readiness.
 type ReadyChecker struct {
-	client        kubernetes.Interface
-	checkJobs     bool
-	pausedAsReady bool
+	client           kubernetes.Interface
+	checkJobs        bool
+	pausedAsReady    bool
+	ignorePVCBinding bool
+	scheme           *runtime.Scheme
 }
 
 // IsReady checks if v is ready. It supports checking readiness for pods,
@@ -83,7 +101,11 @@ type ReadyChecker struct {
 // IsReady will fetch the latest state of the object from the server prior to
 // performing readiness checks, and it will return any error encountered.
 func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, error) {
-	switch value := AsVersioned(v).(type) {
+	s := c.scheme
+	if s == nil {
+		s = kubernetesNativeScheme()
+	}
+	switch value := convertWithScheme(v.Object, v.Mapping, s).(type) {
 	case *corev1.Pod:
 		pod, err := c.client.CoreV1().Pods(v.Namespace).Get(ctx, v.Name, metav1.GetOptions{})
 		if err != nil || !c.isPodReady(pod) {
@@ -105,6 +127,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 		}
 		// If paused deployment will never be ready
 		if currentDeployment.Spec.Paused {
+			slog.Debug("Deployment is paused", "namespace", currentDeployment.GetNamespace(), "name", currentDeployment.GetName(), "pausedAsReady", c.pausedAsReady)
 			return c.pausedAsReady, nil
 		}
 		// Find RS associated with deployment
@@ -144,7 +167,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return false, err
 		}
 		crd := &apiextv1beta1.CustomResourceDefinition{}
-		if err := scheme.Scheme.Convert(v.Object, crd, nil); err != nil {
+		if err := s.Convert(v.Object, crd, nil); err != nil {
 			return false, err
 		}
 		if !c.crdBetaReady(*crd) {
@@ -155,7 +178,7 @@ func (c *ReadyChecker) IsReady(ctx context.Context, v *resource.Info) (bool, err
 			return false, err
 		}
 		crd := &apiextv1.CustomResourceDefinition{}
-		if err := scheme.Scheme.Convert(v.Object, crd, nil); err != nil {
+		if err := s.Convert(v.Object, crd, nil); err != nil {
 			return false, err
 		}
 		if !c.crdReady(*crd) {
@@ -274,6 +297,10 @@ func (c *ReadyChecker) serviceReady(s *corev1.Service) bool {
 }
 
 func (c *ReadyChecker) volumeReady(v *corev1.PersistentVolumeClaim) bool {
+	if c.ignorePVCBinding || !pvcBindingRequired(v.GetAnnotations()) {
+		slog.Debug("PersistentVolumeClaim binding is not checked", "namespace", v.GetNamespace(), "name", v.GetName())
+		return true
+	}
 	if v.Status.Phase != corev1.ClaimBound {
 		slog.Debug("PersistentVolumeClaim is not bound", "namespace", v.GetNamespace(), "name", v.GetName())
 		return false
@@ -1106,8 +1106,11 @@ func Test_ReadyChecker_jobReady(t *testing.T) {
 
 func Test_ReadyChecker_volumeReady(t *testing.T) {
 	type args struct {
-		v *corev1.PersistentVolumeClaim
+		v                *corev1.PersistentVolumeClaim
+		ignorePVCBinding bool
 	}
+	annotatedPVC := newPersistentVolumeClaim("foo", corev1.ClaimPending)
+	annotatedPVC.Annotations = map[string]string{PVCBoundAnno: "false"}
 	tests := []struct {
 		name string
 		args args
@@ -1127,10 +1130,25 @@ func Test_ReadyChecker_volumeReady(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "pvc binding is ignored",
+			args: args{
+				v:                newPersistentVolumeClaim("foo", corev1.ClaimPending),
+				ignorePVCBinding: true,
+			},
+			want: true,
+		},
+		{
+			name: "pvc opts out of the bound check",
+			args: args{
+				v: annotatedPVC,
+			},
+			want: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := NewReadyChecker(fake.NewClientset())
+			c := NewReadyChecker(fake.NewClientset(), IgnorePVCBinding(tt.args.ignorePVCBinding))
 			if got := c.volumeReady(tt.args.v); got != tt.want {
 				t.Errorf("volumeReady() = %v, want %v", got, tt.want)
 			}
@@ -0,0 +1,219 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// relatedFinder finds resources of one type that are related to a workload.
+type relatedFinder struct {
+	// resourceType is the type of the related resources, such as "services".
+	resourceType string
+	// selector restricts the resources listed for the workload.
+	selector func(w *workload) string
+	// matches reports whether a listed resource is related to the workload.
+	matches func(w *workload, obj *unstructured.Unstructured) bool
+}
+
+// workload is a resource managing pods.
+type workload struct {
+	kind, name, namespace string
+	selector              map[string]string
+	podLabels             labels.Set
+}
+
+var relatedFinders = []relatedFinder{
+	{
+		resourceType: "services",
+		matches: func(w *workload, obj *unstructured.Unstructured) bool {
+			selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector")
+			return len(selector) > 0 && labels.SelectorFromSet(selector).Matches(w.podLabels)
+		},
+	},
+	{
+		resourceType: "horizontalpodautoscalers",
+		matches: func(w *workload, obj *unstructured.Unstructured) bool {
+			kind, _, _ := unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "kind")
+			name, _, _ := unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "name")
+			return kind == w.kind && name == w.name
+		},
+	},
+	{
+		resourceType: "poddisruptionbudgets",
+		matches: func(w *workload, obj *unstructured.Unstructured) bool {
+			raw, ok, _ := unstructured.NestedMap(obj.Object, "spec", "selector")
+			if !ok {
+				return false
+			}
+			var ls metav1.LabelSelector
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &ls); err != nil {
+				return false
+			}
+			selector, err := metav1.LabelSelectorAsSelector(&ls)
+			return err == nil && !selector.Empty() && selector.Matches(w.podLabels)
+		},
+	},
+	{
+		resourceType: "replicasets",
+		selector: func(w *workload) string {
+			return labels.Set(w.selector).AsSelector().String()
+		},
+		matches: func(w *workload, obj *unstructured.Unstructured) bool {
+			for _, owner := range obj.GetOwnerReferences() {
+				if owner.Kind == w.kind && owner.Name == w.name {
+					return true
+				}
+			}
+			return false
+		},
+	},
+}
+
+// getRelatedResources adds the Services, EndpointSlices,
+// HorizontalPodAutoscalers, PodDisruptionBudgets and, for Deployments, the
+// owned ReplicaSets related to a workload to objs. Resources already in seen
+// are skipped, so that resources related to several workloads are only
+// listed once.
+func (c *Client) getRelatedResources(info *resource.Info, objs map[string][]runtime.Object, table bool, seen map[string]bool) map[string][]runtime.Object {
+	w, ok := workloadFromInfo(info)
+	if !ok {
+		return objs
+	}
+
+	for _, finder := range relatedFinders {
+		// Only Deployments own the ReplicaSets matching their selector.
+		if finder.resourceType == "replicasets" && w.kind != "Deployment" {
+			continue
+		}
+		selector := ""
+		if finder.selector != nil {
+			selector = finder.selector(w)
+		}
+		infos, err := c.listRelated(w.namespace, finder.resourceType, selector)
+		if err != nil {
+			slog.Warn("unable to list related resources", "type", finder.resourceType, "namespace", w.namespace, slog.Any("error", err))
+			continue
+		}
+		var matches []*resource.Info
+		for _, related := range infos {
+			obj, ok := related.Object.(*unstructured.Unstructured)
+			if ok && finder.matches(w, obj) {
+				matches = append(matches, related)
+			}
+		}
+		objs = c.addRelated(w.namespace, finder.resourceType, matches, objs, table, seen)
+
+		// EndpointSlices are related through the Services they belong to.
+		if finder.resourceType == "services" {
+			for _, service := range matches {
+				endpointSlices, err := c.listRelated(w.namespace, "endpointslices", "kubernetes.io/service-name="+service.Name)
+				if err != nil {
+					slog.Warn("unable to list related resources", "type", "endpointslices", "namespace", w.namespace, slog.Any("error", err))
+					continue
+				}
+				objs = c.addRelated(w.namespace, "endpointslices", endpointSlices, objs, table, seen)
+			}
+		}
+	}
+	return objs
+}
+
+func workloadFromInfo(info *resource.Info) (*workload, bool) {
+	obj, ok := info.Object.(*unstructured.Unstructured)
+	if !ok {
+		return nil, false
+	}
+	selector, ok, _ := getSelectorFromObject(obj)
+	if !ok || len(selector) == 0 {
+		return nil, false
+	}
+	podLabels, ok, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+	if !ok || len(podLabels) == 0 {
+		podLabels = selector
+	}
+	return &workload{
+		kind:      obj.GetKind(),
+		name:      info.Name,
+		namespace: info.Namespace,
+		selector:  selector,
+		podLabels: podLabels,
+	}, true
+}
+
+func (c *Client) listRelated(namespace, resourceType, selector string) ([]*resource.Info, error) {
+	builder := c.Factory.NewBuilder().
+		Unstructured().
+		ContinueOnError().
+		NamespaceParam(namespace).
+		DefaultNamespace().
+		ResourceTypes(resourceType).
+		Flatten()
+	if selector != "" {
+		builder = builder.LabelSelectorParam(selector)
+	} else {
+		builder = builder.SelectAllParam(true)
+	}
+	return builder.Do().Infos()
+}
+
+// addRelated adds the related resources not in seen to objs. When table is
+// set, the resources are fetched again as tables.
+func (c *Client) addRelated(namespace, resourceType string, related []*resource.Info, objs map[string][]runtime.Object, table bool, seen map[string]bool) map[string][]runtime.Object {
+	var names []string
+	for _, info := range related {
+		gvk := info.Mapping.GroupVersionKind
+		key := gvk.Kind + "/" + info.Namespace + "/" + info.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, info.Name)
+		if !table {
+			vk := gvk.Version + "/" + gvk.Kind + "(related)"
+			objs[vk] = append(objs[vk], info.Object)
+		}
+	}
+	if !table || len(names) == 0 {
+		return objs
+	}
+
+	infos, err := c.Factory.NewBuilder().
+		Unstructured().
+		ContinueOnError().
+		NamespaceParam(namespace).
+		DefaultNamespace().
+		ResourceNames(resourceType, names...).
+		TransformRequests(transformRequests).
+		Do().Infos()
+	if err != nil {
+		slog.Warn("unable to get related resources", "type", resourceType, "namespace", namespace, slog.Any("error", err))
+		return objs
+	}
+	for _, info := range infos {
+		gvk := info.Mapping.GroupVersionKind
+		vk := gvk.Version + "/" + gvk.Kind + "(related)"
+		objs[vk] = append(objs[vk], info.Object)
+	}
+	return objs
+}
@@ -18,6 +18,13 @@ package kube // import "helm.sh/helm/v4/pkg/kube"
 
 import "k8s.io/cli-runtime/pkg/resource"
 
+// ResourceSorter determines the order in which resources are created or
+// deleted.
+type ResourceSorter interface {
+	// Less reports whether resource a must be handled before resource b.
+	Less(a, b *resource.Info) bool
+}
+
 // ResourceList provides convenience methods for comparing collections of Infos.
 type ResourceList []*resource.Info
 
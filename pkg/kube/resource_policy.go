@@ -25,3 +25,15 @@ const ResourcePolicyAnno = "helm.sh/resource-policy"
 //
 //	during an uninstallRelease action.
 const KeepPolicy = "keep"
+
+// PVCBoundAnno is the annotation name used to opt a PersistentVolumeClaim out
+// of the bound check performed while waiting. Setting it to "false" makes the
+// claim ready regardless of its phase, which is useful for storage classes
+// with a volumeBindingMode of WaitForFirstConsumer.
+const PVCBoundAnno = "helm.sh/wait-pvc-bound"
+
+// pvcBindingRequired reports whether the annotations of a PersistentVolumeClaim
+// allow waiting for it to be bound.
+func pvcBindingRequired(annotations map[string]string) bool {
+	return annotations[PVCBoundAnno] != "false"
+}
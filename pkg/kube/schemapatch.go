@@ -0,0 +1,176 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/openapi3"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const (
+	groupVersionKindExtensionKey = "x-kubernetes-group-version-kind"
+	listTypeExtensionKey         = "x-kubernetes-list-type"
+	listMapKeysExtensionKey      = "x-kubernetes-list-map-keys"
+	patchMergeKeyExtensionKey    = "x-kubernetes-patch-merge-key"
+	patchStrategyExtensionKey    = "x-kubernetes-patch-strategy"
+)
+
+// schemaPatchMeta looks up the patch metadata of a resource from its
+// OpenAPI v3 schema. Besides the patch extensions of built-in types, it
+// understands the list types that CustomResourceDefinitions declare, so that
+// lists of type "map" are merged by their key and lists of type "set" are
+// merged by value instead of being replaced as a whole.
+type schemaPatchMeta struct {
+	schema  *spec.Schema
+	schemas map[string]*spec.Schema
+}
+
+var _ strategicpatch.LookupPatchMeta = schemaPatchMeta{}
+
+// crdPatchMeta returns the patch metadata of the given kind from the OpenAPI
+// v3 document of its group version, or nil if the kind is not described.
+func crdPatchMeta(root openapi3.Root, gvk schema.GroupVersionKind) (strategicpatch.LookupPatchMeta, error) {
+	gvSpec, err := root.GVSpec(gvk.GroupVersion())
+	if err != nil {
+		return nil, err
+	}
+	return patchMetaForKind(gvSpec, gvk), nil
+}
+
+func patchMetaForKind(gvSpec *spec3.OpenAPI, gvk schema.GroupVersionKind) strategicpatch.LookupPatchMeta {
+	if gvSpec == nil || gvSpec.Components == nil {
+		return nil
+	}
+	for _, s := range gvSpec.Components.Schemas {
+		var gvks []map[string]string
+		if err := s.Extensions.GetObject(groupVersionKindExtensionKey, &gvks); err != nil {
+			continue
+		}
+		for _, g := range gvks {
+			if g["group"] == gvk.Group && g["version"] == gvk.Version && g["kind"] == gvk.Kind {
+				return schemaPatchMeta{schema: s, schemas: gvSpec.Components.Schemas}
+			}
+		}
+	}
+	return nil
+}
+
+// createSchemaAwareMergePatch creates a JSON merge patch from a three-way
+// strategic merge of the configurations using the given patch metadata.
+//
+// Custom resources do not accept strategic merge patches, so the strategic
+// merge is applied to the live object locally and only the difference
+// between the live and the merged object is sent. Lists in that difference
+// are still replaced as a whole, but they already contain the items that
+// were added to the live object outside of Helm.
+func createSchemaAwareMergePatch(original, modified, current []byte, meta strategicpatch.LookupPatchMeta) ([]byte, error) {
+	patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, meta, true)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := strategicpatch.StrategicMergePatchUsingLookupPatchMeta(current, patch, meta)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.CreateMergePatch(current, merged)
+}
+
+func (s schemaPatchMeta) LookupPatchMetadataForStruct(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	field := s.field(key)
+	return field, field.patchMeta(), nil
+}
+
+func (s schemaPatchMeta) LookupPatchMetadataForSlice(key string) (strategicpatch.LookupPatchMeta, strategicpatch.PatchMeta, error) {
+	field := s.field(key)
+	meta := field.patchMeta()
+	if field.schema != nil && field.schema.Items != nil {
+		field = schemaPatchMeta{schema: field.schema.Items.Schema, schemas: s.schemas}.resolve()
+	}
+	return field, meta, nil
+}
+
+func (s schemaPatchMeta) Name() string {
+	if s.schema != nil && len(s.schema.Type) > 0 {
+		return strings.Join(s.schema.Type, "")
+	}
+	return "Struct"
+}
+
+// field returns the patch metadata of the named field. Fields that are not
+// described by the schema, such as those of objects preserving unknown
+// fields, have no patch metadata and are merged like a JSON merge patch.
+func (s schemaPatchMeta) field(key string) schemaPatchMeta {
+	if s.schema == nil {
+		return schemaPatchMeta{schemas: s.schemas}
+	}
+	if prop, ok := s.schema.Properties[key]; ok {
+		return schemaPatchMeta{schema: &prop, schemas: s.schemas}.resolve()
+	}
+	if s.schema.AdditionalProperties != nil && s.schema.AdditionalProperties.Schema != nil {
+		return schemaPatchMeta{schema: s.schema.AdditionalProperties.Schema, schemas: s.schemas}.resolve()
+	}
+	return schemaPatchMeta{schemas: s.schemas}
+}
+
+func (s schemaPatchMeta) resolve() schemaPatchMeta {
+	if s.schema == nil {
+		return s
+	}
+	if len(s.schema.AllOf) > 0 {
+		s.schema = &s.schema.AllOf[0]
+	}
+	if ref := s.schema.Ref.String(); ref != "" {
+		s.schema = s.schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func (s schemaPatchMeta) patchMeta() strategicpatch.PatchMeta {
+	meta := strategicpatch.PatchMeta{}
+	if s.schema == nil {
+		return meta
+	}
+	ext := s.schema.Extensions
+	if key, ok := ext.GetString(patchMergeKeyExtensionKey); ok {
+		meta.SetPatchMergeKey(key)
+	}
+	if strategy, ok := ext.GetString(patchStrategyExtensionKey); ok {
+		meta.SetPatchStrategies(strings.Split(strategy, ","))
+		return meta
+	}
+
+	listType, _ := ext.GetString(listTypeExtensionKey)
+	switch listType {
+	case "set":
+		meta.SetPatchStrategies([]string{"merge"})
+	case "map":
+		// Strategic merge patches only support a single merge key, so lists
+		// keyed by several fields keep being replaced as a whole.
+		keys, _ := ext.GetStringSlice(listMapKeysExtensionKey)
+		if len(keys) == 1 {
+			meta.SetPatchMergeKey(keys[0])
+			meta.SetPatchStrategies([]string{"merge"})
+		}
+	}
+	return meta
+}
@@ -0,0 +1,150 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/spec3"
+)
+
+const widgetOpenAPI = `{
+  "openapi": "3.0.0",
+  "info": {"title": "Kubernetes", "version": "unversioned"},
+  "paths": {},
+  "components": {
+    "schemas": {
+      "io.example.v1.Widget": {
+        "type": "object",
+        "x-kubernetes-group-version-kind": [{"group": "example.io", "version": "v1", "kind": "Widget"}],
+        "properties": {
+          "apiVersion": {"type": "string"},
+          "kind": {"type": "string"},
+          "metadata": {"type": "object", "x-kubernetes-preserve-unknown-fields": true},
+          "spec": {
+            "type": "object",
+            "properties": {
+              "ports": {
+                "type": "array",
+                "x-kubernetes-list-type": "map",
+                "x-kubernetes-list-map-keys": ["name"],
+                "items": {
+                  "type": "object",
+                  "properties": {"name": {"type": "string"}, "port": {"type": "integer"}}
+                }
+              },
+              "tags": {
+                "type": "array",
+                "x-kubernetes-list-type": "set",
+                "items": {"type": "string"}
+              },
+              "args": {
+                "type": "array",
+                "items": {"type": "string"}
+              },
+              "extra": {"type": "object", "x-kubernetes-preserve-unknown-fields": true}
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func widgetSpec(t *testing.T) *spec3.OpenAPI {
+	t.Helper()
+	gvSpec := &spec3.OpenAPI{}
+	require.NoError(t, json.Unmarshal([]byte(widgetOpenAPI), gvSpec))
+	return gvSpec
+}
+
+func TestPatchMetaForKind(t *testing.T) {
+	gvSpec := widgetSpec(t)
+
+	assert.NotNil(t, patchMetaForKind(gvSpec, schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Widget"}))
+	assert.Nil(t, patchMetaForKind(gvSpec, schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Gadget"}))
+	assert.Nil(t, patchMetaForKind(gvSpec, schema.GroupVersionKind{Group: "example.io", Version: "v2", Kind: "Widget"}))
+	assert.Nil(t, patchMetaForKind(&spec3.OpenAPI{}, schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Widget"}))
+}
+
+func TestCreateSchemaAwareMergePatch(t *testing.T) {
+	meta := patchMetaForKind(widgetSpec(t), schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Widget"})
+	require.NotNil(t, meta)
+
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		current  string
+		expected string
+	}{
+		{
+			name:     "list map keeps items added outside of helm",
+			original: `{"spec":{"ports":[{"name":"http","port":80}]}}`,
+			modified: `{"spec":{"ports":[{"name":"http","port":8080}]}}`,
+			current:  `{"spec":{"ports":[{"name":"http","port":80},{"name":"metrics","port":9090}]}}`,
+			expected: `{"spec":{"ports":[{"name":"http","port":8080},{"name":"metrics","port":9090}]}}`,
+		},
+		{
+			name:     "list map removes items removed from the chart",
+			original: `{"spec":{"ports":[{"name":"http","port":80},{"name":"https","port":443}]}}`,
+			modified: `{"spec":{"ports":[{"name":"http","port":80}]}}`,
+			current:  `{"spec":{"ports":[{"name":"http","port":80},{"name":"https","port":443},{"name":"metrics","port":9090}]}}`,
+			expected: `{"spec":{"ports":[{"name":"http","port":80},{"name":"metrics","port":9090}]}}`,
+		},
+		{
+			name:     "list set merges values",
+			original: `{"spec":{"tags":["a"]}}`,
+			modified: `{"spec":{"tags":["a","b"]}}`,
+			current:  `{"spec":{"tags":["a","c"]}}`,
+			expected: `{"spec":{"tags":["a","b","c"]}}`,
+		},
+		{
+			name:     "atomic list is replaced",
+			original: `{"spec":{"args":["a"]}}`,
+			modified: `{"spec":{"args":["b"]}}`,
+			current:  `{"spec":{"args":["a","c"]}}`,
+			expected: `{"spec":{"args":["b"]}}`,
+		},
+		{
+			name:     "fields without schema are merged",
+			original: `{"spec":{"extra":{"a":"1"}}}`,
+			modified: `{"spec":{"extra":{"a":"2","b":"3"}}}`,
+			current:  `{"spec":{"extra":{"a":"1","c":"4"}}}`,
+			expected: `{"spec":{"extra":{"a":"2","b":"3"}}}`,
+		},
+		{
+			name:     "no changes",
+			original: `{"spec":{"ports":[{"name":"http","port":80}]}}`,
+			modified: `{"spec":{"ports":[{"name":"http","port":80}]}}`,
+			current:  `{"spec":{"ports":[{"name":"http","port":80},{"name":"metrics","port":9090}]}}`,
+			expected: `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := createSchemaAwareMergePatch([]byte(tt.original), []byte(tt.modified), []byte(tt.current), meta)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(patch))
+		})
+	}
+}
@@ -32,8 +32,10 @@ import (
 	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
 	"github.com/fluxcd/cli-utils/pkg/kstatus/watcher"
 	"github.com/fluxcd/cli-utils/pkg/object"
-	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/dynamic"
 
@@ -43,6 +45,9 @@ import (
 type statusWaiter struct {
 	client     dynamic.Interface
 	restMapper meta.RESTMapper
+	// healthCheckers take precedence over the built-in readiness rules for
+	// the kinds they support.
+	healthCheckers []HealthChecker
 }
 
 func alwaysReady(_ *unstructured.Unstructured) (*status.Result, error) {
@@ -53,7 +58,11 @@ func alwaysReady(_ *unstructured.Unstructured) (*status.Result, error) {
 }
 
 func (w *statusWaiter) WatchUntilReady(resourceList ResourceList, timeout time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	return w.WatchUntilReadyWithContext(context.Background(), resourceList, timeout)
+}
+
+func (w *statusWaiter) WatchUntilReadyWithContext(ctx context.Context, resourceList ResourceList, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	slog.Debug("waiting for resources", "count", len(resourceList), "timeout", timeout)
 	sw := watcher.NewDefaultStatusWatcher(w.client, w.restMapper)
@@ -78,6 +87,7 @@ func (w *statusWaiter) Wait(resourceList ResourceList, timeout time.Duration) er
 	defer cancel()
 	slog.Debug("waiting for resources", "count", len(resourceList), "timeout", timeout)
 	sw := watcher.NewDefaultStatusWatcher(w.client, w.restMapper)
+	sw.StatusReader = w.statusReader()
 	return w.wait(ctx, resourceList, sw)
 }
 
@@ -87,30 +97,52 @@ func (w *statusWaiter) WaitWithJobs(resourceList ResourceList, timeout time.Dura
 	slog.Debug("waiting for resources", "count", len(resourceList), "timeout", timeout)
 	sw := watcher.NewDefaultStatusWatcher(w.client, w.restMapper)
 	newCustomJobStatusReader := helmStatusReaders.NewCustomJobStatusReader(w.restMapper)
-	customSR := statusreaders.NewStatusReader(w.restMapper, newCustomJobStatusReader)
-	sw.StatusReader = customSR
+	sw.StatusReader = w.statusReader(newCustomJobStatusReader)
 	return w.wait(ctx, resourceList, sw)
 }
 
+// statusReader returns the status reader used when waiting for resources to be
+// ready. Health checkers are consulted first, followed by the given readers,
+// Helm's readers for kinds kstatus does not handle, and finally the kstatus
+// defaults.
+func (w *statusWaiter) statusReader(readers ...engine.StatusReader) engine.StatusReader {
+	customReaders := newHealthCheckStatusReaders(w.restMapper, w.healthCheckers)
+	customReaders = append(customReaders, readers...)
+	customReaders = append(customReaders,
+		helmStatusReaders.NewCustomDeploymentStatusReader(w.restMapper),
+		helmStatusReaders.NewCustomIngressStatusReader(w.restMapper),
+		helmStatusReaders.NewCustomAPIServiceStatusReader(w.restMapper),
+	)
+	return statusreaders.NewStatusReader(w.restMapper, customReaders...)
+}
+
 func (w *statusWaiter) WaitForDelete(resourceList ResourceList, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
 	defer cancel()
 	slog.Debug("waiting for resources to be deleted", "count", len(resourceList), "timeout", timeout)
 	sw := watcher.NewDefaultStatusWatcher(w.client, w.restMapper)
-	return w.waitForDelete(ctx, resourceList, sw)
+	resources, err := objMetadataFromResourceList(resourceList)
+	if err != nil {
+		return err
+	}
+	return w.waitForDelete(ctx, resources, sw)
 }
 
-func (w *statusWaiter) waitForDelete(ctx context.Context, resourceList ResourceList, sw watcher.StatusWatcher) error {
-	cancelCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+func objMetadataFromResourceList(resourceList ResourceList) ([]object.ObjMetadata, error) {
 	resources := []object.ObjMetadata{}
 	for _, resource := range resourceList {
 		obj, err := object.RuntimeToObjMeta(resource.Object)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		resources = append(resources, obj)
 	}
+	return resources, nil
+}
+
+func (w *statusWaiter) waitForDelete(ctx context.Context, resources []object.ObjMetadata, sw watcher.StatusWatcher) error {
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	eventCh := sw.Watch(cancelCtx, resources, watcher.Options{})
 	statusCollector := collector.NewResourceStatusCollector(resources)
 	done := statusCollector.ListenWithObserver(eventCh, statusObserver(cancel, status.NotFoundStatus))
@@ -122,16 +154,24 @@ func (w *statusWaiter) waitForDelete(ctx context.Context, resourceList ResourceL
 
 	// Only check parent context error, otherwise we would error when desired status is achieved.
 	if ctx.Err() != nil {
-		errs := []error{}
+		timeoutErr := &DeletionTimeoutError{Err: ctx.Err()}
 		for _, id := range resources {
 			rs := statusCollector.ResourceStatuses[id]
 			if rs.Status == status.NotFoundStatus {
 				continue
 			}
-			errs = append(errs, fmt.Errorf("resource still exists, name: %s, kind: %s, status: %s", rs.Identifier.Name, rs.Identifier.GroupKind.Kind, rs.Status))
+			stuck := StuckResource{
+				Kind:      rs.Identifier.GroupKind.Kind,
+				Namespace: rs.Identifier.Namespace,
+				Name:      rs.Identifier.Name,
+				Status:    rs.Status.String(),
+			}
+			if rs.Resource != nil {
+				stuck.Finalizers = rs.Resource.GetFinalizers()
+			}
+			timeoutErr.Resources = append(timeoutErr.Resources, stuck)
 		}
-		errs = append(errs, ctx.Err())
-		return errors.Join(errs...)
+		return timeoutErr
 	}
 	return nil
 }
@@ -141,12 +181,6 @@ func (w *statusWaiter) wait(ctx context.Context, resourceList ResourceList, sw w
 	defer cancel()
 	resources := []object.ObjMetadata{}
 	for _, resource := range resourceList {
-		switch value := AsVersioned(resource).(type) {
-		case *appsv1.Deployment:
-			if value.Spec.Paused {
-				continue
-			}
-		}
 		obj, err := object.RuntimeToObjMeta(resource.Object)
 		if err != nil {
 			return err
@@ -222,6 +256,10 @@ func (w *hookOnlyWaiter) WatchUntilReady(resourceList ResourceList, timeout time
 	return w.sw.WatchUntilReady(resourceList, timeout)
 }
 
+func (w *hookOnlyWaiter) WatchUntilReadyWithContext(ctx context.Context, resourceList ResourceList, timeout time.Duration) error {
+	return w.sw.WatchUntilReadyWithContext(ctx, resourceList, timeout)
+}
+
 func (w *hookOnlyWaiter) Wait(_ ResourceList, _ time.Duration) error {
 	return nil
 }
@@ -233,3 +271,50 @@ func (w *hookOnlyWaiter) WaitWithJobs(_ ResourceList, _ time.Duration) error {
 func (w *hookOnlyWaiter) WaitForDelete(_ ResourceList, _ time.Duration) error {
 	return nil
 }
+
+// WaitForHookDeletion waits for the deleted hook resources, and any pods
+// created by deleted Jobs, to be gone from the cluster, which WaitForDelete
+// does not wait for.
+func (w *hookOnlyWaiter) WaitForHookDeletion(resourceList ResourceList, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+	defer cancel()
+	slog.Debug("waiting for hook resources to be deleted", "count", len(resourceList), "timeout", timeout)
+	resources, err := objMetadataFromResourceList(resourceList)
+	if err != nil {
+		return err
+	}
+	// Pods created by a Job are garbage collected after the Job itself is
+	// removed. Wait for them as well so a hook recreated under the same name
+	// does not race with the previous run.
+	pods, err := w.jobPods(ctx, resourceList)
+	if err != nil {
+		return err
+	}
+	sw := watcher.NewDefaultStatusWatcher(w.sw.client, w.sw.restMapper)
+	return w.sw.waitForDelete(ctx, append(resources, pods...), sw)
+}
+
+// jobPods returns the pods that belong to the Jobs in the resource list.
+func (w *hookOnlyWaiter) jobPods(ctx context.Context, resourceList ResourceList) ([]object.ObjMetadata, error) {
+	pods := []object.ObjMetadata{}
+	podGVR := corev1.SchemeGroupVersion.WithResource("pods")
+	for _, resource := range resourceList {
+		if resource.Object.GetObjectKind().GroupVersionKind().GroupKind() != batchv1.SchemeGroupVersion.WithKind("Job").GroupKind() {
+			continue
+		}
+		list, err := w.sw.client.Resource(podGVR).Namespace(resource.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("job-name=%s", resource.Name),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods for job %s: %w", resource.Name, err)
+		}
+		for _, pod := range list.Items {
+			pods = append(pods, object.ObjMetadata{
+				GroupKind: corev1.SchemeGroupVersion.WithKind("Pod").GroupKind(),
+				Namespace: pod.GetNamespace(),
+				Name:      pod.GetName(),
+			})
+		}
+	}
+	return pods, nil
+}
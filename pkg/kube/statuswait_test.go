@@ -17,6 +17,7 @@ limitations under the License.
 package kube // import "helm.sh/helm/v3/pkg/kube"
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -265,6 +266,48 @@ func TestStatusWaitForDeleteNonExistentObject(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+var podTerminatingManifest = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: terminating-pod
+  namespace: ns
+  deletionTimestamp: "2024-01-01T00:00:00Z"
+  finalizers:
+  - example.com/protect
+status:
+  phase: Running
+`
+
+func TestStatusWaitForDeleteStuckFinalizers(t *testing.T) {
+	t.Parallel()
+	c := newTestClient(t)
+	fakeClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme)
+	fakeMapper := testutil.NewFakeRESTMapper(
+		v1.SchemeGroupVersion.WithKind("Pod"),
+	)
+	statusWaiter := statusWaiter{
+		restMapper: fakeMapper,
+		client:     fakeClient,
+	}
+	objs := getRuntimeObjFromManifests(t, []string{podTerminatingManifest})
+	u := objs[0].(*unstructured.Unstructured)
+	require.NoError(t, fakeClient.Tracker().Create(getGVR(t, fakeMapper, u), u, u.GetNamespace()))
+
+	err := statusWaiter.WaitForDelete(getResourceListFromRuntimeObjs(t, c, objs), time.Second)
+	var timeoutErr *DeletionTimeoutError
+	require.ErrorAs(t, err, &timeoutErr)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []StuckResource{{
+		Kind:       "Pod",
+		Namespace:  "ns",
+		Name:       "terminating-pod",
+		Status:     "Terminating",
+		Finalizers: []string{"example.com/protect"},
+	}}, timeoutErr.Resources)
+	assert.EqualError(t, err, "resource still exists, name: terminating-pod, kind: Pod, status: Terminating, finalizers: example.com/protect\ncontext deadline exceeded")
+}
+
 func TestStatusWait(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -445,3 +488,88 @@ func TestWatchForReady(t *testing.T) {
 		})
 	}
 }
+
+var jobPodManifest = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test-abcde
+  namespace: qual
+  labels:
+    job-name: test
+status:
+  phase: Succeeded
+`
+
+func TestHookOnlyWaitForDelete(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		hookDeletion bool
+		deletePod    bool
+		expectErrs   []error
+	}{
+		{
+			name: "does not wait for deletions",
+		},
+		{
+			name:         "waits for the job and its pods to be deleted",
+			hookDeletion: true,
+			deletePod:    true,
+		},
+		{
+			name:         "error when the job pods are not deleted",
+			hookDeletion: true,
+			expectErrs:   []error{errors.New("resource still exists, name: test-abcde, kind: Pod, status: Current"), errors.New("context deadline exceeded")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := newTestClient(t)
+			fakeClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme)
+			fakeMapper := testutil.NewFakeRESTMapper(
+				v1.SchemeGroupVersion.WithKind("Pod"),
+				batchv1.SchemeGroupVersion.WithKind("Job"),
+			)
+			waiter := hookOnlyWaiter{
+				sw: &statusWaiter{
+					restMapper: fakeMapper,
+					client:     fakeClient,
+				},
+			}
+			objs := getRuntimeObjFromManifests(t, []string{jobCompleteManifest, jobPodManifest})
+			for _, obj := range objs {
+				u := obj.(*unstructured.Unstructured)
+				gvr := getGVR(t, fakeMapper, u)
+				err := fakeClient.Tracker().Create(gvr, u, u.GetNamespace())
+				assert.NoError(t, err)
+			}
+			toDelete := objs[:1]
+			if tt.deletePod {
+				toDelete = objs
+			}
+			for _, obj := range toDelete {
+				u := obj.(*unstructured.Unstructured)
+				gvr := getGVR(t, fakeMapper, u)
+				go func() {
+					time.Sleep(time.Millisecond * 500)
+					err := fakeClient.Tracker().Delete(gvr, u.GetNamespace(), u.GetName())
+					assert.NoError(t, err)
+				}()
+			}
+			// Only the job is part of the hook manifest, its pods are discovered.
+			resourceList := getResourceListFromRuntimeObjs(t, c, objs[:1])
+			wait := waiter.WaitForDelete
+			if tt.hookDeletion {
+				wait = waiter.WaitForHookDeletion
+			}
+			err := wait(resourceList, time.Second)
+			if tt.expectErrs != nil {
+				assert.EqualError(t, err, errors.Join(tt.expectErrs...).Error())
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
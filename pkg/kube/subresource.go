@@ -0,0 +1,77 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+const (
+	// StatusSubresource is the name of the status subresource.
+	StatusSubresource = "status"
+	// ScaleSubresource is the name of the scale subresource.
+	ScaleSubresource = "scale"
+)
+
+// PatchSubresource applies a patch to a subresource, such as
+// StatusSubresource or ScaleSubresource, of the live object of a resource.
+// Only the fields exposed by the subresource are changed, so the status of
+// an object can be set without touching its spec and the other way around.
+// When the subresource returns the whole object, as the status subresource
+// does, the resource is refreshed with it.
+func (c *Client) PatchSubresource(info *resource.Info, subresource string, patchType types.PatchType, patch []byte) error {
+	slog.Debug("patching subresource", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, "subresource", subresource)
+	helper := resource.NewHelper(info.Client, info.Mapping).
+		WithFieldManager(getManagedFieldsManager()).
+		WithSubresource(subresource)
+	obj, err := helper.Patch(info.Namespace, info.Name, patchType, patch, nil)
+	if err != nil {
+		return errors.Wrapf(err, "cannot patch %s of %q with kind %s", subresource, info.Name, info.Mapping.GroupVersionKind.Kind)
+	}
+	if obj.GetObjectKind().GroupVersionKind() == info.Mapping.GroupVersionKind {
+		return info.Refresh(obj, true)
+	}
+	return nil
+}
+
+// PatchStatus merges status into the status of the live object of a
+// resource through its status subresource, such as to seed the status of a
+// custom resource.
+func (c *Client) PatchStatus(info *resource.Info, status interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+	return c.PatchSubresource(info, StatusSubresource, types.MergePatchType, patch)
+}
+
+// Scale sets the number of replicas of the live object of a resource through
+// its scale subresource, leaving the rest of its spec untouched.
+func (c *Client) Scale(info *resource.Info, replicas int32) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": replicas},
+	})
+	if err != nil {
+		return err
+	}
+	return c.PatchSubresource(info, ScaleSubresource, types.MergePatchType, patch)
+}
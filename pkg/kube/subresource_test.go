@@ -0,0 +1,82 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestPatchSubresources(t *testing.T) {
+	otter := newPod("otter")
+	patched := newPod("otter")
+	patched.Status.Phase = v1.PodRunning
+
+	patches := map[string]string{}
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			p, m := req.URL.Path, req.Method
+			if m == "PATCH" {
+				assert.Equal(t, string(types.MergePatchType), req.Header.Get("Content-Type"))
+				body, _ := io.ReadAll(req.Body)
+				patches[p] = string(body)
+			}
+			switch {
+			case p == "/namespaces/default/pods/otter/status" && m == "PATCH":
+				return newResponse(200, &patched)
+			case p == "/namespaces/default/pods/otter/scale" && m == "PATCH":
+				return newResponse(200, &autoscalingv1.Scale{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "autoscaling/v1", Kind: "Scale"},
+					ObjectMeta: metav1.ObjectMeta{Name: "otter", Namespace: "default"},
+					Spec:       autoscalingv1.ScaleSpec{Replicas: 3},
+				})
+			default:
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return nil, nil
+			}
+		}),
+	}
+	resources, err := c.Build(objBody(&otter), false)
+	require.NoError(t, err)
+	info := resources[0]
+
+	require.NoError(t, c.PatchStatus(info, map[string]interface{}{"phase": "Running"}))
+	assert.JSONEq(t, `{"status":{"phase":"Running"}}`, patches["/namespaces/default/pods/otter/status"])
+	phase, _, err := unstructured.NestedString(info.Object.(*unstructured.Unstructured).Object, "status", "phase")
+	require.NoError(t, err)
+	assert.Equal(t, "Running", phase)
+
+	require.NoError(t, c.Scale(info, 3))
+	assert.JSONEq(t, `{"spec":{"replicas":3}}`, patches["/namespaces/default/pods/otter/scale"])
+	// The Scale returned by the scale subresource must not replace the pod.
+	kind, err := metadataAccessor.Kind(info.Object)
+	require.NoError(t, err)
+	assert.Equal(t, "Pod", kind)
+}
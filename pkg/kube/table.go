@@ -0,0 +1,94 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TableOptions selects the columns of the Tables returned for resources built
+// with BuildTable.
+type TableOptions struct {
+	// Wide includes the columns the server only prints in wide output, such
+	// as the node and IP of pods, in the columns printed by default.
+	Wide bool
+	// LabelColumns adds a column for each of the given label keys holding
+	// the value of the label, like the --label-columns flag of kubectl get.
+	LabelColumns []string
+}
+
+func (o TableOptions) isZero() bool {
+	return !o.Wide && len(o.LabelColumns) == 0
+}
+
+// apply changes the columns of obj as selected by the options. Objects that
+// are not Tables are left untouched.
+func (o TableOptions) apply(obj runtime.Object) {
+	table, ok := obj.(*unstructured.Unstructured)
+	if !ok || table.GetKind() != "Table" || o.isZero() {
+		return
+	}
+
+	columns, _, _ := unstructured.NestedSlice(table.Object, "columnDefinitions")
+	if o.Wide {
+		for _, column := range columns {
+			if c, ok := column.(map[string]interface{}); ok {
+				c["priority"] = int64(0)
+			}
+		}
+	}
+	for _, key := range o.LabelColumns {
+		columns = append(columns, map[string]interface{}{
+			"name":        labelColumnName(key),
+			"type":        "string",
+			"format":      "",
+			"description": "The value of the " + key + " label.",
+			"priority":    int64(0),
+		})
+	}
+	_ = unstructured.SetNestedSlice(table.Object, columns, "columnDefinitions")
+
+	if len(o.LabelColumns) == 0 {
+		return
+	}
+	rows, _, _ := unstructured.NestedSlice(table.Object, "rows")
+	for _, row := range rows {
+		r, ok := row.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		cells, _, _ := unstructured.NestedSlice(r, "cells")
+		labels, _, _ := unstructured.NestedStringMap(r, "object", "metadata", "labels")
+		for _, key := range o.LabelColumns {
+			cells = append(cells, labels[key])
+		}
+		r["cells"] = cells
+	}
+	_ = unstructured.SetNestedSlice(table.Object, rows, "rows")
+}
+
+// labelColumnName returns the column header for a label key, which is the
+// upper case name of the label without its prefix.
+func labelColumnName(key string) string {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		key = key[i+1:]
+	}
+	return strings.ToUpper(key)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const podTable = `{
+	"apiVersion": "meta.k8s.io/v1",
+	"kind": "Table",
+	"columnDefinitions": [
+		{"name": "Name", "type": "string", "format": "name", "description": "", "priority": 0},
+		{"name": "Node", "type": "string", "format": "", "description": "", "priority": 1}
+	],
+	"rows": [
+		{"cells": ["web-0", "node-a"], "object": {"metadata": {"name": "web-0", "labels": {"app.kubernetes.io/version": "1.2.3"}}}},
+		{"cells": ["web-1", "node-b"], "object": {"metadata": {"name": "web-1"}}}
+	]
+}`
+
+func newPodTable(t *testing.T) *unstructured.Unstructured {
+	t.Helper()
+	table := &unstructured.Unstructured{}
+	require.NoError(t, json.Unmarshal([]byte(podTable), &table.Object))
+	return table
+}
+
+func TestTableOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    TableOptions
+		columns    []string
+		priorities []int64
+		cells      [][]interface{}
+	}{
+		{
+			name:       "default columns",
+			columns:    []string{"Name", "Node"},
+			priorities: []int64{0, 1},
+			cells:      [][]interface{}{{"web-0", "node-a"}, {"web-1", "node-b"}},
+		},
+		{
+			name:       "wide",
+			options:    TableOptions{Wide: true},
+			columns:    []string{"Name", "Node"},
+			priorities: []int64{0, 0},
+			cells:      [][]interface{}{{"web-0", "node-a"}, {"web-1", "node-b"}},
+		},
+		{
+			name:       "label columns",
+			options:    TableOptions{LabelColumns: []string{"app.kubernetes.io/version"}},
+			columns:    []string{"Name", "Node", "VERSION"},
+			priorities: []int64{0, 1, 0},
+			cells:      [][]interface{}{{"web-0", "node-a", "1.2.3"}, {"web-1", "node-b", ""}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newPodTable(t)
+			tt.options.apply(table)
+
+			columns, _, err := unstructured.NestedSlice(table.Object, "columnDefinitions")
+			require.NoError(t, err)
+			var names []string
+			var priorities []int64
+			for _, column := range columns {
+				c := column.(map[string]interface{})
+				names = append(names, c["name"].(string))
+				priority, _, _ := unstructured.NestedFieldNoCopy(c, "priority")
+				switch p := priority.(type) {
+				case int64:
+					priorities = append(priorities, p)
+				case float64:
+					priorities = append(priorities, int64(p))
+				}
+			}
+			assert.Equal(t, tt.columns, names)
+			assert.Equal(t, tt.priorities, priorities)
+
+			rows, _, err := unstructured.NestedSlice(table.Object, "rows")
+			require.NoError(t, err)
+			var cells [][]interface{}
+			for _, row := range rows {
+				cells = append(cells, row.(map[string]interface{})["cells"].([]interface{}))
+			}
+			assert.Equal(t, tt.cells, cells)
+		})
+	}
+}
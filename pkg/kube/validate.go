@@ -0,0 +1,208 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// ValidateOption configures the behavior of Validate.
+type ValidateOption func(*validateOptions)
+
+type validateOptions struct {
+	admission bool
+	manifest  string
+}
+
+// ValidateAdmission returns a ValidateOption that makes Validate also report
+// the resources rejected by admission webhooks. Without it, only the
+// resources rejected by field and schema validation are reported.
+func ValidateAdmission(admission bool) ValidateOption {
+	return func(o *validateOptions) {
+		o.admission = admission
+	}
+}
+
+// ValidateManifest returns a ValidateOption that sets the manifest the
+// resources were built from, so that the errors of Validate report the
+// position of the resources in the manifest and the template they were
+// rendered from.
+func ValidateManifest(manifest string) ValidateOption {
+	return func(o *validateOptions) {
+		o.manifest = manifest
+	}
+}
+
+// ValidationError is the validation failure of a single resource.
+type ValidationError struct {
+	ResourceError
+	// Source is the template the resource was rendered from, as recorded by
+	// the "# Source:" comment of its manifest document. It is empty when
+	// unknown.
+	Source string
+	// Line is the line of the manifest the document of the resource starts
+	// at, starting from 1. It is 0 when unknown.
+	Line int
+}
+
+func (e *ValidationError) Error() string {
+	var position string
+	switch {
+	case e.Source != "" && e.Line > 0:
+		position = fmt.Sprintf("%s (manifest line %d): ", e.Source, e.Line)
+	case e.Source != "":
+		position = e.Source + ": "
+	case e.Line > 0:
+		position = fmt.Sprintf("manifest line %d: ", e.Line)
+	}
+	return fmt.Sprintf("%s%s %q: %s", position, e.Info.Mapping.GroupVersionKind.Kind, e.Info.Name, e.Err)
+}
+
+// Validate submits the resources with server-side dry-run and strict field
+// validation, so that unknown or duplicate fields and invalid values are
+// reported without changing anything. Resources that exist are validated as
+// replacements of the live objects. The failures are returned together as an
+// *AggregateError of *ValidationError.
+func (c *Client) Validate(resources ResourceList, opts ...ValidateOption) error {
+	options := &validateOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	positions := manifestPositions(options.manifest)
+
+	var errs []error
+	slog.Debug("validating resources", "resources", len(resources))
+	err := resources.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		err = validateResource(info)
+		if err == nil || (!options.admission && isAdmissionError(err)) {
+			return nil
+		}
+		slog.Debug("validation rejected resource", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, slog.Any("error", err))
+		validationErr := &ValidationError{ResourceError: ResourceError{Info: info, Err: err}}
+		if pos, ok := positions.find(info); ok {
+			validationErr.Source = pos.source
+			validationErr.Line = pos.line
+		}
+		errs = append(errs, validationErr)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) != 0 {
+		return &AggregateError{Errs: errs}
+	}
+	return nil
+}
+
+func validateResource(info *resource.Info) error {
+	helper := resource.NewHelper(info.Client, info.Mapping).
+		WithFieldManager(getManagedFieldsManager()).
+		WithFieldValidation(metav1.FieldValidationStrict).
+		DryRun(true)
+	if _, err := helper.Get(info.Namespace, info.Name); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "could not get information about the resource")
+		}
+		_, err := helper.Create(info.Namespace, true, info.Object.DeepCopyObject())
+		return err
+	}
+	_, err := helper.Replace(info.Namespace, info.Name, true, info.Object.DeepCopyObject())
+	return err
+}
+
+// isAdmissionError reports whether err is a rejection by an admission webhook.
+func isAdmissionError(err error) bool {
+	return strings.Contains(err.Error(), "admission webhook")
+}
+
+// manifestPosition is the position of a resource document in a manifest.
+type manifestPosition struct {
+	kind, name, namespace string
+	source                string
+	line                  int
+}
+
+type manifestPositionList []manifestPosition
+
+// manifestPositions finds the position of every resource document of a
+// manifest, along with the template recorded by its "# Source:" comment.
+func manifestPositions(manifest string) manifestPositionList {
+	if manifest == "" {
+		return nil
+	}
+	var positions manifestPositionList
+	var doc strings.Builder
+	source := ""
+	start := 1
+	flush := func() {
+		var head struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc.String()), &head); err == nil && head.Kind != "" {
+			positions = append(positions, manifestPosition{
+				kind:      head.Kind,
+				name:      head.Metadata.Name,
+				namespace: head.Metadata.Namespace,
+				source:    source,
+				line:      start,
+			})
+		}
+		doc.Reset()
+		source = ""
+	}
+	for i, line := range strings.Split(manifest, "\n") {
+		if strings.TrimRight(line, " \t\r") == "---" {
+			flush()
+			start = i + 2
+			continue
+		}
+		if s, ok := strings.CutPrefix(line, "# Source: "); ok && source == "" {
+			source = strings.TrimSpace(s)
+		}
+		doc.WriteString(line)
+		doc.WriteByte('\n')
+	}
+	flush()
+	return positions
+}
+
+// find returns the position of the document of a resource. Documents without
+// a namespace match resources in any namespace.
+func (l manifestPositionList) find(info *resource.Info) (manifestPosition, bool) {
+	for _, pos := range l {
+		if pos.kind == info.Mapping.GroupVersionKind.Kind && pos.name == info.Name && (pos.namespace == "" || pos.namespace == info.Namespace) {
+			return pos, true
+		}
+	}
+	return manifestPosition{}, false
+}
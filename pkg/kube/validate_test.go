@@ -0,0 +1,136 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+const validateManifest = `---
+# Source: ocean/templates/starfish.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: starfish
+spec:
+  containers:
+  - name: app
+    image: nginx
+    colour: orange
+---
+# Source: ocean/templates/otter.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: otter
+spec:
+  containers:
+  - name: app
+    image: nginx
+---
+# Source: ocean/templates/dolphin.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: dolphin
+spec:
+  containers:
+  - name: app
+    image: nginx
+`
+
+func TestValidate(t *testing.T) {
+	otter := newPod("otter")
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			p, m := req.URL.Path, req.Method
+			if m != "GET" {
+				assert.Equal(t, "All", req.URL.Query().Get("dryRun"), "%s %s", m, p)
+				assert.Equal(t, metav1.FieldValidationStrict, req.URL.Query().Get("fieldValidation"), "%s %s", m, p)
+			}
+			var body []byte
+			if req.Body != nil {
+				body, _ = io.ReadAll(req.Body)
+			}
+			switch {
+			case p == "/namespaces/default/pods/starfish" && m == "GET":
+				return newResponse(404, notFoundBody())
+			case p == "/namespaces/default/pods/dolphin" && m == "GET":
+				return newResponse(404, notFoundBody())
+			case p == "/namespaces/default/pods" && m == "POST" && bytes.Contains(body, []byte("starfish")):
+				return newResponse(400, &metav1.Status{Status: metav1.StatusFailure, Message: `Pod in version "v1" cannot be handled as a Pod: strict decoding error: unknown field "spec.containers[0].colour"`, Reason: metav1.StatusReasonBadRequest})
+			case p == "/namespaces/default/pods" && m == "POST":
+				return newResponse(403, &metav1.Status{Status: metav1.StatusFailure, Message: `admission webhook "policy.example.com" denied the request`, Reason: metav1.StatusReasonForbidden})
+			case p == "/namespaces/default/pods/otter" && m == "GET":
+				return newResponse(200, &otter)
+			case p == "/namespaces/default/pods/otter" && m == "PUT":
+				return newResponse(200, &otter)
+			default:
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return nil, nil
+			}
+		}),
+	}
+	resources, err := c.Build(strings.NewReader(validateManifest), false)
+	require.NoError(t, err)
+
+	failed := func(err error) []*ValidationError {
+		t.Helper()
+		var aggregate *AggregateError
+		require.True(t, errors.As(err, &aggregate), "expected an *AggregateError, got %T", err)
+		var failures []*ValidationError
+		for _, err := range aggregate.Unwrap() {
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr), "expected a *ValidationError, got %T", err)
+			failures = append(failures, validationErr)
+		}
+		return failures
+	}
+
+	failures := failed(c.Validate(resources, ValidateManifest(validateManifest)))
+	require.Len(t, failures, 1)
+	assert.Equal(t, "starfish", failures[0].Info.Name)
+	assert.Equal(t, "ocean/templates/starfish.yaml", failures[0].Source)
+	assert.Equal(t, 2, failures[0].Line)
+	assert.Contains(t, failures[0].Error(), `ocean/templates/starfish.yaml (manifest line 2): Pod "starfish": `)
+	assert.Contains(t, failures[0].Error(), `unknown field "spec.containers[0].colour"`)
+
+	failures = failed(c.Validate(resources, ValidateManifest(validateManifest), ValidateAdmission(true)))
+	require.Len(t, failures, 2)
+	assert.Equal(t, "dolphin", failures[1].Info.Name)
+	assert.Equal(t, "ocean/templates/dolphin.yaml", failures[1].Source)
+	assert.Equal(t, 23, failures[1].Line)
+
+	failures = failed(c.Validate(resources))
+	require.Len(t, failures, 1)
+	assert.Empty(t, failures[0].Source)
+	assert.Zero(t, failures[0].Line)
+}
@@ -49,20 +49,29 @@ import (
 // legacyWaiter is the legacy implementation of the Waiter interface. This logic was used by default in Helm 3
 // Helm 4 now uses the StatusWaiter implementation instead
 type legacyWaiter struct {
-	c          ReadyChecker
-	kubeClient *kubernetes.Clientset
+	c                ReadyChecker
+	kubeClient       *kubernetes.Clientset
+	ignorePVCBinding bool
+	scheme           *runtime.Scheme
 }
 
 func (hw *legacyWaiter) Wait(resources ResourceList, timeout time.Duration) error {
-	hw.c = NewReadyChecker(hw.kubeClient, PausedAsReady(true))
+	hw.c = NewReadyChecker(hw.kubeClient, PausedAsReady(true), IgnorePVCBinding(hw.ignorePVCBinding), ConversionScheme(hw.scheme))
 	return hw.waitForResources(resources, timeout)
 }
 
 func (hw *legacyWaiter) WaitWithJobs(resources ResourceList, timeout time.Duration) error {
-	hw.c = NewReadyChecker(hw.kubeClient, PausedAsReady(true), CheckJobs(true))
+	hw.c = NewReadyChecker(hw.kubeClient, PausedAsReady(true), CheckJobs(true), IgnorePVCBinding(hw.ignorePVCBinding), ConversionScheme(hw.scheme))
 	return hw.waitForResources(resources, timeout)
 }
 
+func (hw *legacyWaiter) conversionScheme() *runtime.Scheme {
+	if hw.scheme != nil {
+		return hw.scheme
+	}
+	return kubernetesNativeScheme()
+}
+
 // waitForResources polls to get the current status of all pods, PVCs, Services and
 // Jobs(optional) until all are ready or a timeout is reached
 func (hw *legacyWaiter) waitForResources(created ResourceList, timeout time.Duration) error {
@@ -194,9 +203,9 @@ func SelectorsForObject(object runtime.Object) (selector labels.Selector, err er
 	return selector, errors.Wrap(err, "invalid label selector")
 }
 
-func (hw *legacyWaiter) watchTimeout(t time.Duration) func(*resource.Info) error {
+func (hw *legacyWaiter) watchTimeout(ctx context.Context, t time.Duration) func(*resource.Info) error {
 	return func(info *resource.Info) error {
-		return hw.watchUntilReady(t, info)
+		return hw.watchUntilReady(ctx, t, info)
 	}
 }
 
@@ -217,10 +226,23 @@ func (hw *legacyWaiter) watchTimeout(t time.Duration) func(*resource.Info) error
 func (hw *legacyWaiter) WatchUntilReady(resources ResourceList, timeout time.Duration) error {
 	// For jobs, there's also the option to do poll c.Jobs(namespace).Get():
 	// https://github.com/adamreese/kubernetes/blob/master/test/e2e/job.go#L291-L300
-	return perform(resources, hw.watchTimeout(timeout))
+	return hw.WatchUntilReadyWithContext(context.Background(), resources, timeout)
+}
+
+// WatchUntilReadyWithContext is WatchUntilReady, but it also stops watching
+// when ctx is done.
+func (hw *legacyWaiter) WatchUntilReadyWithContext(ctx context.Context, resources ResourceList, timeout time.Duration) error {
+	return perform(resources, hw.watchTimeout(ctx, timeout))
 }
 
 func perform(infos ResourceList, fn func(*resource.Info) error) error {
+	return performOrdered(infos, nil, fn)
+}
+
+// performOrdered runs fn for all infos in the order determined by sorter,
+// running it concurrently for infos that are not ordered relative to each
+// other.
+func performOrdered(infos ResourceList, sorter ResourceSorter, fn func(*resource.Info) error) error {
 	var result error
 
 	if len(infos) == 0 {
@@ -228,7 +250,7 @@ func perform(infos ResourceList, fn func(*resource.Info) error) error {
 	}
 
 	errs := make(chan error)
-	go batchPerform(infos, fn, errs)
+	go batchPerform(infos, sorter, fn, errs)
 
 	for range infos {
 		err := <-errs
@@ -240,7 +262,7 @@ func perform(infos ResourceList, fn func(*resource.Info) error) error {
 	return result
 }
 
-func (hw *legacyWaiter) watchUntilReady(timeout time.Duration, info *resource.Info) error {
+func (hw *legacyWaiter) watchUntilReady(ctx context.Context, timeout time.Duration, info *resource.Info) error {
 	kind := info.Mapping.GroupVersionKind.Kind
 	switch kind {
 	case "Job", "Pod":
@@ -264,12 +286,12 @@ func (hw *legacyWaiter) watchUntilReady(timeout time.Duration, info *resource.In
 	// In the future, we might want to add some special logic for types
 	// like Ingress, Volume, etc.
 
-	ctx, cancel := watchtools.ContextWithOptionalTimeout(context.Background(), timeout)
+	ctx, cancel := watchtools.ContextWithOptionalTimeout(ctx, timeout)
 	defer cancel()
 	_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, nil, func(e watch.Event) (bool, error) {
 		// Make sure the incoming object is versioned as we use unstructured
 		// objects when we build manifests
-		obj := convertWithMapper(e.Object, info.Mapping)
+		obj := convertWithScheme(e.Object, info.Mapping, hw.conversionScheme())
 		switch e.Type {
 		case watch.Added, watch.Modified:
 			// For things like a secret or a config map, this is the best indicator
@@ -0,0 +1,73 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v4/internal/kubeschema"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/lint/support"
+)
+
+// KubeSchemaRule returns a rule validating the rendered manifests against the
+// Kubernetes API schemas of the linted Kubernetes version, without a cluster.
+// The schemas are read from location and cached in cacheDir. An empty
+// location reads them from the kubernetes-json-schema repository.
+func KubeSchemaRule(location, cacheDir string) Rule {
+	meta := RuleMetadata{
+		Name:        "kube-schema",
+		Description: "rendered manifests match the schemas of the Kubernetes API",
+		Severity:    support.ErrorSev,
+	}
+	return NewRule(meta, func(ctx *RuleContext) []Finding {
+		manifests, err := ctx.Render()
+		if err != nil {
+			// The templates rule reports rendering errors.
+			return nil
+		}
+		kubeVersion := chartutil.DefaultCapabilities.KubeVersion
+		if ctx.KubeVersion != nil {
+			kubeVersion = *ctx.KubeVersion
+		}
+		validator := kubeschema.New(kubeVersion.Major, kubeVersion.Minor, location, cacheDir)
+
+		names := make([]string, 0, len(manifests))
+		for name := range manifests {
+			if ext := filepath.Ext(name); ext == ".yaml" || ext == ".yml" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		var findings []Finding
+		for _, name := range names {
+			// Report templates relative to the chart, as the templates rule does.
+			_, fpath, _ := strings.Cut(name, "/")
+			invalid, err := validator.Validate(manifests[name])
+			if err != nil {
+				return append(findings, Finding{Path: fpath, Err: err})
+			}
+			for _, err := range invalid {
+				findings = append(findings, Finding{Path: fpath, Err: err})
+			}
+		}
+		return findings
+	})
+}
@@ -20,13 +20,13 @@ import (
 	"path/filepath"
 
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
-	"helm.sh/helm/v4/pkg/lint/rules"
 	"helm.sh/helm/v4/pkg/lint/support"
 )
 
 type linterOptions struct {
 	KubeVersion          *chartutil.KubeVersion
 	SkipSchemaValidation bool
+	Rules                []Rule
 }
 
 type LinterOption func(lo *linterOptions)
@@ -43,6 +43,15 @@ func WithSkipSchemaValidation(skipSchemaValidation bool) LinterOption {
 	}
 }
 
+// WithRules runs rules after the registered rules.
+func WithRules(rules ...Rule) LinterOption {
+	return func(lo *linterOptions) {
+		lo.Rules = append(lo.Rules, rules...)
+	}
+}
+
+// RunAll runs the registered rules, and those given with WithRules, against
+// the chart in baseDir.
 func RunAll(baseDir string, values map[string]interface{}, namespace string, options ...LinterOption) support.Linter {
 
 	chartDir, _ := filepath.Abs(baseDir)
@@ -56,10 +65,16 @@ func RunAll(baseDir string, values map[string]interface{}, namespace string, opt
 		ChartDir: chartDir,
 	}
 
-	rules.Chartfile(&result)
-	rules.ValuesWithOverrides(&result, values)
-	rules.TemplatesWithSkipSchemaValidation(&result, values, namespace, lo.KubeVersion, lo.SkipSchemaValidation)
-	rules.Dependencies(&result)
+	ctx := &RuleContext{
+		ChartDir:             chartDir,
+		Values:               values,
+		Namespace:            namespace,
+		KubeVersion:          lo.KubeVersion,
+		SkipSchemaValidation: lo.SkipSchemaValidation,
+	}
+	for _, rule := range append(Rules(), lo.Rules...) {
+		rule.Run(&result, ctx)
+	}
 
 	return result
 }
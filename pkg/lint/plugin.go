@@ -0,0 +1,50 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"plugin"
+
+	"github.com/pkg/errors"
+)
+
+// RulesSymbol is the symbol a rule set plugin exports to provide its rules.
+// It must be a function of type func() []lint.Rule.
+const RulesSymbol = "LintRules"
+
+// LoadPlugin loads the rules of a rule set built as a Go plugin with
+// 'go build -buildmode=plugin'. The plugin must be built with the same Go
+// toolchain and Helm version as the binary loading it.
+func LoadPlugin(path string) ([]Rule, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading lint rule plugin %s", path)
+	}
+	sym, err := p.Lookup(RulesSymbol)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading lint rule plugin %s", path)
+	}
+	fn, ok := sym.(func() []Rule)
+	if !ok {
+		return nil, errors.Errorf("lint rule plugin %s: %s is a %T, expected func() []lint.Rule", path, RulesSymbol, sym)
+	}
+	rules := fn()
+	if err := checkNames(rules); err != nil {
+		return nil, errors.Wrapf(err, "lint rule plugin %s", path)
+	}
+	return rules, nil
+}
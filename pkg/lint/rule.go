@@ -0,0 +1,199 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/engine"
+	"helm.sh/helm/v4/pkg/lint/rules"
+	"helm.sh/helm/v4/pkg/lint/support"
+)
+
+// RuleMetadata describes a lint rule.
+type RuleMetadata struct {
+	// Name identifies the rule. Rules outside of Helm should qualify their
+	// names, as in "example.com/required-labels".
+	Name        string
+	Description string
+	// Severity is the severity of the problems found by the rule, one of the
+	// support.*Sev constants. The built-in rules report problems of several
+	// severities and leave it unset.
+	Severity int
+}
+
+// Rule is a check run against a chart by RunAll.
+type Rule interface {
+	Metadata() RuleMetadata
+	// Run checks the chart in ctx, adding the problems it finds to linter.
+	Run(linter *support.Linter, ctx *RuleContext)
+}
+
+// RuleContext holds the chart being linted and the options it is linted with.
+type RuleContext struct {
+	ChartDir             string
+	Values               map[string]interface{}
+	Namespace            string
+	KubeVersion          *chartutil.KubeVersion
+	SkipSchemaValidation bool
+
+	renderOnce sync.Once
+	manifests  map[string]string
+	renderErr  error
+}
+
+// Render renders the chart's templates with the context's values, keyed by
+// template name. Charts are rendered once per run, however many rules ask.
+func (ctx *RuleContext) Render() (map[string]string, error) {
+	ctx.renderOnce.Do(func() {
+		ctx.manifests, ctx.renderErr = ctx.render()
+	})
+	return ctx.manifests, ctx.renderErr
+}
+
+func (ctx *RuleContext) render() (map[string]string, error) {
+	chart, err := loader.Load(ctx.ChartDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessDependencies(chart, ctx.Values); err != nil {
+		return nil, err
+	}
+	caps := chartutil.DefaultCapabilities.Copy()
+	if ctx.KubeVersion != nil {
+		caps.KubeVersion = *ctx.KubeVersion
+	}
+	options := chartutil.ReleaseOptions{
+		Name:      "test-release",
+		Namespace: ctx.Namespace,
+	}
+	valuesToRender, err := chartutil.ToRenderValuesWithSchemaValidation(chart, ctx.Values, options, caps, ctx.SkipSchemaValidation)
+	if err != nil {
+		return nil, err
+	}
+	var e engine.Engine
+	e.LintMode = true
+	return e.Render(chart, valuesToRender)
+}
+
+// Finding is a problem found by a rule created with NewRule.
+type Finding struct {
+	// Path is the chart file the problem was found in.
+	Path string
+	Err  error
+}
+
+type rule struct {
+	meta  RuleMetadata
+	check func(ctx *RuleContext) []Finding
+}
+
+// NewRule returns a Rule reporting the findings of check at the severity in
+// meta.
+func NewRule(meta RuleMetadata, check func(ctx *RuleContext) []Finding) Rule {
+	return &rule{meta: meta, check: check}
+}
+
+func (r *rule) Metadata() RuleMetadata { return r.meta }
+
+func (r *rule) Run(linter *support.Linter, ctx *RuleContext) {
+	for _, f := range r.check(ctx) {
+		linter.RunLinterRule(r.meta.Severity, f.Path, f.Err)
+	}
+}
+
+// builtinRule adapts one of the rules in the rules package.
+type builtinRule struct {
+	meta RuleMetadata
+	run  func(linter *support.Linter, ctx *RuleContext)
+}
+
+func (r *builtinRule) Metadata() RuleMetadata { return r.meta }
+
+func (r *builtinRule) Run(linter *support.Linter, ctx *RuleContext) { r.run(linter, ctx) }
+
+var registry = struct {
+	sync.RWMutex
+	rules []Rule
+}{
+	rules: []Rule{
+		&builtinRule{
+			meta: RuleMetadata{Name: "chartfile", Description: "Chart.yaml is well-formed and complete"},
+			run: func(linter *support.Linter, _ *RuleContext) {
+				rules.Chartfile(linter)
+			},
+		},
+		&builtinRule{
+			meta: RuleMetadata{Name: "values", Description: "values files are valid and match the values schema"},
+			run: func(linter *support.Linter, ctx *RuleContext) {
+				rules.ValuesWithOverrides(linter, ctx.Values)
+			},
+		},
+		&builtinRule{
+			meta: RuleMetadata{Name: "templates", Description: "templates render to valid Kubernetes manifests"},
+			run: func(linter *support.Linter, ctx *RuleContext) {
+				rules.TemplatesWithSkipSchemaValidation(linter, ctx.Values, ctx.Namespace, ctx.KubeVersion, ctx.SkipSchemaValidation)
+			},
+		},
+		&builtinRule{
+			meta: RuleMetadata{Name: "dependencies", Description: "dependencies are declared and vendored consistently"},
+			run: func(linter *support.Linter, _ *RuleContext) {
+				rules.Dependencies(linter)
+			},
+		},
+	},
+}
+
+// Register adds rules to the set run by every call to RunAll. It is meant to
+// be called from the init function of a package compiled into Helm, so that
+// custom builds can enforce house rules.
+func Register(rs ...Rule) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if err := checkNames(append(append([]Rule(nil), registry.rules...), rs...)); err != nil {
+		return err
+	}
+	registry.rules = append(registry.rules, rs...)
+	return nil
+}
+
+// checkNames checks that every rule has a name, and that no two share one.
+func checkNames(rs []Rule) error {
+	seen := map[string]bool{}
+	for _, r := range rs {
+		name := r.Metadata().Name
+		if name == "" {
+			return errors.New("lint rules must have a name")
+		}
+		if seen[name] {
+			return errors.Errorf("lint rule %q is already registered", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Rules returns the registered rules, in the order they run.
+func Rules() []Rule {
+	registry.RLock()
+	defer registry.RUnlock()
+	return append([]Rule(nil), registry.rules...)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/lint/support"
+)
+
+// requiredLabel is a house rule requiring a label on every rendered resource.
+func requiredLabel(label string) Rule {
+	meta := RuleMetadata{
+		Name:        "example.com/required-labels",
+		Description: "resources carry the " + label + " label",
+		Severity:    support.ErrorSev,
+	}
+	return NewRule(meta, func(ctx *RuleContext) []Finding {
+		manifests, err := ctx.Render()
+		if err != nil {
+			return []Finding{{Path: "templates/", Err: err}}
+		}
+		var findings []Finding
+		for name, m := range manifests {
+			var obj struct {
+				Metadata struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"metadata"`
+			}
+			if err := yaml.Unmarshal([]byte(m), &obj); err != nil || strings.TrimSpace(m) == "" {
+				continue
+			}
+			if _, ok := obj.Metadata.Labels[label]; !ok {
+				findings = append(findings, Finding{Path: name, Err: fmt.Errorf("missing label %q", label)})
+			}
+		}
+		return findings
+	})
+}
+
+func TestRunAllWithRules(t *testing.T) {
+	linter := RunAll(goodChartDir, values, namespace, WithRules(requiredLabel("app.kubernetes.io/name")))
+	if linter.HighestSeverity != support.ErrorSev {
+		t.Errorf("expected the house rule to fail with an error, got severity %d", linter.HighestSeverity)
+	}
+	found := false
+	for _, msg := range linter.Messages {
+		if strings.Contains(msg.Err.Error(), `missing label "app.kubernetes.io/name"`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a missing label message, got %v", linter.Messages)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register(NewRule(RuleMetadata{Name: "templates"}, nil)); err == nil {
+		t.Error("expected registering a rule with a built-in name to fail")
+	}
+	if err := Register(NewRule(RuleMetadata{}, nil)); err == nil {
+		t.Error("expected registering a rule without a name to fail")
+	}
+
+	names := []string{}
+	for _, r := range Rules() {
+		names = append(names, r.Metadata().Name)
+	}
+	if got := strings.Join(names, ","); got != "chartfile,values,templates,dependencies" {
+		t.Errorf("unexpected registered rules %s", got)
+	}
+}
+
+func TestLoadPluginMissing(t *testing.T) {
+	if _, err := LoadPlugin("testdata/does-not-exist.so"); err == nil {
+		t.Error("expected loading a missing plugin to fail")
+	}
+}
@@ -85,20 +85,36 @@ func TemplatesWithSkipSchemaValidation(linter *support.Linter, values map[string
 		return
 	}
 
-	cvals, err := chartutil.CoalesceValues(chart, values)
-	if err != nil {
+	cvals, conflicts, err := chartutil.CoalesceValuesWithConflicts(chart, values)
+	if !linter.RunLinterRule(support.ErrorSev, chartutil.ValuesfileName, err) {
 		return
 	}
+	for _, conflict := range conflicts {
+		linter.RunLinterRule(support.WarningSev, chartutil.ValuesfileName, conflict)
+	}
 
-	valuesToRender, err := chartutil.ToRenderValuesWithSchemaValidation(chart, cvals, options, caps, skipSchemaValidation)
+	valuesToRender, violations, err := chartutil.ToRenderValuesWithSchemaWarnings(chart, cvals, options, caps, skipSchemaValidation, false)
 	if err != nil {
 		linter.RunLinterRule(support.ErrorSev, fpath, err)
 		return
 	}
+	for _, v := range violations {
+		linter.RunLinterRule(support.WarningSev, "values.schema.json", errors.New(v.String()))
+	}
 	var e engine.Engine
 	e.LintMode = true
+	e.CollectErrors = true
 	renderedContentMap, err := e.Render(chart, valuesToRender)
 
+	// Report the errors of all the templates at once, each with its template.
+	var renderErrs engine.RenderErrors
+	if errors.As(err, &renderErrs) {
+		for _, renderErr := range renderErrs {
+			linter.RunLinterRule(support.ErrorSev, strings.TrimPrefix(renderErr.Template, chart.Name()+"/"), renderErr)
+		}
+		return
+	}
+
 	renderOk := linter.RunLinterRule(support.ErrorSev, fpath, err)
 
 	if !renderOk {
@@ -116,6 +132,11 @@ func TemplatesWithSkipSchemaValidation(linter *support.Linter, values map[string
 		fileName := template.Name
 		fpath = fileName
 
+		// The Jsonnet templates of Jsonnet charts are not Go templates
+		if chart.Metadata.Engine == "jsonnet" && isJsonnetTemplate(fileName) {
+			continue
+		}
+
 		linter.RunLinterRule(support.ErrorSev, fpath, validateAllowedExtension(fileName))
 
 		// We only apply the following lint rules to yaml files
@@ -202,6 +223,11 @@ func validateTemplatesDir(templatesPath string) error {
 	return nil
 }
 
+func isJsonnetTemplate(fileName string) bool {
+	ext := filepath.Ext(fileName)
+	return ext == ".jsonnet" || ext == ".libsonnet"
+}
+
 func validateAllowedExtension(fileName string) error {
 	ext := filepath.Ext(fileName)
 	validExtensions := []string{".yaml", ".yml", ".tpl", ".txt"}
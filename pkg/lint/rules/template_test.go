@@ -272,6 +272,122 @@ func TestStrictTemplateParsingMapError(t *testing.T) {
 	}
 }
 
+func TestTemplatesReportAllRenderErrors(t *testing.T) {
+	ch := chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "allerrors",
+			APIVersion: "v2",
+			Version:    "0.1.0",
+		},
+		Values: map[string]interface{}{"image": "nginx"},
+		Templates: []*chart.File{
+			{
+				Name: "templates/deployment.yaml",
+				Data: []byte("image: {{ .Values.image.tag }}\nreplicas: {{ .Values.scaling.replicas }}\n"),
+			},
+			{
+				Name: "templates/service.yaml",
+				Data: []byte("port: {{ .Values.service.port }}\n"),
+			},
+		},
+	}
+	dir := t.TempDir()
+	if err := chartutil.SaveDir(&ch, dir); err != nil {
+		t.Fatal(err)
+	}
+	linter := &support.Linter{
+		ChartDir: filepath.Join(dir, ch.Metadata.Name),
+	}
+	Templates(linter, ch.Values, namespace, strict)
+
+	expected := []string{
+		"templates/service.yaml: execution error at (allerrors/templates/service.yaml:1:16): nil pointer evaluating interface {}.port",
+		"templates/deployment.yaml: execution error at (allerrors/templates/deployment.yaml:1:17): can't evaluate field tag in type interface {}",
+		"templates/deployment.yaml: execution error at (allerrors/templates/deployment.yaml:2:20): nil pointer evaluating interface {}.replicas",
+	}
+	var got []string
+	for _, msg := range linter.Messages {
+		got = append(got, msg.Path+": "+msg.Err.Error())
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected messages:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestTemplatesJsonnetChart(t *testing.T) {
+	ch := chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "jsonnetchart",
+			APIVersion: "v2",
+			Version:    "0.1.0",
+			Engine:     "jsonnet",
+		},
+		Values: map[string]interface{}{"name": "settings"},
+		Templates: []*chart.File{
+			{
+				Name: "templates/configmap.jsonnet",
+				Data: []byte(`{ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: std.extVar('Values').name } }`),
+			},
+		},
+	}
+	dir := t.TempDir()
+	if err := chartutil.SaveDir(&ch, dir); err != nil {
+		t.Fatal(err)
+	}
+	linter := &support.Linter{
+		ChartDir: filepath.Join(dir, ch.Metadata.Name),
+	}
+	Templates(linter, ch.Values, namespace, strict)
+	if len(linter.Messages) != 0 {
+		t.Errorf("expected zero messages, got %d", len(linter.Messages))
+		for i, msg := range linter.Messages {
+			t.Logf("Message %d: %q", i, msg)
+		}
+	}
+}
+
+func TestTemplatesReportValueConflicts(t *testing.T) {
+	ch := chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "conflicts",
+			APIVersion: "v2",
+			Version:    "0.1.0",
+		},
+		Raw: []*chart.File{
+			{
+				Name: "values.yaml",
+				Data: []byte("image:\n  repository: nginx\n  tag: latest\n"),
+			},
+		},
+		Templates: []*chart.File{
+			{
+				Name: "templates/configmap.yaml",
+				Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: conflicts\ndata:\n  image: {{ .Values.image | quote }}\n"),
+			},
+		},
+	}
+	dir := t.TempDir()
+	if err := chartutil.SaveDir(&ch, dir); err != nil {
+		t.Fatal(err)
+	}
+	linter := &support.Linter{
+		ChartDir: filepath.Join(dir, ch.Metadata.Name),
+	}
+	Templates(linter, map[string]interface{}{"image": "nginx:1.27"}, namespace, strict)
+
+	if len(linter.Messages) != 1 {
+		t.Fatalf("expected 1 message, got %d: %v", len(linter.Messages), linter.Messages)
+	}
+	msg := linter.Messages[0]
+	if msg.Severity != support.WarningSev || msg.Path != "values.yaml" {
+		t.Errorf("expected a warning for values.yaml, got %v", msg)
+	}
+	expected := "type conflict for conflicts.image: non-table value (nginx:1.27) from user-supplied values cannot be merged with table from conflicts/values.yaml"
+	if msg.Err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, msg.Err.Error())
+	}
+}
+
 func TestValidateMatchSelector(t *testing.T) {
 	md := &K8sYamlStruct{
 		APIVersion: "apps/v1",
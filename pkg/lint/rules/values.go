@@ -22,6 +22,8 @@ import (
 
 	"github.com/pkg/errors"
 
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/lint/support"
 )
@@ -41,7 +43,19 @@ func ValuesWithOverrides(linter *support.Linter, valueOverrides map[string]inter
 		return
 	}
 
-	linter.RunLinterRule(support.ErrorSev, file, validateValuesFile(vf, valueOverrides))
+	err := validateValuesFile(vf, valueOverrides)
+	severity := support.ErrorSev
+	var warning schemaWarning
+	if errors.As(err, &warning) {
+		severity = support.WarningSev
+	}
+	linter.RunLinterRule(severity, file, err)
+}
+
+// schemaWarning is a violation of the values schema of a chart that only
+// warns about them.
+type schemaWarning struct {
+	error
 }
 
 func validateValuesFileExistence(valuesPath string) error {
@@ -65,6 +79,8 @@ func validateValuesFile(valuesPath string, overrides map[string]interface{}) err
 	// CoalesceValues
 	coalescedValues := chartutil.CoalesceTables(make(map[string]interface{}, len(overrides)), overrides)
 	coalescedValues = chartutil.CoalesceTables(coalescedValues, values)
+	// The list directives are not part of the values the templates see.
+	chartutil.StripListDirectives(coalescedValues)
 
 	ext := filepath.Ext(valuesPath)
 	schemaPath := valuesPath[:len(valuesPath)-len(ext)] + ".schema.json"
@@ -75,5 +91,25 @@ func validateValuesFile(valuesPath string, overrides map[string]interface{}) err
 	if err != nil {
 		return err
 	}
-	return chartutil.ValidateAgainstSingleSchema(coalescedValues, schema)
+
+	// The $refs of the schema are resolved against the files of the chart,
+	// as they are at install time. If the chart cannot be loaded, the chart
+	// linter reports it, so only the schema itself is used.
+	var files []*chart.File
+	var warnOnly bool
+	if chrt, err := loader.LoadDir(filepath.Dir(valuesPath)); err == nil {
+		files = chrt.Files
+		warnOnly = chrt.Metadata.Annotations[chartutil.SchemaValidationAnnotation] == "warn"
+		if chrt.Metadata.Annotations[chartutil.SchemaDefaultsAnnotation] == "true" {
+			if err := chartutil.ApplySchemaDefaults(chrt, coalescedValues); err != nil {
+				return err
+			}
+		}
+	}
+	err = chartutil.ValidateAgainstSingleSchemaWithFiles(coalescedValues, schema, files)
+	var validationErr chartutil.JSONSchemaValidationError
+	if warnOnly && errors.As(err, &validationErr) {
+		return schemaWarning{err}
+	}
+	return err
 }
@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"helm.sh/helm/v4/internal/test/ensure"
+	"helm.sh/helm/v4/pkg/lint/support"
 )
 
 var nonExistingValuesFilePath = filepath.Join("/fake/dir", "values.yaml")
@@ -113,6 +114,90 @@ func TestValidateValuesFileSchemaOverrides(t *testing.T) {
 	}
 }
 
+func TestValidateValuesFileListDirectives(t *testing.T) {
+	yaml := "username: admin\npassword: swordfish\nroles:\n- $patch: append\n- admin"
+	tmpdir := ensure.TempFile(t, "values.yaml", []byte(yaml))
+	schema := `{"type": "object", "properties": {"roles": {"type": "array", "items": {"type": "string"}}}}`
+	if err := os.WriteFile(filepath.Join(tmpdir, "values.schema.json"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	valfile := filepath.Join(tmpdir, "values.yaml")
+	if err := validateValuesFile(valfile, map[string]interface{}{}); err != nil {
+		t.Fatalf("Failed validation with %s", err)
+	}
+}
+
+func TestValidateValuesFileSchemaDefaults(t *testing.T) {
+	tmpdir := ensure.TempFile(t, "values.yaml", []byte("username: admin"))
+	schema := `{"type": "object", "required": ["password"], "properties": {"password": {"type": "string", "default": "swordfish"}}}`
+	if err := os.WriteFile(filepath.Join(tmpdir, "values.schema.json"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chartfile := "apiVersion: v2\nname: defaults\nversion: 0.1.0\n"
+	if err := os.WriteFile(filepath.Join(tmpdir, "Chart.yaml"), []byte(chartfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	valfile := filepath.Join(tmpdir, "values.yaml")
+	if err := validateValuesFile(valfile, map[string]interface{}{}); err == nil {
+		t.Fatal("expected values file to fail validation")
+	}
+
+	chartfile += "annotations:\n  helm.sh/schema-defaults: \"true\"\n"
+	if err := os.WriteFile(filepath.Join(tmpdir, "Chart.yaml"), []byte(chartfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateValuesFile(valfile, map[string]interface{}{}); err != nil {
+		t.Fatalf("Failed validation with %s", err)
+	}
+}
+
+func TestValidateValuesFileSchemaRefs(t *testing.T) {
+	yaml := "credentials:\n  username: 1234"
+	tmpdir := ensure.TempFile(t, "values.yaml", []byte(yaml))
+	files := map[string]string{
+		"Chart.yaml":                      "apiVersion: v2\nname: refs\nversion: 0.1.0\n",
+		"values.schema.json":              `{"properties": {"credentials": {"$ref": "schemas/credentials.schema.json"}}}`,
+		"schemas/credentials.schema.json": `{"properties": {"username": {"type": "string"}}}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(tmpdir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := validateValuesFile(filepath.Join(tmpdir, "values.yaml"), map[string]interface{}{})
+	if err == nil {
+		t.Fatal("expected values file to fail validation")
+	}
+
+	assert.Contains(t, err.Error(), "- at '/credentials/username': got number, want string")
+}
+
+func TestValuesWithOverridesSchemaWarnings(t *testing.T) {
+	yaml := "username: 1234\npassword: swordfish"
+	tmpdir := ensure.TempFile(t, "values.yaml", []byte(yaml))
+	createTestingSchema(t, tmpdir)
+	chartfile := "apiVersion: v2\nname: warn\nversion: 0.1.0\nannotations:\n  helm.sh/schema-validation: warn\n"
+	if err := os.WriteFile(filepath.Join(tmpdir, "Chart.yaml"), []byte(chartfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: tmpdir}
+	ValuesWithOverrides(&linter, map[string]interface{}{})
+
+	if len(linter.Messages) != 1 {
+		t.Fatalf("expected 1 message, got %d: %v", len(linter.Messages), linter.Messages)
+	}
+	assert.Equal(t, support.WarningSev, linter.Messages[0].Severity)
+	assert.Contains(t, linter.Messages[0].Err.Error(), "- at '/username': got number, want string")
+}
+
 func TestValidateValuesFile(t *testing.T) {
 	tests := []struct {
 		name         string
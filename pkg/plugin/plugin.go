@@ -42,6 +42,18 @@ type Downloaders struct {
 	Command string `json:"command"`
 }
 
+// TemplateFunctions represents the template functions a plugin provides,
+// implemented by a WebAssembly module.
+type TemplateFunctions struct {
+	// Module is the path of the WebAssembly module, relative to the plugin
+	// directory.
+	Module string `json:"module"`
+	// Functions are the names of the functions the module exports, which are
+	// called in templates by the name of the plugin and the name of the
+	// function joined by an underscore, as in {{ ipmath_cidrHost ... }}.
+	Functions []string `json:"functions"`
+}
+
 // PlatformCommand represents a command for a particular operating system and architecture
 type PlatformCommand struct {
 	OperatingSystem string   `json:"os"`
@@ -134,6 +146,10 @@ type Metadata struct {
 	// for special protocols.
 	Downloaders []Downloaders `json:"downloaders"`
 
+	// TemplateFunctions field is used if the plugin supplies template
+	// functions to the charts Helm renders.
+	TemplateFunctions *TemplateFunctions `json:"templateFunctions,omitempty"`
+
 	// UseTunnelDeprecated indicates that this command needs a tunnel.
 	// Setting this will cause a number of side effects, such as the
 	// automatic setting of HELM_HOST.
@@ -229,6 +245,12 @@ func PrepareCommands(cmds []PlatformCommand, expandArgs bool, extraArgs []string
 	return main, baseArgs, nil
 }
 
+// HasCommand reports whether the plugin has a command, which plugins only
+// supplying template functions may not have.
+func (p *Plugin) HasCommand() bool {
+	return len(p.Metadata.PlatformCommand) > 0 || p.Metadata.Command != ""
+}
+
 // PrepareCommand gets the correct command and arguments for a plugin.
 //
 // It merges extraArgs into any arguments supplied in the plugin. It returns the name of the command and an args array.
@@ -254,6 +276,10 @@ func (p *Plugin) PrepareCommand(extraArgs []string) (string, []string, error) {
 // Plugin names can only contain the ASCII characters a-z, A-Z, 0-9, ​_​ and ​-.
 var validPluginName = regexp.MustCompile("^[A-Za-z0-9_-]+$")
 
+// validTemplateFunctionsPluginName validates the names of the plugins with
+// template functions, which are the namespace of their functions.
+var validTemplateFunctionsPluginName = regexp.MustCompile("^[A-Za-z][A-Za-z0-9]*$")
+
 // validatePluginData validates a plugin's YAML data.
 func validatePluginData(plug *Plugin, filepath string) error {
 	// When metadata section missing, initialize with no data
@@ -273,6 +299,15 @@ func validatePluginData(plug *Plugin, filepath string) error {
 		return fmt.Errorf("both platformHooks and hooks are set in %q", filepath)
 	}
 
+	if tf := plug.Metadata.TemplateFunctions; tf != nil {
+		if !validTemplateFunctionsPluginName.MatchString(plug.Metadata.Name) {
+			return fmt.Errorf("invalid plugin name at %q: the names of plugins with template functions are made of letters and digits, and start with a letter", filepath)
+		}
+		if tf.Module == "" || len(tf.Functions) == 0 {
+			return fmt.Errorf("templateFunctions requires a module and functions in %q", filepath)
+		}
+	}
+
 	// We could also validate SemVer, executable, and other fields should we so choose.
 	return nil
 }
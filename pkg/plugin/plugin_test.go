@@ -483,6 +483,18 @@ func TestValidatePluginData(t *testing.T) {
 		Install: "echo installing...",
 	}
 
+	// A mock plugin with template functions
+	mockWithFuncs := mockPlugin("ipmath")
+	mockWithFuncs.Metadata.TemplateFunctions = &TemplateFunctions{Module: "ipmath.wasm", Functions: []string{"cidrHost"}}
+
+	// A mock plugin with template functions and a name that is no namespace
+	mockWithFuncsBadName := mockPlugin("ip-math")
+	mockWithFuncsBadName.Metadata.TemplateFunctions = mockWithFuncs.Metadata.TemplateFunctions
+
+	// A mock plugin with template functions but no module
+	mockWithFuncsNoModule := mockPlugin("ipmath")
+	mockWithFuncsNoModule.Metadata.TemplateFunctions = &TemplateFunctions{Functions: []string{"cidrHost"}}
+
 	for i, item := range []struct {
 		pass bool
 		plug *Plugin
@@ -498,6 +510,9 @@ func TestValidatePluginData(t *testing.T) {
 		{true, mockLegacyCommand},        // Test legacy command metadata works
 		{false, mockWithCommand},         // Test platformCommand and command both set fails
 		{false, mockWithHooks},           // Test platformHooks and hooks both set fails
+		{true, mockWithFuncs},            // Test template functions work
+		{false, mockWithFuncsBadName},    // Test template functions require a namespace name
+		{false, mockWithFuncsNoModule},   // Test template functions require a module
 	} {
 		err := validatePluginData(item.plug, fmt.Sprintf("test-%d", i))
 		if item.pass && err != nil {
@@ -0,0 +1,250 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wasm runs the template functions of plugins implemented by
+// WebAssembly modules.
+//
+// A module exports its memory as "memory", an allocator
+//
+//	helm_alloc(size i32) i32
+//
+// returning the address of size bytes of its memory, and its functions as
+//
+//	<name>(ptr i32, len i32) i64
+//
+// Helm calls a function with the JSON array of its arguments at ptr, and the
+// function returns the address and the length of the JSON of its result,
+// as the high and the low 32 bits of its return value. A function fails by
+// calling the error function of the host API with its message.
+//
+// The host API, the "helm" module, is all the module can import:
+//
+//	error(ptr i32, len i32) fails the call with the message at ptr
+//	log(ptr i32, len i32)   logs the message at ptr at the debug level
+//
+// Modules have no access to the file system, the environment, the clock or
+// the network, and WASI is not available. Every call runs in a new instance
+// of the module, with bounded memory and time, so that functions cannot keep
+// state from one call to the next.
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+
+	"helm.sh/helm/v4/pkg/engine"
+	"helm.sh/helm/v4/pkg/plugin"
+)
+
+const (
+	// DefaultTimeout is the time a function call may take.
+	DefaultTimeout = 5 * time.Second
+	// DefaultMemoryLimitPages is the number of 64 KiB pages of memory of the
+	// instances of modules, 16 MiB.
+	DefaultMemoryLimitPages = 256
+)
+
+// Module is a compiled WebAssembly module whose exported functions are
+// called as template functions.
+type Module struct {
+	// Name is the name of the module in errors and logs.
+	Name string
+	// Timeout bounds the time of function calls. It defaults to
+	// DefaultTimeout.
+	Timeout time.Duration
+
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// Compile compiles the WebAssembly module of data, with memory limited to
+// memoryLimitPages pages, or to DefaultMemoryLimitPages if it is 0.
+func Compile(ctx context.Context, name string, data []byte, memoryLimitPages uint32) (*Module, error) {
+	if memoryLimitPages == 0 {
+		memoryLimitPages = DefaultMemoryLimitPages
+	}
+	config := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(memoryLimitPages)
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+
+	_, err := r.NewHostModuleBuilder("helm").
+		NewFunctionBuilder().WithFunc(hostError).Export("error").
+		NewFunctionBuilder().WithFunc(hostLog).Export("log").
+		Instantiate(ctx)
+	if err != nil {
+		r.Close(ctx)
+		return nil, errors.Wrap(err, "instantiating the host API")
+	}
+
+	compiled, err := r.CompileModule(ctx, data)
+	if err != nil {
+		r.Close(ctx)
+		return nil, errors.Wrapf(err, "compiling WebAssembly module %s", name)
+	}
+	return &Module{Name: name, runtime: r, compiled: compiled}, nil
+}
+
+// Close releases the resources of the module.
+func (m *Module) Close(ctx context.Context) error {
+	return m.runtime.Close(ctx)
+}
+
+// Func returns the template function calling the exported function name of
+// the module.
+func (m *Module) Func(name string) func(...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		ctx := context.Background()
+		out, err := m.Call(ctx, name, args)
+		if err != nil {
+			return nil, err
+		}
+		var result interface{}
+		if err := json.Unmarshal(out, &result); err != nil {
+			return nil, errors.Wrapf(err, "decoding the result of %s of %s", name, m.Name)
+		}
+		return result, nil
+	}
+}
+
+// callState is the state of a function call, which the host API changes.
+type callState struct {
+	module string
+	err    string
+}
+
+type callStateKey struct{}
+
+// Call calls the exported function name of a new instance of the module with
+// the JSON of args, and returns the JSON of its result.
+func (m *Module) Call(ctx context.Context, name string, args []interface{}) ([]byte, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	in, err := json.Marshal(args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encoding the arguments of %s of %s", name, m.Name)
+	}
+
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	state := &callState{module: m.Name}
+	ctx = context.WithValue(ctx, callStateKey{}, state)
+
+	// Instances are anonymous, so that calls can run concurrently.
+	mod, err := m.runtime.InstantiateModule(ctx, m.compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions())
+	if err != nil {
+		return nil, errors.Wrapf(err, "instantiating WebAssembly module %s", m.Name)
+	}
+	defer mod.Close(ctx)
+
+	alloc, fn := mod.ExportedFunction("helm_alloc"), mod.ExportedFunction(name)
+	if alloc == nil {
+		return nil, errors.Errorf("WebAssembly module %s does not export helm_alloc", m.Name)
+	}
+	if fn == nil {
+		return nil, errors.Errorf("WebAssembly module %s does not export %s", m.Name, name)
+	}
+
+	res, err := alloc.Call(ctx, uint64(len(in)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "calling %s of %s", name, m.Name)
+	}
+	ptr := uint32(res[0])
+	if !mod.Memory().Write(ptr, in) {
+		return nil, errors.Errorf("helm_alloc of %s returned memory out of range", m.Name)
+	}
+
+	res, err = fn.Call(ctx, uint64(ptr), uint64(len(in)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "calling %s of %s", name, m.Name)
+	}
+	if state.err != "" {
+		return nil, errors.Errorf("%s of %s: %s", name, m.Name, state.err)
+	}
+	out, ok := mod.Memory().Read(uint32(res[0]>>32), uint32(res[0]))
+	if !ok {
+		return nil, errors.Errorf("%s of %s returned a result out of range", name, m.Name)
+	}
+	// The memory of the instance is released once it is closed.
+	return append([]byte(nil), out...), nil
+}
+
+func hostError(ctx context.Context, mod api.Module, ptr, size uint32) {
+	state, _ := ctx.Value(callStateKey{}).(*callState)
+	msg, ok := mod.Memory().Read(ptr, size)
+	if state == nil {
+		return
+	}
+	if !ok {
+		state.err = "error message out of range"
+		return
+	}
+	state.err = string(msg)
+}
+
+func hostLog(ctx context.Context, mod api.Module, ptr, size uint32) {
+	state, _ := ctx.Value(callStateKey{}).(*callState)
+	msg, ok := mod.Memory().Read(ptr, size)
+	if state == nil || !ok {
+		return
+	}
+	slog.Debug("template function plugin", "module", state.module, "message", string(msg))
+}
+
+// RegisterPlugin registers the template functions of a plugin in reg, under
+// the name of the plugin. The module of the plugin is only compiled when a
+// function is first called.
+func RegisterPlugin(reg *engine.FuncRegistry, plug *plugin.Plugin) error {
+	tf := plug.Metadata.TemplateFunctions
+	if tf == nil {
+		return nil
+	}
+	load := sync.OnceValues(func() (*Module, error) {
+		data, err := os.ReadFile(filepath.Join(plug.Dir, tf.Module))
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading the template functions of plugin %s", plug.Metadata.Name)
+		}
+		return Compile(context.Background(), plug.Metadata.Name, data, 0)
+	})
+	for _, name := range tf.Functions {
+		name := name
+		err := reg.Register(plug.Metadata.Name, name, func(args ...interface{}) (interface{}, error) {
+			m, err := load()
+			if err != nil {
+				return nil, err
+			}
+			return m.Func(name)(args...)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
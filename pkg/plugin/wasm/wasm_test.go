@@ -0,0 +1,174 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/engine"
+	"helm.sh/helm/v4/pkg/plugin"
+)
+
+// vec encodes a WebAssembly vector of n items.
+func vec(n int, items ...byte) []byte {
+	return append([]byte{byte(n)}, items...)
+}
+
+func name(s string) []byte {
+	return append([]byte{byte(len(s))}, s...)
+}
+
+func section(id byte, content []byte) []byte {
+	return append([]byte{id, byte(len(content))}, content...)
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+// testModule returns a module of the template function ABI, whose memory
+// starts with "42nohello", exporting:
+//
+//	answer: logs "hello" and returns 42
+//	echo:   returns its arguments
+//	fail:   fails with "no"
+//	loop:   never returns
+func testModule() []byte {
+	body := func(code ...byte) []byte {
+		return append([]byte{byte(len(code) + 1), 0x00}, code...)
+	}
+	return concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		section(1, vec(3,
+			0x60, 0x01, 0x7f, 0x01, 0x7f, // (i32) -> i32
+			0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, // (i32, i32) -> i64
+			0x60, 0x02, 0x7f, 0x7f, 0x00, // (i32, i32) -> ()
+		)),
+		section(2, concat([]byte{2},
+			name("helm"), name("error"), []byte{0x00, 0x02},
+			name("helm"), name("log"), []byte{0x00, 0x02},
+		)),
+		section(3, vec(5, 0, 1, 1, 1, 1)),
+		section(5, vec(1, 0x00, 0x01)),
+		section(6, vec(1, 0x7f, 0x01, 0x41, 0x80, 0x08, 0x0b)), // mut i32 = 1024
+		section(7, concat([]byte{6},
+			name("memory"), []byte{0x02, 0x00},
+			name("helm_alloc"), []byte{0x00, 0x02},
+			name("answer"), []byte{0x00, 0x03},
+			name("echo"), []byte{0x00, 0x04},
+			name("fail"), []byte{0x00, 0x05},
+			name("loop"), []byte{0x00, 0x06},
+		)),
+		section(10, concat([]byte{5},
+			// helm_alloc: bump allocation from the global
+			body(0x23, 0x00, 0x23, 0x00, 0x20, 0x00, 0x6a, 0x24, 0x00, 0x0b),
+			// answer: log(4, 5); return 0<<32 | 2
+			body(0x41, 0x04, 0x41, 0x05, 0x10, 0x01, 0x42, 0x02, 0x0b),
+			// echo: return ptr<<32 | len
+			body(0x20, 0x00, 0xad, 0x42, 0x20, 0x86, 0x20, 0x01, 0xad, 0x84, 0x0b),
+			// fail: error(2, 2); return 0
+			body(0x41, 0x02, 0x41, 0x02, 0x10, 0x00, 0x42, 0x00, 0x0b),
+			// loop: loop forever
+			body(0x03, 0x40, 0x0c, 0x00, 0x0b, 0x00, 0x0b),
+		)),
+		section(11, concat([]byte{1, 0x00, 0x41, 0x00, 0x0b}, name("42nohello"))),
+	)
+}
+
+func TestModuleFunc(t *testing.T) {
+	ctx := context.Background()
+	m, err := Compile(ctx, "test", testModule(), 0)
+	require.NoError(t, err)
+	defer m.Close(ctx)
+
+	out, err := m.Func("answer")()
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), out)
+
+	out, err = m.Func("echo")("a", 1, map[string]interface{}{"b": true})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", float64(1), map[string]interface{}{"b": true}}, out)
+
+	_, err = m.Func("fail")()
+	assert.EqualError(t, err, "fail of test: no")
+
+	_, err = m.Func("missing")()
+	assert.EqualError(t, err, "WebAssembly module test does not export missing")
+}
+
+func TestModuleTimeout(t *testing.T) {
+	ctx := context.Background()
+	m, err := Compile(ctx, "test", testModule(), 0)
+	require.NoError(t, err)
+	defer m.Close(ctx)
+	m.Timeout = 10 * time.Millisecond
+
+	_, err = m.Func("loop")()
+	assert.ErrorContains(t, err, "calling loop of test")
+}
+
+func TestModuleWithoutWASI(t *testing.T) {
+	// A module importing fd_write of WASI.
+	wasi := concat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		section(1, vec(1, 0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f)),
+		section(2, concat([]byte{1}, name("wasi_snapshot_preview1"), name("fd_write"), []byte{0x00, 0x00})),
+	)
+	ctx := context.Background()
+	m, err := Compile(ctx, "wasi", wasi, 0)
+	require.NoError(t, err)
+	defer m.Close(ctx)
+
+	_, err = m.Call(ctx, "anything", nil)
+	assert.ErrorContains(t, err, "wasi_snapshot_preview1")
+}
+
+func TestRegisterPlugin(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "funcs.wasm"), testModule(), 0644))
+	plug := &plugin.Plugin{
+		Dir: dir,
+		Metadata: &plugin.Metadata{
+			Name: "acme",
+			TemplateFunctions: &plugin.TemplateFunctions{
+				Module:    "funcs.wasm",
+				Functions: []string{"answer", "echo"},
+			},
+		},
+	}
+	reg := engine.NewFuncRegistry()
+	require.NoError(t, RegisterPlugin(reg, plug))
+	assert.Equal(t, []string{"acme_answer", "acme_echo"}, reg.Names())
+
+	tpl := template.Must(template.New("t").Funcs(reg.FuncMap()).Parse(`{{ acme_answer }} {{ index (acme_echo "x" "y") 1 }}`))
+	var out strings.Builder
+	require.NoError(t, tpl.Execute(&out, nil))
+	assert.Equal(t, "42 y", out.String())
+}
@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+type chain []PostRenderer
+
+// NewChain returns a PostRenderer running renderers as a pipeline, in order,
+// each over the output of the one before. An error names the step that
+// failed. Renderers implementing fmt.Stringer are named by their String
+// method.
+func NewChain(renderers ...PostRenderer) PostRenderer {
+	return chain(renderers)
+}
+
+// Run runs the post-renderers of the chain in order.
+func (c chain) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return c.RunForRelease(renderedManifests, nil)
+}
+
+// RunForRelease runs the post-renderers of the chain in order, passing rel to
+// those that make use of it.
+func (c chain) RunForRelease(renderedManifests *bytes.Buffer, rel *Release) (*bytes.Buffer, error) {
+	for i, r := range c {
+		out, err := ForRelease(r, rel).Run(renderedManifests)
+		if err != nil {
+			return nil, errors.Wrapf(err, "post-renderer %d of %d (%s) failed", i+1, len(c), describe(r))
+		}
+		renderedManifests = out
+	}
+	return renderedManifests, nil
+}
+
+func (c chain) String() string {
+	var b bytes.Buffer
+	for i, r := range c {
+		if i > 0 {
+			b.WriteString(" | ")
+		}
+		b.WriteString(describe(r))
+	}
+	return b.String()
+}
+
+func describe(r PostRenderer) string {
+	if s, ok := r.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", r)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type replaceRenderer struct {
+	old, new string
+}
+
+func (r replaceRenderer) Run(in *bytes.Buffer) (*bytes.Buffer, error) {
+	return bytes.NewBufferString(strings.ReplaceAll(in.String(), r.old, r.new)), nil
+}
+
+type failingRenderer struct{}
+
+func (failingRenderer) Run(*bytes.Buffer) (*bytes.Buffer, error) {
+	return nil, errors.New("boom")
+}
+
+func (failingRenderer) String() string { return "failing" }
+
+func TestChain(t *testing.T) {
+	c := NewChain(replaceRenderer{"FOO", "BAR"}, replaceRenderer{"BAR", "BAZ"})
+	out, err := c.Run(bytes.NewBufferString("FOOTEST"))
+	require.NoError(t, err)
+	assert.Equal(t, "BAZTEST", out.String())
+
+	c = NewChain(replaceRenderer{"FOO", "BAR"}, failingRenderer{}, replaceRenderer{"BAR", "BAZ"})
+	_, err = c.Run(bytes.NewBufferString("FOOTEST"))
+	assert.EqualError(t, err, "post-renderer 2 of 3 (failing) failed: boom")
+}
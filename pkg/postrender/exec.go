@@ -18,9 +18,12 @@ package postrender
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 
 	"github.com/pkg/errors"
 )
@@ -44,7 +47,21 @@ func NewExec(binaryPath string, args ...string) (PostRenderer, error) {
 
 // Run the configured binary for the post render
 func (p *execRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return p.RunForRelease(renderedManifests, nil)
+}
+
+// RunForRelease runs the configured binary for the post render of rel. The
+// release is described to the binary with the environment variables listed
+// by releaseEnv.
+func (p *execRender) RunForRelease(renderedManifests *bytes.Buffer, rel *Release) (*bytes.Buffer, error) {
 	cmd := exec.Command(p.binaryPath, p.args...)
+	if rel != nil {
+		env, err := releaseEnv(rel)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Env = append(os.Environ(), env...)
+	}
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -73,6 +90,35 @@ func (p *execRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error)
 	return postRendered, nil
 }
 
+// releaseEnv describes a release to a post-renderer binary. The whole release
+// is given as JSON in HELM_RELEASE_METADATA, and its most used fields in
+// their own variables.
+func releaseEnv(rel *Release) ([]string, error) {
+	metadata, err := json.Marshal(rel)
+	if err != nil {
+		return nil, err
+	}
+	env := []string{
+		"HELM_RELEASE_NAME=" + rel.Name,
+		"HELM_RELEASE_NAMESPACE=" + rel.Namespace,
+		"HELM_RELEASE_REVISION=" + strconv.Itoa(rel.Revision),
+		"HELM_DRY_RUN=" + strconv.FormatBool(rel.DryRun),
+		"HELM_RELEASE_METADATA=" + string(metadata),
+	}
+	if rel.Chart != nil {
+		env = append(env,
+			"HELM_CHART_NAME="+rel.Chart.Name,
+			"HELM_CHART_VERSION="+rel.Chart.Version,
+			"HELM_CHART_APP_VERSION="+rel.Chart.AppVersion,
+		)
+	}
+	return env, nil
+}
+
+func (p *execRender) String() string {
+	return p.binaryPath
+}
+
 // getFullPath returns the full filepath to the binary to execute. If the path
 // does not contain any separators, it will search in $PATH, otherwise it will
 // resolve any relative paths to a fully qualified path
@@ -25,6 +25,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
 )
 
 const testingScript = `#!/bin/sh
@@ -121,6 +123,40 @@ func TestExecRun(t *testing.T) {
 	is.Contains(output.String(), "BARTEST")
 }
 
+func TestExecRunForRelease(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping on windows")
+	}
+	is := assert.New(t)
+	testpath := setupScript(t, `#!/bin/sh
+cat
+echo "$HELM_RELEASE_NAME $HELM_RELEASE_NAMESPACE $HELM_RELEASE_REVISION $HELM_DRY_RUN"
+echo "$HELM_CHART_NAME $HELM_CHART_VERSION $HELM_CHART_APP_VERSION"
+echo "$HELM_RELEASE_METADATA"
+`)
+
+	renderer, err := NewExec(testpath)
+	require.NoError(t, err)
+
+	rel := &Release{
+		Name:      "myrelease",
+		Namespace: "apps",
+		Revision:  3,
+		IsUpgrade: true,
+		DryRun:    true,
+		Chart:     &chart.Metadata{Name: "mychart", Version: "1.2.3", AppVersion: "4.5.6"},
+	}
+	output, err := ForRelease(renderer, rel).Run(bytes.NewBufferString("FOOTEST\n"))
+	is.NoError(err)
+	is.Contains(output.String(), "FOOTEST\nmyrelease apps 3 true\nmychart 1.2.3 4.5.6\n")
+	is.Contains(output.String(), `"isUpgrade":true`)
+
+	// Without a release, the variables are not set.
+	output, err = renderer.Run(bytes.NewBufferString("FOOTEST\n"))
+	is.NoError(err)
+	is.Contains(output.String(), "FOOTEST\n   \n")
+}
+
 func TestExecRunWithNoOutput(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		// the actual Run test uses a basic sed example, so skip this test on windows
@@ -170,6 +206,11 @@ func TestNewExecWithTwoArgsRun(t *testing.T) {
 
 func setupTestingScript(t *testing.T) (filepath string) {
 	t.Helper()
+	return setupScript(t, testingScript)
+}
+
+func setupScript(t *testing.T, script string) (filepath string) {
+	t.Helper()
 
 	tempdir := t.TempDir()
 
@@ -178,7 +219,7 @@ func setupTestingScript(t *testing.T) (filepath string) {
 		t.Fatalf("unable to create tempfile for testing: %s", err)
 	}
 
-	_, err = f.WriteString(testingScript)
+	_, err = f.WriteString(script)
 	if err != nil {
 		t.Fatalf("unable to write tempfile for testing: %s", err)
 	}
@@ -0,0 +1,138 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// KustomizeResourceFile is the name under which the rendered manifests are
+// added to the resources of a kustomization.
+const KustomizeResourceFile = "helm-rendered.yaml"
+
+type kustomizeRender struct {
+	dir  string
+	file string
+}
+
+// NewKustomize returns a PostRenderer that runs the kustomization in dir over
+// the rendered manifests, without calling out to a kustomize binary.
+//
+// The rendered manifests are added to the resources of the kustomization as
+// KustomizeResourceFile, so the kustomization only needs to hold the patches
+// and transformers to apply. Neither the kustomization nor its directory are
+// modified.
+func NewKustomize(dir string) (PostRenderer, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Kustomize resolves the files it reads through symbolic links.
+	if abs, err = filepath.EvalSymlinks(abs); err != nil {
+		return nil, errors.Wrapf(err, "unable to find kustomization directory %s", dir)
+	}
+	fs := filesys.MakeFsOnDisk()
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		if file := filepath.Join(abs, name); fs.Exists(file) {
+			return &kustomizeRender{dir: abs, file: file}, nil
+		}
+	}
+	return nil, errors.Errorf("no kustomization file found in %s", dir)
+}
+
+// Run builds the kustomization over the rendered manifests.
+func (k *kustomizeRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	disk := filesys.MakeFsOnDisk()
+	data, err := disk.ReadFile(k.file)
+	if err != nil {
+		return nil, err
+	}
+	var kustomization map[string]interface{}
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		return nil, errors.Wrapf(err, "unable to parse %s", k.file)
+	}
+	if kustomization == nil {
+		kustomization = map[string]interface{}{}
+	}
+	resources, _ := kustomization["resources"].([]interface{})
+	if !slices.Contains(resources, interface{}(KustomizeResourceFile)) {
+		kustomization["resources"] = append(resources, KustomizeResourceFile)
+	}
+	if data, err = yaml.Marshal(kustomization); err != nil {
+		return nil, err
+	}
+
+	fs := &overlayFS{
+		FileSystem: disk,
+		files: map[string][]byte{
+			k.file: data,
+			filepath.Join(k.dir, KustomizeResourceFile): renderedManifests.Bytes(),
+		},
+	}
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, k.dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error while running kustomization %s", k.dir)
+	}
+	out, err := resMap.AsYaml()
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, errors.Errorf("kustomization %s produced empty output", k.dir)
+	}
+	return bytes.NewBuffer(out), nil
+}
+
+func (k *kustomizeRender) String() string {
+	return "kustomize " + k.dir
+}
+
+// overlayFS is a file system whose files, keyed by absolute path, are read
+// in place of those on disk.
+type overlayFS struct {
+	filesys.FileSystem
+	files map[string][]byte
+}
+
+func (o *overlayFS) Exists(path string) bool {
+	if _, ok := o.files[path]; ok {
+		return true
+	}
+	return o.FileSystem.Exists(path)
+}
+
+func (o *overlayFS) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	if _, ok := o.files[path]; ok {
+		return filesys.ConfirmedDir(filepath.Dir(path)), filepath.Base(path), nil
+	}
+	return o.FileSystem.CleanedAbs(path)
+}
+
+func (o *overlayFS) ReadFile(path string) ([]byte, error) {
+	if data, ok := o.files[path]; ok {
+		return data, nil
+	}
+	return o.FileSystem.ReadFile(path)
+}
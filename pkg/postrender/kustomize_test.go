@@ -0,0 +1,63 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const renderedManifests = `---
+# Source: mychart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  mode: development
+`
+
+func TestKustomize(t *testing.T) {
+	pr, err := NewKustomize("testdata/kustomize/overlay")
+	require.NoError(t, err)
+
+	out, err := pr.Run(bytes.NewBufferString(renderedManifests))
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "mode: production")
+	assert.Contains(t, out.String(), "name: deny-all")
+	assert.Equal(t, 2, bytes.Count(out.Bytes(), []byte("team: platform")))
+
+	// The kustomization can run again, since it is left untouched.
+	_, err = pr.Run(bytes.NewBufferString(renderedManifests))
+	require.NoError(t, err)
+}
+
+func TestKustomizeErrors(t *testing.T) {
+	_, err := NewKustomize("testdata/kustomize/missing")
+	assert.Error(t, err)
+
+	_, err = NewKustomize("testdata")
+	assert.ErrorContains(t, err, "no kustomization file found")
+
+	pr, err := NewKustomize("testdata/kustomize/overlay")
+	require.NoError(t, err)
+	_, err = pr.Run(bytes.NewBufferString("kind: [unterminated"))
+	assert.Error(t, err)
+}
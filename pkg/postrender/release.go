@@ -0,0 +1,61 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// Release describes the release whose manifests are post-rendered.
+type Release struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+	IsInstall bool   `json:"isInstall"`
+	IsUpgrade bool   `json:"isUpgrade"`
+	// DryRun is set when nothing will be changed in the cluster.
+	DryRun bool            `json:"dryRun"`
+	Chart  *chart.Metadata `json:"chart"`
+}
+
+// ReleasePostRenderer is a PostRenderer that can adapt its transformations to
+// the release being rendered.
+type ReleasePostRenderer interface {
+	PostRenderer
+	// RunForRelease is like Run, for the manifests of rel.
+	RunForRelease(renderedManifests *bytes.Buffer, rel *Release) (*bytes.Buffer, error)
+}
+
+// ForRelease returns a PostRenderer running pr for the manifests of rel. It
+// returns pr itself if pr is not a ReleasePostRenderer.
+func ForRelease(pr PostRenderer, rel *Release) PostRenderer {
+	if r, ok := pr.(ReleasePostRenderer); ok && rel != nil {
+		return &releaseRender{r, rel}
+	}
+	return pr
+}
+
+type releaseRender struct {
+	ReleasePostRenderer
+	rel *Release
+}
+
+func (r *releaseRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return r.RunForRelease(renderedManifests, r.rel)
+}
@@ -59,6 +59,9 @@ type SumCollection struct {
 type Verification struct {
 	// SignedBy contains the entity that signed a chart.
 	SignedBy *openpgp.Entity
+	// Keyless describes the signer of a chart signed with Sigstore. It is set
+	// instead of SignedBy.
+	Keyless *KeylessSigner
 	// FileHash is the hash, prepended with the scheme, for the file that was verified.
 	FileHash string
 	// FileName is the name of the file that FileHash verifies.
@@ -0,0 +1,113 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	sgbundle "github.com/sigstore/sigstore-go/pkg/bundle"
+
+	"helm.sh/helm/v4/internal/sigstore"
+)
+
+// SigstoreBundleExt is the extension of the Sigstore bundle stored next to a
+// keyless signed chart archive, in place of a provenance file.
+const SigstoreBundleExt = ".sigstore.json"
+
+// SigstoreOptions are the constraints a keyless signature must satisfy.
+type SigstoreOptions struct {
+	// TrustedRoot is the path to the Sigstore trusted root holding the
+	// certificate authorities and transparency logs to trust.
+	TrustedRoot string
+	// CertificateIdentity is the identity the chart must be signed by, such
+	// as an email address or a CI workflow URI.
+	CertificateIdentity string
+	// CertificateOIDCIssuer is the OIDC issuer that must have authenticated
+	// the signer.
+	CertificateOIDCIssuer string
+}
+
+// Validate checks that the options constrain signatures enough to be verified.
+func (o SigstoreOptions) Validate() error {
+	if o.TrustedRoot == "" {
+		return errors.New("a Sigstore trusted root is required for keyless verification")
+	}
+	if o.CertificateIdentity == "" || o.CertificateOIDCIssuer == "" {
+		return errors.New("both the expected certificate identity and OIDC issuer are required for keyless verification")
+	}
+	return nil
+}
+
+// KeylessSigner describes who signed a chart with Sigstore.
+type KeylessSigner struct {
+	Identity string
+	Issuer   string
+	// LogIndex is the index of the signature in the transparency log.
+	LogIndex int64
+}
+
+// VerifySigstore checks that the Sigstore bundle at bundlepath holds a valid
+// keyless signature of the chart archive at chartpath, made by the identity in
+// opts.
+//
+// The bundle may be written by any Sigstore client. The signing certificate
+// must carry a certificate transparency timestamp, and the signature must be
+// included in a transparency log of the trusted root.
+func VerifySigstore(chartpath, bundlepath string, opts SigstoreOptions) (*Verification, error) {
+	ver := &Verification{}
+	if err := opts.Validate(); err != nil {
+		return ver, err
+	}
+	for _, fname := range []string{chartpath, bundlepath} {
+		if fi, err := os.Stat(fname); err != nil {
+			return ver, err
+		} else if fi.IsDir() {
+			return ver, errors.Errorf("%s cannot be a directory", fname)
+		}
+	}
+
+	root, err := sigstore.LoadTrustedRoot(opts.TrustedRoot)
+	if err != nil {
+		return ver, err
+	}
+	bundle, err := sgbundle.LoadJSONFromPath(bundlepath)
+	if err != nil {
+		return ver, errors.Wrapf(err, "parsing Sigstore bundle %s", bundlepath)
+	}
+	data, err := os.ReadFile(chartpath)
+	if err != nil {
+		return ver, err
+	}
+	result, err := sigstore.VerifyEntity(bundle, data, root, sigstore.VerifyOptions{
+		Identity: opts.CertificateIdentity,
+		Issuer:   opts.CertificateOIDCIssuer,
+	})
+	if err != nil {
+		return ver, err
+	}
+
+	ver.Keyless = &KeylessSigner{
+		Identity: result.Identity,
+		Issuer:   result.Issuer,
+		LogIndex: result.LogIndex,
+	}
+	ver.FileHash = "sha256:" + result.Digest
+	ver.FileName = filepath.Base(chartpath)
+	return ver, nil
+}
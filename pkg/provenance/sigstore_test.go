@@ -0,0 +1,73 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provenance
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifySigstoreOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   SigstoreOptions
+		expect string
+	}{
+		{
+			name:   "no trusted root",
+			opts:   SigstoreOptions{CertificateIdentity: "me@example.com", CertificateOIDCIssuer: "https://accounts.example.com"},
+			expect: "trusted root is required",
+		},
+		{
+			name:   "no issuer",
+			opts:   SigstoreOptions{TrustedRoot: "trusted_root.json", CertificateIdentity: "me@example.com"},
+			expect: "identity and OIDC issuer are required",
+		},
+		{
+			name:   "missing bundle",
+			opts:   SigstoreOptions{TrustedRoot: "trusted_root.json", CertificateIdentity: "me@example.com", CertificateOIDCIssuer: "https://accounts.example.com"},
+			expect: "no such file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifySigstore(testChartfile, testChartfile+SigstoreBundleExt, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.expect) {
+				t.Errorf("expected error containing %q, got %v", tt.expect, err)
+			}
+		})
+	}
+}
+
+func TestVerifySigstoreBundle(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "trusted_root.json")
+	if err := os.WriteFile(root, []byte(`{"mediaType":"application/vnd.dev.sigstore.trustedroot+json;version=0.1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(dir, "hashtest-1.2.3.tgz"+SigstoreBundleExt)
+	if err := os.WriteFile(bundle, []byte(`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := SigstoreOptions{TrustedRoot: root, CertificateIdentity: "me@example.com", CertificateOIDCIssuer: "https://accounts.example.com"}
+	_, err := VerifySigstore(testChartfile, bundle, opts)
+	if err == nil || !strings.Contains(err.Error(), "parsing Sigstore bundle") {
+		t.Errorf("expected error parsing the bundle, got %v", err)
+	}
+}
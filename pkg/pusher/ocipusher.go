@@ -26,6 +26,8 @@ import (
 
 	"github.com/pkg/errors"
 
+	"helm.sh/helm/v4/internal/sbom"
+	"helm.sh/helm/v4/internal/sigstore"
 	"helm.sh/helm/v4/internal/tlsutil"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	"helm.sh/helm/v4/pkg/registry"
@@ -85,6 +87,22 @@ func (pusher *OCIPusher) push(chartRef, href string) error {
 		}
 		pushOpts = append(pushOpts, registry.PushOptProvData(provBytes))
 	}
+	bundleRef := sigstore.BundleFileName(chartRef)
+	if _, err := os.Stat(bundleRef); err == nil {
+		bundleBytes, err := os.ReadFile(bundleRef)
+		if err != nil {
+			return err
+		}
+		pushOpts = append(pushOpts, registry.PushOptSigstoreBundle(bundleBytes))
+	}
+
+	if pusher.opts.withSBOM {
+		sbomOpts, err := sbomPushOptions(chartRef)
+		if err != nil {
+			return err
+		}
+		pushOpts = append(pushOpts, sbomOpts...)
+	}
 
 	ref := fmt.Sprintf("%s:%s",
 		path.Join(strings.TrimPrefix(href, fmt.Sprintf("%s://", registry.OCIScheme)), meta.Metadata.Name),
@@ -98,6 +116,25 @@ func (pusher *OCIPusher) push(chartRef, href string) error {
 	return err
 }
 
+// sbomPushOptions attaches every SBOM written next to the chart archive.
+func sbomPushOptions(chartRef string) ([]registry.PushOption, error) {
+	var opts []registry.PushOption
+	for _, format := range sbom.Formats {
+		data, err := os.ReadFile(sbom.FileName(chartRef, format))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, registry.PushOptSBOM(data, format.MediaType()))
+	}
+	if len(opts) == 0 {
+		return nil, errors.Errorf("no SBOM found for %s: package the chart with --sbom first", chartRef)
+	}
+	return opts, nil
+}
+
 // NewOCIPusher constructs a valid OCI client as a Pusher
 func NewOCIPusher(ops ...Option) (Pusher, error) {
 	var client OCIPusher
@@ -16,6 +16,7 @@ limitations under the License.
 package pusher
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -94,3 +95,24 @@ func TestNewOCIPusher(t *testing.T) {
 		t.Errorf("Expected NewOCIPusher to contain %p as RegistryClient, got %p", registryClient, op.opts.registryClient)
 	}
 }
+
+func TestSBOMPushOptions(t *testing.T) {
+	chartRef := filepath.Join(t.TempDir(), "mychart-0.1.0.tgz")
+
+	if _, err := sbomPushOptions(chartRef); err == nil {
+		t.Error("expected an error when no SBOM exists next to the chart")
+	}
+
+	for _, name := range []string{chartRef + ".spdx.json", chartRef + ".cdx.json"} {
+		if err := os.WriteFile(name, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	opts, err := sbomPushOptions(chartRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts) != 2 {
+		t.Errorf("expected both SBOMs to be attached, got %d", len(opts))
+	}
+}
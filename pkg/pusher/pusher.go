@@ -33,6 +33,7 @@ type options struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	withSBOM              bool
 }
 
 // Option allows specifying various settings configurable by the user for overriding the defaults
@@ -68,6 +69,13 @@ func WithPlainHTTP(plainHTTP bool) Option {
 	}
 }
 
+// WithSBOM attaches the SBOM files found next to the chart archive on push.
+func WithSBOM(withSBOM bool) Option {
+	return func(opts *options) {
+		opts.withSBOM = withSBOM
+	}
+}
+
 // Pusher is an interface to support upload to the specified URL.
 type Pusher interface {
 	// Push file content by url string
@@ -602,11 +602,13 @@ type (
 
 	// PushResult is the result returned upon successful push.
 	PushResult struct {
-		Manifest *descriptorPushSummary         `json:"manifest"`
-		Config   *descriptorPushSummary         `json:"config"`
-		Chart    *descriptorPushSummaryWithMeta `json:"chart"`
-		Prov     *descriptorPushSummary         `json:"prov"`
-		Ref      string                         `json:"ref"`
+		Manifest  *descriptorPushSummary         `json:"manifest"`
+		Config    *descriptorPushSummary         `json:"config"`
+		Chart     *descriptorPushSummaryWithMeta `json:"chart"`
+		Prov      *descriptorPushSummary         `json:"prov"`
+		SBOMs     []*descriptorPushSummary       `json:"sboms,omitempty"`
+		Signature *descriptorPushSummary         `json:"signature,omitempty"`
+		Ref       string                         `json:"ref"`
 	}
 
 	descriptorPushSummary struct {
@@ -620,9 +622,16 @@ type (
 	}
 
 	pushOperation struct {
-		provData     []byte
-		strictMode   bool
-		creationTime string
+		provData       []byte
+		sboms          []referrerData
+		sigstoreBundle []byte
+		strictMode     bool
+		creationTime   string
+	}
+
+	referrerData struct {
+		data      []byte
+		mediaType string
 	}
 )
 
@@ -702,12 +711,38 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 		return nil, err
 	}
 
+	// SBOMs and signatures are attached as artifacts referring to the chart
+	// manifest, rather than as layers, so that clients which do not know about
+	// them can still pull the chart.
+	var sbomDescriptors []ocispec.Descriptor
+	for _, sbom := range operation.sboms {
+		sbomDescriptor, err := attachReferrer(ctx, memoryStore, manifestDescriptor, sbom)
+		if err != nil {
+			return nil, err
+		}
+		sbomDescriptors = append(sbomDescriptors, sbomDescriptor)
+	}
+	var signatureDescriptor ocispec.Descriptor
+	if operation.sigstoreBundle != nil {
+		signatureDescriptor, err = attachReferrer(ctx, memoryStore, manifestDescriptor, referrerData{
+			data:      operation.sigstoreBundle,
+			mediaType: SigstoreBundleMediaType,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	repository, err := remote.NewRepository(parsedRef.String())
 	if err != nil {
 		return nil, err
 	}
 	repository.PlainHTTP = c.plainHTTP
 	repository.Client = c.authorizer
+	// Registries without the referrers API track referring artifacts in an
+	// index tagged after the chart manifest. Replaced indexes are left in
+	// place, as many registries do not allow deleting manifests.
+	repository.SkipReferrersGC = true
 
 	manifestDescriptor, err = oras.ExtendedCopy(ctx, memoryStore, parsedRef.String(), repository, parsedRef.String(), oras.DefaultExtendedCopyOptions)
 	if err != nil {
@@ -738,6 +773,18 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 			Size:   provDescriptor.Size,
 		}
 	}
+	if operation.sigstoreBundle != nil {
+		result.Signature = &descriptorPushSummary{
+			Digest: signatureDescriptor.Digest.String(),
+			Size:   signatureDescriptor.Size,
+		}
+	}
+	for _, d := range sbomDescriptors {
+		result.SBOMs = append(result.SBOMs, &descriptorPushSummary{
+			Digest: d.Digest.String(),
+			Size:   d.Size,
+		})
+	}
 	fmt.Fprintf(c.out, "Pushed: %s\n", result.Ref)
 	fmt.Fprintf(c.out, "Digest: %s\n", result.Manifest.Digest)
 	if strings.Contains(parsedRef.orasReference.Reference, "_") {
@@ -755,6 +802,36 @@ func PushOptProvData(provData []byte) PushOption {
 	}
 }
 
+// PushOptSBOM returns a function that attaches an SBOM of the given media
+// type to the chart on push
+func PushOptSBOM(data []byte, mediaType string) PushOption {
+	return func(operation *pushOperation) {
+		operation.sboms = append(operation.sboms, referrerData{data: data, mediaType: mediaType})
+	}
+}
+
+// PushOptSigstoreBundle returns a function that attaches a Sigstore signature
+// bundle to the chart on push
+func PushOptSigstoreBundle(bundle []byte) PushOption {
+	return func(operation *pushOperation) {
+		operation.sigstoreBundle = bundle
+	}
+}
+
+// attachReferrer stores data in an artifact manifest whose subject is the
+// given chart manifest, and returns the descriptor of the data.
+func attachReferrer(ctx context.Context, store *memory.Store, subject ocispec.Descriptor, r referrerData) (ocispec.Descriptor, error) {
+	desc, err := oras.PushBytes(ctx, store, r.mediaType, r.data)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	_, err = oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, r.mediaType, oras.PackManifestOptions{
+		Subject: &subject,
+		Layers:  []ocispec.Descriptor{desc},
+	})
+	return desc, err
+}
+
 // PushOptStrictMode returns a function that sets the strictMode setting on push
 func PushOptStrictMode(strictMode bool) PushOption {
 	return func(operation *pushOperation) {
@@ -769,6 +846,54 @@ func PushOptCreationTime(creationTime string) PushOption {
 	}
 }
 
+// PullSigstoreBundle returns the Sigstore signature bundle attached to a chart
+// by pushing it with PushOptSigstoreBundle.
+func (c *Client) PullSigstoreBundle(ref string) ([]byte, error) {
+	parsedRef, err := newReference(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	repository, err := remote.NewRepository(parsedRef.String())
+	if err != nil {
+		return nil, err
+	}
+	repository.PlainHTTP = c.plainHTTP
+	repository.Client = c.authorizer
+
+	ctx := context.Background()
+	subject, err := repository.Resolve(ctx, parsedRef.orasReference.Reference)
+	if err != nil {
+		return nil, err
+	}
+	var referrers []ocispec.Descriptor
+	err = repository.Referrers(ctx, subject, SigstoreBundleMediaType, func(found []ocispec.Descriptor) error {
+		referrers = append(referrers, found...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(referrers) == 0 {
+		return nil, errors.Errorf("no Sigstore signature is attached to %s", ref)
+	}
+
+	manifestData, err := content.FetchAll(ctx, repository, referrers[0])
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "parsing signature manifest %s", referrers[0].Digest)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == SigstoreBundleMediaType {
+			return content.FetchAll(ctx, repository, layer)
+		}
+	}
+	return nil, errors.Errorf("signature manifest %s holds no Sigstore bundle", referrers[0].Digest)
+}
+
 // Tags provides a sorted list all semver compliant tags for a given repository
 func (c *Client) Tags(ref string) ([]string, error) {
 	parsedReference, err := registry.ParseReference(ref)
@@ -32,6 +32,18 @@ const (
 	// ProvLayerMediaType is the reserved media type for Helm chart provenance files
 	ProvLayerMediaType = "application/vnd.cncf.helm.chart.provenance.v1.prov"
 
+	// SPDXMediaType is the media type of SPDX JSON SBOMs attached to charts
+	SPDXMediaType = "application/spdx+json"
+
+	// CycloneDXMediaType is the media type of CycloneDX JSON SBOMs attached to charts
+	CycloneDXMediaType = "application/vnd.cyclonedx+json"
+
+	// SigstoreBundleMediaType is the media type of Sigstore signature bundles attached to charts
+	SigstoreBundleMediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+
+	// ValuesMediaType is the artifact type of values files stored in a registry
+	ValuesMediaType = "application/vnd.cncf.helm.values.v1+yaml"
+
 	// LegacyChartLayerMediaType is the legacy reserved media type for Helm chart package content.
 	LegacyChartLayerMediaType = "application/tar+gzip"
 )
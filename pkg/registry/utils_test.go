@@ -261,6 +261,39 @@ func testPush(suite *TestSuite) {
 	_, err = suite.RegistryClient.Pull(ref)
 	suite.Nil(err, "no error pulling a simple chart")
 
+	// push with an SBOM and a signature attached as referring artifacts
+	sbomData := []byte(`{"spdxVersion":"SPDX-2.3"}`)
+	bundleData := []byte(`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json"}`)
+	result, err := suite.RegistryClient.Push(chartData, ref,
+		PushOptSBOM(sbomData, SPDXMediaType), PushOptSigstoreBundle(bundleData), PushOptCreationTime(testingChartCreationTime))
+	suite.Nil(err, "no error pushing good ref with SBOM and signature")
+	suite.Require().Len(result.SBOMs, 1)
+	suite.Equal(int64(len(sbomData)), result.SBOMs[0].Size)
+	suite.Require().NotNil(result.Signature)
+	suite.Equal(int64(len(bundleData)), result.Signature.Size)
+
+	_, err = suite.RegistryClient.Pull(ref)
+	suite.Nil(err, "no error pulling a chart with an SBOM attached")
+
+	pulledBundle, err := suite.RegistryClient.PullSigstoreBundle(ref)
+	suite.Nil(err, "no error pulling the attached signature")
+	suite.Equal(bundleData, pulledBundle)
+
+	_, err = suite.RegistryClient.PullValues(ref)
+	suite.ErrorContains(err, "is not a values file", "error pulling a chart as a values file")
+
+	// push and pull a values file
+	valuesData := []byte("replicaCount: 3\n")
+	valuesRef := fmt.Sprintf("%s/testrepo/values/production:1.0", suite.DockerRegistryHost)
+	_, err = suite.RegistryClient.PushValues(valuesData, valuesRef)
+	suite.Nil(err, "no error pushing a values file")
+	pulledValues, err := suite.RegistryClient.PullValues(valuesRef)
+	suite.Nil(err, "no error pulling a values file")
+	suite.Equal(valuesData, pulledValues)
+
+	_, err = suite.RegistryClient.PushValues(valuesData, fmt.Sprintf("%s/testrepo/values/production", suite.DockerRegistryHost))
+	suite.ErrorContains(err, "has no tag")
+
 	// Load another test chart
 	chartData, err = os.ReadFile("../downloader/testdata/signtest-0.1.0.tgz")
 	suite.Nil(err, "no error loading test chart")
@@ -273,7 +306,7 @@ func testPush(suite *TestSuite) {
 
 	// push with prov
 	ref = fmt.Sprintf("%s/testrepo/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
-	result, err := suite.RegistryClient.Push(chartData, ref, PushOptProvData(provData), PushOptCreationTime(testingChartCreationTime))
+	result, err = suite.RegistryClient.Push(chartData, ref, PushOptProvData(provData), PushOptCreationTime(testingChartCreationTime))
 	suite.Nil(err, "no error pushing good ref with prov")
 
 	_, err = suite.RegistryClient.Pull(ref, PullOptWithProv(true))
@@ -0,0 +1,130 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// PushValues uploads a values file to a registry, as an artifact holding a
+// single layer of ValuesMediaType. The reference must have a tag.
+//
+// The same artifact can be pushed with other OCI tools, for example:
+//
+//	oras push --artifact-type application/vnd.cncf.helm.values.v1+yaml \
+//	    registry.example.com/values/production:1.0 \
+//	    values.yaml:application/vnd.cncf.helm.values.v1+yaml
+func (c *Client) PushValues(data []byte, ref string) (*PushResult, error) {
+	parsedRef, err := newReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	if parsedRef.Tag == "" {
+		return nil, errors.Errorf("values reference %s has no tag", ref)
+	}
+
+	ctx := context.Background()
+	memoryStore := memory.New()
+	valuesDescriptor, err := oras.PushBytes(ctx, memoryStore, ValuesMediaType, data)
+	if err != nil {
+		return nil, err
+	}
+	manifestDescriptor, err := oras.PackManifest(ctx, memoryStore, oras.PackManifestVersion1_1, ValuesMediaType, oras.PackManifestOptions{
+		Layers: []ocispec.Descriptor{valuesDescriptor},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := memoryStore.Tag(ctx, manifestDescriptor, parsedRef.String()); err != nil {
+		return nil, err
+	}
+
+	repository, err := c.repository(parsedRef)
+	if err != nil {
+		return nil, err
+	}
+	manifestDescriptor, err = oras.Copy(ctx, memoryStore, parsedRef.String(), repository, parsedRef.String(), oras.DefaultCopyOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PushResult{
+		Manifest: &descriptorPushSummary{
+			Digest: manifestDescriptor.Digest.String(),
+			Size:   manifestDescriptor.Size,
+		},
+		Ref: parsedRef.String(),
+	}
+	fmt.Fprintf(c.out, "Pushed: %s\n", result.Ref)
+	fmt.Fprintf(c.out, "Digest: %s\n", result.Manifest.Digest)
+	return result, nil
+}
+
+// PullValues downloads a values file pushed with PushValues.
+func (c *Client) PullValues(ref string) ([]byte, error) {
+	parsedRef, err := newReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	repository, err := c.repository(parsedRef)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := parsedRef.Tag
+	if parsedRef.Digest != "" {
+		reference = parsedRef.Digest
+	}
+
+	ctx := context.Background()
+	desc, manifestData, err := oras.FetchBytes(ctx, repository, reference, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, err
+	}
+	if desc.MediaType != ocispec.MediaTypeImageManifest {
+		return nil, errors.Errorf("%s is not a values file: unsupported media type %q", ref, desc.MediaType)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "parsing manifest of %s", ref)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == ValuesMediaType {
+			return content.FetchAll(ctx, repository, layer)
+		}
+	}
+	return nil, errors.Errorf("%s is not a values file: no layer of media type %q", ref, ValuesMediaType)
+}
+
+func (c *Client) repository(ref reference) (*remote.Repository, error) {
+	repository, err := remote.NewRepository(ref.String())
+	if err != nil {
+		return nil, err
+	}
+	repository.PlainHTTP = c.plainHTTP
+	repository.Client = c.authorizer
+	return repository, nil
+}
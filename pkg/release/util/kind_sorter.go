@@ -18,13 +18,106 @@ package util
 
 import (
 	"sort"
+	"strconv"
 
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
+// ResourceWeightAnnotation is the annotation name for the weight of a
+// resource. Resources with a lower weight are installed before, and
+// uninstalled after, resources with a higher weight regardless of their kind.
+// This allows custom resources to be ordered after the operators serving
+// them. Resources without the annotation have a weight of 0.
+const ResourceWeightAnnotation = "helm.sh/resource-weight"
+
+// UninstallWeightAnnotation is the annotation name for the uninstall weight
+// of a resource, which overrides its ResourceWeightAnnotation when the
+// release is uninstalled. Resources with a lower uninstall weight are deleted,
+// and gone from the cluster, before resources with a higher uninstall weight
+// are deleted. This allows custom resources to be deleted while the operator
+// running their finalizers still runs. Resources without the annotation have
+// the opposite of their resource weight as uninstall weight.
+const UninstallWeightAnnotation = "helm.sh/uninstall-weight"
+
+// KindSorter determines the order in which manifests are installed or
+// uninstalled.
+type KindSorter interface {
+	// Less reports whether the manifest with head a must be processed
+	// before the manifest with head b.
+	Less(a, b *SimpleHead) bool
+}
+
 // KindSortOrder is an ordering of Kinds.
 type KindSortOrder []string
 
+// Less implements KindSorter. Kinds not in the ordering come last, sorted
+// alphabetically.
+func (k KindSortOrder) Less(a, b *SimpleHead) bool {
+	return lessByKind(a, b, a.Kind, b.Kind, k)
+}
+
+// WeightedKindSorter orders manifests by ResourceWeightAnnotation first and by
+// Order for manifests of equal weight.
+type WeightedKindSorter struct {
+	// Order sorts manifests of equal weight.
+	Order KindSorter
+	// Descending processes manifests with a higher weight first, as is
+	// needed when uninstalling. The manifests are then ordered by their
+	// UninstallWeight, so that UninstallWeightAnnotation overrides their
+	// weight.
+	Descending bool
+}
+
+// Less implements KindSorter.
+func (w WeightedKindSorter) Less(a, b *SimpleHead) bool {
+	weightA, weightB := ResourceWeight(a), ResourceWeight(b)
+	if w.Descending {
+		weightA, weightB = UninstallWeight(a), UninstallWeight(b)
+	}
+	if weightA != weightB {
+		return weightA < weightB
+	}
+	return w.Order.Less(a, b)
+}
+
+// ResourceWeight returns the weight set by ResourceWeightAnnotation, or 0 if
+// it is not set or is not an integer.
+func ResourceWeight(head *SimpleHead) int {
+	if head == nil || head.Metadata == nil {
+		return 0
+	}
+	weight, err := strconv.Atoi(head.Metadata.Annotations[ResourceWeightAnnotation])
+	if err != nil {
+		return 0
+	}
+	return weight
+}
+
+// UninstallWeight returns the weight set by UninstallWeightAnnotation, or the
+// opposite of the ResourceWeight if it is not set or is not an integer.
+func UninstallWeight(head *SimpleHead) int {
+	if head != nil && head.Metadata != nil {
+		if weight, err := strconv.Atoi(head.Metadata.Annotations[UninstallWeightAnnotation]); err == nil {
+			return weight
+		}
+	}
+	return -ResourceWeight(head)
+}
+
+// ReverseKindSorter returns a KindSorter processing manifests in the reverse of the
+// order of s. Manifests s considers equal keep their order.
+func ReverseKindSorter(s KindSorter) KindSorter {
+	return reverseKindSorter{s}
+}
+
+type reverseKindSorter struct {
+	KindSorter
+}
+
+func (r reverseKindSorter) Less(a, b *SimpleHead) bool {
+	return r.KindSorter.Less(b, a)
+}
+
 // InstallOrder is the order in which manifests should be installed (by Kind).
 //
 // Those occurring earlier in the list get installed before those occurring later in the list.
@@ -114,12 +207,26 @@ var UninstallOrder KindSortOrder = []string{
 	"PriorityClass",
 }
 
+// InstallSorter is the default KindSorter for installing manifests. It orders
+// manifests by ResourceWeightAnnotation, then by InstallOrder.
+var InstallSorter KindSorter = WeightedKindSorter{Order: InstallOrder}
+
+// UninstallSorter is the default KindSorter for uninstalling manifests. It
+// orders manifests by UninstallWeight, then in the reverse of InstallOrder,
+// so that the kinds InstallOrder does not list, such as custom resources, are
+// uninstalled first.
+var UninstallSorter KindSorter = WeightedKindSorter{Order: ReverseKindSorter(InstallOrder), Descending: true}
+
+// KindUninstallSorter orders manifests by UninstallWeight, then by
+// UninstallOrder, uninstalling the kinds UninstallOrder does not list last.
+var KindUninstallSorter KindSorter = WeightedKindSorter{Order: UninstallOrder, Descending: true}
+
 // sort manifests by kind.
 //
 // Results are sorted by 'ordering', keeping order of items with equal kind/priority
-func sortManifestsByKind(manifests []Manifest, ordering KindSortOrder) []Manifest {
+func sortManifestsByKind(manifests []Manifest, ordering KindSorter) []Manifest {
 	sort.SliceStable(manifests, func(i, j int) bool {
-		return lessByKind(manifests[i], manifests[j], manifests[i].Head.Kind, manifests[j].Head.Kind, ordering)
+		return ordering.Less(manifests[i].Head, manifests[j].Head)
 	})
 
 	return manifests
@@ -128,10 +235,10 @@ func sortManifestsByKind(manifests []Manifest, ordering KindSortOrder) []Manifes
 // sort hooks by kind, using an out-of-place sort to preserve the input parameters.
 //
 // Results are sorted by 'ordering', keeping order of items with equal kind/priority
-func sortHooksByKind(hooks []*release.Hook, ordering KindSortOrder) []*release.Hook {
+func sortHooksByKind(hooks []*release.Hook, ordering KindSorter) []*release.Hook {
 	h := hooks
 	sort.SliceStable(h, func(i, j int) bool {
-		return lessByKind(h[i], h[j], h[i].Kind, h[j].Kind, ordering)
+		return ordering.Less(&SimpleHead{Kind: h[i].Kind}, &SimpleHead{Kind: h[j].Kind})
 	})
 
 	return h
@@ -18,6 +18,7 @@ package util
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	release "helm.sh/helm/v4/pkg/release/v1"
@@ -345,3 +346,72 @@ func TestKindSorterForHooks(t *testing.T) {
 		})
 	}
 }
+
+func TestWeightedKindSorter(t *testing.T) {
+	files := map[string]string{
+		"templates/operator.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: operator
+`,
+		"templates/widget.yaml": `apiVersion: example.io/v1
+kind: Widget
+metadata:
+  name: widget
+  annotations:
+    helm.sh/resource-weight: "10"
+`,
+		"templates/config.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  annotations:
+    helm.sh/resource-weight: "-5"
+`,
+		"templates/sa.yaml": `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: sa
+  annotations:
+    helm.sh/resource-weight: "not-a-number"
+`,
+		"templates/gadget.yaml": `apiVersion: example.io/v1
+kind: Gadget
+metadata:
+  name: gadget
+`,
+		"templates/service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: service
+  annotations:
+    helm.sh/resource-weight: "10"
+    helm.sh/uninstall-weight: "1"
+`,
+	}
+
+	for _, test := range []struct {
+		description string
+		sorter      KindSorter
+		expected    string
+	}{
+		{"install", InstallSorter, "config,sa,operator,gadget,service,widget"},
+		{"uninstall", UninstallSorter, "widget,gadget,operator,sa,service,config"},
+		{"uninstall by kind", KindUninstallSorter, "widget,operator,sa,gadget,service,config"},
+		{"kinds only", InstallOrder, "sa,config,service,operator,gadget,widget"},
+	} {
+		t.Run(test.description, func(t *testing.T) {
+			_, manifests, err := SortManifests(files, nil, test.sorter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, m := range manifests {
+				names = append(names, m.Head.Metadata.Name)
+			}
+			if got := strings.Join(names, ","); got != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
@@ -17,13 +17,15 @@ limitations under the License.
 package util
 
 import (
+	"fmt"
 	"log/slog"
 	"path"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/pkg/errors"
 	"sigs.k8s.io/yaml"
 
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
@@ -41,6 +43,71 @@ type Manifest struct {
 type manifestFile struct {
 	entries map[string]string
 	path    string
+	// content is the rendered content the entries are split from.
+	content string
+}
+
+// YAMLParseError is the error of a rendered template with a document that is
+// not valid YAML.
+type YAMLParseError struct {
+	// Path is the path of the template, such as mychart/templates/pod.yaml.
+	Path string
+	// Line is the line of the rendered template the parser stopped at. It is
+	// zero when the parser does not report a line.
+	Line int
+	// Content is the content of the line.
+	Content string
+	// TemplateLine is the line of the template the line was rendered from,
+	// when it is known.
+	TemplateLine int
+	// References are the references to values of the line of the template.
+	References []string
+	Err        error
+}
+
+func (e *YAMLParseError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "YAML parse error on %s: %s", e.Path, e.Err)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "\n  at line %d of the rendered template: %s", e.Line, e.Content)
+	}
+	if e.TemplateLine > 0 {
+		fmt.Fprintf(&b, "\n  rendered from line %d of the template", e.TemplateLine)
+		if len(e.References) > 0 {
+			fmt.Fprintf(&b, " using %s", strings.Join(e.References, ", "))
+		}
+	}
+	return b.String()
+}
+
+func (e *YAMLParseError) Unwrap() error {
+	return e.Err
+}
+
+// yamlErrorLinePattern matches the line of the errors of the YAML parser, such
+// as "yaml: line 11: could not find expected ':'".
+var yamlErrorLinePattern = regexp.MustCompile(`yaml: line (\d+):`)
+
+// newYAMLParseError returns the error of an entry of a file, located in the
+// rendered file. The parser reports the lines of the entry, which starts at
+// the given offset of the file.
+func (file *manifestFile) newYAMLParseError(entry string, offset int, err error) *YAMLParseError {
+	parseErr := &YAMLParseError{Path: file.path, Err: err}
+	parts := yamlErrorLinePattern.FindStringSubmatch(err.Error())
+	if parts == nil || offset < 0 {
+		return parseErr
+	}
+	line, _ := strconv.Atoi(parts[1])
+	// The parser reports errors found at the end of a line, such as missing
+	// colons, on the next one; report the last line of the entry that has
+	// content.
+	entryLines := strings.Split(entry, "\n")
+	for line > len(entryLines) || (line > 1 && strings.TrimSpace(entryLines[line-1]) == "") {
+		line--
+	}
+	parseErr.Line = strings.Count(file.content[:offset], "\n") + line
+	parseErr.Content = strings.TrimSpace(entryLines[line-1])
+	return parseErr
 }
 
 // result is an intermediate structure used during sorting.
@@ -61,6 +128,8 @@ var events = map[string]release.HookEvent{
 	release.HookPreRollback.String():  release.HookPreRollback,
 	release.HookPostRollback.String(): release.HookPostRollback,
 	release.HookTest.String():         release.HookTest,
+	release.HookPostRender.String():   release.HookPostRender,
+	release.HookPreWait.String():      release.HookPreWait,
 	// Support test-success for backward compatibility with Helm 2 tests
 	"test-success": release.HookTest,
 }
@@ -74,7 +143,10 @@ var events = map[string]release.HookEvent{
 //
 // Files that do not parse into the expected format are simply placed into a map and
 // returned.
-func SortManifests(files map[string]string, _ chartutil.VersionSet, ordering KindSortOrder) ([]*release.Hook, []Manifest, error) {
+//
+// Hooks and manifests are sorted by 'ordering', such as InstallSorter or
+// UninstallSorter.
+func SortManifests(files map[string]string, _ chartutil.VersionSet, ordering KindSorter) ([]*release.Hook, []Manifest, error) {
 	result := &result{}
 
 	var sortedFilePaths []string
@@ -99,6 +171,7 @@ func SortManifests(files map[string]string, _ chartutil.VersionSet, ordering Kin
 		manifestFile := &manifestFile{
 			entries: SplitManifests(content),
 			path:    filePath,
+			content: content,
 		}
 
 		if err := manifestFile.sort(result); err != nil {
@@ -136,6 +209,24 @@ func SortManifests(files map[string]string, _ chartutil.VersionSet, ordering Kin
 //	 metadata:
 //			annotations:
 //				helm.sh/hook-output-log-policy: hook-succeeded,hook-failed
+//
+// To determine how long to wait for the hook and how many times to retry it when it fails, it looks for a YAML
+// structure like this:
+//
+//	 kind: Job
+//	 apiVersion: batch/v1
+//	 metadata:
+//			annotations:
+//				helm.sh/hook-timeout: 10m
+//				helm.sh/hook-retries: "2"
+//
+// To determine where the hook publishes its outputs, it looks for a YAML structure like this:
+//
+//	 kind: Job
+//	 apiVersion: batch/v1
+//	 metadata:
+//			annotations:
+//				helm.sh/hook-output: configmap/allocated-ids
 func (file *manifestFile) sort(result *result) error {
 	// Go through manifests in order found in file (function `SplitManifests` creates integer-sortable keys)
 	var sortedEntryKeys []string
@@ -144,12 +235,24 @@ func (file *manifestFile) sort(result *result) error {
 	}
 	sort.Sort(BySplitManifestsOrder(sortedEntryKeys))
 
+	// offset is the offset in the file of the entry, to locate errors.
+	offset := 0
 	for _, entryKey := range sortedEntryKeys {
 		m := file.entries[entryKey]
+		if offset >= 0 {
+			if i := strings.Index(file.content[offset:], m); i >= 0 {
+				offset += i
+			} else {
+				offset = -1
+			}
+		}
 
 		var entry SimpleHead
 		if err := yaml.Unmarshal([]byte(m), &entry); err != nil {
-			return errors.Wrapf(err, "YAML parse error on %s", file.path)
+			return file.newYAMLParseError(m, offset, err)
+		}
+		if offset >= 0 {
+			offset += len(m)
 		}
 
 		if !hasAnyAnnotation(entry) {
@@ -182,6 +285,9 @@ func (file *manifestFile) sort(result *result) error {
 			Weight:            hw,
 			DeletePolicies:    []release.HookDeletePolicy{},
 			OutputLogPolicies: []release.HookOutputLogPolicy{},
+			Timeout:           calculateHookTimeout(entry),
+			Retries:           calculateHookRetries(entry),
+			Output:            entry.Metadata.Annotations[release.HookOutputAnnotation],
 		}
 
 		isUnknownHook := false
@@ -233,6 +339,42 @@ func calculateHookWeight(entry SimpleHead) int {
 	return hw
 }
 
+// calculateHookTimeout finds the timeout in the hook timeout annotation, either
+// as a duration such as "5m" or as a number of seconds.
+//
+// If no valid timeout is found, the timeout of the operation applies
+func calculateHookTimeout(entry SimpleHead) time.Duration {
+	hts, ok := entry.Metadata.Annotations[release.HookTimeoutAnnotation]
+	if !ok {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(hts); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	ht, err := time.ParseDuration(hts)
+	if err != nil || ht <= 0 {
+		slog.Warn("ignoring invalid hook timeout", "name", entry.Metadata.Name, "timeout", hts)
+		return 0
+	}
+	return ht
+}
+
+// calculateHookRetries finds the number of retries in the hook retries annotation.
+//
+// If no number is found, the hook is not retried
+func calculateHookRetries(entry SimpleHead) int {
+	hrs, ok := entry.Metadata.Annotations[release.HookRetriesAnnotation]
+	if !ok {
+		return 0
+	}
+	hr, err := strconv.Atoi(hrs)
+	if err != nil || hr < 0 {
+		slog.Warn("ignoring invalid hook retries", "name", entry.Metadata.Name, "retries", hrs)
+		return 0
+	}
+	return hr
+}
+
 // operateAnnotationValues finds the given annotation and runs the operate function with the value of that annotation
 func operateAnnotationValues(entry SimpleHead, annotation string, operate func(p string)) {
 	if dps, ok := entry.Metadata.Annotations[annotation]; ok {
@@ -19,6 +19,7 @@ package util
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"sigs.k8s.io/yaml"
 
@@ -225,3 +226,58 @@ metadata:
 		}
 	}
 }
+
+func TestHookTimeoutAndRetries(t *testing.T) {
+	for _, tc := range []struct {
+		annotations map[string]string
+		timeout     time.Duration
+		retries     int
+	}{
+		{annotations: map[string]string{}, timeout: 0, retries: 0},
+		{annotations: map[string]string{release.HookTimeoutAnnotation: "10m", release.HookRetriesAnnotation: "3"}, timeout: 10 * time.Minute, retries: 3},
+		{annotations: map[string]string{release.HookTimeoutAnnotation: "90"}, timeout: 90 * time.Second, retries: 0},
+		{annotations: map[string]string{release.HookTimeoutAnnotation: "soon", release.HookRetriesAnnotation: "-1"}, timeout: 0, retries: 0},
+	} {
+		entry := SimpleHead{Metadata: &struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		}{Name: "hook", Annotations: tc.annotations}}
+		if timeout := calculateHookTimeout(entry); timeout != tc.timeout {
+			t.Errorf("expected timeout %s for %v, got %s", tc.timeout, tc.annotations, timeout)
+		}
+		if retries := calculateHookRetries(entry); retries != tc.retries {
+			t.Errorf("expected %d retries for %v, got %d", tc.retries, tc.annotations, retries)
+		}
+	}
+}
+
+func TestSortManifestsYAMLParseError(t *testing.T) {
+	files := map[string]string{
+		"mychart/templates/configmaps.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: second
+data:
+  greeting: hello: world
+`,
+	}
+	_, _, err := SortManifests(files, nil, InstallOrder)
+	parseErr, ok := err.(*YAMLParseError)
+	if !ok {
+		t.Fatalf("expected a YAML parse error, got %v", err)
+	}
+	if parseErr.Path != "mychart/templates/configmaps.yaml" {
+		t.Errorf("expected the path of the template, got %q", parseErr.Path)
+	}
+	if parseErr.Line != 11 {
+		t.Errorf("expected line 11 of the rendered template, got %d", parseErr.Line)
+	}
+	if parseErr.Content != "greeting: hello: world" {
+		t.Errorf("expected the content of the line, got %q", parseErr.Content)
+	}
+}
@@ -0,0 +1,26 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// Checkpoint records the progress of applying the resources of a release, so
+// that an interrupted upgrade can be resumed where it stopped.
+type Checkpoint struct {
+	// Total is the number of resources of the release.
+	Total int `json:"total"`
+	// Applied are the resources applied so far, identified as
+	// "apiVersion/Kind/namespace/name".
+	Applied []string `json:"applied,omitempty"`
+}
@@ -0,0 +1,39 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import "k8s.io/apimachinery/pkg/runtime"
+
+// DryRun is the outcome of submitting the resources of a release to the API
+// server with server-side dry-run, which runs the admission webhooks without
+// persisting anything.
+type DryRun struct {
+	// Objects are the objects the server would store for the accepted
+	// resources, as mutated by the admission webhooks.
+	Objects []runtime.Object `json:"objects,omitempty"`
+	// Rejections are the resources the server refused.
+	Rejections []DryRunRejection `json:"rejections,omitempty"`
+}
+
+// DryRunRejection is a resource the server refused with server-side dry-run,
+// such as because of an admission webhook.
+type DryRunRejection struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Reason is the error returned by the server.
+	Reason string `json:"reason"`
+}
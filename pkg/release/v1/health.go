@@ -0,0 +1,28 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// ResourceHealth is the health of the live object of a resource of a release.
+type ResourceHealth struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Status is one of "Ready", "InProgress", "Failed", "Missing" and
+	// "Unknown".
+	Status string `json:"status"`
+	// Message explains the status, such as why the resource is not ready.
+	Message string `json:"message,omitempty"`
+}
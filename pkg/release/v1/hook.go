@@ -17,6 +17,8 @@ limitations under the License.
 package v1
 
 import (
+	stdtime "time"
+
 	"helm.sh/helm/v4/pkg/time"
 )
 
@@ -34,6 +36,12 @@ const (
 	HookPreRollback  HookEvent = "pre-rollback"
 	HookPostRollback HookEvent = "post-rollback"
 	HookTest         HookEvent = "test"
+	// HookPostRender fires on install and upgrade once the manifests are
+	// rendered, before the pre-install and pre-upgrade hooks.
+	HookPostRender HookEvent = "post-render"
+	// HookPreWait fires on install, upgrade and rollback once the resources
+	// are applied, before waiting for them to be ready.
+	HookPreWait HookEvent = "pre-wait"
 )
 
 func (x HookEvent) String() string { return string(x) }
@@ -73,6 +81,15 @@ const HookDeleteAnnotation = "helm.sh/hook-delete-policy"
 // HookOutputLogAnnotation is the label name for the output log policy for a hook
 const HookOutputLogAnnotation = "helm.sh/hook-output-log-policy"
 
+// HookTimeoutAnnotation is the label name for the time to wait for a hook
+const HookTimeoutAnnotation = "helm.sh/hook-timeout"
+
+// HookRetriesAnnotation is the label name for the number of times a failed hook is retried
+const HookRetriesAnnotation = "helm.sh/hook-retries"
+
+// HookOutputAnnotation is the label name for where a hook publishes its outputs
+const HookOutputAnnotation = "helm.sh/hook-output"
+
 // Hook defines a hook object.
 type Hook struct {
 	Name string `json:"name,omitempty"`
@@ -92,6 +109,13 @@ type Hook struct {
 	DeletePolicies []HookDeletePolicy `json:"delete_policies,omitempty"`
 	// OutputLogPolicies defines whether we should copy hook logs back to main process
 	OutputLogPolicies []HookOutputLogPolicy `json:"output_log_policies,omitempty"`
+	// Timeout is the time to wait for the hook, overriding the timeout of the operation when set
+	Timeout stdtime.Duration `json:"timeout,omitempty"`
+	// Retries is the number of times the hook is run again after failing
+	Retries int `json:"retries,omitempty"`
+	// Output is where the hook publishes its outputs: configmap/<name>, or
+	// termination-message for the termination messages of its pods
+	Output string `json:"output,omitempty"`
 }
 
 // A HookExecution records the result for the last execution of a hook for a given release.
@@ -102,6 +126,8 @@ type HookExecution struct {
 	CompletedAt time.Time `json:"completed_at,omitempty"`
 	// Phase indicates whether the hook completed successfully
 	Phase HookPhase `json:"phase"`
+	// Logs are the end of the logs of the hook pods, kept when the hook failed or when requested
+	Logs string `json:"logs,omitempty"`
 }
 
 // A HookPhase indicates the state of a hook execution
@@ -16,6 +16,8 @@ limitations under the License.
 package v1
 
 import (
+	"fmt"
+
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"helm.sh/helm/v4/pkg/time"
@@ -37,4 +39,34 @@ type Info struct {
 	Notes string `json:"notes,omitempty"`
 	// Contains the deployed resources information
 	Resources map[string][]runtime.Object `json:"resources,omitempty"`
+	// Checkpoint is the progress of an upgrade that is underway. It is only
+	// recorded when checkpoints are enabled.
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+	// DryRun is the outcome of submitting the resources with server-side
+	// dry-run. It is only recorded by dry runs asking for it.
+	DryRun *DryRun `json:"dry_run,omitempty"`
+	// Health is the health of the live resources of the release. It is only
+	// recorded when the status of the release is asked for it.
+	Health []ResourceHealth `json:"health,omitempty"`
+	// Warnings are the problems found while rendering the release that did
+	// not make it fail.
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Warning is a problem found while rendering a release that did not make it
+// fail, like a violation of a values schema that only warns.
+type Warning struct {
+	// Chart is the path of the chart the warning is about.
+	Chart string `json:"chart,omitempty"`
+	// Path locates the problem within the chart, like the JSON pointer of a
+	// value.
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	if w.Path == "" {
+		return fmt.Sprintf("%s: %s", w.Chart, w.Message)
+	}
+	return fmt.Sprintf("%s: at '%s': %s", w.Chart, w.Path, w.Message)
 }
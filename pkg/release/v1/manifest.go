@@ -0,0 +1,110 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// sourcePrefix starts the comment recording the template a document of a
+// manifest was rendered from.
+const sourcePrefix = "# Source: "
+
+// ManifestDocument is a document of the manifest of a release.
+type ManifestDocument struct {
+	// GroupVersionKind is the type of the resource.
+	GroupVersionKind schema.GroupVersionKind
+	Name             string
+	// Namespace is the namespace set by the document, which is empty for
+	// cluster-scoped resources and for resources created in the namespace of
+	// the release without setting it.
+	Namespace string
+	Labels    map[string]string
+	// Source is the path of the template the document was rendered from. It
+	// is empty when the manifest does not record it, such as when a
+	// post-renderer removed the comments.
+	Source string
+	// Raw is the YAML of the document, without the comment recording its
+	// source.
+	Raw string
+}
+
+// ManifestDocuments returns the documents of the manifest of the release, in
+// the order of the manifest.
+func (r *Release) ManifestDocuments() ([]ManifestDocument, error) {
+	return ParseManifest(r.Manifest)
+}
+
+// ParseManifest splits a manifest into its documents, in order. The documents
+// without any resource, such as those of the Secrets hidden from the output
+// of a dry run, are skipped.
+func ParseManifest(manifest string) ([]ManifestDocument, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(manifest)))
+	var docs []ManifestDocument
+	for {
+		data, err := reader.Read()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to split the manifest")
+		}
+
+		doc := ManifestDocument{}
+		var lines []string
+		// The reader leaves the separator at the start of the first document.
+		body := strings.TrimSpace(string(data))
+		if first, rest, _ := strings.Cut(body, "\n"); strings.TrimSpace(first) == "---" {
+			body = rest
+		}
+		for _, line := range strings.Split(body, "\n") {
+			if source, ok := strings.CutPrefix(line, sourcePrefix); ok && doc.Source == "" {
+				doc.Source = strings.TrimSpace(source)
+				continue
+			}
+			lines = append(lines, line)
+		}
+		doc.Raw = strings.TrimSpace(strings.Join(lines, "\n"))
+
+		var head struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Name      string            `json:"name"`
+				Namespace string            `json:"namespace"`
+				Labels    map[string]string `json:"labels"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc.Raw), &head); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse document %d of the manifest (source %q)", len(docs)+1, doc.Source)
+		}
+		if head.Kind == "" && head.APIVersion == "" {
+			continue
+		}
+		doc.GroupVersionKind = schema.FromAPIVersionAndKind(head.APIVersion, head.Kind)
+		doc.Name = head.Metadata.Name
+		doc.Namespace = head.Metadata.Namespace
+		doc.Labels = head.Metadata.Labels
+		docs = append(docs, doc)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const manifest = `---
+# Source: hello/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: hello
+---
+# Source: hello/templates/secret.yaml
+# HIDDEN: The Secret output has been suppressed
+---
+# Source: hello/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hello
+  namespace: spaced
+  annotations:
+    note: |
+      ---
+      not a document
+---
+apiVersion: example.io/v1
+kind: Widget
+metadata:
+  name: widget
+`
+
+func TestParseManifest(t *testing.T) {
+	docs, err := ParseManifest(manifest)
+	require.NoError(t, err)
+	require.Len(t, docs, 3)
+
+	assert.Equal(t, ManifestDocument{
+		GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+		Name:             "hello",
+		Source:           "hello/templates/service.yaml",
+		Raw:              "apiVersion: v1\nkind: Service\nmetadata:\n  name: hello",
+	}, docs[0])
+
+	assert.Equal(t, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, docs[1].GroupVersionKind)
+	assert.Equal(t, "spaced", docs[1].Namespace)
+	assert.Equal(t, "hello/templates/deployment.yaml", docs[1].Source)
+	assert.Contains(t, docs[1].Raw, "not a document")
+
+	assert.Equal(t, "widget", docs[2].Name)
+	assert.Empty(t, docs[2].Source)
+
+	_, err = ParseManifest("---\n# Source: bad.yaml\nkind: [\n")
+	assert.ErrorContains(t, err, `document 1 of the manifest (source "bad.yaml")`)
+
+	rel := &Release{Manifest: manifest}
+	docs, err = rel.ManifestDocuments()
+	require.NoError(t, err)
+	assert.Len(t, docs, 3)
+}
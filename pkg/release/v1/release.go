@@ -35,6 +35,9 @@ type Release struct {
 	Manifest string `json:"manifest,omitempty"`
 	// Hooks are all of the hooks declared for this release.
 	Hooks []*Hook `json:"hooks,omitempty"`
+	// HookOutputs are the outputs published by the hooks of this release and
+	// of the previous ones, by hook name.
+	HookOutputs map[string]map[string]string `json:"hook_outputs,omitempty"`
 	// Version is an int which represents the revision of the release.
 	Version int `json:"version,omitempty"`
 	// Namespace is the kubernetes namespace of the release.
@@ -0,0 +1,67 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/pkg/errors"
+)
+
+type ageKeys []age.Identity
+
+// AgeKeys returns a KeySource holding age identities, one per line in the
+// format of an age key file. Blank lines and comments are skipped.
+func AgeKeys(data string) (KeySource, error) {
+	ids, err := age.ParseIdentities(strings.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing age identities")
+	}
+	return ageKeys(ids), nil
+}
+
+func (k ageKeys) DataKey(g *KeyGroup) ([]byte, error) {
+	for _, key := range g.Age {
+		data, err := ageDecrypt(key.EncryptedKey, k)
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypting data key for age recipient %s", key.Recipient)
+		}
+		return data, nil
+	}
+	return nil, ErrNoKey
+}
+
+// ageDecrypt decrypts an age file, which may be ASCII armored, with the
+// first identity it is encrypted for.
+func ageDecrypt(file string, ids []age.Identity) ([]byte, error) {
+	var r io.Reader = strings.NewReader(file)
+	if strings.HasPrefix(strings.TrimSpace(file), armor.Header) {
+		r = armor.NewReader(strings.NewReader(strings.TrimSpace(file)))
+	}
+	d, err := age.Decrypt(r, ids...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(d)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"       //nolint
+	"golang.org/x/crypto/openpgp/armor" //nolint
+)
+
+type pgpKeys openpgp.EntityList
+
+// PGPKeys returns a KeySource holding the private keys of a PGP keyring.
+// Keys protected by a passphrase are not supported.
+func PGPKeys(keyring openpgp.EntityList) KeySource {
+	return pgpKeys(keyring)
+}
+
+func (k pgpKeys) DataKey(g *KeyGroup) ([]byte, error) {
+	for _, key := range g.PGP {
+		block, err := armor.Decode(strings.NewReader(key.EncryptedKey))
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding data key for PGP key %s", key.Fingerprint)
+		}
+		md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList(k), nil, nil)
+		if err != nil {
+			// The data key is not encrypted for any of the keys.
+			continue
+		}
+		data, err := io.ReadAll(md.UnverifiedBody)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypting data key for PGP key %s", key.Fingerprint)
+		}
+		return data, nil
+	}
+	return nil, ErrNoKey
+}
+
+type gpgCommand string
+
+// GPGKeys returns a KeySource that decrypts with the gpg command, like the
+// sops command does. It holds the private keys of the gpg agent, which GnuPG
+// 2.1 and later keep in private-keys-v1.d rather than in a keyring file.
+func GPGKeys(command string) KeySource {
+	return gpgCommand(command)
+}
+
+func (c gpgCommand) DataKey(g *KeyGroup) ([]byte, error) {
+	for _, key := range g.PGP {
+		cmd := exec.Command(string(c), "--batch", "--use-agent", "--status-fd", "2", "--decrypt")
+		cmd.Stdin = strings.NewReader(key.EncryptedKey)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err == nil {
+			return out, nil
+		}
+		// The status lines are not translated, unlike the messages.
+		if strings.Contains(stderr.String(), "[GNUPG:] NO_SECKEY") {
+			continue
+		}
+		return nil, errors.Wrapf(err, "decrypting data key for PGP key %s with %s: %s", key.Fingerprint, c, strings.TrimSpace(stderr.String()))
+	}
+	return nil, ErrNoKey
+}
+
+// DefaultKeySources returns the keys found where the sops command looks for
+// them: age identities in $SOPS_AGE_KEY and in the file named by
+// $SOPS_AGE_KEY_FILE or sops/age/keys.txt in the user's configuration
+// directory, and PGP keys in the secret keyring of $GNUPGHOME or ~/.gnupg,
+// followed by those of the gpg agent when the gpg command, or the command
+// named by $SOPS_GPG_EXEC, is installed, and AWS KMS keys.
+func DefaultKeySources() ([]KeySource, error) {
+	var sources []KeySource
+
+	var identities []string
+	if v := os.Getenv("SOPS_AGE_KEY"); v != "" {
+		identities = append(identities, v)
+	}
+	path, explicit := os.LookupEnv("SOPS_AGE_KEY_FILE")
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "sops", "age", "keys.txt")
+		}
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && (explicit || !os.IsNotExist(err)) {
+			return nil, errors.Wrap(err, "reading age identities")
+		}
+		if len(data) > 0 {
+			identities = append(identities, string(data))
+		}
+	}
+	if len(identities) > 0 {
+		keys, err := AgeKeys(strings.Join(identities, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, keys)
+	}
+
+	// GnuPG before 2.1 keeps private keys in a keyring file, which is read
+	// directly.
+	home, ok := os.LookupEnv("GNUPGHOME")
+	if !ok {
+		if dir, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(dir, ".gnupg")
+		}
+	}
+	if home != "" {
+		keyring, err := readKeyring(filepath.Join(home, "secring.gpg"))
+		if err != nil {
+			return nil, err
+		}
+		if keyring != nil {
+			sources = append(sources, PGPKeys(keyring))
+		}
+	}
+
+	gpg := os.Getenv("SOPS_GPG_EXEC")
+	if gpg == "" {
+		gpg = "gpg"
+	}
+	if path, err := exec.LookPath(gpg); err == nil {
+		sources = append(sources, GPGKeys(path))
+	}
+	return append(sources, AWSKMSKeys()), nil
+}
+
+// readKeyring reads a PGP keyring file, or returns nil if it does not exist.
+func readKeyring(path string) (openpgp.EntityList, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	keyring, err := openpgp.ReadKeyRing(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading PGP keyring")
+	}
+	return keyring, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/pkg/errors"
+)
+
+// awsRoleSessionName names the sessions of the roles assumed to decrypt data
+// keys.
+const awsRoleSessionName = "helm-sops"
+
+type awsKMSKeys struct{}
+
+// AWSKMSKeys returns a KeySource that decrypts with AWS KMS keys, like the
+// sops command does. Requests are sent to the region of the key's ARN, and
+// authorized with the credentials of the default credential chain of the AWS
+// SDK, or of the key's AWS profile. The key's role, if any, is assumed with
+// these credentials first.
+func AWSKMSKeys() KeySource {
+	return awsKMSKeys{}
+}
+
+func (awsKMSKeys) DataKey(g *KeyGroup) ([]byte, error) {
+	for _, key := range g.KMS {
+		data, err := key.decrypt(context.Background())
+		if err != nil {
+			// Like sops, try the next key when a key cannot be used, such
+			// as without credentials or access to it.
+			slog.Debug("failed to decrypt the SOPS data key with AWS KMS", "arn", key.ARN, slog.Any("error", err))
+			continue
+		}
+		return data, nil
+	}
+	return nil, ErrNoKey
+}
+
+// decrypt decrypts the data key with AWS KMS.
+func (k *KMSKey) decrypt(ctx context.Context) ([]byte, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(k.EncryptedKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding data key")
+	}
+	keyARN, err := arn.Parse(k.ARN)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing key ARN")
+	}
+
+	opts := []func(*config.LoadOptions) error{config.WithRegion(keyARN.Region)}
+	if k.AWSProfile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(k.AWSProfile))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading the AWS configuration")
+	}
+	if k.Role != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), k.Role, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = awsRoleSessionName
+		}))
+	}
+
+	out, err := kms.NewFromConfig(cfg).Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(k.ARN),
+		CiphertextBlob:    encrypted,
+		EncryptionContext: k.Context,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"github.com/pkg/errors"
+)
+
+// shamirCombine recovers a secret from the shares SOPS splits the data key
+// of a document with multiple key groups into. Each share holds one byte of
+// a point on a polynomial over GF(2^8) for each byte of the secret, followed
+// by the x coordinate of the points. The secret is the value of the
+// polynomials at zero.
+func shamirCombine(parts [][]byte) ([]byte, error) {
+	if len(parts) < 2 {
+		return nil, errors.New("at least two Shamir shares are required")
+	}
+	n := len(parts[0])
+	if n < 2 {
+		return nil, errors.New("invalid Shamir share")
+	}
+	xs := make([]byte, len(parts))
+	seen := map[byte]bool{}
+	for i, p := range parts {
+		if len(p) != n {
+			return nil, errors.New("mismatched Shamir share lengths")
+		}
+		xs[i] = p[n-1]
+		if seen[xs[i]] {
+			return nil, errors.New("duplicate Shamir share")
+		}
+		seen[xs[i]] = true
+	}
+
+	secret := make([]byte, n-1)
+	ys := make([]byte, len(parts))
+	for i := range secret {
+		for j, p := range parts {
+			ys[j] = p[i]
+		}
+		secret[i] = gfInterpolate(xs, ys, 0)
+	}
+	return secret, nil
+}
+
+// gfInterpolate returns the value at x of the polynomial through the points
+// (xs[i], ys[i]), by Lagrange interpolation.
+func gfInterpolate(xs, ys []byte, x byte) byte {
+	var result byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			// Addition and subtraction in GF(2^8) are both XOR.
+			basis = gfMul(basis, gfDiv(x^xs[j], xs[i]^xs[j]))
+		}
+		result ^= gfMul(ys[i], basis)
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) with the AES reducing polynomial.
+func gfMul(a, b byte) byte {
+	var r byte
+	for ; b > 0; b >>= 1 {
+		if b&1 == 1 {
+			r ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+	}
+	return r
+}
+
+// gfDiv divides a by the non-zero b in GF(2^8), as a times the inverse of b,
+// which is b^254.
+func gfDiv(a, b byte) byte {
+	inv := byte(1)
+	for i := 0; i < 254; i++ {
+		inv = gfMul(inv, b)
+	}
+	return gfMul(a, inv)
+}
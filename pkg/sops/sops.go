@@ -0,0 +1,605 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package sops decrypts values files encrypted with SOPS.
+
+SOPS encrypts each value of a YAML or JSON document with a data key, and
+stores the data key encrypted with one or more master keys, such as age or
+PGP keys or cloud KMS keys, in the document's "sops" metadata. A KeySource
+decrypts the data key with the master keys it holds.
+*/
+package sops // import "helm.sh/helm/v4/pkg/sops"
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// MetadataKey is the top level key holding the SOPS metadata of a document.
+const MetadataKey = "sops"
+
+// ErrNoKey indicates that none of the master keys of a document is available.
+var ErrNoKey = errors.New("no SOPS decryption key available")
+
+// KeySource decrypts the data key of a document with one of its master keys.
+type KeySource interface {
+	// DataKey returns the decrypted data key, or ErrNoKey if the source holds
+	// none of the master keys in the group.
+	DataKey(g *KeyGroup) ([]byte, error)
+}
+
+// Metadata is the SOPS metadata of a document.
+type Metadata struct {
+	// KeyGroup holds the master keys of documents that are not encrypted
+	// with key groups.
+	KeyGroup          `yaml:",inline"`
+	KeyGroups         []KeyGroup `yaml:"key_groups"`
+	ShamirThreshold   int        `yaml:"shamir_threshold"`
+	LastModified      string     `yaml:"lastmodified"`
+	MAC               string     `yaml:"mac"`
+	UnencryptedSuffix string     `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string     `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string     `yaml:"unencrypted_regex"`
+	EncryptedRegex    string     `yaml:"encrypted_regex"`
+	MACOnlyEncrypted  bool       `yaml:"mac_only_encrypted"`
+	Version           string     `yaml:"version"`
+}
+
+// KeyGroup is a group of master keys, each of which decrypts the data key.
+type KeyGroup struct {
+	Age     []AgeKey     `yaml:"age"`
+	PGP     []PGPKey     `yaml:"pgp"`
+	KMS     []KMSKey     `yaml:"kms"`
+	GCPKMS  []GCPKMSKey  `yaml:"gcp_kms"`
+	AzureKV []AzureKVKey `yaml:"azure_kv"`
+	HCVault []HCVaultKey `yaml:"hc_vault"`
+}
+
+// AgeKey is the data key encrypted for an age recipient.
+type AgeKey struct {
+	Recipient    string `yaml:"recipient"`
+	EncryptedKey string `yaml:"enc"`
+}
+
+// PGPKey is the data key encrypted for a PGP key.
+type PGPKey struct {
+	Fingerprint  string `yaml:"fp"`
+	EncryptedKey string `yaml:"enc"`
+}
+
+// KMSKey is the data key encrypted with an AWS KMS key.
+type KMSKey struct {
+	ARN          string            `yaml:"arn"`
+	Role         string            `yaml:"role"`
+	Context      map[string]string `yaml:"context"`
+	AWSProfile   string            `yaml:"aws_profile"`
+	EncryptedKey string            `yaml:"enc"`
+}
+
+// GCPKMSKey is the data key encrypted with a GCP KMS key.
+type GCPKMSKey struct {
+	ResourceID   string `yaml:"resource_id"`
+	EncryptedKey string `yaml:"enc"`
+}
+
+// AzureKVKey is the data key encrypted with an Azure Key Vault key.
+type AzureKVKey struct {
+	VaultURL     string `yaml:"vault_url"`
+	Name         string `yaml:"name"`
+	Version      string `yaml:"version"`
+	EncryptedKey string `yaml:"enc"`
+}
+
+// HCVaultKey is the data key encrypted with a HashiCorp Vault transit key.
+type HCVaultKey struct {
+	VaultAddress string `yaml:"vault_address"`
+	EnginePath   string `yaml:"engine_path"`
+	KeyName      string `yaml:"key_name"`
+	EncryptedKey string `yaml:"enc"`
+}
+
+// keyTypes names the types of master keys in the group.
+func (g *KeyGroup) keyTypes() []string {
+	var types []string
+	for _, t := range []struct {
+		name string
+		n    int
+	}{
+		{"age", len(g.Age)},
+		{"PGP", len(g.PGP)},
+		{"AWS KMS", len(g.KMS)},
+		{"GCP KMS", len(g.GCPKMS)},
+		{"Azure Key Vault", len(g.AzureKV)},
+		{"HashiCorp Vault", len(g.HCVault)},
+	} {
+		if t.n > 0 {
+			types = append(types, t.name)
+		}
+	}
+	return types
+}
+
+// IsEncrypted reports whether data is a document encrypted with SOPS.
+func IsEncrypted(data []byte) bool {
+	var doc struct {
+		SOPS *struct {
+			MAC string `yaml:"mac"`
+		} `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return doc.SOPS != nil && doc.SOPS.MAC != ""
+}
+
+// Decrypt decrypts a YAML or JSON document encrypted with SOPS, with the data
+// key from the first of the sources that holds one of its master keys. For
+// documents with multiple key groups, the data key is combined from the key
+// groups the sources hold keys of. The values and comments of the document
+// are decrypted, and its integrity is verified against its MAC. The decrypted
+// document is returned as YAML, without its SOPS metadata.
+func Decrypt(data []byte, sources ...KeySource) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("not a SOPS encrypted document")
+	}
+	root := doc.Content[0]
+
+	var meta *Metadata
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != MetadataKey {
+			continue
+		}
+		meta = &Metadata{}
+		if err := root.Content[i+1].Decode(meta); err != nil {
+			return nil, errors.Wrap(err, "parsing SOPS metadata")
+		}
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		break
+	}
+	if meta == nil || meta.MAC == "" {
+		return nil, errors.New("not a SOPS encrypted document")
+	}
+
+	key, err := meta.dataKey(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decrypter{meta: meta, key: key, hash: sha512.New()}
+	if err := walkBranch(d, &doc, nil, false); err != nil {
+		return nil, err
+	}
+	if err := d.verify(); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(&doc)
+}
+
+// ExecDecrypt decrypts a document with the sops command. It supports the
+// master keys that no KeySource is available for, such as GCP KMS, Azure Key
+// Vault or HashiCorp Vault keys.
+func ExecDecrypt(data []byte) ([]byte, error) {
+	// The document is passed in a file rather than on standard input, which
+	// sops cannot read on all platforms. It is still encrypted.
+	f, err := os.CreateTemp("", "helm-sops-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("sops", "--decrypt", "--input-type", "yaml", "--output-type", "yaml", f.Name())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "running sops: %s", strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// dataKey decrypts the data key with the sources. The data key of a document
+// with multiple key groups is split into a Shamir share for each group, of
+// which the threshold must be decrypted.
+func (m *Metadata) dataKey(sources []KeySource) ([]byte, error) {
+	groups := m.KeyGroups
+	if len(groups) == 0 {
+		groups = []KeyGroup{m.KeyGroup}
+	}
+	if len(groups) == 1 {
+		return groups[0].dataKey(sources)
+	}
+
+	threshold := m.ShamirThreshold
+	if threshold == 0 {
+		threshold = len(groups)
+	}
+	var parts [][]byte
+	var missing []string
+	for i := range groups {
+		part, err := groups[i].dataKey(sources)
+		if errors.Is(err, ErrNoKey) {
+			missing = append(missing, fmt.Sprintf("%d (%s)", i, strings.Join(groups[i].keyTypes(), ", ")))
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "key group %d", i)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) < threshold {
+		return nil, errors.Wrapf(ErrNoKey, "%d of %d key groups are required, missing keys for key groups %s",
+			threshold, len(groups), strings.Join(missing, ", "))
+	}
+	key, err := shamirCombine(parts)
+	if err != nil {
+		return nil, errors.Wrap(err, "combining the data key shares of the key groups")
+	}
+	return key, nil
+}
+
+// dataKey decrypts the data key, or the group's share of it, with the first
+// of the sources that holds one of the group's master keys.
+func (g *KeyGroup) dataKey(sources []KeySource) ([]byte, error) {
+	for _, s := range sources {
+		key, err := s.DataKey(g)
+		if errors.Is(err, ErrNoKey) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	types := g.keyTypes()
+	if len(types) == 0 {
+		return nil, errors.New("SOPS metadata has no master keys")
+	}
+	return nil, errors.Wrapf(ErrNoKey, "document is encrypted with %s keys", strings.Join(types, ", "))
+}
+
+// encrypted reports whether the value at path is encrypted, following the
+// rules the document was encrypted with.
+func (m *Metadata) encrypted(path []string) bool {
+	encrypted := true
+	if m.UnencryptedSuffix != "" {
+		for _, p := range path {
+			if strings.HasSuffix(p, m.UnencryptedSuffix) {
+				encrypted = false
+				break
+			}
+		}
+	}
+	if m.EncryptedSuffix != "" {
+		encrypted = false
+		for _, p := range path {
+			if strings.HasSuffix(p, m.EncryptedSuffix) {
+				encrypted = true
+				break
+			}
+		}
+	}
+	if m.UnencryptedRegex != "" {
+		for _, p := range path {
+			if matched, _ := regexp.MatchString(m.UnencryptedRegex, p); matched {
+				encrypted = false
+				break
+			}
+		}
+	}
+	if m.EncryptedRegex != "" {
+		encrypted = false
+		for _, p := range path {
+			if matched, _ := regexp.MatchString(m.EncryptedRegex, p); matched {
+				encrypted = true
+				break
+			}
+		}
+	}
+	return encrypted
+}
+
+// leafVisitor is called for the values and comments of a document, in the
+// order SOPS adds them to the MAC.
+type leafVisitor interface {
+	// value is called for a scalar value.
+	value(n *yaml.Node, path []string) error
+	// comment is called for a line of a comment, without its "#", and
+	// returns the line to replace it with.
+	comment(line string, path []string) (string, error)
+}
+
+// walkBranch visits the comments and values of a document or mapping the way
+// SOPS reads them into a branch. The path of a value is made of the keys of
+// the mappings it is nested in; list items share the path of their list.
+// Comments share the path of the branch or list they are in.
+func walkBranch(v leafVisitor, n *yaml.Node, path []string, commentsVisited bool) error {
+	if !commentsVisited {
+		if err := walkComments(v, &n.HeadComment, path); err != nil {
+			return err
+		}
+		if err := walkComments(v, &n.LineComment, path); err != nil {
+			return err
+		}
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			if err := walkBranch(v, c, path, false); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			// Comments of scalar values are read into the mapping, those of
+			// mappings and lists into the value itself.
+			scalar := value.Kind == yaml.ScalarNode
+			comments := []*string{&key.HeadComment, &key.LineComment}
+			if scalar {
+				comments = append(comments, &value.HeadComment, &value.LineComment)
+			}
+			for _, c := range comments {
+				if err := walkComments(v, c, path); err != nil {
+					return err
+				}
+			}
+			if err := walkValue(v, value, append(path, key.Value)); err != nil {
+				return err
+			}
+			comments = []*string{&key.FootComment}
+			if scalar {
+				comments = []*string{&value.FootComment, &key.FootComment}
+			}
+			for _, c := range comments {
+				if err := walkComments(v, c, path); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if !commentsVisited {
+		return walkComments(v, &n.FootComment, path)
+	}
+	return nil
+}
+
+func walkValue(v leafVisitor, n *yaml.Node, path []string) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return walkBranch(v, n, path, false)
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			for _, comment := range []*string{&c.HeadComment, &c.LineComment} {
+				if err := walkComments(v, comment, path); err != nil {
+					return err
+				}
+			}
+			if err := walkValue(v, c, path); err != nil {
+				return err
+			}
+			if err := walkComments(v, &c.FootComment, path); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return v.value(n, path)
+	}
+	return nil
+}
+
+// walkComments visits each line of a comment, skipping blank lines.
+func walkComments(v leafVisitor, comment *string, path []string) error {
+	if *comment == "" {
+		return nil
+	}
+	lines := strings.Split(*comment, "\n")
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		out, err := v.comment(strings.TrimPrefix(line, "#"), path)
+		if err != nil {
+			return err
+		}
+		lines[i] = "#" + out
+	}
+	*comment = strings.Join(lines, "\n")
+	return nil
+}
+
+type decrypter struct {
+	meta *Metadata
+	key  []byte
+	// hash accumulates the plaintext values in document order for the MAC.
+	hash hash.Hash
+}
+
+func (d *decrypter) value(n *yaml.Node, path []string) error {
+	encrypted := d.meta.encrypted(path)
+	var v interface{}
+	if encrypted {
+		var err error
+		if v, err = decryptValue(n.Value, d.key, strings.Join(path, ":")+":"); err != nil {
+			return errors.Wrapf(err, "decrypting %s", strings.Join(path, "."))
+		}
+		setScalar(n, v)
+	} else if err := n.Decode(&v); err != nil {
+		return err
+	}
+	d.addToMAC(v, encrypted)
+	return nil
+}
+
+func (d *decrypter) comment(line string, path []string) (string, error) {
+	encrypted := d.meta.encrypted(path)
+	if encrypted && encryptedValue.MatchString(line) {
+		v, err := decryptValue(line, d.key, strings.Join(path, ":")+":")
+		if err != nil {
+			return "", errors.Wrapf(err, "decrypting comment in %s", strings.Join(path, "."))
+		}
+		s, ok := v.(string)
+		if !ok {
+			return "", errors.Errorf("decrypting comment in %s: not a comment", strings.Join(path, "."))
+		}
+		line = s
+	}
+	// Comments written by SOPS before it encrypted them are left as they
+	// are, and are part of the MAC all the same.
+	d.addToMAC(line, encrypted)
+	return line, nil
+}
+
+func (d *decrypter) addToMAC(v interface{}, encrypted bool) {
+	if !d.meta.MACOnlyEncrypted || encrypted {
+		if b, ok := macBytes(v); ok {
+			d.hash.Write(b)
+		}
+	}
+}
+
+func (d *decrypter) verify() error {
+	// The MAC is authenticated with the time the document was last
+	// modified, in the form SOPS writes it.
+	aad := d.meta.LastModified
+	if t, err := time.Parse(time.RFC3339, aad); err == nil {
+		aad = t.Format(time.RFC3339)
+	}
+	mac, err := decryptValue(d.meta.MAC, d.key, aad)
+	if err != nil {
+		return errors.Wrap(err, "decrypting MAC")
+	}
+	if s, ok := mac.(string); !ok || s != fmt.Sprintf("%X", d.hash.Sum(nil)) {
+		return errors.New("MAC mismatch: the document was modified after it was encrypted")
+	}
+	return nil
+}
+
+var encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// decryptValue decrypts a value encrypted with AES-GCM, authenticated with
+// additional data, into its original type.
+func decryptValue(value string, key []byte, aad string) (interface{}, error) {
+	if value == "" {
+		return "", nil
+	}
+	m := encryptedValue.FindStringSubmatch(value)
+	if m == nil {
+		return nil, errors.New("value is not in the SOPS encrypted format")
+	}
+	var parts [3][]byte
+	for i := range parts {
+		b, err := base64.StdEncoding.DecodeString(m[i+1])
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding encrypted value")
+		}
+		parts[i] = b
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, iv, append(data, tag...), []byte(aad))
+	if err != nil {
+		return nil, errors.Wrap(err, "authenticating encrypted value")
+	}
+
+	switch m[4] {
+	case "str":
+		return string(plain), nil
+	case "int":
+		return strconv.Atoi(string(plain))
+	case "float":
+		return strconv.ParseFloat(string(plain), 64)
+	case "bool":
+		return strconv.ParseBool(string(plain))
+	case "bytes":
+		return plain, nil
+	case "comment":
+		return string(plain), nil
+	}
+	return nil, errors.Errorf("unknown encrypted value type %q", m[4])
+}
+
+func setScalar(n *yaml.Node, v interface{}) {
+	n.Style = 0
+	switch v := v.(type) {
+	case int:
+		n.Tag, n.Value = "!!int", strconv.Itoa(v)
+	case float64:
+		n.Tag, n.Value = "!!float", strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		n.Tag, n.Value = "!!bool", strconv.FormatBool(v)
+	case []byte:
+		n.Tag, n.Value = "!!str", string(v)
+	case string:
+		n.Tag, n.Value = "!!str", v
+	}
+}
+
+// macBytes returns the bytes of a value that SOPS adds to the MAC.
+func macBytes(v interface{}) ([]byte, bool) {
+	switch v := v.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return []byte(v), true
+	case []byte:
+		return v, true
+	case int:
+		return []byte(strconv.Itoa(v)), true
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), true
+	case bool:
+		// SOPS writes booleans the way Python does.
+		if v {
+			return []byte("True"), true
+		}
+		return []byte("False"), true
+	}
+	return []byte(fmt.Sprint(v)), true
+}
@@ -0,0 +1,457 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"                //nolint
+	pgparmor "golang.org/x/crypto/openpgp/armor" //nolint
+	"golang.org/x/crypto/openpgp/packet"         //nolint
+	"gopkg.in/yaml.v3"
+)
+
+const testDocument = `# database settings
+database:
+  # primary credentials
+  user: admin
+  password: s3cr3t # rotated monthly
+  port: 5432
+  ratio: 0.5
+  enabled: true
+  quoted: "true"
+  hosts:
+    # failover order
+    - db1
+    - db2
+  tls_unencrypted: false
+replicas: 3
+`
+
+func TestDecryptAge(t *testing.T) {
+	identity, recipient := newAgeIdentity(t)
+	dataKey := randomBytes(t, 32)
+	meta := &Metadata{UnencryptedSuffix: "_unencrypted"}
+	meta.Age = []AgeKey{{Recipient: recipient.String(), EncryptedKey: ageEncrypt(t, dataKey, recipient)}}
+	data := encryptDocument(t, testDocument, dataKey, meta)
+
+	assert.True(t, IsEncrypted(data))
+	assert.False(t, IsEncrypted([]byte(testDocument)))
+	assert.Contains(t, string(data), "ENC[AES256_GCM,")
+	assert.Contains(t, string(data), "tls_unencrypted: false")
+	assert.NotContains(t, string(data), "primary credentials")
+
+	keys, err := AgeKeys("# created: 2024-01-01\n" + identity + "\n")
+	require.NoError(t, err)
+	out, err := Decrypt(data, keys)
+	require.NoError(t, err)
+
+	var got, want map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(out, &got))
+	require.NoError(t, yaml.Unmarshal([]byte(testDocument), &want))
+	assert.Equal(t, want, got)
+	assert.NotContains(t, string(out), MetadataKey+":")
+	for _, comment := range []string{"# database settings", "# primary credentials", "# rotated monthly", "# failover order"} {
+		assert.Contains(t, string(out), comment)
+	}
+}
+
+func TestDecryptPlainComments(t *testing.T) {
+	identity, recipient := newAgeIdentity(t)
+	keys, err := AgeKeys(identity)
+	require.NoError(t, err)
+	dataKey := randomBytes(t, 32)
+	meta := &Metadata{}
+	meta.Age = []AgeKey{{Recipient: recipient.String(), EncryptedKey: ageEncrypt(t, dataKey, recipient)}}
+
+	// Older versions of SOPS left comments unencrypted.
+	enc := &encrypter{t: t, meta: meta, key: dataKey, hash: sha512.New(), plainComments: true}
+	data := encryptDocumentWith(t, "# settings\ngreeting: hello\n", enc)
+	assert.Contains(t, string(data), "# settings")
+
+	out, err := Decrypt(data, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "# settings\ngreeting: hello\n", string(out))
+
+	// Unencrypted comments are part of the MAC.
+	tampered := bytes.Replace(data, []byte("# settings"), []byte("# changed"), 1)
+	_, err = Decrypt(tampered, keys)
+	assert.ErrorContains(t, err, "MAC mismatch")
+}
+
+func TestDecryptPGP(t *testing.T) {
+	config := &packet.Config{DefaultHash: crypto.SHA256}
+	entity, err := openpgp.NewEntity("Helm Testing", "", "helm-testing@helm.sh", config)
+	require.NoError(t, err)
+	dataKey := randomBytes(t, 32)
+
+	var buf bytes.Buffer
+	aw, err := pgparmor.Encode(&buf, "PGP MESSAGE", nil)
+	require.NoError(t, err)
+	w, err := openpgp.Encrypt(aw, openpgp.EntityList{entity}, nil, nil, config)
+	require.NoError(t, err)
+	_, err = w.Write(dataKey)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, aw.Close())
+
+	meta := &Metadata{}
+	meta.PGP = []PGPKey{{Fingerprint: "test", EncryptedKey: buf.String()}}
+	data := encryptDocument(t, "greeting: hello\n", dataKey, meta)
+
+	out, err := Decrypt(data, PGPKeys(openpgp.EntityList{entity}))
+	require.NoError(t, err)
+	assert.Equal(t, "greeting: hello\n", string(out))
+}
+
+func TestDecryptKeyGroups(t *testing.T) {
+	dataKey := randomBytes(t, 32)
+	shares := shamirSplit(t, dataKey, 3, 2)
+
+	meta := &Metadata{ShamirThreshold: 2}
+	var identities []string
+	for _, share := range shares {
+		identity, recipient := newAgeIdentity(t)
+		identities = append(identities, identity)
+		meta.KeyGroups = append(meta.KeyGroups, KeyGroup{
+			Age: []AgeKey{{Recipient: recipient.String(), EncryptedKey: ageEncrypt(t, share, recipient)}},
+		})
+	}
+	data := encryptDocument(t, "greeting: hello\n", dataKey, meta)
+
+	// Any two of the three key groups recover the data key.
+	for _, ids := range [][]string{identities[:2], identities[1:], {identities[0], identities[2]}} {
+		keys, err := AgeKeys(strings.Join(ids, "\n"))
+		require.NoError(t, err)
+		out, err := Decrypt(data, keys)
+		require.NoError(t, err)
+		assert.Equal(t, "greeting: hello\n", string(out))
+	}
+
+	keys, err := AgeKeys(identities[1])
+	require.NoError(t, err)
+	_, err = Decrypt(data, keys)
+	assert.ErrorIs(t, err, ErrNoKey)
+	assert.ErrorContains(t, err, "2 of 3 key groups are required")
+}
+
+func TestDecryptErrors(t *testing.T) {
+	identity, recipient := newAgeIdentity(t)
+	other, _ := newAgeIdentity(t)
+	keys, err := AgeKeys(identity)
+	require.NoError(t, err)
+	otherKeys, err := AgeKeys(other)
+	require.NoError(t, err)
+
+	dataKey := randomBytes(t, 32)
+	meta := &Metadata{}
+	meta.Age = []AgeKey{{Recipient: recipient.String(), EncryptedKey: ageEncrypt(t, dataKey, recipient)}}
+	meta.KMS = []KMSKey{{ARN: "arn:aws:kms:us-east-1:000000000000:key/test", EncryptedKey: "AQID"}}
+	data := encryptDocument(t, "greeting: hello\nreplicas: 3\n", dataKey, meta)
+
+	_, err = Decrypt(data, otherKeys)
+	assert.ErrorIs(t, err, ErrNoKey)
+	assert.ErrorContains(t, err, "age, AWS KMS")
+
+	_, err = Decrypt(data)
+	assert.ErrorIs(t, err, ErrNoKey)
+
+	// Values cannot be moved to other keys.
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal(data, &doc))
+	root := doc.Content[0]
+	root.Content[0].Value = "salutation"
+	moved, err := yaml.Marshal(&doc)
+	require.NoError(t, err)
+	_, err = Decrypt(moved, keys)
+	assert.ErrorContains(t, err, "decrypting salutation")
+
+	// Values cannot be removed.
+	root.Content = root.Content[2:]
+	removed, err := yaml.Marshal(&doc)
+	require.NoError(t, err)
+	_, err = Decrypt(removed, keys)
+	assert.ErrorContains(t, err, "MAC mismatch")
+
+	_, err = Decrypt([]byte(testDocument), keys)
+	assert.ErrorContains(t, err, "not a SOPS encrypted document")
+
+	_, err = AgeKeys("AGE-SECRET-KEY-1INVALID")
+	assert.Error(t, err)
+}
+
+func TestAWSKMSKeys(t *testing.T) {
+	const keyARN = "arn:aws:kms:eu-west-1:111122223333:key/helm"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request") {
+			t.Errorf("unsigned KMS request %s %s", r.Method, r.URL)
+		}
+		var in struct {
+			KeyID             string `json:"KeyId"`
+			CiphertextBlob    []byte
+			EncryptionContext map[string]string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Fatal(err)
+		}
+		if r.Header.Get("X-Amz-Target") != "TrentService.Decrypt" || in.KeyID != keyARN || in.EncryptionContext["app"] != "helm" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": "InvalidCiphertextException", "message": "invalid ciphertext"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": invert(in.CiphertextBlob)})
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL_KMS", server.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	dataKey := randomBytes(t, 32)
+	encrypted := base64.StdEncoding.EncodeToString(invert(dataKey))
+	meta := &Metadata{}
+	meta.KMS = []KMSKey{
+		// The key that cannot be used is skipped.
+		{ARN: "arn:aws:kms:eu-west-1:111122223333:key/other", EncryptedKey: encrypted},
+		{ARN: keyARN, Context: map[string]string{"app": "helm"}, EncryptedKey: encrypted},
+	}
+	data := encryptDocument(t, "greeting: hello\n", dataKey, meta)
+
+	out, err := Decrypt(data, AWSKMSKeys())
+	require.NoError(t, err)
+	assert.Equal(t, "greeting: hello\n", string(out))
+
+	meta.KMS = meta.KMS[:1]
+	data = encryptDocument(t, "greeting: hello\n", dataKey, meta)
+	_, err = Decrypt(data, AWSKMSKeys())
+	assert.ErrorIs(t, err, ErrNoKey)
+}
+
+func TestGPGKeys(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake gpg command is a shell script")
+	}
+	gpg := writeScript(t, "gpg", `input=$(cat)
+case "$input" in
+for-me) printf 'data key' ;;
+broken) echo "gpg: public key decryption failed" >&2; exit 2 ;;
+*) echo "[GNUPG:] NO_SECKEY 0123456789ABCDEF" >&2; exit 2 ;;
+esac
+`)
+	keys := GPGKeys(gpg)
+
+	key, err := keys.DataKey(&KeyGroup{PGP: []PGPKey{{Fingerprint: "a", EncryptedKey: "for-someone"}, {Fingerprint: "b", EncryptedKey: "for-me"}}})
+	require.NoError(t, err)
+	assert.Equal(t, "data key", string(key))
+
+	_, err = keys.DataKey(&KeyGroup{PGP: []PGPKey{{Fingerprint: "a", EncryptedKey: "for-someone"}}})
+	assert.ErrorIs(t, err, ErrNoKey)
+
+	_, err = keys.DataKey(&KeyGroup{PGP: []PGPKey{{Fingerprint: "a", EncryptedKey: "broken"}}})
+	assert.ErrorContains(t, err, "public key decryption failed")
+
+	// The gpg agent is used when there is no keyring file, as with GnuPG 2.1
+	// and later.
+	t.Setenv("GNUPGHOME", t.TempDir())
+	t.Setenv("SOPS_AGE_KEY_FILE", filepath.Join(t.TempDir(), "keys.txt"))
+	t.Setenv("SOPS_GPG_EXEC", gpg)
+	require.NoError(t, os.WriteFile(os.Getenv("SOPS_AGE_KEY_FILE"), nil, 0600))
+	sources, err := DefaultKeySources()
+	require.NoError(t, err)
+	assert.Equal(t, []KeySource{keys, AWSKMSKeys()}, sources)
+}
+
+func TestExecDecrypt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops command is a shell script")
+	}
+	dir := filepath.Dir(writeScript(t, "sops", `for last; do :; done
+sed 's/ENC/DEC/' "$last"
+`))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	out, err := ExecDecrypt([]byte("greeting: ENC\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "greeting: DEC\n", string(out))
+}
+
+// writeScript writes a shell script to a temporary directory.
+func writeScript(t *testing.T, name, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	return path
+}
+
+// encrypter encrypts a document the way SOPS does.
+type encrypter struct {
+	t    *testing.T
+	meta *Metadata
+	key  []byte
+	hash hash.Hash
+	// plainComments leaves comments unencrypted.
+	plainComments bool
+}
+
+func (e *encrypter) value(n *yaml.Node, path []string) error {
+	var v interface{}
+	require.NoError(e.t, n.Decode(&v))
+	b, _ := macBytes(v)
+	e.hash.Write(b)
+	if !e.meta.encrypted(path) {
+		return nil
+	}
+	typ, plain := "str", string(b)
+	switch v := v.(type) {
+	case int:
+		typ = "int"
+	case float64:
+		typ = "float"
+	case bool:
+		typ, plain = "bool", strconv.FormatBool(v)
+	}
+	n.Value = encryptValue(e.t, plain, typ, e.key, strings.Join(path, ":")+":")
+	n.Tag, n.Style = "!!str", 0
+	return nil
+}
+
+func (e *encrypter) comment(line string, path []string) (string, error) {
+	e.hash.Write([]byte(line))
+	if e.plainComments || !e.meta.encrypted(path) {
+		return line, nil
+	}
+	return encryptValue(e.t, line, "comment", e.key, strings.Join(path, ":")+":"), nil
+}
+
+func encryptDocument(t *testing.T, document string, dataKey []byte, meta *Metadata) []byte {
+	t.Helper()
+	return encryptDocumentWith(t, document, &encrypter{t: t, meta: meta, key: dataKey, hash: sha512.New()})
+}
+
+func encryptDocumentWith(t *testing.T, document string, e *encrypter) []byte {
+	t.Helper()
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(document), &doc))
+	require.NoError(t, walkBranch(e, &doc, nil, false))
+
+	meta := e.meta
+	meta.LastModified = time.Now().UTC().Format(time.RFC3339)
+	meta.MAC = encryptValue(t, fmt.Sprintf("%X", e.hash.Sum(nil)), "str", e.key, meta.LastModified)
+	meta.Version = "3.9.0"
+
+	var metaNode yaml.Node
+	require.NoError(t, metaNode.Encode(meta))
+	root := doc.Content[0]
+	root.Content = append(root.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: MetadataKey}, &metaNode)
+	data, err := yaml.Marshal(&doc)
+	require.NoError(t, err)
+	return data
+}
+
+func encryptValue(t *testing.T, plain, typ string, key []byte, aad string) string {
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	iv := randomBytes(t, 32)
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	require.NoError(t, err)
+	sealed := gcm.Seal(nil, iv, []byte(plain), []byte(aad))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	enc := base64.StdEncoding
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		enc.EncodeToString(data), enc.EncodeToString(iv), enc.EncodeToString(tag), typ)
+}
+
+func newAgeIdentity(t *testing.T) (string, *age.X25519Recipient) {
+	id, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	return id.String(), id.Recipient()
+}
+
+// ageEncrypt encrypts data for a recipient into an armored age file.
+func ageEncrypt(t *testing.T, data []byte, recipient age.Recipient) string {
+	var buf bytes.Buffer
+	aw := armor.NewWriter(&buf)
+	w, err := age.Encrypt(aw, recipient)
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, aw.Close())
+	return buf.String()
+}
+
+// shamirSplit splits secret into n shares, any k of which recover it.
+func shamirSplit(t *testing.T, secret []byte, n, k int) [][]byte {
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+	for b, s := range secret {
+		coefficients := append([]byte{s}, randomBytes(t, k-1)...)
+		for _, share := range shares {
+			x, y := share[len(secret)], byte(0)
+			for c := len(coefficients) - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			share[b] = y
+		}
+	}
+	return shares
+}
+
+// invert "encrypts" with a fake KMS by inverting the bits of the data key.
+func invert(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[i] = ^c
+	}
+	return out
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
@@ -35,7 +35,10 @@ import (
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
 
-var _ Driver = (*ConfigMaps)(nil)
+var (
+	_ Driver = (*ConfigMaps)(nil)
+	_ Pager  = (*ConfigMaps)(nil)
+)
 
 // ConfigMapsDriverName is the string name of the driver.
 const ConfigMapsDriverName = "ConfigMap"
@@ -43,6 +46,7 @@ const ConfigMapsDriverName = "ConfigMap"
 // ConfigMaps is a wrapper around an implementation of a kubernetes
 // ConfigMapsInterface.
 type ConfigMaps struct {
+	codec
 	impl corev1.ConfigMapInterface
 }
 
@@ -73,7 +77,7 @@ func (cfgmaps *ConfigMaps) Get(key string) (*rspb.Release, error) {
 		return nil, err
 	}
 	// found the configmap, decode the base64 data string
-	r, err := decodeRelease(obj.Data["release"])
+	r, err := cfgmaps.decodeRelease(obj.Data["release"])
 	if err != nil {
 		slog.Debug("failed to decode data", "key", key, slog.Any("error", err))
 		return nil, err
@@ -87,33 +91,44 @@ func (cfgmaps *ConfigMaps) Get(key string) (*rspb.Release, error) {
 // that filter(release) == true. An error is returned if the
 // configmap fails to retrieve the releases.
 func (cfgmaps *ConfigMaps) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
-	lsel := kblabels.Set{"owner": "helm"}.AsSelector()
-	opts := metav1.ListOptions{LabelSelector: lsel.String()}
-
-	list, err := cfgmaps.impl.List(context.Background(), opts)
-	if err != nil {
-		slog.Debug("failed to list releases", slog.Any("error", err))
-		return nil, err
-	}
+	results, _, err := cfgmaps.ListPage(filter, ListOptions{})
+	return results, err
+}
 
+// ListPage fetches a page of opts.Limit releases with the labels of
+// opts and returns the releases such that filter(release) == true, with
+// the continue token of the next page. An error is returned if the configmap fails to
+// retrieve the releases.
+func (cfgmaps *ConfigMaps) ListPage(filter func(*rspb.Release) bool, opts ListOptions) ([]*rspb.Release, string, error) {
 	var results []*rspb.Release
-
-	// iterate over the configmaps object list
-	// and decode each release
-	for _, item := range list.Items {
-		rls, err := decodeRelease(item.Data["release"])
+	next, err := listKubePage(opts, func(listOpts metav1.ListOptions) (string, error) {
+		list, err := cfgmaps.impl.List(context.Background(), listOpts)
 		if err != nil {
-			slog.Debug("failed to decode release", "item", item, slog.Any("error", err))
-			continue
+			return "", err
 		}
 
-		rls.Labels = item.Labels
+		// iterate over the configmaps object list
+		// and decode each release
+		for _, item := range list.Items {
+			rls, err := cfgmaps.decodeRelease(item.Data["release"])
+			if err != nil {
+				slog.Debug("failed to decode release", "item", item, slog.Any("error", err))
+				continue
+			}
+
+			rls.Labels = item.Labels
 
-		if filter(rls) {
-			results = append(results, rls)
+			if filter(rls) {
+				results = append(results, rls)
+			}
 		}
+		return list.Continue, nil
+	})
+	if err != nil {
+		slog.Debug("failed to list releases", slog.Any("error", err))
+		return nil, "", err
 	}
-	return results, nil
+	return results, next, nil
 }
 
 // Query fetches all releases that match the provided map of labels.
@@ -141,7 +156,7 @@ func (cfgmaps *ConfigMaps) Query(labels map[string]string) ([]*rspb.Release, err
 
 	var results []*rspb.Release
 	for _, item := range list.Items {
-		rls, err := decodeRelease(item.Data["release"])
+		rls, err := cfgmaps.decodeRelease(item.Data["release"])
 		if err != nil {
 			slog.Debug("failed to decode release", slog.Any("error", err))
 			continue
@@ -163,7 +178,7 @@ func (cfgmaps *ConfigMaps) Create(key string, rls *rspb.Release) error {
 	lbs.set("createdAt", fmt.Sprintf("%v", time.Now().Unix()))
 
 	// create a new configmap to hold the release
-	obj, err := newConfigMapsObject(key, rls, lbs)
+	obj, err := newConfigMapsObject(&cfgmaps.codec, key, rls, lbs)
 	if err != nil {
 		slog.Debug("failed to encode release", "name", rls.Name, slog.Any("error", err))
 		return err
@@ -191,7 +206,7 @@ func (cfgmaps *ConfigMaps) Update(key string, rls *rspb.Release) error {
 	lbs.set("modifiedAt", fmt.Sprintf("%v", time.Now().Unix()))
 
 	// create a new configmap object to hold the release
-	obj, err := newConfigMapsObject(key, rls, lbs)
+	obj, err := newConfigMapsObject(&cfgmaps.codec, key, rls, lbs)
 	if err != nil {
 		slog.Debug("failed to encode release", "name", rls.Name, slog.Any("error", err))
 		return err
@@ -230,11 +245,11 @@ func (cfgmaps *ConfigMaps) Delete(key string) (rls *rspb.Release, err error) {
 //	"status"         - status of the release (see pkg/release/status.go for variants)
 //	"owner"          - owner of the configmap, currently "helm".
 //	"name"           - name of the release.
-func newConfigMapsObject(key string, rls *rspb.Release, lbs labels) (*v1.ConfigMap, error) {
+func newConfigMapsObject(c *codec, key string, rls *rspb.Release, lbs labels) (*v1.ConfigMap, error) {
 	const owner = "helm"
 
 	// encode the release
-	s, err := encodeRelease(rls)
+	s, err := c.encodeRelease(rls)
 	if err != nil {
 		return nil, err
 	}
@@ -251,6 +266,9 @@ func newConfigMapsObject(key string, rls *rspb.Release, lbs labels) (*v1.ConfigM
 	lbs.set("owner", owner)
 	lbs.set("status", rls.Info.Status.String())
 	lbs.set("version", strconv.Itoa(rls.Version))
+	for k, v := range chartLabels(rls) {
+		lbs.set(k, v)
+	}
 
 	// create and return configmap object
 	return &v1.ConfigMap{
@@ -60,7 +60,7 @@ func TestUncompressedConfigMapGet(t *testing.T) {
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
 	// Create a test fixture which contains an uncompressed release
-	cfgmap, err := newConfigMapsObject(key, rel, nil)
+	cfgmap, err := newConfigMapsObject(&testCodec, key, rel, nil)
 	if err != nil {
 		t.Fatalf("Failed to create configmap: %s", err)
 	}
@@ -141,6 +141,39 @@ func TestConfigMapList(t *testing.T) {
 	}
 }
 
+func TestConfigMapListPage(t *testing.T) {
+	cfgmaps := newTestFixtureCfgMaps(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-2", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-4", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-5", 1, "default", rspb.StatusSuperseded),
+	}...)
+
+	// list deployed releases two records at a time
+	var dpl []*rspb.Release
+	opts := ListOptions{Limit: 2}
+	for pages := 1; ; pages++ {
+		page, next, err := cfgmaps.ListPage(func(rel *rspb.Release) bool {
+			return rel.Info.Status == rspb.StatusDeployed
+		}, opts)
+		if err != nil {
+			t.Fatalf("Failed to list page %d: %s", pages, err)
+		}
+		dpl = append(dpl, page...)
+		if next == "" {
+			if pages != 3 {
+				t.Errorf("Expected 3 pages, got %d", pages)
+			}
+			break
+		}
+		opts.Continue = next
+	}
+	if len(dpl) != 3 {
+		t.Errorf("Expected 3 deployed, got %d", len(dpl))
+	}
+}
+
 func TestConfigMapQuery(t *testing.T) {
 	cfgmaps := newTestFixtureCfgMaps(t, []*rspb.Release{
 		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
@@ -33,6 +33,8 @@ var (
 	ErrInvalidKey = errors.New("release: invalid key")
 	// ErrNoDeployedReleases indicates that there are no releases with the given key in the deployed state
 	ErrNoDeployedReleases = errors.New("has no deployed releases")
+	// ErrInvalidContinue indicates that a continue token could not be parsed.
+	ErrInvalidContinue = errors.New("release: invalid continue token")
 )
 
 // StorageDriverError records an error and the release name that caused it
@@ -92,6 +94,35 @@ type Queryor interface {
 	Query(labels map[string]string) ([]*rspb.Release, error)
 }
 
+// ListOptions selects a page of the stored releases.
+type ListOptions struct {
+	// Limit is the maximum number of stored releases read for the page.
+	// Values of 0 or less read all the remaining releases.
+	Limit int
+	// Continue is the continue token returned with the previous page, or
+	// empty for the first page.
+	Continue string
+	// Labels are system labels, such as "status" or ChartLabel, that drivers
+	// may use to read only the releases having them. Drivers may also ignore
+	// them, so the filter must still check the releases it is given.
+	Labels map[string]string
+}
+
+// Pager is the interface that wraps the ListPage method.
+//
+// ListPage returns the releases of a page of the stored releases that
+// satisfy the filter predicate, and the continue token of the next page,
+// which is empty after the last page. Since the filter applies to the
+// releases read, a page may hold fewer than Limit releases, or none, even
+// before the last page. Continue tokens are opaque and specific to the
+// driver.
+//
+// Drivers whose backend can read releases a page at a time implement Pager,
+// so that releases can be listed without holding all of them in memory.
+type Pager interface {
+	ListPage(filter func(*rspb.Release) bool, opts ListOptions) ([]*rspb.Release, string, error)
+}
+
 // Driver is the interface composed of Creator, Updator, Deletor, and Queryor
 // interfaces. It defines the behavior for storing, updating, deleted,
 // and retrieving Helm releases from some underlying storage mechanism,
@@ -0,0 +1,201 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v4/pkg/storage/driver"
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// versionEncrypted is the version byte prefixing the encrypted releases. It
+// is followed by the length of the encrypted data key as a uvarint, the
+// encrypted data key, and the AES-GCM nonce and ciphertext of the compressed
+// release.
+const versionEncrypted byte = 0x02
+
+// dataKeySize is the size of the AES-256 data keys encrypting releases.
+const dataKeySize = 32
+
+// ErrNoKeyEncrypter indicates that a release is encrypted, but no key
+// encryption key is configured to decrypt it.
+var ErrNoKeyEncrypter = errors.New("release is encrypted, but no key encryption key is configured")
+
+// KeyEncrypter is a key encryption key (KEK), such as a key of a KMS or of
+// the transit secrets engine of Vault. It encrypts the data keys encrypting
+// the releases, so that reading the stored releases requires access to the
+// KEK, not only to the storage backend.
+type KeyEncrypter interface {
+	// EncryptKey encrypts the data key dek.
+	EncryptKey(ctx context.Context, dek []byte) ([]byte, error)
+	// DecryptKey decrypts a data key encrypted by EncryptKey.
+	DecryptKey(ctx context.Context, encrypted []byte) ([]byte, error)
+}
+
+// SetKeyEncrypter enables the envelope encryption of the releases encoded by
+// the driver with kek, or disables it if kek is nil. Releases are decoded
+// whether or not they are encrypted, but decoding encrypted releases
+// requires their KEK.
+func (c *codec) SetKeyEncrypter(kek KeyEncrypter) {
+	if kek == nil {
+		c.envelope = nil
+		return
+	}
+	c.envelope = &envelopeEncrypter{kek: kek, dataKeys: map[string][]byte{}}
+}
+
+// envelopeEncrypter encrypts releases with data keys encrypted by a KEK.
+//
+// The releases encoded by a driver share a data key, and the decrypted data
+// keys are cached, so that the KEK, which is usually remote, is only used
+// once per data key rather than once per release.
+type envelopeEncrypter struct {
+	kek KeyEncrypter
+
+	mu           sync.Mutex
+	dataKey      []byte
+	encryptedKey []byte
+	// dataKeys are the decrypted data keys by encrypted data key.
+	dataKeys map[string][]byte
+}
+
+// seal encrypts the payload of a release.
+func (e *envelopeEncrypter) seal(payload []byte) ([]byte, error) {
+	dataKey, encryptedKey, err := e.currentDataKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt the data key")
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := binary.AppendUvarint([]byte{versionEncrypted}, uint64(len(encryptedKey)))
+	header = append(header, encryptedKey...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// The header is authenticated, so that the data key cannot be swapped.
+	out := append(bytes.Clone(header), nonce...)
+	return aead.Seal(out, nonce, payload, header), nil
+}
+
+// open decrypts the payload of a release sealed by seal.
+func (e *envelopeEncrypter) open(data []byte) ([]byte, error) {
+	n, size := binary.Uvarint(data[1:])
+	if size <= 0 || n > uint64(len(data)) {
+		return nil, errors.New("invalid encrypted release")
+	}
+	headerSize := 1 + size + int(n)
+	if headerSize > len(data) {
+		return nil, errors.New("invalid encrypted release")
+	}
+	header, encryptedKey := data[:headerSize], data[1+size:headerSize]
+
+	dataKey, err := e.decryptDataKey(encryptedKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt the data key")
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	sealed := data[headerSize:]
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("invalid encrypted release")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	payload, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt release")
+	}
+	return payload, nil
+}
+
+// currentDataKey returns the data key of the driver and its encrypted
+// form, generating it on first use.
+func (e *envelopeEncrypter) currentDataKey() ([]byte, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.dataKey != nil {
+		return e.dataKey, e.encryptedKey, nil
+	}
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	encryptedKey, err := e.kek.EncryptKey(context.Background(), dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	e.dataKey, e.encryptedKey = dataKey, encryptedKey
+	e.dataKeys[string(encryptedKey)] = dataKey
+	return dataKey, encryptedKey, nil
+}
+
+// decryptDataKey returns the data key of encryptedKey, decrypting it with
+// the KEK unless it is cached.
+func (e *envelopeEncrypter) decryptDataKey(encryptedKey []byte) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if dataKey, ok := e.dataKeys[string(encryptedKey)]; ok {
+		return dataKey, nil
+	}
+	dataKey, err := e.kek.DecryptKey(context.Background(), encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey) != dataKeySize {
+		return nil, errors.Errorf("invalid data key of %d bytes", len(dataKey))
+	}
+	e.dataKeys[string(encryptedKey)] = dataKey
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt encrypts the payload of a release if encryption is enabled.
+func (c *codec) encrypt(payload []byte) ([]byte, error) {
+	if c.envelope == nil {
+		return payload, nil
+	}
+	return c.envelope.seal(payload)
+}
+
+// decrypt decrypts the payload of a release if it is encrypted.
+func (c *codec) decrypt(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != versionEncrypted {
+		return data, nil
+	}
+	if c.envelope == nil {
+		return nil, ErrNoKeyEncrypter
+	}
+	return c.envelope.open(data)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	rspb "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// fakeKeyEncrypter "encrypts" data keys by prefixing them, and counts the
+// keys it decrypts.
+type fakeKeyEncrypter struct {
+	decrypted int
+}
+
+func (f *fakeKeyEncrypter) EncryptKey(_ context.Context, dek []byte) ([]byte, error) {
+	return append([]byte("kek:"), dek...), nil
+}
+
+func (f *fakeKeyEncrypter) DecryptKey(_ context.Context, encrypted []byte) ([]byte, error) {
+	if !bytes.HasPrefix(encrypted, []byte("kek:")) {
+		return nil, errors.New("not encrypted by this key")
+	}
+	f.decrypted++
+	return encrypted[len("kek:"):], nil
+}
+
+func TestEncryptRelease(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	// Labels are stored by the drivers, not in the payload.
+	rel.Labels = nil
+
+	var c codec
+	plain, err := c.encodeRelease(rel)
+	if err != nil {
+		t.Fatalf("Failed to encode release: %s", err)
+	}
+
+	kek := &fakeKeyEncrypter{}
+	c.SetKeyEncrypter(kek)
+
+	data, err := c.encodeRelease(rel)
+	if err != nil {
+		t.Fatalf("Failed to encode encrypted release: %s", err)
+	}
+	b, _ := b64.DecodeString(data)
+	if b[0] != versionEncrypted {
+		t.Fatalf("Expected an encrypted payload, got %x", b[:4])
+	}
+	if bytes.Contains(b, []byte(rel.Name)) {
+		t.Error("Expected the release name not to be readable in the payload")
+	}
+
+	// A new driver decrypts the data key once for all its releases.
+	var reader codec
+	reader.SetKeyEncrypter(kek)
+	for _, d := range []string{data, data, plain} {
+		got, err := reader.decodeRelease(d)
+		if err != nil {
+			t.Fatalf("Failed to decode release: %s", err)
+		}
+		if !reflect.DeepEqual(rel, got) {
+			t.Errorf("Expected {%v}, got {%v}", rel, got)
+		}
+	}
+	if kek.decrypted != 1 {
+		t.Errorf("Expected the data key to be decrypted once, got %d", kek.decrypted)
+	}
+
+	// Tampering with the ciphertext is detected.
+	tampered := bytes.Clone(b)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := c.decodeRelease(b64.EncodeToString(tampered)); err == nil {
+		t.Error("Expected an error decoding a tampered release")
+	}
+
+	// The KEK of a driver is not shared with the others.
+	var other codec
+	if _, err := other.decodeRelease(data); err != ErrNoKeyEncrypter {
+		t.Errorf("Expected %v without a KEK, got %v", ErrNoKeyEncrypter, err)
+	}
+	c.SetKeyEncrypter(nil)
+	if _, err := c.decodeRelease(data); err != ErrNoKeyEncrypter {
+		t.Errorf("Expected %v once the KEK is unset, got %v", ErrNoKeyEncrypter, err)
+	}
+}
@@ -0,0 +1,399 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v4/pkg/storage/driver"
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+
+	rspb "helm.sh/helm/v4/pkg/release/v1"
+)
+
+var _ Driver = (*Etcd)(nil)
+
+// EtcdDriverName is the string name of this driver.
+const EtcdDriverName = "Etcd"
+
+// DefaultEtcdPrefix is the key prefix under which releases are stored when
+// none is configured.
+const DefaultEtcdPrefix = "/helm/releases"
+
+const defaultEtcdTimeout = 30 * time.Second
+
+// ErrEtcdModified indicates that a release was modified in etcd by another
+// process since the driver read it.
+var ErrEtcdModified = errors.New("etcd: release modified concurrently")
+
+// EtcdConfig configures the connection of the etcd driver.
+type EtcdConfig struct {
+	// Endpoints are the URLs of the etcd members, such as https://etcd-0:2379.
+	Endpoints []string
+	// Prefix is the key prefix under which releases are stored. It defaults
+	// to DefaultEtcdPrefix.
+	Prefix string
+	// TLSConfig is the TLS configuration used to connect to https endpoints.
+	TLSConfig *tls.Config
+	// Username and Password authenticate the driver when the authentication
+	// of etcd is enabled.
+	Username string
+	Password string
+	// Timeout bounds each request to etcd. It defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Etcd is the etcd storage driver implementation. It stores the releases
+// under a dedicated key prefix of an etcd cluster through the etcd v3 API,
+// outside the Kubernetes API.
+//
+// Releases are stored at <prefix>/<namespace>/<key> as a JSON document
+// holding the labels of the release and the release encoded as by the
+// Secrets driver. A release is only updated if it is unchanged since the
+// driver last read or wrote it, so that concurrent Helm processes cannot
+// silently overwrite each other's records.
+type Etcd struct {
+	codec
+	kv        clientv3.KV
+	prefix    string
+	namespace string
+	timeout   time.Duration
+
+	mu sync.Mutex
+	// revisions are the mod revisions of the releases last read or written,
+	// by etcd key.
+	revisions map[string]int64
+}
+
+// NewEtcd initializes a new etcd driver for the releases of namespace. An
+// empty namespace lists releases of all namespaces.
+func NewEtcd(cfg EtcdConfig, namespace string) (*Etcd, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, errors.New("no etcd endpoints configured")
+	}
+	prefix := strings.TrimSuffix(cfg.Prefix, "/")
+	if prefix == "" {
+		prefix = DefaultEtcdPrefix
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultEtcdTimeout
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   cfg.Endpoints,
+		TLS:         cfg.TLSConfig,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		DialTimeout: timeout,
+		// Errors are returned to the caller rather than logged.
+		Logger: zap.NewNop(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Etcd{
+		kv:        client,
+		prefix:    prefix,
+		namespace: namespace,
+		timeout:   timeout,
+		revisions: map[string]int64{},
+	}, nil
+}
+
+// SetNamespace sets a specific namespace in which releases will be accessed.
+// An empty string indicates all namespaces (for the list operation)
+func (e *Etcd) SetNamespace(ns string) {
+	e.namespace = ns
+}
+
+// Name returns the name of the driver.
+func (e *Etcd) Name() string {
+	return EtcdDriverName
+}
+
+// Get returns the release named by key.
+func (e *Etcd) Get(key string) (*rspb.Release, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	resp, err := e.kv.Get(ctx, e.key(e.namespace, key))
+	if err != nil {
+		return nil, errors.Wrapf(err, "get: failed to get %q", key)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, ErrReleaseNotFound
+	}
+	kv := resp.Kvs[0]
+	e.setRevision(string(kv.Key), kv.ModRevision)
+	rls, lbs, err := e.decodeEtcdRecord(kv.Value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get: failed to decode data %q", key)
+	}
+	rls.Labels = filterSystemLabels(lbs)
+	return rls, nil
+}
+
+// List returns the list of all releases such that filter(release) == true
+func (e *Etcd) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	resp, err := e.rangeNamespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "list: failed to list")
+	}
+
+	var results []*rspb.Release
+	for _, kv := range resp.Kvs {
+		rls, lbs, err := e.decodeEtcdRecord(kv.Value)
+		if err != nil {
+			slog.Debug("list failed to decode release", "key", string(kv.Key), slog.Any("error", err))
+			continue
+		}
+		if lbs["owner"] != "helm" {
+			continue
+		}
+		rls.Labels = lbs
+		if filter(rls) {
+			results = append(results, rls)
+		}
+	}
+	return results, nil
+}
+
+// Query returns the set of releases that match the provided set of labels.
+func (e *Etcd) Query(labels map[string]string) ([]*rspb.Release, error) {
+	resp, err := e.rangeNamespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "query: failed to query with labels")
+	}
+
+	var results []*rspb.Release
+	for _, kv := range resp.Kvs {
+		rls, lbs, err := e.decodeEtcdRecord(kv.Value)
+		if err != nil {
+			slog.Debug("failed to decode release", "key", string(kv.Key), slog.Any("error", err))
+			continue
+		}
+		if !matchLabels(lbs, labels) {
+			continue
+		}
+		rls.Labels = lbs
+		results = append(results, rls)
+	}
+	if len(results) == 0 {
+		return nil, ErrReleaseNotFound
+	}
+	return results, nil
+}
+
+// Create creates a new release or returns ErrReleaseExists.
+func (e *Etcd) Create(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("createdAt", strconv.FormatInt(time.Now().Unix(), 10))
+
+	value, err := e.encodeEtcdRecord(rls, lbs)
+	if err != nil {
+		return errors.Wrapf(err, "create: failed to encode release %q", rls.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	// Only put the release if its key was never created
+	etcdKey := e.key(releaseNamespace(rls), key)
+	resp, err := e.kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(etcdKey), "=", 0)).
+		Then(clientv3.OpPut(etcdKey, string(value))).
+		Commit()
+	if err != nil {
+		return errors.Wrap(err, "create: failed to create")
+	}
+	if !resp.Succeeded {
+		return ErrReleaseExists
+	}
+	e.setRevision(etcdKey, resp.Header.Revision)
+	return nil
+}
+
+// Update updates a release or returns ErrReleaseNotFound. It fails with
+// ErrEtcdModified if the release was modified by another process since the
+// driver last read or wrote it. A release the driver has not read yet is
+// read first.
+func (e *Etcd) Update(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("modifiedAt", strconv.FormatInt(time.Now().Unix(), 10))
+
+	value, err := e.encodeEtcdRecord(rls, lbs)
+	if err != nil {
+		return errors.Wrapf(err, "update: failed to encode release %q", rls.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	etcdKey := e.key(releaseNamespace(rls), key)
+	revision, ok := e.revision(etcdKey)
+	if !ok {
+		resp, err := e.kv.Get(ctx, etcdKey)
+		if err != nil {
+			return errors.Wrap(err, "update: failed to update")
+		}
+		if len(resp.Kvs) == 0 {
+			return ErrReleaseNotFound
+		}
+		revision = resp.Kvs[0].ModRevision
+	}
+
+	// Only put the release if it is unchanged since it was read
+	resp, err := e.kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(etcdKey), "=", revision)).
+		Then(clientv3.OpPut(etcdKey, string(value))).
+		Else(clientv3.OpGet(etcdKey, clientv3.WithKeysOnly())).
+		Commit()
+	if err != nil {
+		return errors.Wrap(err, "update: failed to update")
+	}
+	if !resp.Succeeded {
+		if len(resp.Responses) == 0 || len(resp.Responses[0].GetResponseRange().Kvs) == 0 {
+			e.forgetRevision(etcdKey)
+			return ErrReleaseNotFound
+		}
+		return errors.Wrapf(ErrEtcdModified, "update: failed to update %q", key)
+	}
+	e.setRevision(etcdKey, resp.Header.Revision)
+	return nil
+}
+
+// Delete deletes a release or returns ErrReleaseNotFound.
+func (e *Etcd) Delete(key string) (*rspb.Release, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	etcdKey := e.key(e.namespace, key)
+	resp, err := e.kv.Delete(ctx, etcdKey, clientv3.WithPrevKV())
+	if err != nil {
+		return nil, errors.Wrapf(err, "delete: failed to delete %q", key)
+	}
+	e.forgetRevision(etcdKey)
+	if len(resp.PrevKvs) == 0 {
+		return nil, ErrReleaseNotFound
+	}
+	rls, lbs, err := e.decodeEtcdRecord(resp.PrevKvs[0].Value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "delete: failed to decode data %q", key)
+	}
+	rls.Labels = filterSystemLabels(lbs)
+	return rls, nil
+}
+
+// key returns the etcd key of the release named by key in namespace.
+func (e *Etcd) key(namespace, key string) string {
+	return e.prefix + "/" + namespace + "/" + key
+}
+
+// rangeNamespace returns the key-values of the releases of the driver's
+// namespace, or of all namespaces, and records their revisions.
+func (e *Etcd) rangeNamespace() (*clientv3.GetResponse, error) {
+	prefix := e.prefix + "/"
+	if e.namespace != "" {
+		prefix += e.namespace + "/"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	resp, err := e.kv.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range resp.Kvs {
+		e.setRevision(string(kv.Key), kv.ModRevision)
+	}
+	return resp, nil
+}
+
+// revision returns the mod revision of the release at key last read or
+// written.
+func (e *Etcd) revision(key string) (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	revision, ok := e.revisions[key]
+	return revision, ok
+}
+
+func (e *Etcd) setRevision(key string, revision int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.revisions[key] = revision
+}
+
+func (e *Etcd) forgetRevision(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.revisions, key)
+}
+
+// etcdRecord is the value stored for a release.
+type etcdRecord struct {
+	Labels  map[string]string `json:"labels"`
+	Release string            `json:"release"`
+}
+
+func (e *Etcd) encodeEtcdRecord(rls *rspb.Release, lbs labels) ([]byte, error) {
+	s, err := e.encodeRelease(rls)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply labels
+	lbs.set("name", rls.Name)
+	lbs.set("owner", "helm")
+	lbs.set("status", rls.Info.Status.String())
+	lbs.set("version", strconv.Itoa(rls.Version))
+	for k, v := range chartLabels(rls) {
+		lbs.set(k, v)
+	}
+
+	return json.Marshal(etcdRecord{Labels: lbs.toMap(), Release: s})
+}
+
+func (e *Etcd) decodeEtcdRecord(data []byte) (*rspb.Release, map[string]string, error) {
+	var record etcdRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, nil, err
+	}
+	rls, err := e.decodeRelease(record.Release)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record.Labels == nil {
+		record.Labels = map[string]string{}
+	}
+	return rls, record.Labels, nil
+}
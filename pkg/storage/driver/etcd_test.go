@@ -0,0 +1,266 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	rspb "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func TestEtcdName(t *testing.T) {
+	etcd, _ := newTestFixtureEtcd(t)
+	if etcd.Name() != EtcdDriverName {
+		t.Errorf("Expected name to be %q, got %q", EtcdDriverName, etcd.Name())
+	}
+}
+
+func TestEtcdGet(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	etcd, mock := newTestFixtureEtcd(t, []*rspb.Release{rel}...)
+
+	if _, ok := mock.kvs[DefaultEtcdPrefix+"/default/"+key]; !ok {
+		t.Errorf("Expected release to be stored under %s, got keys %v", DefaultEtcdPrefix, mock.kvs)
+	}
+
+	// get release with key
+	got, err := etcd.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release: %s", err)
+	}
+	// compare fetched release with original
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	if _, err := etcd.Get(testKey(name, 2)); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v for a missing release, got %v", ErrReleaseNotFound, err)
+	}
+}
+
+func TestEtcdList(t *testing.T) {
+	etcd, _ := newTestFixtureEtcd(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-2", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-4", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-5", 1, "default", rspb.StatusSuperseded),
+		releaseStub("key-6", 1, "other", rspb.StatusSuperseded),
+	}...)
+
+	// list all deployed releases
+	dpl, err := etcd.List(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusDeployed
+	})
+	if err != nil {
+		t.Errorf("Failed to list deployed: %s", err)
+	}
+	if len(dpl) != 2 {
+		t.Errorf("Expected 2 deployed, got %d", len(dpl))
+	}
+
+	// releases of other namespaces are only listed for all namespaces
+	ssd, err := etcd.List(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusSuperseded
+	})
+	if err != nil {
+		t.Errorf("Failed to list superseded: %s", err)
+	}
+	if len(ssd) != 1 {
+		t.Errorf("Expected 1 superseded, got %d", len(ssd))
+	}
+
+	etcd.SetNamespace("")
+	all, err := etcd.List(func(*rspb.Release) bool { return true })
+	if err != nil {
+		t.Errorf("Failed to list all namespaces: %s", err)
+	}
+	if len(all) != 6 {
+		t.Errorf("Expected 6 releases, got %d", len(all))
+	}
+
+	// Check if release having both system and custom labels, this is needed to ensure that selector filtering would work.
+	rls := ssd[0]
+	if _, ok := rls.Labels["name"]; !ok {
+		t.Fatalf("Expected 'name' label in results, actual %v", rls.Labels)
+	}
+	if _, ok := rls.Labels["key1"]; !ok {
+		t.Fatalf("Expected 'key1' label in results, actual %v", rls.Labels)
+	}
+}
+
+func TestEtcdQuery(t *testing.T) {
+	etcd, _ := newTestFixtureEtcd(t, []*rspb.Release{
+		releaseStub("smug-pigeon", 1, "default", rspb.StatusSuperseded),
+		releaseStub("smug-pigeon", 2, "default", rspb.StatusDeployed),
+		releaseStub("other", 1, "default", rspb.StatusDeployed),
+	}...)
+
+	rls, err := etcd.Query(map[string]string{"name": "smug-pigeon", "owner": "helm", "status": "deployed"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 1 || rls[0].Version != 2 {
+		t.Errorf("Expected version 2 of smug-pigeon, got %v", rls)
+	}
+
+	rls, err = etcd.Query(map[string]string{"name": "smug-pigeon", "owner": "helm"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 2 {
+		t.Errorf("Expected 2 releases, got %d", len(rls))
+	}
+
+	if _, err := etcd.Query(map[string]string{"name": "smug-pigeon", "status": "unknown"}); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v, got %v", ErrReleaseNotFound, err)
+	}
+}
+
+func TestEtcdCreate(t *testing.T) {
+	etcd, _ := newTestFixtureEtcd(t)
+
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	// store the release in etcd
+	if err := etcd.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+
+	// get the release back
+	got, err := etcd.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+
+	// compare created release with original
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	if err := etcd.Create(key, rel); err != ErrReleaseExists {
+		t.Errorf("Expected %v creating the release again, got %v", ErrReleaseExists, err)
+	}
+}
+
+func TestEtcdUpdate(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	etcd, _ := newTestFixtureEtcd(t, []*rspb.Release{rel}...)
+
+	// modify release status code
+	rel.Info.Status = rspb.StatusSuperseded
+
+	// perform the update
+	if err := etcd.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+
+	// fetch the updated release
+	got, err := etcd.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+
+	// check release has actually been updated by comparing modified fields
+	if rel.Info.Status != got.Info.Status {
+		t.Errorf("Expected status %s, got status %s", rel.Info.Status.String(), got.Info.Status.String())
+	}
+
+	if err := etcd.Update(testKey(name, 2), rel); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v updating a missing release, got %v", ErrReleaseNotFound, err)
+	}
+}
+
+func TestEtcdDelete(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	etcd, _ := newTestFixtureEtcd(t, []*rspb.Release{rel}...)
+
+	// perform the delete on a non-existing release
+	if _, err := etcd.Delete("nonexistent"); err != ErrReleaseNotFound {
+		t.Fatalf("Expected %v, got %v", ErrReleaseNotFound, err)
+	}
+
+	// perform the delete
+	rls, err := etcd.Delete(key)
+	if err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, rls) {
+		t.Errorf("Expected {%v}, got {%v}", rel, rls)
+	}
+
+	// fetch the deleted release
+	if _, err := etcd.Get(key); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v after delete, got %v", ErrReleaseNotFound, err)
+	}
+}
+
+func TestEtcdConcurrentUpdate(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+
+	etcd, mock := newTestFixtureEtcd(t, rel)
+	if _, err := etcd.Get(key); err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+
+	// Another process updates the release after it was read
+	mock.modify(DefaultEtcdPrefix + "/default/" + key)
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := etcd.Update(key, rel); !errors.Is(err, ErrEtcdModified) {
+		t.Errorf("Expected %v updating a concurrently modified release, got %v", ErrEtcdModified, err)
+	}
+
+	// The release is updated once it is read again.
+	if _, err := etcd.Get(key); err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if err := etcd.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	if err := etcd.Update(key, rel); err != nil {
+		t.Errorf("Failed to update the release written by the driver: %s", err)
+	}
+
+	// A release deleted by another process is not recreated.
+	mock.Delete(context.Background(), DefaultEtcdPrefix+"/default/"+key)
+	if err := etcd.Update(key, rel); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v updating a deleted release, got %v", ErrReleaseNotFound, err)
+	}
+}
@@ -0,0 +1,327 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v4/pkg/storage/driver"
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/internal/fileutil"
+	rspb "helm.sh/helm/v4/pkg/release/v1"
+)
+
+var _ Driver = (*Filesystem)(nil)
+
+// FilesystemDriverName is the string name of this driver.
+const FilesystemDriverName = "Filesystem"
+
+const (
+	filesystemRecordExt  = ".json"
+	filesystemLockFile   = ".lock"
+	filesystemLockWait   = 30 * time.Second
+	filesystemLockRetry  = 100 * time.Millisecond
+	filesystemRecordMode = 0600
+)
+
+// Filesystem is the storage driver persisting releases as files of a local
+// directory, for development clusters, CI sandboxes and rendering-only
+// environments. Releases are stored at <dir>/<namespace>/<key>.json as
+// labeled records.
+//
+// A lock file in the directory serializes the writes of concurrent Helm
+// processes, and files are written atomically so readers never see partial
+// records.
+type Filesystem struct {
+	codec
+	dir       string
+	namespace string
+
+	// mu serializes the operations of the process, since the state of the
+	// file lock is shared by all its users.
+	mu   sync.Mutex
+	lock *flock.Flock
+}
+
+// NewFilesystem initializes a new filesystem driver storing the releases of
+// namespace in dir, which is created if needed. An empty namespace lists
+// releases of all namespaces.
+func NewFilesystem(dir string, namespace string) (*Filesystem, error) {
+	if dir == "" {
+		return nil, errors.New("no directory configured")
+	}
+	// Releases may hold secrets, such as values, so they are private.
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Filesystem{
+		dir:       dir,
+		namespace: namespace,
+		lock:      flock.New(filepath.Join(dir, filesystemLockFile)),
+	}, nil
+}
+
+// SetNamespace sets a specific namespace in which releases will be accessed.
+// An empty string indicates all namespaces (for the list operation)
+func (fs *Filesystem) SetNamespace(ns string) {
+	fs.namespace = ns
+}
+
+// Name returns the name of the driver.
+func (fs *Filesystem) Name() string {
+	return FilesystemDriverName
+}
+
+// Get returns the release named by key.
+func (fs *Filesystem) Get(key string) (*rspb.Release, error) {
+	unlock, err := fs.acquire(false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get: failed to get %q", key)
+	}
+	defer unlock()
+
+	return fs.read(fs.namespace, key)
+}
+
+// List returns the list of all releases such that filter(release) == true
+func (fs *Filesystem) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	unlock, err := fs.acquire(false)
+	if err != nil {
+		return nil, errors.Wrap(err, "list: failed to list")
+	}
+	defer unlock()
+
+	records, err := fs.readNamespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "list: failed to list")
+	}
+
+	var results []*rspb.Release
+	for _, record := range records {
+		if record.labels["owner"] != "helm" {
+			continue
+		}
+		if filter(record.rls) {
+			results = append(results, record.rls)
+		}
+	}
+	return results, nil
+}
+
+// Query returns the set of releases that match the provided set of labels.
+func (fs *Filesystem) Query(labels map[string]string) ([]*rspb.Release, error) {
+	unlock, err := fs.acquire(false)
+	if err != nil {
+		return nil, errors.Wrap(err, "query: failed to query with labels")
+	}
+	defer unlock()
+
+	records, err := fs.readNamespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "query: failed to query with labels")
+	}
+
+	var results []*rspb.Release
+	for _, record := range records {
+		if matchLabels(record.labels, labels) {
+			results = append(results, record.rls)
+		}
+	}
+	if len(results) == 0 {
+		return nil, ErrReleaseNotFound
+	}
+	return results, nil
+}
+
+// Create creates a new release or returns ErrReleaseExists.
+func (fs *Filesystem) Create(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("createdAt", strconv.FormatInt(time.Now().Unix(), 10))
+
+	path, err := fs.path(releaseNamespace(rls), key)
+	if err != nil {
+		return err
+	}
+	unlock, err := fs.acquire(true)
+	if err != nil {
+		return errors.Wrap(err, "create: failed to create")
+	}
+	defer unlock()
+
+	if _, err := os.Stat(path); err == nil {
+		return ErrReleaseExists
+	}
+	return errors.Wrap(fs.write(path, rls, lbs), "create: failed to create")
+}
+
+// Update updates a release or returns ErrReleaseNotFound.
+func (fs *Filesystem) Update(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("modifiedAt", strconv.FormatInt(time.Now().Unix(), 10))
+
+	path, err := fs.path(releaseNamespace(rls), key)
+	if err != nil {
+		return err
+	}
+	unlock, err := fs.acquire(true)
+	if err != nil {
+		return errors.Wrap(err, "update: failed to update")
+	}
+	defer unlock()
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return ErrReleaseNotFound
+	}
+	return errors.Wrap(fs.write(path, rls, lbs), "update: failed to update")
+}
+
+// Delete deletes a release or returns ErrReleaseNotFound.
+func (fs *Filesystem) Delete(key string) (*rspb.Release, error) {
+	path, err := fs.path(fs.namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := fs.acquire(true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "delete: failed to delete %q", key)
+	}
+	defer unlock()
+
+	rls, err := fs.read(fs.namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, errors.Wrapf(err, "delete: failed to delete %q", key)
+	}
+	return rls, nil
+}
+
+// acquire takes the lock of the directory, shared to read or exclusive to
+// write, and returns the function releasing it.
+func (fs *Filesystem) acquire(write bool) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), filesystemLockWait)
+	defer cancel()
+
+	fs.mu.Lock()
+	tryLock := fs.lock.TryRLockContext
+	if write {
+		tryLock = fs.lock.TryLockContext
+	}
+	if _, err := tryLock(ctx, filesystemLockRetry); err != nil {
+		fs.mu.Unlock()
+		return nil, errors.Wrapf(err, "failed to lock %s", fs.dir)
+	}
+	return func() {
+		if err := fs.lock.Unlock(); err != nil {
+			slog.Debug("failed to unlock", "dir", fs.dir, slog.Any("error", err))
+		}
+		fs.mu.Unlock()
+	}, nil
+}
+
+// path returns the path of the file of the release named by key in
+// namespace.
+func (fs *Filesystem) path(namespace, key string) (string, error) {
+	// Neither may escape the directory of the driver.
+	for _, name := range []string{namespace, key} {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return "", ErrInvalidKey
+		}
+	}
+	return filepath.Join(fs.dir, namespace, key+filesystemRecordExt), nil
+}
+
+func (fs *Filesystem) read(namespace, key string) (*rspb.Release, error) {
+	path, err := fs.path(namespace, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "get: failed to get %q", key)
+	}
+	rls, lbs, err := fs.decodeLabeledRecord(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get: failed to decode data %q", key)
+	}
+	rls.Labels = filterSystemLabels(lbs)
+	return rls, nil
+}
+
+func (fs *Filesystem) write(path string, rls *rspb.Release, lbs labels) error {
+	data, err := fs.encodeLabeledRecord(rls, lbs)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode release %q", rls.Name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return fileutil.AtomicWriteFile(path, bytes.NewReader(data), filesystemRecordMode)
+}
+
+type filesystemRecord struct {
+	rls    *rspb.Release
+	labels map[string]string
+}
+
+// readNamespace reads the releases of the driver's namespace, or of all
+// namespaces.
+func (fs *Filesystem) readNamespace() ([]filesystemRecord, error) {
+	pattern := filepath.Join(fs.dir, "*", "*"+filesystemRecordExt)
+	if fs.namespace != "" {
+		pattern = filepath.Join(fs.dir, fs.namespace, "*"+filesystemRecordExt)
+	}
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []filesystemRecord
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		rls, lbs, err := fs.decodeLabeledRecord(data)
+		if err != nil {
+			slog.Debug("failed to decode release", "path", path, slog.Any("error", err))
+			continue
+		}
+		rls.Labels = lbs
+		records = append(records, filesystemRecord{rls: rls, labels: lbs})
+	}
+	return records, nil
+}
@@ -0,0 +1,181 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	rspb "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func newTestFixtureFilesystem(t *testing.T, releases ...*rspb.Release) *Filesystem {
+	t.Helper()
+
+	fs, err := NewFilesystem(filepath.Join(t.TempDir(), "releases"), "default")
+	if err != nil {
+		t.Fatalf("Failed to create filesystem driver: %s", err)
+	}
+	for _, rls := range releases {
+		if err := fs.Create(testKey(rls.Name, rls.Version), rls); err != nil {
+			t.Fatalf("Failed to create release %s: %s", rls.Name, err)
+		}
+	}
+	return fs
+}
+
+func TestFilesystemName(t *testing.T) {
+	fs := newTestFixtureFilesystem(t)
+	if fs.Name() != FilesystemDriverName {
+		t.Errorf("Expected name to be %q, got %q", FilesystemDriverName, fs.Name())
+	}
+}
+
+func TestFilesystemGet(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	fs := newTestFixtureFilesystem(t, []*rspb.Release{rel}...)
+
+	info, err := os.Stat(filepath.Join(fs.dir, namespace, key+".json"))
+	if err != nil {
+		t.Fatalf("Expected the release file: %s", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the release file to be private, got mode %v", info.Mode().Perm())
+	}
+
+	// get release with key
+	got, err := fs.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release: %s", err)
+	}
+	// compare fetched release with original
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	if _, err := fs.Get(testKey(name, 2)); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v for a missing release, got %v", ErrReleaseNotFound, err)
+	}
+	if _, err := fs.Get("../escape"); err != ErrInvalidKey {
+		t.Errorf("Expected %v for a key outside the directory, got %v", ErrInvalidKey, err)
+	}
+}
+
+func TestFilesystemListAndQuery(t *testing.T) {
+	fs := newTestFixtureFilesystem(t, []*rspb.Release{
+		releaseStub("smug-pigeon", 1, "default", rspb.StatusSuperseded),
+		releaseStub("smug-pigeon", 2, "default", rspb.StatusDeployed),
+		releaseStub("other", 1, "default", rspb.StatusDeployed),
+		releaseStub("elsewhere", 1, "other", rspb.StatusDeployed),
+	}...)
+
+	dpl, err := fs.List(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusDeployed
+	})
+	if err != nil {
+		t.Fatalf("Failed to list deployed: %s", err)
+	}
+	if len(dpl) != 2 {
+		t.Errorf("Expected 2 deployed, got %d", len(dpl))
+	}
+
+	rls, err := fs.Query(map[string]string{"name": "smug-pigeon", "owner": "helm", "status": "deployed"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 1 || rls[0].Version != 2 {
+		t.Errorf("Expected version 2 of smug-pigeon, got %v", rls)
+	}
+
+	if _, err := fs.Query(map[string]string{"name": "elsewhere"}); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v querying another namespace, got %v", ErrReleaseNotFound, err)
+	}
+
+	fs.SetNamespace("")
+	all, err := fs.List(func(*rspb.Release) bool { return true })
+	if err != nil {
+		t.Fatalf("Failed to list all namespaces: %s", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Expected 4 releases, got %d", len(all))
+	}
+}
+
+func TestFilesystemCreateAndUpdate(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+
+	fs := newTestFixtureFilesystem(t, rel)
+
+	if err := fs.Create(key, rel); err != ErrReleaseExists {
+		t.Errorf("Expected %v creating the release again, got %v", ErrReleaseExists, err)
+	}
+
+	// modify release status code
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := fs.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+
+	// Another driver on the same directory sees the update
+	other, err := NewFilesystem(fs.dir, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := other.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if got.Info.Status != rspb.StatusSuperseded {
+		t.Errorf("Expected status %s, got status %s", rspb.StatusSuperseded, got.Info.Status)
+	}
+
+	if err := fs.Update(testKey(rel.Name, 2), rel); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v updating a missing release, got %v", ErrReleaseNotFound, err)
+	}
+}
+
+func TestFilesystemDelete(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+
+	fs := newTestFixtureFilesystem(t, rel)
+
+	if _, err := fs.Delete("nonexistent"); err != ErrReleaseNotFound {
+		t.Fatalf("Expected %v, got %v", ErrReleaseNotFound, err)
+	}
+
+	rls, err := fs.Delete(key)
+	if err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, rls) {
+		t.Errorf("Expected {%v}, got {%v}", rel, rls)
+	}
+
+	if _, err := fs.Get(key); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v after delete, got %v", ErrReleaseNotFound, err)
+	}
+}
@@ -17,13 +17,21 @@ limitations under the License.
 package driver // import "helm.sh/helm/v4/pkg/storage/driver"
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
 
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -34,6 +42,9 @@ import (
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
 
+// testCodec encodes the fixtures of the tests as the drivers do by default.
+var testCodec codec
+
 func releaseStub(name string, vers int, namespace string, status rspb.Status) *rspb.Release {
 	return &rspb.Release{
 		Name:      name,
@@ -103,7 +114,7 @@ func (mock *MockConfigMapsInterface) Init(t *testing.T, releases ...*rspb.Releas
 	for _, rls := range releases {
 		objkey := testKey(rls.Name, rls.Version)
 
-		cfgmap, err := newConfigMapsObject(objkey, rls, nil)
+		cfgmap, err := newConfigMapsObject(&testCodec, objkey, rls, nil)
 		if err != nil {
 			t.Fatalf("Failed to create configmap: %s", err)
 		}
@@ -129,8 +140,8 @@ func (mock *MockConfigMapsInterface) List(_ context.Context, opts metav1.ListOpt
 		return nil, err
 	}
 
-	for _, cfgmap := range mock.objects {
-		if labelSelector.Matches(kblabels.Set(cfgmap.Labels)) {
+	for _, name := range pageNames(mock.objects, opts, &list.Continue) {
+		if cfgmap := mock.objects[name]; labelSelector.Matches(kblabels.Set(cfgmap.Labels)) {
 			list.Items = append(list.Items, *cfgmap)
 		}
 	}
@@ -168,6 +179,25 @@ func (mock *MockConfigMapsInterface) Delete(_ context.Context, name string, _ me
 
 // newTestFixtureSecrets initializes a MockSecretsInterface.
 // Secrets are created for each release provided.
+// pageNames returns the names of the page of objects selected by the limit
+// and continue token of opts, in order, and sets next to the continue token
+// of the next page. The continue token is the name of the last object of the
+// previous page.
+func pageNames[T any](objects map[string]T, opts metav1.ListOptions, next *string) []string {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		if name > opts.Continue {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if opts.Limit > 0 && int64(len(names)) > opts.Limit {
+		names = names[:opts.Limit]
+		*next = names[len(names)-1]
+	}
+	return names
+}
+
 func newTestFixtureSecrets(t *testing.T, releases ...*rspb.Release) *Secrets {
 	var mock MockSecretsInterface
 	mock.Init(t, releases...)
@@ -189,7 +219,7 @@ func (mock *MockSecretsInterface) Init(t *testing.T, releases ...*rspb.Release)
 	for _, rls := range releases {
 		objkey := testKey(rls.Name, rls.Version)
 
-		secret, err := newSecretsObject(objkey, rls, nil)
+		secret, err := newSecretsObject(&testCodec, objkey, rls, nil)
 		if err != nil {
 			t.Fatalf("Failed to create secret: %s", err)
 		}
@@ -215,8 +245,8 @@ func (mock *MockSecretsInterface) List(_ context.Context, opts metav1.ListOption
 		return nil, err
 	}
 
-	for _, secret := range mock.objects {
-		if labelSelector.Matches(kblabels.Set(secret.Labels)) {
+	for _, name := range pageNames(mock.objects, opts, &list.Continue) {
+		if secret := mock.objects[name]; labelSelector.Matches(kblabels.Set(secret.Labels)) {
 			list.Items = append(list.Items, *secret)
 		}
 	}
@@ -266,3 +296,273 @@ func newTestFixtureSQL(t *testing.T, _ ...*rspb.Release) (*SQL, sqlmock.Sqlmock)
 		statementBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
 	}, mock
 }
+
+// newTestFixtureEtcd initializes an etcd driver on a MockEtcd, and creates
+// the releases provided.
+func newTestFixtureEtcd(t *testing.T, releases ...*rspb.Release) (*Etcd, *MockEtcd) {
+	t.Helper()
+
+	mock := &MockEtcd{kvs: map[string]*mvccpb.KeyValue{}}
+	etcd := &Etcd{
+		kv:        mock,
+		prefix:    DefaultEtcdPrefix,
+		namespace: "default",
+		timeout:   defaultEtcdTimeout,
+		revisions: map[string]int64{},
+	}
+	for _, rls := range releases {
+		if err := etcd.Create(testKey(rls.Name, rls.Version), rls); err != nil {
+			t.Fatalf("Failed to create release %s: %s", rls.Name, err)
+		}
+	}
+	return etcd, mock
+}
+
+// MockEtcd mocks the etcd v3 KV API in memory.
+type MockEtcd struct {
+	sync.Mutex
+	kvs      map[string]*mvccpb.KeyValue
+	revision int64
+}
+
+func (mock *MockEtcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	resp, err := mock.Do(ctx, clientv3.OpPut(key, val, opts...))
+	return resp.Put(), err
+}
+
+func (mock *MockEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	resp, err := mock.Do(ctx, clientv3.OpGet(key, opts...))
+	return resp.Get(), err
+}
+
+func (mock *MockEtcd) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	resp, err := mock.Do(ctx, clientv3.OpDelete(key, opts...))
+	return resp.Del(), err
+}
+
+func (mock *MockEtcd) Compact(context.Context, int64, ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	return nil, errors.New("compact is not supported")
+}
+
+func (mock *MockEtcd) Do(_ context.Context, op clientv3.Op) (clientv3.OpResponse, error) {
+	mock.Lock()
+	defer mock.Unlock()
+	return mock.do(op), nil
+}
+
+func (mock *MockEtcd) Txn(context.Context) clientv3.Txn {
+	return &mockEtcdTxn{mock: mock}
+}
+
+// modify simulates another process writing the value at key.
+func (mock *MockEtcd) modify(key string) {
+	mock.Lock()
+	defer mock.Unlock()
+	mock.do(clientv3.OpPut(key, string(mock.kvs[key].Value)))
+}
+
+func (mock *MockEtcd) do(op clientv3.Op) clientv3.OpResponse {
+	header := func() *etcdserverpb.ResponseHeader {
+		return &etcdserverpb.ResponseHeader{Revision: mock.revision}
+	}
+	switch {
+	case op.IsPut():
+		mock.revision++
+		key := string(op.KeyBytes())
+		kv := &mvccpb.KeyValue{Key: op.KeyBytes(), Value: op.ValueBytes(), CreateRevision: mock.revision, ModRevision: mock.revision}
+		if prev, ok := mock.kvs[key]; ok {
+			kv.CreateRevision = prev.CreateRevision
+		}
+		mock.kvs[key] = kv
+		return (&clientv3.PutResponse{Header: header()}).OpResponse()
+	case op.IsDelete():
+		kvs := mock.rangeKeys(op.KeyBytes(), op.RangeBytes())
+		for _, kv := range kvs {
+			delete(mock.kvs, string(kv.Key))
+		}
+		return (&clientv3.DeleteResponse{Header: header(), Deleted: int64(len(kvs)), PrevKvs: kvs}).OpResponse()
+	default:
+		kvs := mock.rangeKeys(op.KeyBytes(), op.RangeBytes())
+		return (&clientv3.GetResponse{Header: header(), Kvs: kvs, Count: int64(len(kvs))}).OpResponse()
+	}
+}
+
+func (mock *MockEtcd) rangeKeys(key, rangeEnd []byte) []*mvccpb.KeyValue {
+	var kvs []*mvccpb.KeyValue
+	for k, kv := range mock.kvs {
+		if rangeEnd == nil {
+			if k == string(key) {
+				kvs = append(kvs, kv)
+			}
+			continue
+		}
+		if bytes.Compare([]byte(k), key) >= 0 && bytes.Compare([]byte(k), rangeEnd) < 0 {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return bytes.Compare(kvs[i].Key, kvs[j].Key) < 0 })
+	return kvs
+}
+
+// mockEtcdTxn is a transaction of a MockEtcd comparing revisions.
+type mockEtcdTxn struct {
+	mock      *MockEtcd
+	cmps      []clientv3.Cmp
+	then, els []clientv3.Op
+}
+
+func (txn *mockEtcdTxn) If(cmps ...clientv3.Cmp) clientv3.Txn {
+	txn.cmps = append(txn.cmps, cmps...)
+	return txn
+}
+
+func (txn *mockEtcdTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	txn.then = append(txn.then, ops...)
+	return txn
+}
+
+func (txn *mockEtcdTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	txn.els = append(txn.els, ops...)
+	return txn
+}
+
+func (txn *mockEtcdTxn) Commit() (*clientv3.TxnResponse, error) {
+	mock := txn.mock
+	mock.Lock()
+	defer mock.Unlock()
+
+	succeeded := true
+	for _, cmp := range txn.cmps {
+		c := etcdserverpb.Compare(cmp)
+		var actual, expected int64
+		kv := mock.kvs[string(c.Key)]
+		switch c.Target {
+		case etcdserverpb.Compare_CREATE:
+			expected = c.GetCreateRevision()
+			if kv != nil {
+				actual = kv.CreateRevision
+			}
+		case etcdserverpb.Compare_MOD:
+			expected = c.GetModRevision()
+			if kv != nil {
+				actual = kv.ModRevision
+			}
+		default:
+			return nil, errors.Errorf("unsupported comparison of %s", c.Target)
+		}
+		if c.Result != etcdserverpb.Compare_EQUAL {
+			return nil, errors.Errorf("unsupported comparison result %s", c.Result)
+		}
+		succeeded = succeeded && actual == expected
+	}
+
+	ops := txn.then
+	if !succeeded {
+		ops = txn.els
+	}
+	resp := &clientv3.TxnResponse{Succeeded: succeeded}
+	for _, op := range ops {
+		r := mock.do(op)
+		switch {
+		case op.IsPut():
+			resp.Responses = append(resp.Responses, &etcdserverpb.ResponseOp{Response: &etcdserverpb.ResponseOp_ResponsePut{ResponsePut: (*etcdserverpb.PutResponse)(r.Put())}})
+		case op.IsDelete():
+			resp.Responses = append(resp.Responses, &etcdserverpb.ResponseOp{Response: &etcdserverpb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: (*etcdserverpb.DeleteRangeResponse)(r.Del())}})
+		default:
+			resp.Responses = append(resp.Responses, &etcdserverpb.ResponseOp{Response: &etcdserverpb.ResponseOp_ResponseRange{ResponseRange: (*etcdserverpb.RangeResponse)(r.Get())}})
+		}
+	}
+	resp.Header = &etcdserverpb.ResponseHeader{Revision: mock.revision}
+	return resp, nil
+}
+
+// newTestFixtureObjectStorage initializes an ObjectStorage driver on a
+// MockObjectStore, and creates the releases provided.
+func newTestFixtureObjectStorage(t *testing.T, releases ...*rspb.Release) (*ObjectStorage, *MockObjectStore) {
+	t.Helper()
+
+	mock := &MockObjectStore{objects: map[string]mockObject{}}
+	o := NewObjectStorage(mock, "helm/releases", "default")
+	for _, rls := range releases {
+		if err := o.Create(testKey(rls.Name, rls.Version), rls); err != nil {
+			t.Fatalf("Failed to create release %s: %s", rls.Name, err)
+		}
+	}
+	return o, mock
+}
+
+// MockObjectStore mocks an ObjectStore in memory.
+type MockObjectStore struct {
+	sync.Mutex
+	objects  map[string]mockObject
+	versions int
+}
+
+type mockObject struct {
+	data    []byte
+	version string
+}
+
+func (mock *MockObjectStore) Get(_ context.Context, key string) ([]byte, string, error) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	obj, ok := mock.objects[key]
+	if !ok {
+		return nil, "", ErrObjectNotFound
+	}
+	return obj.data, obj.version, nil
+}
+
+func (mock *MockObjectStore) Put(_ context.Context, key string, data []byte, ifVersion string) (string, error) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	if obj, ok := mock.objects[key]; ok != (ifVersion != "") || obj.version != ifVersion {
+		return "", ErrObjectModified
+	}
+	mock.versions++
+	version := fmt.Sprint(mock.versions)
+	mock.objects[key] = mockObject{data: data, version: version}
+	return version, nil
+}
+
+// modify simulates another process writing the object at key.
+func (mock *MockObjectStore) modify(key string) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	mock.versions++
+	obj := mock.objects[key]
+	obj.version = fmt.Sprint(mock.versions)
+	mock.objects[key] = obj
+}
+
+func (mock *MockObjectStore) Delete(_ context.Context, key string, ifVersion string) error {
+	mock.Lock()
+	defer mock.Unlock()
+
+	obj, ok := mock.objects[key]
+	if !ok {
+		return ErrObjectNotFound
+	}
+	if obj.version != ifVersion {
+		return ErrObjectModified
+	}
+	delete(mock.objects, key)
+	return nil
+}
+
+func (mock *MockObjectStore) List(_ context.Context, prefix string) ([]string, error) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	var keys []string
+	for key := range mock.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
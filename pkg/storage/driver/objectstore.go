@@ -0,0 +1,362 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v4/pkg/storage/driver"
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	rspb "helm.sh/helm/v4/pkg/release/v1"
+)
+
+var _ Driver = (*ObjectStorage)(nil)
+
+// ObjectStorageDriverName is the string name of this driver.
+const ObjectStorageDriverName = "ObjectStorage"
+
+// objectStorageReads is the number of objects read at once when listing and
+// querying releases.
+const objectStorageReads = 16
+
+var (
+	// ErrObjectNotFound indicates that an object does not exist in an object store.
+	ErrObjectNotFound = errors.New("object: not found")
+	// ErrObjectModified indicates that the condition of a conditional write
+	// failed, because the object was created, changed or deleted since it
+	// was read.
+	ErrObjectModified = errors.New("object: modified concurrently")
+)
+
+// ObjectStore is a bucket of an object store, such as S3, GCS or Azure Blob
+// Storage, that supports conditional writes.
+//
+// Each version of an object is identified by an opaque string, such as its
+// ETag or generation.
+type ObjectStore interface {
+	// Get returns the data and the version of the object at key, or
+	// ErrObjectNotFound.
+	Get(ctx context.Context, key string) (data []byte, version string, err error)
+	// Put writes the object at key if its current version is ifVersion, or
+	// if it does not exist when ifVersion is empty, and returns its new
+	// version. Otherwise it returns ErrObjectModified.
+	Put(ctx context.Context, key string, data []byte, ifVersion string) (version string, err error)
+	// Delete deletes the object at key if its current version is ifVersion.
+	// It returns ErrObjectNotFound if the object does not exist, and
+	// ErrObjectModified if its version is another.
+	Delete(ctx context.Context, key string, ifVersion string) error
+	// List returns the keys of the objects starting with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// ObjectStorage is the storage driver persisting releases in an object store.
+// Releases are stored at <prefix>/<namespace>/<key> as labeled records, and
+// are only ever written with conditional writes, so that concurrent Helm
+// processes cannot silently overwrite each other's records: a release is
+// only updated or deleted if it is unchanged since the driver last read or
+// wrote it.
+//
+// Since object stores cannot filter on labels, listing and querying releases
+// reads every release of the namespace.
+type ObjectStorage struct {
+	codec
+	store     ObjectStore
+	prefix    string
+	namespace string
+
+	mu sync.Mutex
+	// versions are the versions of the objects last read or written, by
+	// object key.
+	versions map[string]string
+}
+
+// NewObjectStorage initializes a new object storage driver for the releases
+// of namespace, stored under prefix in store. An empty namespace lists
+// releases of all namespaces.
+func NewObjectStorage(store ObjectStore, prefix string, namespace string) *ObjectStorage {
+	return &ObjectStorage{
+		store:     store,
+		prefix:    strings.Trim(prefix, "/"),
+		namespace: namespace,
+		versions:  map[string]string{},
+	}
+}
+
+// SetNamespace sets a specific namespace in which releases will be accessed.
+// An empty string indicates all namespaces (for the list operation)
+func (o *ObjectStorage) SetNamespace(ns string) {
+	o.namespace = ns
+}
+
+// Name returns the name of the driver.
+func (o *ObjectStorage) Name() string {
+	return ObjectStorageDriverName
+}
+
+// Get returns the release named by key.
+func (o *ObjectStorage) Get(key string) (*rspb.Release, error) {
+	data, err := o.read(context.Background(), o.key(o.namespace, key))
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "get: failed to get %q", key)
+	}
+	rls, lbs, err := o.decodeLabeledRecord(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get: failed to decode data %q", key)
+	}
+	rls.Labels = filterSystemLabels(lbs)
+	return rls, nil
+}
+
+// List returns the list of all releases such that filter(release) == true
+func (o *ObjectStorage) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	records, err := o.readNamespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "list: failed to list")
+	}
+
+	var results []*rspb.Release
+	for _, record := range records {
+		if record.labels["owner"] != "helm" {
+			continue
+		}
+		if filter(record.rls) {
+			results = append(results, record.rls)
+		}
+	}
+	return results, nil
+}
+
+// Query returns the set of releases that match the provided set of labels.
+func (o *ObjectStorage) Query(labels map[string]string) ([]*rspb.Release, error) {
+	records, err := o.readNamespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "query: failed to query with labels")
+	}
+
+	var results []*rspb.Release
+	for _, record := range records {
+		if matchLabels(record.labels, labels) {
+			results = append(results, record.rls)
+		}
+	}
+	if len(results) == 0 {
+		return nil, ErrReleaseNotFound
+	}
+	return results, nil
+}
+
+// Create creates a new release or returns ErrReleaseExists.
+func (o *ObjectStorage) Create(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("createdAt", strconv.FormatInt(time.Now().Unix(), 10))
+
+	data, err := o.encodeLabeledRecord(rls, lbs)
+	if err != nil {
+		return errors.Wrapf(err, "create: failed to encode release %q", rls.Name)
+	}
+	err = o.write(context.Background(), o.key(releaseNamespace(rls), key), data, "")
+	if errors.Is(err, ErrObjectModified) {
+		return ErrReleaseExists
+	}
+	return errors.Wrap(err, "create: failed to create")
+}
+
+// Update updates a release or returns ErrReleaseNotFound. It fails with
+// ErrObjectModified if the release was modified by another process since the
+// driver last read or wrote it. A release the driver has not read yet is read
+// first.
+func (o *ObjectStorage) Update(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("modifiedAt", strconv.FormatInt(time.Now().Unix(), 10))
+
+	data, err := o.encodeLabeledRecord(rls, lbs)
+	if err != nil {
+		return errors.Wrapf(err, "update: failed to encode release %q", rls.Name)
+	}
+
+	ctx := context.Background()
+	objKey := o.key(releaseNamespace(rls), key)
+	version, ok := o.version(objKey)
+	if !ok {
+		if _, err := o.read(ctx, objKey); err != nil {
+			if errors.Is(err, ErrObjectNotFound) {
+				return ErrReleaseNotFound
+			}
+			return errors.Wrap(err, "update: failed to update")
+		}
+		version, _ = o.version(objKey)
+	}
+	err = o.write(ctx, objKey, data, version)
+	if errors.Is(err, ErrObjectNotFound) {
+		return ErrReleaseNotFound
+	}
+	return errors.Wrap(err, "update: failed to update")
+}
+
+// Delete deletes a release or returns ErrReleaseNotFound. It fails if the
+// release is modified by another process while it is deleted.
+func (o *ObjectStorage) Delete(key string) (*rspb.Release, error) {
+	ctx := context.Background()
+	objKey := o.key(o.namespace, key)
+	data, err := o.read(ctx, objKey)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "delete: failed to get %q", key)
+	}
+	rls, lbs, err := o.decodeLabeledRecord(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "delete: failed to decode data %q", key)
+	}
+	rls.Labels = filterSystemLabels(lbs)
+
+	version, _ := o.version(objKey)
+	err = o.store.Delete(ctx, objKey, version)
+	if err == nil || errors.Is(err, ErrObjectNotFound) {
+		o.setVersion(objKey, "")
+	}
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "delete: failed to delete %q", key)
+	}
+	return rls, nil
+}
+
+// key returns the object key of the release named by key in namespace.
+func (o *ObjectStorage) key(namespace, key string) string {
+	return path.Join(o.prefix, namespace, key)
+}
+
+type objectStorageRecord struct {
+	rls    *rspb.Release
+	labels map[string]string
+}
+
+// readNamespace reads the releases of the driver's namespace, or of all
+// namespaces. The objects are read objectStorageReads at a time.
+func (o *ObjectStorage) readNamespace() ([]objectStorageRecord, error) {
+	prefix := o.prefix
+	if o.namespace != "" {
+		prefix = path.Join(prefix, o.namespace)
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	keys, err := o.store.List(context.Background(), prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*objectStorageRecord, len(keys))
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetLimit(objectStorageReads)
+	for i, key := range keys {
+		g.Go(func() error {
+			data, err := o.read(ctx, key)
+			if err != nil {
+				// The release may have been deleted since it was listed
+				if errors.Is(err, ErrObjectNotFound) {
+					return nil
+				}
+				return err
+			}
+			rls, lbs, err := o.decodeLabeledRecord(data)
+			if err != nil {
+				slog.Debug("failed to decode release", "key", key, slog.Any("error", err))
+				return nil
+			}
+			rls.Labels = lbs
+			records[i] = &objectStorageRecord{rls: rls, labels: lbs}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var result []objectStorageRecord
+	for _, record := range records {
+		if record != nil {
+			result = append(result, *record)
+		}
+	}
+	return result, nil
+}
+
+// read returns the data of the object at key, and records its version.
+func (o *ObjectStorage) read(ctx context.Context, key string) ([]byte, error) {
+	data, version, err := o.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			o.setVersion(key, "")
+		}
+		return nil, err
+	}
+	o.setVersion(key, version)
+	return data, nil
+}
+
+// write writes the object at key if its version is ifVersion, and records
+// its new version.
+func (o *ObjectStorage) write(ctx context.Context, key string, data []byte, ifVersion string) error {
+	version, err := o.store.Put(ctx, key, data, ifVersion)
+	if err != nil {
+		return err
+	}
+	o.setVersion(key, version)
+	return nil
+}
+
+// version returns the version of the object at key last read or written.
+func (o *ObjectStorage) version(key string) (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	version, ok := o.versions[key]
+	return version, ok
+}
+
+// setVersion records the version of the object at key, or forgets it if
+// version is empty.
+func (o *ObjectStorage) setVersion(key, version string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if version == "" {
+		delete(o.versions, key)
+		return
+	}
+	o.versions[key] = version
+}
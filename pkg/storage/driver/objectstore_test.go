@@ -0,0 +1,180 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	rspb "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func TestObjectStorageName(t *testing.T) {
+	o, _ := newTestFixtureObjectStorage(t)
+	if o.Name() != ObjectStorageDriverName {
+		t.Errorf("Expected name to be %q, got %q", ObjectStorageDriverName, o.Name())
+	}
+}
+
+func TestObjectStorageGet(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	o, mock := newTestFixtureObjectStorage(t, []*rspb.Release{rel}...)
+
+	if _, ok := mock.objects["helm/releases/default/"+key]; !ok {
+		t.Errorf("Expected release to be stored under helm/releases/default, got %v", mock.objects)
+	}
+
+	// get release with key
+	got, err := o.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release: %s", err)
+	}
+	// compare fetched release with original
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	if _, err := o.Get(testKey(name, 2)); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v for a missing release, got %v", ErrReleaseNotFound, err)
+	}
+}
+
+func TestObjectStorageListAndQuery(t *testing.T) {
+	o, _ := newTestFixtureObjectStorage(t, []*rspb.Release{
+		releaseStub("smug-pigeon", 1, "default", rspb.StatusSuperseded),
+		releaseStub("smug-pigeon", 2, "default", rspb.StatusDeployed),
+		releaseStub("other", 1, "default", rspb.StatusDeployed),
+		releaseStub("elsewhere", 1, "other", rspb.StatusDeployed),
+	}...)
+
+	dpl, err := o.List(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusDeployed
+	})
+	if err != nil {
+		t.Fatalf("Failed to list deployed: %s", err)
+	}
+	if len(dpl) != 2 {
+		t.Errorf("Expected 2 deployed, got %d", len(dpl))
+	}
+
+	rls, err := o.Query(map[string]string{"name": "smug-pigeon", "owner": "helm"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 2 {
+		t.Errorf("Expected 2 releases, got %d", len(rls))
+	}
+
+	if _, err := o.Query(map[string]string{"name": "elsewhere"}); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v querying another namespace, got %v", ErrReleaseNotFound, err)
+	}
+
+	o.SetNamespace("")
+	all, err := o.List(func(*rspb.Release) bool { return true })
+	if err != nil {
+		t.Fatalf("Failed to list all namespaces: %s", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Expected 4 releases, got %d", len(all))
+	}
+}
+
+func TestObjectStorageCreate(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+
+	o, _ := newTestFixtureObjectStorage(t, rel)
+
+	if err := o.Create(key, rel); err != ErrReleaseExists {
+		t.Errorf("Expected %v creating the release again, got %v", ErrReleaseExists, err)
+	}
+}
+
+func TestObjectStorageUpdate(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+
+	o, mock := newTestFixtureObjectStorage(t, rel)
+
+	// modify release status code
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := o.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	got, err := o.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if got.Info.Status != rspb.StatusSuperseded {
+		t.Errorf("Expected status %s, got status %s", rspb.StatusSuperseded, got.Info.Status)
+	}
+
+	if err := o.Update(testKey(rel.Name, 2), rel); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v updating a missing release, got %v", ErrReleaseNotFound, err)
+	}
+
+	// Another process updates the release after it was read
+	mock.modify("helm/releases/default/" + key)
+	if err := o.Update(key, rel); !errors.Is(err, ErrObjectModified) {
+		t.Errorf("Expected %v updating a concurrently modified release, got %v", ErrObjectModified, err)
+	}
+	// and is not overwritten until the release is read again.
+	if err := o.Update(key, rel); !errors.Is(err, ErrObjectModified) {
+		t.Errorf("Expected %v updating a stale release again, got %v", ErrObjectModified, err)
+	}
+	if _, err := o.Get(key); err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if err := o.Update(key, rel); err != nil {
+		t.Errorf("Failed to update the release read again: %s", err)
+	}
+
+	// A release read by another driver is read before it is updated.
+	other := NewObjectStorage(mock, "helm/releases", "default")
+	if err := other.Update(key, rel); err != nil {
+		t.Errorf("Failed to update a release not read yet: %s", err)
+	}
+}
+
+func TestObjectStorageDelete(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+
+	o, _ := newTestFixtureObjectStorage(t, rel)
+
+	if _, err := o.Delete("nonexistent"); err != ErrReleaseNotFound {
+		t.Fatalf("Expected %v, got %v", ErrReleaseNotFound, err)
+	}
+
+	rls, err := o.Delete(key)
+	if err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, rls) {
+		t.Errorf("Expected {%v}, got {%v}", rel, rls)
+	}
+
+	if _, err := o.Get(key); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v after delete, got %v", ErrReleaseNotFound, err)
+	}
+}
@@ -35,7 +35,10 @@ import (
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
 
-var _ Driver = (*Secrets)(nil)
+var (
+	_ Driver = (*Secrets)(nil)
+	_ Pager  = (*Secrets)(nil)
+)
 
 // SecretsDriverName is the string name of the driver.
 const SecretsDriverName = "Secret"
@@ -43,6 +46,7 @@ const SecretsDriverName = "Secret"
 // Secrets is a wrapper around an implementation of a kubernetes
 // SecretsInterface.
 type Secrets struct {
+	codec
 	impl corev1.SecretInterface
 }
 
@@ -71,7 +75,7 @@ func (secrets *Secrets) Get(key string) (*rspb.Release, error) {
 		return nil, errors.Wrapf(err, "get: failed to get %q", key)
 	}
 	// found the secret, decode the base64 data string
-	r, err := decodeRelease(string(obj.Data["release"]))
+	r, err := secrets.decodeRelease(string(obj.Data["release"]))
 	r.Labels = filterSystemLabels(obj.Labels)
 	return r, errors.Wrapf(err, "get: failed to decode data %q", key)
 }
@@ -80,32 +84,43 @@ func (secrets *Secrets) Get(key string) (*rspb.Release, error) {
 // that filter(release) == true. An error is returned if the
 // secret fails to retrieve the releases.
 func (secrets *Secrets) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
-	lsel := kblabels.Set{"owner": "helm"}.AsSelector()
-	opts := metav1.ListOptions{LabelSelector: lsel.String()}
-
-	list, err := secrets.impl.List(context.Background(), opts)
-	if err != nil {
-		return nil, errors.Wrap(err, "list: failed to list")
-	}
+	results, _, err := secrets.ListPage(filter, ListOptions{})
+	return results, err
+}
 
+// ListPage fetches a page of opts.Limit releases with the labels of
+// opts and returns the releases such that filter(release) == true, with
+// the continue token of the next page. An error is returned if the secret fails to
+// retrieve the releases.
+func (secrets *Secrets) ListPage(filter func(*rspb.Release) bool, opts ListOptions) ([]*rspb.Release, string, error) {
 	var results []*rspb.Release
-
-	// iterate over the secrets object list
-	// and decode each release
-	for _, item := range list.Items {
-		rls, err := decodeRelease(string(item.Data["release"]))
+	next, err := listKubePage(opts, func(listOpts metav1.ListOptions) (string, error) {
+		list, err := secrets.impl.List(context.Background(), listOpts)
 		if err != nil {
-			slog.Debug("list failed to decode release", "key", item.Name, slog.Any("error", err))
-			continue
+			return "", err
 		}
 
-		rls.Labels = item.Labels
+		// iterate over the secrets object list
+		// and decode each release
+		for _, item := range list.Items {
+			rls, err := secrets.decodeRelease(string(item.Data["release"]))
+			if err != nil {
+				slog.Debug("list failed to decode release", "key", item.Name, slog.Any("error", err))
+				continue
+			}
+
+			rls.Labels = item.Labels
 
-		if filter(rls) {
-			results = append(results, rls)
+			if filter(rls) {
+				results = append(results, rls)
+			}
 		}
+		return list.Continue, nil
+	})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "list: failed to list")
 	}
-	return results, nil
+	return results, next, nil
 }
 
 // Query fetches all releases that match the provided map of labels.
@@ -132,7 +147,7 @@ func (secrets *Secrets) Query(labels map[string]string) ([]*rspb.Release, error)
 
 	var results []*rspb.Release
 	for _, item := range list.Items {
-		rls, err := decodeRelease(string(item.Data["release"]))
+		rls, err := secrets.decodeRelease(string(item.Data["release"]))
 		if err != nil {
 			slog.Debug("failed to decode release", "key", item.Name, slog.Any("error", err))
 			continue
@@ -154,7 +169,7 @@ func (secrets *Secrets) Create(key string, rls *rspb.Release) error {
 	lbs.set("createdAt", fmt.Sprintf("%v", time.Now().Unix()))
 
 	// create a new secret to hold the release
-	obj, err := newSecretsObject(key, rls, lbs)
+	obj, err := newSecretsObject(&secrets.codec, key, rls, lbs)
 	if err != nil {
 		return errors.Wrapf(err, "create: failed to encode release %q", rls.Name)
 	}
@@ -180,7 +195,7 @@ func (secrets *Secrets) Update(key string, rls *rspb.Release) error {
 	lbs.set("modifiedAt", fmt.Sprintf("%v", time.Now().Unix()))
 
 	// create a new secret object to hold the release
-	obj, err := newSecretsObject(key, rls, lbs)
+	obj, err := newSecretsObject(&secrets.codec, key, rls, lbs)
 	if err != nil {
 		return errors.Wrapf(err, "update: failed to encode release %q", rls.Name)
 	}
@@ -202,7 +217,7 @@ func (secrets *Secrets) Delete(key string) (rls *rspb.Release, err error) {
 
 // newSecretsObject constructs a kubernetes Secret object
 // to store a release. Each secret data entry is the base64
-// encoded gzipped string of a release.
+// encoded compressed string of a release, encoded by c.
 //
 // The following labels are used within each secret:
 //
@@ -212,11 +227,11 @@ func (secrets *Secrets) Delete(key string) (rls *rspb.Release, err error) {
 //	"status"         - status of the release (see pkg/release/status.go for variants)
 //	"owner"          - owner of the secret, currently "helm".
 //	"name"           - name of the release.
-func newSecretsObject(key string, rls *rspb.Release, lbs labels) (*v1.Secret, error) {
+func newSecretsObject(c *codec, key string, rls *rspb.Release, lbs labels) (*v1.Secret, error) {
 	const owner = "helm"
 
 	// encode the release
-	s, err := encodeRelease(rls)
+	s, err := c.encodeRelease(rls)
 	if err != nil {
 		return nil, err
 	}
@@ -233,6 +248,9 @@ func newSecretsObject(key string, rls *rspb.Release, lbs labels) (*v1.Secret, er
 	lbs.set("owner", owner)
 	lbs.set("status", rls.Info.Status.String())
 	lbs.set("version", strconv.Itoa(rls.Version))
+	for k, v := range chartLabels(rls) {
+		lbs.set(k, v)
+	}
 
 	// create and return secret object.
 	// Helm 3 introduced setting the 'Type' field
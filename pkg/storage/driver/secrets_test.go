@@ -22,6 +22,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 
+	chart "helm.sh/helm/v4/pkg/chart/v2"
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
 
@@ -60,7 +61,7 @@ func TestUNcompressedSecretGet(t *testing.T) {
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
 	// Create a test fixture which contains an uncompressed release
-	secret, err := newSecretsObject(key, rel, nil)
+	secret, err := newSecretsObject(&testCodec, key, rel, nil)
 	if err != nil {
 		t.Fatalf("Failed to create secret: %s", err)
 	}
@@ -141,6 +142,82 @@ func TestSecretList(t *testing.T) {
 	}
 }
 
+func TestSecretListPage(t *testing.T) {
+	secrets := newTestFixtureSecrets(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-2", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-4", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-5", 1, "default", rspb.StatusSuperseded),
+	}...)
+
+	// list deployed releases two records at a time
+	var dpl []*rspb.Release
+	opts := ListOptions{Limit: 2}
+	for pages := 1; ; pages++ {
+		page, next, err := secrets.ListPage(func(rel *rspb.Release) bool {
+			return rel.Info.Status == rspb.StatusDeployed
+		}, opts)
+		if err != nil {
+			t.Fatalf("Failed to list page %d: %s", pages, err)
+		}
+		dpl = append(dpl, page...)
+		if next == "" {
+			if pages != 3 {
+				t.Errorf("Expected 3 pages, got %d", pages)
+			}
+			break
+		}
+		opts.Continue = next
+	}
+	if len(dpl) != 3 {
+		t.Errorf("Expected 3 deployed, got %d", len(dpl))
+	}
+}
+
+func TestSecretListPageByChart(t *testing.T) {
+	withChart := func(rel *rspb.Release, name, version string) *rspb.Release {
+		rel.Chart = &chart.Chart{Metadata: &chart.Metadata{Name: name, Version: version}}
+		return rel
+	}
+	secrets := newTestFixtureSecrets(t, []*rspb.Release{
+		withChart(releaseStub("key-1", 1, "default", rspb.StatusDeployed), "nginx", "1.0.0"),
+		withChart(releaseStub("key-2", 1, "default", rspb.StatusFailed), "nginx", "1.0.0"),
+		withChart(releaseStub("key-3", 1, "default", rspb.StatusFailed), "redis", "1.0.0"),
+		withChart(releaseStub("key-4", 1, "default", rspb.StatusFailed), "nginx", "1.0.0"),
+	}...)
+
+	// key-4 was stored before charts were labeled
+	mock := secrets.impl.(*MockSecretsInterface)
+	legacy := mock.objects[testKey("key-4", 1)]
+	delete(legacy.Labels, ChartLabel)
+	delete(legacy.Labels, ChartVersionLabel)
+	if got := mock.objects[testKey("key-1", 1)].Labels[ChartLabel]; got != "nginx" {
+		t.Errorf("Expected chart label nginx, got %q", got)
+	}
+
+	labels := map[string]string{ChartLabel: "nginx", "status": "failed"}
+	var names []string
+	opts := ListOptions{Labels: labels}
+	for {
+		page, next, err := secrets.ListPage(func(*rspb.Release) bool { return true }, opts)
+		if err != nil {
+			t.Fatalf("Failed to list releases by chart: %s", err)
+		}
+		for _, rel := range page {
+			names = append(names, rel.Name)
+		}
+		if next == "" {
+			break
+		}
+		opts.Continue = next
+	}
+	// the labeled release is read first, then the unlabeled one
+	if expected := []string{"key-2", "key-4"}; !reflect.DeepEqual(expected, names) {
+		t.Errorf("Expected releases %v, got %v", expected, names)
+	}
+}
+
 func TestSecretQuery(t *testing.T) {
 	secrets := newTestFixtureSecrets(t, []*rspb.Release{
 		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
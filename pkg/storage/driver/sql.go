@@ -17,10 +17,13 @@ limitations under the License.
 package driver // import "helm.sh/helm/v4/pkg/storage/driver"
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log/slog"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -34,7 +37,10 @@ import (
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
 
-var _ Driver = (*SQL)(nil)
+var (
+	_ Driver = (*SQL)(nil)
+	_ Pager  = (*SQL)(nil)
+)
 
 var labelMap = map[string]struct{}{
 	"modifiedAt": {},
@@ -54,16 +60,18 @@ const sqlReleaseTableName = "releases_v1"
 const sqlCustomLabelsTableName = "custom_labels_v1"
 
 const (
-	sqlReleaseTableKeyColumn        = "key"
-	sqlReleaseTableTypeColumn       = "type"
-	sqlReleaseTableBodyColumn       = "body"
-	sqlReleaseTableNameColumn       = "name"
-	sqlReleaseTableNamespaceColumn  = "namespace"
-	sqlReleaseTableVersionColumn    = "version"
-	sqlReleaseTableStatusColumn     = "status"
-	sqlReleaseTableOwnerColumn      = "owner"
-	sqlReleaseTableCreatedAtColumn  = "createdAt"
-	sqlReleaseTableModifiedAtColumn = "modifiedAt"
+	sqlReleaseTableKeyColumn          = "key"
+	sqlReleaseTableTypeColumn         = "type"
+	sqlReleaseTableBodyColumn         = "body"
+	sqlReleaseTableNameColumn         = "name"
+	sqlReleaseTableNamespaceColumn    = "namespace"
+	sqlReleaseTableVersionColumn      = "version"
+	sqlReleaseTableStatusColumn       = "status"
+	sqlReleaseTableOwnerColumn        = "owner"
+	sqlReleaseTableCreatedAtColumn    = "createdAt"
+	sqlReleaseTableModifiedAtColumn   = "modifiedAt"
+	sqlReleaseTableChartColumn        = "chart"
+	sqlReleaseTableChartVersionColumn = "chartVersion"
 
 	sqlCustomLabelsTableReleaseKeyColumn       = "releaseKey"
 	sqlCustomLabelsTableReleaseNamespaceColumn = "releaseNamespace"
@@ -84,9 +92,43 @@ const (
 
 // SQL is the sql storage driver implementation.
 type SQL struct {
+	codec
 	db               *sqlx.DB
 	namespace        string
 	statementBuilder sq.StatementBuilderType
+
+	// stmts caches the prepared statements by query. Queries only vary with
+	// the set of labels filtered on, so the cache stays small.
+	stmtsMu sync.Mutex
+	stmts   map[string]*sqlx.Stmt
+}
+
+// SQLPoolConfig configures the connection pool of the SQL driver. Zero values
+// keep the defaults of database/sql.
+type SQLPoolConfig struct {
+	// MaxOpenConns is the maximum number of open connections to the database.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections kept in the pool.
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum amount of time a connection may be reused.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the maximum amount of time a connection may be idle.
+	ConnMaxIdleTime time.Duration
+}
+
+func (c SQLPoolConfig) apply(db *sqlx.DB) {
+	if c.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
 }
 
 // Name returns the name of the driver.
@@ -128,122 +170,6 @@ func (s *SQL) checkAlreadyApplied(migrations []*migrate.Migration) bool {
 	return true
 }
 
-func (s *SQL) ensureDBSetup() error {
-
-	migrations := &migrate.MemoryMigrationSource{
-		Migrations: []*migrate.Migration{
-			{
-				Id: "init",
-				Up: []string{
-					fmt.Sprintf(`
-						CREATE TABLE %s (
-							%s VARCHAR(90),
-							%s VARCHAR(64) NOT NULL,
-							%s TEXT NOT NULL,
-							%s VARCHAR(64) NOT NULL,
-							%s VARCHAR(64) NOT NULL,
-							%s INTEGER NOT NULL,
-							%s TEXT NOT NULL,
-							%s TEXT NOT NULL,
-							%s INTEGER NOT NULL,
-							%s INTEGER NOT NULL DEFAULT 0,
-							PRIMARY KEY(%s, %s)
-						);
-						CREATE INDEX ON %s (%s, %s);
-						CREATE INDEX ON %s (%s);
-						CREATE INDEX ON %s (%s);
-						CREATE INDEX ON %s (%s);
-						CREATE INDEX ON %s (%s);
-						CREATE INDEX ON %s (%s);
-	
-						GRANT ALL ON %s TO PUBLIC;
-	
-						ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
-					`,
-						sqlReleaseTableName,
-						sqlReleaseTableKeyColumn,
-						sqlReleaseTableTypeColumn,
-						sqlReleaseTableBodyColumn,
-						sqlReleaseTableNameColumn,
-						sqlReleaseTableNamespaceColumn,
-						sqlReleaseTableVersionColumn,
-						sqlReleaseTableStatusColumn,
-						sqlReleaseTableOwnerColumn,
-						sqlReleaseTableCreatedAtColumn,
-						sqlReleaseTableModifiedAtColumn,
-						sqlReleaseTableKeyColumn,
-						sqlReleaseTableNamespaceColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableKeyColumn,
-						sqlReleaseTableNamespaceColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableVersionColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableStatusColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableOwnerColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableCreatedAtColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableModifiedAtColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableName,
-					),
-				},
-				Down: []string{
-					fmt.Sprintf(`
-						DROP TABLE %s;
-					`, sqlReleaseTableName),
-				},
-			},
-			{
-				Id: "custom_labels",
-				Up: []string{
-					fmt.Sprintf(`
-						CREATE TABLE %s (
-							%s VARCHAR(64),
-							%s VARCHAR(67),
-							%s VARCHAR(%d),
-							%s VARCHAR(%d)
-						);
-						CREATE INDEX ON %s (%s, %s);
-						
-						GRANT ALL ON %s TO PUBLIC;
-						ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
-					`,
-						sqlCustomLabelsTableName,
-						sqlCustomLabelsTableReleaseKeyColumn,
-						sqlCustomLabelsTableReleaseNamespaceColumn,
-						sqlCustomLabelsTableKeyColumn,
-						sqlCustomLabelsTableKeyMaxLength,
-						sqlCustomLabelsTableValueColumn,
-						sqlCustomLabelsTableValueMaxLength,
-						sqlCustomLabelsTableName,
-						sqlCustomLabelsTableReleaseKeyColumn,
-						sqlCustomLabelsTableReleaseNamespaceColumn,
-						sqlCustomLabelsTableName,
-						sqlCustomLabelsTableName,
-					),
-				},
-				Down: []string{
-					fmt.Sprintf(`
-						DELETE TABLE %s;
-					`, sqlCustomLabelsTableName),
-				},
-			},
-		},
-	}
-
-	// Check that init migration already applied
-	if s.checkAlreadyApplied(migrations.Migrations) {
-		return nil
-	}
-
-	// Populate the database with the relations we need if they don't exist yet
-	_, err := migrate.Exec(s.db.DB, postgreSQLDialect, migrations, migrate.Up)
-	return err
-}
-
 // SQLReleaseWrapper describes how Helm releases are stored in an SQL database
 type SQLReleaseWrapper struct {
 	// The primary key, made of {release-name}.{release-version}
@@ -276,10 +202,17 @@ type SQLReleaseCustomLabelWrapper struct {
 
 // NewSQL initializes a new sql driver.
 func NewSQL(connectionString string, namespace string) (*SQL, error) {
+	return NewSQLWithPool(connectionString, namespace, SQLPoolConfig{})
+}
+
+// NewSQLWithPool initializes a new sql driver whose connection pool is
+// configured by pool.
+func NewSQLWithPool(connectionString string, namespace string, pool SQLPoolConfig) (*SQL, error) {
 	db, err := sqlx.Connect(postgreSQLDialect, connectionString)
 	if err != nil {
 		return nil, err
 	}
+	pool.apply(db)
 
 	driver := &SQL{
 		db:               db,
@@ -295,6 +228,25 @@ func NewSQL(connectionString string, namespace string) (*SQL, error) {
 	return driver, nil
 }
 
+// prepare returns the prepared statement of query, preparing it on first use.
+func (s *SQL) prepare(query string) (*sqlx.Stmt, error) {
+	s.stmtsMu.Lock()
+	defer s.stmtsMu.Unlock()
+
+	if stmt, ok := s.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := s.db.Preparex(query)
+	if err != nil {
+		return nil, err
+	}
+	if s.stmts == nil {
+		s.stmts = make(map[string]*sqlx.Stmt)
+	}
+	s.stmts[query] = stmt
+	return stmt, nil
+}
+
 // Get returns the release named by key.
 func (s *SQL) Get(key string) (*rspb.Release, error) {
 	var record SQLReleaseWrapper
@@ -311,13 +263,19 @@ func (s *SQL) Get(key string) (*rspb.Release, error) {
 		return nil, err
 	}
 
+	stmt, err := s.prepare(query)
+	if err != nil {
+		slog.Debug("failed to prepare query", slog.Any("error", err))
+		return nil, err
+	}
+
 	// Get will return an error if the result is empty
-	if err := s.db.Get(&record, query, args...); err != nil {
+	if err := stmt.Get(&record, args...); err != nil {
 		slog.Debug("got SQL error when getting release", "key", key, slog.Any("error", err))
 		return nil, ErrReleaseNotFound
 	}
 
-	release, err := decodeRelease(record.Body)
+	release, err := s.decodeRelease(record.Body)
 	if err != nil {
 		slog.Debug("failed to decode data", "key", key, slog.Any("error", err))
 		return nil, err
@@ -333,6 +291,15 @@ func (s *SQL) Get(key string) (*rspb.Release, error) {
 
 // List returns the list of all releases such that filter(release) == true
 func (s *SQL) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	releases, _, err := s.ListPage(filter, ListOptions{})
+	return releases, err
+}
+
+// ListPage returns a page of opts.Limit releases such that
+// filter(release) == true, with the continue token of the next page.
+// Releases are read in the order of their namespace and key, and the
+// continue token holds the namespace and key of the last release read.
+func (s *SQL) ListPage(filter func(*rspb.Release) bool, opts ListOptions) ([]*rspb.Release, string, error) {
 	sb := s.statementBuilder.
 		Select(sqlReleaseTableKeyColumn, sqlReleaseTableNamespaceColumn, sqlReleaseTableBodyColumn).
 		From(sqlReleaseTableName).
@@ -343,21 +310,50 @@ func (s *SQL) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
 		sb = sb.Where(sq.Eq{sqlReleaseTableNamespaceColumn: s.namespace})
 	}
 
+	if cond := sqlLabelsCondition(opts.Labels); len(cond) > 0 {
+		sb = sb.Where(cond)
+	}
+
+	if opts.Continue != "" {
+		namespace, key, err := decodeSQLContinue(opts.Continue)
+		if err != nil {
+			return nil, "", err
+		}
+		sb = sb.Where(sq.Or{
+			sq.Gt{sqlReleaseTableNamespaceColumn: namespace},
+			sq.And{
+				sq.Eq{sqlReleaseTableNamespaceColumn: namespace},
+				sq.Gt{sqlReleaseTableKeyColumn: key},
+			},
+		})
+	}
+	if opts.Limit > 0 {
+		sb = sb.
+			OrderBy(sqlReleaseTableNamespaceColumn, sqlReleaseTableKeyColumn).
+			Limit(uint64(opts.Limit))
+	}
+
 	query, args, err := sb.ToSql()
 	if err != nil {
 		slog.Debug("failed to build query", slog.Any("error", err))
-		return nil, err
+		return nil, "", err
+	}
+
+	stmt, err := s.prepare(query)
+	if err != nil {
+		slog.Debug("failed to prepare query", slog.Any("error", err))
+		return nil, "", err
 	}
 
 	var records = []SQLReleaseWrapper{}
-	if err := s.db.Select(&records, query, args...); err != nil {
+	if err := stmt.Select(&records, args...); err != nil {
 		slog.Debug("failed to list", slog.Any("error", err))
-		return nil, err
+		return nil, "", err
 	}
 
 	var releases []*rspb.Release
 	for _, record := range records {
-		release, err := decodeRelease(record.Body)
+		release, err := s.decodeRelease(record.Body)
 		if err != nil {
 			slog.Debug("failed to decode release", "record", record, slog.Any("error", err))
 			continue
@@ -365,7 +361,7 @@ func (s *SQL) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
 
 		if release.Labels, err = s.getReleaseCustomLabels(record.Key, record.Namespace); err != nil {
 			slog.Debug("failed to get release custom labels", "namespace", record.Namespace, "key", record.Key, slog.Any("error", err))
-			return nil, err
+			return nil, "", err
 		}
 		for k, v := range getReleaseSystemLabels(release) {
 			release.Labels[k] = v
@@ -376,7 +372,65 @@ func (s *SQL) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
 		}
 	}
 
-	return releases, nil
+	var next string
+	if opts.Limit > 0 && len(records) == opts.Limit {
+		last := records[len(records)-1]
+		next = encodeSQLContinue(last.Namespace, last.Key)
+	}
+	return releases, next, nil
+}
+
+// sqlLabelColumns are the columns of the system labels releases can be
+// listed by.
+var sqlLabelColumns = map[string]string{
+	"name":            sqlReleaseTableNameColumn,
+	"status":          sqlReleaseTableStatusColumn,
+	ChartLabel:        sqlReleaseTableChartColumn,
+	ChartVersionLabel: sqlReleaseTableChartVersionColumn,
+}
+
+// sqlLabelsCondition returns the condition selecting the releases with the
+// system labels lbs. Releases stored by older versions of Helm have no chart
+// columns, so they are left to the filter of the list.
+func sqlLabelsCondition(lbs map[string]string) sq.And {
+	keys := make([]string, 0, len(lbs))
+	for k := range lbs {
+		if _, ok := sqlLabelColumns[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	// The order of the conditions is stable, so that statements are reused.
+	sort.Strings(keys)
+
+	cond := sq.And{}
+	for _, k := range keys {
+		column := sqlLabelColumns[k]
+		if k == ChartLabel || k == ChartVersionLabel {
+			cond = append(cond, sq.Or{sq.Eq{column: lbs[k]}, sq.Eq{column: nil}})
+			continue
+		}
+		cond = append(cond, sq.Eq{column: lbs[k]})
+	}
+	return cond
+}
+
+// encodeSQLContinue returns the continue token of the page following the
+// release of namespace and key.
+func encodeSQLContinue(namespace, key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(namespace + "/" + key))
+}
+
+// decodeSQLContinue returns the namespace and key of a continue token.
+func decodeSQLContinue(token string) (string, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", ErrInvalidContinue
+	}
+	namespace, key, ok := strings.Cut(string(b), "/")
+	if !ok {
+		return "", "", ErrInvalidContinue
+	}
+	return namespace, key, nil
 }
 
 // Query returns the set of releases that match the provided set of labels.
@@ -411,8 +465,14 @@ func (s *SQL) Query(labels map[string]string) ([]*rspb.Release, error) {
 		return nil, err
 	}
 
+	stmt, err := s.prepare(query)
+	if err != nil {
+		slog.Debug("failed to prepare query", slog.Any("error", err))
+		return nil, err
+	}
+
 	var records = []SQLReleaseWrapper{}
-	if err := s.db.Select(&records, query, args...); err != nil {
+	if err := stmt.Select(&records, args...); err != nil {
 		slog.Debug("failed to query with labels", slog.Any("error", err))
 		return nil, err
 	}
@@ -423,7 +483,7 @@ func (s *SQL) Query(labels map[string]string) ([]*rspb.Release, error) {
 
 	var releases []*rspb.Release
 	for _, record := range records {
-		release, err := decodeRelease(record.Body)
+		release, err := s.decodeRelease(record.Body)
 		if err != nil {
 			slog.Debug("failed to decode release", "record", record, slog.Any("error", err))
 			continue
@@ -452,7 +512,7 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 	}
 	s.namespace = namespace
 
-	body, err := encodeRelease(rls)
+	body, err := s.encodeRelease(rls)
 	if err != nil {
 		slog.Debug("failed to encode release", slog.Any("error", err))
 		return err
@@ -476,6 +536,8 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 			sqlReleaseTableStatusColumn,
 			sqlReleaseTableOwnerColumn,
 			sqlReleaseTableCreatedAtColumn,
+			sqlReleaseTableChartColumn,
+			sqlReleaseTableChartVersionColumn,
 		).
 		Values(
 			key,
@@ -487,6 +549,8 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 			rls.Info.Status.String(),
 			sqlReleaseDefaultOwner,
 			int(time.Now().Unix()),
+			chartName(rls),
+			chartVersion(rls),
 		).ToSql()
 	if err != nil {
 		slog.Debug("failed to build insert query", slog.Any("error", err))
@@ -559,7 +623,7 @@ func (s *SQL) Update(key string, rls *rspb.Release) error {
 	}
 	s.namespace = namespace
 
-	body, err := encodeRelease(rls)
+	body, err := s.encodeRelease(rls)
 	if err != nil {
 		slog.Debug("failed to encode release", slog.Any("error", err))
 		return err
@@ -573,6 +637,8 @@ func (s *SQL) Update(key string, rls *rspb.Release) error {
 		Set(sqlReleaseTableStatusColumn, rls.Info.Status.String()).
 		Set(sqlReleaseTableOwnerColumn, sqlReleaseDefaultOwner).
 		Set(sqlReleaseTableModifiedAtColumn, int(time.Now().Unix())).
+		Set(sqlReleaseTableChartColumn, chartName(rls)).
+		Set(sqlReleaseTableChartVersionColumn, chartVersion(rls)).
 		Where(sq.Eq{sqlReleaseTableKeyColumn: key}).
 		Where(sq.Eq{sqlReleaseTableNamespaceColumn: namespace}).
 		ToSql()
@@ -582,7 +648,13 @@ func (s *SQL) Update(key string, rls *rspb.Release) error {
 		return err
 	}
 
-	if _, err := s.db.Exec(query, args...); err != nil {
+	stmt, err := s.prepare(query)
+	if err != nil {
+		slog.Debug("failed to prepare update query", slog.Any("error", err))
+		return err
+	}
+
+	if _, err := stmt.Exec(args...); err != nil {
 		slog.Debug("failed to update release in SQL database", "key", key, slog.Any("error", err))
 		return err
 	}
@@ -616,7 +688,7 @@ func (s *SQL) Delete(key string) (*rspb.Release, error) {
 		return nil, ErrReleaseNotFound
 	}
 
-	release, err := decodeRelease(record.Body)
+	release, err := s.decodeRelease(record.Body)
 	if err != nil {
 		slog.Debug("failed to decode release", "key", key, slog.Any("error", err))
 		transaction.Rollback()
@@ -671,8 +743,13 @@ func (s *SQL) getReleaseCustomLabels(key string, _ string) (map[string]string, e
 		return nil, err
 	}
 
+	stmt, err := s.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
 	var labelsList = []SQLReleaseCustomLabelWrapper{}
-	if err := s.db.Select(&labelsList, query, args...); err != nil {
+	if err := stmt.Select(&labelsList, args...); err != nil {
 		return nil, err
 	}
 
@@ -686,10 +763,26 @@ func (s *SQL) getReleaseCustomLabels(key string, _ string) (map[string]string, e
 
 // Rebuild system labels from release object
 func getReleaseSystemLabels(rls *rspb.Release) map[string]string {
-	return map[string]string{
-		"name":    rls.Name,
-		"owner":   sqlReleaseDefaultOwner,
-		"status":  rls.Info.Status.String(),
-		"version": strconv.Itoa(rls.Version),
+	lbs := chartLabels(rls)
+	lbs["name"] = rls.Name
+	lbs["owner"] = sqlReleaseDefaultOwner
+	lbs["status"] = rls.Info.Status.String()
+	lbs["version"] = strconv.Itoa(rls.Version)
+	return lbs
+}
+
+// chartName returns the name of the chart of the release.
+func chartName(rls *rspb.Release) string {
+	if rls.Chart == nil || rls.Chart.Metadata == nil {
+		return ""
+	}
+	return rls.Chart.Metadata.Name
+}
+
+// chartVersion returns the version of the chart of the release.
+func chartVersion(rls *rspb.Release) string {
+	if rls.Chart == nil || rls.Chart.Metadata == nil {
+		return ""
 	}
+	return rls.Chart.Metadata.Version
 }
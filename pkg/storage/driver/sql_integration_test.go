@@ -0,0 +1,101 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	rspb "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// TestSQLIntegration runs the SQL driver against the PostgreSQL database of
+// $HELM_TEST_SQL_CONNECTION_STRING, and is skipped when it is unset.
+func TestSQLIntegration(t *testing.T) {
+	connectionString := os.Getenv("HELM_TEST_SQL_CONNECTION_STRING")
+	if connectionString == "" {
+		t.Skip("HELM_TEST_SQL_CONNECTION_STRING is not set")
+	}
+
+	// A namespace of its own keeps the test away from the releases of other runs.
+	namespace := fmt.Sprintf("helm-test-%d", time.Now().UnixNano())
+	pool := SQLPoolConfig{MaxOpenConns: 4, MaxIdleConns: 2, ConnMaxLifetime: time.Minute}
+
+	sqlDriver, err := NewSQLWithPool(connectionString, namespace, pool)
+	if err != nil {
+		t.Fatalf("failed to create SQL driver: %v", err)
+	}
+	// Migrations are only applied once
+	if _, err := NewSQLWithPool(connectionString, namespace, pool); err != nil {
+		t.Fatalf("failed to create SQL driver on a migrated database: %v", err)
+	}
+
+	rel := releaseStub("smug-pigeon", 1, namespace, rspb.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+
+	if err := sqlDriver.Create(key, rel); err != nil {
+		t.Fatalf("failed to create release: %v", err)
+	}
+	defer sqlDriver.Delete(key)
+
+	if err := sqlDriver.Create(key, rel); err != ErrReleaseExists {
+		t.Errorf("Expected %v creating the release again, got %v", ErrReleaseExists, err)
+	}
+
+	got, err := sqlDriver.Get(key)
+	if err != nil {
+		t.Fatalf("failed to get release: %v", err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected release {%v}, got {%v}", rel, got)
+	}
+
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := sqlDriver.Update(key, rel); err != nil {
+		t.Fatalf("failed to update release: %v", err)
+	}
+
+	results, err := sqlDriver.Query(map[string]string{
+		"name":   rel.Name,
+		"owner":  sqlReleaseDefaultOwner,
+		"status": rspb.StatusSuperseded.String(),
+	})
+	if err != nil {
+		t.Fatalf("failed to query releases: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected 1 superseded release, got %d", len(results))
+	}
+
+	list, err := sqlDriver.List(func(*rspb.Release) bool { return true })
+	if err != nil {
+		t.Fatalf("failed to list releases: %v", err)
+	}
+	if len(list) != 1 {
+		t.Errorf("Expected 1 release, got %d", len(list))
+	}
+
+	if _, err := sqlDriver.Delete(key); err != nil {
+		t.Fatalf("failed to delete release: %v", err)
+	}
+	if _, err := sqlDriver.Get(key); err != ErrReleaseNotFound {
+		t.Errorf("Expected %v after deleting the release, got %v", ErrReleaseNotFound, err)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v4/pkg/storage/driver"
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// sqlMigrationsLockID identifies the PostgreSQL advisory lock that Helm holds
+// while migrating the database schema.
+const sqlMigrationsLockID int64 = 0x68656c6d // "helm"
+
+// sqlMigrations returns the migrations of the database schema, in the order
+// they are applied. Applied migrations are recorded by their ID, so the Up
+// statements of a released migration must never change; schema changes are
+// made by appending a new migration instead.
+func sqlMigrations() []*migrate.Migration {
+	return []*migrate.Migration{
+		{
+			Id: "init",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE TABLE %s (
+						%s VARCHAR(90),
+						%s VARCHAR(64) NOT NULL,
+						%s TEXT NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s INTEGER NOT NULL,
+						%s TEXT NOT NULL,
+						%s TEXT NOT NULL,
+						%s INTEGER NOT NULL,
+						%s INTEGER NOT NULL DEFAULT 0,
+						PRIMARY KEY(%s, %s)
+					);
+					CREATE INDEX ON %s (%s, %s);
+					CREATE INDEX ON %s (%s);
+					CREATE INDEX ON %s (%s);
+					CREATE INDEX ON %s (%s);
+					CREATE INDEX ON %s (%s);
+					CREATE INDEX ON %s (%s);
+
+					GRANT ALL ON %s TO PUBLIC;
+
+					ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
+				`,
+					sqlReleaseTableName,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableTypeColumn,
+					sqlReleaseTableBodyColumn,
+					sqlReleaseTableNameColumn,
+					sqlReleaseTableNamespaceColumn,
+					sqlReleaseTableVersionColumn,
+					sqlReleaseTableStatusColumn,
+					sqlReleaseTableOwnerColumn,
+					sqlReleaseTableCreatedAtColumn,
+					sqlReleaseTableModifiedAtColumn,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableNamespaceColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableNamespaceColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableVersionColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableStatusColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableOwnerColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableCreatedAtColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableModifiedAtColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableName,
+				),
+			},
+			Down: []string{
+				fmt.Sprintf(`
+					DROP TABLE %s;
+				`, sqlReleaseTableName),
+			},
+		},
+		{
+			Id: "custom_labels",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE TABLE %s (
+						%s VARCHAR(64),
+						%s VARCHAR(67),
+						%s VARCHAR(%d),
+						%s VARCHAR(%d)
+					);
+					CREATE INDEX ON %s (%s, %s);
+					
+					GRANT ALL ON %s TO PUBLIC;
+					ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
+				`,
+					sqlCustomLabelsTableName,
+					sqlCustomLabelsTableReleaseKeyColumn,
+					sqlCustomLabelsTableReleaseNamespaceColumn,
+					sqlCustomLabelsTableKeyColumn,
+					sqlCustomLabelsTableKeyMaxLength,
+					sqlCustomLabelsTableValueColumn,
+					sqlCustomLabelsTableValueMaxLength,
+					sqlCustomLabelsTableName,
+					sqlCustomLabelsTableReleaseKeyColumn,
+					sqlCustomLabelsTableReleaseNamespaceColumn,
+					sqlCustomLabelsTableName,
+					sqlCustomLabelsTableName,
+				),
+			},
+			Down: []string{
+				fmt.Sprintf(`
+					DROP TABLE %s;
+				`, sqlCustomLabelsTableName),
+			},
+		},
+		{
+			Id: "releases_owner_namespace_index",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE INDEX IF NOT EXISTS %s_owner_namespace_idx ON %s (%s, %s);
+				`,
+					sqlReleaseTableName,
+					sqlReleaseTableName,
+					sqlReleaseTableOwnerColumn,
+					sqlReleaseTableNamespaceColumn,
+				),
+			},
+			Down: []string{
+				fmt.Sprintf(`
+					DROP INDEX IF EXISTS %s_owner_namespace_idx;
+				`, sqlReleaseTableName),
+			},
+		},
+		{
+			Id: "releases_chart_columns",
+			Up: []string{
+				fmt.Sprintf(`
+					ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s TEXT, ADD COLUMN IF NOT EXISTS %s TEXT;
+					CREATE INDEX IF NOT EXISTS %s_chart_idx ON %s (%s, %s);
+				`,
+					sqlReleaseTableName,
+					sqlReleaseTableChartColumn,
+					sqlReleaseTableChartVersionColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableName,
+					sqlReleaseTableChartColumn,
+					sqlReleaseTableChartVersionColumn,
+				),
+			},
+			Down: []string{
+				fmt.Sprintf(`
+					DROP INDEX IF EXISTS %s_chart_idx;
+					ALTER TABLE %s DROP COLUMN IF EXISTS %s, DROP COLUMN IF EXISTS %s;
+				`,
+					sqlReleaseTableName,
+					sqlReleaseTableName,
+					sqlReleaseTableChartColumn,
+					sqlReleaseTableChartVersionColumn,
+				),
+			},
+		},
+	}
+}
+
+func (s *SQL) ensureDBSetup() error {
+	migrations := &migrate.MemoryMigrationSource{
+		Migrations: sqlMigrations(),
+	}
+
+	// Check that all migrations are already applied
+	if s.checkAlreadyApplied(migrations.Migrations) {
+		return nil
+	}
+
+	// Concurrent Helm processes sharing a database would otherwise race to
+	// apply the same migrations.
+	unlock, err := s.lockMigrations()
+	if err != nil {
+		return fmt.Errorf("failed to lock the database for migrations: %w", err)
+	}
+	defer unlock()
+
+	// Another process may have applied the migrations while we waited for the lock
+	if s.checkAlreadyApplied(migrations.Migrations) {
+		return nil
+	}
+
+	// Populate the database with the relations we need if they don't exist yet
+	n, err := migrate.Exec(s.db.DB, postgreSQLDialect, migrations, migrate.Up)
+	if err != nil {
+		return err
+	}
+	slog.Debug("applied SQL migrations", "count", n)
+	return nil
+}
+
+// lockMigrations takes the advisory lock for migrations on a dedicated
+// connection and returns the function releasing it.
+func (s *SQL) lockMigrations() (func(), error) {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", sqlMigrationsLockID); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", sqlMigrationsLockID); err != nil {
+			slog.Debug("failed to release the migrations lock", slog.Any("error", err))
+		}
+		conn.Close()
+	}, nil
+}
@@ -40,7 +40,7 @@ func TestSQLGet(t *testing.T) {
 	key := testKey(name, vers)
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
-	body, _ := encodeRelease(rel)
+	body, _ := testCodec.encodeRelease(rel)
 
 	sqlDriver, mock := newTestFixtureSQL(t)
 
@@ -52,6 +52,7 @@ func TestSQLGet(t *testing.T) {
 		sqlReleaseTableNamespaceColumn,
 	)
 
+	mock.ExpectPrepare(query)
 	mock.
 		ExpectQuery(query).
 		WithArgs(key, namespace).
@@ -63,6 +64,7 @@ func TestSQLGet(t *testing.T) {
 			),
 		).RowsWillBeClosed()
 
+	mockPrepareReleaseCustomLabels(mock)
 	mockGetReleaseCustomLabels(mock, key, namespace, rel.Labels)
 
 	got, err := sqlDriver.Get(key)
@@ -105,14 +107,21 @@ func TestSQLList(t *testing.T) {
 			sqlReleaseTableBodyColumn,
 		})
 		for _, r := range releases {
-			body, _ := encodeRelease(r)
+			body, _ := testCodec.encodeRelease(r)
 			rows.AddRow(body)
 		}
+		// Statements are only prepared on first use
+		if i == 0 {
+			mock.ExpectPrepare(regexp.QuoteMeta(query))
+		}
 		mock.
 			ExpectQuery(regexp.QuoteMeta(query)).
 			WithArgs(sqlReleaseDefaultOwner, sqlDriver.namespace).
 			WillReturnRows(rows).RowsWillBeClosed()
 
+		if i == 0 {
+			mockPrepareReleaseCustomLabels(mock)
+		}
 		for _, r := range releases {
 			mockGetReleaseCustomLabels(mock, "", r.Namespace, r.Labels)
 		}
@@ -170,6 +179,116 @@ func TestSQLList(t *testing.T) {
 	}
 }
 
+func TestSQLListPage(t *testing.T) {
+	sqlDriver, mock := newTestFixtureSQL(t)
+
+	first := releaseStub("key-1", 1, "default", rspb.StatusDeployed)
+	second := releaseStub("key-2", 1, "default", rspb.StatusUninstalled)
+	third := releaseStub("key-3", 1, "default", rspb.StatusDeployed)
+
+	selectQuery := fmt.Sprintf(
+		"SELECT %s, %s, %s FROM %s WHERE %s = $1 AND %s = $2",
+		sqlReleaseTableKeyColumn,
+		sqlReleaseTableNamespaceColumn,
+		sqlReleaseTableBodyColumn,
+		sqlReleaseTableName,
+		sqlReleaseTableOwnerColumn,
+		sqlReleaseTableNamespaceColumn,
+	)
+	order := fmt.Sprintf(" ORDER BY %s, %s LIMIT 2", sqlReleaseTableNamespaceColumn, sqlReleaseTableKeyColumn)
+	continueQuery := selectQuery + fmt.Sprintf(
+		" AND (%s > $3 OR (%s = $4 AND %s > $5))",
+		sqlReleaseTableNamespaceColumn,
+		sqlReleaseTableNamespaceColumn,
+		sqlReleaseTableKeyColumn,
+	) + order
+
+	pageRows := func(releases ...*rspb.Release) *sqlmock.Rows {
+		rows := mock.NewRows([]string{
+			sqlReleaseTableKeyColumn,
+			sqlReleaseTableNamespaceColumn,
+			sqlReleaseTableBodyColumn,
+		})
+		for _, r := range releases {
+			body, _ := testCodec.encodeRelease(r)
+			rows.AddRow(testKey(r.Name, r.Version), r.Namespace, body)
+		}
+		return rows
+	}
+
+	firstQuery := selectQuery + order
+	mock.ExpectPrepare(regexp.QuoteMeta(firstQuery))
+	mock.
+		ExpectQuery(regexp.QuoteMeta(firstQuery)).
+		WithArgs(sqlReleaseDefaultOwner, sqlDriver.namespace).
+		WillReturnRows(pageRows(first, second)).RowsWillBeClosed()
+	mockPrepareReleaseCustomLabels(mock)
+	mockGetReleaseCustomLabels(mock, testKey(first.Name, first.Version), first.Namespace, nil)
+	mockGetReleaseCustomLabels(mock, testKey(second.Name, second.Version), second.Namespace, nil)
+
+	mock.ExpectPrepare(regexp.QuoteMeta(continueQuery))
+	mock.
+		ExpectQuery(regexp.QuoteMeta(continueQuery)).
+		WithArgs(sqlReleaseDefaultOwner, sqlDriver.namespace, "default", "default", testKey(second.Name, second.Version)).
+		WillReturnRows(pageRows(third)).RowsWillBeClosed()
+	mockGetReleaseCustomLabels(mock, testKey(third.Name, third.Version), third.Namespace, nil)
+
+	deployed := func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusDeployed
+	}
+	page, next, err := sqlDriver.ListPage(deployed, ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to list first page: %v", err)
+	}
+	if len(page) != 1 || next == "" {
+		t.Errorf("Expected 1 deployed release and a continue token, got %d and %q", len(page), next)
+	}
+
+	page, next, err = sqlDriver.ListPage(deployed, ListOptions{Limit: 2, Continue: next})
+	if err != nil {
+		t.Fatalf("Failed to list second page: %v", err)
+	}
+	if len(page) != 1 || next != "" {
+		t.Errorf("Expected 1 deployed release and no continue token, got %d and %q", len(page), next)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("sql expectations weren't met: %v", err)
+	}
+
+	if _, _, err := sqlDriver.ListPage(deployed, ListOptions{Continue: "!"}); err != ErrInvalidContinue {
+		t.Errorf("Expected %v, got %v", ErrInvalidContinue, err)
+	}
+}
+
+func TestSqlLabelsCondition(t *testing.T) {
+	cond := sqlLabelsCondition(map[string]string{
+		ChartLabel:        "nginx",
+		ChartVersionLabel: "1.0.0",
+		"status":          "failed",
+		"unknown":         "ignored",
+	})
+	query, args, err := cond.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprintf(
+		"((%s = ? OR %s IS NULL) AND (%s = ? OR %s IS NULL) AND %s = ?)",
+		sqlReleaseTableChartColumn,
+		sqlReleaseTableChartColumn,
+		sqlReleaseTableChartVersionColumn,
+		sqlReleaseTableChartVersionColumn,
+		sqlReleaseTableStatusColumn,
+	)
+	if query != expected {
+		t.Errorf("Expected condition %q, got %q", expected, query)
+	}
+	if expectedArgs := []interface{}{"nginx", "1.0.0", "failed"}; !reflect.DeepEqual(expectedArgs, args) {
+		t.Errorf("Expected args %v, got %v", expectedArgs, args)
+	}
+}
+
 func TestSqlCreate(t *testing.T) {
 	vers := 1
 	name := "smug-pigeon"
@@ -178,10 +297,10 @@ func TestSqlCreate(t *testing.T) {
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
 	sqlDriver, mock := newTestFixtureSQL(t)
-	body, _ := encodeRelease(rel)
+	body, _ := testCodec.encodeRelease(rel)
 
 	query := fmt.Sprintf(
-		"INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)",
+		"INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)",
 		sqlReleaseTableName,
 		sqlReleaseTableKeyColumn,
 		sqlReleaseTableTypeColumn,
@@ -192,12 +311,14 @@ func TestSqlCreate(t *testing.T) {
 		sqlReleaseTableStatusColumn,
 		sqlReleaseTableOwnerColumn,
 		sqlReleaseTableCreatedAtColumn,
+		sqlReleaseTableChartColumn,
+		sqlReleaseTableChartVersionColumn,
 	)
 
 	mock.ExpectBegin()
 	mock.
 		ExpectExec(regexp.QuoteMeta(query)).
-		WithArgs(key, sqlReleaseDefaultType, body, rel.Name, rel.Namespace, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix())).
+		WithArgs(key, sqlReleaseDefaultType, body, rel.Name, rel.Namespace, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix()), chartName(rel), chartVersion(rel)).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	labelsQuery := fmt.Sprintf(
@@ -235,10 +356,10 @@ func TestSqlCreateAlreadyExists(t *testing.T) {
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
 	sqlDriver, mock := newTestFixtureSQL(t)
-	body, _ := encodeRelease(rel)
+	body, _ := testCodec.encodeRelease(rel)
 
 	insertQuery := fmt.Sprintf(
-		"INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)",
+		"INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)",
 		sqlReleaseTableName,
 		sqlReleaseTableKeyColumn,
 		sqlReleaseTableTypeColumn,
@@ -249,13 +370,15 @@ func TestSqlCreateAlreadyExists(t *testing.T) {
 		sqlReleaseTableStatusColumn,
 		sqlReleaseTableOwnerColumn,
 		sqlReleaseTableCreatedAtColumn,
+		sqlReleaseTableChartColumn,
+		sqlReleaseTableChartVersionColumn,
 	)
 
 	// Insert fails (primary key already exists)
 	mock.ExpectBegin()
 	mock.
 		ExpectExec(regexp.QuoteMeta(insertQuery)).
-		WithArgs(key, sqlReleaseDefaultType, body, rel.Name, rel.Namespace, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix())).
+		WithArgs(key, sqlReleaseDefaultType, body, rel.Name, rel.Namespace, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix()), chartName(rel), chartVersion(rel)).
 		WillReturnError(fmt.Errorf("dialect dependent SQL error"))
 
 	selectQuery := fmt.Sprintf(
@@ -296,10 +419,10 @@ func TestSqlUpdate(t *testing.T) {
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
 	sqlDriver, mock := newTestFixtureSQL(t)
-	body, _ := encodeRelease(rel)
+	body, _ := testCodec.encodeRelease(rel)
 
 	query := fmt.Sprintf(
-		"UPDATE %s SET %s = $1, %s = $2, %s = $3, %s = $4, %s = $5, %s = $6 WHERE %s = $7 AND %s = $8",
+		"UPDATE %s SET %s = $1, %s = $2, %s = $3, %s = $4, %s = $5, %s = $6, %s = $7, %s = $8 WHERE %s = $9 AND %s = $10",
 		sqlReleaseTableName,
 		sqlReleaseTableBodyColumn,
 		sqlReleaseTableNameColumn,
@@ -307,13 +430,16 @@ func TestSqlUpdate(t *testing.T) {
 		sqlReleaseTableStatusColumn,
 		sqlReleaseTableOwnerColumn,
 		sqlReleaseTableModifiedAtColumn,
+		sqlReleaseTableChartColumn,
+		sqlReleaseTableChartVersionColumn,
 		sqlReleaseTableKeyColumn,
 		sqlReleaseTableNamespaceColumn,
 	)
 
+	mock.ExpectPrepare(regexp.QuoteMeta(query))
 	mock.
 		ExpectExec(regexp.QuoteMeta(query)).
-		WithArgs(body, rel.Name, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix()), key, namespace).
+		WithArgs(body, rel.Name, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix()), chartName(rel), chartVersion(rel), key, namespace).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	if err := sqlDriver.Update(key, rel); err != nil {
@@ -343,9 +469,9 @@ func TestSqlQuery(t *testing.T) {
 	}
 
 	supersededRelease := releaseStub("smug-pigeon", 1, "default", rspb.StatusSuperseded)
-	supersededReleaseBody, _ := encodeRelease(supersededRelease)
+	supersededReleaseBody, _ := testCodec.encodeRelease(supersededRelease)
 	deployedRelease := releaseStub("smug-pigeon", 2, "default", rspb.StatusDeployed)
-	deployedReleaseBody, _ := encodeRelease(deployedRelease)
+	deployedReleaseBody, _ := testCodec.encodeRelease(deployedRelease)
 
 	// Let's actually start our test
 	sqlDriver, mock := newTestFixtureSQL(t)
@@ -362,6 +488,7 @@ func TestSqlQuery(t *testing.T) {
 		sqlReleaseTableNamespaceColumn,
 	)
 
+	mock.ExpectPrepare(regexp.QuoteMeta(query))
 	mock.
 		ExpectQuery(regexp.QuoteMeta(query)).
 		WithArgs("smug-pigeon", sqlReleaseDefaultOwner, "unknown", "default").
@@ -382,6 +509,7 @@ func TestSqlQuery(t *testing.T) {
 			),
 		).RowsWillBeClosed()
 
+	mockPrepareReleaseCustomLabels(mock)
 	mockGetReleaseCustomLabels(mock, "", deployedRelease.Namespace, deployedRelease.Labels)
 
 	query = fmt.Sprintf(
@@ -395,6 +523,7 @@ func TestSqlQuery(t *testing.T) {
 		sqlReleaseTableNamespaceColumn,
 	)
 
+	mock.ExpectPrepare(regexp.QuoteMeta(query))
 	mock.
 		ExpectQuery(regexp.QuoteMeta(query)).
 		WithArgs("smug-pigeon", sqlReleaseDefaultOwner, "default").
@@ -456,7 +585,7 @@ func TestSqlDelete(t *testing.T) {
 	key := testKey(name, vers)
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
-	body, _ := encodeRelease(rel)
+	body, _ := testCodec.encodeRelease(rel)
 
 	sqlDriver, mock := newTestFixtureSQL(t)
 
@@ -492,6 +621,7 @@ func TestSqlDelete(t *testing.T) {
 		WithArgs(key, namespace).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
+	mockPrepareReleaseCustomLabels(mock)
 	mockGetReleaseCustomLabels(mock, key, namespace, rel.Labels)
 
 	deleteLabelsQuery := fmt.Sprintf(
@@ -520,8 +650,8 @@ func TestSqlDelete(t *testing.T) {
 	}
 }
 
-func mockGetReleaseCustomLabels(mock sqlmock.Sqlmock, key string, namespace string, labels map[string]string) {
-	query := fmt.Sprintf(
+func releaseCustomLabelsQuery() string {
+	return fmt.Sprintf(
 		regexp.QuoteMeta("SELECT %s, %s FROM %s WHERE %s = $1 AND %s = $2"),
 		sqlCustomLabelsTableKeyColumn,
 		sqlCustomLabelsTableValueColumn,
@@ -529,8 +659,14 @@ func mockGetReleaseCustomLabels(mock sqlmock.Sqlmock, key string, namespace stri
 		sqlCustomLabelsTableReleaseKeyColumn,
 		sqlCustomLabelsTableReleaseNamespaceColumn,
 	)
+}
 
-	eq := mock.ExpectQuery(query).
+func mockPrepareReleaseCustomLabels(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(releaseCustomLabelsQuery())
+}
+
+func mockGetReleaseCustomLabels(mock sqlmock.Sqlmock, key string, namespace string, labels map[string]string) {
+	eq := mock.ExpectQuery(releaseCustomLabelsQuery()).
 		WithArgs(key, namespace)
 
 	returnRows := mock.NewRows([]string{
@@ -590,3 +726,28 @@ func TestSqlCheckAppliedMigrations(t *testing.T) {
 		}
 	}
 }
+
+func TestSqlPoolConfig(t *testing.T) {
+	sqlDriver, _ := newTestFixtureSQL(t)
+
+	SQLPoolConfig{MaxOpenConns: 8, MaxIdleConns: 4}.apply(sqlDriver.db)
+	if got := sqlDriver.db.Stats().MaxOpenConnections; got != 8 {
+		t.Errorf("Expected 8 max open connections, got %d", got)
+	}
+
+	// Zero values keep the current settings
+	SQLPoolConfig{}.apply(sqlDriver.db)
+	if got := sqlDriver.db.Stats().MaxOpenConnections; got != 8 {
+		t.Errorf("Expected 8 max open connections, got %d", got)
+	}
+}
+
+func TestSqlMigrationIDsAreUnique(t *testing.T) {
+	ids := make(map[string]struct{})
+	for _, m := range sqlMigrations() {
+		if _, ok := ids[m.Id]; ok {
+			t.Errorf("Migration id %q is used more than once", m.Id)
+		}
+		ids[m.Id] = struct{}{}
+	}
+}
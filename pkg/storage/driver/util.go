@@ -22,6 +22,16 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kblabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -30,42 +40,127 @@ var b64 = base64.StdEncoding
 
 var magicGzip = []byte{0x1f, 0x8b, 0x08}
 
-var systemLabels = []string{"name", "owner", "status", "version", "createdAt", "modifiedAt"}
+// versionZstd is the version byte prefixing the releases compressed with
+// zstd. It can be neither the first byte of a gzip stream nor of a JSON
+// document, so it tells the encodings apart.
+const versionZstd byte = 0x01
+
+// Compression is the algorithm compressing the encoded releases.
+type Compression string
+
+const (
+	// CompressionGzip compresses releases with gzip, which all versions of
+	// Helm can read. It is the default.
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses releases with zstd, which is smaller and
+	// faster than gzip, but cannot be read by older versions of Helm.
+	CompressionZstd Compression = "zstd"
+)
+
+// zstdCodec returns the zstd encoder and decoder, which are safe for
+// concurrent use and created on first use.
+var zstdCodec = sync.OnceValues(func() (*zstd.Encoder, *zstd.Decoder) {
+	// Neither fails without options.
+	enc, _ := zstd.NewWriter(nil)
+	dec, _ := zstd.NewReader(nil)
+	return enc, dec
+})
+
+// codec encodes and decodes the releases of a driver. It is embedded by the
+// drivers that encode releases, so each of them is configured on its own.
+type codec struct {
+	// compression compresses the encoded releases. Empty means gzip.
+	compression Compression
+	// envelope encrypts the encoded releases, unless it is nil.
+	envelope *envelopeEncrypter
+}
+
+// SetCompression sets the algorithm compressing the releases encoded by the
+// driver. Releases are decoded whatever algorithm compressed them.
+func (c *codec) SetCompression(comp Compression) error {
+	switch comp {
+	case CompressionGzip, CompressionZstd:
+	default:
+		return errors.Errorf("unknown compression %q: must be one of %q, %q", comp, CompressionGzip, CompressionZstd)
+	}
+	c.compression = comp
+	return nil
+}
+
+const (
+	// ChartLabel is the system label holding the name of the chart of a
+	// release.
+	ChartLabel = "helm.sh/chart"
+	// ChartVersionLabel is the system label holding the version of the chart
+	// of a release.
+	ChartVersionLabel = "helm.sh/chart-version"
+)
+
+var systemLabels = []string{"name", "owner", "status", "version", "createdAt", "modifiedAt", ChartLabel, ChartVersionLabel}
 
 // encodeRelease encodes a release returning a base64 encoded
-// gzipped string representation, or error.
-func encodeRelease(rls *rspb.Release) (string, error) {
+// compressed, and optionally encrypted, string representation, or error.
+func (c *codec) encodeRelease(rls *rspb.Release) (string, error) {
 	b, err := json.Marshal(rls)
 	if err != nil {
 		return "", err
 	}
+	b, err = c.compress(b)
+	if err != nil {
+		return "", err
+	}
+	b, err = c.encrypt(b)
+	if err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(b), nil
+}
+
+// compress compresses b with the compression of the codec.
+func (c *codec) compress(b []byte) ([]byte, error) {
+	if c.compression == CompressionZstd {
+		enc, _ := zstdCodec()
+		return enc.EncodeAll(b, []byte{versionZstd}), nil
+	}
 	var buf bytes.Buffer
 	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if _, err = w.Write(b); err != nil {
-		return "", err
+		return nil, err
 	}
 	w.Close()
-
-	return b64.EncodeToString(buf.Bytes()), nil
+	return buf.Bytes(), nil
 }
 
 // decodeRelease decodes the bytes of data into a release
-// type. Data must contain a base64 encoded gzipped string of a
-// valid release, otherwise an error is returned.
-func decodeRelease(data string) (*rspb.Release, error) {
+// type. Data must contain a base64 encoded gzip or zstd compressed,
+// and optionally encrypted, string of a valid release, otherwise an
+// error is returned.
+func (c *codec) decodeRelease(data string) (*rspb.Release, error) {
 	// base64 decode string
 	b, err := b64.DecodeString(data)
 	if err != nil {
 		return nil, err
 	}
+	b, err = c.decrypt(b)
+	if err != nil {
+		return nil, err
+	}
 
 	// For backwards compatibility with releases that were stored before
-	// compression was introduced we skip decompression if the
-	// gzip magic header is not found
-	if len(b) > 3 && bytes.Equal(b[0:3], magicGzip) {
+	// compression was introduced we skip decompression if neither the
+	// zstd version byte nor the gzip magic header is found
+	switch {
+	case len(b) > 1 && b[0] == versionZstd:
+		_, dec := zstdCodec()
+		b2, err := dec.DecodeAll(b[1:], nil)
+		if err != nil {
+			return nil, err
+		}
+		b = b2
+	case len(b) > 3 && bytes.Equal(b[0:3], magicGzip):
 		r, err := gzip.NewReader(bytes.NewReader(b))
 		if err != nil {
 			return nil, err
@@ -120,3 +215,138 @@ func ContainsSystemLabels(lbs map[string]string) bool {
 func GetSystemLabels() []string {
 	return systemLabels
 }
+
+// labeledRecord is a release stored with its labels, as a JSON document,
+// by the drivers whose backend has no labels of its own. The release is
+// encoded as by encodeRelease.
+type labeledRecord struct {
+	Labels  map[string]string `json:"labels"`
+	Release string            `json:"release"`
+}
+
+// chartLabels returns the ChartLabel and ChartVersionLabel labels of the
+// release. Values that are not valid label values, such as versions with
+// build metadata, are left out, so releases cannot be queried by them.
+func chartLabels(rls *rspb.Release) map[string]string {
+	lbs := map[string]string{}
+	if rls.Chart == nil || rls.Chart.Metadata == nil {
+		return lbs
+	}
+	for k, v := range map[string]string{
+		ChartLabel:        rls.Chart.Metadata.Name,
+		ChartVersionLabel: rls.Chart.Metadata.Version,
+	} {
+		if v != "" && len(validation.IsValidLabelValue(v)) == 0 {
+			lbs[k] = v
+		}
+	}
+	return lbs
+}
+
+// releaseNamespace returns the namespace of the release, defaulting to the
+// default namespace.
+func releaseNamespace(rls *rspb.Release) string {
+	if rls.Namespace == "" {
+		return defaultNamespace
+	}
+	return rls.Namespace
+}
+
+// matchLabels reports whether lbs holds all the labels of selector.
+func matchLabels(lbs, selector map[string]string) bool {
+	for k, v := range selector {
+		if lbs[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeLabeledRecord encodes the release with its system labels and the
+// labels of lbs into a labeled record.
+func (c *codec) encodeLabeledRecord(rls *rspb.Release, lbs labels) ([]byte, error) {
+	s, err := c.encodeRelease(rls)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply labels
+	lbs.set("name", rls.Name)
+	lbs.set("owner", "helm")
+	lbs.set("status", rls.Info.Status.String())
+	lbs.set("version", strconv.Itoa(rls.Version))
+	for k, v := range chartLabels(rls) {
+		lbs.set(k, v)
+	}
+
+	return json.Marshal(labeledRecord{Labels: lbs.toMap(), Release: s})
+}
+
+// decodeLabeledRecord decodes a labeled record into the release and its
+// labels.
+func (c *codec) decodeLabeledRecord(data []byte) (*rspb.Release, map[string]string, error) {
+	var record labeledRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, nil, err
+	}
+	rls, err := c.decodeRelease(record.Release)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record.Labels == nil {
+		record.Labels = map[string]string{}
+	}
+	return rls, record.Labels, nil
+}
+
+// unlabeledContinue prefixes the continue tokens of the pages of releases
+// stored before their chart was labeled.
+const unlabeledContinue = "unlabeled/"
+
+// listKubePage lists the page of opts of the Kubernetes objects of releases
+// with list, and returns the continue token of the next page.
+//
+// The label selector of the objects is narrowed down by the labels of opts.
+// Since releases stored by older versions of Helm have no chart labels,
+// queries by chart then read those releases after the labeled ones.
+func listKubePage(opts ListOptions, list func(metav1.ListOptions) (string, error)) (string, error) {
+	lbs := kblabels.Set{"owner": "helm"}
+	byChart := false
+	for k, v := range opts.Labels {
+		// Releases with values that are not valid label values are not
+		// labeled with them, and are left to the filter.
+		if len(validation.IsValidLabelValue(v)) != 0 {
+			continue
+		}
+		lbs[k] = v
+		byChart = byChart || k == ChartLabel || k == ChartVersionLabel
+	}
+
+	selector := lbs.AsSelector()
+	token, unlabeled := strings.CutPrefix(opts.Continue, unlabeledContinue)
+	if unlabeled {
+		delete(lbs, ChartLabel)
+		delete(lbs, ChartVersionLabel)
+		req, err := kblabels.NewRequirement(ChartLabel, selection.DoesNotExist, nil)
+		if err != nil {
+			return "", err
+		}
+		selector = lbs.AsSelector().Add(*req)
+	}
+
+	next, err := list(metav1.ListOptions{
+		LabelSelector: selector.String(),
+		Limit:         int64(max(opts.Limit, 0)),
+		Continue:      token,
+	})
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case unlabeled && next != "":
+		next = unlabeledContinue + next
+	case !unlabeled && next == "" && byChart:
+		next = unlabeledContinue
+	}
+	return next, nil
+}
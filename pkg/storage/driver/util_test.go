@@ -14,8 +14,12 @@ limitations under the License.
 package driver
 
 import (
+	"bytes"
+	"encoding/json"
 	"reflect"
 	"testing"
+
+	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
 
 func TestGetSystemLabel(t *testing.T) {
@@ -106,3 +110,62 @@ func TestContainsSystemLabels(t *testing.T) {
 		}
 	}
 }
+
+func TestEncodeReleaseCompression(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	// Labels are stored by the drivers, not in the payload.
+	rel.Labels = nil
+
+	// gzip is the default, readable by older versions of Helm.
+	var gzc codec
+	gz, err := gzc.encodeRelease(rel)
+	if err != nil {
+		t.Fatalf("Failed to encode release with gzip: %s", err)
+	}
+	if b, _ := b64.DecodeString(gz); !bytes.HasPrefix(b, magicGzip) {
+		t.Errorf("Expected a gzip payload, got %x", b[:4])
+	}
+
+	// The compression of a codec does not change that of the others.
+	var zstc codec
+	if err := zstc.SetCompression(CompressionZstd); err != nil {
+		t.Fatalf("Failed to set compression: %s", err)
+	}
+	if gzc.compression != "" {
+		t.Errorf("Expected the gzip codec to be left alone, got %q", gzc.compression)
+	}
+
+	zst, err := zstc.encodeRelease(rel)
+	if err != nil {
+		t.Fatalf("Failed to encode release with zstd: %s", err)
+	}
+	if b, _ := b64.DecodeString(zst); b[0] != versionZstd {
+		t.Errorf("Expected a zstd payload, got %x", b[:4])
+	}
+
+	// Releases are decoded whatever compressed them.
+	raw, _ := json.Marshal(rel)
+	for name, data := range map[string]string{
+		"gzip":         gz,
+		"zstd":         zst,
+		"uncompressed": b64.EncodeToString(raw),
+	} {
+		got, err := gzc.decodeRelease(data)
+		if err != nil {
+			t.Fatalf("Failed to decode %s release: %s", name, err)
+		}
+		if !reflect.DeepEqual(rel, got) {
+			t.Errorf("Expected %s release {%v}, got {%v}", name, rel, got)
+		}
+	}
+}
+
+func TestSetCompressionUnknown(t *testing.T) {
+	c := codec{compression: CompressionZstd}
+	if err := c.SetCompression("lz4"); err == nil {
+		t.Error("Expected an error for an unknown compression")
+	}
+	if c.compression != CompressionZstd {
+		t.Errorf("Expected compression to remain %q, got %q", CompressionZstd, c.compression)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption // import "helm.sh/helm/v4/pkg/storage/encryption"
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+var _ driver.KeyEncrypter = (*AWSKMS)(nil)
+
+// AWSKMS is a symmetric key of AWS KMS. Requests are authorized with the
+// credentials of the default credential chain of the AWS SDK, such as the
+// environment, the shared configuration files, web identities and the roles
+// of EC2 instances or ECS tasks.
+type AWSKMS struct {
+	client *kms.Client
+	keyID  string
+}
+
+// NewAWSKMS returns the key of keyID, a key ID, ARN or alias, in region. The
+// region defaults to that of the shared configuration of the AWS SDK, such as
+// the AWS_REGION environment variable. An endpoint, which also defaults to
+// that of the shared configuration, such as the AWS_ENDPOINT_URL_KMS
+// environment variable, selects another endpoint of the service.
+func NewAWSKMS(client *http.Client, keyID, region, endpoint string) (*AWSKMS, error) {
+	if keyID == "" {
+		return nil, errors.New("no AWS KMS key configured")
+	}
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the AWS configuration")
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("no AWS region configured: set the region parameter or AWS_REGION")
+	}
+	return &AWSKMS{
+		client: kms.NewFromConfig(cfg, func(o *kms.Options) {
+			o.HTTPClient = client
+			if endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+			}
+		}),
+		keyID: keyID,
+	}, nil
+}
+
+// EncryptKey encrypts the data key dek.
+func (k *AWSKMS) EncryptKey(ctx context.Context, dek []byte) ([]byte, error) {
+	out, err := k.client.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:     aws.String(k.keyID),
+		Plaintext: dek,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.CiphertextBlob, nil
+}
+
+// DecryptKey decrypts a data key encrypted by EncryptKey.
+func (k *AWSKMS) DecryptKey(ctx context.Context, encrypted []byte) ([]byte, error) {
+	// The key is named so that KMS rejects data keys of other keys.
+	out, err := k.client.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:          aws.String(k.keyID),
+		CiphertextBlob: encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
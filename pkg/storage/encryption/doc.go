@@ -0,0 +1,40 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package encryption implements the key encryption keys (KEKs) of the envelope
+encryption of releases: local keys, keys of the transit secrets engine of
+HashiCorp Vault, and keys of AWS KMS.
+
+The keys are opened from URLs:
+
+	local:///path/to/key
+	vault://key-name?mount=transit
+	awskms://alias/helm-releases?region=eu-west-1
+
+A local key is a file holding a 32-byte AES key, raw or base64 encoded.
+
+Vault is addressed by the VAULT_ADDR environment variable and authorized with
+the token of VAULT_TOKEN, in the namespace of VAULT_NAMESPACE if any. The
+mount of the transit engine defaults to "transit".
+
+AWS KMS keys are named by key ID, ARN or alias. Requests are authorized with
+the default credential chain of the AWS SDK, which reads the environment, the
+shared configuration files, web identity tokens and the roles of EC2 instances
+and ECS tasks. The "region" and "endpoint" query parameters override the
+region and the endpoint of the shared configuration.
+*/
+package encryption // import "helm.sh/helm/v4/pkg/storage/encryption"
@@ -0,0 +1,85 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption // import "helm.sh/helm/v4/pkg/storage/encryption"
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+const requestTimeout = 30 * time.Second
+
+// Open opens the key encryption key of rawURL.
+func Open(rawURL string) (driver.KeyEncrypter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid key encryption key URL")
+	}
+	query := u.Query()
+	client := &http.Client{Timeout: requestTimeout}
+
+	switch u.Scheme {
+	case "local":
+		return NewLocalKey(u.Path)
+	case "vault":
+		return NewVaultTransit(client, u.Host, query.Get("mount"))
+	case "awskms":
+		return NewAWSKMS(client, strings.TrimPrefix(u.Host+u.Path, "/"), query.Get("region"), query.Get("endpoint"))
+	default:
+		return nil, errors.Errorf("unsupported key encryption key %q: must be one of local, vault, awskms", u.Scheme)
+	}
+}
+
+// do sends req and decodes its JSON response into out. Errors carry the
+// message extracted by errMessage.
+func do(client *http.Client, req *http.Request, out any, errMessage func([]byte) string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := errMessage(body)
+		if msg == "" {
+			msg = resp.Status
+		}
+		return fmt.Errorf("%s %s://%s%s: %s", req.Method, req.URL.Scheme, req.URL.Host, req.URL.EscapedPath(), msg)
+	}
+	return json.Unmarshal(body, out)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
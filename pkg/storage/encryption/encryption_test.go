@@ -0,0 +1,199 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+// testKeyEncrypter encrypts and decrypts a data key with kek.
+func testKeyEncrypter(t *testing.T, kek driver.KeyEncrypter) {
+	t.Helper()
+	ctx := context.Background()
+
+	dek := bytes.Repeat([]byte{0x2a}, 32)
+	encrypted, err := kek.EncryptKey(ctx, dek)
+	if err != nil {
+		t.Fatalf("failed to encrypt data key: %s", err)
+	}
+	if bytes.Contains(encrypted, dek) {
+		t.Error("expected the encrypted data key not to hold the data key")
+	}
+	got, err := kek.DecryptKey(ctx, encrypted)
+	if err != nil {
+		t.Fatalf("failed to decrypt data key: %s", err)
+	}
+	if !bytes.Equal(dek, got) {
+		t.Errorf("expected data key %x, got %x", dek, got)
+	}
+	if _, err := kek.DecryptKey(ctx, []byte("not a data key")); err == nil {
+		t.Error("expected an error decrypting an invalid data key")
+	}
+}
+
+func TestLocalKey(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw")
+	if err := os.WriteFile(raw, bytes.Repeat([]byte{1}, 32), 0600); err != nil {
+		t.Fatal(err)
+	}
+	encoded := filepath.Join(dir, "encoded")
+	if err := os.WriteFile(encoded, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{raw, encoded} {
+		kek, err := Open("local://" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testKeyEncrypter(t, kek)
+	}
+
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(short, []byte("too short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open("local://" + short); err == nil {
+		t.Error("expected an error for a key of the wrong size")
+	}
+}
+
+// fakeTransit is a Vault transit engine "encrypting" by base64 encoding.
+func fakeTransit(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			t.Errorf("unauthorized Vault request %s %s", r.Method, r.URL)
+		}
+		var in map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Fatal(err)
+		}
+		var data map[string]string
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/helm":
+			data = map[string]string{"ciphertext": "vault:v1:" + base64.StdEncoding.EncodeToString([]byte(in["plaintext"]))}
+		case "/v1/transit/decrypt/helm":
+			plaintext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(in["ciphertext"], "vault:v1:"))
+			if err != nil || !strings.HasPrefix(in["ciphertext"], "vault:v1:") {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string][]string{"errors": {"invalid ciphertext"}})
+				return
+			}
+			data = map[string]string{"plaintext": string(plaintext)}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}
+}
+
+func TestVaultTransit(t *testing.T) {
+	server := httptest.NewServer(fakeTransit(t))
+	defer server.Close()
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "token")
+	kek, err := Open("vault://helm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testKeyEncrypter(t, kek)
+}
+
+func TestVaultTransitWithoutToken(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+	t.Setenv("VAULT_TOKEN", "")
+	if _, err := Open("vault://helm"); err == nil {
+		t.Error("expected an error without a Vault token")
+	}
+}
+
+// fakeKMS is an AWS KMS "encrypting" by inverting the bits of the data key
+// and prefixing the key ID.
+func fakeKMS(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request") {
+			t.Errorf("unsigned KMS request %s %s", r.Method, r.URL)
+		}
+		var in struct {
+			KeyID          string `json:"KeyId"`
+			Plaintext      []byte
+			CiphertextBlob []byte
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Fatal(err)
+		}
+		if in.KeyID != "alias/helm" {
+			t.Errorf("expected key alias/helm, got %q", in.KeyID)
+		}
+		prefix := []byte(in.KeyID + ":")
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": append(prefix, invert(in.Plaintext)...)})
+		case "TrentService.Decrypt":
+			if !bytes.HasPrefix(in.CiphertextBlob, prefix) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"__type": "InvalidCiphertextException", "message": "invalid ciphertext"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": invert(in.CiphertextBlob[len(prefix):])})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+}
+
+func invert(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[i] = ^c
+	}
+	return out
+}
+
+func TestAWSKMS(t *testing.T) {
+	server := httptest.NewServer(fakeKMS(t))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	kek, err := Open("awskms://alias/helm?region=eu-west-1&endpoint=" + url.QueryEscape(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testKeyEncrypter(t, kek)
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	if _, err := Open("gcpkms://key"); err == nil {
+		t.Error("expected an error for an unsupported key encryption key")
+	}
+}
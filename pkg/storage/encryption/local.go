@@ -0,0 +1,92 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption // import "helm.sh/helm/v4/pkg/storage/encryption"
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+var _ driver.KeyEncrypter = (*LocalKey)(nil)
+
+// localKeySize is the size of the AES-256 local keys.
+const localKeySize = 32
+
+// LocalKey is a key encryption key read from a local file. Data keys are
+// encrypted with AES-GCM.
+type LocalKey struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKey returns the key of the file at path, which holds a 32-byte
+// key, raw or base64 encoded.
+func NewLocalKey(path string) (*LocalKey, error) {
+	if path == "" {
+		return nil, errors.New("no local key file configured")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read local key")
+	}
+	key := data
+	if len(key) != localKeySize {
+		key, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+		if err != nil || len(key) != localKeySize {
+			return nil, errors.Errorf("local key %s must hold %d bytes, raw or base64 encoded", path, localKeySize)
+		}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKey{aead: aead}, nil
+}
+
+// EncryptKey encrypts the data key dek.
+func (k *LocalKey) EncryptKey(_ context.Context, dek []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return k.aead.Seal(nonce, nonce, dek, nil), nil
+}
+
+// DecryptKey decrypts a data key encrypted by EncryptKey.
+func (k *LocalKey) DecryptKey(_ context.Context, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < k.aead.NonceSize() {
+		return nil, errors.New("invalid encrypted data key")
+	}
+	nonce, ciphertext := encrypted[:k.aead.NonceSize()], encrypted[k.aead.NonceSize():]
+	dek, err := k.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt data key with local key")
+	}
+	return dek, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption // import "helm.sh/helm/v4/pkg/storage/encryption"
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+var _ driver.KeyEncrypter = (*VaultTransit)(nil)
+
+// defaultVaultMount is the default mount of the transit secrets engine.
+const defaultVaultMount = "transit"
+
+// VaultTransit is a key of the transit secrets engine of HashiCorp Vault.
+// Requests are authorized with the token of the VAULT_TOKEN environment
+// variable.
+type VaultTransit struct {
+	client    *http.Client
+	address   *url.URL
+	mount     string
+	key       string
+	token     string
+	namespace string
+}
+
+// NewVaultTransit returns the transit key named key of the Vault of the
+// VAULT_ADDR environment variable, in the namespace of VAULT_NAMESPACE. The
+// mount of the transit engine defaults to "transit".
+func NewVaultTransit(client *http.Client, key, mount string) (*VaultTransit, error) {
+	if key == "" {
+		return nil, errors.New("no Vault transit key configured")
+	}
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return nil, errors.New("Vault requires the VAULT_ADDR environment variable")
+	}
+	u, err := url.Parse(address)
+	if err != nil || u.Host == "" {
+		return nil, errors.Errorf("invalid $VAULT_ADDR %q", address)
+	}
+	v := &VaultTransit{
+		client:    client,
+		address:   u,
+		mount:     strings.Trim(firstNonEmpty(mount, defaultVaultMount), "/"),
+		key:       key,
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+	}
+	if v.token == "" {
+		return nil, errors.New("Vault requires the VAULT_TOKEN environment variable")
+	}
+	return v, nil
+}
+
+// EncryptKey encrypts the data key dek.
+func (v *VaultTransit) EncryptKey(ctx context.Context, dek []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	in := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dek)}
+	if err := v.do(ctx, "encrypt", in, &resp); err != nil {
+		return nil, err
+	}
+	// The ciphertext, such as vault:v1:..., names the version of the key.
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+// DecryptKey decrypts a data key encrypted by EncryptKey.
+func (v *VaultTransit) DecryptKey(ctx context.Context, encrypted []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	in := map[string]string{"ciphertext": string(encrypted)}
+	if err := v.do(ctx, "decrypt", in, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+// do posts in to the operation of the transit key, and decodes the response
+// into out.
+func (v *VaultTransit) do(ctx context.Context, operation string, in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	u := *v.address
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/v1/" + v.mount + "/" + operation + "/" + url.PathEscape(v.key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	return do(v.client, req, out, func(body []byte) string {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(body, &e) != nil {
+			return ""
+		}
+		return strings.Join(e.Errors, "; ")
+	})
+}
@@ -0,0 +1,164 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+var _ driver.ObjectStore = (*AzureBlob)(nil)
+
+// AzureBlob is a container of Azure Blob Storage. Requests are authorized
+// with the shared access signature of the AZURE_STORAGE_SAS_TOKEN environment
+// variable or, without one, with the default credential chain of the Azure
+// SDK, such as the service principal of the environment, workload and
+// managed identities, and the credentials of the Azure CLI. Conditional
+// writes use the If-Match and If-None-Match headers.
+type AzureBlob struct {
+	client *container.Client
+}
+
+// NewAzureBlob returns the container of the storage account of the
+// AZURE_STORAGE_ACCOUNT environment variable. An endpoint selects another
+// blob service, such as an emulator, instead of the one of the account.
+func NewAzureBlob(client *http.Client, containerName, endpoint string) (*AzureBlob, error) {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if endpoint == "" {
+		account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+		if account == "" {
+			return nil, errors.New("Azure Blob Storage requires the AZURE_STORAGE_ACCOUNT environment variable or an endpoint")
+		}
+		endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+	containerURL := endpoint + "/" + containerName
+	opts := &container.ClientOptions{ClientOptions: policy.ClientOptions{Transport: client}}
+
+	var c *container.Client
+	var err error
+	if sas := strings.TrimPrefix(os.Getenv("AZURE_STORAGE_SAS_TOKEN"), "?"); sas != "" {
+		c, err = container.NewClientWithNoCredential(containerURL+"?"+sas, opts)
+	} else {
+		var cred *azidentity.DefaultAzureCredential
+		cred, err = azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+			ClientOptions: policy.ClientOptions{Transport: client},
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load the Azure credentials")
+		}
+		c, err = container.NewClient(containerURL, cred, opts)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the Azure Blob Storage client")
+	}
+	return &AzureBlob{client: c}, nil
+}
+
+// Get returns the data and the ETag of the blob at key.
+func (a *AzureBlob) Get(ctx context.Context, key string) ([]byte, string, error) {
+	resp, err := a.client.NewBlobClient(key).DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, "", azureError(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, string(*resp.ETag), nil
+}
+
+// Put writes the blob at key if its ETag is ifVersion, or if it does not
+// exist when ifVersion is empty, and returns its new ETag.
+func (a *AzureBlob) Put(ctx context.Context, key string, data []byte, ifVersion string) (string, error) {
+	cond := &blob.ModifiedAccessConditions{}
+	if ifVersion == "" {
+		cond.IfNoneMatch = to(azcore.ETagAny)
+	} else {
+		cond.IfMatch = to(azcore.ETag(ifVersion))
+	}
+	resp, err := a.client.NewBlockBlobClient(key).Upload(ctx, streaming.NopCloser(bytes.NewReader(data)), &blockblob.UploadOptions{
+		AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: cond},
+	})
+	if err != nil {
+		return "", azureError(err)
+	}
+	return string(*resp.ETag), nil
+}
+
+// Delete deletes the blob at key if its ETag is ifVersion.
+func (a *AzureBlob) Delete(ctx context.Context, key string, ifVersion string) error {
+	_, err := a.client.NewBlobClient(key).Delete(ctx, &blob.DeleteOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: to(azcore.ETag(ifVersion))},
+		},
+	})
+	return azureError(err)
+}
+
+// List returns the keys of the blobs starting with prefix.
+func (a *AzureBlob) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	pages := a.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: &prefix})
+	for pages.More() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, azureError(err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			keys = append(keys, *item.Name)
+		}
+	}
+	return keys, nil
+}
+
+// azureError returns driver.ErrObjectNotFound and driver.ErrObjectModified
+// for the errors of missing blobs and of failed conditional writes.
+func azureError(err error) error {
+	var resp *azcore.ResponseError
+	if !errors.As(err, &resp) {
+		return err
+	}
+	// An If-None-Match write of an existing blob fails with 409, and an
+	// If-Match write of another version with 412.
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return driver.ErrObjectNotFound
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return driver.ErrObjectModified
+	}
+	return err
+}
+
+func to[T any](v T) *T {
+	return &v
+}
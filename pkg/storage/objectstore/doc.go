@@ -0,0 +1,39 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package objectstore implements the object stores of the ObjectStorage
+storage driver: Amazon S3 and S3-compatible stores, Google Cloud Storage and
+Azure Blob Storage.
+
+The stores are opened from URLs naming the bucket, with the path of the URL
+as the key prefix of the releases:
+
+	s3://bucket/prefix?region=eu-west-1
+	gs://bucket/prefix
+	azblob://container/prefix
+
+The "endpoint" query parameter overrides the endpoint of the service, to use
+an S3-compatible store or an emulator.
+
+The stores are used through the SDKs of their services, and authorized with
+their default credentials: the default credential chain of the AWS SDK for
+S3, the application default credentials for GCS, and the shared access
+signature of the AZURE_STORAGE_SAS_TOKEN environment variable or the default
+credential chain of the Azure SDK for Azure Blob Storage, whose account is
+named by the AZURE_STORAGE_ACCOUNT environment variable.
+*/
+package objectstore // import "helm.sh/helm/v4/pkg/storage/objectstore"
@@ -0,0 +1,153 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+var _ driver.ObjectStore = (*GCS)(nil)
+
+// GCS is a bucket of Google Cloud Storage. Requests are authorized with the
+// application default credentials, such as the service account key file of
+// the GOOGLE_APPLICATION_CREDENTIALS environment variable, the credentials of
+// gcloud, workload identities or the service account of the Compute Engine
+// metadata server. Conditional writes use the generations of the objects.
+type GCS struct {
+	bucket *storage.BucketHandle
+}
+
+// NewGCS returns the GCS bucket. An endpoint selects another endpoint of the
+// JSON API of the service. The STORAGE_EMULATOR_HOST environment variable
+// selects an emulator, whose requests are not authorized.
+func NewGCS(bucket, endpoint string) (*GCS, error) {
+	var opts []option.ClientOption
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the GCS client")
+	}
+	return &GCS{bucket: client.Bucket(bucket)}, nil
+}
+
+// Get returns the data and the generation of the object at key.
+func (g *GCS) Get(ctx context.Context, key string) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := g.bucket.Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, "", gcsError(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, strconv.FormatInt(r.Attrs.Generation, 10), nil
+}
+
+// Put writes the object at key if its generation is ifVersion, or if it does
+// not exist when ifVersion is empty, and returns its new generation.
+func (g *GCS) Put(ctx context.Context, key string, data []byte, ifVersion string) (string, error) {
+	cond := storage.Conditions{DoesNotExist: true}
+	if ifVersion != "" {
+		generation, err := strconv.ParseInt(ifVersion, 10, 64)
+		if err != nil {
+			return "", errors.Errorf("invalid generation %q", ifVersion)
+		}
+		cond = storage.Conditions{GenerationMatch: generation}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	w := g.bucket.Object(key).If(cond).NewWriter(ctx)
+	// The object is uploaded in a single request.
+	w.ChunkSize = 0
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return "", gcsError(err)
+	}
+	if err := w.Close(); err != nil {
+		return "", gcsError(err)
+	}
+	return strconv.FormatInt(w.Attrs().Generation, 10), nil
+}
+
+// Delete deletes the object at key if its generation is ifVersion.
+func (g *GCS) Delete(ctx context.Context, key string, ifVersion string) error {
+	generation, err := strconv.ParseInt(ifVersion, 10, 64)
+	if err != nil {
+		return errors.Errorf("invalid generation %q", ifVersion)
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	return gcsError(g.bucket.Object(key).If(storage.Conditions{GenerationMatch: generation}).Delete(ctx))
+}
+
+// List returns the keys of the objects starting with prefix.
+func (g *GCS) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	query := &storage.Query{Prefix: prefix}
+	if err := query.SetAttrSelection([]string{"Name"}); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	it := g.bucket.Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return keys, nil
+		}
+		if err != nil {
+			return nil, gcsError(err)
+		}
+		keys = append(keys, attrs.Name)
+	}
+}
+
+// gcsError returns driver.ErrObjectNotFound and driver.ErrObjectModified for
+// the errors of missing objects and of failed conditional writes.
+func gcsError(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return driver.ErrObjectNotFound
+	}
+	var e *googleapi.Error
+	if errors.As(err, &e) {
+		switch e.Code {
+		case http.StatusNotFound:
+			return driver.ErrObjectNotFound
+		case http.StatusPreconditionFailed:
+			return driver.ErrObjectModified
+		}
+	}
+	return err
+}
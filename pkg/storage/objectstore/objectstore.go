@@ -0,0 +1,67 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore // import "helm.sh/helm/v4/pkg/storage/objectstore"
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+// DefaultPrefix is the key prefix of the releases when the URL has no path.
+const DefaultPrefix = "helm/releases"
+
+const requestTimeout = 30 * time.Second
+
+// Open opens the object store of rawURL, and returns it with the key prefix
+// of the releases.
+func Open(rawURL string) (driver.ObjectStore, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "invalid object store URL")
+	}
+	if u.Host == "" {
+		return nil, "", errors.Errorf("object store URL %q has no bucket", rawURL)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	query := u.Query()
+	client := &http.Client{Timeout: requestTimeout}
+
+	var store driver.ObjectStore
+	switch u.Scheme {
+	case "s3":
+		store, err = NewS3(client, u.Host, query.Get("region"), query.Get("endpoint"))
+	case "gs":
+		store, err = NewGCS(u.Host, query.Get("endpoint"))
+	case "azblob":
+		store, err = NewAzureBlob(client, u.Host, query.Get("endpoint"))
+	default:
+		return nil, "", errors.Errorf("unsupported object store %q: must be one of s3, gs, azblob", u.Scheme)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return store, prefix, nil
+}
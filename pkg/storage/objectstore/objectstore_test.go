@@ -0,0 +1,413 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+// fakeBucket is an in-memory bucket with versioned objects, listing one key
+// per page to exercise pagination.
+type fakeBucket struct {
+	sync.Mutex
+	objects  map[string][]byte
+	versions map[string]int
+	version  int
+}
+
+func newFakeBucket() *fakeBucket {
+	return &fakeBucket{objects: map[string][]byte{}, versions: map[string]int{}}
+}
+
+// write writes the object at key if its version is ifVersion, or if it does
+// not exist when ifVersion is empty, and returns its new version, or the HTTP
+// status of the failed write.
+func (b *fakeBucket) write(key string, data []byte, ifVersion string, conflict int) (string, int) {
+	b.Lock()
+	defer b.Unlock()
+
+	v, exists := b.versions[key]
+	if ifVersion == "" && exists {
+		return "", conflict
+	}
+	if ifVersion != "" && (!exists || strconv.Itoa(v) != ifVersion) {
+		return "", http.StatusPreconditionFailed
+	}
+	b.version++
+	b.objects[key] = data
+	b.versions[key] = b.version
+	return strconv.Itoa(b.version), http.StatusOK
+}
+
+func (b *fakeBucket) read(key string) ([]byte, string, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	data, ok := b.objects[key]
+	return data, strconv.Itoa(b.versions[key]), ok
+}
+
+func (b *fakeBucket) remove(key, ifVersion string) int {
+	b.Lock()
+	defer b.Unlock()
+
+	v, exists := b.versions[key]
+	if !exists {
+		return http.StatusNotFound
+	}
+	if strconv.Itoa(v) != ifVersion {
+		return http.StatusPreconditionFailed
+	}
+	delete(b.objects, key)
+	delete(b.versions, key)
+	return http.StatusNoContent
+}
+
+// page returns the first key starting with prefix after marker, and the
+// marker of the next page.
+func (b *fakeBucket) page(prefix, marker string) ([]string, string) {
+	b.Lock()
+	defer b.Unlock()
+
+	var keys []string
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > 1 {
+		return keys[:1], keys[0]
+	}
+	return keys, ""
+}
+
+func unquote(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+func (b *fakeBucket) s3Handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("unsigned S3 request %s %s", r.Method, r.URL)
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			keys, next := b.page(r.URL.Query().Get("prefix"), r.URL.Query().Get("continuation-token"))
+			fmt.Fprint(w, "<ListBucketResult>")
+			for _, k := range keys {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", k)
+			}
+			fmt.Fprintf(w, "<IsTruncated>%t</IsTruncated><NextContinuationToken>%s</NextContinuationToken></ListBucketResult>", next != "", next)
+		case r.Method == http.MethodGet:
+			data, version, ok := b.read(key)
+			if !ok {
+				writeS3Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", `"`+version+`"`)
+			w.Write(data)
+		case r.Method == http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			version, status := b.write(key, data, unquote(r.Header.Get("If-Match")), http.StatusPreconditionFailed)
+			if status != http.StatusOK {
+				writeS3Error(w, "PreconditionFailed", status)
+				return
+			}
+			w.Header().Set("ETag", `"`+version+`"`)
+		case r.Method == http.MethodDelete:
+			if status := b.remove(key, unquote(r.Header.Get("If-Match"))); status != http.StatusNoContent {
+				writeS3Error(w, http.StatusText(status), status)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, code string, status int) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code></Error>", code)
+}
+
+func (b *fakeBucket) gcsHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/upload/storage/v1/b/bucket/o":
+			ifVersion := query.Get("ifGenerationMatch")
+			if ifVersion == "0" {
+				ifVersion = ""
+			}
+			name, data := readMultipart(t, r)
+			version, status := b.write(name, data, ifVersion, http.StatusPreconditionFailed)
+			if status != http.StatusOK {
+				writeGCSError(w, status)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"bucket": "bucket", "name": name, "generation": version})
+		case r.URL.Path == "/storage/v1/b/bucket/o":
+			keys, next := b.page(query.Get("prefix"), query.Get("pageToken"))
+			var result struct {
+				Items         []map[string]string `json:"items"`
+				NextPageToken string              `json:"nextPageToken,omitempty"`
+			}
+			for _, k := range keys {
+				result.Items = append(result.Items, map[string]string{"name": k})
+			}
+			result.NextPageToken = next
+			json.NewEncoder(w).Encode(result)
+		case r.Method == http.MethodGet:
+			key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/storage/v1/b/bucket/o/"), "/bucket/")
+			data, version, ok := b.read(key)
+			if !ok {
+				writeGCSError(w, http.StatusNotFound)
+				return
+			}
+			w.Header().Set("X-Goog-Generation", version)
+			w.Header().Set("X-Goog-Metageneration", "1")
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Write(data)
+		case r.Method == http.MethodDelete:
+			status := b.remove(strings.TrimPrefix(r.URL.Path, "/storage/v1/b/bucket/o/"), query.Get("ifGenerationMatch"))
+			if status != http.StatusNoContent {
+				writeGCSError(w, status)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// readMultipart returns the name and the data of a multipart upload to GCS.
+func readMultipart(t *testing.T, r *http.Request) (string, []byte) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := multipart.NewReader(r.Body, params["boundary"])
+	var metadata struct {
+		Name string `json:"name"`
+	}
+	part, err := parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewDecoder(part).Decode(&metadata); err != nil {
+		t.Fatal(err)
+	}
+	part, err = parts.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(part)
+	return metadata.Name, data
+}
+
+func writeGCSError(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error": {"code": %d, "message": %q}}`, status, http.StatusText(status))
+}
+
+func (b *fakeBucket) azureHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("sig") != "signature" {
+			t.Errorf("unauthorized Azure request %s %s", r.Method, r.URL)
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/container/")
+		switch {
+		case r.Method == http.MethodGet && query.Get("comp") == "list":
+			keys, next := b.page(query.Get("prefix"), query.Get("marker"))
+			var result struct {
+				XMLName    xml.Name `xml:"EnumerationResults"`
+				Names      []string `xml:"Blobs>Blob>Name"`
+				NextMarker string
+			}
+			result.Names = keys
+			result.NextMarker = next
+			w.Header().Set("Content-Type", "application/xml")
+			xml.NewEncoder(w).Encode(result)
+		case r.Method == http.MethodGet:
+			data, version, ok := b.read(key)
+			if !ok {
+				writeAzureError(w, "BlobNotFound", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", `"`+version+`"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Write(data)
+		case r.Method == http.MethodPut:
+			if r.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+				t.Errorf("put of a blob without a blob type")
+			}
+			data, _ := io.ReadAll(r.Body)
+			version, status := b.write(key, data, unquote(r.Header.Get("If-Match")), http.StatusConflict)
+			if status != http.StatusOK {
+				writeAzureError(w, "ConditionNotMet", status)
+				return
+			}
+			w.Header().Set("ETag", `"`+version+`"`)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodDelete:
+			if status := b.remove(key, unquote(r.Header.Get("If-Match"))); status != http.StatusNoContent {
+				writeAzureError(w, "ConditionNotMet", status)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+}
+
+func writeAzureError(w http.ResponseWriter, code string, status int) {
+	w.Header().Set("X-Ms-Error-Code", code)
+	w.WriteHeader(status)
+}
+
+// testObjectStore checks the conditional writes and listing of store.
+func testObjectStore(t *testing.T, store driver.ObjectStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	created, err := store.Put(ctx, "helm/default/a", []byte("a1"), "")
+	if err != nil {
+		t.Fatalf("failed to create object: %s", err)
+	}
+	if _, err := store.Put(ctx, "helm/default/a", []byte("a2"), ""); !errors.Is(err, driver.ErrObjectModified) {
+		t.Errorf("expected %v creating an existing object, got %v", driver.ErrObjectModified, err)
+	}
+	data, version, err := store.Get(ctx, "helm/default/a")
+	if err != nil {
+		t.Fatalf("failed to get object: %s", err)
+	}
+	if string(data) != "a1" || version != created {
+		t.Errorf("unexpected object %q of version %q, created as %q", data, version, created)
+	}
+
+	updated, err := store.Put(ctx, "helm/default/a", []byte("a2"), version)
+	if err != nil {
+		t.Fatalf("failed to update object: %s", err)
+	}
+	if updated == "" || updated == version {
+		t.Errorf("expected a new version, got %q", updated)
+	}
+	if _, err := store.Put(ctx, "helm/default/a", []byte("a3"), version); !errors.Is(err, driver.ErrObjectModified) {
+		t.Errorf("expected %v updating a stale version, got %v", driver.ErrObjectModified, err)
+	}
+	if err := store.Delete(ctx, "helm/default/a", version); !errors.Is(err, driver.ErrObjectModified) {
+		t.Errorf("expected %v deleting a stale version, got %v", driver.ErrObjectModified, err)
+	}
+
+	for _, key := range []string{"helm/default/b", "helm/default/c", "helm/other/d"} {
+		if _, err := store.Put(ctx, key, []byte(key), ""); err != nil {
+			t.Fatalf("failed to create object: %s", err)
+		}
+	}
+	keys, err := store.List(ctx, "helm/default/")
+	if err != nil {
+		t.Fatalf("failed to list objects: %s", err)
+	}
+	if strings.Join(keys, ",") != "helm/default/a,helm/default/b,helm/default/c" {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	_, version, _ = store.Get(ctx, "helm/default/a")
+	if err := store.Delete(ctx, "helm/default/a", version); err != nil {
+		t.Fatalf("failed to delete object: %s", err)
+	}
+	if _, _, err := store.Get(ctx, "helm/default/a"); !errors.Is(err, driver.ErrObjectNotFound) {
+		t.Errorf("expected %v after delete, got %v", driver.ErrObjectNotFound, err)
+	}
+	if err := store.Delete(ctx, "helm/default/a", version); !errors.Is(err, driver.ErrObjectNotFound) {
+		t.Errorf("expected %v deleting a missing object, got %v", driver.ErrObjectNotFound, err)
+	}
+}
+
+func TestS3(t *testing.T) {
+	server := httptest.NewServer(newFakeBucket().s3Handler(t))
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	store, prefix, err := Open("s3://bucket/helm?region=eu-west-1&endpoint=" + url.QueryEscape(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != "helm" {
+		t.Errorf("expected prefix helm, got %q", prefix)
+	}
+	testObjectStore(t, store)
+}
+
+func TestGCS(t *testing.T) {
+	server := httptest.NewServer(newFakeBucket().gcsHandler(t))
+	defer server.Close()
+
+	// Requests to an emulator are not authorized.
+	t.Setenv("STORAGE_EMULATOR_HOST", server.URL)
+	store, prefix, err := Open("gs://bucket/helm/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != "helm" {
+		t.Errorf("expected prefix helm, got %q", prefix)
+	}
+	testObjectStore(t, store)
+}
+
+func TestAzureBlob(t *testing.T) {
+	server := httptest.NewServer(newFakeBucket().azureHandler(t))
+	defer server.Close()
+
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2021-12-02&sig=signature")
+	store, prefix, err := Open("azblob://container?endpoint=" + url.QueryEscape(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != DefaultPrefix {
+		t.Errorf("expected prefix %s, got %q", DefaultPrefix, prefix)
+	}
+	testObjectStore(t, store)
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	for _, u := range []string{"ftp://bucket/helm", "s3:///helm"} {
+		if _, _, err := Open(u); err == nil {
+			t.Errorf("expected an error opening %s", u)
+		}
+	}
+}
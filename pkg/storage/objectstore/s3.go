@@ -0,0 +1,162 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+var _ driver.ObjectStore = (*S3)(nil)
+
+// S3 is a bucket of Amazon S3 or of an S3-compatible store. Requests are
+// authorized with the credentials of the default credential chain of the AWS
+// SDK, such as the environment, the shared configuration files, web
+// identities and the roles of EC2 instances or ECS tasks. Conditional writes
+// use the If-Match and If-None-Match headers.
+type S3 struct {
+	client *s3.Client
+	bucket string
+}
+
+// NewS3 returns the S3 bucket of region. The region defaults to that of the
+// shared configuration of the AWS SDK, such as the AWS_REGION environment
+// variable, and then to us-east-1. An endpoint, which also defaults to that
+// of the shared configuration, such as the AWS_ENDPOINT_URL_S3 environment
+// variable, selects an S3-compatible store, addressed with path-style
+// requests.
+func NewS3(client *http.Client, bucket, region, endpoint string) (*S3, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the AWS configuration")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3{
+		client: s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.HTTPClient = client
+			if endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+				o.UsePathStyle = true
+				// S3-compatible stores may not support the checksums the SDK
+				// adds to the requests by default.
+				o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+			}
+		}),
+		bucket: bucket,
+	}, nil
+}
+
+// Get returns the data and the ETag of the object at key.
+func (s *S3) Get(ctx context.Context, key string) ([]byte, string, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", s3Error(err)
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, aws.ToString(out.ETag), nil
+}
+
+// Put writes the object at key if its ETag is ifVersion, or if it does not
+// exist when ifVersion is empty, and returns its new ETag.
+func (s *S3) Put(ctx context.Context, key string, data []byte, ifVersion string) (string, error) {
+	in := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	}
+	if ifVersion == "" {
+		in.IfNoneMatch = aws.String("*")
+	} else {
+		in.IfMatch = aws.String(ifVersion)
+	}
+	out, err := s.client.PutObject(ctx, in)
+	if err != nil {
+		return "", s3Error(err)
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+// Delete deletes the object at key if its ETag is ifVersion.
+func (s *S3) Delete(ctx context.Context, key string, ifVersion string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(key),
+		IfMatch: aws.String(ifVersion),
+	})
+	return s3Error(err)
+}
+
+// List returns the keys of the objects starting with prefix.
+func (s *S3) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, s3Error(err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
+
+// s3Error returns driver.ErrObjectNotFound and driver.ErrObjectModified for
+// the errors of missing objects and of failed conditional writes.
+func s3Error(err error) error {
+	var resp *awshttp.ResponseError
+	if !errors.As(err, &resp) {
+		return err
+	}
+	// An If-None-Match write of an existing object fails with 412, as does
+	// an If-Match write of another version; 409 reports a concurrent
+	// conditional write of the same object.
+	switch resp.HTTPStatusCode() {
+	case http.StatusNotFound:
+		return driver.ErrObjectNotFound
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return driver.ErrObjectModified
+	}
+	return err
+}
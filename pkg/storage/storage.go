@@ -20,12 +20,14 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	relutil "helm.sh/helm/v4/pkg/release/util"
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 	"helm.sh/helm/v4/pkg/storage/driver"
+	helmtime "helm.sh/helm/v4/pkg/time"
 )
 
 // HelmStorageType is the type field of the Kubernetes storage object which stores the Helm release
@@ -43,6 +45,12 @@ type Storage struct {
 	// be retained, including the most recent release. Values of 0 or less are
 	// ignored (meaning no limits are imposed).
 	MaxHistory int
+	// MaxHistoryAge specifies the age past which historical releases are
+	// removed when a new release is stored, in addition to the MaxHistory
+	// limit. The age of a release is measured from when it was last deployed.
+	// The last deployed release is always retained. Values of 0 or less are
+	// ignored (meaning no limits are imposed).
+	MaxHistoryAge time.Duration
 }
 
 // Get retrieves the release from storage. An error is returned
@@ -65,6 +73,12 @@ func (s *Storage) Create(rls *rspb.Release) error {
 			return err
 		}
 	}
+	if s.MaxHistoryAge > 0 {
+		if err := s.removeOlderThan(rls.Name, s.MaxHistoryAge); err != nil &&
+			!errors.Is(err, driver.ErrReleaseNotFound) {
+			return err
+		}
+	}
 	return s.Driver.Create(makeKey(rls.Name, rls.Version), rls)
 }
 
@@ -91,6 +105,23 @@ func (s *Storage) ListReleases() ([]*rspb.Release, error) {
 	return s.List(func(_ *rspb.Release) bool { return true })
 }
 
+// ListPage returns a page of the releases from storage such that
+// filter(release) == true, and the continue token of the next page, which is
+// empty after the last page. Drivers that do not implement driver.Pager
+// return all the releases as a single page. An error is returned if the
+// storage backend fails to retrieve the releases.
+func (s *Storage) ListPage(filter func(*rspb.Release) bool, opts driver.ListOptions) ([]*rspb.Release, string, error) {
+	slog.Debug("listing a page of releases in storage", "limit", opts.Limit)
+	if p, ok := s.Driver.(driver.Pager); ok {
+		return p.ListPage(filter, opts)
+	}
+	if opts.Continue != "" {
+		return nil, "", driver.ErrInvalidContinue
+	}
+	rls, err := s.List(filter)
+	return rls, "", err
+}
+
 // ListUninstalled returns all releases with Status == UNINSTALLED. An error is returned
 // if the storage backend fails to retrieve the releases.
 func (s *Storage) ListUninstalled() ([]*rspb.Release, error) {
@@ -195,12 +226,44 @@ func (s *Storage) removeLeastRecent(name string, maximum int) error {
 		}
 	}
 
+	return s.deleteReleaseVersions(name, toDelete)
+}
+
+// removeOlderThan removes the releases last deployed longer than maxAge ago,
+// except for the last deployed release.
+func (s *Storage) removeOlderThan(name string, maxAge time.Duration) error {
+	h, err := s.History(name)
+	if err != nil {
+		return err
+	}
+
+	lastDeployed, err := s.Deployed(name)
+	if err != nil && !errors.Is(err, driver.ErrNoDeployedReleases) {
+		return err
+	}
+
+	cutoff := helmtime.Now().Add(-maxAge)
+	var toDelete []*rspb.Release
+	for _, rel := range h {
+		if lastDeployed != nil && rel.Version == lastDeployed.Version {
+			continue
+		}
+		if rel.Info != nil && rel.Info.LastDeployed.Before(cutoff) {
+			toDelete = append(toDelete, rel)
+		}
+	}
+	if len(toDelete) == 0 {
+		return nil
+	}
+	return s.deleteReleaseVersions(name, toDelete)
+}
+
+func (s *Storage) deleteReleaseVersions(name string, toDelete []*rspb.Release) error {
 	// Delete as many as possible. In the case of API throughput limitations,
 	// multiple invocations of this function will eventually delete them all.
 	errs := []error{}
 	for _, rel := range toDelete {
-		err = s.deleteReleaseVersion(name, rel.Version)
-		if err != nil {
+		if err := s.deleteReleaseVersion(name, rel.Version); err != nil {
 			errs = append(errs, err)
 		}
 	}
@@ -20,11 +20,13 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 	"helm.sh/helm/v4/pkg/storage/driver"
+	helmtime "helm.sh/helm/v4/pkg/time"
 )
 
 func TestStorageCreate(t *testing.T) {
@@ -167,6 +169,26 @@ func TestStorageList(t *testing.T) {
 	}
 }
 
+func TestStorageListPage(t *testing.T) {
+	storage := Init(driver.NewMemory())
+
+	rls0 := ReleaseTestData{Name: "happy-catdog", Status: rspb.StatusDeployed}.ToRelease()
+	rls1 := ReleaseTestData{Name: "livid-human", Status: rspb.StatusUninstalled}.ToRelease()
+	assertErrNil(t.Fatal, storage.Create(rls0), "Storing release 'rls0'")
+	assertErrNil(t.Fatal, storage.Create(rls1), "Storing release 'rls1'")
+
+	// The memory driver is not a pager, so all the releases are a single page.
+	list, next, err := storage.ListPage(func(_ *rspb.Release) bool { return true }, driver.ListOptions{Limit: 1})
+	assertErrNil(t.Fatal, err, "ListPage")
+	if len(list) != 2 || next != "" {
+		t.Errorf("ListPage: expected 2 releases and no continue token, got %d and %q", len(list), next)
+	}
+
+	if _, _, err := storage.ListPage(func(_ *rspb.Release) bool { return true }, driver.ListOptions{Continue: "token"}); err != driver.ErrInvalidContinue {
+		t.Errorf("ListPage: expected %v, got %v", driver.ErrInvalidContinue, err)
+	}
+}
+
 func TestStorageDeployed(t *testing.T) {
 	storage := Init(driver.NewMemory())
 
@@ -441,6 +463,42 @@ func TestStorageDoNotDeleteDeployed(t *testing.T) {
 	}
 }
 
+func TestStorageRemoveOlderThanMaxHistoryAge(t *testing.T) {
+	storage := Init(driver.NewMemory())
+	storage.MaxHistoryAge = 30 * 24 * time.Hour
+
+	const name = "angry-bird"
+
+	// setup storage with test releases, the first three older than the limit
+	now := helmtime.Now()
+	statuses := []rspb.Status{rspb.StatusSuperseded, rspb.StatusDeployed, rspb.StatusFailed, rspb.StatusFailed}
+	ageInDays := []int{40, 35, 31, 1}
+	for i, status := range statuses {
+		rls := ReleaseTestData{Name: name, Version: i + 1, Status: status}.ToRelease()
+		rls.Info.LastDeployed = now.Add(-time.Duration(ageInDays[i]) * 24 * time.Hour)
+		assertErrNil(t.Fatal, storage.Driver.Create(makeKey(rls.Name, rls.Version), rls), fmt.Sprintf("Storing release 'angry-bird' (v%d)", rls.Version))
+	}
+
+	rls5 := ReleaseTestData{Name: name, Version: 5, Status: rspb.StatusPendingUpgrade}.ToRelease()
+	rls5.Info.LastDeployed = now
+	assertErrNil(t.Fatal, storage.Create(rls5), "Storing release 'angry-bird' (v5)")
+
+	// Versions 1 and 3 are past the limit, while version 2 is the last
+	// deployed release.
+	hist, err := storage.History(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := map[int]bool{}
+	for _, item := range hist {
+		versions[item.Version] = true
+	}
+	expectedVersions := map[int]bool{2: true, 4: true, 5: true}
+	if !reflect.DeepEqual(expectedVersions, versions) {
+		t.Errorf("expected versions %v, got %v", expectedVersions, versions)
+	}
+}
+
 func TestStorageLast(t *testing.T) {
 	storage := Init(driver.NewMemory())
 